@@ -24,6 +24,10 @@ func main() {
 		cmd.RunMigrate()
 	case "create-user":
 		cmd.RunCreateUser()
+	case "workerd":
+		cmd.RunWorkerDaemon()
+	case "backup":
+		cmd.RunBackup()
 	case "help":
 		showHelp()
 	default:
@@ -38,8 +42,10 @@ func showHelp() {
 	fmt.Println("Usage: ./goth [command] [args]")
 	fmt.Println("\nAvailable commands:")
 	fmt.Println("  server       Start the web server (default)")
-	fmt.Println("  migrate      Run database migrations")
+	fmt.Println("  migrate      Manage database migrations (up|down <n>|status|force <version>)")
 	fmt.Println("  seed         Run migrations and seed the database")
 	fmt.Println("  create-user  Create a new user (args: <email> <password>)")
+	fmt.Println("  workerd      Run a remote worker daemon (env: WORKERD_SERVER_URL, WORKERD_SECRET)")
+	fmt.Println("  backup       Export/import jobs and DLQ as NDJSON (export [--type T] [--tenant ID] [--include-dlq] | import [--dedupe] [--preserve-timestamps])")
 	fmt.Println("  help         Show this help message")
 }
@@ -0,0 +1,239 @@
+// Package audit persiste uma trilha de auditoria para ações sensíveis
+// (login, troca de senha, mudança de papel, uploads, chamadas de ferramentas
+// do LLM) a partir dos mesmos atributos já acumulados pelo wide-event logger
+// em internal/logging.
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/PauloHFS/goth/internal/db"
+	"github.com/PauloHFS/goth/internal/logging"
+)
+
+// Action identifica o tipo de evento auditado.
+type Action string
+
+const (
+	ActionLoginSuccess   Action = "login_success"
+	ActionLoginFailure   Action = "login_failure"
+	ActionPasswordReset  Action = "password_reset"
+	ActionRoleChange     Action = "role_change"
+	ActionUpload         Action = "upload"
+	ActionLLMToolCall    Action = "llm_tool_call"
+	ActionAccountCreated Action = "account_created"
+	ActionSessionRevoked Action = "session_revoked"
+	Action2FAEnabled     Action = "2fa_enabled"
+	Action2FALoginDenied Action = "2fa_login_denied"
+)
+
+// Result descreve o desfecho da ação auditada.
+type Result string
+
+const (
+	ResultSuccess Result = "success"
+	ResultFailure Result = "failure"
+	ResultDenied  Result = "denied"
+)
+
+// Entry representa uma linha da tabela audits.
+type Entry struct {
+	ID         int64
+	TenantID   string
+	Timestamp  time.Time
+	ActorID    sql.NullInt64
+	IP         string
+	UserAgent  string
+	Action     Action
+	TargetType string
+	TargetID   string
+	Result     Result
+	SessionID  string
+	Extra      json.RawMessage
+}
+
+// Auditor grava e consulta a trilha de auditoria.
+type Auditor struct {
+	db *sql.DB
+}
+
+// NewAuditor cria um Auditor sobre a conexão de escrita do banco.
+func NewAuditor(dbConn *sql.DB) *Auditor {
+	return &Auditor{db: dbConn}
+}
+
+// EnsureTable cria a tabela audits se ainda não existir. Ela é mantida fora do
+// fluxo normal de db.RunMigrations para que a auditoria funcione mesmo antes
+// de uma migração dedicada ser escrita.
+func (a *Auditor) EnsureTable(ctx context.Context) error {
+	_, err := a.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS audits (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			tenant_id TEXT NOT NULL DEFAULT 'default',
+			timestamp DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			actor_id INTEGER,
+			ip TEXT,
+			user_agent TEXT,
+			action TEXT NOT NULL,
+			target_type TEXT NOT NULL,
+			target_id TEXT NOT NULL,
+			result TEXT NOT NULL,
+			session_id TEXT,
+			extra TEXT
+		);
+		CREATE INDEX IF NOT EXISTS idx_audits_tenant_time ON audits(tenant_id, timestamp);
+		CREATE INDEX IF NOT EXISTS idx_audits_actor ON audits(actor_id);
+		CREATE INDEX IF NOT EXISTS idx_audits_action ON audits(action);
+	`)
+	if err != nil {
+		return fmt.Errorf("falha ao garantir tabela audits: %w", err)
+	}
+	return nil
+}
+
+// Record persiste um evento de auditoria, mesclando os atributos já
+// acumulados no logging.Event do contexto (IP, user agent, request_id etc.)
+// ao campo extra, de forma que o registro correlaciona com a linha de log.
+func (a *Auditor) Record(ctx context.Context, action Action, actorID int64, targetType, targetID string, result Result, extra map[string]any) error {
+	tenantID := "default"
+
+	merged := map[string]any{}
+	if event := logging.EventFromContext(ctx); event != nil {
+		for _, raw := range event.Attrs() {
+			if attr, ok := raw.(slog.Attr); ok {
+				merged[attr.Key] = attr.Value.Any()
+			}
+		}
+	}
+	for k, v := range extra {
+		merged[k] = v
+	}
+
+	extraJSON, err := json.Marshal(merged)
+	if err != nil {
+		return fmt.Errorf("falha ao serializar campos extra da auditoria: %w", err)
+	}
+
+	var actor sql.NullInt64
+	if actorID > 0 {
+		actor = sql.NullInt64{Int64: actorID, Valid: true}
+	}
+
+	ip, _ := ctx.Value(ipContextKey).(string)
+	ua, _ := ctx.Value(uaContextKey).(string)
+	sessionID, _ := ctx.Value(sessionContextKey).(string)
+
+	_, err = a.db.ExecContext(ctx, `
+		INSERT INTO audits (tenant_id, actor_id, ip, user_agent, action, target_type, target_id, result, session_id, extra)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, tenantID, actor, ip, ua, string(action), targetType, targetID, string(result), sessionID, string(extraJSON))
+	if err != nil {
+		return fmt.Errorf("falha ao gravar auditoria: %w", err)
+	}
+
+	return nil
+}
+
+type contextKey string
+
+const (
+	ipContextKey      contextKey = "audit_ip"
+	uaContextKey      contextKey = "audit_ua"
+	sessionContextKey contextKey = "audit_session_id"
+)
+
+// WithRequestMetadata anexa IP, user agent e ID de sessão ao contexto para
+// que Record os inclua automaticamente no registro de auditoria.
+func WithRequestMetadata(ctx context.Context, ip, userAgent, sessionID string) context.Context {
+	ctx = context.WithValue(ctx, ipContextKey, ip)
+	ctx = context.WithValue(ctx, uaContextKey, userAgent)
+	ctx = context.WithValue(ctx, sessionContextKey, sessionID)
+	return ctx
+}
+
+// ListAuditsParams filtra a consulta paginada de auditorias.
+type ListAuditsParams struct {
+	TenantID string
+	ActorID  int64
+	Action   Action
+	Since    time.Time
+	Until    time.Time
+	Paging   db.PagingParams
+}
+
+// ListAudits retorna uma página de registros de auditoria filtrada para
+// consumo pela UI administrativa.
+func (a *Auditor) ListAudits(ctx context.Context, params ListAuditsParams) (db.PagedResult[Entry], error) {
+	tenantID := params.TenantID
+	if tenantID == "" {
+		tenantID = "default"
+	}
+
+	where := "WHERE tenant_id = ?"
+	args := []any{tenantID}
+
+	if params.ActorID > 0 {
+		where += " AND actor_id = ?"
+		args = append(args, params.ActorID)
+	}
+	if params.Action != "" {
+		where += " AND action = ?"
+		args = append(args, string(params.Action))
+	}
+	if !params.Since.IsZero() {
+		where += " AND timestamp >= ?"
+		args = append(args, params.Since)
+	}
+	if !params.Until.IsZero() {
+		where += " AND timestamp <= ?"
+		args = append(args, params.Until)
+	}
+
+	var total int
+	countQuery := "SELECT COUNT(*) FROM audits " + where
+	if err := a.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return db.PagedResult[Entry]{}, fmt.Errorf("falha ao contar auditorias: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, tenant_id, timestamp, actor_id, ip, user_agent, action, target_type, target_id, result, session_id, extra
+		FROM audits %s
+		ORDER BY timestamp DESC
+		LIMIT ? OFFSET ?
+	`, where)
+	args = append(args, params.Paging.Limit(), params.Paging.Offset())
+
+	rows, err := a.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return db.PagedResult[Entry]{}, fmt.Errorf("falha ao listar auditorias: %w", err)
+	}
+	defer rows.Close()
+
+	var items []Entry
+	for rows.Next() {
+		var e Entry
+		var action, result string
+		var extra sql.NullString
+		if err := rows.Scan(&e.ID, &e.TenantID, &e.Timestamp, &e.ActorID, &e.IP, &e.UserAgent, &action, &e.TargetType, &e.TargetID, &result, &e.SessionID, &extra); err != nil {
+			return db.PagedResult[Entry]{}, fmt.Errorf("falha ao ler linha de auditoria: %w", err)
+		}
+		e.Action = Action(action)
+		e.Result = Result(result)
+		if extra.Valid {
+			e.Extra = json.RawMessage(extra.String)
+		}
+		items = append(items, e)
+	}
+
+	return db.PagedResult[Entry]{
+		Items:       items,
+		TotalItems:  total,
+		CurrentPage: params.Paging.Page,
+		PerPage:     params.Paging.Limit(),
+	}, nil
+}
@@ -0,0 +1,133 @@
+package authz
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/PauloHFS/goth/internal/db"
+)
+
+// Manager carrega PermissionSets a partir da tabela roles e os mantém em
+// cache por role_id, evitando uma consulta ao banco a cada request — o
+// conjunto de permissões de um papel raramente muda durante a vida do
+// processo, então ele é resolvido uma vez e reutilizado pelo restante da
+// sessão do usuário.
+type Manager struct {
+	db *sql.DB
+
+	mu    sync.RWMutex
+	cache map[string]*PermissionSet
+}
+
+// NewManager cria um Manager pronto para uso sobre uma conexão já aberta.
+func NewManager(dbConn *sql.DB) *Manager {
+	return &Manager{
+		db:    dbConn,
+		cache: make(map[string]*PermissionSet),
+	}
+}
+
+// EnsureSchema garante a coluna roles.inherits (JSON array de role_id pai,
+// ver Load), idempotente como worker.JobServer.EnsureLeaseSchema — a coluna
+// é adicionada em runtime via ALTER TABLE, não por um arquivo versionado de
+// migrations.FS (ver db.MigrateUp).
+func (m *Manager) EnsureSchema(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `ALTER TABLE roles ADD COLUMN inherits TEXT`)
+	if err != nil && !strings.Contains(strings.ToLower(err.Error()), "duplicate column") {
+		return fmt.Errorf("authz: falha ao adicionar roles.inherits: %w", err)
+	}
+	return nil
+}
+
+// Load resolve o PermissionSet do papel roleID, mesclando as permissões dos
+// papéis listados em roles.inherits (ex. "editor" herdando de "member") na
+// ordem em que aparecem. A ordem não muda o resultado de Allows: um deny que
+// combine, venha do próprio papel ou de um herdado, sempre nega. Consulta o banco apenas na primeira
+// chamada para cada roleID; o resultado já mesclado fica em cache.
+func (m *Manager) Load(ctx context.Context, roleID string) (*PermissionSet, error) {
+	m.mu.RLock()
+	set, ok := m.cache[roleID]
+	m.mu.RUnlock()
+	if ok {
+		return set, nil
+	}
+
+	set, err := m.load(ctx, roleID, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.cache[roleID] = set
+	m.mu.Unlock()
+
+	return set, nil
+}
+
+// load resolve roleID sem tocar o cache, mesclando recursivamente cada
+// papel em visited — visited evita um loop infinito caso roles.inherits
+// descreva um ciclo (ex. "a" herda de "b" que herda de "a").
+func (m *Manager) load(ctx context.Context, roleID string, visited map[string]bool) (*PermissionSet, error) {
+	if visited[roleID] {
+		return &PermissionSet{}, nil
+	}
+	visited[roleID] = true
+
+	var raw json.RawMessage
+	var inherits sql.NullString
+	err := m.db.QueryRowContext(ctx, "SELECT permissions, inherits FROM roles WHERE id = ?", roleID).Scan(&raw, &inherits)
+	if err != nil {
+		return nil, fmt.Errorf("authz: falha ao carregar papel %q: %w", roleID, err)
+	}
+
+	set, err := ParsePermissions(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if !inherits.Valid || inherits.String == "" {
+		return set, nil
+	}
+
+	var parents []string
+	if err := json.Unmarshal([]byte(inherits.String), &parents); err != nil {
+		return nil, fmt.Errorf("authz: roles.inherits inválido para %q: %w", roleID, err)
+	}
+
+	for _, parent := range parents {
+		parentSet, err := m.load(ctx, parent, visited)
+		if err != nil {
+			return nil, err
+		}
+		set = set.Merge(parentSet)
+	}
+
+	return set, nil
+}
+
+// Invalidate remove roleID do cache, forçando a próxima Load a reconsultar
+// o banco — use após alterar roles.permissions/roles.inherits em runtime
+// (ex. admin UI). Papéis filhos que já tenham um PermissionSet mesclado em
+// cache não são invalidados automaticamente: se roleID for herdado por
+// outros papéis, invalide-os também.
+func (m *Manager) Invalidate(roleID string) {
+	m.mu.Lock()
+	delete(m.cache, roleID)
+	m.mu.Unlock()
+}
+
+// Enforce resolve o papel de actor e reporta se perm é concedida,
+// avaliando resourceAttrs contra eventuais condições — atalho usado por
+// internal/policies para substituir comparações de actor.RoleID
+// espalhadas pelo código por uma única fonte de verdade.
+func (m *Manager) Enforce(ctx context.Context, actor db.User, perm string, resourceAttrs map[string]string) (bool, error) {
+	set, err := m.Load(ctx, actor.RoleID)
+	if err != nil {
+		return false, err
+	}
+	return set.Allows(perm, resourceAttrs, actor), nil
+}
@@ -0,0 +1,66 @@
+package authz
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/PauloHFS/goth/internal/middleware"
+)
+
+type contextKey string
+
+const permissionSetContextKey contextKey = "authz_permission_set"
+
+// ResourceFunc extrai, a partir da request (tipicamente de path values ou do
+// contexto), os atributos do recurso sendo acessado — ex. {"tenant_id":
+// r.PathValue("tenant")} — para que RequirePermission avalie políticas
+// condicionais como ownership por tenant. Pode ser nil quando o perm exigido
+// não tem políticas condicionais.
+type ResourceFunc func(*http.Request) map[string]string
+
+// RequirePermission exige que o usuário autenticado (ver middleware.RequireAuth,
+// que deve rodar antes) possua perm no papel carregado via mgr, avaliando as
+// condições de políticas contra os atributos que resourceFn extrair de cada
+// request (nil se resourceFn for nil). Em caso de concessão, o
+// PermissionSet fica disponível no contexto para HasPermission.
+func RequirePermission(mgr *Manager, perm string, resourceFn ResourceFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := middleware.GetUser(r.Context())
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			set, err := mgr.Load(r.Context(), user.RoleID)
+			if err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+			var resourceAttrs map[string]string
+			if resourceFn != nil {
+				resourceAttrs = resourceFn(r)
+			}
+
+			if !set.Allows(perm, resourceAttrs, user) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), permissionSetContextKey, set)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// HasPermission reporta se o PermissionSet colocado no contexto por
+// RequirePermission concede perm. Retorna false se nenhum PermissionSet foi
+// carregado para esta request.
+func HasPermission(ctx context.Context, perm string) bool {
+	set, ok := ctx.Value(permissionSetContextKey).(*PermissionSet)
+	if !ok {
+		return false
+	}
+	return set.Has(perm)
+}
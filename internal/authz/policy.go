@@ -0,0 +1,196 @@
+// Package authz implementa o controle de permissões baseado em papéis
+// (roles) usado pelas rotas autenticadas. Cada papel, gravado na tabela
+// roles (ver internal/db/seeder.go), carrega um array JSON de permissões
+// que mistura dois formatos:
+//
+//   - uma string simples, ex. "posts.write" ou "posts.*" ou "*"; um "!" na
+//     frente ("!posts.delete") a transforma num deny explícito;
+//   - um objeto de política com condição e, opcionalmente, effect, ex.
+//     {"resource":"post","action":"write","condition":{"tenant_id":"$user.tenant_id"}}
+//     ou {"resource":"post","action":"delete","effect":"deny"}
+//
+// Políticas com condição só são concedidas quando os atributos do recurso
+// avaliado, informados pelo chamador, batem com os valores de $user.* do
+// usuário autenticado — ver PermissionSet.Allows. Um deny que combine com o
+// perm avaliado sempre derruba qualquer allow equivalente, mesmo vindo de um
+// papel herdado (ver Manager.Load) — não existe ordem de prioridade, o
+// primeiro deny que combinar já é suficiente para negar.
+package authz
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/PauloHFS/goth/internal/db"
+)
+
+// Condition mapeia um atributo do recurso (ex. "tenant_id") para um valor
+// esperado, que pode referenciar campos do usuário autenticado via o
+// prefixo "$user." (ex. "$user.tenant_id").
+type Condition map[string]string
+
+// Policy é a entrada da DSL de permissões persistida na tabela roles.
+type Policy struct {
+	Resource  string    `json:"resource"`
+	Action    string    `json:"action"`
+	Condition Condition `json:"condition,omitempty"`
+	// Effect é "allow" (padrão, quando vazio) ou "deny". Um deny com
+	// Condition só nega quando a condição também bate.
+	Effect string `json:"effect,omitempty"`
+}
+
+// Permission retorna a permissão equivalente no formato "resource.action",
+// usado para casar com wildcards como "posts.*".
+func (p Policy) Permission() string {
+	return p.Resource + "." + p.Action
+}
+
+func (p Policy) isDeny() bool {
+	return p.Effect == "deny"
+}
+
+type entry struct {
+	wildcard string
+	deny     bool
+	policy   *Policy
+}
+
+// PermissionSet é o conjunto de permissões resolvido para um papel.
+type PermissionSet struct {
+	entries []entry
+}
+
+// ParsePermissions decodifica a coluna roles.permissions (array JSON de
+// strings e/ou objetos de política) em um PermissionSet.
+func ParsePermissions(raw json.RawMessage) (*PermissionSet, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("authz: permissions inválido: %w", err)
+	}
+
+	set := &PermissionSet{entries: make([]entry, 0, len(items))}
+	for _, item := range items {
+		var wildcard string
+		if err := json.Unmarshal(item, &wildcard); err == nil {
+			deny := false
+			if rest, ok := strings.CutPrefix(wildcard, "!"); ok {
+				deny, wildcard = true, rest
+			}
+			set.entries = append(set.entries, entry{wildcard: wildcard, deny: deny})
+			continue
+		}
+
+		var policy Policy
+		if err := json.Unmarshal(item, &policy); err != nil {
+			return nil, fmt.Errorf("authz: entrada de permissão inválida %q: %w", item, err)
+		}
+		set.entries = append(set.entries, entry{policy: &policy})
+	}
+
+	return set, nil
+}
+
+// Merge retorna um novo PermissionSet com as entradas de s seguidas das de
+// other — usado por Manager.Load para compor um papel com as permissões
+// herdadas de seus papéis pai (ver roles.inherits).
+func (s *PermissionSet) Merge(other *PermissionSet) *PermissionSet {
+	if s == nil {
+		return other
+	}
+	if other == nil {
+		return s
+	}
+
+	merged := make([]entry, 0, len(s.entries)+len(other.entries))
+	merged = append(merged, s.entries...)
+	merged = append(merged, other.entries...)
+	return &PermissionSet{entries: merged}
+}
+
+// Has reporta se perm ("resource.action") é concedida por alguma entrada
+// sem condição (string simples ou política sem Condition).
+func (s *PermissionSet) Has(perm string) bool {
+	return s.Allows(perm, nil, db.User{})
+}
+
+// Allows reporta se perm é concedida, avaliando a condição de políticas
+// contra resourceAttrs (atributos do recurso sendo acessado) e os campos
+// $user.* do usuário autenticado.
+func (s *PermissionSet) Allows(perm string, resourceAttrs map[string]string, user db.User) bool {
+	if s == nil {
+		return false
+	}
+
+	allowed := false
+	for _, e := range s.entries {
+		switch {
+		case e.wildcard != "":
+			if matchesWildcard(e.wildcard, perm) {
+				if e.deny {
+					return false
+				}
+				allowed = true
+			}
+		case e.policy != nil:
+			if matchesWildcard(e.policy.Permission(), perm) && evalCondition(e.policy.Condition, resourceAttrs, user) {
+				if e.policy.isDeny() {
+					return false
+				}
+				allowed = true
+			}
+		}
+	}
+
+	return allowed
+}
+
+func matchesWildcard(pattern, perm string) bool {
+	if pattern == "*" || pattern == perm {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(perm, prefix)
+	}
+	return false
+}
+
+// evalCondition substitui cada valor "$user.*" pelo campo correspondente do
+// usuário autenticado e compara com o atributo do recurso. Uma condição
+// vazia sempre passa; um atributo de recurso ausente nunca bate.
+func evalCondition(cond Condition, resourceAttrs map[string]string, user db.User) bool {
+	if len(cond) == 0 {
+		return true
+	}
+
+	for field, tmpl := range cond {
+		want := substituteUser(tmpl, user)
+		got, ok := resourceAttrs[field]
+		if !ok || got != want {
+			return false
+		}
+	}
+
+	return true
+}
+
+func substituteUser(tmpl string, user db.User) string {
+	key, ok := strings.CutPrefix(tmpl, "$user.")
+	if !ok {
+		return tmpl
+	}
+
+	switch key {
+	case "id":
+		return strconv.FormatInt(user.ID, 10)
+	case "tenant_id":
+		return user.TenantID
+	case "role_id":
+		return user.RoleID
+	case "email":
+		return user.Email
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,211 @@
+// Package awssig implementa o processo de assinatura AWS Signature Version
+// 4 (SigV4) diretamente sobre net/http, sem depender do aws-sdk-go — o
+// projeto evita deliberadamente a SDK da AWS (ver AWSESProvider, o único
+// chamador hoje). Cobre só o necessário pra assinar uma requisição POST com
+// corpo JSON contra um serviço regional (ex.: SES v2), não o conjunto
+// completo de casos da SDK (upload em chunks, presigned URLs, etc.).
+package awssig
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	algorithm  = "AWS4-HMAC-SHA256"
+	terminator = "aws4_request"
+
+	amzDateFormat = "20060102T150405Z"
+	dateFormat    = "20060102"
+)
+
+// HashPayload devolve o SHA256 em hex do corpo — usado tanto como
+// HashedPayload do canonical request quanto no header X-Amz-Content-Sha256.
+func HashPayload(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// SigningKey deriva a chave de assinatura pela cadeia de HMAC do SigV4:
+// kDate = HMAC("AWS4"+secret, date) -> kRegion -> kService -> kSigning
+// ("aws4_request").
+func SigningKey(secretKey, date, region, service string) []byte {
+	kDate := hmacSHA256([]byte("AWS4"+secretKey), []byte(date))
+	kRegion := hmacSHA256(kDate, []byte(region))
+	kService := hmacSHA256(kRegion, []byte(service))
+	return hmacSHA256(kService, []byte(terminator))
+}
+
+// Signature assina stringToSign com signingKey (ver SigningKey) e devolve a
+// assinatura em hex.
+func Signature(signingKey []byte, stringToSign string) string {
+	return hex.EncodeToString(hmacSHA256(signingKey, []byte(stringToSign)))
+}
+
+// StringToSign monta o "string to sign" do SigV4 (Task 2 da documentação
+// AWS).
+func StringToSign(amzDate, credentialScope, canonicalRequestHash string) string {
+	return strings.Join([]string{algorithm, amzDate, credentialScope, canonicalRequestHash}, "\n")
+}
+
+func isUnreservedByte(c byte) bool {
+	return (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+		c == '-' || c == '_' || c == '.' || c == '~'
+}
+
+// uriEncode segue a regra de percent-encoding do SigV4 (RFC 3986, sempre
+// maiúsculo nos dois dígitos hex) — encodeSlash controla se "/" dentro de s
+// também deve ser escapado (sim para query string/valores, não para
+// segmentos de path, que são escapados um a um por CanonicalURI).
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isUnreservedByte(c) || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// CanonicalURI escapa cada segmento do path separadamente, preservando as
+// barras que os separam.
+func CanonicalURI(path string) string {
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		segments[i] = uriEncode(seg, true)
+	}
+	return strings.Join(segments, "/")
+}
+
+// CanonicalQueryString ordena os parâmetros por chave (e, dentro da mesma
+// chave, por valor) e os escapa conforme o SigV4 exige.
+func CanonicalQueryString(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// SignedHeadersList devolve os nomes de headers em minúsculo, ordenados —
+// tanto a lista usada no canonical request quanto a que vai no header
+// SignedHeaders do Authorization.
+func SignedHeadersList(headers http.Header) []string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CanonicalRequest monta o canonical request do SigV4 (Task 1): método, URI
+// canônica, query string canônica, headers canônicos (nome em minúsculo,
+// ordenados, um por linha terminada em "\n"), a lista de signed headers e o
+// hash do payload.
+func CanonicalRequest(method, canonicalURI, canonicalQuery string, headers http.Header, signedHeaders []string, payloadHash string) string {
+	var canonicalHeaders strings.Builder
+	for _, name := range signedHeaders {
+		canonicalHeaders.WriteString(name)
+		canonicalHeaders.WriteString(":")
+		canonicalHeaders.WriteString(strings.TrimSpace(headers.Get(name)))
+		canonicalHeaders.WriteString("\n")
+	}
+
+	return strings.Join([]string{
+		method,
+		canonicalURI,
+		canonicalQuery,
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+}
+
+// Request é a entrada de Sign — não assina um *http.Request diretamente
+// porque o corpo (pro payload hash) e a query canônica precisam estar
+// disponíveis antes de calcular o Authorization, e quem chama decide onde
+// colocar os headers resultantes.
+type Request struct {
+	Method    string
+	Host      string
+	Path      string
+	Query     url.Values
+	Body      []byte
+	AccessKey string
+	SecretKey string
+	Region    string
+	Service   string
+	Time      time.Time
+}
+
+// SignedHeaders é o que Sign calcula, pronto pra ser setado no
+// *http.Request real por quem chamou.
+type SignedHeaders struct {
+	Authorization string
+	AmzDate       string
+	ContentSHA256 string
+}
+
+// Sign calcula o Authorization (com Credential/SignedHeaders/Signature),
+// X-Amz-Date e X-Amz-Content-Sha256 de req, assinando host e x-amz-date
+// (únicos headers cobertos hoje, suficiente para o SES v2).
+func Sign(req Request) SignedHeaders {
+	t := req.Time.UTC()
+	amzDate := t.Format(amzDateFormat)
+	date := t.Format(dateFormat)
+	contentSHA256 := HashPayload(req.Body)
+
+	headers := http.Header{}
+	headers.Set("host", req.Host)
+	headers.Set("x-amz-date", amzDate)
+
+	signedHeaders := SignedHeadersList(headers)
+	canonicalQuery := CanonicalQueryString(req.Query)
+	canonicalReq := CanonicalRequest(req.Method, CanonicalURI(req.Path), canonicalQuery, headers, signedHeaders, contentSHA256)
+	canonicalReqHash := HashPayload([]byte(canonicalReq))
+
+	credentialScope := fmt.Sprintf("%s/%s/%s/%s", date, req.Region, req.Service, terminator)
+	stringToSign := StringToSign(amzDate, credentialScope, canonicalReqHash)
+
+	signingKey := SigningKey(req.SecretKey, date, req.Region, req.Service)
+	signature := Signature(signingKey, stringToSign)
+
+	authorization := fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algorithm, req.AccessKey, credentialScope, strings.Join(signedHeaders, ";"), signature)
+
+	return SignedHeaders{
+		Authorization: authorization,
+		AmzDate:       amzDate,
+		ContentSHA256: contentSHA256,
+	}
+}
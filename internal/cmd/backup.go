@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/PauloHFS/goth/internal/logging"
+	"github.com/PauloHFS/goth/internal/worker"
+)
+
+// RunBackup despacha os subcomandos de "goth backup" (mesmo padrão de
+// RunMigrate): "export [--type T] [--tenant ID] [--include-dlq]" grava o
+// dump NDJSON no stdout; "import [--dedupe] [--preserve-timestamps]" lê o
+// dump do stdin e recria os jobs. Pensado para `goth backup export >
+// dump.ndjson` antes de uma migração, e `goth backup import --dedupe <
+// dump.ndjson` para reproduzir itens de uma DLQ de produção em staging.
+func RunBackup() {
+	if len(os.Args) < 3 {
+		os.Stderr.WriteString("Usage: backup export|import [flags]\n")
+		os.Exit(1)
+	}
+
+	dbConn, err := initDB()
+	if err != nil {
+		panic(err)
+	}
+	defer dbConn.Close()
+
+	logging.Init()
+	logger := logging.Get()
+	ctx := context.Background()
+
+	manager := worker.NewBackupManager(dbConn, logger)
+	if err := manager.EnsurePayloadHashSchema(ctx); err != nil {
+		logger.Error("failed to ensure payload_hash schema", "error", err)
+		os.Exit(1)
+	}
+
+	switch os.Args[2] {
+	case "export":
+		flags := backupExportFlags(os.Args[3:])
+		filter := worker.JobFilter{
+			Type:              flags.jobType,
+			TenantID:          flags.tenantID,
+			IncludeDeadLetter: flags.includeDLQ,
+		}
+		if err := manager.ExportJobs(ctx, os.Stdout, filter); err != nil {
+			logger.Error("backup export failed", "error", err)
+			os.Exit(1)
+		}
+
+	case "import":
+		flags := backupImportFlags(os.Args[3:])
+		report, err := manager.ImportJobs(ctx, os.Stdin, worker.ImportOptions{
+			Dedupe:             flags.dedupe,
+			PreserveTimestamps: flags.preserveTimestamps,
+		})
+		if err != nil {
+			logger.Error("backup import failed", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("backup import finished",
+			"imported", report.Imported, "skipped", report.Skipped, "failed", report.Failed)
+
+	default:
+		os.Stderr.WriteString(fmt.Sprintf("Unknown backup subcommand: %s\n", os.Args[2]))
+		os.Stderr.WriteString("Usage: backup export|import [flags]\n")
+		os.Exit(1)
+	}
+}
+
+type backupExportArgs struct {
+	jobType    string
+	tenantID   string
+	includeDLQ bool
+}
+
+func backupExportFlags(args []string) backupExportArgs {
+	var flags backupExportArgs
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--type":
+			if i+1 < len(args) {
+				i++
+				flags.jobType = args[i]
+			}
+		case "--tenant":
+			if i+1 < len(args) {
+				i++
+				flags.tenantID = args[i]
+			}
+		case "--include-dlq":
+			flags.includeDLQ = true
+		}
+	}
+	return flags
+}
+
+type backupImportArgs struct {
+	dedupe             bool
+	preserveTimestamps bool
+}
+
+func backupImportFlags(args []string) backupImportArgs {
+	var flags backupImportArgs
+	for _, arg := range args {
+		switch arg {
+		case "--dedupe":
+			flags.dedupe = true
+		case "--preserve-timestamps":
+			flags.preserveTimestamps = true
+		}
+	}
+	return flags
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
 
 	"github.com/PauloHFS/goth/internal/config"
@@ -52,7 +54,19 @@ func RunSeed() {
 	logger.Info("database seeded successfully")
 }
 
+// RunMigrate despacha os subcomandos de "goth migrate", lidos direto de
+// os.Args (mesmo padrão de RunCreateUser): "up" (default, quando nenhum
+// subcomando é informado) aplica as migrações pendentes; "down N" desfaz as
+// N últimas; "status" lista cada migração conhecida e se já foi aplicada;
+// "force VERSION" resincroniza schema_migrations sem executar SQL. Fora de
+// RunServer/RunSeed, que só chamam db.RunMigrations no boot, para permitir
+// gerenciar o schema manualmente.
 func RunMigrate() {
+	sub := "up"
+	if len(os.Args) > 2 {
+		sub = os.Args[2]
+	}
+
 	dbConn, err := initDB()
 	if err != nil {
 		panic(err)
@@ -61,10 +75,65 @@ func RunMigrate() {
 
 	logging.Init()
 	logger := logging.Get()
+	ctx := context.Background()
 
-	if err := db.RunMigrations(context.Background(), dbConn); err != nil {
-		logger.Error("failed to run migrations", "error", err)
-		return
+	switch sub {
+	case "up":
+		if err := db.MigrateUp(ctx, dbConn, 0); err != nil {
+			logger.Error("failed to run migrations", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("migrations executed successfully")
+
+	case "down":
+		if len(os.Args) < 4 {
+			os.Stderr.WriteString("Usage: migrate down <n>\n")
+			os.Exit(1)
+		}
+		n, err := strconv.Atoi(os.Args[3])
+		if err != nil {
+			os.Stderr.WriteString(fmt.Sprintf("invalid migration count %q: %v\n", os.Args[3], err))
+			os.Exit(1)
+		}
+		if err := db.MigrateDown(ctx, dbConn, n); err != nil {
+			logger.Error("failed to roll back migrations", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("migrations rolled back successfully", "count", n)
+
+	case "status":
+		entries, err := db.MigrationStatus(ctx, dbConn)
+		if err != nil {
+			logger.Error("failed to read migration status", "error", err)
+			os.Exit(1)
+		}
+		for _, e := range entries {
+			state := "pending"
+			if e.Applied {
+				state = "applied at " + e.AppliedAt
+			}
+			fmt.Printf("%04d_%s: %s\n", e.Version, e.Name, state)
+		}
+
+	case "force":
+		if len(os.Args) < 4 {
+			os.Stderr.WriteString("Usage: migrate force <version>\n")
+			os.Exit(1)
+		}
+		version, err := strconv.Atoi(os.Args[3])
+		if err != nil {
+			os.Stderr.WriteString(fmt.Sprintf("invalid version %q: %v\n", os.Args[3], err))
+			os.Exit(1)
+		}
+		if err := db.ForceVersion(ctx, dbConn, version); err != nil {
+			logger.Error("failed to force migration version", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("migration version forced successfully", "version", version)
+
+	default:
+		os.Stderr.WriteString(fmt.Sprintf("Unknown migrate subcommand: %s\n", sub))
+		os.Stderr.WriteString("Usage: migrate [up|down <n>|status|force <version>]\n")
+		os.Exit(1)
 	}
-	logger.Info("migrations executed successfully")
 }
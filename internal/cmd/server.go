@@ -20,10 +20,19 @@ import (
 	httpSwagger "github.com/swaggo/http-swagger"
 
 	_ "github.com/PauloHFS/goth/docs"
+	"github.com/PauloHFS/goth/internal/audit"
+	"github.com/PauloHFS/goth/internal/authz"
 	"github.com/PauloHFS/goth/internal/config"
 	"github.com/PauloHFS/goth/internal/db"
+	"github.com/PauloHFS/goth/internal/filestore"
 	"github.com/PauloHFS/goth/internal/logging"
+	"github.com/PauloHFS/goth/internal/magiclink"
 	"github.com/PauloHFS/goth/internal/middleware"
+	"github.com/PauloHFS/goth/internal/oauth"
+	"github.com/PauloHFS/goth/internal/oidc"
+	"github.com/PauloHFS/goth/internal/pubsub"
+	"github.com/PauloHFS/goth/internal/session"
+	"github.com/PauloHFS/goth/internal/totp"
 	"github.com/PauloHFS/goth/internal/web"
 	"github.com/PauloHFS/goth/internal/webhook"
 	"github.com/PauloHFS/goth/internal/worker"
@@ -58,9 +67,9 @@ func RunServer(assetsFS embed.FS) {
 	}
 	defer dbConn.Close()
 
-	// 1.1 Garantir diretórios de storage
-	if err := os.MkdirAll("storage/avatars", 0755); err != nil {
-		logger.Error("failed to create storage directories", "error", err)
+	fileStore, err := filestore.New(cfg)
+	if err != nil {
+		logger.Error("failed to configure file storage backend", "error", err)
 		panic(err)
 	}
 
@@ -74,10 +83,86 @@ func RunServer(assetsFS embed.FS) {
 	sessionManager := scs.New()
 	sessionManager.Store = sqlite3store.New(dbConn)
 
+	auditor := audit.NewAuditor(dbConn)
+	if err := auditor.EnsureTable(context.Background()); err != nil {
+		logger.Error("failed to ensure audits table", "error", err)
+		panic(err)
+	}
+
+	sessions := session.NewStore(dbConn)
+	if err := sessions.EnsureTable(context.Background()); err != nil {
+		logger.Error("failed to ensure sessions table", "error", err)
+		panic(err)
+	}
+
+	authorizer := authz.NewManager(dbConn)
+	if err := authorizer.EnsureSchema(context.Background()); err != nil {
+		logger.Error("failed to ensure roles schema", "error", err)
+		panic(err)
+	}
+
+	// Evita repetir GetUserByID a cada request autenticado — invalidado nos
+	// pontos onde o usuário muda (perfil, role, senha, logout), ver
+	// internal/middleware/user_cache.go.
+	userCache := middleware.NewMemoryUserCache(0)
+
+	identities := oidc.NewIdentityStore(dbConn)
+	if err := identities.EnsureTable(context.Background()); err != nil {
+		logger.Error("failed to ensure identities table", "error", err)
+		panic(err)
+	}
+	oidcManager := oidc.NewManager(cfg.OIDC.Providers)
+
+	totpStore := totp.NewStore(dbConn)
+	if err := totpStore.EnsureTable(context.Background()); err != nil {
+		logger.Error("failed to ensure user_totp table", "error", err)
+		panic(err)
+	}
+
+	oauthClients := oauth.NewClientStore(dbConn)
+	if err := oauthClients.EnsureTable(context.Background()); err != nil {
+		logger.Error("failed to ensure oauth_clients table", "error", err)
+		panic(err)
+	}
+	oauthAuthorizations := oauth.NewAuthorizationStore(dbConn)
+	if err := oauthAuthorizations.EnsureTable(context.Background()); err != nil {
+		logger.Error("failed to ensure oauth_authorizations table", "error", err)
+		panic(err)
+	}
+	oauthRefreshTokens := oauth.NewRefreshStore(dbConn)
+	if err := oauthRefreshTokens.EnsureTable(context.Background()); err != nil {
+		logger.Error("failed to ensure oauth_refresh_tokens table", "error", err)
+		panic(err)
+	}
+	oauthKeys := oauth.NewKeyStore(dbConn)
+	if err := oauthKeys.EnsureTable(context.Background()); err != nil {
+		logger.Error("failed to ensure signing_keys table", "error", err)
+		panic(err)
+	}
+
+	magicLinks := magiclink.NewStore(dbConn)
+	if err := magicLinks.EnsureTable(context.Background()); err != nil {
+		logger.Error("failed to ensure magic_links table", "error", err)
+		panic(err)
+	}
+
+	// jobsBroker acorda o worker assim que um job é enfileirado, em vez de
+	// depender só do ticker de polling — ver internal/pubsub e
+	// worker.Processor.Start.
+	jobsBroker := pubsub.New()
+
 	workerCtx, cancelWorker := context.WithCancel(context.Background())
 	defer cancelWorker()
 
-	w := worker.New(cfg, dbConn, queries, logger)
+	w := worker.New(cfg, dbConn, queries, logger, fileStore, jobsBroker)
+	if err := w.JobServer().EnsureLeaseSchema(context.Background()); err != nil {
+		logger.Error("failed to ensure jobs lease schema", "error", err)
+		panic(err)
+	}
+	if err := w.JobServer().EnsureTargetHostSchema(context.Background()); err != nil {
+		logger.Error("failed to ensure jobs target_host schema", "error", err)
+		panic(err)
+	}
 	if err := w.RescueZombies(workerCtx); err != nil {
 		logger.Error("zombie hunter failed", "error", err)
 	}
@@ -85,12 +170,18 @@ func RunServer(assetsFS embed.FS) {
 
 	mux := http.NewServeMux()
 	mux.Handle("GET /assets/", http.StripPrefix("/assets/", http.FileServer(http.FS(assetsFS))))
-	mux.Handle("GET /storage/", http.StripPrefix("/storage/", http.FileServer(http.Dir("storage"))))
+	mux.Handle("GET /storage/", http.StripPrefix("/storage/", http.FileServer(http.Dir(cfg.Storage.LocalDir))))
 	mux.Handle("GET /metrics", promhttp.Handler())
 	mux.HandleFunc("GET /events", web.GlobalSSEHandler)
 	mux.Handle("GET /swagger/", httpSwagger.WrapHandler)
 
-	mux.Handle("POST /webhooks/{source}", webhook.NewHandler(queries))
+	webhookVerifiers := webhook.NewRegistryFromConfig(cfg.Webhook)
+	mux.Handle("POST /webhooks/{source}", webhook.NewHandler(dbConn, queries, jobsBroker, webhookVerifiers))
+
+	// Expõe o mesmo JobServer usado pelo worker in-process (ver worker.New)
+	// para daemons remotos via cmd/workerd, atrás de um shared secret — sem
+	// WORKER_REMOTE_SECRET configurado, Register não monta nenhuma rota.
+	w.JobServer().Register(mux, cfg.Worker.RemoteSecret)
 
 	mux.HandleFunc("GET "+web.Health, func(w http.ResponseWriter, r *http.Request) {
 		// 1. Ping DB
@@ -135,6 +226,21 @@ func RunServer(assetsFS embed.FS) {
 		Queries:        queries,
 		SessionManager: sessionManager,
 		Config:         cfg,
+		Auditor:        auditor,
+		Sessions:       sessions,
+		FileStore:      fileStore,
+		Authz:          authorizer,
+		OIDC:           oidcManager,
+		Identities:     identities,
+		TOTP:           totpStore,
+		MagicLinks:     magicLinks,
+		UserCache:      userCache,
+		Jobs:           jobsBroker,
+
+		OAuthClients:        oauthClients,
+		OAuthAuthorizations: oauthAuthorizations,
+		OAuthRefreshTokens:  oauthRefreshTokens,
+		OAuthKeys:           oauthKeys,
 	})
 
 	csrfHandler := nosurf.New(mux)
@@ -146,11 +252,13 @@ func RunServer(assetsFS embed.FS) {
 
 	handler := middleware.Recovery(
 		middleware.RateLimitDefault(
-			middleware.SecurityHeaders(cfg.Env == "prod")(
+			middleware.SecurityHeaders(middleware.DefaultSecurityHeadersConfig(cfg.Env == "prod"))(
 				middleware.Logger(
 					middleware.Locale(
 						sessionManager.LoadAndSave(
-							middleware.InjectCSRF(csrfHandler),
+							web.WithAuditMetadata(sessionManager)(
+								middleware.InjectCSRF(csrfHandler),
+							),
 						),
 					),
 				),
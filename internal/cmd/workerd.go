@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/PauloHFS/goth/internal/config"
+	"github.com/PauloHFS/goth/internal/filestore"
+	"github.com/PauloHFS/goth/internal/logging"
+	"github.com/PauloHFS/goth/internal/worker"
+)
+
+// workerdAcquireTimeout dá folga ao long-poll de acquireLongPollTimeout do
+// servidor (ver internal/worker/rpc.go) para a resposta HTTP voltar antes
+// do client desistir.
+const workerdAcquireTimeout = 35 * time.Second
+
+// workerdJob e workerdAcquireResponse espelham, pelo lado do client, o
+// formato JSON que worker.JobServer expõe — não há geração de código
+// compartilhada entre os dois lados (sem dRPC/gRPC no projeto), então o
+// contrato é o JSON em si.
+type workerdJob struct {
+	ID           int64           `json:"id"`
+	Type         string          `json:"type"`
+	Payload      json.RawMessage `json:"payload"`
+	AttemptCount int64           `json:"attempt_count"`
+	TenantID     string          `json:"tenant_id,omitempty"`
+}
+
+type workerdAcquireResponse struct {
+	Job *workerdJob `json:"job,omitempty"`
+}
+
+// RunWorkerDaemon roda um worker fora do processo do servidor HTTP:
+// anuncia os tipos de job suportados, faz long-poll em
+// POST /api/worker/acquire, executa o handler localmente com
+// worker.Processor.DispatchJob (o mesmo código usado pelo worker
+// in-process) e reporta o resultado via /complete ou /fail. Permite
+// escalar process_ai/process_webhook horizontalmente sem subir o app HTTP
+// inteiro em cada réplica.
+//
+// Configuração por variáveis de ambiente: WORKERD_SERVER_URL (default
+// BASE_URL), WORKERD_SECRET (default WORKER_REMOTE_SECRET), WORKERD_ID
+// (default o hostname) e WORKERD_JOB_TYPES (lista separada por vírgula,
+// default worker.HandledJobTypes).
+func RunWorkerDaemon() {
+	cfg, err := config.Load()
+	if err != nil {
+		panic(fmt.Sprintf("failed to load config: %v", err))
+	}
+
+	logging.Init()
+	logger := logging.Get()
+
+	serverURL := strings.TrimSuffix(workerdEnv("WORKERD_SERVER_URL", cfg.BaseURL), "/")
+	secret := workerdEnv("WORKERD_SECRET", cfg.Worker.RemoteSecret)
+	if secret == "" {
+		logger.Error("workerd: nem WORKERD_SECRET nem WORKER_REMOTE_SECRET configurados")
+		os.Exit(1)
+	}
+	workerID := workerdEnv("WORKERD_ID", workerdHostname())
+
+	jobTypes := worker.HandledJobTypes
+	if raw := os.Getenv("WORKERD_JOB_TYPES"); raw != "" {
+		parts := strings.Split(raw, ",")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		jobTypes = parts
+	}
+
+	fileStore, err := filestore.New(cfg)
+	if err != nil {
+		logger.Error("workerd: failed to configure file storage backend", "error", err)
+		os.Exit(1)
+	}
+
+	// dbConn e queries ficam nil: este Processor só existe para reexecutar
+	// DispatchJob (mailer/fileStore), nunca para falar com o banco — quem
+	// decide acquire/complete/fail é o worker.JobServer do lado do servidor.
+	processor := worker.New(cfg, nil, nil, logger, fileStore, nil)
+
+	client := &http.Client{Timeout: workerdAcquireTimeout}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-done
+		logger.Info("workerd stopping")
+		cancel()
+	}()
+
+	go workerdHeartbeatLoop(ctx, client, serverURL, secret, workerID, jobTypes, logger)
+
+	logger.Info("workerd started", "worker_id", workerID, "server_url", serverURL, "job_types", jobTypes)
+
+	for ctx.Err() == nil {
+		job, ok := workerdAcquire(ctx, client, serverURL, secret, workerID, jobTypes, logger)
+		if !ok {
+			continue
+		}
+
+		if errProcessing := processor.DispatchJob(ctx, job.Type, job.Payload); errProcessing != nil {
+			workerdReportFailure(ctx, client, serverURL, secret, workerID, job.ID, errProcessing, logger)
+			continue
+		}
+		workerdReportCompletion(ctx, client, serverURL, secret, workerID, job.ID, logger)
+	}
+
+	logger.Info("workerd exited properly")
+}
+
+func workerdEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func workerdHostname() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return "workerd"
+	}
+	return host
+}
+
+// workerdPost envia uma requisição JSON autenticada pelo shared secret;
+// out, se não nil, recebe o corpo decodificado de uma resposta 200.
+func workerdPost(ctx context.Context, client *http.Client, serverURL, secret, path string, body, out any) (int, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, serverURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+secret)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if out != nil && resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, err
+		}
+	}
+
+	return resp.StatusCode, nil
+}
+
+func workerdAcquire(ctx context.Context, client *http.Client, serverURL, secret, workerID string, jobTypes []string, logger *slog.Logger) (workerdJob, bool) {
+	var resp workerdAcquireResponse
+	status, err := workerdPost(ctx, client, serverURL, secret, "/api/worker/acquire",
+		map[string]any{"worker_id": workerID, "job_types": jobTypes}, &resp)
+	if err != nil {
+		if ctx.Err() != nil {
+			return workerdJob{}, false
+		}
+		logger.Warn("workerd: acquire failed, retrying", "error", err.Error())
+		workerdSleep(ctx, time.Second)
+		return workerdJob{}, false
+	}
+
+	if status != http.StatusOK || resp.Job == nil {
+		return workerdJob{}, false
+	}
+
+	return *resp.Job, true
+}
+
+func workerdReportCompletion(ctx context.Context, client *http.Client, serverURL, secret, workerID string, jobID int64, logger *slog.Logger) {
+	if _, err := workerdPost(ctx, client, serverURL, secret, "/api/worker/complete",
+		map[string]any{"worker_id": workerID, "job_id": jobID}, nil); err != nil {
+		logger.Error("workerd: failed to report job completion", "job_id", jobID, "error", err.Error())
+	}
+}
+
+func workerdReportFailure(ctx context.Context, client *http.Client, serverURL, secret, workerID string, jobID int64, jobErr error, logger *slog.Logger) {
+	if _, err := workerdPost(ctx, client, serverURL, secret, "/api/worker/fail",
+		map[string]any{"worker_id": workerID, "job_id": jobID, "error": jobErr.Error()}, nil); err != nil {
+		logger.Error("workerd: failed to report job failure", "job_id", jobID, "error", err.Error())
+	}
+}
+
+func workerdHeartbeatLoop(ctx context.Context, client *http.Client, serverURL, secret, workerID string, jobTypes []string, logger *slog.Logger) {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := workerdPost(ctx, client, serverURL, secret, "/api/worker/heartbeat",
+				map[string]any{"worker_id": workerID, "job_types": jobTypes}, nil); err != nil {
+				logger.Warn("workerd: heartbeat failed", "error", err.Error())
+			}
+		}
+	}
+}
+
+func workerdSleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
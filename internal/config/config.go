@@ -3,37 +3,135 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/PauloHFS/goth/internal/oidc"
 	"github.com/joho/godotenv"
 )
 
+// Campos trazem tags json (chaves em snake_case) porque Config agora também
+// é serializável via ConfigHandler (ver reload.go): MarshalJSONPath usa
+// essas mesmas chaves para endereçar campos individuais, ex.
+// "vector.embedding_dimension".
 type Config struct {
-	Port          string
-	DatabaseURL   string
-	BaseURL       string
-	SMTPHost      string
-	SMTPPort      string
-	SMTPUser      string
-	SMTPPass      string
-	SMTPFrom      string
-	SessionSecret string
-	Env           string // "dev" or "prod"
-	Vector        VectorConfig
+	Port          string `json:"port"`
+	DatabaseURL   string `json:"database_url"`
+	BaseURL       string `json:"base_url"`
+	SMTPHost      string `json:"smtp_host"`
+	SMTPPort      string `json:"smtp_port"`
+	SMTPUser      string `json:"smtp_user"`
+	SMTPPass      string `json:"smtp_pass"`
+	SMTPFrom      string `json:"smtp_from"`
+	SessionSecret string `json:"session_secret"`
+	Env           string `json:"env"` // "dev" or "prod"
+
+	Vector    VectorConfig    `json:"vector"`
+	OIDC      OIDCConfig      `json:"oidc"`
+	Storage   StorageConfig   `json:"storage"`
+	MagicLink MagicLinkConfig `json:"magic_link"`
+	Worker    WorkerConfig    `json:"worker"`
+	Webhook   WebhookConfig   `json:"webhook"`
 }
 
 type VectorConfig struct {
-	Enabled            bool
-	EmbeddingDimension int
-	TableName          string
+	Enabled            bool   `json:"enabled"`
+	EmbeddingDimension int    `json:"embedding_dimension"`
+	TableName          string `json:"table_name"`
+}
+
+// OIDCConfig habilita login via um ou mais provedores OIDC externos (ver
+// internal/oidc e internal/web/oidc.go). Cada provedor é declarado via
+// OIDC_PROVIDERS (lista de nomes) e variáveis OIDC_<NOME>_* individuais;
+// RolesClaim/RoleMapping/DefaultRole são políticas compartilhadas por todos
+// os provedores habilitados.
+type OIDCConfig struct {
+	Enabled     bool              `json:"enabled"`
+	Providers   []oidc.Provider   `json:"providers"`
+	RolesClaim  string            `json:"roles_claim"`  // nome do claim usado para mapear para a tabela roles
+	RoleMapping map[string]string `json:"role_mapping"` // allowlist: role externo (claim) -> role_id interno
+	DefaultRole string            `json:"default_role"` // role_id usado quando o claim não bate com RoleMapping
+}
+
+// StorageConfig seleciona e configura o backend de armazenamento de arquivos
+// usado por internal/filestore. Backend "local" usa LocalDir; "webdav" usa
+// os demais campos WebDAV*; "s3" usa os campos S3* (hoje não implementado,
+// ver filestore.S3Backend). CASEnabled envolve o backend escolhido em um
+// filestore.CASBackend, deduplicando uploads idênticos pelo SHA-256 do
+// conteúdo.
+type StorageConfig struct {
+	Backend           string `json:"backend"` // "local", "webdav" ou "s3"
+	LocalDir          string `json:"local_dir"`
+	LocalBaseURL      string `json:"local_base_url"`
+	WebDAVBaseURL     string `json:"webdav_base_url"`
+	WebDAVUsername    string `json:"webdav_username"`
+	WebDAVPassword    string `json:"webdav_password"`
+	WebDAVBearerToken string `json:"webdav_bearer_token"`
+	S3Bucket          string `json:"s3_bucket"`
+	S3Region          string `json:"s3_region"`
+	S3Endpoint        string `json:"s3_endpoint"`
+	S3AccessKey       string `json:"s3_access_key"`
+	S3SecretKey       string `json:"s3_secret_key"`
+	CASEnabled        bool   `json:"cas_enabled"`
+}
+
+// MagicLinkConfig ajusta o comportamento do login sem senha por link
+// mágico (ver internal/magiclink e internal/web/magiclink.go). BindToOrigin,
+// quando habilitado, rejeita a verificação de um link a partir de um
+// user-agent/IP diferente do que o solicitou, na troca de conveniência
+// (usar o link de outro dispositivo, ex. abrir no celular um e-mail lido no
+// notebook) por segurança contra link roubado.
+type MagicLinkConfig struct {
+	BindToOrigin bool `json:"bind_to_origin"`
+}
+
+// WorkerConfig habilita o acesso remoto ao worker.JobServer (ver
+// internal/worker/rpc.go e cmd/workerd), usado por daemons externos que
+// processam jobs fora do processo do servidor HTTP. RemoteSecret autentica
+// as chamadas via header Authorization: Bearer <secret>; string vazia
+// desabilita o endpoint remoto (comportamento anterior, só worker in-process).
+// HostShardCount e BadHostTTLSeconds configuram o particionamento por host
+// de destino usado para jobs que fazem chamadas HTTP de saída (ver
+// worker.BadHostTracker e worker.ShardFor) — isola um host lento ou 429
+// no seu próprio shard em vez de travar a fila inteira.
+type WorkerConfig struct {
+	RemoteSecret      string `json:"remote_secret"`
+	HostShardCount    int    `json:"host_shard_count"`
+	BadHostTTLSeconds int    `json:"bad_host_ttl_seconds"`
+}
+
+// WebhookConfig declara, por fonte (path {source} de /webhooks/{source}),
+// qual webhook.Verifier autentica as requisições recebidas — ver
+// internal/webhook/verify.go. Segue o mesmo padrão de OIDCConfig.Providers:
+// uma lista de nomes em WEBHOOK_SOURCES e variáveis WEBHOOK_<NOME>_* por
+// fonte. Uma fonte sem entrada aqui não tem Verifier registrado, e o
+// Handler rejeita toda requisição para ela.
+type WebhookConfig struct {
+	Providers []WebhookProviderConfig `json:"providers"`
+}
+
+// WebhookProviderConfig configura o Verifier de uma única fonte. Provider
+// seleciona a implementação embutida ("stripe", "github" ou "hmac", o
+// padrão quando em branco); Secret autentica a assinatura; ToleranceSeconds
+// limita o desvio aceito entre o timestamp assinado e agora (só usado por
+// provedores que assinam um timestamp, como stripe); zero cai no padrão de
+// cada Verifier.
+type WebhookProviderConfig struct {
+	Source           string `json:"source"`
+	Provider         string `json:"provider"`
+	Secret           string `json:"secret"`
+	ToleranceSeconds int    `json:"tolerance_seconds"`
 }
 
 func Load() (*Config, error) {
 	_ = godotenv.Load()
 
+	baseURL := getEnv("BASE_URL", "http://localhost:8080")
+	oidcProviders := loadOIDCProviders(baseURL)
+
 	cfg := &Config{
 		Port:          getEnv("PORT", "8080"),
 		DatabaseURL:   getEnv("DATABASE_URL", "./goth.db"),
-		BaseURL:       getEnv("BASE_URL", "http://localhost:8080"),
+		BaseURL:       baseURL,
 		SMTPHost:      getEnv("SMTP_HOST", "localhost"),
 		SMTPPort:      getEnv("SMTP_PORT", "1025"),
 		SMTPUser:      os.Getenv("SMTP_USER"),
@@ -46,27 +144,131 @@ func Load() (*Config, error) {
 			EmbeddingDimension: getEnvAsInt("VECTOR_EMBEDDING_DIMENSION", 1536),
 			TableName:          getEnv("VECTOR_TABLE_NAME", "vectors"),
 		},
+		OIDC: OIDCConfig{
+			Enabled:     getEnvAsBool("OIDC_ENABLED", len(oidcProviders) > 0),
+			Providers:   oidcProviders,
+			RolesClaim:  getEnv("OIDC_ROLES_CLAIM", "roles"),
+			RoleMapping: getEnvAsMap("OIDC_ROLE_MAPPING", nil),
+			DefaultRole: getEnv("OIDC_DEFAULT_ROLE", "user"),
+		},
+		Storage: StorageConfig{
+			Backend:           getEnv("STORAGE_BACKEND", "local"),
+			LocalDir:          getEnv("STORAGE_LOCAL_DIR", "storage"),
+			LocalBaseURL:      getEnv("STORAGE_LOCAL_BASE_URL", "/storage"),
+			WebDAVBaseURL:     getEnv("STORAGE_WEBDAV_BASE_URL", ""),
+			WebDAVUsername:    os.Getenv("STORAGE_WEBDAV_USERNAME"),
+			WebDAVPassword:    os.Getenv("STORAGE_WEBDAV_PASSWORD"),
+			WebDAVBearerToken: os.Getenv("STORAGE_WEBDAV_BEARER_TOKEN"),
+			S3Bucket:          getEnv("STORAGE_S3_BUCKET", ""),
+			S3Region:          getEnv("STORAGE_S3_REGION", "us-east-1"),
+			S3Endpoint:        getEnv("STORAGE_S3_ENDPOINT", ""),
+			S3AccessKey:       os.Getenv("STORAGE_S3_ACCESS_KEY"),
+			S3SecretKey:       os.Getenv("STORAGE_S3_SECRET_KEY"),
+			CASEnabled:        getEnvAsBool("STORAGE_CAS_ENABLED", false),
+		},
+		MagicLink: MagicLinkConfig{
+			BindToOrigin: getEnvAsBool("MAGIC_LINK_BIND_TO_ORIGIN", false),
+		},
+		Worker: WorkerConfig{
+			RemoteSecret:      os.Getenv("WORKER_REMOTE_SECRET"),
+			HostShardCount:    getEnvAsInt("WORKER_HOST_SHARD_COUNT", 4),
+			BadHostTTLSeconds: getEnvAsInt("WORKER_BAD_HOST_TTL_SECONDS", 60),
+		},
+		Webhook: WebhookConfig{
+			Providers: loadWebhookProviders(),
+		},
 	}
 
-	// Validação Estrita para Produção
-	if cfg.Env == "prod" {
-		if cfg.SMTPPass == "" {
-			return nil, fmt.Errorf("produção: SMTP_PASS é obrigatório")
-		}
-		if cfg.SMTPUser == "" {
-			return nil, fmt.Errorf("produção: SMTP_USER é obrigatório")
-		}
-		if cfg.SessionSecret == "" {
-			return nil, fmt.Errorf("produção: SESSION_SECRET é obrigatório")
+	// No dev, se não houver secret, usamos um valor fraco apenas para não quebrar o boot
+	if cfg.Env != "prod" && cfg.SessionSecret == "" {
+		cfg.SessionSecret = "dev-secret-keep-it-simple-but-not-safe"
+	}
+
+	if err := validate(cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// validate aplica a validação estrita de produção, chamada tanto por Load
+// quanto por ConfigHandler ao recarregar o arquivo de configuração — um
+// reload que produzisse um Config inválido em produção fica sem efeito,
+// mantendo o Config anterior no ar em vez de derrubar o processo.
+func validate(cfg *Config) error {
+	if cfg.Env != "prod" {
+		return nil
+	}
+
+	if cfg.SMTPPass == "" {
+		return fmt.Errorf("produção: SMTP_PASS é obrigatório")
+	}
+	if cfg.SMTPUser == "" {
+		return fmt.Errorf("produção: SMTP_USER é obrigatório")
+	}
+	if cfg.SessionSecret == "" {
+		return fmt.Errorf("produção: SESSION_SECRET é obrigatório")
+	}
+
+	return nil
+}
+
+// loadOIDCProviders monta os provedores OIDC habilitados a partir de
+// OIDC_PROVIDERS (lista de nomes) e, para cada nome, variáveis
+// OIDC_<NOME>_ISSUER_URL/CLIENT_ID/CLIENT_SECRET/REDIRECT_URL/SCOPES. Quando
+// OIDC_PROVIDERS não está definida, cai para a configuração de provedor
+// único anterior (OIDC_ISSUER_URL e afins), sintetizando um único provedor
+// chamado "default" — mantém deployments existentes funcionando.
+func loadOIDCProviders(baseURL string) []oidc.Provider {
+	names := getEnvAsList("OIDC_PROVIDERS", nil)
+	if len(names) == 0 {
+		if getEnv("OIDC_ISSUER_URL", "") == "" {
+			return nil
 		}
-	} else {
-		// No dev, se não houver secret, usamos um valor fraco apenas para não quebrar o boot
-		if cfg.SessionSecret == "" {
-			cfg.SessionSecret = "dev-secret-keep-it-simple-but-not-safe"
+		return []oidc.Provider{
+			{
+				Name:         "default",
+				IssuerURL:    getEnv("OIDC_ISSUER_URL", ""),
+				ClientID:     getEnv("OIDC_CLIENT_ID", ""),
+				ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+				RedirectURL:  getEnv("OIDC_REDIRECT_URL", baseURL+"/auth/default/callback"),
+				Scopes:       getEnvAsList("OIDC_SCOPES", []string{"openid", "email", "profile"}),
+			},
 		}
 	}
 
-	return cfg, nil
+	providers := make([]oidc.Provider, 0, len(names))
+	for _, name := range names {
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		providers = append(providers, oidc.Provider{
+			Name:         name,
+			IssuerURL:    getEnv(prefix+"ISSUER_URL", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  getEnv(prefix+"REDIRECT_URL", baseURL+"/auth/"+name+"/callback"),
+			Scopes:       getEnvAsList(prefix+"SCOPES", []string{"openid", "email", "profile"}),
+		})
+	}
+	return providers
+}
+
+// loadWebhookProviders monta WebhookConfig.Providers a partir de
+// WEBHOOK_SOURCES (lista de nomes de fonte) e, para cada uma, variáveis
+// WEBHOOK_<NOME>_PROVIDER/SECRET/TOLERANCE_SECONDS — o mesmo esquema de
+// loadOIDCProviders para múltiplos provedores homônimos.
+func loadWebhookProviders() []WebhookProviderConfig {
+	sources := getEnvAsList("WEBHOOK_SOURCES", nil)
+	providers := make([]WebhookProviderConfig, 0, len(sources))
+	for _, source := range sources {
+		prefix := "WEBHOOK_" + strings.ToUpper(source) + "_"
+		providers = append(providers, WebhookProviderConfig{
+			Source:           source,
+			Provider:         getEnv(prefix+"PROVIDER", "hmac"),
+			Secret:           os.Getenv(prefix + "SECRET"),
+			ToleranceSeconds: getEnvAsInt(prefix+"TOLERANCE_SECONDS", 0),
+		})
+	}
+	return providers
 }
 
 func getEnv(key, fallback string) string {
@@ -83,6 +285,38 @@ func getEnvAsBool(key string, fallback bool) bool {
 	return fallback
 }
 
+func getEnvAsList(key string, fallback []string) []string {
+	if value, ok := os.LookupEnv(key); ok {
+		parts := strings.Split(value, ",")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		return parts
+	}
+	return fallback
+}
+
+// getEnvAsMap faz parsing de uma lista "externo:interno,externo2:interno2"
+// em um map — usado para o allowlist de OIDC_ROLE_MAPPING, que traduz roles
+// vindas do claim do IdP para role_id internos sem confiar cegamente no
+// valor do claim como chave estrangeira.
+func getEnvAsMap(key string, fallback map[string]string) map[string]string {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	result := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		k, v, found := strings.Cut(pair, ":")
+		if !found {
+			continue
+		}
+		result[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return result
+}
+
 func getEnvAsInt(key string, fallback int) int {
 	if value, ok := os.LookupEnv(key); ok {
 		var result int
@@ -0,0 +1,475 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrFingerprintMismatch é devolvido por DoLockedAction quando o Fingerprint
+// informado pelo chamador não bate mais com o Config atual — alguém (outro
+// admin, um reload de arquivo) já mudou a configuração nesse meio tempo, e a
+// edição é recusada em vez de pisar silenciosamente em cima dela.
+var ErrFingerprintMismatch = errors.New("config: fingerprint não confere, configuração mudou desde a leitura")
+
+// OnConfigChange é chamado depois de um reload (por arquivo ou por
+// DoLockedAction) que produziu um Config válido, com old sendo o Config
+// substituído e new o que passou a valer. Subsistemas como
+// middleware.RateLimiter ou sse.Broker podem usá-lo para ajustar limites em
+// tempo real sem reiniciar o processo.
+type OnConfigChange func(old, new *Config)
+
+const defaultPollInterval = 2 * time.Second
+
+// ConfigHandler mantém um *Config vivo carregado de um arquivo (JSON ou
+// YAML, pela extensão de path) e o recarrega sempre que o arquivo muda no
+// disco. Como o repositório não depende de um watcher de filesystem (ex.
+// fsnotify), a detecção é por polling do mtime do arquivo — suficiente para
+// um arquivo de configuração que muda poucas vezes por hora. Variáveis de
+// ambiente continuam tendo prioridade: cada reload aplica o arquivo por
+// cima de Load() e depois reaplica os overrides de ambiente (ver
+// applyEnvOverrides para as chaves cobertas).
+type ConfigHandler struct {
+	path         string
+	pollInterval time.Duration
+
+	mu          sync.RWMutex
+	current     *Config
+	subscribers []OnConfigChange
+
+	lastModTime time.Time
+	stopCh      chan struct{}
+	stopOnce    sync.Once
+}
+
+// NewConfigHandler cria um ConfigHandler para path, faz a carga inicial
+// (arquivo, se existir, por cima de Load(); senão só Load()) e começa a
+// vigiar o arquivo em segundo plano.
+func NewConfigHandler(path string) (*ConfigHandler, error) {
+	h := &ConfigHandler{
+		path:         path,
+		pollInterval: defaultPollInterval,
+		stopCh:       make(chan struct{}),
+	}
+
+	cfg, err := h.loadFromDisk()
+	if err != nil {
+		return nil, err
+	}
+
+	h.current = cfg
+	if info, err := os.Stat(path); err == nil {
+		h.lastModTime = info.ModTime()
+	}
+
+	go h.watch()
+
+	return h, nil
+}
+
+// Current devolve o *Config em vigor no momento da chamada.
+func (h *ConfigHandler) Current() *Config {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.current
+}
+
+// Subscribe registra cb para ser chamado depois de cada troca de Config
+// bem-sucedida.
+func (h *ConfigHandler) Subscribe(cb OnConfigChange) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subscribers = append(h.subscribers, cb)
+}
+
+// Stop encerra o polling do arquivo.
+func (h *ConfigHandler) Stop() {
+	h.stopOnce.Do(func() { close(h.stopCh) })
+}
+
+func (h *ConfigHandler) watch() {
+	ticker := time.NewTicker(h.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.stopCh:
+			return
+		case <-ticker.C:
+			info, err := os.Stat(h.path)
+			if err != nil {
+				continue
+			}
+			if !info.ModTime().After(h.lastModTime) {
+				continue
+			}
+			h.lastModTime = info.ModTime()
+			_ = h.Reload()
+		}
+	}
+}
+
+// Reload relê o arquivo de configuração, reaplica os overrides de ambiente
+// e, se o resultado passar em validate, troca o Config em vigor e notifica
+// os subscribers. Se a validação falhar, o Config anterior continua valendo
+// e o erro é devolvido ao chamador.
+func (h *ConfigHandler) Reload() error {
+	candidate, err := h.loadFromDisk()
+	if err != nil {
+		return err
+	}
+
+	return h.swap(candidate)
+}
+
+func (h *ConfigHandler) swap(candidate *Config) error {
+	if err := validate(candidate); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	old := h.current
+	h.current = candidate
+	subscribers := append([]OnConfigChange(nil), h.subscribers...)
+	h.mu.Unlock()
+
+	for _, sub := range subscribers {
+		sub(old, candidate)
+	}
+
+	return nil
+}
+
+// loadFromDisk monta um Config a partir de Load() (que já aplica as
+// variáveis de ambiente) com o arquivo em h.path sobreposto por cima quando
+// ele existe, e então reaplica os overrides de ambiente — para uma
+// variável de ambiente explicitamente setada vencer o que está no arquivo
+// (nas chaves cobertas por applyEnvOverrides).
+func (h *ConfigHandler) loadFromDisk() (*Config, error) {
+	cfg, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(h.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config: falha ao ler %s: %w", h.path, err)
+	}
+
+	if err := unmarshalByExtension(h.path, data, cfg); err != nil {
+		return nil, fmt.Errorf("config: falha ao decodificar %s: %w", h.path, err)
+	}
+
+	applyEnvOverrides(cfg)
+
+	return cfg, nil
+}
+
+// applyEnvOverrides sobrescreve em cfg só os campos cuja variável de
+// ambiente correspondente está explicitamente setada, para o arquivo não
+// apagar um override de ambiente — espelha as chaves de Load() listadas
+// abaixo, mas sem os valores padrão (que o arquivo já forneceu). OIDC_*,
+// WORKER_HOST_SHARD_COUNT, WORKER_BAD_HOST_TTL_SECONDS e WEBHOOK_* não são
+// reaplicadas: nesses campos o valor do arquivo vence o do ambiente.
+func applyEnvOverrides(cfg *Config) {
+	setIfPresent := func(key string, dst *string) {
+		if v, ok := os.LookupEnv(key); ok {
+			*dst = v
+		}
+	}
+
+	setIfPresent("PORT", &cfg.Port)
+	setIfPresent("DATABASE_URL", &cfg.DatabaseURL)
+	setIfPresent("BASE_URL", &cfg.BaseURL)
+	setIfPresent("SMTP_HOST", &cfg.SMTPHost)
+	setIfPresent("SMTP_PORT", &cfg.SMTPPort)
+	setIfPresent("SMTP_USER", &cfg.SMTPUser)
+	setIfPresent("SMTP_PASS", &cfg.SMTPPass)
+	setIfPresent("SMTP_FROM", &cfg.SMTPFrom)
+	setIfPresent("SESSION_SECRET", &cfg.SessionSecret)
+	setIfPresent("APP_ENV", &cfg.Env)
+
+	if v, ok := os.LookupEnv("VECTOR_ENABLED"); ok {
+		cfg.Vector.Enabled = v == "true" || v == "1" || v == "yes"
+	}
+	if v, ok := os.LookupEnv("VECTOR_EMBEDDING_DIMENSION"); ok {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.Vector.EmbeddingDimension = n
+		}
+	}
+	setIfPresent("VECTOR_TABLE_NAME", &cfg.Vector.TableName)
+
+	setIfPresent("STORAGE_BACKEND", &cfg.Storage.Backend)
+	setIfPresent("STORAGE_LOCAL_DIR", &cfg.Storage.LocalDir)
+	setIfPresent("STORAGE_LOCAL_BASE_URL", &cfg.Storage.LocalBaseURL)
+	setIfPresent("STORAGE_WEBDAV_BASE_URL", &cfg.Storage.WebDAVBaseURL)
+	setIfPresent("STORAGE_WEBDAV_USERNAME", &cfg.Storage.WebDAVUsername)
+	setIfPresent("STORAGE_WEBDAV_PASSWORD", &cfg.Storage.WebDAVPassword)
+	setIfPresent("STORAGE_WEBDAV_BEARER_TOKEN", &cfg.Storage.WebDAVBearerToken)
+	setIfPresent("STORAGE_S3_BUCKET", &cfg.Storage.S3Bucket)
+	setIfPresent("STORAGE_S3_REGION", &cfg.Storage.S3Region)
+	setIfPresent("STORAGE_S3_ENDPOINT", &cfg.Storage.S3Endpoint)
+	setIfPresent("STORAGE_S3_ACCESS_KEY", &cfg.Storage.S3AccessKey)
+	setIfPresent("STORAGE_S3_SECRET_KEY", &cfg.Storage.S3SecretKey)
+	if v, ok := os.LookupEnv("STORAGE_CAS_ENABLED"); ok {
+		cfg.Storage.CASEnabled = v == "true" || v == "1" || v == "yes"
+	}
+
+	if v, ok := os.LookupEnv("MAGIC_LINK_BIND_TO_ORIGIN"); ok {
+		cfg.MagicLink.BindToOrigin = v == "true" || v == "1" || v == "yes"
+	}
+
+	setIfPresent("WORKER_REMOTE_SECRET", &cfg.Worker.RemoteSecret)
+}
+
+// unmarshalByExtension decodifica data em cfg conforme a extensão de path:
+// ".yaml"/".yml" usa o subconjunto de YAML deste pacote (ver yaml.go); tudo
+// o mais (".json" incluso) usa encoding/json.
+func unmarshalByExtension(path string, data []byte, cfg *Config) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return unmarshalYAML(data, cfg)
+	default:
+		return json.Unmarshal(data, cfg)
+	}
+}
+
+// marshalByExtension é o inverso de unmarshalByExtension, usado por
+// WriteFile e pelos testes que fazem round-trip.
+func marshalByExtension(path string, cfg *Config) ([]byte, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return marshalYAML(cfg)
+	default:
+		return json.MarshalIndent(cfg, "", "  ")
+	}
+}
+
+// WriteFile serializa cfg (JSON ou YAML, pela extensão de h.path) e grava em
+// h.path, atualizando lastModTime para o watcher não se auto-disparar no
+// próximo poll.
+func (h *ConfigHandler) WriteFile(cfg *Config) error {
+	data, err := marshalByExtension(h.path, cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(h.path, data, 0o600); err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(h.path); err == nil {
+		h.lastModTime = info.ModTime()
+	}
+
+	return nil
+}
+
+// Fingerprint devolve um hash estável (sha256, hex) do Config em vigor, a
+// ser lido junto de Current() e apresentado depois a DoLockedAction para
+// detectar edições concorrentes.
+func (h *ConfigHandler) Fingerprint() string {
+	h.mu.RLock()
+	cfg := h.current
+	h.mu.RUnlock()
+	return fingerprintOf(cfg)
+}
+
+func fingerprintOf(cfg *Config) string {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// DoLockedAction aplica cb a uma cópia do Config em vigor e troca para o
+// resultado, mas só se fingerprint ainda bater com o Fingerprint atual —
+// caso contrário devolve ErrFingerprintMismatch sem chamar cb, prevenindo
+// que uma edição concorrente (outro admin, um reload de arquivo) seja
+// perdida. Se o Config em vigor mudar enquanto cb roda, o resultado é
+// descartado com o mesmo ErrFingerprintMismatch. Assim como em Reload, um
+// resultado que falhe validate não é aplicado e o Config anterior continua
+// valendo.
+func (h *ConfigHandler) DoLockedAction(fingerprint string, cb func(*Config) error) error {
+	h.mu.Lock()
+	if fingerprintOf(h.current) != fingerprint {
+		h.mu.Unlock()
+		return ErrFingerprintMismatch
+	}
+	base := h.current
+	h.mu.Unlock()
+
+	candidate, err := cloneConfig(base)
+	if err != nil {
+		return err
+	}
+
+	if err := cb(candidate); err != nil {
+		return err
+	}
+	if err := validate(candidate); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	if h.current != base {
+		h.mu.Unlock()
+		return ErrFingerprintMismatch
+	}
+	h.current = candidate
+	subscribers := append([]OnConfigChange(nil), h.subscribers...)
+	h.mu.Unlock()
+
+	for _, sub := range subscribers {
+		sub(base, candidate)
+	}
+	return nil
+}
+
+func cloneConfig(cfg *Config) (*Config, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	clone := &Config{}
+	if err := json.Unmarshal(data, clone); err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
+
+// MarshalJSONPath devolve só o valor no path dotted de Config (ex.
+// "vector.embedding_dimension"), navegando a árvore produzida por um
+// round-trip via encoding/json com as tags json de Config.
+func (h *ConfigHandler) MarshalJSONPath(path string) ([]byte, error) {
+	h.mu.RLock()
+	cfg := h.current
+	h.mu.RUnlock()
+
+	tree, err := toTree(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := lookupPath(tree, path)
+	if !ok {
+		return nil, fmt.Errorf("config: path %q não encontrado", path)
+	}
+
+	return json.Marshal(value)
+}
+
+// UnmarshalJSONPath decodifica data e grava o resultado no path dotted do
+// Config em vigor (ex. UnmarshalJSONPath("vector.embedding_dimension",
+// []byte("2048"))), validando o resultado antes de trocar — path inválido
+// ou validação falha deixam o Config anterior intacto.
+func (h *ConfigHandler) UnmarshalJSONPath(path string, data []byte) error {
+	h.mu.RLock()
+	base := h.current
+	h.mu.RUnlock()
+
+	tree, err := toTree(base)
+	if err != nil {
+		return err
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	if !setPath(tree, path, value) {
+		return fmt.Errorf("config: path %q não encontrado", path)
+	}
+
+	merged, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+
+	candidate := &Config{}
+	if err := json.Unmarshal(merged, candidate); err != nil {
+		return err
+	}
+
+	return h.swap(candidate)
+}
+
+// toTree faz o round-trip cfg -> JSON -> map[string]interface{}, a
+// representação usada internamente para navegação por path dotted.
+func toTree(cfg *Config) (map[string]interface{}, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	tree := map[string]interface{}{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// lookupPath navega tree por path ("a.b.c"), indexando em
+// map[string]interface{} a cada segmento.
+func lookupPath(tree map[string]interface{}, path string) (interface{}, bool) {
+	segments := strings.Split(path, ".")
+	var cur interface{} = tree
+
+	for _, seg := range segments {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		cur, ok = m[seg]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return cur, true
+}
+
+// setPath navega tree por path e grava value no último segmento — mas só se
+// todo segmento do caminho já existe e cada intermediário é um objeto (não
+// cria chaves novas nem sobrescreve um escalar por engano); caso contrário
+// devolve false.
+func setPath(tree map[string]interface{}, path string, value interface{}) bool {
+	segments := strings.Split(path, ".")
+	cur := tree
+
+	for i, seg := range segments {
+		last := i == len(segments)-1
+		if last {
+			if _, exists := cur[seg]; !exists {
+				return false
+			}
+			cur[seg] = value
+			return true
+		}
+
+		next, ok := cur[seg]
+		if !ok {
+			return false
+		}
+		m, ok := next.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		cur = m
+	}
+
+	return false
+}
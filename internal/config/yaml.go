@@ -0,0 +1,339 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Este arquivo implementa um subconjunto mínimo de YAML (mapeamentos e
+// listas em bloco, indentados por 2 espaços, escalares simples) suficiente
+// para serializar/desserializar Config — não um parser YAML geral (sem
+// flow-style "{}"/"[]" inline com conteúdo, âncoras, tags ou escalares
+// multi-linha). Como nenhuma biblioteca de YAML está entre as dependências
+// do projeto, o formato é o bastante para round-tripar o que marshalYAML
+// produz, o mesmo raciocínio usado para o client RESP hand-rolled de
+// middleware.RedisStore.
+
+// marshalYAML serializa cfg no subconjunto de YAML deste pacote, passando
+// pela mesma árvore genérica (map[string]interface{}) usada por
+// ConfigHandler.MarshalJSONPath.
+func marshalYAML(cfg *Config) ([]byte, error) {
+	tree, err := toTree(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writeYAMLMapBody(&buf, tree, 0, "")
+	return buf.Bytes(), nil
+}
+
+// unmarshalYAML decodifica data (no subconjunto de YAML deste pacote) em
+// cfg, passando pela mesma árvore genérica usada por encoding/json.
+func unmarshalYAML(data []byte, cfg *Config) error {
+	lines := tokenizeYAML(data)
+	idx := 0
+
+	value, err := parseYAMLBlock(lines, &idx, 0)
+	if err != nil {
+		return err
+	}
+
+	tree, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("config: yaml raiz precisa ser um mapeamento")
+	}
+
+	merged, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(merged, cfg)
+}
+
+func indentYAML(n int) string {
+	return strings.Repeat("  ", n)
+}
+
+func writeYAMLMapBody(buf *bytes.Buffer, m map[string]interface{}, indent int, firstPrefix string) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for i, k := range keys {
+		prefix := indentYAML(indent)
+		if i == 0 && firstPrefix != "" {
+			prefix = firstPrefix
+		}
+
+		switch cv := m[k].(type) {
+		case map[string]interface{}:
+			if len(cv) == 0 {
+				buf.WriteString(prefix + k + ": {}\n")
+			} else {
+				buf.WriteString(prefix + k + ":\n")
+				writeYAMLMapBody(buf, cv, indent+1, "")
+			}
+		case []interface{}:
+			if len(cv) == 0 {
+				buf.WriteString(prefix + k + ": []\n")
+			} else {
+				buf.WriteString(prefix + k + ":\n")
+				writeYAMLList(buf, cv, indent+1)
+			}
+		default:
+			buf.WriteString(prefix + k + ": " + scalarToYAML(cv) + "\n")
+		}
+	}
+}
+
+func writeYAMLList(buf *bytes.Buffer, items []interface{}, indent int) {
+	for _, item := range items {
+		switch iv := item.(type) {
+		case map[string]interface{}:
+			writeYAMLMapBody(buf, iv, indent+1, indentYAML(indent)+"- ")
+		case []interface{}:
+			buf.WriteString(indentYAML(indent) + "-\n")
+			writeYAMLList(buf, iv, indent+1)
+		default:
+			buf.WriteString(indentYAML(indent) + "- " + scalarToYAML(iv) + "\n")
+		}
+	}
+}
+
+var yamlReservedWords = map[string]bool{
+	"true": true, "false": true, "null": true, "~": true, "": true,
+}
+
+func scalarToYAML(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(val)
+	case string:
+		if yamlNeedsQuote(val) {
+			return strconv.Quote(val)
+		}
+		return val
+	case float64:
+		if val == float64(int64(val)) {
+			return strconv.FormatInt(int64(val), 10)
+		}
+		return strconv.FormatFloat(val, 'g', -1, 64)
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+func yamlNeedsQuote(s string) bool {
+	if yamlReservedWords[s] {
+		return true
+	}
+	if strings.ContainsAny(s, ":#{}[]&*!|>'\"%@`\n") {
+		return true
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return true
+	}
+	return false
+}
+
+type yamlLine struct {
+	indent  int
+	content string
+}
+
+func tokenizeYAML(data []byte) []yamlLine {
+	var out []yamlLine
+	for _, raw := range strings.Split(string(data), "\n") {
+		trimmedRight := strings.TrimRight(raw, " \t\r")
+		trimmed := strings.TrimLeft(trimmedRight, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		out = append(out, yamlLine{indent: len(trimmedRight) - len(trimmed), content: trimmed})
+	}
+	return out
+}
+
+func splitYAMLKeyValue(s string) (key, rest string, ok bool) {
+	idx := strings.Index(s, ":")
+	if idx == -1 {
+		return "", "", false
+	}
+	key = strings.TrimSpace(s[:idx])
+	rest = strings.TrimSpace(s[idx+1:])
+	if key == "" {
+		return "", "", false
+	}
+	return key, rest, true
+}
+
+func parseYAMLScalar(s string) interface{} {
+	switch s {
+	case "null", "~", "":
+		return nil
+	case "true":
+		return true
+	case "false":
+		return false
+	case "{}":
+		return map[string]interface{}{}
+	case "[]":
+		return []interface{}{}
+	}
+
+	if len(s) >= 2 && strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`) {
+		if unquoted, err := strconv.Unquote(s); err == nil {
+			return unquoted
+		}
+	}
+
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+
+	return s
+}
+
+func parseYAMLBlock(lines []yamlLine, idx *int, indent int) (interface{}, error) {
+	if *idx >= len(lines) || lines[*idx].indent < indent {
+		return map[string]interface{}{}, nil
+	}
+
+	if strings.HasPrefix(lines[*idx].content, "-") {
+		return parseYAMLList(lines, idx, lines[*idx].indent)
+	}
+
+	return parseYAMLMap(lines, idx, lines[*idx].indent)
+}
+
+func parseYAMLMap(lines []yamlLine, idx *int, indent int) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+
+	for *idx < len(lines) {
+		line := lines[*idx]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("config: yaml indentação inesperada: %q", line.content)
+		}
+
+		key, rest, ok := splitYAMLKeyValue(line.content)
+		if !ok {
+			return nil, fmt.Errorf("config: yaml linha inválida: %q", line.content)
+		}
+		*idx++
+
+		if rest != "" {
+			result[key] = parseYAMLScalar(rest)
+			continue
+		}
+
+		if *idx < len(lines) && lines[*idx].indent > indent {
+			child, err := parseYAMLBlock(lines, idx, lines[*idx].indent)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = child
+		} else {
+			result[key] = nil
+		}
+	}
+
+	return result, nil
+}
+
+func parseYAMLList(lines []yamlLine, idx *int, indent int) ([]interface{}, error) {
+	result := []interface{}{}
+
+	for *idx < len(lines) {
+		line := lines[*idx]
+		if line.indent != indent || !strings.HasPrefix(line.content, "-") {
+			break
+		}
+
+		item := strings.TrimPrefix(strings.TrimPrefix(line.content, "-"), " ")
+
+		if item == "" {
+			*idx++
+			if *idx < len(lines) && lines[*idx].indent > indent {
+				child, err := parseYAMLBlock(lines, idx, lines[*idx].indent)
+				if err != nil {
+					return nil, err
+				}
+				result = append(result, child)
+			} else {
+				result = append(result, nil)
+			}
+			continue
+		}
+
+		if key, rest, ok := splitYAMLKeyValue(item); ok {
+			m, err := parseYAMLListMapItem(lines, idx, indent, key, rest)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, m)
+			continue
+		}
+
+		*idx++
+		result = append(result, parseYAMLScalar(item))
+	}
+
+	return result, nil
+}
+
+// parseYAMLListMapItem lida com "- chave: valor" (o primeiro campo de um
+// mapa dentro de uma lista): os campos seguintes do mesmo mapa vêm em
+// linhas próprias, indentadas duas colunas além do "-".
+func parseYAMLListMapItem(lines []yamlLine, idx *int, listIndent int, firstKey, firstRest string) (map[string]interface{}, error) {
+	m := map[string]interface{}{}
+	itemIndent := listIndent + 2
+	*idx++
+
+	if firstRest != "" {
+		m[firstKey] = parseYAMLScalar(firstRest)
+	} else if *idx < len(lines) && lines[*idx].indent > listIndent {
+		child, err := parseYAMLBlock(lines, idx, lines[*idx].indent)
+		if err != nil {
+			return nil, err
+		}
+		m[firstKey] = child
+	}
+
+	for *idx < len(lines) && lines[*idx].indent == itemIndent {
+		key, rest, ok := splitYAMLKeyValue(lines[*idx].content)
+		if !ok {
+			break
+		}
+		*idx++
+
+		if rest != "" {
+			m[key] = parseYAMLScalar(rest)
+			continue
+		}
+
+		if *idx < len(lines) && lines[*idx].indent > itemIndent {
+			child, err := parseYAMLBlock(lines, idx, lines[*idx].indent)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = child
+		} else {
+			m[key] = nil
+		}
+	}
+
+	return m, nil
+}
@@ -2,39 +2,355 @@ package db
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
+	"regexp"
 	"sort"
-	"strings"
+	"strconv"
 
 	"github.com/PauloHFS/goth/migrations"
 )
 
-// RunMigrations executa todos os arquivos .sql do FS embutido em ordem alfabética.
-func RunMigrations(ctx context.Context, db *sql.DB) error {
+// createSchemaMigrationsTable registra quais versões já rodaram, com o
+// checksum do .up.sql executado — conferido em toda chamada de MigrateUp
+// para que um arquivo já aplicado, se editado depois, falhe alto em vez de
+// divergir silenciosamente do schema real.
+const createSchemaMigrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	checksum TEXT NOT NULL,
+	applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+// migrationFilePattern reconhece arquivos "NNNN_nome.up.sql" /
+// "NNNN_nome.down.sql" no FS embutido migrations.FS — NNNN é a version
+// registrada em schema_migrations, nome só documenta a intenção da
+// migração.
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+type migrationFile struct {
+	version  int
+	name     string
+	upFile   string
+	downFile string
+	upSQL    string
+	downSQL  string
+	checksum string
+}
+
+// loadMigrationFiles lê migrations.FS e monta, por version, o par up/down.
+// down é opcional (nem toda migração é reversível); up é obrigatório, e um
+// .down.sql órfão (sem .up.sql correspondente) é um erro de configuração,
+// não apenas ignorado.
+func loadMigrationFiles() ([]migrationFile, error) {
 	entries, err := migrations.FS.ReadDir(".")
 	if err != nil {
-		return fmt.Errorf("falha ao ler diretório de migrações: %w", err)
+		return nil, fmt.Errorf("falha ao ler diretório de migrações: %w", err)
 	}
 
-	var filenames []string
+	byVersion := make(map[int]*migrationFile)
 	for _, e := range entries {
-		if !e.IsDir() && strings.HasSuffix(e.Name(), ".sql") {
-			filenames = append(filenames, e.Name())
+		if e.IsDir() {
+			continue
+		}
+
+		m := migrationFilePattern.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
 		}
-	}
-	sort.Strings(filenames)
 
-	for _, name := range filenames {
-		content, err := migrations.FS.ReadFile(name)
+		version, err := strconv.Atoi(m[1])
 		if err != nil {
-			return fmt.Errorf("falha ao ler arquivo %s: %w", name, err)
+			return nil, fmt.Errorf("versão inválida em %s: %w", e.Name(), err)
 		}
 
-		if _, err := db.ExecContext(ctx, string(content)); err != nil {
-			return fmt.Errorf("falha ao executar migração %s: %w", name, err)
+		content, err := migrations.FS.ReadFile(e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("falha ao ler arquivo %s: %w", e.Name(), err)
+		}
+
+		mf, ok := byVersion[version]
+		if !ok {
+			mf = &migrationFile{version: version, name: m[2]}
+			byVersion[version] = mf
 		}
+
+		switch m[3] {
+		case "up":
+			mf.upFile = e.Name()
+			mf.upSQL = string(content)
+			sum := sha256.Sum256(content)
+			mf.checksum = hex.EncodeToString(sum[:])
+		case "down":
+			mf.downFile = e.Name()
+			mf.downSQL = string(content)
+		}
+	}
+
+	files := make([]migrationFile, 0, len(byVersion))
+	for _, mf := range byVersion {
+		if mf.upFile == "" {
+			return nil, fmt.Errorf("migração %04d (%s) não tem arquivo .up.sql", mf.version, mf.name)
+		}
+		files = append(files, *mf)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].version < files[j].version })
+	return files, nil
+}
+
+type appliedMigration struct {
+	name     string
+	checksum string
+}
+
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int]appliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, checksum FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("falha ao listar migrações aplicadas: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[int]appliedMigration)
+	for rows.Next() {
+		var version int
+		var rec appliedMigration
+		if err := rows.Scan(&version, &rec.name, &rec.checksum); err != nil {
+			return nil, err
+		}
+		result[version] = rec
+	}
+	return result, rows.Err()
+}
+
+// withMigrationLock pega uma *sql.Conn dedicada do pool e abre nela um
+// BEGIN IMMEDIATE antes de chamar fn — a forma do SQLite de travar a
+// escrita exclusivamente, equivalente a um advisory lock em Postgres, para
+// que dois processos subindo ao mesmo tempo não apliquem a mesma versão em
+// paralelo. database/sql.Tx não expõe o modo "immediate" diretamente, por
+// isso o BEGIN/COMMIT/ROLLBACK são statements soltos na mesma conexão em
+// vez de um *sql.Tx.
+func withMigrationLock(ctx context.Context, dbConn *sql.DB, fn func(ctx context.Context, conn *sql.Conn) error) error {
+	conn, err := dbConn.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("falha ao obter conexão para lock de migração: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return fmt.Errorf("falha ao adquirir lock de migração: %w", err)
+	}
+
+	if err := fn(ctx, conn); err != nil {
+		_, _ = conn.ExecContext(ctx, "ROLLBACK")
+		return err
 	}
 
+	if _, err := conn.ExecContext(ctx, "COMMIT"); err != nil {
+		return fmt.Errorf("falha ao commitar lock de migração: %w", err)
+	}
 	return nil
 }
+
+// RunMigrations aplica todas as migrações pendentes — mantém o nome e a
+// assinatura de antes (chamado por cmd.RunServer e cmd.RunSeed) como um
+// atalho para MigrateUp(ctx, dbConn, 0).
+func RunMigrations(ctx context.Context, dbConn *sql.DB) error {
+	return MigrateUp(ctx, dbConn, 0)
+}
+
+// MigrateUp aplica, em ordem de version, toda migração pendente até target
+// (inclusive) — target 0 aplica todas. Antes de aplicar qualquer coisa,
+// confere o checksum de cada versão já registrada em schema_migrations
+// contra o arquivo .up.sql correspondente: uma divergência aborta sem
+// tocar no banco, em vez de assumir silenciosamente que o arquivo mudou por
+// engano inofensivo.
+func MigrateUp(ctx context.Context, dbConn *sql.DB, target int) error {
+	files, err := loadMigrationFiles()
+	if err != nil {
+		return err
+	}
+
+	return withMigrationLock(ctx, dbConn, func(ctx context.Context, conn *sql.Conn) error {
+		if _, err := conn.ExecContext(ctx, createSchemaMigrationsTable); err != nil {
+			return fmt.Errorf("falha ao criar schema_migrations: %w", err)
+		}
+
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mf := range files {
+			rec, ok := applied[mf.version]
+			if ok && rec.checksum != mf.checksum {
+				return fmt.Errorf("migração %04d_%s foi alterada depois de aplicada (checksum não confere)", mf.version, mf.name)
+			}
+		}
+
+		for _, mf := range files {
+			if target > 0 && mf.version > target {
+				break
+			}
+			if _, ok := applied[mf.version]; ok {
+				continue
+			}
+
+			if _, err := conn.ExecContext(ctx, mf.upSQL); err != nil {
+				return fmt.Errorf("falha ao executar migração %04d_%s: %w", mf.version, mf.name, err)
+			}
+			if _, err := conn.ExecContext(ctx,
+				"INSERT INTO schema_migrations (version, name, checksum) VALUES (?, ?, ?)",
+				mf.version, mf.name, mf.checksum,
+			); err != nil {
+				return fmt.Errorf("falha ao registrar migração %04d_%s: %w", mf.version, mf.name, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// MigrateDown desfaz as n últimas migrações aplicadas, em ordem
+// decrescente de version, cada uma executando seu .down.sql e removendo a
+// linha de schema_migrations. Falha sem aplicar nada se alguma das n não
+// tiver arquivo .down.sql, já que não dá para reverter parcialmente.
+func MigrateDown(ctx context.Context, dbConn *sql.DB, n int) error {
+	if n <= 0 {
+		return fmt.Errorf("número de migrações a desfazer deve ser positivo, recebeu %d", n)
+	}
+
+	files, err := loadMigrationFiles()
+	if err != nil {
+		return err
+	}
+	byVersion := make(map[int]migrationFile, len(files))
+	for _, mf := range files {
+		byVersion[mf.version] = mf
+	}
+
+	return withMigrationLock(ctx, dbConn, func(ctx context.Context, conn *sql.Conn) error {
+		if _, err := conn.ExecContext(ctx, createSchemaMigrationsTable); err != nil {
+			return fmt.Errorf("falha ao criar schema_migrations: %w", err)
+		}
+
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		versions := make([]int, 0, len(applied))
+		for v := range applied {
+			versions = append(versions, v)
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+		if len(versions) < n {
+			return fmt.Errorf("só há %d migrações aplicadas, não é possível desfazer %d", len(versions), n)
+		}
+
+		for _, version := range versions[:n] {
+			mf, ok := byVersion[version]
+			if !ok || mf.downFile == "" {
+				return fmt.Errorf("migração %04d não tem arquivo .down.sql", version)
+			}
+
+			if _, err := conn.ExecContext(ctx, mf.downSQL); err != nil {
+				return fmt.Errorf("falha ao desfazer migração %04d_%s: %w", version, mf.name, err)
+			}
+			if _, err := conn.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = ?", version); err != nil {
+				return fmt.Errorf("falha ao remover registro da migração %04d_%s: %w", version, mf.name, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// MigrationStatusEntry descreve uma version conhecida pelo FS embutido e se
+// já foi aplicada — usado por "goth migrate status".
+type MigrationStatusEntry struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt string
+}
+
+// MigrationStatus devolve, para cada migração embutida, se ela já foi
+// aplicada e quando.
+func MigrationStatus(ctx context.Context, dbConn *sql.DB) ([]MigrationStatusEntry, error) {
+	files, err := loadMigrationFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := dbConn.ExecContext(ctx, createSchemaMigrationsTable); err != nil {
+		return nil, fmt.Errorf("falha ao criar schema_migrations: %w", err)
+	}
+
+	rows, err := dbConn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("falha ao listar migrações aplicadas: %w", err)
+	}
+	defer rows.Close()
+
+	appliedAt := make(map[int]string)
+	for rows.Next() {
+		var version int
+		var at string
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		appliedAt[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	entries := make([]MigrationStatusEntry, 0, len(files))
+	for _, mf := range files {
+		at, ok := appliedAt[mf.version]
+		entries = append(entries, MigrationStatusEntry{Version: mf.version, Name: mf.name, Applied: ok, AppliedAt: at})
+	}
+	return entries, nil
+}
+
+// ForceVersion marca version como aplicada, gravando (ou atualizando) sua
+// linha em schema_migrations sem executar o .up.sql — usado por "goth
+// migrate force VERSION" para resincronizar o registro depois de uma
+// migração que rodou (ou foi corrigida) manualmente fora do migrator, sem
+// reexecutar SQL que talvez já tenha rodado parcialmente.
+func ForceVersion(ctx context.Context, dbConn *sql.DB, version int) error {
+	files, err := loadMigrationFiles()
+	if err != nil {
+		return err
+	}
+
+	var target *migrationFile
+	for i := range files {
+		if files[i].version == version {
+			target = &files[i]
+			break
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("versão %d não encontrada nas migrações embutidas", version)
+	}
+
+	return withMigrationLock(ctx, dbConn, func(ctx context.Context, conn *sql.Conn) error {
+		if _, err := conn.ExecContext(ctx, createSchemaMigrationsTable); err != nil {
+			return fmt.Errorf("falha ao criar schema_migrations: %w", err)
+		}
+
+		if _, err := conn.ExecContext(ctx,
+			`INSERT INTO schema_migrations (version, name, checksum) VALUES (?, ?, ?)
+			 ON CONFLICT(version) DO UPDATE SET checksum = excluded.checksum`,
+			target.version, target.name, target.checksum,
+		); err != nil {
+			return fmt.Errorf("falha ao forçar versão %d: %w", version, err)
+		}
+		return nil
+	})
+}
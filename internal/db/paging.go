@@ -1,5 +1,11 @@
 package db
 
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
 // PagingParams define os parâmetros básicos de entrada
 type PagingParams struct {
 	Page    int
@@ -34,3 +40,103 @@ func (p PagedResult[T]) TotalPages() int {
 	}
 	return (p.TotalItems + p.PerPage - 1) / p.PerPage
 }
+
+// CursorParams é a alternativa a PagingParams para listagens grandes e
+// append-heavy (fila de jobs, dead letter queue, eventos de webhook) onde
+// OFFSET degrada conforme a tabela cresce: custo O(offset + limit) em vez de
+// O(limit). PagingParams continua sendo o jeito certo para as listagens de
+// admin/UI já existentes (ver internal/web/handlers.go e
+// audit.ListAudits), que precisam de número de página e não pagam esse
+// custo em volume suficiente para importar.
+//
+// Cursor é opaco para quem chama — sempre o valor devolvido em
+// PagedCursor.NextCursor/PrevCursor, nunca montado à mão. Backward indica
+// que Cursor veio de PrevCursor: a busca percorre a tabela na direção
+// oposta e o resultado é revertido antes de devolver, para que Items
+// continue na ordem de exibição normal.
+type CursorParams struct {
+	Cursor   string
+	PerPage  int
+	Backward bool
+}
+
+func (p CursorParams) Limit() int {
+	if p.PerPage < 1 {
+		return 10
+	}
+	return p.PerPage
+}
+
+// cursorPosition é o conteúdo decodificado de um cursor: o valor da coluna
+// de ordenação no último item visto e seu id, usado como tie-breaker
+// quando a coluna de ordenação tem empates (ex.: moved_at truncado).
+type cursorPosition struct {
+	SortValue string `json:"v"`
+	ID        int64  `json:"id"`
+}
+
+// EncodeCursor codifica sortValue/id em um cursor opaco em base64, o valor
+// devolvido em PagedCursor.NextCursor/PrevCursor.
+func EncodeCursor(sortValue string, id int64) string {
+	raw, _ := json.Marshal(cursorPosition{SortValue: sortValue, ID: id})
+	return base64.URLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor decifra um cursor produzido por EncodeCursor. cursor == ""
+// devolve ok=false sem erro — é a primeira página, sem posição anterior.
+func DecodeCursor(cursor string) (sortValue string, id int64, ok bool) {
+	if cursor == "" {
+		return "", 0, false
+	}
+	raw, err := base64.URLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", 0, false
+	}
+	var pos cursorPosition
+	if err := json.Unmarshal(raw, &pos); err != nil {
+		return "", 0, false
+	}
+	return pos.SortValue, pos.ID, true
+}
+
+// KeysetWhere monta a cláusula WHERE (sem a palavra "WHERE") de uma página
+// de keyset pagination sobre (sortCol, id), na direção desc (mais recente
+// primeiro) ou asc. Devolve clause == "" quando cursor está vazio — primeira
+// página, sem filtro. args deve ser passado para a query na mesma ordem dos
+// "?" da clause.
+func KeysetWhere(cursor string, sortCol string, desc bool) (clause string, args []any) {
+	sortValue, id, ok := DecodeCursor(cursor)
+	if !ok {
+		return "", nil
+	}
+
+	op := "<"
+	if !desc {
+		op = ">"
+	}
+	clause = fmt.Sprintf("(%s, id) %s (?, ?)", sortCol, op)
+	return clause, []any{sortValue, id}
+}
+
+// KeysetOrderBy devolve a cláusula ORDER BY (sem "ORDER BY") correspondente
+// a sortCol/desc, com id como tie-breaker — sempre a mesma direção usada na
+// chamada equivalente a KeysetWhere.
+func KeysetOrderBy(sortCol string, desc bool) string {
+	dir := "DESC"
+	if !desc {
+		dir = "ASC"
+	}
+	return fmt.Sprintf("%s %s, id %s", sortCol, dir, dir)
+}
+
+// PagedCursor é o equivalente de PagedResult para paginação por keyset.
+// NextCursor/PrevCursor são nil quando não há mais página naquela direção.
+// TotalItems só é preenchido quando o chamador pede explicitamente (ver os
+// parâmetros includeTotal dos métodos List*) — contar linhas é exatamente o
+// custo que keyset pagination existe para evitar no caminho comum.
+type PagedCursor[T any] struct {
+	Items      []T
+	NextCursor *string
+	PrevCursor *string
+	TotalItems *int
+}
@@ -0,0 +1,60 @@
+package db
+
+import "testing"
+
+func TestEncodeDecodeCursor(t *testing.T) {
+	cursor := EncodeCursor("2026-07-26T10:00:00Z", 42)
+
+	sortValue, id, ok := DecodeCursor(cursor)
+	if !ok {
+		t.Fatal("esperava decodificar o cursor com sucesso")
+	}
+	if sortValue != "2026-07-26T10:00:00Z" || id != 42 {
+		t.Fatalf("esperava sortValue=2026-07-26T10:00:00Z id=42, obteve sortValue=%s id=%d", sortValue, id)
+	}
+}
+
+func TestDecodeCursor_Empty(t *testing.T) {
+	if _, _, ok := DecodeCursor(""); ok {
+		t.Fatal("esperava ok=false para cursor vazio (primeira página)")
+	}
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	if _, _, ok := DecodeCursor("not-valid-base64!!"); ok {
+		t.Fatal("esperava ok=false para cursor inválido")
+	}
+}
+
+func TestKeysetWhere_EmptyCursorHasNoClause(t *testing.T) {
+	clause, args := KeysetWhere("", "moved_at", true)
+	if clause != "" || args != nil {
+		t.Fatalf("esperava clause e args vazios para cursor vazio, obteve clause=%q args=%v", clause, args)
+	}
+}
+
+func TestKeysetWhere_Directions(t *testing.T) {
+	cursor := EncodeCursor("v", 7)
+
+	clause, args := KeysetWhere(cursor, "moved_at", true)
+	if clause != "(moved_at, id) < (?, ?)" {
+		t.Fatalf("clause desc inesperada: %q", clause)
+	}
+	if len(args) != 2 || args[0] != "v" || args[1] != int64(7) {
+		t.Fatalf("args inesperados: %v", args)
+	}
+
+	clause, _ = KeysetWhere(cursor, "moved_at", false)
+	if clause != "(moved_at, id) > (?, ?)" {
+		t.Fatalf("clause asc inesperada: %q", clause)
+	}
+}
+
+func TestKeysetOrderBy(t *testing.T) {
+	if got := KeysetOrderBy("moved_at", true); got != "moved_at DESC, id DESC" {
+		t.Fatalf("order by desc inesperado: %q", got)
+	}
+	if got := KeysetOrderBy("moved_at", false); got != "moved_at ASC, id ASC" {
+		t.Fatalf("order by asc inesperado: %q", got)
+	}
+}
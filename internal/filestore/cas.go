@@ -0,0 +1,82 @@
+package filestore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CASBackend decora outro Backend para armazenar o conteúdo sob uma chave
+// derivada do SHA-256 do payload, ao estilo do Git-LFS: reenviar o mesmo
+// arquivo (ex.: o mesmo avatar) resolve para a mesma chave e não grava uma
+// segunda cópia. A chave lógica pedida pelo chamador só é usada para extrair
+// a extensão do arquivo.
+type CASBackend struct {
+	inner Backend
+}
+
+// NewCASBackend cria um CASBackend que delega a gravação/leitura efetiva a
+// inner, usando-o apenas com chaves content-addressed.
+func NewCASBackend(inner Backend) *CASBackend {
+	return &CASBackend{inner: inner}
+}
+
+func casKey(logicalKey string, sum string) string {
+	ext := filepath.Ext(logicalKey)
+	return "cas/" + sum[:2] + "/" + sum + ext
+}
+
+// Put grava r em um arquivo temporário para calcular seu SHA-256 antes de
+// decidir a chave final, depois rebobina o temporário para a gravação real.
+// Se a chave já existir no backend interno, a gravação é pulada e a URL
+// existente é reaproveitada.
+func (b *CASBackend) Put(ctx context.Context, key string, r io.Reader, contentType string) (string, error) {
+	tmp, err := os.CreateTemp("", "filestore-cas-*")
+	if err != nil {
+		return "", fmt.Errorf("filestore: falha ao criar arquivo temporário: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hasher), r); err != nil {
+		return "", fmt.Errorf("filestore: falha ao calcular hash do conteúdo: %w", err)
+	}
+
+	sum := hex.EncodeToString(hasher.Sum(nil))
+	key = casKey(key, sum)
+
+	if _, err := b.inner.Stat(ctx, key); err == nil {
+		return b.inner.SignedURL(ctx, key, 0)
+	} else if !errors.Is(err, ErrNotFound) {
+		return "", err
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("filestore: falha ao rebobinar arquivo temporário: %w", err)
+	}
+
+	return b.inner.Put(ctx, key, tmp, contentType)
+}
+
+func (b *CASBackend) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return b.inner.Get(ctx, key)
+}
+
+func (b *CASBackend) Delete(ctx context.Context, key string) error {
+	return b.inner.Delete(ctx, key)
+}
+
+func (b *CASBackend) Stat(ctx context.Context, key string) (Info, error) {
+	return b.inner.Stat(ctx, key)
+}
+
+func (b *CASBackend) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	return b.inner.SignedURL(ctx, key, ttl)
+}
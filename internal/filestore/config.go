@@ -0,0 +1,53 @@
+package filestore
+
+import (
+	"fmt"
+
+	"github.com/PauloHFS/goth/internal/config"
+)
+
+// New seleciona e constrói o Backend configurado em cfg.Storage
+// (STORAGE_BACKEND=local|webdav|s3). Quando STORAGE_CAS_ENABLED=true, o
+// backend escolhido é envolvido em um CASBackend, deduplicando uploads
+// idênticos pelo SHA-256 do conteúdo.
+func New(cfg *config.Config) (Backend, error) {
+	backend, err := newBaseBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Storage.CASEnabled {
+		return NewCASBackend(backend), nil
+	}
+	return backend, nil
+}
+
+func newBaseBackend(cfg *config.Config) (Backend, error) {
+	switch cfg.Storage.Backend {
+	case "", "local":
+		return NewLocalBackend(cfg.Storage.LocalDir, cfg.Storage.LocalBaseURL)
+	case "webdav":
+		if cfg.Storage.WebDAVBaseURL == "" {
+			return nil, fmt.Errorf("filestore: STORAGE_WEBDAV_BASE_URL é obrigatório para STORAGE_BACKEND=webdav")
+		}
+		return NewWebDAVBackend(
+			cfg.Storage.WebDAVBaseURL,
+			cfg.Storage.WebDAVUsername,
+			cfg.Storage.WebDAVPassword,
+			cfg.Storage.WebDAVBearerToken,
+		), nil
+	case "s3":
+		if cfg.Storage.S3Bucket == "" {
+			return nil, fmt.Errorf("filestore: STORAGE_S3_BUCKET é obrigatório para STORAGE_BACKEND=s3")
+		}
+		return NewS3Backend(
+			cfg.Storage.S3Bucket,
+			cfg.Storage.S3Region,
+			cfg.Storage.S3Endpoint,
+			cfg.Storage.S3AccessKey,
+			cfg.Storage.S3SecretKey,
+		), nil
+	default:
+		return nil, fmt.Errorf("filestore: backend de storage desconhecido %q", cfg.Storage.Backend)
+	}
+}
@@ -0,0 +1,37 @@
+// Package filestore abstrai o armazenamento de arquivos enviados pelos
+// usuários (hoje, avatares) atrás de um Backend comum, para que a escolha
+// entre disco local e um servidor WebDAV (ex.: Nextcloud) seja apenas
+// configuração, não código espalhado pelos handlers.
+package filestore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrNotFound é retornado por Get/Stat/Delete quando a chave não existe.
+var ErrNotFound = errors.New("filestore: chave não encontrada")
+
+// Info descreve os metadados de um objeto armazenado.
+type Info struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// Backend é implementado por cada mecanismo de armazenamento suportado.
+type Backend interface {
+	// Put grava o conteúdo de r sob key e retorna a URL pública (ou
+	// assinada, dependendo do backend) para acessá-lo.
+	Put(ctx context.Context, key string, r io.Reader, contentType string) (url string, err error)
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (Info, error)
+	// SignedURL retorna uma URL temporária para acesso direto ao objeto,
+	// válida por ttl. Backends sem suporte nativo podem retornar a URL
+	// pública normal.
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
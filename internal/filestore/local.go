@@ -0,0 +1,121 @@
+package filestore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LocalBackend persiste arquivos no disco local, sob root. Usado como
+// backend padrão (STORAGE_BACKEND=local) e em desenvolvimento.
+type LocalBackend struct {
+	root    string
+	baseURL string
+}
+
+// NewLocalBackend cria um LocalBackend enraizado em root. baseURL é
+// prefixado às chaves para formar a URL pública retornada por Put, tipo
+// "/storage" quando o servidor expõe o diretório via http.FileServer.
+func NewLocalBackend(root, baseURL string) (*LocalBackend, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("falha ao criar diretório de storage: %w", err)
+	}
+	return &LocalBackend{root: root, baseURL: strings.TrimRight(baseURL, "/")}, nil
+}
+
+func (b *LocalBackend) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	full := filepath.Join(b.root, clean)
+	if !strings.HasPrefix(full, filepath.Clean(b.root)+string(os.PathSeparator)) && full != filepath.Clean(b.root) {
+		return "", fmt.Errorf("filestore: chave inválida %q", key)
+	}
+	return full, nil
+}
+
+func (b *LocalBackend) Put(ctx context.Context, key string, r io.Reader, contentType string) (string, error) {
+	full, err := b.path(key)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		return "", fmt.Errorf("falha ao criar diretório: %w", err)
+	}
+
+	f, err := os.Create(full)
+	if err != nil {
+		return "", fmt.Errorf("falha ao criar arquivo: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, r); err != nil {
+		return "", fmt.Errorf("falha ao gravar arquivo: %w", err)
+	}
+
+	return b.baseURL + "/" + strings.TrimLeft(key, "/"), nil
+}
+
+func (b *LocalBackend) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	full, err := b.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(full)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("falha ao abrir arquivo: %w", err)
+	}
+	return f, nil
+}
+
+func (b *LocalBackend) Delete(ctx context.Context, key string) error {
+	full, err := b.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(full); err != nil {
+		if os.IsNotExist(err) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("falha ao remover arquivo: %w", err)
+	}
+	return nil
+}
+
+func (b *LocalBackend) Stat(ctx context.Context, key string) (Info, error) {
+	full, err := b.path(key)
+	if err != nil {
+		return Info{}, err
+	}
+
+	fi, err := os.Stat(full)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Info{}, ErrNotFound
+		}
+		return Info{}, fmt.Errorf("falha ao obter metadados: %w", err)
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(full))
+	return Info{
+		Key:         key,
+		Size:        fi.Size(),
+		ContentType: contentType,
+		ModTime:     fi.ModTime(),
+	}, nil
+}
+
+// SignedURL não possui suporte nativo no backend local (não há como expirar
+// um arquivo servido por http.FileServer), então retorna a URL pública.
+func (b *LocalBackend) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	return b.baseURL + "/" + strings.TrimLeft(key, "/"), nil
+}
@@ -0,0 +1,63 @@
+package filestore
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestLocalBackendRoundTrip(t *testing.T) {
+	backend, err := NewLocalBackend(t.TempDir(), "/storage")
+	if err != nil {
+		t.Fatalf("NewLocalBackend: %v", err)
+	}
+
+	ctx := context.Background()
+	key := "avatars/1/photo.png"
+
+	url, err := backend.Put(ctx, key, strings.NewReader("conteudo"), "image/png")
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if url != "/storage/"+key {
+		t.Errorf("expected url /storage/%s, got %s", key, url)
+	}
+
+	rc, err := backend.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer rc.Close()
+
+	info, err := backend.Stat(ctx, key)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Size != int64(len("conteudo")) {
+		t.Errorf("expected size %d, got %d", len("conteudo"), info.Size)
+	}
+
+	if err := backend.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	if _, err := backend.Stat(ctx, key); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestLocalBackendNeutralizesPathTraversal(t *testing.T) {
+	root := t.TempDir()
+	backend, err := NewLocalBackend(root, "/storage")
+	if err != nil {
+		t.Fatalf("NewLocalBackend: %v", err)
+	}
+
+	full, err := backend.path("../../etc/passwd")
+	if err != nil {
+		t.Fatalf("path: %v", err)
+	}
+	if !strings.HasPrefix(full, root) {
+		t.Errorf("expected resolved path to stay under root %q, got %q", root, full)
+	}
+}
@@ -0,0 +1,62 @@
+package filestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// S3Backend fala com um serviço compatível com a API S3 (AWS S3, MinIO,
+// Cloudflare R2 etc.). As requisições não são assinadas: todas as operações
+// retornam errS3NotImplemented. O assinador SigV4 de internal/awssig, usado
+// por mailer.AWSESProvider para o SES, não é usado aqui.
+type S3Backend struct {
+	bucket    string
+	region    string
+	endpoint  string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+// NewS3Backend cria um S3Backend apontando para bucket/region. endpoint pode
+// ser vazio para usar o endpoint padrão da AWS ou apontar para um serviço
+// compatível (ex.: MinIO, R2).
+func NewS3Backend(bucket, region, endpoint, accessKey, secretKey string) *S3Backend {
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, region)
+	}
+	return &S3Backend{
+		bucket:    bucket,
+		region:    region,
+		endpoint:  endpoint,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+var errS3NotImplemented = errors.New("filestore: backend s3 não implementado - use aws-sdk-go-v2 ou minio-go para assinatura SigV4")
+
+func (b *S3Backend) Put(ctx context.Context, key string, r io.Reader, contentType string) (string, error) {
+	return "", errS3NotImplemented
+}
+
+func (b *S3Backend) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return nil, errS3NotImplemented
+}
+
+func (b *S3Backend) Delete(ctx context.Context, key string) error {
+	return errS3NotImplemented
+}
+
+func (b *S3Backend) Stat(ctx context.Context, key string) (Info, error) {
+	return Info{}, errS3NotImplemented
+}
+
+func (b *S3Backend) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	return "", errS3NotImplemented
+}
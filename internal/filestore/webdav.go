@@ -0,0 +1,310 @@
+package filestore
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WebDAVBackend fala com um servidor WebDAV (Nextcloud e afins) via PUT/GET/
+// DELETE/MKCOL/PROPFIND simples, sem depender de nenhuma lib externa de
+// WebDAV.
+type WebDAVBackend struct {
+	baseURL     string
+	username    string
+	password    string
+	bearerToken string
+	httpClient  *http.Client
+}
+
+// NewWebDAVBackend cria um WebDAVBackend apontando para baseURL (a raiz da
+// coleção onde as chaves são resolvidas). Informe username/password para
+// autenticação básica ou bearerToken para Bearer; os dois são mutuamente
+// exclusivos, com bearerToken tendo prioridade se ambos forem informados.
+func NewWebDAVBackend(baseURL, username, password, bearerToken string) *WebDAVBackend {
+	return &WebDAVBackend{
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		username:    username,
+		password:    password,
+		bearerToken: bearerToken,
+		httpClient:  http.DefaultClient,
+	}
+}
+
+func (b *WebDAVBackend) setAuth(req *http.Request) {
+	if b.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+b.bearerToken)
+		return
+	}
+	if b.username != "" {
+		req.SetBasicAuth(b.username, b.password)
+	}
+}
+
+func (b *WebDAVBackend) url(key string) string {
+	return b.baseURL + "/" + strings.TrimLeft(path.Clean("/"+key), "/")
+}
+
+// mkdirAll cria, uma a uma, as coleções WebDAV do caminho dir, ignorando o
+// 405 Method Not Allowed que a maioria dos servidores retorna quando a
+// coleção já existe.
+func (b *WebDAVBackend) mkdirAll(ctx context.Context, dir string) error {
+	dir = strings.Trim(path.Clean("/"+dir), "/")
+	if dir == "." || dir == "" {
+		return nil
+	}
+
+	segments := strings.Split(dir, "/")
+	built := ""
+	for _, seg := range segments {
+		built += "/" + seg
+
+		req, err := http.NewRequestWithContext(ctx, "MKCOL", b.baseURL+built, nil)
+		if err != nil {
+			return fmt.Errorf("falha ao preparar MKCOL: %w", err)
+		}
+		b.setAuth(req)
+
+		resp, err := b.httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("falha ao criar coleção %s: %w", built, err)
+		}
+		resp.Body.Close()
+
+		switch resp.StatusCode {
+		case http.StatusCreated, http.StatusMethodNotAllowed, http.StatusConflict:
+			// criada agora, ou já existia
+		default:
+			return fmt.Errorf("MKCOL %s retornou status inesperado %d", built, resp.StatusCode)
+		}
+	}
+	return nil
+}
+
+func (b *WebDAVBackend) Put(ctx context.Context, key string, r io.Reader, contentType string) (string, error) {
+	if err := b.mkdirAll(ctx, path.Dir(key)); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, b.url(key), r)
+	if err != nil {
+		return "", fmt.Errorf("falha ao preparar upload WebDAV: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	b.setAuth(req)
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("falha ao enviar arquivo via WebDAV: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("WebDAV PUT retornou status inesperado %d", resp.StatusCode)
+	}
+
+	return b.url(key), nil
+}
+
+func (b *WebDAVBackend) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.url(key), nil)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao preparar download WebDAV: %w", err)
+	}
+	b.setAuth(req)
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao baixar arquivo via WebDAV: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("WebDAV GET retornou status inesperado %d", resp.StatusCode)
+	}
+
+	return resp.Body, nil
+}
+
+func (b *WebDAVBackend) Delete(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, b.url(key), nil)
+	if err != nil {
+		return fmt.Errorf("falha ao preparar remoção WebDAV: %w", err)
+	}
+	b.setAuth(req)
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("falha ao remover arquivo via WebDAV: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("WebDAV DELETE retornou status inesperado %d", resp.StatusCode)
+	}
+	return nil
+}
+
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<D:propfind xmlns:D="DAV:">
+  <D:prop>
+    <D:getcontentlength/>
+    <D:getcontenttype/>
+    <D:getlastmodified/>
+    <D:resourcetype/>
+  </D:prop>
+</D:propfind>`
+
+type davMultistatus struct {
+	Responses []davResponse `xml:"response"`
+}
+
+type davResponse struct {
+	Href     string        `xml:"href"`
+	Propstat []davPropstat `xml:"propstat"`
+}
+
+type davPropstat struct {
+	Prop   davProp `xml:"prop"`
+	Status string  `xml:"status"`
+}
+
+type davProp struct {
+	ContentLength string      `xml:"getcontentlength"`
+	ContentType   string      `xml:"getcontenttype"`
+	LastModified  string      `xml:"getlastmodified"`
+	ResourceType  davResource `xml:"resourcetype"`
+}
+
+type davResource struct {
+	Collection *struct{} `xml:"collection"`
+}
+
+// propfind executa um PROPFIND na chave com a profundidade informada e
+// retorna as entradas decodificadas do multistatus.
+func (b *WebDAVBackend) propfind(ctx context.Context, key string, depth string) ([]davResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "PROPFIND", b.url(key), strings.NewReader(propfindBody))
+	if err != nil {
+		return nil, fmt.Errorf("falha ao preparar PROPFIND: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/xml")
+	req.Header.Set("Depth", depth)
+	b.setAuth(req)
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao executar PROPFIND: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, fmt.Errorf("PROPFIND retornou status inesperado %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao ler resposta do PROPFIND: %w", err)
+	}
+
+	var ms davMultistatus
+	if err := xml.Unmarshal(body, &ms); err != nil {
+		return nil, fmt.Errorf("falha ao decodificar multistatus: %w", err)
+	}
+
+	return ms.Responses, nil
+}
+
+func infoFromResponse(key string, resp davResponse) Info {
+	info := Info{Key: key}
+	for _, ps := range resp.Propstat {
+		if !strings.Contains(ps.Status, "200") {
+			continue
+		}
+		if ps.Prop.ContentLength != "" {
+			if size, err := strconv.ParseInt(ps.Prop.ContentLength, 10, 64); err == nil {
+				info.Size = size
+			}
+		}
+		if ps.Prop.ContentType != "" {
+			info.ContentType = ps.Prop.ContentType
+		}
+		if ps.Prop.LastModified != "" {
+			if t, err := time.Parse(time.RFC1123, ps.Prop.LastModified); err == nil {
+				info.ModTime = t
+			}
+		}
+	}
+	return info
+}
+
+// Stat usa PROPFIND com Depth 0 para obter os metadados de uma única chave.
+func (b *WebDAVBackend) Stat(ctx context.Context, key string) (Info, error) {
+	responses, err := b.propfind(ctx, key, "0")
+	if err != nil {
+		return Info{}, err
+	}
+	if len(responses) == 0 {
+		return Info{}, ErrNotFound
+	}
+	return infoFromResponse(key, responses[0]), nil
+}
+
+// SignedURL não tem equivalente padrão em WebDAV puro (Nextcloud expõe isso
+// via sua própria API de compartilhamento, fora de escopo aqui), então
+// retorna a URL direta do recurso; o caller decide se a autenticação do
+// backend é suficiente para o consumidor final.
+func (b *WebDAVBackend) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	return b.url(key), nil
+}
+
+// Walk lista recursivamente as entradas sob root usando PROPFIND com
+// Depth infinity, chamando fn para cada recurso que não seja uma coleção.
+// Nem todo servidor WebDAV aceita Depth infinity (Nextcloud, por exemplo,
+// limita); nesse caso Walk retorna o erro do servidor para o caller decidir
+// como paginar com Depth 1.
+func (b *WebDAVBackend) Walk(ctx context.Context, root string, fn func(Info) error) error {
+	responses, err := b.propfind(ctx, root, "infinity")
+	if err != nil {
+		return err
+	}
+
+	rootHref := b.url(root)
+	for _, resp := range responses {
+		if resp.Href == rootHref || strings.TrimRight(resp.Href, "/") == strings.TrimRight(rootHref, "/") {
+			continue
+		}
+
+		isCollection := false
+		for _, ps := range resp.Propstat {
+			if ps.Prop.ResourceType.Collection != nil {
+				isCollection = true
+			}
+		}
+		if isCollection {
+			continue
+		}
+
+		key := strings.TrimPrefix(resp.Href, b.baseURL)
+		if err := fn(infoFromResponse(key, resp)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
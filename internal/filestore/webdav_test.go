@@ -0,0 +1,166 @@
+package filestore
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newTestWebDAVServer sobe um servidor WebDAV mínimo (PUT/GET/DELETE/MKCOL/
+// PROPFIND sobre um diretório local) suficiente para exercitar WebDAVBackend
+// sem depender de um Nextcloud real.
+func newTestWebDAVServer(t *testing.T) *httptest.Server {
+	root := t.TempDir()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "tester" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		full := filepath.Join(root, filepath.Clean("/"+r.URL.Path))
+
+		switch r.Method {
+		case http.MethodPut:
+			if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			f, err := os.Create(full)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			defer f.Close()
+			if _, err := io.Copy(f, r.Body); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+
+		case http.MethodGet:
+			f, err := os.Open(full)
+			if err != nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			defer f.Close()
+			io.Copy(w, f)
+
+		case http.MethodDelete:
+			if err := os.Remove(full); err != nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		case "MKCOL":
+			if err := os.Mkdir(full, 0755); err != nil {
+				if os.IsExist(err) {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+
+		case "PROPFIND":
+			fi, err := os.Stat(full)
+			if err != nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusMultiStatus)
+			writePropfindResponse(w, r.URL.Path, fi)
+
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+
+	return httptest.NewServer(mux)
+}
+
+func writePropfindResponse(w http.ResponseWriter, href string, fi os.FileInfo) {
+	collection := ""
+	if fi.IsDir() {
+		collection = "<D:collection/>"
+	}
+	_, _ = w.Write([]byte(`<?xml version="1.0"?>
+<D:multistatus xmlns:D="DAV:">
+  <D:response>
+    <D:href>` + href + `</D:href>
+    <D:propstat>
+      <D:prop>
+        <D:getcontentlength>` + strconv.FormatInt(fi.Size(), 10) + `</D:getcontentlength>
+        <D:getcontenttype>application/octet-stream</D:getcontenttype>
+        <D:getlastmodified>` + fi.ModTime().UTC().Format(http.TimeFormat) + `</D:getlastmodified>
+        <D:resourcetype>` + collection + `</D:resourcetype>
+      </D:prop>
+      <D:status>HTTP/1.1 200 OK</D:status>
+    </D:propstat>
+  </D:response>
+</D:multistatus>`))
+}
+
+func TestWebDAVBackendRoundTrip(t *testing.T) {
+	server := newTestWebDAVServer(t)
+	defer server.Close()
+
+	backend := NewWebDAVBackend(server.URL, "tester", "secret", "")
+	ctx := context.Background()
+
+	key := "avatars/1/photo.png"
+	url, err := backend.Put(ctx, key, strings.NewReader("conteudo"), "image/png")
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if url != server.URL+"/"+key {
+		t.Errorf("expected url %s, got %s", server.URL+"/"+key, url)
+	}
+
+	rc, err := backend.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	body, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(body) != "conteudo" {
+		t.Errorf("expected body 'conteudo', got %q", body)
+	}
+
+	info, err := backend.Stat(ctx, key)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Size != int64(len("conteudo")) {
+		t.Errorf("expected size %d, got %d", len("conteudo"), info.Size)
+	}
+
+	if err := backend.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	if _, err := backend.Get(ctx, key); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestWebDAVBackendRejectsBadCredentials(t *testing.T) {
+	server := newTestWebDAVServer(t)
+	defer server.Close()
+
+	backend := NewWebDAVBackend(server.URL, "tester", "wrong-password", "")
+	if _, err := backend.Put(context.Background(), "x.png", strings.NewReader("x"), "image/png"); err == nil {
+		t.Error("expected error with bad credentials, got nil")
+	}
+}
@@ -1,10 +1,23 @@
 package httpclient
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
 	"log/slog"
+	"math"
+	"math/rand"
+	"net"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
 	"github.com/PauloHFS/goth/internal/logging"
 )
 
@@ -21,19 +34,54 @@ type Config struct {
 	RetryWaitMax time.Duration
 }
 
-func New(cfg Config) *Client {
-	transport := &loggingTransport{
-		RoundTripper: http.DefaultTransport,
+// New monta o client com a cadeia transporte -> retryTransport (se
+// cfg.MaxRetries > 0) -> metricsTransport -> loggingTransport, e aplica opts
+// por cima (ex.: WithAuth, WithRetryPolicy, WithoutMetrics). retryTransport
+// fica dentro de loggingTransport de propósito: loggingTransport cria o
+// logging.Event uma vez por chamada a Client.Do, e retryTransport roda todas
+// as tentativas dentro dessa mesma chamada, então consegue gravar
+// "attempts" nesse mesmo Event (ver retryTransport.RoundTrip) antes do log
+// final sair. metricsTransport fica entre os dois, envolvendo
+// retryTransport: cada chamada lógica gera uma única observação de duração
+// e status, que cobre todas as tentativas e reflete o resultado final.
+func New(cfg Config, opts ...func(*Client)) *Client {
+	registerMetrics()
+
+	var transport http.RoundTripper = http.DefaultTransport
+
+	if cfg.MaxRetries > 0 {
+		transport = &retryTransport{
+			RoundTripper: transport,
+			maxRetries:   cfg.MaxRetries,
+			waitMin:      cfg.RetryWaitMin,
+			waitMax:      cfg.RetryWaitMax,
+			shouldRetry:  defaultShouldRetry,
+		}
+	}
+
+	transport = &metricsTransport{
+		RoundTripper: transport,
+		name:         cfg.Name,
+	}
+
+	transport = &loggingTransport{
+		RoundTripper: transport,
 		name:         cfg.Name,
 	}
 
-	return &Client{
+	c := &Client{
 		Client: &http.Client{
 			Timeout:   cfg.Timeout,
 			Transport: transport,
 		},
 		name: cfg.Name,
 	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
 }
 
 func Default() *Client {
@@ -108,3 +156,314 @@ func (t *authTransport) RoundTrip(r *http.Request) (*http.Response, error) {
 	t.authFunc(r)
 	return t.RoundTripper.RoundTrip(r)
 }
+
+// RetryPolicy decide, a partir da resposta (pode ser nil) e/ou erro de uma
+// tentativa, se vale a pena tentar de novo.
+type RetryPolicy func(*http.Response, error) bool
+
+// defaultShouldRetry retenta erros de transporte, 429 e 5xx — exceto 501
+// (Not Implemented), que não é transitório: tentar de novo nunca vai
+// funcionar.
+func defaultShouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	if resp.StatusCode == http.StatusNotImplemented {
+		return false
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// WithRetryPolicy troca a RetryPolicy do retryTransport já montado por New
+// (ver New — só existe um se cfg.MaxRetries > 0). Pensado pra clientes como
+// o de streaming do LLM, que precisam não retentar certos status (ex.: uma
+// resposta parcial de streaming já em andamento).
+func WithRetryPolicy(policy RetryPolicy) func(*Client) {
+	return func(c *Client) {
+		rt := findRetryTransport(c.Transport)
+		if rt != nil {
+			rt.shouldRetry = policy
+		}
+	}
+}
+
+// findRetryTransport percorre a cadeia de RoundTripper (loggingTransport,
+// authTransport, ...) até achar o *retryTransport montado por New, ou nil
+// se cfg.MaxRetries era <= 0.
+func findRetryTransport(rt http.RoundTripper) *retryTransport {
+	for rt != nil {
+		switch v := rt.(type) {
+		case *retryTransport:
+			return v
+		case *loggingTransport:
+			rt = v.RoundTripper
+		case *metricsTransport:
+			rt = v.RoundTripper
+		case *authTransport:
+			rt = v.RoundTripper
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// retryTransport reenvia a requisição até maxRetries vezes quando
+// shouldRetry indica que vale a pena, com backoff exponencial com jitter
+// (sleep = rand(0, min(waitMax, waitMin*2^attempt))), honrando Retry-After
+// quando presente (segundos ou data HTTP). Aborta assim que ctx.Err() != nil
+// — inclusive entre uma tentativa e a espera da próxima.
+type retryTransport struct {
+	http.RoundTripper
+	maxRetries  int
+	waitMin     time.Duration
+	waitMax     time.Duration
+	shouldRetry RetryPolicy
+}
+
+func (t *retryTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	getBody, err := requestBodyGetter(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *http.Response
+	var lastErr error
+
+	for attempt := 0; ; attempt++ {
+		if err := r.Context().Err(); err != nil {
+			if lastErr != nil {
+				return nil, lastErr
+			}
+			return nil, err
+		}
+
+		if attempt > 0 {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			r.Body = body
+		}
+
+		resp, lastErr = t.RoundTripper.RoundTrip(r)
+		logging.AddToEvent(r.Context(), slog.Int("attempts", attempt+1))
+
+		if attempt >= t.maxRetries || !t.shouldRetry(resp, lastErr) {
+			return resp, lastErr
+		}
+
+		wait := t.retryDelay(attempt, resp)
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			timer.Stop()
+			return nil, r.Context().Err()
+		}
+	}
+}
+
+func (t *retryTransport) retryDelay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if d, ok := retryAfterDelay(resp.Header.Get("Retry-After")); ok {
+			return d
+		}
+	}
+	return fullJitterDelay(attempt, t.waitMin, t.waitMax)
+}
+
+func fullJitterDelay(attempt int, waitMin, waitMax time.Duration) time.Duration {
+	if waitMin <= 0 {
+		waitMin = 500 * time.Millisecond
+	}
+	if waitMax <= 0 {
+		waitMax = 30 * time.Second
+	}
+
+	exp := time.Duration(math.Min(float64(waitMax), float64(waitMin)*math.Pow(2, float64(attempt))))
+	if exp <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(exp)))
+}
+
+// retryAfterDelay entende tanto a forma delta-seconds ("120") quanto a
+// forma de data HTTP (RFC 1123 etc., ver http.ParseTime) do header
+// Retry-After.
+func retryAfterDelay(header string) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(header); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if when, err := http.ParseTime(header); err == nil {
+		d := time.Until(when)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+
+	return 0, false
+}
+
+// requestBodyGetter devolve uma função capaz de recriar o corpo de r a cada
+// retry. Usa r.GetBody quando o request já o fornece (caso comum: corpo
+// criado a partir de bytes/strings/bytes.Reader via http.NewRequest);
+// senão, drena o corpo uma vez e guarda em memória pra recriar um
+// io.ReadCloser a cada tentativa — a mesma técnica que net/http/httputil
+// usa internamente (ver drainBody) pra poder devolver um corpo já lido
+// intacto.
+func requestBodyGetter(r *http.Request) (func() (io.ReadCloser, error), error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return func() (io.ReadCloser, error) { return http.NoBody, nil }, nil
+	}
+
+	if r.GetBody != nil {
+		getBody := r.GetBody
+		body, err := getBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+		return getBody, nil
+	}
+
+	data, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	getBody := func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	body, _ := getBody()
+	r.Body = body
+	return getBody, nil
+}
+
+// RequestDurationBuckets são os buckets do histograma
+// httpclient_request_duration_seconds. Só tem efeito se trocado antes da
+// primeira chamada a New — os coletores são registrados uma única vez (ver
+// registerMetrics), porque um HistogramVec não pode trocar de buckets
+// depois de criado.
+var RequestDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+var (
+	metricsOnce sync.Once
+
+	httpClientRequestDuration  *prometheus.HistogramVec
+	httpClientRequestsInFlight *prometheus.GaugeVec
+	httpClientRequestErrors    *prometheus.CounterVec
+)
+
+func registerMetrics() {
+	metricsOnce.Do(func() {
+		httpClientRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "httpclient_request_duration_seconds",
+			Help:    "httpclient request duration in seconds",
+			Buckets: RequestDurationBuckets,
+		}, []string{"client", "method", "host", "status_class"})
+
+		httpClientRequestsInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "httpclient_requests_in_flight",
+			Help: "Number of in-flight httpclient requests",
+		}, []string{"client"})
+
+		httpClientRequestErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "httpclient_request_errors_total",
+			Help: "Total number of httpclient request errors",
+		}, []string{"client", "host", "error_type"})
+	})
+}
+
+// metricsTransport mede cada chamada de RoundTrip, com todas as tentativas
+// de retryTransport dentro dela (ver New — fica entre retryTransport e
+// loggingTransport), mirando o mesmo
+// trio duração/contagem/erros que internal/llm expõe pros clients LLM
+// (llm_request_duration_seconds, _requests_total, _errors_total).
+type metricsTransport struct {
+	http.RoundTripper
+	name string
+}
+
+func (t *metricsTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	host := r.URL.Host
+
+	httpClientRequestsInFlight.WithLabelValues(t.name).Inc()
+	defer httpClientRequestsInFlight.WithLabelValues(t.name).Dec()
+
+	start := time.Now()
+	resp, err := t.RoundTripper.RoundTrip(r)
+	duration := time.Since(start)
+
+	if err != nil {
+		httpClientRequestErrors.WithLabelValues(t.name, host, classifyTransportError(err)).Inc()
+		httpClientRequestDuration.WithLabelValues(t.name, r.Method, host, "error").Observe(duration.Seconds())
+		return resp, err
+	}
+
+	httpClientRequestDuration.WithLabelValues(t.name, r.Method, host, statusClass(resp.StatusCode)).Observe(duration.Seconds())
+	return resp, nil
+}
+
+func statusClass(status int) string {
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// classifyTransportError espelha llm.classifyError, mas pro nível de
+// transporte: não há APIError aqui, só os jeitos que um RoundTrip pode
+// falhar antes de qualquer resposta chegar.
+func classifyTransportError(err error) string {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timeout"
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return "dns"
+	}
+
+	var tlsErr *tls.CertificateVerificationError
+	if errors.As(err, &tlsErr) {
+		return "tls"
+	}
+
+	return "unknown"
+}
+
+// WithoutMetrics remove o metricsTransport montado por New da cadeia — útil
+// em testes, ou quando o operador já coleta essas métricas em outra camada
+// (ex.: um proxy/sidecar na frente da chamada de saída).
+func WithoutMetrics() func(*Client) {
+	return func(c *Client) {
+		c.Transport = removeMetricsTransport(c.Transport)
+	}
+}
+
+func removeMetricsTransport(rt http.RoundTripper) http.RoundTripper {
+	switch v := rt.(type) {
+	case *metricsTransport:
+		return v.RoundTripper
+	case *loggingTransport:
+		v.RoundTripper = removeMetricsTransport(v.RoundTripper)
+		return v
+	case *authTransport:
+		v.RoundTripper = removeMetricsTransport(v.RoundTripper)
+		return v
+	default:
+		return rt
+	}
+}
@@ -0,0 +1,171 @@
+// Package i18n carrega o catálogo de mensagens de internal/i18n/locales e
+// resolve mensagens por locale com cadeia de fallback e pluralização.
+//
+//go:generate go run ./gen -check en.yaml
+package i18n
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/PauloHFS/goth/internal/contextkeys"
+)
+
+//go:embed locales/*.yaml
+var localesFS embed.FS
+
+// catalog é o estado global carregado de localesFS no init(). Mensagens
+// são só-leitura depois do boot — não há recarga em runtime (diferente de
+// internal/config/reload.go, que observa um arquivo externo; os catálogos
+// aqui são embarcados no binário).
+var catalog = loadCatalog()
+
+type localeCatalog map[string]msgEntry
+
+func loadCatalog() map[string]localeCatalog {
+	entries, err := localesFS.ReadDir("locales")
+	if err != nil {
+		panic(fmt.Sprintf("i18n: failed to read embedded locales: %v", err))
+	}
+
+	result := make(map[string]localeCatalog)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".yaml") {
+			continue
+		}
+		locale := strings.TrimSuffix(e.Name(), ".yaml")
+
+		data, err := localesFS.ReadFile("locales/" + e.Name())
+		if err != nil {
+			panic(fmt.Sprintf("i18n: failed to read %s: %v", e.Name(), err))
+		}
+
+		messages, err := parseCatalogYAML(data)
+		if err != nil {
+			panic(fmt.Sprintf("i18n: failed to parse %s: %v", e.Name(), err))
+		}
+
+		result[locale] = messages
+	}
+	return result
+}
+
+// SupportedLocales devolve os locales com um arquivo próprio em
+// locales/*.yaml, em ordem não definida (vem da iteração de um map).
+// middleware.Locale não a consulta: negocia o Accept-Language contra a sua
+// própria lista middleware.SupportedTags.
+func SupportedLocales() []string {
+	locales := make([]string, 0, len(catalog))
+	for locale := range catalog {
+		locales = append(locales, locale)
+	}
+	return locales
+}
+
+// FallbackChain devolve, a partir de locale, a sequência de locales a
+// tentar até achar uma mensagem: o locale pedido, cada prefixo dele
+// cortado no "-" (pt-BR → pt), e por fim "en" como piso universal. Duplicatas
+// são removidas mantendo a primeira ocorrência.
+func FallbackChain(locale string) []string {
+	var chain []string
+	seen := make(map[string]bool)
+
+	add := func(l string) {
+		if l != "" && !seen[l] {
+			seen[l] = true
+			chain = append(chain, l)
+		}
+	}
+
+	add(locale)
+	for i := strings.LastIndex(locale, "-"); i > 0; i = strings.LastIndex(locale, "-") {
+		locale = locale[:i]
+		add(locale)
+	}
+	add("en")
+
+	return chain
+}
+
+// pluralCategory implementa a regra simplificada de CLDR usada pelos
+// locales embarcados (en, pt, pt-BR): singular "one" para n == 1, "other"
+// em qualquer outro caso. Idiomas com mais categorias (árabe, russo, ...)
+// exigiriam uma tabela de regras por locale que este pacote não tem hoje —
+// ponto de extensão documentado, não implementado, porque nenhum locale
+// atual precisa dele.
+func pluralCategory(n int) string {
+	if n == 1 {
+		return "one"
+	}
+	return "other"
+}
+
+// lookup resolve id percorrendo FallbackChain(locale), devolvendo a
+// msgEntry e o locale em que foi encontrada. ok é false se nenhum locale
+// da cadeia tem essa mensagem.
+func lookup(locale, id string) (msgEntry, bool) {
+	for _, l := range FallbackChain(locale) {
+		if messages, ok := catalog[l]; ok {
+			if entry, ok := messages[id]; ok {
+				return entry, true
+			}
+		}
+	}
+	return msgEntry{}, false
+}
+
+// T resolve a mensagem id para o locale em ctx (ver contextkeys.LocaleKey,
+// definido por middleware.Locale), escolhendo a forma singular/plural
+// conforme n (ver pluralCategory) e substituindo "{{.N}}" pelo valor de n.
+// Se id não existir em nenhum locale da cadeia de fallback, devolve o
+// próprio id entre colchetes — "[cart.items]" — para que uma mensagem
+// faltando seja óbvia em vez de aparecer em branco.
+func T(ctx context.Context, id string, n int) string {
+	locale, _ := ctx.Value(contextkeys.LocaleKey).(string)
+
+	entry, ok := lookup(locale, id)
+	if !ok {
+		return "[" + id + "]"
+	}
+
+	msg := entry.Other
+	if pluralCategory(n) == "one" && entry.One != "" {
+		msg = entry.One
+	}
+	if msg == "" {
+		return "[" + id + "]"
+	}
+
+	return strings.ReplaceAll(msg, "{{.N}}", strconv.Itoa(n))
+}
+
+// Translation é o formato antigo de Get, mantido só para os call sites que
+// ainda não migraram para T.
+//
+// Deprecated: use T(ctx, id, n) diretamente.
+type Translation struct {
+	Login     string
+	Email     string
+	Password  string
+	Register  string
+	Welcome   string
+	Dashboard string
+}
+
+// Get é um shim sobre o catálogo para preservar a API anterior.
+//
+// Deprecated: use T(ctx, id, n) — Get só existe para não quebrar call
+// sites escritos antes do catálogo.
+func Get(ctx context.Context) Translation {
+	return Translation{
+		Login:     T(ctx, "login", 1),
+		Email:     T(ctx, "email", 1),
+		Password:  T(ctx, "password", 1),
+		Register:  T(ctx, "register", 1),
+		Welcome:   T(ctx, "welcome", 1),
+		Dashboard: T(ctx, "dashboard", 1),
+	}
+}
@@ -0,0 +1,64 @@
+package i18n
+
+import (
+	"context"
+	"testing"
+
+	"github.com/PauloHFS/goth/internal/contextkeys"
+)
+
+func withLocale(locale string) context.Context {
+	return context.WithValue(context.Background(), contextkeys.LocaleKey, locale)
+}
+
+func TestFallbackChain(t *testing.T) {
+	chain := FallbackChain("pt-BR")
+	want := []string{"pt-BR", "pt", "en"}
+	if len(chain) != len(want) {
+		t.Fatalf("esperava %v, obteve %v", want, chain)
+	}
+	for i := range want {
+		if chain[i] != want[i] {
+			t.Fatalf("esperava %v, obteve %v", want, chain)
+		}
+	}
+}
+
+func TestFallbackChain_AlreadyEnglish(t *testing.T) {
+	chain := FallbackChain("en")
+	if len(chain) != 1 || chain[0] != "en" {
+		t.Fatalf("esperava [en], obteve %v", chain)
+	}
+}
+
+func TestT_PluralForms(t *testing.T) {
+	if got := T(withLocale("en"), "cart.items", 1); got != "1 item in the cart" {
+		t.Fatalf("esperava singular em inglês, obteve %q", got)
+	}
+	if got := T(withLocale("en"), "cart.items", 3); got != "3 items in the cart" {
+		t.Fatalf("esperava plural em inglês, obteve %q", got)
+	}
+}
+
+func TestT_FallsBackToParentLocale(t *testing.T) {
+	// pt-BR.yaml só sobrescreve "welcome" — "login" precisa cair para pt.yaml.
+	if got := T(withLocale("pt-BR"), "login", 1); got != "Entrar" {
+		t.Fatalf("esperava herdar de pt.yaml, obteve %q", got)
+	}
+	if got := T(withLocale("pt-BR"), "welcome", 1); got != "Bem-vindo(a)" {
+		t.Fatalf("esperava a sobrescrita de pt-BR.yaml, obteve %q", got)
+	}
+}
+
+func TestT_UnknownMessageID(t *testing.T) {
+	if got := T(withLocale("en"), "does.not.exist", 1); got != "[does.not.exist]" {
+		t.Fatalf("esperava id entre colchetes, obteve %q", got)
+	}
+}
+
+func TestGet_Shim(t *testing.T) {
+	tr := Get(withLocale("en"))
+	if tr.Login != "Login" || tr.Dashboard != "Dashboard" {
+		t.Fatalf("shim Get não bate com o catálogo: %+v", tr)
+	}
+}
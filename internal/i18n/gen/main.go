@@ -0,0 +1,130 @@
+// Command gen extrai message IDs usados em i18n.T(ctx, "id", ...) dos
+// arquivos de template do projeto e confere que cada um existe no
+// catálogo de referência (ver go:generate em internal/i18n/catalog.go).
+//
+// Varre *.templ e *_templ.go (o .go gerado por templ a partir de um
+// .templ) a partir da raiz do módulo. Esta árvore não tem nenhum arquivo
+// .templ ainda — rodar este comando hoje só confirma que não há IDs
+// pendentes; ele existe para o dia em que as views forem adicionadas, sem
+// exigir mudança nesta ferramenta.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var messageIDPattern = regexp.MustCompile(`i18n\.T\(\s*[^,]+,\s*"([^"]+)"`)
+
+func main() {
+	checkFile := flag.String("check", "", "arquivo em locales/ a usar como catálogo de referência (ex.: en.yaml)")
+	root := flag.String("root", "../..", "raiz a varrer por *.templ e *_templ.go")
+	flag.Parse()
+
+	ids, err := extractMessageIDs(*root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gen: failed to scan templates: %v\n", err)
+		os.Exit(1)
+	}
+
+	sort.Strings(ids)
+	for _, id := range ids {
+		fmt.Println(id)
+	}
+
+	if *checkFile == "" {
+		return
+	}
+
+	known, err := loadCatalogIDs(filepath.Join("locales", *checkFile))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gen: failed to load %s: %v\n", *checkFile, err)
+		os.Exit(1)
+	}
+
+	var missing []string
+	for _, id := range ids {
+		if !known[id] {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		fmt.Fprintf(os.Stderr, "gen: %d message id(s) used in templates but missing from %s:\n", len(missing), *checkFile)
+		for _, id := range missing {
+			fmt.Fprintf(os.Stderr, "  %s\n", id)
+		}
+		os.Exit(1)
+	}
+}
+
+// extractMessageIDs varre root por *.templ e *_templ.go e devolve, sem
+// duplicatas, todo ID usado em uma chamada i18n.T(ctx, "id", ...).
+func extractMessageIDs(root string) ([]string, error) {
+	seen := make(map[string]bool)
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if !strings.HasSuffix(path, ".templ") && !strings.HasSuffix(path, "_templ.go") {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range messageIDPattern.FindAllStringSubmatch(string(data), -1) {
+			seen[m[1]] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// loadCatalogIDs lê um arquivo locales/*.yaml só o suficiente para extrair
+// os message IDs de topo (chaves de primeiro nível, indentação zero) —
+// não precisa interpretar os valores para esta checagem.
+func loadCatalogIDs(path string) (map[string]bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]bool)
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmedRight := strings.TrimRight(line, " \t\r")
+		trimmed := strings.TrimLeft(trimmedRight, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if len(trimmedRight)-len(trimmed) != 0 {
+			continue
+		}
+		idx := strings.Index(trimmed, ":")
+		if idx == -1 {
+			continue
+		}
+		ids[strings.TrimSpace(trimmed[:idx])] = true
+	}
+	return ids, nil
+}
@@ -0,0 +1,105 @@
+package i18n
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// msgEntry é uma mensagem do catálogo: One/Other cobrem as duas categorias
+// de plural suportadas por pluralCategory (ver catalog.go). Other também
+// guarda o valor de uma mensagem não pluralizada ("login: Entrar").
+type msgEntry struct {
+	One   string
+	Other string
+}
+
+// parseCatalogYAML lê o subconjunto de YAML usado pelos arquivos em
+// locales/*.yaml: um mapeamento raso de message ID para uma string, ou
+// para um mapeamento aninhado de uma linha com "one"/"other". Não é um
+// parser de YAML geral — como internal/config/yaml.go, propositalmente
+// menor porque o formato do catálogo é fixo, não YAML arbitrário.
+func parseCatalogYAML(data []byte) (map[string]msgEntry, error) {
+	messages := make(map[string]msgEntry)
+
+	lines := strings.Split(string(data), "\n")
+	for i := 0; i < len(lines); i++ {
+		raw := lines[i]
+		trimmedRight := strings.TrimRight(raw, " \t\r")
+		trimmed := strings.TrimLeft(trimmedRight, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(trimmedRight) - len(trimmed)
+		if indent != 0 {
+			// Uma subforma de plural "órfã" (sem message ID pai na linha
+			// anterior) é um erro de formatação do arquivo, não algo pra
+			// ignorar silenciosamente.
+			return nil, fmt.Errorf("i18n: linha %d com indentação inesperada: %q", i+1, trimmed)
+		}
+
+		id, rest, ok := splitCatalogKeyValue(trimmed)
+		if !ok {
+			return nil, fmt.Errorf("i18n: linha %d inválida: %q", i+1, trimmed)
+		}
+
+		if rest != "" {
+			messages[id] = msgEntry{Other: unquoteCatalogValue(rest)}
+			continue
+		}
+
+		entry := msgEntry{}
+		for i+1 < len(lines) {
+			nextRaw := lines[i+1]
+			nextTrimmedRight := strings.TrimRight(nextRaw, " \t\r")
+			nextTrimmed := strings.TrimLeft(nextTrimmedRight, " ")
+			if nextTrimmed == "" || strings.HasPrefix(nextTrimmed, "#") {
+				i++
+				continue
+			}
+			nextIndent := len(nextTrimmedRight) - len(nextTrimmed)
+			if nextIndent == 0 {
+				break
+			}
+
+			i++
+			category, value, ok := splitCatalogKeyValue(nextTrimmed)
+			if !ok {
+				return nil, fmt.Errorf("i18n: linha %d inválida: %q", i+1, nextTrimmed)
+			}
+			switch category {
+			case "one":
+				entry.One = unquoteCatalogValue(value)
+			case "other":
+				entry.Other = unquoteCatalogValue(value)
+			default:
+				return nil, fmt.Errorf("i18n: linha %d: categoria de plural desconhecida %q (suportadas: one, other)", i+1, category)
+			}
+		}
+		messages[id] = entry
+	}
+
+	return messages, nil
+}
+
+func splitCatalogKeyValue(s string) (key, rest string, ok bool) {
+	idx := strings.Index(s, ":")
+	if idx == -1 {
+		return "", "", false
+	}
+	key = strings.TrimSpace(s[:idx])
+	rest = strings.TrimSpace(s[idx+1:])
+	if key == "" {
+		return "", "", false
+	}
+	return key, rest, true
+}
+
+func unquoteCatalogValue(s string) string {
+	if len(s) >= 2 && strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`) {
+		if unquoted, err := strconv.Unquote(s); err == nil {
+			return unquoted
+		}
+	}
+	return s
+}
@@ -0,0 +1,273 @@
+package llm
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// defaultAnthropicVersion é o valor padrão do cabeçalho anthropic-version
+// exigido por toda requisição a /v1/messages; WithAnthropicVersion permite
+// sobrescrevê-lo quando a API evoluir.
+const defaultAnthropicVersion = "2023-06-01"
+
+// defaultAnthropicMaxTokens é usado quando CompletionRequest.MaxTokens não é
+// informado, já que max_tokens é obrigatório em /v1/messages (diferente da
+// OpenAI, que o trata como opcional).
+const defaultAnthropicMaxTokens = 4096
+
+type anthropicContentBlock struct {
+	Type  string                 `json:"type"`
+	Text  string                 `json:"text,omitempty"`
+	ID    string                 `json:"id,omitempty"`
+	Name  string                 `json:"name,omitempty"`
+	Input map[string]interface{} `json:"input,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string                  `json:"role"`
+	Content []anthropicContentBlock `json:"content"`
+}
+
+// anthropicTool espelha o formato de ferramenta da Anthropic, que usa
+// input_schema onde o CompletionRequest genérico (e a OpenAI) usam
+// function.parameters — ver toAnthropicTool.
+type anthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema,omitempty"`
+}
+
+// anthropicToolChoice espelha tool_choice da Anthropic: {"type": "auto"},
+// {"type": "any"} (equivalente ao "required" da OpenAI) ou
+// {"type": "tool", "name": "..."} para forçar uma ferramenta específica.
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+// toAnthropicTool converte um Tool genérico (formato OpenAI) para o formato
+// da Anthropic.
+func toAnthropicTool(t Tool) anthropicTool {
+	return anthropicTool{
+		Name:        t.Function.Name,
+		Description: t.Function.Description,
+		InputSchema: t.Function.Parameters,
+	}
+}
+
+// toAnthropicToolChoice converte o ToolChoice genérico para o formato da
+// Anthropic — "none" não tem equivalente direto na API de mensagens (que
+// não permite desligar ferramentas já anexadas ao request), então é mapeado
+// para "auto", o comportamento mais próximo disponível.
+func toAnthropicToolChoice(tc *ToolChoice) *anthropicToolChoice {
+	if tc == nil {
+		return nil
+	}
+	switch tc.Type {
+	case "required":
+		return &anthropicToolChoice{Type: "any"}
+	case "function":
+		if tc.Function != nil {
+			return &anthropicToolChoice{Type: "tool", Name: tc.Function.Name}
+		}
+		return &anthropicToolChoice{Type: "auto"}
+	case "none":
+		return &anthropicToolChoice{Type: "auto"}
+	default:
+		return &anthropicToolChoice{Type: "auto"}
+	}
+}
+
+// anthropicRequest espelha o corpo esperado por POST /v1/messages: mensagens
+// viram blocos de conteúdo e a mensagem de sistema sai do array messages
+// para o campo system, como a API exige.
+type anthropicRequest struct {
+	Model         string               `json:"model"`
+	Messages      []anthropicMessage   `json:"messages"`
+	System        string               `json:"system,omitempty"`
+	MaxTokens     int                  `json:"max_tokens"`
+	Temperature   float64              `json:"temperature,omitempty"`
+	TopP          float64              `json:"top_p,omitempty"`
+	Stream        bool                 `json:"stream,omitempty"`
+	StopSequences []string             `json:"stop_sequences,omitempty"`
+	Tools         []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice    *anthropicToolChoice `json:"tool_choice,omitempty"`
+}
+
+// toAnthropicRequest converte um CompletionRequest genérico para o formato
+// de mensagens da Anthropic. Mensagens com Role RoleSystem são concatenadas
+// em System em vez de entrarem no array messages, que a API rejeita.
+// ResponseFormat não tem equivalente na API de mensagens (a Anthropic não
+// aceita um json_schema de saída) e por isso é ignorado aqui — quem precisa
+// de structured output com Anthropic deve descrever o schema no prompt, e
+// validateResponseSchema (ver generate.go) ainda confere o resultado.
+func toAnthropicRequest(req CompletionRequest) anthropicRequest {
+	out := anthropicRequest{
+		Model:         req.Model,
+		MaxTokens:     req.MaxTokens,
+		Temperature:   req.Temperature,
+		TopP:          req.TopP,
+		Stream:        req.Stream,
+		StopSequences: req.Stop,
+		ToolChoice:    toAnthropicToolChoice(req.ToolChoice),
+	}
+	if out.MaxTokens == 0 {
+		out.MaxTokens = defaultAnthropicMaxTokens
+	}
+	for _, t := range req.Tools {
+		out.Tools = append(out.Tools, toAnthropicTool(t))
+	}
+
+	var system []string
+	for _, m := range req.Messages {
+		if m.Role == RoleSystem {
+			system = append(system, m.Content)
+			continue
+		}
+		out.Messages = append(out.Messages, anthropicMessage{
+			Role:    string(m.Role),
+			Content: []anthropicContentBlock{{Type: "text", Text: m.Content}},
+		})
+	}
+	out.System = strings.Join(system, "\n\n")
+
+	return out
+}
+
+// anthropicResponse espelha o corpo de uma resposta não-streaming de
+// POST /v1/messages.
+type anthropicResponse struct {
+	ID         string                  `json:"id"`
+	Model      string                  `json:"model"`
+	StopReason string                  `json:"stop_reason"`
+	Content    []anthropicContentBlock `json:"content"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// toCompletionResponse normaliza a resposta da Anthropic para o formato
+// usado pelo restante do cliente. Blocos "tool_use" viram ToolCall (com
+// Input recodificado como a string JSON que Function.Arguments espera nos
+// outros provedores), na ordem em que aparecem em Content.
+func (a anthropicResponse) toCompletionResponse() *CompletionResponse {
+	var text strings.Builder
+	var toolCalls []ToolCall
+	for _, block := range a.Content {
+		switch block.Type {
+		case "tool_use":
+			args, err := json.Marshal(block.Input)
+			if err != nil {
+				args = []byte("{}")
+			}
+			toolCalls = append(toolCalls, ToolCall{
+				ID:   block.ID,
+				Type: "function",
+				Function: Function{
+					Name:      block.Name,
+					Arguments: string(args),
+				},
+			})
+		default:
+			text.WriteString(block.Text)
+		}
+	}
+
+	return &CompletionResponse{
+		ID:    a.ID,
+		Model: a.Model,
+		Choices: []Choice{{
+			Message:      &Message{Role: RoleAssistant, Content: text.String(), ToolCalls: toolCalls},
+			FinishReason: a.StopReason,
+		}},
+		Usage: Usage{
+			PromptTokens:     a.Usage.InputTokens,
+			CompletionTokens: a.Usage.OutputTokens,
+			TotalTokens:      a.Usage.InputTokens + a.Usage.OutputTokens,
+		},
+	}
+}
+
+// anthropicStreamEvent espelha os eventos de um stream SSE de /v1/messages:
+// message_start carrega o id/modelo/uso inicial, content_block_start abre um
+// bloco (texto ou tool_use) identificado por Index, content_block_delta
+// carrega o texto incremental ou (delta.type "input_json_delta") um
+// fragmento de Function.Arguments do tool_use aberto em Index, e
+// message_delta carrega o motivo de parada e o uso final. message_stop
+// encerra o stream e não carrega payload útil.
+type anthropicStreamEvent struct {
+	Type  string `json:"type"`
+	Index int    `json:"index"`
+	Delta struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	ContentBlock struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"content_block"`
+	Message struct {
+		ID    string `json:"id"`
+		Model string `json:"model"`
+	} `json:"message"`
+	Usage struct {
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// toStreamChunk normaliza um evento Anthropic para o StreamChunk genérico
+// usado pelo canal de Stream. O segundo retorno é false para eventos que não
+// carregam nenhuma informação relevante para o chamador (ex.: content_block_
+// start de um bloco de texto, que só content_block_delta preenche). Um
+// content_block_start de tool_use, em troca, já carrega ID/Name e por isso
+// vira ele mesmo um ToolCall delta (com Function.Arguments vazio, preenchido
+// pelos input_json_delta seguintes de mesmo Index).
+func (e anthropicStreamEvent) toStreamChunk() (StreamChunk, bool) {
+	switch e.Type {
+	case "message_start":
+		return StreamChunk{ID: e.Message.ID, Model: e.Message.Model}, true
+	case "content_block_start":
+		if e.ContentBlock.Type != "tool_use" {
+			return StreamChunk{}, false
+		}
+		index := e.Index
+		return StreamChunk{
+			Choices: []StreamChoice{{Delta: Message{
+				Role: RoleAssistant,
+				ToolCalls: []ToolCall{{
+					Index: &index,
+					ID:    e.ContentBlock.ID,
+					Type:  "function",
+					Function: Function{
+						Name: e.ContentBlock.Name,
+					},
+				}},
+			}}},
+		}, true
+	case "content_block_delta":
+		if e.Delta.Type == "input_json_delta" {
+			index := e.Index
+			return StreamChunk{
+				Choices: []StreamChoice{{Delta: Message{
+					Role:      RoleAssistant,
+					ToolCalls: []ToolCall{{Index: &index, Function: Function{Arguments: e.Delta.PartialJSON}}},
+				}}},
+			}, true
+		}
+		return StreamChunk{
+			Choices: []StreamChoice{{Delta: Message{Role: RoleAssistant, Content: e.Delta.Text}}},
+		}, true
+	case "message_delta":
+		chunk := StreamChunk{Choices: []StreamChoice{{FinishReason: e.Delta.StopReason}}}
+		if e.Usage.OutputTokens > 0 {
+			chunk.Usage = &Usage{CompletionTokens: e.Usage.OutputTokens}
+		}
+		return chunk, true
+	default:
+		return StreamChunk{}, false
+	}
+}
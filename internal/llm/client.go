@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"math/rand"
 	"net/http"
 	"strings"
@@ -18,29 +19,40 @@ type LLMClient interface {
 }
 
 type Client struct {
-	baseURL         string
-	apiKey          string
-	model           string
-	httpClient      *http.Client
-	defaultHeaders  map[string]string
-	timeout         time.Duration
-	maxRetries      int
-	retryWaitMin    time.Duration
-	retryWaitMax    time.Duration
-	streamingFormat StreamingFormat
-	organization    string
-	betaHeader      string
+	baseURL                 string
+	apiKey                  string
+	model                   string
+	httpClient              *http.Client
+	defaultHeaders          map[string]string
+	timeout                 time.Duration
+	maxRetries              int
+	retryWaitMin            time.Duration
+	retryWaitMax            time.Duration
+	streamingFormat         StreamingFormat
+	streamingFormatExplicit bool
+	organization            string
+	betaHeader              string
+	provider                Provider
+	anthropicVersion        string
+	limiter                 *AdaptiveLimiter
+
+	readDeadline  *deadlineTimer
+	writeDeadline *deadlineTimer
 }
 
 func NewClient(opts ...ClientOption) (*Client, error) {
 	c := &Client{
-		baseURL:         URLOpenAI,
-		httpClient:      http.DefaultClient,
-		timeout:         60 * time.Second,
-		maxRetries:      3,
-		retryWaitMin:    500 * time.Millisecond,
-		retryWaitMax:    30 * time.Second,
-		streamingFormat: StreamingFormatSSE,
+		baseURL:          URLOpenAI,
+		httpClient:       http.DefaultClient,
+		timeout:          60 * time.Second,
+		maxRetries:       3,
+		retryWaitMin:     500 * time.Millisecond,
+		retryWaitMax:     30 * time.Second,
+		streamingFormat:  StreamingFormatSSE,
+		provider:         ProviderOpenAI,
+		anthropicVersion: defaultAnthropicVersion,
+		readDeadline:     newDeadlineTimer(),
+		writeDeadline:    newDeadlineTimer(),
 	}
 
 	for _, opt := range opts {
@@ -60,6 +72,59 @@ func (c *Client) buildURL(path string) string {
 	return base + path
 }
 
+// completionsPath retorna o endpoint de chat do provedor configurado:
+// Anthropic usa /v1/messages, Ollama usa sua API nativa /api/chat, e o
+// restante (OpenAI e compatíveis) usa /v1/chat/completions.
+func (c *Client) completionsPath() string {
+	switch c.provider {
+	case ProviderAnthropic:
+		return "/v1/messages"
+	case ProviderOllama:
+		return "/api/chat"
+	default:
+		return "/v1/chat/completions"
+	}
+}
+
+// marshalCompletionRequest serializa o CompletionRequest genérico no formato
+// esperado pelo provedor configurado. O resultado é um json.RawMessage para
+// que newRequest o envie como está, sem codificá-lo de novo.
+func (c *Client) marshalCompletionRequest(req CompletionRequest) (json.RawMessage, error) {
+	switch c.provider {
+	case ProviderAnthropic:
+		return json.Marshal(toAnthropicRequest(req))
+	case ProviderOllama:
+		return json.Marshal(toOllamaRequest(req))
+	default:
+		return json.Marshal(req)
+	}
+}
+
+// decodeCompletionResponse decodifica o corpo de uma resposta não-streaming
+// no formato do provedor configurado e o normaliza para CompletionResponse.
+func (c *Client) decodeCompletionResponse(body io.Reader) (*CompletionResponse, error) {
+	switch c.provider {
+	case ProviderAnthropic:
+		var resp anthropicResponse
+		if err := json.NewDecoder(body).Decode(&resp); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return resp.toCompletionResponse(), nil
+	case ProviderOllama:
+		var resp OllamaStreamChunk
+		if err := json.NewDecoder(body).Decode(&resp); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return resp.toCompletionResponse(), nil
+	default:
+		var completion CompletionResponse
+		if err := json.NewDecoder(body).Decode(&completion); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return &completion, nil
+	}
+}
+
 func (c *Client) newRequest(ctx context.Context, method, path string, body any) (*http.Request, error) {
 	url := c.buildURL(path)
 
@@ -80,16 +145,23 @@ func (c *Client) newRequest(ctx context.Context, method, path string, body any)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
 
-	if c.apiKey != "" {
-		req.Header.Set("Authorization", "Bearer "+c.apiKey)
-	}
+	if c.provider == ProviderAnthropic {
+		if c.apiKey != "" {
+			req.Header.Set("x-api-key", c.apiKey)
+		}
+		req.Header.Set("anthropic-version", c.anthropicVersion)
+	} else {
+		if c.apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+c.apiKey)
+		}
 
-	if c.organization != "" {
-		req.Header.Set("OpenAI-Organization", c.organization)
-	}
+		if c.organization != "" {
+			req.Header.Set("OpenAI-Organization", c.organization)
+		}
 
-	if c.betaHeader != "" {
-		req.Header.Set("OpenAI-Beta", c.betaHeader)
+		if c.betaHeader != "" {
+			req.Header.Set("OpenAI-Beta", c.betaHeader)
+		}
 	}
 
 	for key, value := range c.defaultHeaders {
@@ -117,6 +189,12 @@ func (c *Client) doRequestWithRetry(ctx context.Context, method, path string, bo
 	var lastErr error
 
 	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		if c.limiter != nil {
+			if err := c.limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+
 		req, err := c.newRequest(ctx, method, path, body)
 		if err != nil {
 			return nil, err
@@ -135,18 +213,32 @@ func (c *Client) doRequestWithRetry(ctx context.Context, method, path string, bo
 			return resp, nil
 		}
 
+		respBody, readErr := io.ReadAll(resp.Body)
 		resp.Body.Close()
+		if readErr != nil {
+			lastErr = fmt.Errorf("failed to read error response: %w", readErr)
+		} else {
+			lastErr = parseAPIError(resp.StatusCode, respBody)
+		}
+
+		if c.limiter != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+			c.limiter.Throttled(resp, respBody)
+		}
+
+		if attempt == c.maxRetries {
+			break
+		}
+
+		retryAfter, ok := parseRetryAfterHeader(resp.Header.Get("Retry-After"))
+		if !ok {
+			retryAfter = c.calculateRetryAfter(attempt)
+		}
 
-		retryAfter := c.calculateRetryAfter(attempt)
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		case <-time.After(retryAfter):
 		}
-		lastErr = &APIError{
-			StatusCode: resp.StatusCode,
-			Message:    "max retries exceeded",
-		}
 	}
 
 	return nil, lastErr
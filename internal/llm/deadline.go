@@ -0,0 +1,111 @@
+package llm
+
+import (
+	"bufio"
+	"sync"
+	"time"
+)
+
+// deadlineTimer implements the read/write deadline pattern used by net.Conn
+// implementations such as gVisor's netstack/gonet: a cancel channel that gets
+// closed when the deadline fires, guarded by a mutex so Set can race safely
+// with a blocked read or write. SetReadDeadline/SetWriteDeadline on Client
+// each wrap one of these so in-flight SSE/NDJSON reads can be bounded
+// per-token, not just per-request via ctx.
+type deadlineTimer struct {
+	mu       sync.Mutex
+	cancelCh chan struct{}
+	timer    *time.Timer
+}
+
+func newDeadlineTimer() *deadlineTimer {
+	return &deadlineTimer{cancelCh: make(chan struct{})}
+}
+
+// set (re)arms the deadline. A zero time.Time disables it.
+func (d *deadlineTimer) set(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+
+	select {
+	case <-d.cancelCh:
+		// O canal anterior já havia disparado; troca por um novo para a
+		// próxima leitura/escrita não ser cancelada imediatamente.
+		d.cancelCh = make(chan struct{})
+	default:
+	}
+
+	if t.IsZero() {
+		d.timer = nil
+		return
+	}
+
+	now := time.Now()
+	if !t.After(now) {
+		close(d.cancelCh)
+		d.timer = nil
+		return
+	}
+
+	cancelCh := d.cancelCh
+	d.timer = time.AfterFunc(t.Sub(now), func() {
+		close(cancelCh)
+	})
+}
+
+func (d *deadlineTimer) channel() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.cancelCh
+}
+
+// timeoutError satisfies the net.Error Timeout() contract so callers can
+// distinguish a deadline from other stream errors with errors.As.
+type timeoutError struct{ msg string }
+
+func (e *timeoutError) Error() string   { return e.msg }
+func (e *timeoutError) Timeout() bool   { return true }
+func (e *timeoutError) Temporary() bool { return true }
+
+var errReadDeadlineExceeded = &timeoutError{msg: "llm: read deadline exceeded"}
+
+// SetReadDeadline bounds how long a single Stream read (one SSE/NDJSON line)
+// may block. A zero value clears any previously set deadline.
+func (c *Client) SetReadDeadline(t time.Time) {
+	c.readDeadline.set(t)
+}
+
+// SetWriteDeadline records a write deadline; a zero value clears it. No
+// request path waits on it: writing the outgoing request body is bounded
+// only by ctx and the Client timeout.
+func (c *Client) SetWriteDeadline(t time.Time) {
+	c.writeDeadline.set(t)
+}
+
+type lineResult struct {
+	line string
+	err  error
+}
+
+// readLineWithDeadline reads a single line, racing the blocking read against
+// the client's read deadline channel. On timeout the underlying goroutine is
+// left to finish against the reader; callers close the response body, which
+// unblocks it.
+func (c *Client) readLineWithDeadline(reader *bufio.Reader) (string, error) {
+	resCh := make(chan lineResult, 1)
+	go func() {
+		line, err := reader.ReadString('\n')
+		resCh <- lineResult{line: line, err: err}
+	}()
+
+	select {
+	case res := <-resCh:
+		return res.line, res.err
+	case <-c.readDeadline.channel():
+		return "", errReadDeadlineExceeded
+	}
+}
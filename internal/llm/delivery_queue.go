@@ -0,0 +1,530 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/PauloHFS/goth/internal/sse"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/time/rate"
+)
+
+var (
+	deliveryEnqueuedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "llm_delivery_enqueued_total",
+		Help: "Total number of requests enqueued on a DeliveryQueue, by host",
+	}, []string{"host"})
+
+	deliverySucceededTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "llm_delivery_succeeded_total",
+		Help: "Total number of DeliveryQueue requests that completed successfully, by host",
+	}, []string{"host"})
+
+	deliveryFailedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "llm_delivery_failed_total",
+		Help: "Total number of DeliveryQueue requests that failed (executed but errored, or rejected by an open circuit breaker), by host",
+	}, []string{"host"})
+
+	deliveryDroppedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "llm_delivery_dropped_total",
+		Help: "Total number of DeliveryQueue requests dropped without executing (context cancelled or target cancelled), by host",
+	}, []string{"host"})
+
+	deliveryQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "llm_delivery_queue_depth",
+		Help: "Current number of requests waiting in a DeliveryQueue host group",
+	}, []string{"host"})
+)
+
+// HostUnhealthyError é devolvido por DeliveryQueue.Enqueue quando o circuit
+// breaker do host está aberto: a requisição é recusada sem nunca chegar a
+// sair pela rede, em vez de esperar na fila atrás de outras que também vão
+// falhar.
+type HostUnhealthyError struct {
+	Host string
+}
+
+func (e *HostUnhealthyError) Error() string {
+	return fmt.Sprintf("llm delivery queue: host %q está indisponível (circuit breaker aberto)", e.Host)
+}
+
+// DeliveryRequest é uma chamada de LLM submetida a uma DeliveryQueue. Host
+// agrupa requisições que compartilham o mesmo worker pool, token bucket e
+// circuit breaker (normalmente o host do Client, ex. "api.openai.com").
+// TargetID identifica a entidade lógica dona da requisição (ex. o ID de uma
+// avaliação) e permite cancelar em bloco via CancelTarget.
+type DeliveryRequest struct {
+	Host     string
+	TargetID string
+	Client   LLMClient
+	Request  CompletionRequest
+}
+
+// DeliveryResult é o resultado de uma DeliveryRequest, entregue pelo canal
+// devolvido por Enqueue.
+type DeliveryResult struct {
+	Response *CompletionResponse
+	Err      error
+}
+
+// DeliveryQueueConfig controla o dimensionamento de cada grupo de workers
+// por host de uma DeliveryQueue.
+type DeliveryQueueConfig struct {
+	WorkersPerHost   int
+	HostRate         rate.Limit
+	HostBurst        int
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	MaxBackoff       time.Duration
+}
+
+var DefaultDeliveryQueueConfig = DeliveryQueueConfig{
+	WorkersPerHost:   4,
+	HostRate:         5,
+	HostBurst:        5,
+	BreakerThreshold: 5,
+	BreakerCooldown:  30 * time.Second,
+	MaxBackoff:       5 * time.Minute,
+}
+
+// DeliveryQueueOption configura aspectos opcionais de NewDeliveryQueue.
+type DeliveryQueueOption func(*DeliveryQueue) error
+
+// WithDeliveryQueueConfig substitui DefaultDeliveryQueueConfig por cfg.
+func WithDeliveryQueueConfig(cfg DeliveryQueueConfig) DeliveryQueueOption {
+	return func(q *DeliveryQueue) error {
+		q.cfg = cfg
+		return nil
+	}
+}
+
+// WithProgressBroker liga a DeliveryQueue a um sse.Broker: transições de
+// estado de cada requisição (enviada, concluída, com erro) passam a também
+// disparar SendEvaluationProgress/Complete/Error para TargetID, desde que
+// TargetID não esteja vazio.
+func WithProgressBroker(broker *sse.Broker) DeliveryQueueOption {
+	return func(q *DeliveryQueue) error {
+		q.broker = broker
+		return nil
+	}
+}
+
+// hostState é o worker pool, token bucket e circuit breaker compartilhados
+// por todas as DeliveryRequest de um mesmo host.
+type hostState struct {
+	host string
+
+	mu             sync.Mutex
+	queue          []*queueItem
+	pausedUntil    time.Time
+	backoffAttempt int
+
+	notify  chan struct{}
+	limiter *rate.Limiter
+	breaker *circuitBreaker
+}
+
+type queueItem struct {
+	ctx      context.Context
+	task     DeliveryRequest
+	resultCh chan DeliveryResult
+}
+
+func (h *hostState) enqueue(item *queueItem) {
+	h.mu.Lock()
+	h.queue = append(h.queue, item)
+	depth := len(h.queue)
+	h.mu.Unlock()
+
+	deliveryQueueDepth.WithLabelValues(h.host).Set(float64(depth))
+
+	select {
+	case h.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (h *hostState) popNext() *queueItem {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.queue) == 0 {
+		return nil
+	}
+
+	item := h.queue[0]
+	h.queue = h.queue[1:]
+	deliveryQueueDepth.WithLabelValues(h.host).Set(float64(len(h.queue)))
+	return item
+}
+
+// cancelTarget remove da fila, sem executar, todos os itens cujo TargetID
+// seja targetID, devolvendo quantos foram removidos.
+func (h *hostState) cancelTarget(targetID string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	remaining := h.queue[:0]
+	dropped := 0
+	for _, item := range h.queue {
+		if item.task.TargetID == targetID {
+			dropped++
+			item.resultCh <- DeliveryResult{Err: context.Canceled}
+			close(item.resultCh)
+			continue
+		}
+		remaining = append(remaining, item)
+	}
+	h.queue = remaining
+
+	deliveryQueueDepth.WithLabelValues(h.host).Set(float64(len(h.queue)))
+	return dropped
+}
+
+// pauseFor suspende a retirada de novos itens da fila deste host por d,
+// usado quando o provedor responde 429 e pede para esperar antes da próxima
+// tentativa.
+func (h *hostState) pauseFor(d time.Duration) {
+	h.mu.Lock()
+	h.pausedUntil = time.Now().Add(d)
+	h.mu.Unlock()
+}
+
+func (h *hostState) waitUntil() time.Time {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.pausedUntil
+}
+
+// nextBackoff calcula o próximo tempo de pausa exponencial (sem Retry-After
+// explícito), capado em maxBackoff, e avança o contador de tentativas do
+// host.
+func (h *hostState) nextBackoff(maxBackoff time.Duration) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	wait := time.Duration(1<<h.backoffAttempt) * time.Second
+	if wait > maxBackoff || wait <= 0 {
+		wait = maxBackoff
+	}
+	h.backoffAttempt++
+	return wait
+}
+
+func (h *hostState) resetBackoff() {
+	h.mu.Lock()
+	h.backoffAttempt = 0
+	h.mu.Unlock()
+}
+
+// breakerState é o estado de um circuitBreaker, no modelo clássico de três
+// estados fechado/aberto/meio-aberto.
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker abre depois de threshold falhas consecutivas de um host,
+// recusando novas requisições (HostUnhealthyError) sem nem tentar a rede.
+// Depois de cooldown, deixa uma única requisição de sonda passar
+// (meio-aberto); se ela for bem, o breaker fecha de novo, senão volta a
+// abrir e reinicia o cooldown.
+type circuitBreaker struct {
+	mu               sync.Mutex
+	state            breakerState
+	threshold        int
+	cooldown         time.Duration
+	failureCount     int
+	openedAt         time.Time
+	halfOpenInFlight bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	if threshold <= 0 {
+		threshold = 5
+	}
+	if cooldown <= 0 {
+		cooldown = 30 * time.Second
+	}
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+func (cb *circuitBreaker) Allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case breakerOpen:
+		if time.Since(cb.openedAt) < cb.cooldown {
+			return false
+		}
+		cb.state = breakerHalfOpen
+		cb.halfOpenInFlight = true
+		return true
+	case breakerHalfOpen:
+		if cb.halfOpenInFlight {
+			return false
+		}
+		cb.halfOpenInFlight = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (cb *circuitBreaker) RecordSuccess() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.state = breakerClosed
+	cb.failureCount = 0
+	cb.halfOpenInFlight = false
+}
+
+func (cb *circuitBreaker) RecordFailure() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state == breakerHalfOpen {
+		cb.state = breakerOpen
+		cb.openedAt = time.Now()
+		cb.halfOpenInFlight = false
+		return
+	}
+
+	cb.failureCount++
+	if cb.failureCount >= cb.threshold {
+		cb.state = breakerOpen
+		cb.openedAt = time.Now()
+	}
+}
+
+// DeliveryQueue desacopla a submissão de uma CompletionRequest da sua
+// execução HTTP: Enqueue devolve um canal de DeliveryResult imediatamente,
+// enquanto um pool de workers por host (agrupados por DeliveryRequest.Host)
+// drena a fila respeitando um token bucket e um circuit breaker próprios de
+// cada host. Um 429 pausa o grupo inteiro do host pelo Retry-After
+// informado (ou um backoff exponencial capado, se o provedor não informar
+// um); 5xx/timeouts repetidos abrem o circuit breaker do host, que passa a
+// recusar novas requisições com HostUnhealthyError até o cooldown passar.
+type DeliveryQueue struct {
+	cfg    DeliveryQueueConfig
+	broker *sse.Broker
+
+	mu     sync.Mutex
+	hosts  map[string]*hostState
+	stopCh chan struct{}
+}
+
+func NewDeliveryQueue(opts ...DeliveryQueueOption) (*DeliveryQueue, error) {
+	q := &DeliveryQueue{
+		cfg:    DefaultDeliveryQueueConfig,
+		hosts:  make(map[string]*hostState),
+		stopCh: make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		if err := opt(q); err != nil {
+			return nil, fmt.Errorf("failed to apply option: %w", err)
+		}
+	}
+
+	return q, nil
+}
+
+func (q *DeliveryQueue) getOrCreateHost(host string) *hostState {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if h, ok := q.hosts[host]; ok {
+		return h
+	}
+
+	h := &hostState{
+		host:    host,
+		notify:  make(chan struct{}, 1),
+		limiter: rate.NewLimiter(q.cfg.HostRate, q.cfg.HostBurst),
+		breaker: newCircuitBreaker(q.cfg.BreakerThreshold, q.cfg.BreakerCooldown),
+	}
+	q.hosts[host] = h
+
+	workers := q.cfg.WorkersPerHost
+	if workers <= 0 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go q.runWorker(h)
+	}
+
+	return h
+}
+
+// Enqueue agenda req para execução assim que o host dela tiver uma vaga e
+// devolve um canal com capacidade 1 onde o resultado chega — o canal é
+// sempre fechado depois de entregar exatamente um DeliveryResult, mesmo
+// quando req é descartada por CancelTarget ou por ctx cancelado antes de
+// rodar.
+func (q *DeliveryQueue) Enqueue(ctx context.Context, req DeliveryRequest) <-chan DeliveryResult {
+	resultCh := make(chan DeliveryResult, 1)
+	deliveryEnqueuedTotal.WithLabelValues(req.Host).Inc()
+
+	host := q.getOrCreateHost(req.Host)
+	host.enqueue(&queueItem{ctx: ctx, task: req, resultCh: resultCh})
+
+	return resultCh
+}
+
+// CancelTarget descarta, sem executar, toda DeliveryRequest ainda na fila
+// com TargetID igual a targetID (ex. quando a avaliação dona delas foi
+// apagada) e devolve quantas foram descartadas. Requisições já em execução
+// não são interrompidas por aqui — cancele o ctx passado a Enqueue para
+// isso.
+func (q *DeliveryQueue) CancelTarget(targetID string) int {
+	q.mu.Lock()
+	hosts := make([]*hostState, 0, len(q.hosts))
+	for _, h := range q.hosts {
+		hosts = append(hosts, h)
+	}
+	q.mu.Unlock()
+
+	dropped := 0
+	for _, h := range hosts {
+		n := h.cancelTarget(targetID)
+		if n > 0 {
+			deliveryDroppedTotal.WithLabelValues(h.host).Add(float64(n))
+			dropped += n
+		}
+	}
+	return dropped
+}
+
+// Shutdown encerra todos os workers de todos os hosts. Itens ainda na fila
+// não são resolvidos — chame antes CancelTarget ou drene os canais
+// pendentes se isso importar ao chamador.
+func (q *DeliveryQueue) Shutdown() {
+	close(q.stopCh)
+}
+
+func (q *DeliveryQueue) runWorker(h *hostState) {
+	for {
+		select {
+		case <-q.stopCh:
+			return
+		case <-h.notify:
+		}
+
+		for {
+			item := h.popNext()
+			if item == nil {
+				break
+			}
+			q.process(h, item)
+		}
+	}
+}
+
+func (q *DeliveryQueue) process(h *hostState, item *queueItem) {
+	ctx := item.ctx
+
+	if err := ctx.Err(); err != nil {
+		deliveryDroppedTotal.WithLabelValues(h.host).Inc()
+		deliverItem(item, DeliveryResult{Err: err})
+		return
+	}
+
+	if !h.breaker.Allow() {
+		deliveryFailedTotal.WithLabelValues(h.host).Inc()
+		deliverItem(item, DeliveryResult{Err: &HostUnhealthyError{Host: h.host}})
+		return
+	}
+
+	if wait := time.Until(h.waitUntil()); wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			deliveryDroppedTotal.WithLabelValues(h.host).Inc()
+			deliverItem(item, DeliveryResult{Err: ctx.Err()})
+			return
+		case <-q.stopCh:
+			return
+		}
+	}
+
+	if err := h.limiter.Wait(ctx); err != nil {
+		deliveryDroppedTotal.WithLabelValues(h.host).Inc()
+		deliverItem(item, DeliveryResult{Err: err})
+		return
+	}
+
+	q.notifyProgress(item.task, "enviando requisição para "+h.host)
+
+	resp, err := item.task.Client.Generate(ctx, item.task.Request)
+	if err != nil {
+		h.handleFailure(err, q.cfg.MaxBackoff)
+		deliveryFailedTotal.WithLabelValues(h.host).Inc()
+		q.notifyError(item.task, err)
+		deliverItem(item, DeliveryResult{Err: err})
+		return
+	}
+
+	h.breaker.RecordSuccess()
+	h.resetBackoff()
+	deliverySucceededTotal.WithLabelValues(h.host).Inc()
+	q.notifyComplete(item.task)
+	deliverItem(item, DeliveryResult{Response: resp})
+}
+
+// handleFailure interpreta err: um RateLimitError pausa o grupo do host
+// inteiro pelo RetryAfter informado (ou por um backoff exponencial capado
+// em maxBackoff, se o provedor não mandou um), e qualquer erro retryável
+// conta como falha para o circuit breaker do host.
+func (h *hostState) handleFailure(err error, maxBackoff time.Duration) {
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		wait := rateLimitErr.RetryAfter
+		if wait > 0 {
+			h.resetBackoff()
+		} else {
+			wait = h.nextBackoff(maxBackoff)
+		}
+		h.pauseFor(wait)
+		h.breaker.RecordFailure()
+		return
+	}
+
+	if IsRetryableError(err) {
+		h.breaker.RecordFailure()
+	}
+}
+
+func (q *DeliveryQueue) notifyProgress(task DeliveryRequest, message string) {
+	if q.broker == nil || task.TargetID == "" {
+		return
+	}
+	q.broker.SendEvaluationProgress(task.TargetID, "llm_delivery", 0, 0, "<p>"+message+"</p>")
+}
+
+func (q *DeliveryQueue) notifyComplete(task DeliveryRequest) {
+	if q.broker == nil || task.TargetID == "" {
+		return
+	}
+	q.broker.SendEvaluationComplete(task.TargetID, "<p>requisição LLM concluída</p>")
+}
+
+func (q *DeliveryQueue) notifyError(task DeliveryRequest, err error) {
+	if q.broker == nil || task.TargetID == "" {
+		return
+	}
+	q.broker.SendEvaluationError(task.TargetID, fmt.Sprintf("<p>falha na requisição LLM: %s</p>", err))
+}
+
+func deliverItem(item *queueItem, result DeliveryResult) {
+	item.resultCh <- result
+	close(item.resultCh)
+}
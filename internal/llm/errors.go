@@ -18,6 +18,7 @@ var (
 	ErrStreamClosed    = errors.New("stream closed")
 	ErrMaxRetries      = errors.New("max retries exceeded")
 	ErrStreamingFormat = errors.New("invalid streaming format")
+	ErrInvalidProvider = errors.New("invalid provider")
 )
 
 type APIErrorResponse struct {
@@ -139,6 +140,32 @@ func parseRetryAfter(body string) time.Duration {
 	return 0
 }
 
+// parseRetryAfterHeader interpreta o cabeçalho HTTP Retry-After (RFC 7231
+// §7.1.3), que pode vir como um número de segundos ou como uma data no
+// formato HTTP. Retorna ok=false quando o cabeçalho está ausente ou é
+// inválido, caso em que o chamador deve recorrer ao backoff exponencial.
+func parseRetryAfterHeader(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
 func IsRateLimitError(err error) bool {
 	var rateLimitErr *RateLimitError
 	return errors.As(err, &rateLimitErr)
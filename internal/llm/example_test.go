@@ -2,11 +2,27 @@
 //
 // # Supported Providers
 //
-// The client is compatible with any OpenAI-compatible API including:
+// By default the client speaks the OpenAI-compatible chat completions
+// format, which also covers OpenRouter and Ollama's OpenAI-compatible
+// endpoint:
 //
 //   - OpenAI (https://api.openai.com)
 //   - OpenRouter (https://openrouter.ai/api)
-//   - Ollama (http://localhost:11434/v1)
+//   - Ollama, OpenAI-compatible mode (http://localhost:11434/v1)
+//
+// WithProvider switches the request shaping, headers and stream decoding to
+// a provider's native API instead:
+//
+//	client, err := llm.NewClient(
+//	    llm.WithProvider(llm.ProviderAnthropic),
+//	    llm.WithAPIKey("sk-ant-..."),
+//	    llm.WithModel("claude-3-5-sonnet-latest"),
+//	)
+//
+//   - Anthropic (llm.ProviderAnthropic): POST /v1/messages with an
+//     anthropic-version header and content: [{type, text}] blocks.
+//   - Ollama (llm.ProviderOllama): POST /api/chat, NDJSON streaming.
+//   - OpenAI (llm.ProviderOpenAI): the default described above.
 //
 // # Quick Start
 //
@@ -2,12 +2,19 @@ package llm
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 )
 
+// Complete is an alias for Generate kept for callers that prefer the more
+// generic verb. Both are bounded by ctx and the Client timeout only:
+// SetReadDeadline applies to Stream reads, and SetWriteDeadline is not
+// consulted.
+func (c *Client) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	return c.Generate(ctx, req)
+}
+
 func (c *Client) Generate(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
 	if ctx == nil {
 		return nil, ErrNilContext
@@ -23,12 +30,12 @@ func (c *Client) Generate(ctx context.Context, req CompletionRequest) (*Completi
 
 	req.Stream = false
 
-	body, err := json.Marshal(req)
+	body, err := c.marshalCompletionRequest(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	resp, err := c.doRequestWithRetry(ctx, http.MethodPost, "/v1/chat/completions", body)
+	resp, err := c.doRequestWithRetry(ctx, http.MethodPost, c.completionsPath(), body)
 	if err != nil {
 		return nil, err
 	}
@@ -42,10 +49,29 @@ func (c *Client) Generate(ctx context.Context, req CompletionRequest) (*Completi
 		return nil, parseAPIError(resp.StatusCode, respBody)
 	}
 
-	var completion CompletionResponse
-	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	completion, err := c.decodeCompletionResponse(resp.Body)
+	if err != nil {
+		return nil, err
 	}
 
-	return &completion, nil
+	if err := validateCompletionResponse(req, completion); err != nil {
+		return nil, err
+	}
+
+	return completion, nil
+}
+
+// validateCompletionResponse roda validateResponseSchema sobre o conteúdo da
+// primeira choice quando req.ResponseFormat pede "json_schema" — nenhum dos
+// três provedores garante isso no servidor (Anthropic/Ollama nem conhecem
+// response_format, ver toAnthropicRequest/toOllamaRequest), então o cliente
+// confere antes de devolver a resposta ao chamador.
+func validateCompletionResponse(req CompletionRequest, resp *CompletionResponse) error {
+	if req.ResponseFormat == nil || req.ResponseFormat.Type != "json_schema" || req.ResponseFormat.JSONSchema == nil {
+		return nil
+	}
+	if len(resp.Choices) == 0 || resp.Choices[0].Message == nil {
+		return nil
+	}
+	return validateResponseSchema(resp.Choices[0].Message.Content, req.ResponseFormat.JSONSchema.Schema)
 }
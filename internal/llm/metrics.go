@@ -31,6 +31,16 @@ var (
 		Name: "llm_tokens_total",
 		Help: "Total number of tokens used",
 	}, []string{"method", "model", "token_type"})
+
+	llmRateLimitCurrent = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "llm_adaptive_rate_limit_current",
+		Help: "Current requests/second allowed by the client's AdaptiveLimiter, if configured",
+	})
+
+	llmRateLimitHitsTotal = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "llm_adaptive_rate_limit_hits_total",
+		Help: "Total number of 429/503 responses observed by the client's AdaptiveLimiter",
+	})
 )
 
 type MetricsCollector struct{}
@@ -151,6 +161,22 @@ func (c *Client) WithMetrics() *TracedClient {
 	return NewTracedClient(c)
 }
 
+// RateLimitStats expõe o estado do AdaptiveLimiter do client (requisições/s
+// atual, até quando está pausado e quantos 429/503 já foram vistos) e
+// atualiza as métricas Prometheus correspondentes. O segundo retorno é false
+// quando o client não foi configurado com WithAdaptiveRateLimit.
+func (t *TracedClient) RateLimitStats() (RateLimitStats, bool) {
+	if t.client.limiter == nil {
+		return RateLimitStats{}, false
+	}
+
+	stats := t.client.limiter.Stats()
+	llmRateLimitCurrent.Set(stats.CurrentLimit)
+	llmRateLimitHitsTotal.Set(float64(stats.Count429))
+
+	return stats, true
+}
+
 func classifyError(err error) string {
 	if err == nil {
 		return "none"
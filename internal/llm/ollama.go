@@ -0,0 +1,89 @@
+package llm
+
+import "encoding/json"
+
+// ollamaOptions espelha o subconjunto de parâmetros de geração aceitos pelo
+// campo "options" da API nativa do Ollama (/api/chat); ao contrário da
+// OpenAI, esses parâmetros não ficam no nível superior do corpo.
+type ollamaOptions struct {
+	Temperature float64  `json:"temperature,omitempty"`
+	TopP        float64  `json:"top_p,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+}
+
+type ollamaRequest struct {
+	Model    string        `json:"model"`
+	Messages []Message     `json:"messages"`
+	Stream   bool          `json:"stream"`
+	Options  ollamaOptions `json:"options,omitempty"`
+	// Tools usa o mesmo formato {type: "function", function: {...}} da
+	// OpenAI — ao contrário de Anthropic, o /api/chat do Ollama aceita
+	// Tool/ToolCall sem tradução nenhuma.
+	Tools []Tool `json:"tools,omitempty"`
+	// Format é "json" para ResponseFormat.Type "json_object", ou o próprio
+	// JSON Schema (não um envelope {type, json_schema: {...}} como a
+	// OpenAI) para ResponseFormat.Type "json_schema" — ver
+	// toOllamaResponseFormat.
+	Format json.RawMessage `json:"format,omitempty"`
+}
+
+// toOllamaRequest converte um CompletionRequest genérico para o corpo
+// esperado por POST /api/chat.
+func toOllamaRequest(req CompletionRequest) ollamaRequest {
+	return ollamaRequest{
+		Model:    req.Model,
+		Messages: req.Messages,
+		Stream:   req.Stream,
+		Options: ollamaOptions{
+			Temperature: req.Temperature,
+			TopP:        req.TopP,
+			Stop:        req.Stop,
+		},
+		Tools:  req.Tools,
+		Format: toOllamaResponseFormat(req.ResponseFormat),
+	}
+}
+
+// toOllamaResponseFormat converte ResponseFormat para o campo "format" do
+// Ollama: "json_object" vira a string JSON "json", e "json_schema" vira o
+// JSON Schema cru (sem o envelope response_format/json_schema da OpenAI,
+// que o Ollama não entende). nil (sem ResponseFormat) devolve nil, omitindo
+// o campo.
+func toOllamaResponseFormat(rf *ResponseFormat) json.RawMessage {
+	if rf == nil {
+		return nil
+	}
+	switch rf.Type {
+	case "json_object":
+		return json.RawMessage(`"json"`)
+	case "json_schema":
+		if rf.JSONSchema == nil || rf.JSONSchema.Schema == nil {
+			return json.RawMessage(`"json"`)
+		}
+		encoded, err := json.Marshal(rf.JSONSchema.Schema)
+		if err != nil {
+			return json.RawMessage(`"json"`)
+		}
+		return encoded
+	default:
+		return nil
+	}
+}
+
+// toCompletionResponse normaliza uma resposta não-streaming de /api/chat
+// (que usa o mesmo formato de objeto único dos chunks de stream, só que com
+// done=true) para o CompletionResponse genérico.
+func (o *OllamaStreamChunk) toCompletionResponse() *CompletionResponse {
+	return &CompletionResponse{
+		Model: o.Model,
+		Choices: []Choice{{
+			Message:      &o.Message,
+			FinishReason: o.DoneReason,
+		}},
+		Usage: Usage{
+			PromptTokens:     o.PromptEvalCount,
+			CompletionTokens: o.EvalCount,
+			TotalTokens:      o.PromptEvalCount + o.EvalCount,
+		},
+	}
+}
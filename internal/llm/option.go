@@ -2,20 +2,35 @@ package llm
 
 import (
 	"net/http"
+	"strings"
 	"time"
 )
 
 type StreamingFormat string
 
 const (
-	StreamingFormatSSE    StreamingFormat = "sse"
-	StreamingFormatNDJSON StreamingFormat = "ndjson"
+	StreamingFormatSSE       StreamingFormat = "sse"
+	StreamingFormatNDJSON    StreamingFormat = "ndjson"
+	StreamingFormatAnthropic StreamingFormat = "anthropic"
+	StreamingFormatGemini    StreamingFormat = "gemini"
 )
 
 const (
 	URLOpenAI     = "https://api.openai.com"
 	URLOpenRouter = "https://openrouter.ai/api"
 	URLOllama     = "http://localhost:11434/v1"
+	URLAnthropic  = "https://api.anthropic.com"
+)
+
+// Provider seleciona como o Client monta a requisição (caminho, cabeçalhos e
+// corpo) e decodifica a resposta/stream, já que Anthropic, OpenAI e Ollama
+// divergem nesses três pontos apesar de todos exporem um chat "compatível".
+type Provider string
+
+const (
+	ProviderOpenAI    Provider = "openai"
+	ProviderAnthropic Provider = "anthropic"
+	ProviderOllama    Provider = "ollama"
 )
 
 type ClientOption func(*Client) error
@@ -101,14 +116,18 @@ func WithRetryWaitRange(min, max time.Duration) ClientOption {
 	}
 }
 
+// WithStreamingFormat fixa o StreamParser usado por Stream, desligando a
+// auto-detecção por Content-Type que NewClient deixa ligada por padrão
+// (WithProvider também a desliga).
 func WithStreamingFormat(format StreamingFormat) ClientOption {
 	return func(c *Client) error {
 		switch format {
-		case StreamingFormatSSE, StreamingFormatNDJSON:
+		case StreamingFormatSSE, StreamingFormatNDJSON, StreamingFormatAnthropic, StreamingFormatGemini:
 			c.streamingFormat = format
 		default:
 			c.streamingFormat = StreamingFormatSSE
 		}
+		c.streamingFormatExplicit = true
 		return nil
 	}
 }
@@ -126,3 +145,54 @@ func WithBetaHeader(version string) ClientOption {
 		return nil
 	}
 }
+
+// WithProvider seleciona o provedor e ajusta a baseURL e o formato de stream
+// padrão de acordo (Anthropic: SSE em api.anthropic.com; Ollama: NDJSON em
+// localhost:11434; OpenAI: SSE em api.openai.com). Para apontar a um proxy ou
+// gateway compatível, chame WithBaseURL depois de WithProvider para
+// sobrescrever o host default.
+func WithProvider(provider Provider) ClientOption {
+	return func(c *Client) error {
+		switch provider {
+		case ProviderAnthropic:
+			c.provider = provider
+			c.baseURL = URLAnthropic
+			c.streamingFormat = StreamingFormatAnthropic
+		case ProviderOllama:
+			c.provider = provider
+			c.baseURL = strings.TrimSuffix(URLOllama, "/v1")
+			c.streamingFormat = StreamingFormatNDJSON
+		case ProviderOpenAI:
+			c.provider = provider
+			c.baseURL = URLOpenAI
+			c.streamingFormat = StreamingFormatSSE
+		default:
+			return ErrInvalidProvider
+		}
+		c.streamingFormatExplicit = true
+		return nil
+	}
+}
+
+// WithAdaptiveRateLimit liga um AdaptiveLimiter compartilhado por Generate,
+// Stream e Embed: rps e burst definem o token bucket normal, e a taxa é
+// reduzida automaticamente por um tempo sempre que o provedor responder
+// 429/503, em vez de só aplicar o Retry-After no retry pontual.
+func WithAdaptiveRateLimit(rps float64, burst int) ClientOption {
+	return func(c *Client) error {
+		c.limiter = NewAdaptiveLimiter(rps, burst)
+		return nil
+	}
+}
+
+// WithAnthropicVersion sobrescreve o valor padrão do cabeçalho
+// anthropic-version exigido pela API de mensagens da Anthropic.
+func WithAnthropicVersion(version string) ClientOption {
+	return func(c *Client) error {
+		if version == "" {
+			return nil
+		}
+		c.anthropicVersion = version
+		return nil
+	}
+}
@@ -0,0 +1,122 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// AdaptiveLimiter é um rate.Limiter que se ajusta sozinho quando o provedor
+// responde 429/503: em vez de só honrar o Retry-After num retry pontual (o
+// que doRequestWithRetry já fazia), ele pausa novas requisições até o fim da
+// janela informada e reduz a taxa permitida logo em seguida, para não voltar
+// a bater no mesmo limite de quota no primeiro request após a pausa. É
+// compartilhado entre Generate, Stream e Embed de um mesmo Client.
+type AdaptiveLimiter struct {
+	mu          sync.Mutex
+	limiter     *rate.Limiter
+	baseRate    rate.Limit
+	pausedUntil time.Time
+	count429    int64
+}
+
+// NewAdaptiveLimiter cria um limiter com a taxa (requisições por segundo) e
+// burst informados.
+func NewAdaptiveLimiter(rps float64, burst int) *AdaptiveLimiter {
+	r := rate.Limit(rps)
+	return &AdaptiveLimiter{
+		limiter:  rate.NewLimiter(r, burst),
+		baseRate: r,
+	}
+}
+
+// Wait bloqueia até que o limiter libere uma nova requisição, respeitando
+// tanto o token bucket quanto uma eventual pausa em vigor por causa de um
+// 429/503 recente.
+func (a *AdaptiveLimiter) Wait(ctx context.Context) error {
+	a.mu.Lock()
+	pausedUntil := a.pausedUntil
+	a.mu.Unlock()
+
+	if wait := time.Until(pausedUntil); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+
+	return a.limiter.Wait(ctx)
+}
+
+// Throttled registra uma resposta 429/503: interpreta o Retry-After (cabeçalho
+// HTTP, segundos ou data) e, na ausência dele, hints no corpo JSON como
+// {"error":{"retry_after":N}}, pausa novas requisições até o fim dessa janela
+// e reduz a taxa do limiter pela metade até lá — restaurada automaticamente
+// quando a pausa termina.
+func (a *AdaptiveLimiter) Throttled(resp *http.Response, body []byte) {
+	atomic.AddInt64(&a.count429, 1)
+
+	retryAfter, ok := parseRetryAfterHeader(resp.Header.Get("Retry-After"))
+	if !ok {
+		retryAfter, ok = parseRetryAfterBody(body)
+	}
+	if !ok || retryAfter <= 0 {
+		retryAfter = time.Minute
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.pausedUntil = time.Now().Add(retryAfter)
+	reduced := a.baseRate / 2
+	a.limiter.SetLimit(reduced)
+
+	time.AfterFunc(retryAfter, func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.limiter.SetLimit(a.baseRate)
+	})
+}
+
+// parseRetryAfterBody procura por um hint de retry-after no corpo JSON de um
+// erro, no formato {"error":{"retry_after":N}} (N em segundos), usado por
+// alguns provedores quando o cabeçalho Retry-After não está presente.
+func parseRetryAfterBody(body []byte) (time.Duration, bool) {
+	var hint struct {
+		Error struct {
+			RetryAfter float64 `json:"retry_after"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &hint); err != nil {
+		return 0, false
+	}
+	if hint.Error.RetryAfter <= 0 {
+		return 0, false
+	}
+	return time.Duration(hint.Error.RetryAfter * float64(time.Second)), true
+}
+
+// RateLimitStats resume o estado atual do AdaptiveLimiter para observabilidade.
+type RateLimitStats struct {
+	CurrentLimit float64
+	PausedUntil  time.Time
+	Count429     int64
+}
+
+// Stats devolve um snapshot do estado do limiter.
+func (a *AdaptiveLimiter) Stats() RateLimitStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return RateLimitStats{
+		CurrentLimit: float64(a.limiter.Limit()),
+		PausedUntil:  a.pausedUntil,
+		Count429:     atomic.LoadInt64(&a.count429),
+	}
+}
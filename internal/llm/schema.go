@@ -0,0 +1,148 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ResponseSchemaError é devolvido por Client.Generate (e por Run, que o
+// chama) quando CompletionRequest.ResponseFormat pede "json_schema" e o
+// conteúdo devolvido pelo provedor não bate com JSONSchema.Schema — o
+// provedor aceitou o pedido mas não garante (ou, no caso de Anthropic/Ollama,
+// nem entende) o contrato, então Generate faz essa checagem do lado do
+// cliente antes de devolver a resposta ao chamador. Stream não a faz.
+type ResponseSchemaError struct {
+	Path    string
+	Message string
+}
+
+func (e *ResponseSchemaError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("response does not match json_schema: %s", e.Message)
+	}
+	return fmt.Sprintf("response does not match json_schema at %s: %s", e.Path, e.Message)
+}
+
+// validateResponseSchema decodifica content como JSON e o confere contra
+// schema. Cobre só o subconjunto de JSON Schema mais comum em structured
+// outputs (type, properties/required, items, enum) — o bastante para pegar o
+// erro mais comum (o modelo devolveu texto solto ou omitiu um campo
+// obrigatório), não um validador completo do spec.
+func validateResponseSchema(content string, schema map[string]interface{}) error {
+	var value interface{}
+	if err := json.Unmarshal([]byte(content), &value); err != nil {
+		return &ResponseSchemaError{Message: fmt.Sprintf("content is not valid JSON: %v", err)}
+	}
+	return validateSchemaNode("", value, schema)
+}
+
+func validateSchemaNode(path string, value interface{}, schema map[string]interface{}) error {
+	if schema == nil {
+		return nil
+	}
+
+	if rawType, ok := schema["type"]; ok {
+		if typeName, ok := rawType.(string); ok {
+			if err := checkJSONType(path, value, typeName); err != nil {
+				return err
+			}
+		}
+	}
+
+	if rawEnum, ok := schema["enum"].([]interface{}); ok {
+		if !enumContains(rawEnum, value) {
+			return &ResponseSchemaError{Path: path, Message: "value is not one of the allowed enum values"}
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, req := range stringSlice(schema["required"]) {
+			if _, ok := v[req]; !ok {
+				return &ResponseSchemaError{Path: joinPath(path, req), Message: "required property is missing"}
+			}
+		}
+		if properties, ok := schema["properties"].(map[string]interface{}); ok {
+			for key, propSchema := range properties {
+				propValue, present := v[key]
+				if !present {
+					continue
+				}
+				propSchemaMap, ok := propSchema.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if err := validateSchemaNode(joinPath(path, key), propValue, propSchemaMap); err != nil {
+					return err
+				}
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				if err := validateSchemaNode(fmt.Sprintf("%s[%d]", path, i), item, items); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func checkJSONType(path string, value interface{}, typeName string) error {
+	ok := false
+	switch typeName {
+	case "object":
+		_, ok = value.(map[string]interface{})
+	case "array":
+		_, ok = value.([]interface{})
+	case "string":
+		_, ok = value.(string)
+	case "number":
+		_, ok = value.(float64)
+	case "integer":
+		n, isNumber := value.(float64)
+		ok = isNumber && n == float64(int64(n))
+	case "boolean":
+		_, ok = value.(bool)
+	case "null":
+		ok = value == nil
+	default:
+		return nil
+	}
+	if !ok {
+		return &ResponseSchemaError{Path: path, Message: fmt.Sprintf("expected type %q", typeName)}
+	}
+	return nil
+}
+
+func enumContains(enum []interface{}, value interface{}) bool {
+	for _, candidate := range enum {
+		if fmt.Sprint(candidate) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func stringSlice(raw interface{}) []string {
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil
+	}
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
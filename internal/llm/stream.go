@@ -2,14 +2,12 @@ package llm
 
 import (
 	"bufio"
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
-	"unicode/utf8"
 )
 
 type StreamChunkWithError struct {
@@ -33,20 +31,26 @@ func (c *Client) Stream(ctx context.Context, req CompletionRequest) (<-chan Stre
 
 	req.Stream = true
 
-	body, err := json.Marshal(req)
+	body, err := c.marshalCompletionRequest(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	reqHTTP, err := c.newRequest(ctx, http.MethodPost, "/v1/chat/completions", body)
+	reqHTTP, err := c.newRequest(ctx, http.MethodPost, c.completionsPath(), body)
 	if err != nil {
 		return nil, err
 	}
 
-	if c.streamingFormat == StreamingFormatSSE {
-		reqHTTP.Header.Set("Accept", "text/event-stream")
-	} else {
+	if c.streamingFormat == StreamingFormatNDJSON {
 		reqHTTP.Header.Set("Accept", "application/x-ndjson")
+	} else {
+		reqHTTP.Header.Set("Accept", "text/event-stream")
+	}
+
+	if c.limiter != nil {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
 	}
 
 	resp, err := c.doRequest(reqHTTP)
@@ -59,16 +63,31 @@ func (c *Client) Stream(ctx context.Context, req CompletionRequest) (<-chan Stre
 		if readErr != nil {
 			return nil, fmt.Errorf("failed to read error response: %w", readErr)
 		}
+		if c.limiter != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+			c.limiter.Throttled(resp, respBody)
+		}
 		return nil, parseAPIError(resp.StatusCode, respBody)
 	}
 
+	format := c.streamingFormat
+	if !c.streamingFormatExplicit {
+		if detected, ok := detectStreamFormat(resp.Header.Get("Content-Type")); ok {
+			format = detected
+		}
+	}
+
 	ch := make(chan StreamChunk, 1)
 
-	go c.streamReader(ctx, resp.Body, ch, c.streamingFormat)
+	go c.streamReader(ctx, resp.Body, ch, format)
 
 	return ch, nil
 }
 
+// streamReader lê o corpo de uma resposta de streaming linha a linha e
+// delega a interpretação de cada linha ao StreamParser do formato
+// resolvido, normalizando as diferenças entre provedores (SSE no estilo
+// OpenAI, NDJSON do Ollama, eventos nomeados da Anthropic e o array JSON
+// incremental do Gemini) atrás de uma única interface.
 func (c *Client) streamReader(ctx context.Context, body io.Reader, ch chan<- StreamChunk, format StreamingFormat) {
 	defer close(ch)
 
@@ -79,14 +98,8 @@ func (c *Client) streamReader(ctx context.Context, body io.Reader, ch chan<- Str
 		reader = bufio.NewReader(body)
 	}
 
-	if format == StreamingFormatNDJSON {
-		c.readNDJSONStream(ctx, reader, ch)
-	} else {
-		c.readSSEStream(ctx, reader, ch)
-	}
-}
+	parser := newStreamParser(format)
 
-func (c *Client) readSSEStream(ctx context.Context, reader *bufio.Reader, ch chan<- StreamChunk) {
 	for {
 		select {
 		case <-ctx.Done():
@@ -94,85 +107,34 @@ func (c *Client) readSSEStream(ctx context.Context, reader *bufio.Reader, ch cha
 		default:
 		}
 
-		line, err := reader.ReadString('\n')
+		line, err := c.readLineWithDeadline(reader)
 		if err != nil {
-			if err != io.EOF {
-				ch <- StreamChunk{Choices: []StreamChoice{{Delta: Message{Content: ""}}}} // Signal error
+			var timeoutErr *timeoutError
+			if errors.As(err, &timeoutErr) {
+				return
 			}
-			return
-		}
-
-		line = strings.TrimRight(line, "\r\n")
-		line = strings.TrimSpace(line)
-
-		if line == "" {
-			continue
-		}
-
-		if strings.HasPrefix(line, ":") {
-			continue
-		}
-
-		if !strings.HasPrefix(line, "data: ") {
-			continue
-		}
-
-		data := strings.TrimPrefix(line, "data: ")
-		data = strings.TrimSpace(data)
-
-		if data == "[DONE]" {
-			return
-		}
-
-		var chunk StreamChunk
-		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
-			continue
-		}
-
-		select {
-		case ch <- chunk:
-		case <-ctx.Done():
-			return
-		}
-	}
-}
-
-func (c *Client) readNDJSONStream(ctx context.Context, reader *bufio.Reader, ch chan<- StreamChunk) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-		}
-
-		line, err := reader.ReadBytes('\n')
-		if err != nil {
 			if err != io.EOF {
 				ch <- StreamChunk{Choices: []StreamChoice{{Delta: Message{Content: ""}}}}
 			}
 			return
 		}
 
-		line = bytes.TrimRight(line, "\r\n")
-
-		if len(line) == 0 {
-			continue
-		}
+		line = strings.TrimRight(line, "\r\n")
 
-		if !utf8.Valid(line) {
+		chunks, done, err := parser.ParseLine([]byte(line))
+		if err != nil {
 			continue
 		}
 
-		var ollamaChunk OllamaStreamChunk
-		if err := json.Unmarshal(line, &ollamaChunk); err != nil {
-			continue
+		for _, chunk := range chunks {
+			select {
+			case ch <- chunk:
+			case <-ctx.Done():
+				return
+			}
 		}
 
-		chunk := ollamaChunk.ToStreamChunk()
-
-		select {
-		case ch <- chunk:
-		case <-ctx.Done():
+		if done {
 			return
 		}
 	}
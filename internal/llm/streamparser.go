@@ -0,0 +1,187 @@
+package llm
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+)
+
+// StreamParser interpreta uma linha de um corpo de streaming HTTP e a
+// normaliza para zero ou mais StreamChunk, abstraindo as diferenças de
+// formato entre provedores (SSE no estilo OpenAI, NDJSON do Ollama, eventos
+// nomeados da Anthropic e o array JSON incremental do Gemini) atrás de uma
+// única interface usada por streamReader.
+type StreamParser interface {
+	// ParseLine recebe uma linha já sem terminador e devolve os StreamChunk
+	// nela contidos. O segundo retorno sinaliza que o stream terminou
+	// (ex.: "[DONE]" da OpenAI, message_stop da Anthropic ou o "]" de
+	// fechamento do array do Gemini).
+	ParseLine(line []byte) ([]StreamChunk, bool, error)
+}
+
+// newStreamParser devolve o StreamParser correspondente a format, recorrendo
+// a sseStreamParser para formatos não reconhecidos.
+func newStreamParser(format StreamingFormat) StreamParser {
+	switch format {
+	case StreamingFormatAnthropic:
+		return anthropicStreamParser{}
+	case StreamingFormatGemini:
+		return geminiStreamParser{}
+	case StreamingFormatNDJSON:
+		return ndjsonStreamParser{}
+	default:
+		return sseStreamParser{}
+	}
+}
+
+// detectStreamFormat tenta inferir o StreamingFormat a partir do cabeçalho
+// Content-Type da resposta, usado quando o chamador não fixou um formato via
+// WithStreamingFormat/WithProvider (ver Client.streamingFormatExplicit).
+func detectStreamFormat(contentType string) (StreamingFormat, bool) {
+	ct := strings.ToLower(contentType)
+	switch {
+	case ct == "":
+		return "", false
+	case strings.Contains(ct, "ndjson") || strings.Contains(ct, "jsonlines"):
+		return StreamingFormatNDJSON, true
+	case strings.Contains(ct, "event-stream"):
+		return StreamingFormatSSE, true
+	case strings.Contains(ct, "json"):
+		return StreamingFormatGemini, true
+	default:
+		return "", false
+	}
+}
+
+// sseStreamParser interpreta o SSE no estilo OpenAI: só linhas "data: ...",
+// terminado pelo sentinela de texto "[DONE]".
+type sseStreamParser struct{}
+
+func (sseStreamParser) ParseLine(line []byte) ([]StreamChunk, bool, error) {
+	l := strings.TrimSpace(string(line))
+	if l == "" || strings.HasPrefix(l, ":") || !strings.HasPrefix(l, "data: ") {
+		return nil, false, nil
+	}
+
+	data := strings.TrimSpace(strings.TrimPrefix(l, "data: "))
+	if data == "[DONE]" {
+		return nil, true, nil
+	}
+
+	var chunk StreamChunk
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		return nil, false, nil
+	}
+	return []StreamChunk{chunk}, false, nil
+}
+
+// ndjsonStreamParser interpreta o NDJSON do Ollama: um OllamaStreamChunk por
+// linha, terminado pelo campo done=true do último chunk.
+type ndjsonStreamParser struct{}
+
+func (ndjsonStreamParser) ParseLine(line []byte) ([]StreamChunk, bool, error) {
+	if len(line) == 0 || !utf8.Valid(line) {
+		return nil, false, nil
+	}
+
+	var ollamaChunk OllamaStreamChunk
+	if err := json.Unmarshal(line, &ollamaChunk); err != nil {
+		return nil, false, nil
+	}
+	return []StreamChunk{ollamaChunk.ToStreamChunk()}, ollamaChunk.Done, nil
+}
+
+// anthropicStreamParser interpreta o stream de /v1/messages. Diferente do
+// SSE da OpenAI, a Anthropic nomeia cada evento em uma linha "event: ..."
+// antes de "data: ...", então essas linhas de evento são ignoradas aqui e o
+// tipo usado para decidir o que fazer é lido de dentro do próprio payload
+// JSON; o stream termina no evento message_stop em vez de um sentinela de
+// texto.
+type anthropicStreamParser struct{}
+
+func (anthropicStreamParser) ParseLine(line []byte) ([]StreamChunk, bool, error) {
+	l := strings.TrimSpace(string(line))
+	if l == "" || strings.HasPrefix(l, "event:") || strings.HasPrefix(l, ":") || !strings.HasPrefix(l, "data: ") {
+		return nil, false, nil
+	}
+
+	data := strings.TrimSpace(strings.TrimPrefix(l, "data: "))
+
+	var event anthropicStreamEvent
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		return nil, false, nil
+	}
+	if event.Type == "message_stop" {
+		return nil, true, nil
+	}
+
+	chunk, ok := event.toStreamChunk()
+	if !ok {
+		return nil, false, nil
+	}
+	return []StreamChunk{chunk}, false, nil
+}
+
+// geminiStreamResponse espelha um elemento do array JSON devolvido por
+// streamGenerateContent: cada item carrega um candidato com o texto
+// incremental em parts e, no último, finishReason e o uso de tokens.
+type geminiStreamResponse struct {
+	Candidates []struct {
+		Content struct {
+			Parts []struct {
+				Text string `json:"text"`
+			} `json:"parts"`
+		} `json:"content"`
+		FinishReason string `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// geminiStreamParser interpreta o array JSON incremental devolvido por
+// streamGenerateContent, onde cada elemento chega em sua própria linha,
+// opcionalmente cercado por "[", "," e "]".
+type geminiStreamParser struct{}
+
+func (geminiStreamParser) ParseLine(line []byte) ([]StreamChunk, bool, error) {
+	l := strings.TrimSpace(string(line))
+	closing := l == "]"
+
+	trimmed := strings.Trim(l, "[],")
+	trimmed = strings.TrimSpace(trimmed)
+	if trimmed == "" {
+		return nil, closing, nil
+	}
+
+	var resp geminiStreamResponse
+	if err := json.Unmarshal([]byte(trimmed), &resp); err != nil {
+		return nil, false, nil
+	}
+	if len(resp.Candidates) == 0 {
+		return nil, false, nil
+	}
+
+	var text strings.Builder
+	for _, part := range resp.Candidates[0].Content.Parts {
+		text.WriteString(part.Text)
+	}
+
+	chunk := StreamChunk{
+		Choices: []StreamChoice{{
+			Delta:        Message{Role: RoleAssistant, Content: text.String()},
+			FinishReason: resp.Candidates[0].FinishReason,
+		}},
+	}
+	if resp.UsageMetadata.TotalTokenCount > 0 {
+		chunk.Usage = &Usage{
+			PromptTokens:     resp.UsageMetadata.PromptTokenCount,
+			CompletionTokens: resp.UsageMetadata.CandidatesTokenCount,
+			TotalTokens:      resp.UsageMetadata.TotalTokenCount,
+		}
+	}
+
+	return []StreamChunk{chunk}, false, nil
+}
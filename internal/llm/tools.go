@@ -0,0 +1,302 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ToolHandler executa uma tool_call registrada a partir dos argumentos JSON
+// devolvidos pelo modelo e retorna o resultado a ser serializado de volta
+// como uma mensagem role:"tool".
+type ToolHandler func(ctx context.Context, args json.RawMessage) (any, error)
+
+type registeredTool struct {
+	schema  FunctionDefinition
+	handler ToolHandler
+}
+
+// ToolRegistry associa nomes de função aos handlers Go que as executam, para
+// que Client.Run/RunStream fechem o loop de function-calling sem o chamador
+// precisar despachar tool_calls manualmente.
+type ToolRegistry struct {
+	mu    sync.RWMutex
+	tools map[string]registeredTool
+}
+
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{tools: make(map[string]registeredTool)}
+}
+
+// RegisterTool registra handler para name, anunciando schema ao modelo em
+// CompletionRequest.Tools sempre que este registry for passado a Run.
+func (r *ToolRegistry) RegisterTool(name string, schema FunctionDefinition, handler ToolHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	schema.Name = name
+	r.tools[name] = registeredTool{schema: schema, handler: handler}
+}
+
+// Tools devolve a lista de Tool anunciada ao modelo para todos os handlers
+// registrados, pronta para CompletionRequest.Tools.
+func (r *ToolRegistry) Tools() []Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tools := make([]Tool, 0, len(r.tools))
+	for _, t := range r.tools {
+		tools = append(tools, Tool{Type: "function", Function: t.schema})
+	}
+	return tools
+}
+
+func (r *ToolRegistry) get(name string) (ToolHandler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.tools[name]
+	if !ok {
+		return nil, false
+	}
+	return t.handler, true
+}
+
+// ErrUnknownToolName é devolvido quando o modelo chama uma tool sem handler
+// registrado em ToolRegistry.
+type ErrUnknownToolName struct {
+	Name string
+}
+
+func (e *ErrUnknownToolName) Error() string {
+	return fmt.Sprintf("llm: unknown tool %q", e.Name)
+}
+
+// ToolCallError descreve a falha ao executar uma tool_call específica,
+// carregando ID e nome para o chamador correlacionar com a mensagem
+// role:"tool" que não pôde ser produzida. Cobre tool desconhecida, erro do
+// handler e panic recuperado.
+type ToolCallError struct {
+	ToolCallID string
+	Name       string
+	Err        error
+}
+
+func (e *ToolCallError) Error() string {
+	return fmt.Sprintf("llm: tool call %s (%s) failed: %v", e.ToolCallID, e.Name, e.Err)
+}
+
+func (e *ToolCallError) Unwrap() error {
+	return e.Err
+}
+
+// ErrStepLimitExceeded é devolvido por Run/RunStream quando o modelo ainda
+// pede tool_calls depois de RunOptions.MaxSteps rodadas.
+var ErrStepLimitExceeded = errors.New("llm: tool-calling step limit exceeded")
+
+const (
+	defaultMaxSteps    = 10
+	defaultToolTimeout = 30 * time.Second
+)
+
+// RunOptions ajusta o loop de execução de tools de Run/RunStream.
+type RunOptions struct {
+	MaxSteps    int
+	ToolTimeout time.Duration
+	// OnStep, se não nil, é chamado ao fim de cada rodada (antes do
+	// despacho das tool_calls) com a resposta bruta do modelo, servindo
+	// como gancho de trace por etapa.
+	OnStep func(step int, resp *CompletionResponse)
+}
+
+func (o RunOptions) withDefaults() RunOptions {
+	if o.MaxSteps <= 0 {
+		o.MaxSteps = defaultMaxSteps
+	}
+	if o.ToolTimeout <= 0 {
+		o.ToolTimeout = defaultToolTimeout
+	}
+	return o
+}
+
+// Run chama Generate repetidamente, despachando cada tool_call devolvida
+// pelo modelo para o handler registrado em registry, anexando os resultados
+// como mensagens role:"tool" e reinvocando o modelo até ele responder com
+// conteúdo (sem tool_calls) ou opts.MaxSteps rodadas se esgotarem.
+func (c *Client) Run(ctx context.Context, req CompletionRequest, registry *ToolRegistry, opts RunOptions) (*CompletionResponse, error) {
+	opts = opts.withDefaults()
+	if registry != nil && req.Tools == nil {
+		req.Tools = registry.Tools()
+	}
+
+	for step := 0; step < opts.MaxSteps; step++ {
+		start := time.Now()
+		resp, err := c.Generate(ctx, req)
+		status := "success"
+		if err != nil {
+			status = "error"
+		}
+		recordRequest("run_step", req.Model, status, time.Since(start))
+		if err != nil {
+			recordError("run_step", req.Model, classifyError(err))
+			return nil, err
+		}
+
+		if opts.OnStep != nil {
+			opts.OnStep(step, resp)
+		}
+
+		if len(resp.Choices) == 0 || resp.Choices[0].Message == nil || len(resp.Choices[0].Message.ToolCalls) == 0 {
+			return resp, nil
+		}
+
+		msg := *resp.Choices[0].Message
+		req.Messages = append(req.Messages, msg)
+
+		toolMessages, err := c.dispatchToolCalls(ctx, registry, msg.ToolCalls, opts.ToolTimeout)
+		if err != nil {
+			return nil, err
+		}
+		req.Messages = append(req.Messages, toolMessages...)
+	}
+
+	return nil, ErrStepLimitExceeded
+}
+
+// RunStream é o equivalente de Run para streaming: cada rodada intermediária
+// é consumida internamente para decidir se o modelo pediu tool_calls, e só
+// os chunks da rodada final (a que não pede mais tools) chegam ao canal
+// devolvido ao chamador.
+func (c *Client) RunStream(ctx context.Context, req CompletionRequest, registry *ToolRegistry, opts RunOptions) (<-chan StreamChunk, error) {
+	opts = opts.withDefaults()
+	if registry != nil && req.Tools == nil {
+		req.Tools = registry.Tools()
+	}
+
+	for step := 0; step < opts.MaxSteps; step++ {
+		start := time.Now()
+		stream, err := c.Stream(ctx, req)
+		if err != nil {
+			recordError("run_step", req.Model, classifyError(err))
+			return nil, err
+		}
+
+		var msg Message
+		msg.Role = RoleAssistant
+		chunks := make([]StreamChunk, 0, 16)
+		toolCallPos := make(map[int]int)
+		for chunk := range stream {
+			chunks = append(chunks, chunk)
+			if len(chunk.Choices) == 0 {
+				continue
+			}
+			delta := chunk.Choices[0].Delta
+			msg.Content += delta.Content
+			for _, tc := range delta.ToolCalls {
+				if tc.Index != nil {
+					if pos, ok := toolCallPos[*tc.Index]; ok {
+						msg.ToolCalls[pos].Function.Arguments += tc.Function.Arguments
+						continue
+					}
+					toolCallPos[*tc.Index] = len(msg.ToolCalls)
+				}
+				msg.ToolCalls = append(msg.ToolCalls, tc)
+			}
+		}
+		recordRequest("run_step", req.Model, "success", time.Since(start))
+
+		if opts.OnStep != nil {
+			opts.OnStep(step, &CompletionResponse{Choices: []Choice{{Message: &msg}}})
+		}
+
+		if len(msg.ToolCalls) == 0 {
+			out := make(chan StreamChunk, len(chunks))
+			for _, chunk := range chunks {
+				out <- chunk
+			}
+			close(out)
+			return out, nil
+		}
+
+		req.Messages = append(req.Messages, msg)
+
+		toolMessages, err := c.dispatchToolCalls(ctx, registry, msg.ToolCalls, opts.ToolTimeout)
+		if err != nil {
+			return nil, err
+		}
+		req.Messages = append(req.Messages, toolMessages...)
+	}
+
+	return nil, ErrStepLimitExceeded
+}
+
+// dispatchToolCalls roda calls concorrentemente, cada uma com um timeout
+// próprio, e devolve as mensagens role:"tool" resultantes na mesma ordem.
+func (c *Client) dispatchToolCalls(ctx context.Context, registry *ToolRegistry, calls []ToolCall, timeout time.Duration) ([]Message, error) {
+	results := make([]Message, len(calls))
+	errs := make([]error, len(calls))
+
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		wg.Add(1)
+		go func(i int, call ToolCall) {
+			defer wg.Done()
+			results[i], errs[i] = c.runToolCall(ctx, registry, call, timeout)
+		}(i, call)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+func (c *Client) runToolCall(ctx context.Context, registry *ToolRegistry, call ToolCall, timeout time.Duration) (Message, error) {
+	if registry == nil {
+		return Message{}, &ToolCallError{ToolCallID: call.ID, Name: call.Function.Name, Err: &ErrUnknownToolName{Name: call.Function.Name}}
+	}
+
+	handler, ok := registry.get(call.Function.Name)
+	if !ok {
+		return Message{}, &ToolCallError{ToolCallID: call.ID, Name: call.Function.Name, Err: &ErrUnknownToolName{Name: call.Function.Name}}
+	}
+
+	toolCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type callResult struct {
+		value any
+		err   error
+	}
+	resultCh := make(chan callResult, 1)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				resultCh <- callResult{err: fmt.Errorf("tool handler panicked: %v", r)}
+			}
+		}()
+		value, err := handler(toolCtx, json.RawMessage(call.Function.Arguments))
+		resultCh <- callResult{value: value, err: err}
+	}()
+
+	select {
+	case <-toolCtx.Done():
+		return Message{}, &ToolCallError{ToolCallID: call.ID, Name: call.Function.Name, Err: toolCtx.Err()}
+	case res := <-resultCh:
+		if res.err != nil {
+			return Message{}, &ToolCallError{ToolCallID: call.ID, Name: call.Function.Name, Err: res.err}
+		}
+		encoded, err := json.Marshal(res.value)
+		if err != nil {
+			return Message{}, &ToolCallError{ToolCallID: call.ID, Name: call.Function.Name, Err: fmt.Errorf("failed to encode tool result: %w", err)}
+		}
+		return Message{Role: RoleTool, ToolCallID: call.ID, Name: call.Function.Name, Content: string(encoded)}, nil
+	}
+}
@@ -105,6 +105,12 @@ type FunctionDefinition struct {
 }
 
 type ToolCall struct {
+	// Index identifica a posição do tool call dentro da mensagem quando ele
+	// chega fatiado em vários StreamChoice.Delta (cada fragmento de
+	// Function.Arguments deve ser concatenado aos fragmentos anteriores de
+	// mesmo Index) — ausente (nil) em respostas não-streaming, onde
+	// ToolCall já vem completo.
+	Index    *int     `json:"index,omitempty"`
 	ID       string   `json:"id"`
 	Type     string   `json:"type"`
 	Function Function `json:"function"`
@@ -133,6 +139,7 @@ type JSONSchema struct {
 	Name        string                 `json:"name,omitempty"`
 	Description string                 `json:"description,omitempty"`
 	Schema      map[string]interface{} `json:"schema"`
+	Strict      bool                   `json:"strict,omitempty"`
 }
 
 type EmbeddingRequest struct {
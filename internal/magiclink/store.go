@@ -0,0 +1,145 @@
+// Package magiclink implementa login sem senha por link de uso único
+// enviado por e-mail, como alternativa ao fluxo de senha em
+// internal/web/handlers.go (handleLogin).
+package magiclink
+
+import (
+	"context"
+	crypto_rand "crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// TTL é a validade de um link mágico — curta o bastante para reduzir a
+// janela de um link vazado, mas suficiente para o usuário checar o e-mail.
+const TTL = 15 * time.Minute
+
+// Link representa uma linha da tabela magic_links.
+type Link struct {
+	TokenHash  string
+	Email      string
+	OriginHash string
+	ExpiresAt  time.Time
+	ConsumedAt sql.NullTime
+	CreatedAt  time.Time
+}
+
+// Expired reporta se o link já passou do TTL.
+func (l Link) Expired(now time.Time) bool {
+	return now.After(l.ExpiresAt)
+}
+
+// Store grava e consome links mágicos de login.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore cria um Store sobre a conexão de escrita do banco.
+func NewStore(dbConn *sql.DB) *Store {
+	return &Store{db: dbConn}
+}
+
+// EnsureTable cria a tabela magic_links se ainda não existir. Assim como
+// internal/session.Store.EnsureTable, fica fora do fluxo normal de
+// db.RunMigrations até que uma migração dedicada seja escrita.
+func (s *Store) EnsureTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS magic_links (
+			token_hash TEXT PRIMARY KEY,
+			email TEXT NOT NULL,
+			origin_hash TEXT NOT NULL,
+			expires_at DATETIME NOT NULL,
+			consumed_at DATETIME,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_magic_links_email ON magic_links(email);
+	`)
+	if err != nil {
+		return fmt.Errorf("falha ao garantir tabela magic_links: %w", err)
+	}
+	return nil
+}
+
+// HashOrigin resume o user-agent e o IP da requisição que solicitou o link,
+// para permitir (quando Config.MagicLinkBindToOrigin estiver habilitado)
+// rejeitar o uso do link a partir de um dispositivo diferente do que o
+// solicitou.
+func HashOrigin(userAgent, ip string) string {
+	sum := sha256.Sum256([]byte(userAgent + "|" + ip))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateParams descreve os dados necessários para emitir um novo link.
+type CreateParams struct {
+	Email      string
+	OriginHash string
+}
+
+// Create gera um token aleatório de 32 bytes e grava apenas o seu hash
+// SHA-256, com validade TTL, mirando o mesmo padrão usado para o token de
+// recuperação de senha (ver handleForgotPassword). O token em texto plano é
+// retornado apenas para ser embutido no link enviado por e-mail — nunca é
+// persistido.
+func (s *Store) Create(ctx context.Context, params CreateParams) (token string, err error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := crypto_rand.Read(tokenBytes); err != nil {
+		return "", fmt.Errorf("falha ao gerar token: %w", err)
+	}
+	token = hex.EncodeToString(tokenBytes)
+	tokenHash := HashToken(token)
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO magic_links (token_hash, email, origin_hash, expires_at)
+		VALUES (?, ?, ?, ?)
+	`, tokenHash, params.Email, params.OriginHash, time.Now().Add(TTL))
+	if err != nil {
+		return "", fmt.Errorf("falha ao gravar link mágico: %w", err)
+	}
+
+	return token, nil
+}
+
+// HashToken resume o token em texto plano do link para o formato persistido
+// na coluna token_hash.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetByTokenHash busca o link associado ao hash do token do link mágico.
+func (s *Store) GetByTokenHash(ctx context.Context, tokenHash string) (Link, error) {
+	var l Link
+	var consumedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, `
+		SELECT token_hash, email, origin_hash, expires_at, consumed_at, created_at
+		FROM magic_links WHERE token_hash = ?
+	`, tokenHash).Scan(&l.TokenHash, &l.Email, &l.OriginHash, &l.ExpiresAt, &consumedAt, &l.CreatedAt)
+	if err != nil {
+		return Link{}, err
+	}
+	l.ConsumedAt = consumedAt
+	return l, nil
+}
+
+// Consume marca o link como usado de forma atômica, retornando
+// sql.ErrNoRows se ele já tiver sido consumido por outra requisição
+// concorrente (mesmo padrão de internal/oauth.AuthorizationStore.Consume).
+func (s *Store) Consume(ctx context.Context, tokenHash string) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE magic_links SET consumed_at = ? WHERE token_hash = ? AND consumed_at IS NULL
+	`, time.Now(), tokenHash)
+	if err != nil {
+		return fmt.Errorf("falha ao consumir link mágico: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("falha ao confirmar consumo do link mágico: %w", err)
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
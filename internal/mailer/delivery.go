@@ -0,0 +1,423 @@
+package mailer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PauloHFS/goth/internal/metrics"
+)
+
+const (
+	defaultDeliveryWorkers    = 4
+	defaultDeliveryAttempts   = 5
+	defaultShardBufferSize    = 64
+	defaultDeliveryProviderID = "multi"
+)
+
+var deliveryRateLimitBackoff = deliveryBackoffConfig{
+	BaseDelay: 1 * time.Second,
+	MaxDelay:  5 * time.Minute,
+}
+
+// deliveryBackoffConfig tem o mesmo formato de worker.BackoffConfig. mailer
+// não pode importar internal/worker (worker já importa mailer, para
+// Processor.mailer), então a lógica de backoff com jitter é reimplementada
+// aqui em vez de compartilhada.
+type deliveryBackoffConfig struct {
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// fullJitterBackoff espelha a fórmula de full-jitter de worker.FullJitter.
+func fullJitterBackoff(attempt int, cfg deliveryBackoffConfig) time.Duration {
+	if attempt <= 0 {
+		return cfg.BaseDelay
+	}
+
+	exp := min(cfg.BaseDelay*time.Duration(1<<attempt), cfg.MaxDelay)
+	jitter := time.Duration(rand.Int63n(int64(exp)))
+	return exp/2 + jitter
+}
+
+type deliveryOutcome int
+
+const (
+	outcomeRetryable deliveryOutcome = iota
+	outcomeRateLimited
+	outcomePermanent
+)
+
+// deliveryPermanentPatterns são substrings de um erro que indicam que a
+// mensagem em si é inválida (endereço ruim, auth) em vez do provider estar
+// temporariamente indisponível — esses vão direto pro canal de dead letter
+// em vez de serem retentados.
+var deliveryPermanentPatterns = []string{
+	"400", "401", "403", "404", "422",
+	"invalid email", "invalid address", "unauthorized",
+}
+
+func classifyDeliveryError(err error) deliveryOutcome {
+	if errors.Is(err, ErrRateLimitExceeded) || isRateLimitError(err) {
+		return outcomeRateLimited
+	}
+	if errors.Is(err, ErrInvalidAPIKey) {
+		return outcomePermanent
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, pattern := range deliveryPermanentPatterns {
+		if strings.Contains(msg, pattern) {
+			return outcomePermanent
+		}
+	}
+
+	return outcomeRetryable
+}
+
+// emailSender é o recorte de EmailProvider que DeliveryQueue realmente
+// precisa — MultiProvider satisfaz, mas qualquer EmailProvider isolado
+// também.
+type emailSender interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// FailedEmail é o que Failures() entrega depois que uma mensagem esgota as
+// tentativas (ou leva um 4xx permanente) e não será tentada de novo.
+type FailedEmail struct {
+	Email    Email
+	Err      error
+	Attempts int
+	FailedAt time.Time
+}
+
+type queueItem struct {
+	email    Email
+	attempts int
+	outboxID int64 // 0 quando não há persistência (cfg.DB == nil)
+}
+
+// DeliveryQueueConfig configura NewDeliveryQueue. Zero-values recebem
+// defaults (ver NewDeliveryQueue) — só DB precisa ser setado explicitamente
+// por quem quiser o buffer persistente em mail_outbox.
+type DeliveryQueueConfig struct {
+	Workers       int
+	MaxAttempts   int
+	ProviderLabel string
+	DB            *sql.DB
+	Logger        *slog.Logger
+}
+
+// DeliveryQueue entrega Email de forma assíncrona por um pool de workers,
+// cada um dono de um shard (ver shardFor) — mensagens para o mesmo
+// destinatário caem sempre no mesmo shard, então nunca são reordenadas
+// entre si, mas destinatários diferentes no mesmo shard podem esperar um
+// atrás do outro (mesma ideia de worker.ShardFor, só que particionando por
+// destinatário de e-mail em vez de host de destino).
+type DeliveryQueue struct {
+	provider emailSender
+	cfg      DeliveryQueueConfig
+	logger   *slog.Logger
+
+	shards   []chan queueItem
+	failures chan FailedEmail
+	wg       sync.WaitGroup
+}
+
+func NewDeliveryQueue(provider emailSender, cfg DeliveryQueueConfig) *DeliveryQueue {
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaultDeliveryWorkers
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultDeliveryAttempts
+	}
+	if cfg.ProviderLabel == "" {
+		cfg.ProviderLabel = defaultDeliveryProviderID
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	shards := make([]chan queueItem, cfg.Workers)
+	for i := range shards {
+		shards[i] = make(chan queueItem, defaultShardBufferSize)
+	}
+
+	return &DeliveryQueue{
+		provider: provider,
+		cfg:      cfg,
+		logger:   logger,
+		shards:   shards,
+		failures: make(chan FailedEmail, defaultShardBufferSize),
+	}
+}
+
+// shardFor distribui destinatários entre os workers por hash, igual
+// worker.ShardFor faz com hosts de destino.
+func shardFor(target string, shardCount int) int {
+	if shardCount <= 0 || target == "" {
+		return 0
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(target))
+	return int(h.Sum32() % uint32(shardCount))
+}
+
+// Start garante o schema de mail_outbox (se cfg.DB estiver setado), sobe um
+// worker por shard e recoloca na fila qualquer linha de mail_outbox deixada
+// por uma execução anterior — assim um restart não perde mensagens
+// enfileiradas antes do crash.
+func (q *DeliveryQueue) Start(ctx context.Context) error {
+	if q.cfg.DB != nil {
+		if err := q.ensureOutboxSchema(ctx); err != nil {
+			return err
+		}
+	}
+
+	q.wg.Add(len(q.shards))
+	for i := range q.shards {
+		go q.runWorker(ctx, i)
+	}
+
+	pending, err := q.loadPendingOutbox(ctx)
+	if err != nil {
+		q.logger.Warn("mailer: failed to recover pending mail_outbox rows", "err", err)
+		return nil
+	}
+	for _, item := range pending {
+		shard := shardFor(item.email.To, len(q.shards))
+		select {
+		case q.shards[shard] <- item:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return nil
+}
+
+// Stop fecha os canais de shard e espera os workers drenarem o que já está
+// em voo, respeitando o deadline de ctx — se ctx expirar antes, Stop
+// devolve ctx.Err() e as entregas em andamento continuam em background.
+func (q *DeliveryQueue) Stop(ctx context.Context) error {
+	for _, ch := range q.shards {
+		close(ch)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		close(q.failures)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Failures expõe as mensagens que esgotaram as tentativas (ou levaram um
+// 4xx permanente) — quem consome decide o que fazer (notificar, logar,
+// etc.), DeliveryQueue não reencaminha nada sozinha.
+func (q *DeliveryQueue) Failures() <-chan FailedEmail {
+	return q.failures
+}
+
+// Enqueue persiste email em mail_outbox (quando cfg.DB está setado) e
+// empurra para o shard do destinatário. Bloqueia até o shard ter espaço ou
+// ctx ser cancelado.
+func (q *DeliveryQueue) Enqueue(ctx context.Context, email Email) error {
+	outboxID, err := q.persistToOutbox(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	metrics.MailerDeliveryEnqueued.WithLabelValues(q.cfg.ProviderLabel).Inc()
+
+	item := queueItem{email: email, outboxID: outboxID}
+	shard := shardFor(email.To, len(q.shards))
+
+	select {
+	case q.shards[shard] <- item:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *DeliveryQueue) EnqueueBatch(ctx context.Context, emails []Email) error {
+	for _, email := range emails {
+		if err := q.Enqueue(ctx, email); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (q *DeliveryQueue) runWorker(ctx context.Context, shardIndex int) {
+	defer q.wg.Done()
+	ch := q.shards[shardIndex]
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case item, ok := <-ch:
+			if !ok {
+				return
+			}
+			q.deliver(ctx, item)
+		}
+	}
+}
+
+// deliver entrega item, retentando dentro do próprio loop em vez de
+// reenfileirar no canal do shard — reenfileirar bloquearia esse mesmo
+// worker (ele é o único consumidor do canal), então a entrega (com todas as
+// tentativas) acontece aqui antes do worker voltar a puxar o próximo item
+// do shard. Isso preserva a ordem por destinatário: nenhuma outra mensagem
+// do mesmo shard avança enquanto esta não termina.
+func (q *DeliveryQueue) deliver(ctx context.Context, item queueItem) {
+	for {
+		err := q.provider.Send(ctx, item.email.To, item.email.Subject, item.email.Body)
+		if err == nil {
+			metrics.MailerDeliverySent.WithLabelValues(q.cfg.ProviderLabel).Inc()
+			q.removeFromOutbox(ctx, item.outboxID)
+			return
+		}
+
+		switch classifyDeliveryError(err) {
+		case outcomeRateLimited:
+			item.attempts++
+			if item.attempts >= q.cfg.MaxAttempts {
+				q.deadLetter(item, err)
+				return
+			}
+			metrics.MailerDeliveryRetried.WithLabelValues(q.cfg.ProviderLabel).Inc()
+			if !q.sleep(ctx, fullJitterBackoff(item.attempts, deliveryRateLimitBackoff)) {
+				return
+			}
+
+		case outcomePermanent:
+			q.deadLetter(item, err)
+			return
+
+		default: // outcomeRetryable: 5xx e erros não classificados
+			item.attempts++
+			if item.attempts >= q.cfg.MaxAttempts {
+				q.deadLetter(item, err)
+				return
+			}
+			metrics.MailerDeliveryRetried.WithLabelValues(q.cfg.ProviderLabel).Inc()
+			if !q.sleep(ctx, fullJitterBackoff(item.attempts, deliveryRateLimitBackoff)) {
+				return
+			}
+		}
+	}
+}
+
+func (q *DeliveryQueue) sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (q *DeliveryQueue) deadLetter(item queueItem, sendErr error) {
+	metrics.MailerDeliveryFailed.WithLabelValues(q.cfg.ProviderLabel).Inc()
+	q.removeFromOutbox(context.Background(), item.outboxID)
+
+	failed := FailedEmail{
+		Email:    item.email,
+		Err:      sendErr,
+		Attempts: item.attempts,
+		FailedAt: time.Now(),
+	}
+
+	select {
+	case q.failures <- failed:
+	default:
+		q.logger.Warn("mailer: failures channel full, dropping dead letter", "to", item.email.To, "err", sendErr)
+	}
+}
+
+// ensureOutboxSchema cria mail_outbox se ainda não existir — mesmo padrão
+// idempotente de worker.BackupManager.EnsurePayloadHashSchema.
+func (q *DeliveryQueue) ensureOutboxSchema(ctx context.Context) error {
+	_, err := q.cfg.DB.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS mail_outbox (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			recipient TEXT NOT NULL,
+			subject TEXT NOT NULL,
+			body TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("mailer: failed to ensure mail_outbox schema: %w", err)
+	}
+	return nil
+}
+
+func (q *DeliveryQueue) persistToOutbox(ctx context.Context, email Email) (int64, error) {
+	if q.cfg.DB == nil {
+		return 0, nil
+	}
+
+	res, err := q.cfg.DB.ExecContext(ctx,
+		`INSERT INTO mail_outbox (recipient, subject, body) VALUES (?, ?, ?)`,
+		email.To, email.Subject, email.Body,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("mailer: failed to persist to mail_outbox: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+func (q *DeliveryQueue) removeFromOutbox(ctx context.Context, id int64) {
+	if q.cfg.DB == nil || id == 0 {
+		return
+	}
+	if _, err := q.cfg.DB.ExecContext(ctx, `DELETE FROM mail_outbox WHERE id = ?`, id); err != nil {
+		q.logger.Warn("mailer: failed to remove delivered mail_outbox row", "id", id, "err", err)
+	}
+}
+
+func (q *DeliveryQueue) loadPendingOutbox(ctx context.Context) ([]queueItem, error) {
+	if q.cfg.DB == nil {
+		return nil, nil
+	}
+
+	rows, err := q.cfg.DB.QueryContext(ctx, `SELECT id, recipient, subject, body, attempts FROM mail_outbox ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("mailer: failed to load pending mail_outbox rows: %w", err)
+	}
+	defer rows.Close()
+
+	var pending []queueItem
+	for rows.Next() {
+		var item queueItem
+		if err := rows.Scan(&item.outboxID, &item.email.To, &item.email.Subject, &item.email.Body, &item.attempts); err != nil {
+			return nil, fmt.Errorf("mailer: failed to scan mail_outbox row: %w", err)
+		}
+		pending = append(pending, item)
+	}
+	return pending, rows.Err()
+}
@@ -0,0 +1,56 @@
+package mailer
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// alwaysSender sempre devolve o mesmo erro a cada Send, contando quantas
+// vezes foi chamado — usado para forçar deliver() a esgotar as tentativas.
+type alwaysSender struct {
+	err   error
+	calls int32
+}
+
+func (s *alwaysSender) Send(ctx context.Context, to, subject, body string) error {
+	atomic.AddInt32(&s.calls, 1)
+	return s.err
+}
+
+// useFastDeliveryBackoff substitui deliveryRateLimitBackoff durante o teste para que as
+// tentativas não levem minutos de backoff para esgotar.
+func useFastDeliveryBackoff(t *testing.T) {
+	t.Helper()
+	original := deliveryRateLimitBackoff
+	deliveryRateLimitBackoff = deliveryBackoffConfig{BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+	t.Cleanup(func() { deliveryRateLimitBackoff = original })
+}
+
+func TestDeliverDeadLettersAfterMaxAttemptsWhenRateLimited(t *testing.T) {
+	useFastDeliveryBackoff(t)
+
+	sender := &alwaysSender{err: ErrRateLimitExceeded}
+	q := NewDeliveryQueue(sender, DeliveryQueueConfig{MaxAttempts: 3})
+
+	// deliver() é síncrono: retorna assim que o item é entregue ou cai em
+	// dead letter, então basta chamar e em seguida ler o canal bufferizado.
+	q.deliver(context.Background(), queueItem{email: Email{To: "rate-limited@example.com", Subject: "s", Body: "b"}})
+
+	select {
+	case failed := <-q.Failures():
+		if failed.Attempts != 3 {
+			t.Errorf("dead-lettered after %d attempts, want %d (MaxAttempts)", failed.Attempts, 3)
+		}
+		if failed.Err != ErrRateLimitExceeded {
+			t.Errorf("dead letter Err = %v, want %v", failed.Err, ErrRateLimitExceeded)
+		}
+	default:
+		t.Fatal("deliver never dead-lettered a persistently rate-limited item")
+	}
+
+	if calls := atomic.LoadInt32(&sender.calls); calls != 3 {
+		t.Errorf("provider.Send called %d times, want exactly MaxAttempts (%d)", calls, 3)
+	}
+}
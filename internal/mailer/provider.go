@@ -13,11 +13,15 @@ import (
 	"io"
 	"net/http"
 	"net/smtp"
+	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/PauloHFS/goth/internal/awssig"
 	"github.com/PauloHFS/goth/internal/config"
+	"github.com/PauloHFS/goth/internal/metrics"
 )
 
 var (
@@ -54,26 +58,34 @@ type EmailProvider interface {
 	IsAvailable() bool
 }
 
+// current is an atomic cursor instead of int+sync.Mutex: Send no longer
+// locks for the whole failover loop, so concurrent sends run in parallel.
+// A race on the cursor itself is harmless — it's only a load-balancing
+// hint, not something that needs strong consistency.
 type MultiProvider struct {
 	providers []EmailProvider
-	current   int
-	mu        sync.Mutex
+	current   atomic.Int64
 }
 
+// NewMultiProvider wraps each provider in a circuitBreakerProvider so that a
+// provider that starts failing gets skipped by IsAvailable instead of being
+// hammered on every Send (see circuitBreakerProvider).
 func NewMultiProvider(providers ...EmailProvider) *MultiProvider {
+	wrapped := make([]EmailProvider, len(providers))
+	for i, p := range providers {
+		wrapped[i] = newCircuitBreakerProvider(p)
+	}
 	return &MultiProvider{
-		providers: providers,
-		current:   0,
+		providers: wrapped,
 	}
 }
 
 func (mp *MultiProvider) Send(ctx context.Context, to, subject, body string) error {
-	mp.mu.Lock()
-	defer mp.mu.Unlock()
+	start := int(mp.current.Load())
 
 	var lastErr error
 	for i := 0; i < len(mp.providers); i++ {
-		idx := (mp.current + i) % len(mp.providers)
+		idx := (start + i) % len(mp.providers)
 		provider := mp.providers[idx]
 
 		if !provider.IsAvailable() {
@@ -88,7 +100,7 @@ func (mp *MultiProvider) Send(ctx context.Context, to, subject, body string) err
 			return err
 		}
 
-		mp.current = idx
+		mp.current.Store(int64(idx))
 		return nil
 	}
 
@@ -107,6 +119,266 @@ func (mp *MultiProvider) SendBatch(ctx context.Context, emails []Email) error {
 	return nil
 }
 
+// ProviderHealth is a point-in-time view of a provider's circuit breaker,
+// returned by MultiProvider.HealthReport for a dashboard endpoint.
+type ProviderHealth struct {
+	Provider  ProviderType
+	State     string
+	OpenUntil time.Time
+	Failures  int
+	Successes int
+}
+
+// HealthReport devolve o estado atual do circuit breaker de cada provider,
+// na mesma ordem passada a NewMultiProvider.
+func (mp *MultiProvider) HealthReport() []ProviderHealth {
+	report := make([]ProviderHealth, 0, len(mp.providers))
+	for _, p := range mp.providers {
+		if cb, ok := p.(*circuitBreakerProvider); ok {
+			report = append(report, cb.health.snapshot())
+		}
+	}
+	return report
+}
+
+// ResetProvider tira um provider do estado Disabled (ErrInvalidAPIKey não
+// se corrige sozinho com o tempo, ao contrário de rate limit, então não há
+// cooldown automático — é preciso corrigir a credencial e chamar isto).
+// Devolve false se nenhum provider do tipo dado foi encontrado.
+func (mp *MultiProvider) ResetProvider(pt ProviderType) bool {
+	for _, p := range mp.providers {
+		if cb, ok := p.(*circuitBreakerProvider); ok && cb.GetType() == pt {
+			cb.health.reset()
+			return true
+		}
+	}
+	return false
+}
+
+type circuitState int32
+
+const (
+	stateClosed circuitState = iota
+	stateHalfOpen
+	stateOpen
+	stateDisabled
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case stateHalfOpen:
+		return "half_open"
+	case stateOpen:
+		return "open"
+	case stateDisabled:
+		return "disabled"
+	default:
+		return "closed"
+	}
+}
+
+const (
+	circuitWindow          = 60 * time.Second
+	circuitMinFailures     = 5
+	circuitFailureRatio    = 0.5
+	circuitInitialCooldown = 30 * time.Second
+	circuitMaxCooldown     = 10 * time.Minute
+)
+
+type healthEvent struct {
+	at      time.Time
+	success bool
+}
+
+// providerHealth guarda, por provider, uma janela deslizante (últimos 60s)
+// de sucesso/falha usada para decidir quando abrir o circuito, e o estado
+// Closed/HalfOpen/Open/Disabled em si. Open -> HalfOpen acontece sozinho
+// depois de openUntil; Disabled só sai por ResetProvider (falha de
+// autenticação não se resolve com tempo de espera).
+type providerHealth struct {
+	mu           sync.Mutex
+	provider     string
+	events       []healthEvent
+	state        circuitState
+	openUntil    time.Time
+	cooldown     time.Duration
+	halfOpenBusy bool
+}
+
+func newProviderHealth(provider string) *providerHealth {
+	h := &providerHealth{provider: provider, state: stateClosed}
+	metrics.MailerProviderState.WithLabelValues(provider).Set(float64(stateClosed))
+	return h
+}
+
+// record aplica o resultado de um Send à janela. ErrInvalidAPIKey derruba
+// direto pra Disabled, sem passar pela contagem de falhas — uma chave
+// inválida não vira "saudável" só porque as próximas tentativas também
+// vão falhar.
+func (h *providerHealth) record(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	h.prune(now)
+
+	if errors.Is(err, ErrInvalidAPIKey) {
+		h.state = stateDisabled
+		metrics.MailerProviderState.WithLabelValues(h.provider).Set(float64(stateDisabled))
+		return
+	}
+
+	if err == nil {
+		h.events = append(h.events, healthEvent{at: now, success: true})
+		if h.state == stateHalfOpen {
+			h.state = stateClosed
+			h.cooldown = 0
+			h.halfOpenBusy = false
+			metrics.MailerProviderState.WithLabelValues(h.provider).Set(float64(stateClosed))
+		}
+		return
+	}
+
+	h.events = append(h.events, healthEvent{at: now, success: false})
+
+	if h.state == stateHalfOpen || (h.state == stateClosed && h.shouldTrip()) {
+		h.trip(now)
+	}
+}
+
+func (h *providerHealth) shouldTrip() bool {
+	failures, total := 0, 0
+	for _, e := range h.events {
+		total++
+		if !e.success {
+			failures++
+		}
+	}
+	if failures < circuitMinFailures {
+		return false
+	}
+	return float64(failures)/float64(total) > circuitFailureRatio
+}
+
+// trip abre o circuito com um cooldown que dobra a cada reabertura
+// consecutiva (30s, 1m, 2m, ... até o teto de 10m) — um provider que volta
+// a falhar logo depois do probe de HalfOpen espera cada vez mais antes do
+// próximo probe.
+func (h *providerHealth) trip(now time.Time) {
+	if h.cooldown == 0 {
+		h.cooldown = circuitInitialCooldown
+	} else {
+		h.cooldown = min(h.cooldown*2, circuitMaxCooldown)
+	}
+	h.state = stateOpen
+	h.openUntil = now.Add(h.cooldown)
+	h.halfOpenBusy = false
+	metrics.MailerProviderTrips.WithLabelValues(h.provider).Inc()
+	metrics.MailerProviderState.WithLabelValues(h.provider).Set(float64(stateOpen))
+}
+
+// allow decide se uma chamada pode ir pro provider agora, avançando o
+// estado quando necessário: Open -> HalfOpen assim que openUntil passa,
+// admitindo uma única sonda (halfOpenBusy evita que chamadas concorrentes
+// vazem mais de uma por vez).
+func (h *providerHealth) allow() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch h.state {
+	case stateDisabled:
+		return false
+	case stateOpen:
+		if time.Now().Before(h.openUntil) {
+			return false
+		}
+		h.state = stateHalfOpen
+		h.halfOpenBusy = true
+		metrics.MailerProviderState.WithLabelValues(h.provider).Set(float64(stateHalfOpen))
+		return true
+	case stateHalfOpen:
+		if h.halfOpenBusy {
+			return false
+		}
+		h.halfOpenBusy = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (h *providerHealth) prune(now time.Time) {
+	cutoff := now.Add(-circuitWindow)
+	i := 0
+	for i < len(h.events) && h.events[i].at.Before(cutoff) {
+		i++
+	}
+	if i > 0 {
+		h.events = h.events[i:]
+	}
+}
+
+func (h *providerHealth) snapshot() ProviderHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	failures, successes := 0, 0
+	for _, e := range h.events {
+		if e.success {
+			successes++
+		} else {
+			failures++
+		}
+	}
+
+	return ProviderHealth{
+		Provider:  ProviderType(h.provider),
+		State:     h.state.String(),
+		OpenUntil: h.openUntil,
+		Failures:  failures,
+		Successes: successes,
+	}
+}
+
+func (h *providerHealth) reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.state = stateClosed
+	h.cooldown = 0
+	h.openUntil = time.Time{}
+	h.halfOpenBusy = false
+	h.events = nil
+	metrics.MailerProviderState.WithLabelValues(h.provider).Set(float64(stateClosed))
+}
+
+// circuitBreakerProvider embrulha um EmailProvider com providerHealth:
+// IsAvailable() passa a refletir saúde em runtime (não só config estática),
+// e Send registra o resultado na janela. MultiProvider já faz failover por
+// rate limit; o breaker complementa isso evitando que um provider doente
+// continue recebendo uma fração do tráfego round-robin enquanto Open.
+type circuitBreakerProvider struct {
+	EmailProvider
+	health *providerHealth
+}
+
+func newCircuitBreakerProvider(p EmailProvider) *circuitBreakerProvider {
+	return &circuitBreakerProvider{
+		EmailProvider: p,
+		health:        newProviderHealth(string(p.GetType())),
+	}
+}
+
+func (c *circuitBreakerProvider) Send(ctx context.Context, to, subject, body string) error {
+	err := c.EmailProvider.Send(ctx, to, subject, body)
+	c.health.record(err)
+	return err
+}
+
+func (c *circuitBreakerProvider) IsAvailable() bool {
+	return c.EmailProvider.IsAvailable() && c.health.allow()
+}
+
 func isRateLimitError(err error) bool {
 	if err == nil {
 		return false
@@ -273,8 +545,139 @@ func NewAWSESProvider(accessKey, secretKey, region, fromEmail, fromName string)
 	}
 }
 
+// sesSendEmailRequest é o corpo de POST /v2/email/outbound-emails da API
+// SES v2 — só o subconjunto "Simple" (assunto + corpo HTML) usado aqui.
+type sesSendEmailRequest struct {
+	FromEmailAddress string          `json:"FromEmailAddress"`
+	Destination      sesDestination  `json:"Destination"`
+	Content          sesEmailContent `json:"Content"`
+}
+
+type sesDestination struct {
+	ToAddresses []string `json:"ToAddresses"`
+}
+
+type sesEmailContent struct {
+	Simple sesSimpleContent `json:"Simple"`
+}
+
+type sesSimpleContent struct {
+	Subject sesContentData `json:"Subject"`
+	Body    sesBodyContent `json:"Body"`
+}
+
+type sesContentData struct {
+	Data string `json:"Data"`
+}
+
+type sesBodyContent struct {
+	Html sesContentData `json:"Html"`
+}
+
+type sesSendEmailResponse struct {
+	MessageId string `json:"MessageId"`
+}
+
+type sesErrorResponse struct {
+	Type    string `json:"__type"`
+	Message string `json:"message"`
+}
+
+// SendEmailResult é o que SendWithResult devolve em caso de sucesso, pra
+// quem precisar logar/correlacionar com o MessageId do SES.
+type SendEmailResult struct {
+	MessageID string
+}
+
 func (a *AWSESProvider) Send(ctx context.Context, to, subject, body string) error {
-	return errors.New("aws ses: not implemented - use aws-sdk-go-v2")
+	_, err := a.SendWithResult(ctx, to, subject, body)
+	return err
+}
+
+// SendWithResult faz a mesma chamada que Send, mas devolve o MessageId do
+// SES — EmailProvider.Send não tem espaço pra isso na assinatura (também é
+// implementada por SMTPProvider/ResendProvider), então quem precisa do
+// MessageId chama este método diretamente no *AWSESProvider.
+//
+// Assina a requisição com SigV4 (ver internal/awssig) em vez de usar a
+// aws-sdk-go, que o projeto evita deliberadamente.
+func (a *AWSESProvider) SendWithResult(ctx context.Context, to, subject, body string) (*SendEmailResult, error) {
+	from := a.fromEmail
+	if a.fromName != "" {
+		from = fmt.Sprintf("%s <%s>", a.fromName, a.fromEmail)
+	}
+
+	reqBody := sesSendEmailRequest{
+		FromEmailAddress: from,
+		Destination:      sesDestination{ToAddresses: []string{to}},
+		Content: sesEmailContent{
+			Simple: sesSimpleContent{
+				Subject: sesContentData{Data: subject},
+				Body:    sesBodyContent{Html: sesContentData{Data: body}},
+			},
+		},
+	}
+
+	bodyBytes, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	const path = "/v2/email/outbound-emails"
+	host := fmt.Sprintf("email.%s.amazonaws.com", a.region)
+
+	signed := awssig.Sign(awssig.Request{
+		Method:    http.MethodPost,
+		Host:      host,
+		Path:      path,
+		Query:     url.Values{},
+		Body:      bodyBytes,
+		AccessKey: a.accessKey,
+		SecretKey: a.secretKey,
+		Region:    a.region,
+		Service:   "ses",
+		Time:      time.Now(),
+	})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint+path, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Amz-Date", signed.AmzDate)
+	req.Header.Set("X-Amz-Content-Sha256", signed.ContentSHA256)
+	req.Header.Set("Authorization", signed.Authorization)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, ErrRateLimitExceeded
+	}
+
+	if resp.StatusCode >= 400 {
+		var apiErr sesErrorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+
+		if resp.StatusCode == http.StatusForbidden || strings.Contains(apiErr.Type, "InvalidClientTokenId") {
+			return nil, ErrInvalidAPIKey
+		}
+		if apiErr.Message != "" {
+			return nil, fmt.Errorf("ses error: %s - %s", apiErr.Type, apiErr.Message)
+		}
+		return nil, fmt.Errorf("ses error: status %d", resp.StatusCode)
+	}
+
+	var sesResp sesSendEmailResponse
+	if err := json.NewDecoder(resp.Body).Decode(&sesResp); err != nil {
+		return nil, err
+	}
+
+	return &SendEmailResult{MessageID: sesResp.MessageId}, nil
 }
 
 func (a *AWSESProvider) SendBatch(ctx context.Context, emails []Email) error {
@@ -30,4 +30,59 @@ var (
 		Name: "jobs_dead_letter_total",
 		Help: "Total number of jobs moved to dead letter queue",
 	}, []string{"type"})
+
+	JobsLeased = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "jobs_leased",
+		Help: "Number of jobs currently leased by a worker",
+	})
+
+	JobLeaseRescuesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "job_lease_rescues_total",
+		Help: "Total number of jobs rescued after their lease expired",
+	}, []string{"type", "outcome"})
+
+	JobsQueuedByHost = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "jobs_queued_by_host",
+		Help: "Number of pending jobs observed targeting each destination host",
+	}, []string{"host"})
+
+	BannedHosts = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "worker_banned_hosts",
+		Help: "Number of destination hosts currently parked after a 5xx/429 response",
+	})
+
+	SSEClientDroppedEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "sse_client_dropped_events_total",
+		Help: "Total number of SSE events dropped from a client's bounded buffer",
+	}, []string{"policy"})
+
+	MailerDeliveryEnqueued = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mailer_delivery_enqueued_total",
+		Help: "Total number of emails enqueued on the async delivery queue",
+	}, []string{"provider"})
+
+	MailerDeliverySent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mailer_delivery_sent_total",
+		Help: "Total number of emails successfully delivered by the async queue",
+	}, []string{"provider"})
+
+	MailerDeliveryFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mailer_delivery_failed_total",
+		Help: "Total number of emails dropped to the dead letter channel after delivery failed",
+	}, []string{"provider"})
+
+	MailerDeliveryRetried = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mailer_delivery_retried_total",
+		Help: "Total number of delivery attempts retried after a rate limit or transient error",
+	}, []string{"provider"})
+
+	MailerProviderState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "mailer_provider_state",
+		Help: "Current circuit breaker state of an email provider (0=closed, 1=half_open, 2=open, 3=disabled)",
+	}, []string{"provider"})
+
+	MailerProviderTrips = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mailer_provider_trips_total",
+		Help: "Total number of times an email provider's circuit breaker tripped open",
+	}, []string{"provider"})
 )
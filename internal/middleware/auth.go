@@ -2,15 +2,62 @@ package middleware
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/PauloHFS/goth/internal/contextkeys"
 	"github.com/PauloHFS/goth/internal/db"
 	"github.com/PauloHFS/goth/internal/routes"
+	"github.com/PauloHFS/goth/internal/session"
 	"github.com/alexedwards/scs/v2"
 )
 
-func RequireAuth(sm *scs.SessionManager, queries *db.Queries, next http.Handler) http.Handler {
+// defaultUserCacheTTL é o TTL padrão de uma entrada de UserCache quando
+// RequireAuth não recebe WithUserCacheTTL — curto o bastante para que uma
+// mudança de role/perfil não fique visível por muito tempo além do previsto.
+const defaultUserCacheTTL = 5 * time.Minute
+
+// requireAuthConfig acumula as RequireAuthOption aplicadas a uma chamada de
+// RequireAuth.
+type requireAuthConfig struct {
+	cache UserCache
+	ttl   time.Duration
+}
+
+// RequireAuthOption customiza RequireAuth sem quebrar os ~dez call sites
+// existentes, que continuam chamando RequireAuth(sm, queries, sessions, next)
+// sem passar nenhuma opção.
+type RequireAuthOption func(*requireAuthConfig)
+
+// WithUserCache consulta cache antes de ir ao banco buscar o usuário da
+// sessão, na linha do que o comentário original deste arquivo sugeria
+// (cache Redis/LRU em apps de altíssimo tráfego). cache nil desliga o cache
+// (comportamento padrão).
+func WithUserCache(cache UserCache) RequireAuthOption {
+	return func(c *requireAuthConfig) {
+		c.cache = cache
+	}
+}
+
+// WithUserCacheTTL ajusta por quanto tempo uma entrada cacheada (positiva ou
+// negativa) permanece válida; sem esta opção usa defaultUserCacheTTL.
+func WithUserCacheTTL(ttl time.Duration) RequireAuthOption {
+	return func(c *requireAuthConfig) {
+		c.ttl = ttl
+	}
+}
+
+// RequireAuth exige um usuário autenticado via scs. Quando sessions não é
+// nil, também rejeita tokens revogados/expirados na tabela sessions e
+// atualiza last_activity_at a cada request — ver internal/session.
+func RequireAuth(sm *scs.SessionManager, queries *db.Queries, sessions *session.Store, next http.Handler, opts ...RequireAuthOption) http.Handler {
+	cfg := requireAuthConfig{ttl: defaultUserCacheTTL}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		userID := sm.GetInt64(r.Context(), "user_id")
 		if userID == 0 {
@@ -18,20 +65,66 @@ func RequireAuth(sm *scs.SessionManager, queries *db.Queries, next http.Handler)
 			return
 		}
 
-		// Buscar usuário completo e colocar no contexto
-		// Nota: Em apps de altíssimo tráfego, você poderia colocar o usuário no cache (Redis/LRU)
-		user, err := queries.GetUserByID(r.Context(), userID)
-		if err != nil {
+		user, found := loadUser(r.Context(), queries, cfg, userID)
+		if !found {
 			_ = sm.Destroy(r.Context())
 			redirectLogin(w, r)
 			return
 		}
 
+		if sessions != nil {
+			tokenHash := session.HashToken(sm.Token(r.Context()))
+			sess, err := sessions.GetByTokenHash(r.Context(), tokenHash)
+			if err != nil {
+				// Nega por padrão: uma linha ausente (revogada/expirada e já
+				// limpa) e uma falha transitória de banco são indistinguíveis
+				// aqui, e o ponto todo de sessions é revogação — tratar
+				// qualquer erro como "sessão inválida" evita abrir (fail
+				// open) o que deveria ser fail closed.
+				_ = sm.Destroy(r.Context())
+				redirectLogin(w, r)
+				return
+			}
+			if !sess.Active(time.Now()) {
+				_ = sm.Destroy(r.Context())
+				redirectLogin(w, r)
+				return
+			}
+			_ = sessions.Touch(r.Context(), tokenHash)
+		}
+
 		ctx := context.WithValue(r.Context(), contextkeys.UserContextKey, user)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// loadUser busca o usuário userID, consultando cfg.cache primeiro quando
+// configurado. Um hit negativo (usuário não encontrado em uma consulta
+// anterior) evita repetir queries.GetUserByID a cada request de um token já
+// sabido inválido; um miss cai para o banco e popula o cache com o
+// resultado, positivo ou negativo (negativo só para sql.ErrNoRows; outros
+// erros de banco não são cacheados).
+func loadUser(ctx context.Context, queries *db.Queries, cfg requireAuthConfig, userID int64) (db.User, bool) {
+	if cfg.cache != nil {
+		if user, exists, found := cfg.cache.Get(ctx, userID); found {
+			return user, exists
+		}
+	}
+
+	user, err := queries.GetUserByID(ctx, userID)
+	if err != nil {
+		if cfg.cache != nil && errors.Is(err, sql.ErrNoRows) {
+			cfg.cache.PutNotFound(ctx, userID, cfg.ttl)
+		}
+		return db.User{}, false
+	}
+
+	if cfg.cache != nil {
+		cfg.cache.Put(ctx, userID, user, cfg.ttl)
+	}
+	return user, true
+}
+
 func redirectLogin(w http.ResponseWriter, r *http.Request) {
 	if r.Header.Get("HX-Request") != "" {
 		w.Header().Set("HX-Redirect", routes.Login)
@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/PauloHFS/goth/internal/db"
+	"github.com/PauloHFS/goth/internal/session"
+	"github.com/alexedwards/scs/v2"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// setupAuthTestDB migra um sqlite em memória e cria um usuário — mesma
+// receita de setupBenchDB em user_cache_test.go, adaptada para *testing.T.
+func setupAuthTestDB(t *testing.T) (*sql.DB, *db.Queries, int64) {
+	t.Helper()
+
+	dbConn, err := sql.Open("sqlite3", ":memory:?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dbConn.Close() })
+
+	ctx := context.Background()
+	if err := db.RunMigrations(ctx, dbConn); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	queries := db.New(dbConn)
+	user, err := queries.CreateUser(ctx, db.CreateUserParams{
+		TenantID:     "default",
+		Email:        "auth-test@example.com",
+		PasswordHash: "x",
+		RoleID:       "user",
+	})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	return dbConn, queries, user.ID
+}
+
+// authenticatedRequest comita um valor user_id num scs.SessionManager real
+// (gerando um token de verdade), recarrega esse token num novo contexto de
+// request — o mesmo par Load/Commit que LoadAndSave faz por request em
+// produção — e devolve o request resultante junto com o token bruto, para
+// que o chamador possa gravar (ou não) a linha sessions correspondente.
+func authenticatedRequest(t *testing.T, sm *scs.SessionManager, userID int64) (*http.Request, string) {
+	t.Helper()
+
+	putCtx, err := sm.Load(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm.Put(putCtx, "user_id", userID)
+	token, _, err := sm.Commit(putCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reqCtx, err := sm.Load(context.Background(), token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("GET", "/dashboard", nil).WithContext(reqCtx)
+	return req, token
+}
+
+func TestRequireAuthSessionRevocation(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	t.Run("AllowsActiveSession", func(t *testing.T) {
+		dbConn, queries, userID := setupAuthTestDB(t)
+		sessions := session.NewStore(dbConn)
+		if err := sessions.EnsureTable(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		sm := scs.New()
+		req, token := authenticatedRequest(t, sm, userID)
+
+		if _, err := sessions.Create(context.Background(), session.CreateParams{
+			UserID: userID,
+			Token:  token,
+			TTL:    session.DefaultTTL,
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		RequireAuth(sm, queries, sessions, next).ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("active session got status %d, want %d", rr.Code, http.StatusOK)
+		}
+	})
+
+	t.Run("DeniesRevokedSession", func(t *testing.T) {
+		dbConn, queries, userID := setupAuthTestDB(t)
+		sessions := session.NewStore(dbConn)
+		if err := sessions.EnsureTable(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		sm := scs.New()
+		req, token := authenticatedRequest(t, sm, userID)
+
+		sess, err := sessions.Create(context.Background(), session.CreateParams{
+			UserID: userID,
+			Token:  token,
+			TTL:    session.DefaultTTL,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sessions.Revoke(context.Background(), sess.ID, userID); err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		RequireAuth(sm, queries, sessions, next).ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusSeeOther {
+			t.Errorf("revoked session got status %d, want a %d redirect to login", rr.Code, http.StatusSeeOther)
+		}
+	})
+
+	t.Run("DeniesSessionLookupFailure", func(t *testing.T) {
+		// Nenhuma linha sessions correspondente ao token é gravada: simula
+		// tanto uma sessão já expurgada do banco quanto uma falha
+		// transitória de lookup — em ambos os casos GetByTokenHash erra, e
+		// RequireAuth deve negar por padrão (fail closed) em vez de deixar a
+		// ausência de linha passar como se sessions nunca tivesse sido
+		// configurado.
+		dbConn, queries, userID := setupAuthTestDB(t)
+		sessions := session.NewStore(dbConn)
+		if err := sessions.EnsureTable(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		sm := scs.New()
+		req, _ := authenticatedRequest(t, sm, userID)
+
+		rr := httptest.NewRecorder()
+		RequireAuth(sm, queries, sessions, next).ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusSeeOther {
+			t.Errorf("a session lookup miss got status %d, want a %d redirect to login", rr.Code, http.StatusSeeOther)
+		}
+	})
+}
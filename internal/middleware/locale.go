@@ -3,27 +3,111 @@ package middleware
 import (
 	"context"
 	"net/http"
-	"strings"
+
+	"golang.org/x/text/language"
 
 	"github.com/PauloHFS/goth/internal/contextkeys"
 )
 
+const (
+	localeCookieName   = "lang"
+	localeCookieMaxAge = 365 * 24 * 60 * 60 // 1 ano
+)
+
+// SupportedTags são os idiomas que a aplicação sabe servir, na ordem de
+// preferência usada como desempate por matcher — configurável via
+// SetSupportedTags (ex.: ao adicionar um novo locale ao catálogo i18n).
+var SupportedTags = []language.Tag{
+	language.BrazilianPortuguese,
+	language.English,
+}
+
+var matcher = language.NewMatcher(SupportedTags)
+
+// SetSupportedTags troca os idiomas suportados e reconstrói o matcher —
+// precisa ser uma função em vez de só reatribuir SupportedTags porque
+// language.Matcher não se atualiza sozinho depois de montado por
+// language.NewMatcher.
+func SetSupportedTags(tags []language.Tag) {
+	SupportedTags = tags
+	matcher = language.NewMatcher(tags)
+}
+
+// Locale resolve o idioma da requisição e guarda tanto o language.Tag
+// completo (contextkeys.LanguageTagKey — pra quem precisa de subtags de
+// região/script, ex. formatar datas/números) quanto a string curta que
+// i18n.T já espera (contextkeys.LocaleKey, ver keep-compat em
+// localeString).
+//
+// O cookie "lang" sempre vence, mas só se for um dos SupportedTags — um
+// valor arbitrário ou desatualizado (idioma removido do catálogo) é
+// ignorado e cai pra negociação via Accept-Language. Na ausência de um
+// cookie válido, o header é parseado com language.ParseAcceptLanguage e
+// resolvido contra matcher.Match, que já lida com pesos "q=", subtags de
+// região/script e cai pro primeiro SupportedTags em caso de erro de parse,
+// header vazio ou nenhum match.
 func Locale(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// 1. Verificar Cookie (preferência manual)
-		locale := "pt"
-		cookie, err := r.Cookie("lang")
-		if err == nil {
-			locale = cookie.Value
-		} else {
-			// 2. Verificar Header Accept-Language
-			accept := r.Header.Get("Accept-Language")
-			if strings.HasPrefix(accept, "en") {
-				locale = "en"
-			}
-		}
+		tag := resolveTag(r)
 
-		ctx := context.WithValue(r.Context(), contextkeys.LocaleKey, locale)
+		ctx := context.WithValue(r.Context(), contextkeys.LanguageTagKey, tag)
+		ctx = context.WithValue(ctx, contextkeys.LocaleKey, localeString(tag))
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+func resolveTag(r *http.Request) language.Tag {
+	if cookie, err := r.Cookie(localeCookieName); err == nil && cookie.Value != "" {
+		if parsed, err := language.Parse(cookie.Value); err == nil && isSupportedTag(parsed) {
+			return parsed
+		}
+	}
+	return negotiateTag(r.Header.Get("Accept-Language"))
+}
+
+func isSupportedTag(tag language.Tag) bool {
+	for _, supported := range SupportedTags {
+		if tag == supported {
+			return true
+		}
+	}
+	return false
+}
+
+func negotiateTag(header string) language.Tag {
+	if header == "" {
+		return SupportedTags[0]
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(header)
+	if err != nil || len(tags) == 0 {
+		return SupportedTags[0]
+	}
+
+	tag, _, _ := matcher.Match(tags...)
+	return tag
+}
+
+// localeString devolve a string curta que o catálogo i18n indexa (ver
+// i18n.FallbackChain) — os nomes dos arquivos de locale (en.yaml, pt.yaml,
+// pt-BR.yaml) já seguem o mesmo formato BCP 47 que language.Tag.String()
+// produz para os SupportedTags padrão, então não precisa de tabela de
+// tradução própria.
+func localeString(tag language.Tag) string {
+	return tag.String()
+}
+
+// SetLocaleCookie grava a preferência manual de idioma do usuário — lida de
+// volta por Locale na próxima requisição. Secure + SameSite=Lax porque é só
+// uma preferência de exibição, não precisa sobreviver a navegação
+// cross-site nem ser enviada em texto claro.
+func SetLocaleCookie(w http.ResponseWriter, tag language.Tag) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     localeCookieName,
+		Value:    tag.String(),
+		Path:     "/",
+		MaxAge:   localeCookieMaxAge,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/text/language"
+
+	"github.com/PauloHFS/goth/internal/contextkeys"
+)
+
+func TestNegotiateTag_PicksHighestQuality(t *testing.T) {
+	tag := negotiateTag("fr-FR;q=0.9, pt-BR;q=0.8, en;q=0.5")
+	if tag != language.BrazilianPortuguese {
+		t.Fatalf("esperava pt-BR, obteve %v", tag)
+	}
+}
+
+func TestNegotiateTag_FallsBackThroughBaseLanguage(t *testing.T) {
+	tag := negotiateTag("pt")
+	if tag != language.BrazilianPortuguese {
+		t.Fatalf("esperava pt-BR como o suportado mais próximo de pt, obteve %v", tag)
+	}
+}
+
+func TestNegotiateTag_NoHeaderUsesDefault(t *testing.T) {
+	if tag := negotiateTag(""); tag != SupportedTags[0] {
+		t.Fatalf("esperava o default %v sem header, obteve %v", SupportedTags[0], tag)
+	}
+}
+
+func TestNegotiateTag_InvalidHeaderUsesDefault(t *testing.T) {
+	if tag := negotiateTag("!!!"); tag != SupportedTags[0] {
+		t.Fatalf("esperava o default %v com header inválido, obteve %v", SupportedTags[0], tag)
+	}
+}
+
+func TestIsSupportedTag(t *testing.T) {
+	if !isSupportedTag(language.English) {
+		t.Fatal("esperava que language.English fosse suportado")
+	}
+	if isSupportedTag(language.French) {
+		t.Fatal("esperava que language.French não fosse suportado")
+	}
+}
+
+func TestLocale_ValidCookieTakesPrecedence(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "lang", Value: "en"})
+	req.Header.Set("Accept-Language", "pt-BR")
+
+	var gotTag language.Tag
+	handler := Locale(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotTag = r.Context().Value(contextkeys.LanguageTagKey).(language.Tag)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if gotTag != language.English {
+		t.Fatalf("esperava que o cookie vencesse com en, obteve %v", gotTag)
+	}
+}
+
+func TestLocale_UnsupportedCookieFallsBackToNegotiation(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "lang", Value: "fr"}) // parseável, mas fora de SupportedTags
+	req.Header.Set("Accept-Language", "pt-BR")
+
+	var gotLocale string
+	handler := Locale(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotLocale = r.Context().Value(contextkeys.LocaleKey).(string)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if gotLocale != "pt-BR" {
+		t.Fatalf("esperava pt-BR vindo do Accept-Language, obteve %q", gotLocale)
+	}
+}
+
+func TestSetLocaleCookie(t *testing.T) {
+	w := httptest.NewRecorder()
+	SetLocaleCookie(w, language.English)
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("esperava 1 cookie, obteve %d", len(cookies))
+	}
+
+	c := cookies[0]
+	if c.Value != "en" || !c.Secure || c.SameSite != http.SameSiteLaxMode {
+		t.Fatalf("cookie inesperado: %+v", c)
+	}
+}
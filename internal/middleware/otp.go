@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/PauloHFS/goth/internal/contextkeys"
+	"github.com/PauloHFS/goth/internal/db"
+	"github.com/PauloHFS/goth/internal/totp"
+	"github.com/alexedwards/scs/v2"
+)
+
+// otpElevationTTL é por quanto tempo após validar um código TOTP a sessão é
+// considerada "elevada" para fins de RequireOTPElevation.
+const otpElevationTTL = 5 * time.Minute
+
+// RequireOTPElevation, colocada depois de RequireAuth, exige que usuários
+// com segundo fator habilitado tenham validado um código TOTP (login ou
+// reautenticação) nos últimos 5 minutos antes de executar ações sensíveis
+// (troca de senha, upload de avatar). Usuários sem TOTP habilitado passam
+// direto, já que não há segundo fator para reverificar.
+func RequireOTPElevation(sm *scs.SessionManager, totpStore *totp.Store, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := r.Context().Value(contextkeys.UserContextKey).(db.User)
+		if !ok {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if totpStore != nil {
+			enrollment, err := totpStore.Get(r.Context(), user.ID)
+			if err != nil && err != sql.ErrNoRows {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+			if err == nil && enrollment.Enabled() {
+				elevatedAt := sm.GetInt64(r.Context(), "otp_elevated_at")
+				if elevatedAt == 0 || time.Since(time.Unix(elevatedAt, 0)) > otpElevationTTL {
+					http.Error(w, "reautenticação necessária", http.StatusForbidden)
+					return
+				}
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
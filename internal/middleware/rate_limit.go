@@ -6,7 +6,6 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"golang.org/x/time/rate"
@@ -48,26 +47,42 @@ var DefaultRateLimitConfigs = map[string]RateLimitConfig{
 	},
 }
 
-type limiterEntry struct {
-	limiter  *rate.Limiter
-	lastSeen time.Time
-}
-
+// RateLimiter aplica RateLimitConfig a um http.Handler, delegando a decisão
+// de permitir ou não cada requisição a um Store — por padrão um MemoryStore
+// local ao processo, mas injetável via WithStore para compartilhar o limite
+// entre réplicas (SQLiteStore, RedisStore).
 type RateLimiter struct {
-	limiters sync.Map
+	store    Store
 	config   RateLimitConfig
 	category string
-	stopCh   chan struct{}
-	mu       sync.Mutex
 }
 
-func NewRateLimiter(category string, cfg RateLimitConfig) *RateLimiter {
+// RateLimiterOption configura aspectos opcionais de NewRateLimiter, como o
+// Store usado.
+type RateLimiterOption func(*RateLimiter)
+
+// WithStore substitui o MemoryStore padrão de NewRateLimiter por store,
+// permitindo compartilhar o estado do limiter entre processos/réplicas.
+func WithStore(store Store) RateLimiterOption {
+	return func(rl *RateLimiter) {
+		rl.store = store
+	}
+}
+
+func NewRateLimiter(category string, cfg RateLimitConfig, opts ...RateLimiterOption) *RateLimiter {
 	rl := &RateLimiter{
 		config:   cfg,
 		category: category,
-		stopCh:   make(chan struct{}),
 	}
-	go rl.cleanup()
+
+	for _, opt := range opts {
+		opt(rl)
+	}
+
+	if rl.store == nil {
+		rl.store = NewMemoryStore()
+	}
+
 	return rl
 }
 
@@ -75,15 +90,16 @@ func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		key := rl.getKey(r)
 
-		entry, _ := rl.limiters.LoadOrStore(key, &limiterEntry{
-			limiter: rate.NewLimiter(rl.config.Rate, rl.config.Burst),
-		})
-
-		e := entry.(*limiterEntry)
-		e.lastSeen = time.Now()
+		allowed, _, resetAt, err := rl.store.Allow(r.Context(), key, rl.config.Rate, rl.config.Burst, rl.config.Window)
+		if err != nil {
+			// Store indisponível (ex.: Redis fora do ar): falha aberta, para não
+			// derrubar todo o tráfego por causa de uma dependência externa.
+			next.ServeHTTP(w, r)
+			return
+		}
 
-		if !e.limiter.Allow() {
-			rl.onLimited(w, r)
+		if !allowed {
+			rl.onLimited(w, r, resetAt)
 			return
 		}
 
@@ -99,48 +115,41 @@ func (rl *RateLimiter) getKey(r *http.Request) string {
 	return rl.category + ":" + ip
 }
 
-func (rl *RateLimiter) onLimited(w http.ResponseWriter, r *http.Request) {
+func (rl *RateLimiter) onLimited(w http.ResponseWriter, r *http.Request, resetAt time.Time) {
+	retryAfter := time.Until(resetAt)
+	if retryAfter < 0 {
+		retryAfter = 0
+	}
+
 	if rl.config.OnLimited != nil {
-		rl.config.OnLimited(w, r, rl.config.Window)
+		rl.config.OnLimited(w, r, retryAfter)
 		return
 	}
 
-	retryAfter := int(rl.config.Window.Seconds())
-	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
 	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(int(rl.config.Rate)*int(rl.config.Window.Seconds())))
 	w.Header().Set("X-RateLimit-Remaining", "0")
-	w.Header().Set("X-RateLimit-Reset", strconv.Itoa(int(time.Now().Add(rl.config.Window).Unix())))
+	w.Header().Set("X-RateLimit-Reset", strconv.Itoa(int(resetAt.Unix())))
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusTooManyRequests)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"error":       "rate limit exceeded",
-		"retry_after": retryAfter,
+		"retry_after": int(retryAfter.Seconds()),
 		"category":    rl.category,
 	})
 }
 
-func (rl *RateLimiter) cleanup() {
-	ticker := time.NewTicker(time.Minute)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			rl.limiters.Range(func(key, value interface{}) bool {
-				entry := value.(*limiterEntry)
-				if time.Since(entry.lastSeen) > 3*time.Minute {
-					rl.limiters.Delete(key)
-				}
-				return true
-			})
-		case <-rl.stopCh:
-			return
-		}
-	}
+// stopper é implementado por Store que mantêm goroutines em segundo plano
+// (MemoryStore.cleanup, SQLiteStore.sweep) que precisam ser encerradas.
+type stopper interface {
+	Stop()
 }
 
+// Stop encerra o sweeper em segundo plano do Store de rl, se ele tiver um.
 func (rl *RateLimiter) Stop() {
-	close(rl.stopCh)
+	if s, ok := rl.store.(stopper); ok {
+		s.Stop()
+	}
 }
 
 func ExtractIP(r *http.Request) string {
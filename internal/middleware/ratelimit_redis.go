@@ -0,0 +1,251 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// gcraLuaScript implementa o mesmo GCRA de gcraDecision, mas como script Lua
+// rodado atomicamente dentro do Redis via EVAL: lê o tat (em milissegundos)
+// de KEYS[1], calcula new_tat = max(now, tat) + emission_interval e permite
+// se new_tat - now não ultrapassar o burst_interval, gravando o novo tat com
+// TTL igual ao burst_interval (mais uma folga) para a chave se auto-expirar
+// quando o tráfego parar.
+const gcraLuaScript = `
+local tat = tonumber(redis.call("GET", KEYS[1]))
+local now_ms = tonumber(ARGV[1])
+local emission_interval_ms = tonumber(ARGV[2])
+local burst_interval_ms = tonumber(ARGV[3])
+local ttl_ms = tonumber(ARGV[4])
+
+if tat == nil or tat < now_ms then
+  tat = now_ms
+end
+
+local new_tat = tat + emission_interval_ms
+local allow_at = new_tat - burst_interval_ms
+
+if allow_at > now_ms then
+  return {0, allow_at - now_ms}
+end
+
+redis.call("SET", KEYS[1], new_tat, "PX", ttl_ms)
+return {1, new_tat}
+`
+
+// RedisStore é um Store de RateLimiter que persiste o tat do GCRA no Redis
+// via gcraLuaScript, para o limite ser compartilhado por todas as réplicas
+// e sobreviver a restarts. Como nenhum client Redis já está entre as
+// dependências do projeto, fala o protocolo RESP diretamente através de
+// redisConn em vez de trazer um SDK novo só para isso.
+type RedisStore struct {
+	conn *redisConn
+}
+
+// NewRedisStore cria um RedisStore conectado a addr (ex.: "localhost:6379").
+func NewRedisStore(addr string) *RedisStore {
+	return &RedisStore{conn: newRedisConn(addr)}
+}
+
+// Conn expõe a conexão RESP subjacente, para que outros componentes (ex.:
+// RedisUserCache) compartilhem a mesma conexão em vez de abrir uma segunda.
+func (s *RedisStore) Conn() *redisConn {
+	return s.conn
+}
+
+func (s *RedisStore) Allow(ctx context.Context, key string, limit rate.Limit, burst int, window time.Duration) (bool, int, time.Time, error) {
+	if limit <= 0 {
+		limit = 1
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+
+	emissionIntervalMs := float64(time.Second/time.Millisecond) / float64(limit)
+	burstIntervalMs := emissionIntervalMs * float64(burst)
+	ttlMs := burstIntervalMs + float64(window/time.Millisecond)
+	nowMs := float64(time.Now().UnixMilli())
+
+	reply, err := s.conn.do(ctx,
+		"EVAL", gcraLuaScript, "1", key,
+		formatFloat(nowMs), formatFloat(emissionIntervalMs), formatFloat(burstIntervalMs), formatFloat(ttlMs),
+	)
+	if err != nil {
+		return false, 0, time.Time{}, fmt.Errorf("redis rate limit: %w", err)
+	}
+
+	arr, ok := reply.([]interface{})
+	if !ok || len(arr) != 2 {
+		return false, 0, time.Time{}, fmt.Errorf("redis rate limit: unexpected reply %#v", reply)
+	}
+
+	allowedFlag, err1 := toInt64(arr[0])
+	value, err2 := toInt64(arr[1])
+	if err1 != nil || err2 != nil {
+		return false, 0, time.Time{}, fmt.Errorf("redis rate limit: malformed reply %#v", reply)
+	}
+
+	now := time.Now()
+	if allowedFlag == 0 {
+		retryAfter := time.Duration(value) * time.Millisecond
+		return false, 0, now.Add(retryAfter), nil
+	}
+
+	newTATMs := value
+	resetAt := time.UnixMilli(newTATMs)
+	remaining := burst - int((float64(newTATMs)-nowMs)/emissionIntervalMs)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return true, remaining, resetAt, nil
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatInt(int64(f), 10)
+}
+
+func toInt64(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case int64:
+		return n, nil
+	case string:
+		return strconv.ParseInt(n, 10, 64)
+	default:
+		return 0, fmt.Errorf("expected integer, got %T", v)
+	}
+}
+
+// redisConn é um client RESP mínimo sobre uma única conexão TCP reconectável,
+// suficiente para enviar EVAL a um servidor Redis sem depender de um SDK
+// externo. Não faz pooling nem pipelining; para o volume de um rate limiter
+// HTTP isso é suficiente.
+type redisConn struct {
+	mu   sync.Mutex
+	addr string
+	conn net.Conn
+}
+
+func newRedisConn(addr string) *redisConn {
+	return &redisConn{addr: addr}
+}
+
+func (c *redisConn) ensureConn(ctx context.Context) (net.Conn, error) {
+	if c.conn != nil {
+		return c.conn, nil
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c.conn = conn
+	return conn, nil
+}
+
+func (c *redisConn) do(ctx context.Context, args ...string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	conn, err := c.ensureConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	} else {
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	}
+
+	if _, err := conn.Write(encodeRESPCommand(args)); err != nil {
+		c.conn = nil
+		return nil, err
+	}
+
+	reply, err := readRESP(bufio.NewReader(conn))
+	if err != nil {
+		c.conn = nil
+		return nil, err
+	}
+
+	return reply, nil
+}
+
+// encodeRESPCommand serializa args no formato de array de bulk strings do
+// protocolo RESP usado por comandos Redis.
+func encodeRESPCommand(args []string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&buf, "$%d\r\n%s\r\n", len(a), a)
+	}
+	return buf.Bytes()
+}
+
+// readRESP decodifica uma resposta RESP (simple string, error, integer, bulk
+// string ou array), recursivamente para arrays.
+func readRESP(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply line")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, errors.New(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		arr := make([]interface{}, n)
+		for i := 0; i < n; i++ {
+			v, err := readRESP(r)
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = v
+		}
+		return arr, nil
+	default:
+		return nil, fmt.Errorf("redis: unknown reply type %q", line[0])
+	}
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// SQLiteStore é um Store de RateLimiter baseado em GCRA persistido numa
+// tabela rate_limits, para compartilhar o limite entre processos num único
+// nó (ou em testes) sem depender de um Redis. Para múltiplas réplicas em
+// produção, prefira RedisStore.
+type SQLiteStore struct {
+	db            *sql.DB
+	sweepInterval time.Duration
+	stopCh        chan struct{}
+}
+
+// NewSQLiteStore cria um SQLiteStore sobre dbConn, garante a tabela
+// rate_limits e inicia o sweeper de linhas expiradas.
+func NewSQLiteStore(dbConn *sql.DB) (*SQLiteStore, error) {
+	s := &SQLiteStore{
+		db:            dbConn,
+		sweepInterval: time.Minute,
+		stopCh:        make(chan struct{}),
+	}
+
+	if err := s.EnsureTable(context.Background()); err != nil {
+		return nil, err
+	}
+
+	go s.sweep()
+
+	return s, nil
+}
+
+// EnsureTable cria a tabela rate_limits se ainda não existir. Assim como
+// internal/magiclink.Store.EnsureTable, fica fora do fluxo normal de
+// db.RunMigrations até que uma migração dedicada seja escrita.
+func (s *SQLiteStore) EnsureTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS rate_limits (
+			key        TEXT PRIMARY KEY,
+			tat         INTEGER NOT NULL,
+			expires_at INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_rate_limits_expires_at ON rate_limits(expires_at);
+	`)
+	if err != nil {
+		return fmt.Errorf("falha ao garantir tabela rate_limits: %w", err)
+	}
+	return nil
+}
+
+// Allow lê o tat (theoretical arrival time, em nanossegundos Unix) gravado
+// para key, aplica o GCRA via gcraDecision e, se permitido, faz upsert do
+// novo tat numa única transação para evitar corrida entre leitura e escrita.
+func (s *SQLiteStore) Allow(ctx context.Context, key string, limit rate.Limit, burst int, window time.Duration) (bool, int, time.Time, error) {
+	now := time.Now()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, 0, time.Time{}, err
+	}
+	defer tx.Rollback()
+
+	var tatNano int64
+	prevTAT := now
+	err = tx.QueryRowContext(ctx, `SELECT tat FROM rate_limits WHERE key = ?`, key).Scan(&tatNano)
+	switch {
+	case err == nil:
+		prevTAT = time.Unix(0, tatNano)
+	case err == sql.ErrNoRows:
+		// sem estado anterior: trate como se o tat já estivesse em dia.
+	default:
+		return false, 0, time.Time{}, err
+	}
+
+	allowed, newTAT, resetAt, remaining := gcraDecision(now, prevTAT, limit, burst)
+	if !allowed {
+		if err := tx.Commit(); err != nil {
+			return false, 0, time.Time{}, err
+		}
+		return false, 0, resetAt, nil
+	}
+
+	burstInterval := time.Duration(float64(time.Second)/float64(limit)) * time.Duration(burst)
+	expiresAt := newTAT.Add(burstInterval)
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO rate_limits (key, tat, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET tat = excluded.tat, expires_at = excluded.expires_at
+	`, key, newTAT.UnixNano(), expiresAt.Unix())
+	if err != nil {
+		return false, 0, time.Time{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, 0, time.Time{}, err
+	}
+
+	return true, remaining, resetAt, nil
+}
+
+// sweep apaga periodicamente as linhas de rate_limits cujo burst já expirou,
+// para a tabela não crescer indefinidamente com chaves (IPs, e-mails) que
+// nunca mais voltam a bater no limiter.
+func (s *SQLiteStore) sweep() {
+	ticker := time.NewTicker(s.sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			_, _ = s.db.Exec(`DELETE FROM rate_limits WHERE expires_at < ?`, time.Now().Unix())
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+func (s *SQLiteStore) Stop() {
+	close(s.stopCh)
+}
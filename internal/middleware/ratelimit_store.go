@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Store é o backend de estado de um RateLimiter: para uma chave, decide se
+// a próxima requisição pode passar e, caso não possa, até quando ela deve
+// esperar. RateLimiter não sabe se o Store é local ao processo ou
+// compartilhado entre réplicas (MemoryStore, SQLiteStore, RedisStore).
+type Store interface {
+	// Allow consome uma unidade de rate para key, configurada por rate/burst
+	// (mesma semântica de golang.org/x/time/rate) e window (usado para
+	// estimar X-RateLimit-Reset). Devolve se a requisição é permitida,
+	// quantas unidades restam e quando o limite se recompõe.
+	Allow(ctx context.Context, key string, limit rate.Limit, burst int, window time.Duration) (allowed bool, remaining int, resetAt time.Time, err error)
+}
+
+// memoryEntry guarda o rate.Limiter de uma chave e o último acesso, usado
+// por MemoryStore para expirar chaves ociosas.
+type memoryEntry struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// MemoryStore é o Store padrão de RateLimiter: mantém um rate.Limiter por
+// chave num sync.Map local ao processo. Simples e sem dependências, mas o
+// estado não é compartilhado entre réplicas e se perde a cada restart —
+// para isso, use SQLiteStore ou RedisStore.
+type MemoryStore struct {
+	limiters sync.Map
+	stopCh   chan struct{}
+}
+
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{stopCh: make(chan struct{})}
+	go s.cleanup()
+	return s
+}
+
+func (s *MemoryStore) Allow(_ context.Context, key string, limit rate.Limit, burst int, window time.Duration) (bool, int, time.Time, error) {
+	entryAny, _ := s.limiters.LoadOrStore(key, &memoryEntry{
+		limiter: rate.NewLimiter(limit, burst),
+	})
+
+	entry := entryAny.(*memoryEntry)
+	entry.lastSeen = time.Now()
+
+	allowed := entry.limiter.Allow()
+
+	remaining := int(entry.limiter.Tokens())
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return allowed, remaining, time.Now().Add(window), nil
+}
+
+func (s *MemoryStore) cleanup() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.limiters.Range(func(key, value interface{}) bool {
+				entry := value.(*memoryEntry)
+				if time.Since(entry.lastSeen) > 3*time.Minute {
+					s.limiters.Delete(key)
+				}
+				return true
+			})
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+func (s *MemoryStore) Stop() {
+	close(s.stopCh)
+}
+
+// gcraDecision aplica o GCRA (Generic Cell Rate Algorithm) a partir do tat
+// (theoretical arrival time) anterior de uma chave: new_tat é max(now, tat)
+// mais o intervalo entre emissões, e a requisição é permitida se
+// new_tat - now não ultrapassar o burst_interval (a folga acumulada pelo
+// burst). É a mesma fórmula usada pelo script Lua de RedisStore, escrita
+// aqui em Go para SQLiteStore.
+func gcraDecision(now, prevTAT time.Time, limit rate.Limit, burst int) (allowed bool, newTAT, resetAt time.Time, remaining int) {
+	if limit <= 0 {
+		limit = 1
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+
+	emissionInterval := time.Duration(float64(time.Second) / float64(limit))
+	burstInterval := emissionInterval * time.Duration(burst)
+
+	tat := prevTAT
+	if tat.Before(now) {
+		tat = now
+	}
+
+	candidateTAT := tat.Add(emissionInterval)
+	allowAt := candidateTAT.Add(-burstInterval)
+
+	if allowAt.After(now) {
+		return false, prevTAT, allowAt, 0
+	}
+
+	remaining = burst - int(candidateTAT.Sub(now)/emissionInterval)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return true, candidateTAT, candidateTAT, remaining
+}
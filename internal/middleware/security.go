@@ -1,41 +1,199 @@
 package middleware
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"net/http"
+	"strings"
 )
 
-func SecurityHeaders(isProd bool) func(http.Handler) http.Handler {
+// CSPPolicy monta o valor do cabeçalho Content-Security-Policy diretiva por
+// diretiva, em vez do texto fixo que SecurityHeaders aplicava antes desta
+// refatoração. Cada campo lista as fontes de uma diretiva (ex.:
+// []string{"'self'", "https://cdn.example.com"}); um campo vazio/nil omite
+// a diretiva inteira do cabeçalho, exceto ScriptSrc e StyleSrc: script-src e
+// style-src são sempre emitidas, ao menos com o nonce da requisição.
+type CSPPolicy struct {
+	DefaultSrc     []string
+	ScriptSrc      []string
+	StyleSrc       []string
+	ImgSrc         []string
+	FontSrc        []string
+	ConnectSrc     []string
+	FrameAncestors []string
+	// StrictDynamic acrescenta 'strict-dynamic' a script-src: navegadores
+	// que o suportam passam a confiar em qualquer script carregado por um
+	// script já autorizado pelo nonce, ignorando os hosts listados em
+	// ScriptSrc — que continuam valendo como fallback para navegadores mais
+	// antigos. Permite tirar CDNs do allowlist sem quebrar esses
+	// navegadores (ver https://csp.withgoogle.com/docs/strict-dynamic.html).
+	StrictDynamic bool
+}
+
+// DefaultCSPPolicy reproduz a política que SecurityHeaders aplicava de forma
+// fixa antes desta refatoração, CDNs incluídas, com uma diferença: style-src
+// leva o nonce da requisição em vez de 'unsafe-inline' (ver header), então um
+// <style> inline sem o nonce passa a ser bloqueado.
+func DefaultCSPPolicy() CSPPolicy {
+	return CSPPolicy{
+		DefaultSrc:     []string{"'self'"},
+		ScriptSrc:      []string{"'self'", "https://cdn.jsdelivr.net", "https://unpkg.com"},
+		StyleSrc:       []string{"'self'"},
+		ImgSrc:         []string{"'self'", "data:", "https:"},
+		FontSrc:        []string{"'self'"},
+		ConnectSrc:     []string{"'self'", "/events"},
+		FrameAncestors: []string{"'none'"},
+	}
+}
+
+// header monta o valor de Content-Security-Policy para esta política com
+// nonce anexado a script-src e style-src — nonce em style-src substitui o
+// 'unsafe-inline' que a política fixa anterior usava, que autorizava
+// qualquer <style> inline, nonce ou não.
+func (p CSPPolicy) header(nonce string) string {
+	scriptSrc := append(append([]string{}, p.ScriptSrc...), "'nonce-"+nonce+"'")
+	if p.StrictDynamic {
+		scriptSrc = append(scriptSrc, "'strict-dynamic'")
+	}
+	styleSrc := append(append([]string{}, p.StyleSrc...), "'nonce-"+nonce+"'")
+
+	var b strings.Builder
+	writeDirective := func(name string, sources []string) {
+		if len(sources) == 0 {
+			return
+		}
+		if b.Len() > 0 {
+			b.WriteString(" ")
+		}
+		b.WriteString(name)
+		b.WriteString(" ")
+		b.WriteString(strings.Join(sources, " "))
+		b.WriteString(";")
+	}
+
+	writeDirective("default-src", p.DefaultSrc)
+	writeDirective("script-src", scriptSrc)
+	writeDirective("style-src", styleSrc)
+	writeDirective("img-src", p.ImgSrc)
+	writeDirective("font-src", p.FontSrc)
+	writeDirective("connect-src", p.ConnectSrc)
+	writeDirective("frame-ancestors", p.FrameAncestors)
+
+	return b.String()
+}
+
+// SecurityHeadersConfig controla os cabeçalhos que SecurityHeaders aplica a
+// cada resposta. O zero value não é utilizável diretamente — construa a
+// partir de DefaultSecurityHeadersConfig.
+type SecurityHeadersConfig struct {
+	IsProd bool
+	CSP    CSPPolicy
+	// RouteOverrides troca a CSPPolicy inteira para requests cujo
+	// r.URL.Path bate exatamente com uma chave — para rotas que legitimamente
+	// precisam de embeds ou CDNs que o resto do app não usa, sem afrouxar a
+	// política default para todo o site. Ver WithRouteOverrides.
+	RouteOverrides map[string]CSPPolicy
+	// RequireTrustedTypes liga Require-Trusted-Types-For: 'script', que
+	// bloqueia sinks DOM perigosos (innerHTML, document.write etc.) a menos
+	// que o valor passe por um TrustedTypePolicy registrado no navegador.
+	// Opt-in porque exige que todo o JS do app já esteja livre de innerHTML
+	// com string crua, o que esta refatoração não garante sozinha.
+	RequireTrustedTypes bool
+	// CrossOriginIsolation liga Cross-Origin-Opener-Policy: same-origin e
+	// Cross-Origin-Embedder-Policy: require-corp, isolando o processo de
+	// browsing context do app (necessário para APIs como SharedArrayBuffer).
+	// Opt-in porque require-corp quebra qualquer recurso cross-origin
+	// (imagem, script de CDN) que não sirva Cross-Origin-Resource-Policy.
+	CrossOriginIsolation bool
+}
+
+// DefaultSecurityHeadersConfig devolve a configuração equivalente ao
+// comportamento de SecurityHeaders antes desta refatoração (exceto style-src,
+// ver DefaultCSPPolicy): CSP de DefaultCSPPolicy, sem overrides de rota nem os cabeçalhos de isolamento
+// opt-in.
+func DefaultSecurityHeadersConfig(isProd bool) SecurityHeadersConfig {
+	return SecurityHeadersConfig{
+		IsProd: isProd,
+		CSP:    DefaultCSPPolicy(),
+	}
+}
+
+// WithRouteOverrides devolve uma cópia de cfg com RouteOverrides substituído
+// por overrides, para compor com DefaultSecurityHeadersConfig sem repetir os
+// outros campos:
+//
+//	middleware.SecurityHeaders(middleware.WithRouteOverrides(
+//		middleware.DefaultSecurityHeadersConfig(isProd),
+//		map[string]CSPPolicy{"/embed/widget": embedPolicy},
+//	))
+func WithRouteOverrides(cfg SecurityHeadersConfig, overrides map[string]CSPPolicy) SecurityHeadersConfig {
+	cfg.RouteOverrides = overrides
+	return cfg
+}
+
+type cspNonceContextKey struct{}
+
+// CSPNonceFromContext devolve o nonce gerado por SecurityHeaders para a
+// requisição de ctx, ou "" se SecurityHeaders não rodou no pipeline. Use em
+// templates (o handler chama Render(r.Context(), w), então o valor chega até
+// o template) para atribuir o mesmo nonce a um <script>/<style> inline em vez
+// de depender de 'unsafe-inline'.
+func CSPNonceFromContext(ctx context.Context) string {
+	nonce, _ := ctx.Value(cspNonceContextKey{}).(string)
+	return nonce
+}
+
+// SecurityHeaders aplica os cabeçalhos de segurança padrão do app, incluindo
+// uma Content-Security-Policy com nonce por requisição — ver
+// SecurityHeadersConfig para as opções e DefaultSecurityHeadersConfig para o
+// comportamento equivalente ao anterior a esta refatoração.
+func SecurityHeaders(cfg SecurityHeadersConfig) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			nonce := generateNonce()
+			nonce, err := generateNonce()
+			if err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
 
 			w.Header().Set("X-Frame-Options", "DENY")
 			w.Header().Set("X-Content-Type-Options", "nosniff")
 			w.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
 			w.Header().Set("X-XSS-Protection", "1; mode=block")
 
-			if isProd {
+			if cfg.IsProd {
 				w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
 			}
 
-			w.Header().Set("Content-Security-Policy",
-				"default-src 'self'; "+
-					"script-src 'self' 'nonce-"+nonce+"' https://cdn.jsdelivr.net https://unpkg.com; "+
-					"style-src 'self' 'unsafe-inline'; "+
-					"img-src 'self' data: https:; "+
-					"font-src 'self'; "+
-					"connect-src 'self' /events; "+
-					"frame-ancestors 'none';")
+			policy := cfg.CSP
+			if override, ok := cfg.RouteOverrides[r.URL.Path]; ok {
+				policy = override
+			}
+			w.Header().Set("Content-Security-Policy", policy.header(nonce))
 
-			next.ServeHTTP(w, r)
+			if cfg.RequireTrustedTypes {
+				w.Header().Set("Require-Trusted-Types-For", "'script'")
+			}
+			if cfg.CrossOriginIsolation {
+				w.Header().Set("Cross-Origin-Opener-Policy", "same-origin")
+				w.Header().Set("Cross-Origin-Embedder-Policy", "require-corp")
+			}
+
+			ctx := context.WithValue(r.Context(), cspNonceContextKey{}, nonce)
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
-func generateNonce() string {
+// generateNonce gera um nonce CSP de 16 bytes aleatórios, codificado em hex.
+// Propaga o erro de rand.Read em vez de ignorá-lo (como oidc.RandomString):
+// um nonce previsível (ex. zerado, se a leitura falhasse silenciosamente)
+// anularia a proteção do CSP baseada em nonce.
+func generateNonce() (string, error) {
 	b := make([]byte, 16)
-	_, _ = rand.Read(b)
-	return hex.EncodeToString(b)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGenerateNonce(t *testing.T) {
+	a, err := generateNonce()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := generateNonce()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(a) != 32 { // 16 bytes em hex
+		t.Errorf("nonce length = %d, want 32", len(a))
+	}
+	if a == b {
+		t.Error("two consecutive nonces were identical, want independent random values")
+	}
+}
+
+func TestSecurityHeaders_SetsCSPWithNonceAndExposesItInContext(t *testing.T) {
+	cfg := DefaultSecurityHeadersConfig(false)
+
+	var nonceSeenByHandler string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonceSeenByHandler = CSPNonceFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	rr := httptest.NewRecorder()
+	SecurityHeaders(cfg)(next).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusOK)
+	}
+	if nonceSeenByHandler == "" {
+		t.Fatal("CSPNonceFromContext returned empty inside the handler")
+	}
+
+	csp := rr.Header().Get("Content-Security-Policy")
+	if !strings.Contains(csp, "'nonce-"+nonceSeenByHandler+"'") {
+		t.Errorf("Content-Security-Policy %q does not contain the nonce exposed via context", csp)
+	}
+
+	if got := rr.Header().Get("X-Frame-Options"); got != "DENY" {
+		t.Errorf("X-Frame-Options = %q, want DENY", got)
+	}
+	if got := rr.Header().Get("Strict-Transport-Security"); got != "" {
+		t.Errorf("Strict-Transport-Security = %q, want unset when IsProd is false", got)
+	}
+}
+
+func TestSecurityHeaders_SetsHSTSOnlyWhenProd(t *testing.T) {
+	cfg := DefaultSecurityHeadersConfig(true)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	req := httptest.NewRequest("GET", "/", nil)
+	rr := httptest.NewRecorder()
+	SecurityHeaders(cfg)(next).ServeHTTP(rr, req)
+
+	if got := rr.Header().Get("Strict-Transport-Security"); got == "" {
+		t.Error("Strict-Transport-Security not set when IsProd is true")
+	}
+}
+
+func TestSecurityHeaders_AppliesRouteOverride(t *testing.T) {
+	cfg := DefaultSecurityHeadersConfig(false)
+	cfg = WithRouteOverrides(cfg, map[string]CSPPolicy{
+		"/embed/widget": {FrameAncestors: []string{"https://partner.example.com"}},
+	})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	req := httptest.NewRequest("GET", "/embed/widget", nil)
+	rr := httptest.NewRecorder()
+	SecurityHeaders(cfg)(next).ServeHTTP(rr, req)
+
+	csp := rr.Header().Get("Content-Security-Policy")
+	if !strings.Contains(csp, "frame-ancestors https://partner.example.com;") {
+		t.Errorf("Content-Security-Policy %q did not apply the route override", csp)
+	}
+	if strings.Contains(csp, "default-src") {
+		t.Errorf("Content-Security-Policy %q applied the default policy's directives alongside the override", csp)
+	}
+}
+
+func TestCSPNonceFromContext_EmptyWhenNotSet(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	if got := CSPNonceFromContext(req.Context()); got != "" {
+		t.Errorf("CSPNonceFromContext = %q, want empty string when SecurityHeaders did not run", got)
+	}
+}
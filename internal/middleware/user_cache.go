@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/PauloHFS/goth/internal/db"
+)
+
+// UserCache é consultado por RequireAuth antes de ir ao banco buscar o
+// usuário da sessão (ver WithUserCache), para que apps de altíssimo tráfego
+// não consultem o banco a cada request autenticado.
+// Implementações: MemoryUserCache (por processo, sharded) e RedisUserCache
+// (compartilhado entre réplicas, reaproveitando o redisConn do RedisStore).
+type UserCache interface {
+	// Get retorna a entrada em cache para id. found é false em cache miss;
+	// quando found é true, exists distingue um hit positivo (usuário
+	// existe, user preenchido) de um hit negativo (usuário não encontrado
+	// em consulta anterior, cacheado para evitar repetir a query).
+	Get(ctx context.Context, id int64) (user db.User, exists bool, found bool)
+	// Put grava um hit positivo para id, válido por ttl.
+	Put(ctx context.Context, id int64, user db.User, ttl time.Duration)
+	// PutNotFound grava um hit negativo para id, válido por ttl.
+	PutNotFound(ctx context.Context, id int64, ttl time.Duration)
+	// Invalidate remove qualquer entrada (positiva ou negativa) para id —
+	// chamado nos pontos onde o usuário muda (perfil, role, senha, logout)
+	// para que dados obsoletos não sobrevivam ao reload natural do cache.
+	Invalidate(ctx context.Context, id int64)
+}
+
+const defaultUserCacheShards = 32
+
+// userCacheEntry é o valor guardado em cada shard; exists=false representa
+// um hit negativo (usuário não encontrado).
+type userCacheEntry struct {
+	id      int64
+	user    db.User
+	exists  bool
+	expires time.Time
+}
+
+// userCacheShard é um LRU+TTL independente, com seu próprio mutex — sharded
+// por id para que um MemoryUserCache sob alta concorrência não serialize
+// todo request em um único lock global.
+type userCacheShard struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[int64]*list.Element
+	order    *list.List
+}
+
+// MemoryUserCache é um UserCache em processo: LRU com expiração por TTL,
+// sharded por id e com estatísticas de hit/miss em contadores atomic (sem
+// envolver o mutex do shard no caminho de leitura das métricas).
+type MemoryUserCache struct {
+	shards []*userCacheShard
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+const defaultMemoryUserCacheCapacity = 10_000
+
+// NewMemoryUserCache cria um MemoryUserCache capaz de guardar até maxEntries
+// usuários no total (distribuídos entre os shards); maxEntries <= 0 usa o
+// padrão de 10 mil.
+func NewMemoryUserCache(maxEntries int) *MemoryUserCache {
+	if maxEntries <= 0 {
+		maxEntries = defaultMemoryUserCacheCapacity
+	}
+
+	perShard := maxEntries / defaultUserCacheShards
+	if perShard < 1 {
+		perShard = 1
+	}
+
+	shards := make([]*userCacheShard, defaultUserCacheShards)
+	for i := range shards {
+		shards[i] = &userCacheShard{
+			capacity: perShard,
+			items:    make(map[int64]*list.Element),
+			order:    list.New(),
+		}
+	}
+
+	return &MemoryUserCache{shards: shards}
+}
+
+func (c *MemoryUserCache) shardFor(id int64) *userCacheShard {
+	return c.shards[uint64(id)%uint64(len(c.shards))]
+}
+
+func (c *MemoryUserCache) Get(_ context.Context, id int64) (db.User, bool, bool) {
+	shard := c.shardFor(id)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	el, ok := shard.items[id]
+	if !ok {
+		c.misses.Add(1)
+		return db.User{}, false, false
+	}
+
+	entry := el.Value.(*userCacheEntry)
+	if time.Now().After(entry.expires) {
+		shard.order.Remove(el)
+		delete(shard.items, id)
+		c.misses.Add(1)
+		return db.User{}, false, false
+	}
+
+	shard.order.MoveToFront(el)
+	c.hits.Add(1)
+	return entry.user, entry.exists, true
+}
+
+func (c *MemoryUserCache) Put(_ context.Context, id int64, user db.User, ttl time.Duration) {
+	c.set(id, user, true, ttl)
+}
+
+func (c *MemoryUserCache) PutNotFound(_ context.Context, id int64, ttl time.Duration) {
+	c.set(id, db.User{}, false, ttl)
+}
+
+func (c *MemoryUserCache) set(id int64, user db.User, exists bool, ttl time.Duration) {
+	shard := c.shardFor(id)
+	entry := &userCacheEntry{id: id, user: user, exists: exists, expires: time.Now().Add(ttl)}
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if el, ok := shard.items[id]; ok {
+		el.Value = entry
+		shard.order.MoveToFront(el)
+		return
+	}
+
+	shard.items[id] = shard.order.PushFront(entry)
+
+	for shard.order.Len() > shard.capacity {
+		oldest := shard.order.Back()
+		if oldest == nil {
+			break
+		}
+		shard.order.Remove(oldest)
+		delete(shard.items, oldest.Value.(*userCacheEntry).id)
+	}
+}
+
+func (c *MemoryUserCache) Invalidate(_ context.Context, id int64) {
+	shard := c.shardFor(id)
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if el, ok := shard.items[id]; ok {
+		shard.order.Remove(el)
+		delete(shard.items, id)
+	}
+}
+
+// Stats retorna os contadores acumulados de hit/miss, para expor em métricas
+// ou depuração administrativa.
+func (c *MemoryUserCache) Stats() (hits, misses int64) {
+	return c.hits.Load(), c.misses.Load()
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/PauloHFS/goth/internal/db"
+)
+
+// redisUserCacheKeyPrefix isola as chaves do cache de usuários das chaves de
+// rate limit de RedisStore (a key do RateLimiter, ex. "auth:1.2.3.4") que
+// também vivem no mesmo Redis.
+const redisUserCacheKeyPrefix = "usercache:"
+
+// redisUserCacheEntry é o payload JSON gravado no Redis; Exists=false
+// representa um hit negativo (usuário não encontrado).
+type redisUserCacheEntry struct {
+	Exists bool    `json:"exists"`
+	User   db.User `json:"user,omitempty"`
+}
+
+// RedisUserCache é um UserCache compartilhado entre réplicas, persistido no
+// mesmo Redis usado por RedisStore — recebe o *redisConn já existente em vez
+// de abrir uma segunda conexão, para as duas features dividirem a mesma
+// conexão TCP (redisConn não faz pooling).
+type RedisUserCache struct {
+	conn *redisConn
+}
+
+// NewRedisUserCache cria um RedisUserCache sobre conn — normalmente obtido
+// via (*RedisStore).Conn() do rate limiter já configurado para o mesmo Redis.
+func NewRedisUserCache(conn *redisConn) *RedisUserCache {
+	return &RedisUserCache{conn: conn}
+}
+
+func redisUserCacheKey(id int64) string {
+	return redisUserCacheKeyPrefix + strconv.FormatInt(id, 10)
+}
+
+func (c *RedisUserCache) Get(ctx context.Context, id int64) (db.User, bool, bool) {
+	reply, err := c.conn.do(ctx, "GET", redisUserCacheKey(id))
+	if err != nil {
+		return db.User{}, false, false
+	}
+
+	raw, ok := reply.(string)
+	if !ok {
+		return db.User{}, false, false
+	}
+
+	var entry redisUserCacheEntry
+	if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+		return db.User{}, false, false
+	}
+
+	return entry.User, entry.Exists, true
+}
+
+func (c *RedisUserCache) Put(ctx context.Context, id int64, user db.User, ttl time.Duration) {
+	c.set(ctx, id, redisUserCacheEntry{Exists: true, User: user}, ttl)
+}
+
+func (c *RedisUserCache) PutNotFound(ctx context.Context, id int64, ttl time.Duration) {
+	c.set(ctx, id, redisUserCacheEntry{Exists: false}, ttl)
+}
+
+func (c *RedisUserCache) set(ctx context.Context, id int64, entry redisUserCacheEntry, ttl time.Duration) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	if ttl <= 0 {
+		ttl = defaultUserCacheTTL
+	}
+
+	_, _ = c.conn.do(ctx, "SET", redisUserCacheKey(id), string(data), "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+}
+
+func (c *RedisUserCache) Invalidate(ctx context.Context, id int64) {
+	_, _ = c.conn.do(ctx, "DEL", redisUserCacheKey(id))
+}
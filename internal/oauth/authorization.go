@@ -0,0 +1,162 @@
+package oauth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/PauloHFS/goth/internal/oidc"
+)
+
+// authorizationCodeTTL é a validade de um código de autorização emitido por
+// GET/POST /oauth/authorize — curta o bastante para só sobreviver à troca
+// imediata em POST /oauth/token, como exige o RFC 6749.
+const authorizationCodeTTL = 2 * time.Minute
+
+// Authorization representa uma linha de oauth_authorizations: um código de
+// autorização de uso único, vinculado a um desafio PKCE.
+type Authorization struct {
+	Code                string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	UserID              int64
+	ClientID            string
+	Scopes              []string
+	ExpiresAt           time.Time
+	ConsumedAt          sql.NullTime
+}
+
+// Expired reporta se o código já passou do TTL.
+func (a Authorization) Expired(now time.Time) bool {
+	return now.After(a.ExpiresAt)
+}
+
+// AuthorizationStore grava e consome códigos de autorização.
+type AuthorizationStore struct {
+	db *sql.DB
+}
+
+// NewAuthorizationStore cria um AuthorizationStore sobre a conexão de
+// escrita do banco.
+func NewAuthorizationStore(dbConn *sql.DB) *AuthorizationStore {
+	return &AuthorizationStore{db: dbConn}
+}
+
+// EnsureTable cria a tabela oauth_authorizations se ainda não existir.
+func (s *AuthorizationStore) EnsureTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS oauth_authorizations (
+			code TEXT PRIMARY KEY,
+			code_challenge TEXT NOT NULL,
+			code_challenge_method TEXT NOT NULL,
+			user_id INTEGER NOT NULL,
+			client_id TEXT NOT NULL,
+			scopes TEXT NOT NULL DEFAULT '[]',
+			expires_at DATETIME NOT NULL,
+			consumed_at DATETIME
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("falha ao garantir tabela oauth_authorizations: %w", err)
+	}
+	return nil
+}
+
+// CreateParams descreve os dados necessários para emitir um novo código.
+type CreateParams struct {
+	UserID              int64
+	ClientID            string
+	Scopes              []string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// Create emite um código de autorização de uso único, válido por
+// authorizationCodeTTL.
+func (s *AuthorizationStore) Create(ctx context.Context, params CreateParams) (Authorization, error) {
+	code, err := oidc.RandomString(32)
+	if err != nil {
+		return Authorization{}, err
+	}
+
+	scopesJSON, err := json.Marshal(params.Scopes)
+	if err != nil {
+		return Authorization{}, err
+	}
+
+	expiresAt := time.Now().Add(authorizationCodeTTL)
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO oauth_authorizations (code, code_challenge, code_challenge_method, user_id, client_id, scopes, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, code, params.CodeChallenge, params.CodeChallengeMethod, params.UserID, params.ClientID, string(scopesJSON), expiresAt)
+	if err != nil {
+		return Authorization{}, fmt.Errorf("falha ao gravar código de autorização: %w", err)
+	}
+
+	return Authorization{
+		Code:                code,
+		CodeChallenge:       params.CodeChallenge,
+		CodeChallengeMethod: params.CodeChallengeMethod,
+		UserID:              params.UserID,
+		ClientID:            params.ClientID,
+		Scopes:              params.Scopes,
+		ExpiresAt:           expiresAt,
+	}, nil
+}
+
+// Consume busca o código e o marca como usado atomicamente (um UPDATE
+// condicionado a consumed_at IS NULL), prevenindo replay do mesmo código.
+func (s *AuthorizationStore) Consume(ctx context.Context, code string) (Authorization, error) {
+	var a Authorization
+	var scopesJSON string
+	var consumedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, `
+		SELECT code, code_challenge, code_challenge_method, user_id, client_id, scopes, expires_at, consumed_at
+		FROM oauth_authorizations WHERE code = ?
+	`, code).Scan(&a.Code, &a.CodeChallenge, &a.CodeChallengeMethod, &a.UserID, &a.ClientID, &scopesJSON, &a.ExpiresAt, &consumedAt)
+	if err != nil {
+		return Authorization{}, err
+	}
+	a.ConsumedAt = consumedAt
+
+	if consumedAt.Valid {
+		return Authorization{}, fmt.Errorf("código de autorização já utilizado")
+	}
+	if a.Expired(time.Now()) {
+		return Authorization{}, fmt.Errorf("código de autorização expirado")
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE oauth_authorizations SET consumed_at = ? WHERE code = ? AND consumed_at IS NULL
+	`, time.Now(), code)
+	if err != nil {
+		return Authorization{}, fmt.Errorf("falha ao consumir código de autorização: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return Authorization{}, err
+	}
+	if affected == 0 {
+		return Authorization{}, fmt.Errorf("código de autorização já utilizado")
+	}
+
+	if err := json.Unmarshal([]byte(scopesJSON), &a.Scopes); err != nil {
+		return Authorization{}, fmt.Errorf("scopes inválidos no código de autorização: %w", err)
+	}
+
+	return a, nil
+}
+
+// VerifyPKCE confere o code_verifier recebido em /oauth/token contra o
+// code_challenge gravado em /oauth/authorize. Apenas S256 é suportado —
+// "plain" expõe o verifier em texto puro se o código vazar em logs/proxies.
+func (a Authorization) VerifyPKCE(verifier string) bool {
+	if !strings.EqualFold(a.CodeChallengeMethod, "S256") {
+		return false
+	}
+	return oidc.Challenge(verifier) == a.CodeChallenge
+}
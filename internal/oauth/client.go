@@ -0,0 +1,195 @@
+// Package oauth transforma este módulo em um authorization server OAuth2/OIDC
+// para aplicações de terceiros ("Sign in with this GOTH app"): clientes
+// registrados, o fluxo Authorization Code com PKCE, emissão de tokens
+// assinados (RS256) e os endpoints de descoberta exigidos por um cliente
+// OIDC padrão. Ver internal/web/oauth.go para os handlers HTTP.
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Client representa uma aplicação de terceiros registrada em oauth_clients.
+type Client struct {
+	ClientID         string
+	ClientSecretHash string
+	Name             string
+	RedirectURIs     []string
+	AllowedScopes    []string
+	IsPublic         bool
+	CreatedAt        time.Time
+}
+
+// HasRedirectURI reporta se uri está na allowlist do cliente — exigido antes
+// de emitir qualquer código de autorização.
+func (c Client) HasRedirectURI(uri string) bool {
+	for _, allowed := range c.RedirectURIs {
+		if allowed == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowedScopeSet filtra requested para os escopos que o cliente pode pedir,
+// na mesma ordem em que foram solicitados.
+func (c Client) AllowedScopeSet(requested []string) []string {
+	allowed := make([]string, 0, len(requested))
+	for _, scope := range requested {
+		for _, clientScope := range c.AllowedScopes {
+			if scope == clientScope {
+				allowed = append(allowed, scope)
+				break
+			}
+		}
+	}
+	return allowed
+}
+
+// ClientStore grava e consulta aplicações OAuth2 registradas.
+type ClientStore struct {
+	db *sql.DB
+}
+
+// NewClientStore cria um ClientStore sobre a conexão de escrita do banco.
+func NewClientStore(dbConn *sql.DB) *ClientStore {
+	return &ClientStore{db: dbConn}
+}
+
+// EnsureTable cria a tabela oauth_clients se ainda não existir. Assim como
+// internal/session.Store.EnsureTable, fica fora do fluxo normal de
+// db.RunMigrations até que uma migração dedicada seja escrita.
+func (s *ClientStore) EnsureTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS oauth_clients (
+			client_id TEXT PRIMARY KEY,
+			client_secret_hash TEXT NOT NULL,
+			name TEXT NOT NULL,
+			redirect_uris TEXT NOT NULL DEFAULT '[]',
+			allowed_scopes TEXT NOT NULL DEFAULT '[]',
+			is_public BOOLEAN NOT NULL DEFAULT 0,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("falha ao garantir tabela oauth_clients: %w", err)
+	}
+	return nil
+}
+
+// Register cria um novo cliente, gerando client_id/client_secret. Para
+// clientes públicos (SPAs, apps mobile, sem como guardar segredo), o secret
+// retornado é vazio e ValidateSecret sempre aceita.
+func (s *ClientStore) Register(ctx context.Context, name string, redirectURIs, allowedScopes []string, isPublic bool) (clientID, clientSecret string, err error) {
+	clientID, err = randomID("client")
+	if err != nil {
+		return "", "", err
+	}
+
+	var secretHash string
+	if !isPublic {
+		clientSecret, err = randomID("secret")
+		if err != nil {
+			return "", "", err
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(clientSecret), bcrypt.DefaultCost)
+		if err != nil {
+			return "", "", fmt.Errorf("falha ao hashear client_secret: %w", err)
+		}
+		secretHash = string(hash)
+	}
+
+	redirectJSON, err := json.Marshal(redirectURIs)
+	if err != nil {
+		return "", "", err
+	}
+	scopesJSON, err := json.Marshal(allowedScopes)
+	if err != nil {
+		return "", "", err
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO oauth_clients (client_id, client_secret_hash, name, redirect_uris, allowed_scopes, is_public)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, clientID, secretHash, name, string(redirectJSON), string(scopesJSON), isPublic)
+	if err != nil {
+		return "", "", fmt.Errorf("falha ao registrar cliente oauth: %w", err)
+	}
+
+	return clientID, clientSecret, nil
+}
+
+// Get busca um cliente pelo client_id.
+func (s *ClientStore) Get(ctx context.Context, clientID string) (Client, error) {
+	var c Client
+	var redirectJSON, scopesJSON string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT client_id, client_secret_hash, name, redirect_uris, allowed_scopes, is_public, created_at
+		FROM oauth_clients WHERE client_id = ?
+	`, clientID).Scan(&c.ClientID, &c.ClientSecretHash, &c.Name, &redirectJSON, &scopesJSON, &c.IsPublic, &c.CreatedAt)
+	if err != nil {
+		return Client{}, err
+	}
+
+	if err := json.Unmarshal([]byte(redirectJSON), &c.RedirectURIs); err != nil {
+		return Client{}, fmt.Errorf("redirect_uris inválido para %q: %w", clientID, err)
+	}
+	if err := json.Unmarshal([]byte(scopesJSON), &c.AllowedScopes); err != nil {
+		return Client{}, fmt.Errorf("allowed_scopes inválido para %q: %w", clientID, err)
+	}
+
+	return c, nil
+}
+
+// List lista todos os clientes registrados, mais recentes primeiro — usado
+// pela tela /admin/apps.
+func (s *ClientStore) List(ctx context.Context) ([]Client, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT client_id, client_secret_hash, name, redirect_uris, allowed_scopes, is_public, created_at
+		FROM oauth_clients ORDER BY created_at DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao listar clientes oauth: %w", err)
+	}
+	defer rows.Close()
+
+	var clients []Client
+	for rows.Next() {
+		var c Client
+		var redirectJSON, scopesJSON string
+		if err := rows.Scan(&c.ClientID, &c.ClientSecretHash, &c.Name, &redirectJSON, &scopesJSON, &c.IsPublic, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("falha ao ler linha de cliente oauth: %w", err)
+		}
+		_ = json.Unmarshal([]byte(redirectJSON), &c.RedirectURIs)
+		_ = json.Unmarshal([]byte(scopesJSON), &c.AllowedScopes)
+		clients = append(clients, c)
+	}
+	return clients, nil
+}
+
+// ValidateSecret confere secret contra o hash do cliente. Clientes públicos
+// (is_public) não carregam segredo e são sempre aceitos aqui — a proteção
+// deles vem do PKCE obrigatório, não de client authentication.
+func (c Client) ValidateSecret(secret string) bool {
+	if c.IsPublic {
+		return true
+	}
+	return bcrypt.CompareHashAndPassword([]byte(c.ClientSecretHash), []byte(secret)) == nil
+}
+
+func randomID(prefix string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + "_" + strings.ToLower(hex.EncodeToString(b)), nil
+}
@@ -0,0 +1,213 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"database/sql"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// signingKeyBits é o tamanho da chave RSA usada para assinar access/ID
+// tokens — o mesmo piso (2048 bits) aceito por todo provedor OIDC relevante.
+const signingKeyBits = 2048
+
+// signingKeyRotationInterval é por quanto tempo uma chave ativa assina
+// novos tokens antes de uma rotação automática; a chave anterior continua
+// publicada no JWKS por mais signingKeyGracePeriod para não invalidar
+// tokens/ID tokens já emitidos.
+const (
+	signingKeyRotationInterval = 30 * 24 * time.Hour
+	signingKeyGracePeriod      = 7 * 24 * time.Hour
+)
+
+// SigningKey representa uma linha da tabela signing_keys.
+type SigningKey struct {
+	KID           string
+	PrivateKeyPEM string
+	CreatedAt     time.Time
+	RotatedAt     sql.NullTime
+}
+
+// KeyStore gerencia o par de chaves RSA usado para assinar tokens e o
+// rodízio delas, persistindo tudo em signing_keys para sobreviver a
+// restarts do processo.
+type KeyStore struct {
+	db *sql.DB
+	mu sync.Mutex
+}
+
+// NewKeyStore cria um KeyStore sobre a conexão de escrita do banco.
+func NewKeyStore(dbConn *sql.DB) *KeyStore {
+	return &KeyStore{db: dbConn}
+}
+
+// EnsureTable cria a tabela signing_keys se ainda não existir.
+func (s *KeyStore) EnsureTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS signing_keys (
+			kid TEXT PRIMARY KEY,
+			private_key_pem TEXT NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			rotated_at DATETIME
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("falha ao garantir tabela signing_keys: %w", err)
+	}
+	return nil
+}
+
+// Active retorna a chave privada em uso para assinar novos tokens,
+// gerando e persistindo uma nova quando nenhuma existe ainda ou a mais
+// recente já passou de signingKeyRotationInterval.
+func (s *KeyStore) Active(ctx context.Context) (*rsa.PrivateKey, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var kid, pemStr string
+	var createdAt time.Time
+	err := s.db.QueryRowContext(ctx, `
+		SELECT kid, private_key_pem, created_at FROM signing_keys
+		WHERE rotated_at IS NULL ORDER BY created_at DESC LIMIT 1
+	`).Scan(&kid, &pemStr, &createdAt)
+
+	if err == nil && time.Since(createdAt) < signingKeyRotationInterval {
+		key, parseErr := parsePrivateKeyPEM(pemStr)
+		if parseErr != nil {
+			return nil, "", parseErr
+		}
+		return key, kid, nil
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return nil, "", fmt.Errorf("falha ao buscar chave de assinatura ativa: %w", err)
+	}
+
+	if err == nil {
+		// A chave ativa passou do intervalo de rotação: mantém publicada no
+		// JWKS durante a janela de graça, mas deixa de assinar novos tokens.
+		if _, rotErr := s.db.ExecContext(ctx, `UPDATE signing_keys SET rotated_at = ? WHERE kid = ?`, time.Now(), kid); rotErr != nil {
+			return nil, "", fmt.Errorf("falha ao rotacionar chave de assinatura: %w", rotErr)
+		}
+	}
+
+	return s.generate(ctx)
+}
+
+func (s *KeyStore) generate(ctx context.Context) (*rsa.PrivateKey, string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, signingKeyBits)
+	if err != nil {
+		return nil, "", fmt.Errorf("falha ao gerar chave de assinatura: %w", err)
+	}
+
+	kid, err := randomID("key")
+	if err != nil {
+		return nil, "", err
+	}
+
+	pemStr := encodePrivateKeyPEM(key)
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO signing_keys (kid, private_key_pem) VALUES (?, ?)
+	`, kid, pemStr); err != nil {
+		return nil, "", fmt.Errorf("falha ao persistir chave de assinatura: %w", err)
+	}
+
+	return key, kid, nil
+}
+
+// PublicJWKS retorna as chaves ainda dentro da janela de graça (ativa ou
+// recém-rotacionada) no formato exposto por GET /.well-known/jwks.json.
+func (s *KeyStore) PublicJWKS(ctx context.Context) (JWKSet, error) {
+	cutoff := time.Now().Add(-signingKeyGracePeriod)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT kid, private_key_pem FROM signing_keys
+		WHERE rotated_at IS NULL OR rotated_at > ?
+		ORDER BY created_at DESC
+	`, cutoff)
+	if err != nil {
+		return JWKSet{}, fmt.Errorf("falha ao listar chaves de assinatura: %w", err)
+	}
+	defer rows.Close()
+
+	var set JWKSet
+	for rows.Next() {
+		var kid, pemStr string
+		if err := rows.Scan(&kid, &pemStr); err != nil {
+			return JWKSet{}, err
+		}
+		key, err := parsePrivateKeyPEM(pemStr)
+		if err != nil {
+			return JWKSet{}, err
+		}
+		set.Keys = append(set.Keys, publicJWK(kid, &key.PublicKey))
+	}
+	return set, nil
+}
+
+// PublicKey resolve a chave pública identificada por kid, usada para
+// verificar a assinatura de tokens emitidos por este servidor (ex. no
+// endpoint userinfo).
+func (s *KeyStore) PublicKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	var pemStr string
+	err := s.db.QueryRowContext(ctx, `SELECT private_key_pem FROM signing_keys WHERE kid = ?`, kid).Scan(&pemStr)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parsePrivateKeyPEM(pemStr)
+	if err != nil {
+		return nil, err
+	}
+	return &key.PublicKey, nil
+}
+
+func encodePrivateKeyPEM(key *rsa.PrivateKey) string {
+	der := x509.MarshalPKCS1PrivateKey(key)
+	block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: der}
+	return string(pem.EncodeToMemory(block))
+}
+
+func parsePrivateKeyPEM(pemStr string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(pemStr))
+	if block == nil {
+		return nil, fmt.Errorf("chave de assinatura em PEM inválido")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("falha ao decodificar chave de assinatura: %w", err)
+	}
+	return key, nil
+}
+
+// JWK é a representação pública de uma chave RSA em um JSON Web Key Set —
+// mesmo formato consumido por internal/oidc ao verificar provedores
+// externos, aqui produzido em vez de consumido.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKSet é o corpo JSON de GET /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+func publicJWK(kid string, pub *rsa.PublicKey) JWK {
+	return JWK{
+		Kty: "RSA",
+		Kid: kid,
+		Alg: "RS256",
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}
+}
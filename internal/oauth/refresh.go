@@ -0,0 +1,126 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/PauloHFS/goth/internal/oidc"
+)
+
+// RefreshTokenTTL é a validade de um refresh_token — bem mais longa que o
+// access token, já que é ele quem sustenta sessões de app de terceiro sem
+// exigir um novo consentimento.
+const RefreshTokenTTL = 30 * 24 * time.Hour
+
+// RefreshStore grava e consulta refresh tokens opacos, hasheados em
+// repouso como internal/session.Store faz com o token do scs.
+type RefreshStore struct {
+	db *sql.DB
+}
+
+// NewRefreshStore cria um RefreshStore sobre a conexão de escrita do banco.
+func NewRefreshStore(dbConn *sql.DB) *RefreshStore {
+	return &RefreshStore{db: dbConn}
+}
+
+// EnsureTable cria a tabela oauth_refresh_tokens se ainda não existir.
+func (s *RefreshStore) EnsureTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS oauth_refresh_tokens (
+			token_hash TEXT PRIMARY KEY,
+			user_id INTEGER NOT NULL,
+			client_id TEXT NOT NULL,
+			scopes TEXT NOT NULL DEFAULT '[]',
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			expires_at DATETIME NOT NULL,
+			revoked_at DATETIME
+		);
+		CREATE INDEX IF NOT EXISTS idx_oauth_refresh_tokens_user ON oauth_refresh_tokens(user_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("falha ao garantir tabela oauth_refresh_tokens: %w", err)
+	}
+	return nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create emite um novo refresh_token para (userID, clientID, scopes).
+func (s *RefreshStore) Create(ctx context.Context, userID int64, clientID string, scopes []string) (string, error) {
+	token, err := oidc.RandomString(32)
+	if err != nil {
+		return "", err
+	}
+
+	scopesJSON, err := json.Marshal(scopes)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO oauth_refresh_tokens (token_hash, user_id, client_id, scopes, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, hashRefreshToken(token), userID, clientID, string(scopesJSON), time.Now().Add(RefreshTokenTTL))
+	if err != nil {
+		return "", fmt.Errorf("falha ao gravar refresh token: %w", err)
+	}
+
+	return token, nil
+}
+
+// Grant é o resultado de validar um refresh_token em POST /oauth/token.
+type Grant struct {
+	UserID   int64
+	ClientID string
+	Scopes   []string
+}
+
+// Validate confirma que token pertence a clientID, não expirou e não foi
+// revogado — sem consumi-lo, já que refresh tokens são reutilizáveis até
+// expirar ou serem revogados explicitamente.
+func (s *RefreshStore) Validate(ctx context.Context, token, clientID string) (Grant, error) {
+	var g Grant
+	var scopesJSON string
+	var expiresAt time.Time
+	var revokedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, `
+		SELECT user_id, client_id, scopes, expires_at, revoked_at
+		FROM oauth_refresh_tokens WHERE token_hash = ?
+	`, hashRefreshToken(token)).Scan(&g.UserID, &g.ClientID, &scopesJSON, &expiresAt, &revokedAt)
+	if err != nil {
+		return Grant{}, err
+	}
+
+	if g.ClientID != clientID {
+		return Grant{}, fmt.Errorf("refresh token não pertence a este cliente")
+	}
+	if revokedAt.Valid {
+		return Grant{}, fmt.Errorf("refresh token revogado")
+	}
+	if time.Now().After(expiresAt) {
+		return Grant{}, fmt.Errorf("refresh token expirado")
+	}
+	if err := json.Unmarshal([]byte(scopesJSON), &g.Scopes); err != nil {
+		return Grant{}, fmt.Errorf("scopes inválidos no refresh token: %w", err)
+	}
+
+	return g, nil
+}
+
+// Revoke invalida token antes do prazo — reservado para um futuro endpoint
+// de revogação/"desconectar app".
+func (s *RefreshStore) Revoke(ctx context.Context, token string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE oauth_refresh_tokens SET revoked_at = ? WHERE token_hash = ?`, time.Now(), hashRefreshToken(token))
+	if err != nil {
+		return fmt.Errorf("falha ao revogar refresh token: %w", err)
+	}
+	return nil
+}
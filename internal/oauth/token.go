@@ -0,0 +1,122 @@
+package oauth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AccessTokenTTL e IDTokenTTL seguem a mesma ordem de grandeza recomendada
+// pelo RFC 6749/OIDC Core para tokens de curta duração — o refresh_token
+// (ver refresh.go) é o que sustenta sessões longas.
+const (
+	AccessTokenTTL = 1 * time.Hour
+	IDTokenTTL     = 1 * time.Hour
+)
+
+// Claims é o payload de um token emitido por este servidor.
+type Claims map[string]any
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// SignJWT assina claims como um JWT RS256 com a chave/kid fornecidos —
+// usado tanto para access tokens quanto para ID tokens.
+func SignJWT(key *rsa.PrivateKey, kid string, claims Claims) (string, error) {
+	header := jwtHeader{Alg: "RS256", Kid: kid}
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	payloadJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signedInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(payloadJSON)
+
+	hash := sha256.Sum256([]byte(signedInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", fmt.Errorf("oauth: falha ao assinar token: %w", err)
+	}
+
+	return signedInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// VerifyJWT confere a assinatura RS256 de token contra pub e retorna as
+// claims decodificadas, validando exp. Usado para validar tokens Bearer
+// apresentados a GET /oauth/userinfo e a handlers de recursos protegidos.
+func VerifyJWT(token string, pub *rsa.PublicKey) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("oauth: token mal formado")
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("oauth: header do token inválido: %w", err)
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("oauth: header do token inválido: %w", err)
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("oauth: algoritmo de assinatura não suportado: %s", header.Alg)
+	}
+
+	payloadJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("oauth: payload do token inválido: %w", err)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("oauth: assinatura do token inválida: %w", err)
+	}
+
+	signedInput := parts[0] + "." + parts[1]
+	hash := sha256.Sum256([]byte(signedInput))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash[:], sig); err != nil {
+		return nil, fmt.Errorf("oauth: assinatura do token inválida")
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payloadJSON, &claims); err != nil {
+		return nil, fmt.Errorf("oauth: payload do token inválido: %w", err)
+	}
+
+	if exp, ok := claims["exp"].(float64); ok {
+		if time.Now().After(time.Unix(int64(exp), 0)) {
+			return nil, fmt.Errorf("oauth: token expirado")
+		}
+	}
+
+	return claims, nil
+}
+
+// ParseKID lê o kid do header sem verificar a assinatura — usado para
+// escolher qual chave pública buscar no KeyStore antes de chamar VerifyJWT.
+func ParseKID(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("oauth: token mal formado")
+	}
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", fmt.Errorf("oauth: header do token inválido: %w", err)
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return "", fmt.Errorf("oauth: header do token inválido: %w", err)
+	}
+	return header.Kid, nil
+}
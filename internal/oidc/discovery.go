@@ -0,0 +1,55 @@
+package oidc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// discoveryDoc é o subconjunto do documento /.well-known/openid-configuration
+// que realmente usamos.
+type discoveryDoc struct {
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+const discoveryTTL = 10 * time.Minute
+
+type discoveryCacheEntry struct {
+	doc      *discoveryDoc
+	cachedAt time.Time
+}
+
+func (m *Manager) discover(providerName string, issuerURL string) (*discoveryDoc, error) {
+	m.discoveryMu.Lock()
+	if entry, ok := m.discoveryCache[providerName]; ok && time.Since(entry.cachedAt) < discoveryTTL {
+		m.discoveryMu.Unlock()
+		return entry.doc, nil
+	}
+	m.discoveryMu.Unlock()
+
+	resp, err := m.httpClient().Get(strings.TrimRight(issuerURL, "/") + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, fmt.Errorf("oidc: falha ao buscar discovery document de %q: %w", providerName, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc: discovery document de %q retornou status %d", providerName, resp.StatusCode)
+	}
+
+	var doc discoveryDoc
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("oidc: falha ao decodificar discovery document de %q: %w", providerName, err)
+	}
+
+	m.discoveryMu.Lock()
+	m.discoveryCache[providerName] = discoveryCacheEntry{doc: &doc, cachedAt: time.Now()}
+	m.discoveryMu.Unlock()
+
+	return &doc, nil
+}
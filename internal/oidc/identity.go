@@ -0,0 +1,69 @@
+package oidc
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// IdentityStore persiste o vínculo entre uma identidade federada
+// (provider, subject) e o db.User local correspondente, permitindo que o
+// mesmo usuário faça login por senha ou por qualquer provedor já linkado.
+type IdentityStore struct {
+	db *sql.DB
+}
+
+// NewIdentityStore cria um IdentityStore sobre uma conexão já aberta.
+func NewIdentityStore(dbConn *sql.DB) *IdentityStore {
+	return &IdentityStore{db: dbConn}
+}
+
+// EnsureTable cria a tabela identities caso ainda não exista.
+func (s *IdentityStore) EnsureTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS identities (
+			id         INTEGER PRIMARY KEY AUTOINCREMENT,
+			provider   TEXT NOT NULL,
+			subject    TEXT NOT NULL,
+			user_id    INTEGER NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(provider, subject)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("oidc: falha ao criar tabela identities: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_identities_user_id ON identities(user_id)`)
+	if err != nil {
+		return fmt.Errorf("oidc: falha ao criar índice de identities: %w", err)
+	}
+
+	return nil
+}
+
+// Link associa (provider, subject) a userID. Idempotente: relinkar o mesmo
+// par ao mesmo usuário não é erro.
+func (s *IdentityStore) Link(ctx context.Context, provider, subject string, userID int64) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO identities (provider, subject, user_id) VALUES (?, ?, ?)
+		ON CONFLICT(provider, subject) DO UPDATE SET user_id = excluded.user_id
+	`, provider, subject, userID)
+	if err != nil {
+		return fmt.Errorf("oidc: falha ao linkar identidade %s/%s: %w", provider, subject, err)
+	}
+	return nil
+}
+
+// FindUserID retorna o user_id linkado a (provider, subject), ou
+// sql.ErrNoRows se nenhuma identidade ainda foi linkada.
+func (s *IdentityStore) FindUserID(ctx context.Context, provider, subject string) (int64, error) {
+	var userID int64
+	err := s.db.QueryRowContext(ctx,
+		"SELECT user_id FROM identities WHERE provider = ? AND subject = ?", provider, subject,
+	).Scan(&userID)
+	if err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
@@ -0,0 +1,87 @@
+package oidc
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+// jwk é a representação de uma chave pública RSA em um JSON Web Key Set,
+// restrita aos campos que precisamos para verificar assinaturas RS256.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+type jwksCacheEntry struct {
+	set      *jwkSet
+	cachedAt time.Time
+}
+
+const jwksTTL = 10 * time.Minute
+
+func (m *Manager) fetchJWKS(providerName, jwksURI string) (*jwkSet, error) {
+	m.jwksMu.Lock()
+	if entry, ok := m.jwksCache[providerName]; ok && time.Since(entry.cachedAt) < jwksTTL {
+		m.jwksMu.Unlock()
+		return entry.set, nil
+	}
+	m.jwksMu.Unlock()
+
+	resp, err := m.httpClient().Get(jwksURI)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: falha ao buscar JWKS de %q: %w", providerName, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc: JWKS de %q retornou status %d", providerName, resp.StatusCode)
+	}
+
+	var set jwkSet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("oidc: falha ao decodificar JWKS de %q: %w", providerName, err)
+	}
+
+	m.jwksMu.Lock()
+	m.jwksCache[providerName] = jwksCacheEntry{set: &set, cachedAt: time.Now()}
+	m.jwksMu.Unlock()
+
+	return &set, nil
+}
+
+// publicKey resolve a chave RSA identificada por kid dentro do conjunto.
+func (s *jwkSet) publicKey(kid string) (*rsa.PublicKey, error) {
+	for _, k := range s.Keys {
+		if k.Kid != kid || k.Kty != "RSA" {
+			continue
+		}
+
+		nBytes, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("oidc: modulus inválido na JWK %q: %w", kid, err)
+		}
+		eBytes, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("oidc: expoente inválido na JWK %q: %w", kid, err)
+		}
+
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(nBytes),
+			E: int(new(big.Int).SetBytes(eBytes).Int64()),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("oidc: nenhuma JWK encontrada para kid %q", kid)
+}
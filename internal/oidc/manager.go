@@ -0,0 +1,247 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Manager resolve provedores configurados pelo nome e conduz o fluxo
+// Authorization Code + PKCE contra cada um, incluindo descoberta de
+// endpoints e verificação de ID tokens via JWKS.
+type Manager struct {
+	providers map[string]Provider
+
+	discoveryMu    sync.Mutex
+	discoveryCache map[string]discoveryCacheEntry
+
+	jwksMu    sync.Mutex
+	jwksCache map[string]jwksCacheEntry
+
+	client *http.Client
+}
+
+// NewManager constrói um Manager para o conjunto de provedores fornecido,
+// indexados pelo próprio Provider.Name.
+func NewManager(providers []Provider) *Manager {
+	m := &Manager{
+		providers:      make(map[string]Provider, len(providers)),
+		discoveryCache: make(map[string]discoveryCacheEntry),
+		jwksCache:      make(map[string]jwksCacheEntry),
+	}
+	for _, p := range providers {
+		m.providers[p.Name] = p
+	}
+	return m
+}
+
+func (m *Manager) httpClient() *http.Client {
+	if m.client != nil {
+		return m.client
+	}
+	return http.DefaultClient
+}
+
+// Provider retorna o provedor configurado com o nome dado.
+func (m *Manager) Provider(name string) (Provider, bool) {
+	p, ok := m.providers[name]
+	return p, ok
+}
+
+// AuthCodeURL monta a URL de autorização (com PKCE) para redirecionar o
+// usuário ao provedor name. extra permite repassar parâmetros adicionais,
+// como um login_challenge de um consent server na frente do IdP.
+func (m *Manager) AuthCodeURL(name, state, nonce, codeVerifier string, extra url.Values) (string, error) {
+	provider, ok := m.Provider(name)
+	if !ok {
+		return "", fmt.Errorf("oidc: provedor %q não configurado", name)
+	}
+
+	doc, err := m.discover(name, provider.IssuerURL)
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := url.Parse(doc.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("oidc: authorization_endpoint inválido para %q: %w", name, err)
+	}
+
+	q := authURL.Query()
+	for k, vs := range extra {
+		for _, v := range vs {
+			q.Add(k, v)
+		}
+	}
+	q.Set("response_type", "code")
+	q.Set("client_id", provider.ClientID)
+	q.Set("redirect_uri", provider.RedirectURL)
+	q.Set("scope", strings.Join(provider.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", Challenge(codeVerifier))
+	q.Set("code_challenge_method", "S256")
+	authURL.RawQuery = q.Encode()
+
+	return authURL.String(), nil
+}
+
+// TokenResponse é a resposta do token endpoint que realmente usamos.
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	IDToken     string `json:"id_token"`
+	TokenType   string `json:"token_type"`
+}
+
+// Exchange troca o código de autorização pelos tokens do provedor name,
+// validando o code_verifier (PKCE) no processo.
+func (m *Manager) Exchange(ctx context.Context, name, code, codeVerifier string) (TokenResponse, error) {
+	provider, ok := m.Provider(name)
+	if !ok {
+		return TokenResponse{}, fmt.Errorf("oidc: provedor %q não configurado", name)
+	}
+
+	doc, err := m.discover(name, provider.IssuerURL)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", provider.RedirectURL)
+	form.Set("client_id", provider.ClientID)
+	form.Set("client_secret", provider.ClientSecret)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := m.httpClient().Do(req)
+	if err != nil {
+		return TokenResponse{}, fmt.Errorf("oidc: falha ao trocar código por token em %q: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return TokenResponse{}, fmt.Errorf("oidc: token endpoint de %q retornou status %d", name, resp.StatusCode)
+	}
+
+	var tokens TokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return TokenResponse{}, fmt.Errorf("oidc: falha ao decodificar resposta do token endpoint de %q: %w", name, err)
+	}
+
+	return tokens, nil
+}
+
+// VerifyIDToken verifica a assinatura (JWKS), iss/aud/exp/nonce do ID token
+// emitido pelo provedor name e retorna suas claims decodificadas.
+func (m *Manager) VerifyIDToken(ctx context.Context, name, idToken, expectedNonce string) (Claims, error) {
+	provider, ok := m.Provider(name)
+	if !ok {
+		return nil, fmt.Errorf("oidc: provedor %q não configurado", name)
+	}
+
+	doc, err := m.discover(name, provider.IssuerURL)
+	if err != nil {
+		return nil, err
+	}
+	if doc.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc: provedor %q não anuncia jwks_uri", name)
+	}
+
+	keys, err := m.fetchJWKS(name, doc.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+
+	return verifyIDToken(provider, keys, idToken, expectedNonce)
+}
+
+// Userinfo consulta o userinfo_endpoint do provedor name com accessToken,
+// usado como complemento quando o ID token não carrega todas as claims que
+// precisamos (ex. GitHub só expõe e-mail verificado via userinfo).
+func (m *Manager) Userinfo(ctx context.Context, name, accessToken string) (map[string]any, error) {
+	provider, ok := m.Provider(name)
+	if !ok {
+		return nil, fmt.Errorf("oidc: provedor %q não configurado", name)
+	}
+
+	doc, err := m.discover(name, provider.IssuerURL)
+	if err != nil {
+		return nil, err
+	}
+	if doc.UserinfoEndpoint == "" {
+		return nil, fmt.Errorf("oidc: provedor %q não anuncia userinfo_endpoint", name)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, doc.UserinfoEndpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := m.httpClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: falha ao buscar userinfo de %q: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc: userinfo_endpoint de %q retornou status %d", name, resp.StatusCode)
+	}
+
+	var info map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("oidc: falha ao decodificar userinfo de %q: %w", name, err)
+	}
+
+	return info, nil
+}
+
+// MapIdentity extrai a Identity normalizada das claims de acordo com o
+// ClaimMapping do provider (ou DefaultClaimMapping, se não declarado).
+func MapIdentity(provider Provider, claims map[string]any) Identity {
+	mapping := provider.claimMapping()
+	return Identity{
+		Subject:       stringClaim(claims, mapping.Subject),
+		Email:         stringClaim(claims, mapping.Email),
+		EmailVerified: boolClaim(claims, mapping.EmailVerified),
+		Username:      stringClaim(claims, mapping.Username),
+		Picture:       stringClaim(claims, mapping.Picture),
+	}
+}
+
+func stringClaim(claims map[string]any, key string) string {
+	if key == "" {
+		return ""
+	}
+	s, _ := claims[key].(string)
+	return s
+}
+
+// boolClaim lê uma claim booleana, aceitando tanto bool quanto string
+// ("true"/"false") — alguns provedores (ex. Keycloak em certas versões)
+// emitem email_verified como string em vez de booleano JSON nativo.
+func boolClaim(claims map[string]any, key string) bool {
+	if key == "" {
+		return false
+	}
+	switch v := claims[key].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	default:
+		return false
+	}
+}
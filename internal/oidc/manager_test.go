@@ -0,0 +1,51 @@
+package oidc
+
+import "testing"
+
+func TestMapIdentityEmailVerified(t *testing.T) {
+	provider := Provider{Name: "google"}
+
+	tests := []struct {
+		name   string
+		claims map[string]any
+		want   bool
+	}{
+		{"native bool true", map[string]any{"email_verified": true}, true},
+		{"native bool false", map[string]any{"email_verified": false}, false},
+		{"string true", map[string]any{"email_verified": "true"}, true},
+		{"string false", map[string]any{"email_verified": "false"}, false},
+		{"absent claim", map[string]any{}, false},
+		{"unexpected type", map[string]any{"email_verified": 1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity := MapIdentity(provider, tt.claims)
+			if identity.EmailVerified != tt.want {
+				t.Errorf("EmailVerified = %v, want %v", identity.EmailVerified, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapIdentityRespectsCustomClaimMapping(t *testing.T) {
+	provider := Provider{
+		Name: "custom-idp",
+		ClaimMapping: ClaimMapping{
+			Subject:       "sub",
+			Email:         "mail",
+			EmailVerified: "mail_confirmed",
+			Username:      "preferred_username",
+		},
+	}
+
+	identity := MapIdentity(provider, map[string]any{
+		"sub":            "abc-123",
+		"mail":           "user@example.com",
+		"mail_confirmed": true,
+	})
+
+	if identity.Subject != "abc-123" || identity.Email != "user@example.com" || !identity.EmailVerified {
+		t.Errorf("MapIdentity with custom ClaimMapping = %+v", identity)
+	}
+}
@@ -0,0 +1,58 @@
+// Package oidc implementa um cliente OpenID Connect multi-provedor:
+// descoberta via /.well-known/openid-configuration, Authorization Code com
+// PKCE, verificação de assinatura do ID token via JWKS e mapeamento de
+// claims para a identidade federada usada por internal/web para
+// linkar/provisionar db.User — ver internal/web/oidc.go.
+package oidc
+
+// ClaimMapping indica em quais chaves do ID token / userinfo cada provedor
+// expõe os campos que usamos. A maioria segue o padrão OIDC (DefaultClaimMapping),
+// mas provedores como GitHub usam nomes próprios (ex. "login").
+type ClaimMapping struct {
+	Subject       string
+	Email         string
+	EmailVerified string
+	Username      string
+	Picture       string
+}
+
+// DefaultClaimMapping é o mapeamento usado quando o provedor não declara um
+// ClaimMapping próprio.
+var DefaultClaimMapping = ClaimMapping{
+	Subject:       "sub",
+	Email:         "email",
+	EmailVerified: "email_verified",
+	Username:      "preferred_username",
+	Picture:       "picture",
+}
+
+// Provider descreve um provedor de identidade externo habilitado para SSO.
+type Provider struct {
+	Name         string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	ClaimMapping ClaimMapping
+}
+
+func (p Provider) claimMapping() ClaimMapping {
+	if p.ClaimMapping == (ClaimMapping{}) {
+		return DefaultClaimMapping
+	}
+	return p.ClaimMapping
+}
+
+// Identity é a identidade federada resolvida a partir das claims do provedor,
+// já normalizada pelo ClaimMapping do Provider. EmailVerified reflete a
+// claim "email_verified" (ou o equivalente do ClaimMapping do provider) e
+// deve ser a única fonte confiável para decidir se Email pode linkar uma
+// conta já existente — ver internal/web/oidc.go resolveOIDCUser.
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string
+	Picture       string
+}
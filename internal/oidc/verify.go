@@ -0,0 +1,108 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Claims é o payload decodificado de um ID token já verificado.
+type Claims map[string]any
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// verifyIDToken valida a assinatura RS256 de idToken contra o JWKS do
+// provedor, confere iss/aud/exp/nonce e retorna as claims decodificadas.
+// Apenas RS256 é suportado — é o algoritmo usado por todo provedor OIDC
+// relevante (Google, Azure AD, Okta, Keycloak); HS256 exigiria distribuir o
+// client_secret como chave de verificação, o que não faz sentido aqui.
+func verifyIDToken(provider Provider, keys *jwkSet, idToken, expectedNonce string) (Claims, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("oidc: id_token mal formado")
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("oidc: header do id_token inválido: %w", err)
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("oidc: header do id_token inválido: %w", err)
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("oidc: algoritmo de assinatura não suportado: %s", header.Alg)
+	}
+
+	payloadJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("oidc: payload do id_token inválido: %w", err)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("oidc: assinatura do id_token inválida: %w", err)
+	}
+
+	pubKey, err := keys.publicKey(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	signedInput := parts[0] + "." + parts[1]
+	digest := sha256.Sum256([]byte(signedInput))
+	if err := rsa.VerifyPKCS1v15(pubKey, crypto.SHA256, digest[:], sig); err != nil {
+		return nil, fmt.Errorf("oidc: assinatura do id_token inválida: %w", err)
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payloadJSON, &claims); err != nil {
+		return nil, fmt.Errorf("oidc: claims do id_token inválidas: %w", err)
+	}
+
+	if iss, _ := claims["iss"].(string); !issuerMatches(iss, provider.IssuerURL) {
+		return nil, fmt.Errorf("oidc: iss %q não corresponde ao emissor configurado", iss)
+	}
+
+	if !audienceContains(claims["aud"], provider.ClientID) {
+		return nil, fmt.Errorf("oidc: aud do id_token não contém o client_id configurado")
+	}
+
+	if exp, ok := claims["exp"].(float64); ok && time.Now().After(time.Unix(int64(exp), 0)) {
+		return nil, fmt.Errorf("oidc: id_token expirado")
+	}
+
+	if expectedNonce != "" {
+		if nonce, _ := claims["nonce"].(string); nonce != expectedNonce {
+			return nil, fmt.Errorf("oidc: nonce do id_token não corresponde")
+		}
+	}
+
+	return claims, nil
+}
+
+func issuerMatches(iss, configured string) bool {
+	return strings.TrimRight(iss, "/") == strings.TrimRight(configured, "/")
+}
+
+func audienceContains(aud any, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []any:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -1,24 +1,56 @@
 package policies
 
-import "github.com/PauloHFS/goth/internal/db"
+import (
+	"context"
+	"strconv"
 
-func CanUpdateUser(actor, target db.User) bool {
-	if actor.RoleID == "admin" {
+	"github.com/PauloHFS/goth/internal/authz"
+	"github.com/PauloHFS/goth/internal/db"
+)
+
+// CanUpdateUser, CanDeleteUser e CanViewUser decidem por trás de
+// authz.Manager.Enforce em vez de comparar actor.RoleID == "admin"
+// diretamente, como antes — o mesmo motor de permissões (papéis, herança
+// via roles.inherits e políticas condicionais) já usado pelas rotas HTTP
+// via authz.RequirePermission, de modo que reconfigurar quem pode
+// editar/excluir/ver um usuário é uma mudança de dados em roles.permissions,
+// não de código.
+//
+// CanUpdateUser reporta se actor pode atualizar target: sempre verdadeiro
+// para o próprio usuário, caso contrário decidido pela política
+// "users.update" do papel de actor.
+func CanUpdateUser(ctx context.Context, mgr *authz.Manager, actor, target db.User) bool {
+	if actor.ID == target.ID {
 		return true
 	}
-	return actor.ID == target.ID
+	allowed, _ := mgr.Enforce(ctx, actor, "users.update", userResourceAttrs(target))
+	return allowed
+}
+
+// CanDeleteUser reporta se actor pode excluir target, decidido pela
+// política "users.delete" do papel de actor — ao contrário de CanUpdateUser
+// e CanViewUser, não há exceção para o próprio usuário: excluir a si mesmo
+// por essa via também depende da política (o fluxo de account deletion é
+// outro handler).
+func CanDeleteUser(ctx context.Context, mgr *authz.Manager, actor, target db.User) bool {
+	allowed, _ := mgr.Enforce(ctx, actor, "users.delete", userResourceAttrs(target))
+	return allowed
 }
 
-func CanDeleteUser(actor, target db.User) bool {
-	if actor.RoleID == "admin" {
+// CanViewUser reporta se actor pode ver target, decidido pela política
+// "users.view" do papel de actor — tipicamente concedida para usuários do
+// mesmo tenant via uma condição {"tenant_id":"$user.tenant_id"}.
+func CanViewUser(ctx context.Context, mgr *authz.Manager, actor, target db.User) bool {
+	if actor.ID == target.ID {
 		return true
 	}
-	return actor.ID == target.ID
+	allowed, _ := mgr.Enforce(ctx, actor, "users.view", userResourceAttrs(target))
+	return allowed
 }
 
-func CanViewUser(actor, target db.User) bool {
-	if actor.RoleID == "admin" {
-		return true
+func userResourceAttrs(target db.User) map[string]string {
+	return map[string]string{
+		"tenant_id": target.TenantID,
+		"user_id":   strconv.FormatInt(target.ID, 10),
 	}
-	return actor.TenantID == target.TenantID
 }
@@ -0,0 +1,96 @@
+package policies
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/PauloHFS/goth/internal/authz"
+	"github.com/PauloHFS/goth/internal/db"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupTestManager(t *testing.T) *authz.Manager {
+	t.Helper()
+
+	dbConn, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dbConn.Close() })
+
+	if _, err := dbConn.Exec(`CREATE TABLE roles (id TEXT PRIMARY KEY, permissions TEXT NOT NULL)`); err != nil {
+		t.Fatal(err)
+	}
+
+	mgr := authz.NewManager(dbConn)
+	if err := mgr.EnsureSchema(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	seed := []struct{ id, permissions, inherits string }{
+		{"admin", `["*"]`, ""},
+		{"editor", `["users.update"]`, `["member"]`},
+		{"member", `["users.view"]`, ""},
+		{"restricted", `["*", "!users.delete"]`, ""},
+	}
+	for _, s := range seed {
+		if _, err := dbConn.Exec(`INSERT INTO roles (id, permissions) VALUES (?, ?)`, s.id, s.permissions); err != nil {
+			t.Fatal(err)
+		}
+		if s.inherits != "" {
+			if _, err := dbConn.Exec(`UPDATE roles SET inherits = ? WHERE id = ?`, s.inherits, s.id); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	return mgr
+}
+
+func TestCanUpdateUser(t *testing.T) {
+	mgr := setupTestManager(t)
+	ctx := context.Background()
+
+	admin := db.User{ID: 1, RoleID: "admin", TenantID: "default"}
+	editor := db.User{ID: 2, RoleID: "editor", TenantID: "default"}
+	member := db.User{ID: 3, RoleID: "member", TenantID: "default"}
+	target := db.User{ID: 9, RoleID: "member", TenantID: "default"}
+
+	if !CanUpdateUser(ctx, mgr, admin, target) {
+		t.Error("admin should be able to update any user")
+	}
+	if !CanUpdateUser(ctx, mgr, editor, target) {
+		t.Error("editor should be able to update other users via users.update")
+	}
+	if CanUpdateUser(ctx, mgr, member, target) {
+		t.Error("member should not be able to update other users")
+	}
+	if !CanUpdateUser(ctx, mgr, member, member) {
+		t.Error("a user should always be able to update themselves")
+	}
+}
+
+func TestCanDeleteUser_DenyOverridesWildcard(t *testing.T) {
+	mgr := setupTestManager(t)
+	ctx := context.Background()
+
+	restricted := db.User{ID: 4, RoleID: "restricted", TenantID: "default"}
+	target := db.User{ID: 9, RoleID: "member", TenantID: "default"}
+
+	if CanDeleteUser(ctx, mgr, restricted, target) {
+		t.Error("explicit deny should override the '*' wildcard")
+	}
+}
+
+func TestCanViewUser_InheritedPermission(t *testing.T) {
+	mgr := setupTestManager(t)
+	ctx := context.Background()
+
+	editor := db.User{ID: 2, RoleID: "editor", TenantID: "default"}
+	target := db.User{ID: 9, RoleID: "member", TenantID: "default"}
+
+	if !CanViewUser(ctx, mgr, editor, target) {
+		t.Error("editor should inherit users.view from member")
+	}
+}
@@ -0,0 +1,114 @@
+// Package pubsub implementa um broker de publish/subscribe em processo, por
+// tópico, inspirado no padrão Acquirer do provisionerdserver do Coder:
+// assinantes ociosos bloqueiam em um canal até serem notificados em vez de
+// fazer polling, e Publish acorda os assinantes em rodízio (round-robin) em
+// vez de sempre acordar o mesmo assinante primeiro — importante quando há
+// múltiplos workers competindo pelo mesmo job e só um vai vencer a corrida
+// no UPDATE...RETURNING. Quem publica continua livre para manter um
+// fallback por polling (ticker), já que uma notificação pode se perder
+// (processo que morre entre o commit e o Publish, ou assinante que não
+// estava inscrito ainda).
+package pubsub
+
+import "sync"
+
+// Broker é o registro de tópicos e seus assinantes. O valor zero não é
+// utilizável — use New.
+type Broker struct {
+	mu     sync.Mutex
+	topics map[string]*waiterQueue
+}
+
+// New cria um Broker vazio.
+func New() *Broker {
+	return &Broker{topics: make(map[string]*waiterQueue)}
+}
+
+// waiterQueue fair-queues os assinantes de um tópico: next é o índice do
+// próximo assinante a ser tentado primeiro no próximo Publish.
+type waiterQueue struct {
+	subs []*Subscription
+	next int
+}
+
+// Subscription é devolvida por Subscribe; C recebe um valor a cada Publish
+// no tópico que este assinante conseguiu consumir. Tem capacidade 1, então
+// uma notificação nunca se perde enquanto o assinante estiver entre duas
+// iterações do seu loop — mas Publish não bloqueia esperando o assinante
+// drenar.
+type Subscription struct {
+	C      <-chan struct{}
+	ch     chan struct{}
+	topic  string
+	broker *Broker
+}
+
+// Subscribe inscreve um novo assinante em topic. Chame Close quando o
+// assinante for embora, para não ficar retido na fila round-robin.
+func (b *Broker) Subscribe(topic string) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &Subscription{topic: topic, broker: b, ch: make(chan struct{}, 1)}
+	sub.C = sub.ch
+
+	q := b.topics[topic]
+	if q == nil {
+		q = &waiterQueue{}
+		b.topics[topic] = q
+	}
+	q.subs = append(q.subs, sub)
+
+	return sub
+}
+
+// Close remove a assinatura do rodízio do seu tópico.
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	q := s.broker.topics[s.topic]
+	if q == nil {
+		return
+	}
+
+	for i, sub := range q.subs {
+		if sub == s {
+			q.subs = append(q.subs[:i], q.subs[i+1:]...)
+			if q.next > i {
+				q.next--
+			}
+			break
+		}
+	}
+}
+
+// Publish acorda um assinante de topic — o primeiro, a partir do cursor
+// round-robin do tópico, cujo canal tiver espaço livre. Se nenhum assinante
+// existir ou nenhum tiver espaço (todos já notificados e ainda não
+// processaram), Publish não bloqueia e retorna sem efeito: o fallback por
+// ticker de cada assinante cobre esse caso.
+func (b *Broker) Publish(topic string) {
+	b.mu.Lock()
+	q := b.topics[topic]
+	if q == nil || len(q.subs) == 0 {
+		b.mu.Unlock()
+		return
+	}
+
+	n := len(q.subs)
+	start := q.next
+	q.next = (q.next + 1) % n
+	subs := make([]*Subscription, n)
+	copy(subs, q.subs)
+	b.mu.Unlock()
+
+	for i := 0; i < n; i++ {
+		sub := subs[(start+i)%n]
+		select {
+		case sub.ch <- struct{}{}:
+			return
+		default:
+		}
+	}
+}
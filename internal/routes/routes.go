@@ -11,4 +11,24 @@ const (
 	Dashboard      = "/dashboard"
 	Health         = "/health"
 	Metrics        = "/metrics"
+
+	AccountSessions           = "/account/sessions"
+	AccountSessionRevoke      = "/account/sessions/{id}/revoke"
+	AccountSessionRevokeOther = "/account/sessions/revoke-all-others"
+
+	AdminUserPermissions = "/admin/users/{id}/permissions"
+
+	LoginOTP   = "/login/otp"
+	Profile2FA = "/profile/2fa"
+
+	LoginMagic       = "/login/magic"
+	LoginMagicVerify = "/login/magic/verify"
+
+	OAuthAuthorize = "/oauth/authorize"
+	OAuthToken     = "/oauth/token"
+	OAuthUserinfo  = "/oauth/userinfo"
+	OIDCDiscovery  = "/.well-known/openid-configuration"
+	JWKS           = "/.well-known/jwks.json"
+
+	AdminApps = "/admin/apps"
 )
@@ -0,0 +1,279 @@
+// Package search combina busca textual FTS5 (BM25) com busca vetorial k-NN
+// (internal/vector) em um único ranking via Reciprocal Rank Fusion, para
+// consultas onde nem palavra-chave nem similaridade semântica sozinhas dão
+// a melhor resposta.
+package search
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/PauloHFS/goth/internal/vector"
+)
+
+// QueryEmbedder gera o vetor de uma consulta textual para a perna vetorial
+// de Hybrid. vector.Embedder já satisfaz esta interface.
+type QueryEmbedder interface {
+	Embed(ctx context.Context, text string) ([]float64, error)
+}
+
+// FTSConfig aponta para a tabela virtual FTS5 usada pela perna textual de
+// Hybrid. A tabela deve expor uma coluna (ContentIDColumn, "rowid" por
+// padrão) referenciando o mesmo id usado em vector.Embedding.ContentID, e
+// opcionalmente uma coluna de tenant para isolar buscas multi-tenant.
+type FTSConfig struct {
+	TableName       string
+	ContentIDColumn string
+	TenantColumn    string
+}
+
+func (c FTSConfig) withDefaults() FTSConfig {
+	if c.ContentIDColumn == "" {
+		c.ContentIDColumn = "rowid"
+	}
+	return c
+}
+
+// Searcher combina busca textual FTS5 e busca vetorial sobre o mesmo
+// conjunto de documentos.
+type Searcher struct {
+	db       *sql.DB
+	fts      FTSConfig
+	vectors  *vector.Service
+	embedder QueryEmbedder
+}
+
+// NewSearcher cria um Searcher sobre a tabela virtual FTS5 e o Service de
+// embeddings informados.
+func NewSearcher(db *sql.DB, fts FTSConfig, vectors *vector.Service, embedder QueryEmbedder) *Searcher {
+	return &Searcher{db: db, fts: fts.withDefaults(), vectors: vectors, embedder: embedder}
+}
+
+// HybridOptions ajusta os pesos de cada ranker, o corte de score mínimo e o
+// filtro de tenant aplicados por Hybrid.
+type HybridOptions struct {
+	// TenantID, se não vazio, restringe a perna FTS via FTSConfig.TenantColumn
+	// e filtra a perna vetorial por Metadata["tenant_id"], já que o índice
+	// vetorial não suporta filtro por atributo nativamente (ver
+	// vector.Service.SearchGlobal).
+	TenantID string
+	// ContentType restringe a perna vetorial a vector.Service.Search; vazio
+	// usa SearchGlobal sobre toda a tabela.
+	ContentType string
+	Metric      vector.DistanceMetric
+
+	FTSWeight    float64
+	VectorWeight float64
+	MinScore     float64
+	// RRFConstant é o k da fórmula de Reciprocal Rank Fusion (padrão 60,
+	// como no paper original de Cormack et al.).
+	RRFConstant int
+}
+
+const defaultRRFConstant = 60
+
+func (o HybridOptions) withDefaults() HybridOptions {
+	if o.FTSWeight == 0 {
+		o.FTSWeight = 1
+	}
+	if o.VectorWeight == 0 {
+		o.VectorWeight = 1
+	}
+	if o.RRFConstant <= 0 {
+		o.RRFConstant = defaultRRFConstant
+	}
+	if o.Metric == "" {
+		o.Metric = vector.DistanceCosine
+	}
+	return o
+}
+
+// HybridHit é um documento combinado pela fusão de rankings. FTSRank e
+// VectorRank são 0 quando o documento não apareceu naquela perna; FTSScore
+// e VectorScore carregam os sub-scores brutos (bm25 e distância,
+// respectivamente) para depuração.
+type HybridHit struct {
+	ContentID   int64
+	Score       float64
+	FTSRank     int
+	FTSScore    float64
+	VectorRank  int
+	VectorScore float64
+}
+
+// Hybrid roda a busca textual (FTS5 MATCH ordenada por bm25) e a busca
+// vetorial (k-NN via vector.Service) em paralelo e funde os dois rankings
+// com Reciprocal Rank Fusion: o score de um documento d é
+// Σ peso_i / (RRFConstant + rank_i(d)) somado sobre cada ranker que
+// devolveu d, com rank_i começando em 1.
+func (s *Searcher) Hybrid(ctx context.Context, query string, k int, opts HybridOptions) ([]HybridHit, error) {
+	if k <= 0 {
+		k = 10
+	}
+	opts = opts.withDefaults()
+
+	fanout := k * 4
+	if fanout < 20 {
+		fanout = 20
+	}
+
+	var (
+		ftsHits    []ftsHit
+		ftsErr     error
+		vectorHits []vector.SearchResult
+		vectorErr  error
+	)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		ftsHits, ftsErr = s.searchFTS(ctx, query, fanout, opts)
+	}()
+
+	go func() {
+		defer wg.Done()
+		vectorHits, vectorErr = s.searchVector(ctx, query, fanout, opts)
+	}()
+
+	wg.Wait()
+
+	if ftsErr != nil {
+		return nil, fmt.Errorf("fts search failed: %w", ftsErr)
+	}
+	if vectorErr != nil {
+		return nil, fmt.Errorf("vector search failed: %w", vectorErr)
+	}
+
+	return fuseRRF(ftsHits, vectorHits, k, opts), nil
+}
+
+// fuseRRF funde ftsHits e vectorHits (já ordenados por relevância decrescente
+// de cada perna) num único ranking via Reciprocal Rank Fusion, conforme a
+// fórmula descrita no doc comment de Hybrid. Extraída de Hybrid como função
+// pura para ser testável sem um *sql.DB nem um vector.Service reais.
+func fuseRRF(ftsHits []ftsHit, vectorHits []vector.SearchResult, k int, opts HybridOptions) []HybridHit {
+	byID := make(map[int64]*HybridHit)
+
+	for rank, hit := range ftsHits {
+		h := byID[hit.contentID]
+		if h == nil {
+			h = &HybridHit{ContentID: hit.contentID}
+			byID[hit.contentID] = h
+		}
+		h.FTSRank = rank + 1
+		h.FTSScore = hit.bm25
+		h.Score += opts.FTSWeight / float64(opts.RRFConstant+rank+1)
+	}
+
+	for rank, hit := range vectorHits {
+		h := byID[hit.ContentID]
+		if h == nil {
+			h = &HybridHit{ContentID: hit.ContentID}
+			byID[hit.ContentID] = h
+		}
+		h.VectorRank = rank + 1
+		h.VectorScore = hit.Similarity
+		h.Score += opts.VectorWeight / float64(opts.RRFConstant+rank+1)
+	}
+
+	hits := make([]HybridHit, 0, len(byID))
+	for _, h := range byID {
+		if h.Score < opts.MinScore {
+			continue
+		}
+		hits = append(hits, *h)
+	}
+
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+
+	return hits
+}
+
+type ftsHit struct {
+	contentID int64
+	bm25      float64
+}
+
+// searchFTS roda a busca textual MATCH ordenada por bm25() ascendente (no
+// FTS5, bm25 menor é mais relevante), opcionalmente filtrada por
+// FTSConfig.TenantColumn.
+func (s *Searcher) searchFTS(ctx context.Context, query string, limit int, opts HybridOptions) ([]ftsHit, error) {
+	args := []any{query}
+	tenantFilter := ""
+	if opts.TenantID != "" && s.fts.TenantColumn != "" {
+		tenantFilter = fmt.Sprintf(" AND %s = ?", s.fts.TenantColumn)
+		args = append(args, opts.TenantID)
+	}
+	args = append(args, limit)
+
+	sqlQuery := fmt.Sprintf(`
+		SELECT %s, bm25(%s) AS score
+		FROM %s
+		WHERE %s MATCH ?%s
+		ORDER BY score
+		LIMIT ?
+	`, s.fts.ContentIDColumn, s.fts.TableName, s.fts.TableName, s.fts.TableName, tenantFilter)
+
+	rows, err := s.db.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []ftsHit
+	for rows.Next() {
+		var hit ftsHit
+		if err := rows.Scan(&hit.contentID, &hit.bm25); err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
+
+// searchVector embeda query e busca os vizinhos mais próximos via
+// vector.Service, filtrando por ContentType quando informado (ou correndo
+// sobre a tabela inteira via SearchGlobal caso contrário), e por
+// Metadata["tenant_id"] na aplicação quando opts.TenantID é informado, já
+// que o índice vetorial não suporta filtro por atributo nativamente.
+func (s *Searcher) searchVector(ctx context.Context, query string, limit int, opts HybridOptions) ([]vector.SearchResult, error) {
+	queryVector, err := s.embedder.Embed(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	// filter fica nil aqui (em vez de vector.Eq("tenant_id", opts.TenantID)):
+	// a filtragem por TenantID abaixo é intencionalmente pós-busca, e migrar
+	// para pushdown exigiria garantir "tenant_id" em
+	// vector.Config.AllowedFilterKeys em todo call site que monta este
+	// Searcher — fora do escopo desta mudança (ver vector.Filter).
+	var results []vector.SearchResult
+	if opts.ContentType != "" {
+		results, err = s.vectors.Search(ctx, opts.ContentType, queryVector, limit, opts.Metric, nil, vector.SearchParams{})
+	} else {
+		results, err = s.vectors.SearchGlobal(ctx, queryVector, limit, opts.Metric, nil, vector.SearchParams{})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.TenantID == "" {
+		return results, nil
+	}
+
+	filtered := results[:0]
+	for _, r := range results {
+		if tenant, ok := r.Metadata["tenant_id"].(string); ok && tenant == opts.TenantID {
+			filtered = append(filtered, r)
+		}
+	}
+	return filtered, nil
+}
@@ -0,0 +1,72 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/PauloHFS/goth/internal/vector"
+)
+
+func TestFuseRRFOrdersByCombinedScore(t *testing.T) {
+	// doc1 é o #1 em ambas as pernas: deve vencer.
+	// doc2 só aparece na perna FTS; doc3 só na perna vetorial.
+	ftsHits := []ftsHit{
+		{contentID: 1, bm25: 0.1},
+		{contentID: 2, bm25: 0.5},
+	}
+	vectorHits := []vector.SearchResult{
+		{Embedding: vector.Embedding{ContentID: 1}, Similarity: 0.9},
+		{Embedding: vector.Embedding{ContentID: 3}, Similarity: 0.4},
+	}
+
+	opts := HybridOptions{}.withDefaults()
+	hits := fuseRRF(ftsHits, vectorHits, 10, opts)
+
+	if len(hits) != 3 {
+		t.Fatalf("fuseRRF returned %d hits, want 3", len(hits))
+	}
+	if hits[0].ContentID != 1 {
+		t.Errorf("top hit = %d, want 1 (present in both rankers at rank 1)", hits[0].ContentID)
+	}
+	if hits[0].FTSRank != 1 || hits[0].VectorRank != 1 {
+		t.Errorf("top hit ranks = (fts=%d, vector=%d), want (1, 1)", hits[0].FTSRank, hits[0].VectorRank)
+	}
+
+	// doc2 e doc3 só apareceram numa perna cada: o rank da perna ausente
+	// fica zero.
+	for _, h := range hits[1:] {
+		if h.ContentID == 2 && h.VectorRank != 0 {
+			t.Errorf("doc2 should have no vector rank, got %d", h.VectorRank)
+		}
+		if h.ContentID == 3 && h.FTSRank != 0 {
+			t.Errorf("doc3 should have no fts rank, got %d", h.FTSRank)
+		}
+	}
+
+	for i := 1; i < len(hits); i++ {
+		if hits[i].Score > hits[i-1].Score {
+			t.Errorf("hits not sorted by descending score: %+v", hits)
+		}
+	}
+}
+
+func TestFuseRRFAppliesMinScoreAndLimit(t *testing.T) {
+	ftsHits := []ftsHit{
+		{contentID: 1, bm25: 0.1},
+		{contentID: 2, bm25: 0.2},
+		{contentID: 3, bm25: 0.3},
+	}
+
+	opts := HybridOptions{RRFConstant: 60, FTSWeight: 1, VectorWeight: 1, MinScore: 1.0 / 61}
+	hits := fuseRRF(ftsHits, nil, 10, opts)
+
+	// Score de cada um: 1/(60+rank). doc1 (rank1) = 1/61 >= MinScore, fica;
+	// doc2 (rank2) = 1/62 < MinScore, cai; doc3 (rank3) = 1/63 < MinScore, cai.
+	if len(hits) != 1 || hits[0].ContentID != 1 {
+		t.Fatalf("expected only doc1 to survive MinScore, got %+v", hits)
+	}
+
+	limited := fuseRRF(ftsHits, nil, 2, HybridOptions{}.withDefaults())
+	if len(limited) != 2 {
+		t.Fatalf("fuseRRF with k=2 returned %d hits, want 2", len(limited))
+	}
+}
@@ -2,34 +2,98 @@ package services
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
-	"fmt"
 	"time"
 
 	"github.com/PauloHFS/goth/internal/config"
 	"github.com/PauloHFS/goth/internal/db"
+	"github.com/PauloHFS/goth/internal/logging"
 	"github.com/PauloHFS/goth/internal/validator"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// RateLimiter decide se outra tentativa de key (um IP ou um e-mail) pode
+// prosseguir — a mesma responsabilidade de middleware.Store.Allow (ver
+// internal/middleware/ratelimit_store.go), mas sem acoplar AuthService à
+// configuração rate.Limit/burst/window de um middleware HTTP: cada instância
+// injetada via WithRateLimiters já carrega seus próprios limites embutidos,
+// um por IP e outro por e-mail (mesmo desenho de
+// magicLinkIPLimiter/magicLinkEmailLimiter em internal/web/magiclink.go).
+type RateLimiter interface {
+	Allow(ctx context.Context, key string) (bool, error)
+}
+
 type AuthService struct {
-	queries *db.Queries
-	db      *sql.DB
-	config  *config.Config
+	queries      *db.Queries
+	db           *sql.DB
+	config       *config.Config
+	ipLimiter    RateLimiter
+	emailLimiter RateLimiter
+}
+
+// AuthServiceOption configura aspectos opcionais de NewAuthService.
+type AuthServiceOption func(*AuthService)
+
+// WithRateLimiters injeta os RateLimiter por IP e por e-mail aplicados por
+// Register/Login/ForgotPassword. Qualquer um dos dois pode ser nil para
+// desabilitar aquela dimensão do limite (ex. só limitar por e-mail).
+func WithRateLimiters(ipLimiter, emailLimiter RateLimiter) AuthServiceOption {
+	return func(s *AuthService) {
+		s.ipLimiter = ipLimiter
+		s.emailLimiter = emailLimiter
+	}
 }
 
-func NewAuthService(queries *db.Queries, db *sql.DB, cfg *config.Config) *AuthService {
-	return &AuthService{
+func NewAuthService(queries *db.Queries, dbConn *sql.DB, cfg *config.Config, opts ...AuthServiceOption) *AuthService {
+	s := &AuthService{
 		queries: queries,
-		db:      db,
+		db:      dbConn,
 		config:  cfg,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// tooManyAttemptsMessage é devolvida quando allowed bloqueia uma tentativa —
+// deliberadamente vaga sobre qual dimensão (IP ou e-mail) foi atingida, para
+// não dar a um atacante informação sobre qual dos dois limites o freou.
+const tooManyAttemptsMessage = "Muitas tentativas. Tente novamente mais tarde."
+
+// allowed consulta s.ipLimiter e s.emailLimiter (os que estiverem
+// configurados) para category; qualquer um dos dois negando já bloqueia a
+// tentativa. Um erro do limiter (ex. store indisponível) falha aberto, igual
+// a middleware.RateLimiter.Middleware, para não derrubar todo o fluxo de
+// autenticação por causa de uma dependência do rate limit.
+func (s *AuthService) allowed(ctx context.Context, category, ip, email string) bool {
+	if s.ipLimiter != nil && ip != "" {
+		ok, err := s.ipLimiter.Allow(ctx, category+":ip:"+ip)
+		if err != nil {
+			logging.Get().Warn("rate limiter de IP indisponível, falhando aberto", "category", category, "error", err)
+		} else if !ok {
+			return false
+		}
+	}
+	if s.emailLimiter != nil && email != "" {
+		ok, err := s.emailLimiter.Allow(ctx, category+":email:"+email)
+		if err != nil {
+			logging.Get().Warn("rate limiter de e-mail indisponível, falhando aberto", "category", category, "error", err)
+		} else if !ok {
+			return false
+		}
+	}
+	return true
 }
 
 type RegisterInput struct {
 	Email    string
 	Password string
+	IP       string
 }
 
 type RegisterOutput struct {
@@ -38,6 +102,10 @@ type RegisterOutput struct {
 }
 
 func (s *AuthService) Register(ctx context.Context, input RegisterInput) RegisterOutput {
+	if !s.allowed(ctx, "register", input.IP, input.Email) {
+		return RegisterOutput{Success: false, Error: tooManyAttemptsMessage}
+	}
+
 	validation := validator.ValidateRegistration(input.Email, input.Password)
 	if !validation.Valid {
 		errMsg := ""
@@ -79,10 +147,10 @@ func (s *AuthService) Register(ctx context.Context, input RegisterInput) Registe
 	}
 
 	tokenBytes := make([]byte, 32)
-	if _, err := fmt.Scanln(tokenBytes); err != nil {
+	if _, err := rand.Read(tokenBytes); err != nil {
 		return RegisterOutput{Success: false, Error: "Erro interno"}
 	}
-	token := fmt.Sprintf("%x", tokenBytes)
+	token := hex.EncodeToString(tokenBytes)
 
 	if err := qtx.UpsertEmailVerification(ctx, db.UpsertEmailVerificationParams{
 		Email:     input.Email,
@@ -116,6 +184,7 @@ func (s *AuthService) Register(ctx context.Context, input RegisterInput) Registe
 type LoginInput struct {
 	Email    string
 	Password string
+	IP       string
 }
 
 type LoginOutput struct {
@@ -125,6 +194,10 @@ type LoginOutput struct {
 }
 
 func (s *AuthService) Login(ctx context.Context, input LoginInput) LoginOutput {
+	if !s.allowed(ctx, "login", input.IP, input.Email) {
+		return LoginOutput{Success: false, Error: tooManyAttemptsMessage}
+	}
+
 	if input.Email == "" || input.Password == "" {
 		return LoginOutput{Success: false, Error: "Email e senha são obrigatórios"}
 	}
@@ -145,8 +218,15 @@ func (s *AuthService) Login(ctx context.Context, input LoginInput) LoginOutput {
 	return LoginOutput{Success: true, User: &user}
 }
 
+// antiEnumerationMessage é devolvida por ForgotPassword/RequestPasswordReset
+// tanto quando o e-mail existe quanto quando não existe, para não revelar a
+// terceiros quais e-mails estão cadastrados (mesmo racional de
+// magicLinkSentMessage em internal/web/magiclink.go).
+const antiEnumerationMessage = "Se o e-mail existir, um link será enviado."
+
 type ForgotPasswordInput struct {
 	Email string
+	IP    string
 }
 
 type ForgotPasswordOutput struct {
@@ -154,9 +234,37 @@ type ForgotPasswordOutput struct {
 	Message string
 }
 
+// ForgotPassword é o ponto de entrada usado pelo formulário público — delega
+// o trabalho de verdade a RequestPasswordReset, traduzindo seu resultado
+// para o formato já consumido pela página de "esqueci minha senha".
 func (s *AuthService) ForgotPassword(ctx context.Context, input ForgotPasswordInput) ForgotPasswordOutput {
+	out := s.RequestPasswordReset(ctx, RequestPasswordResetInput{Email: input.Email, IP: input.IP})
+	return ForgotPasswordOutput{Success: out.Success, Message: out.Message}
+}
+
+type RequestPasswordResetInput struct {
+	Email string
+	IP    string
+}
+
+type RequestPasswordResetOutput struct {
+	Success bool
+	Message string
+}
+
+// RequestPasswordReset gera um token de reset, grava só o seu hash SHA-256
+// (o token em si nunca é persistido — apenas enviado por e-mail via o job
+// send_password_reset_email, o mesmo esquema de handleForgotPassword em
+// internal/web/handlers.go) e enfileira esse job numa única transação com o
+// UpsertPasswordReset. Devolve antiEnumerationMessage exista ou não o
+// e-mail, para não permitir enumeração de contas.
+func (s *AuthService) RequestPasswordReset(ctx context.Context, input RequestPasswordResetInput) RequestPasswordResetOutput {
 	if err := validator.ValidateEmail(input.Email); err != nil {
-		return ForgotPasswordOutput{Success: false, Message: err.Error()}
+		return RequestPasswordResetOutput{Success: false, Message: err.Error()}
+	}
+
+	if !s.allowed(ctx, "forgot_password", input.IP, input.Email) {
+		return RequestPasswordResetOutput{Success: false, Message: tooManyAttemptsMessage}
 	}
 
 	_, err := s.queries.GetUserByEmail(ctx, db.GetUserByEmailParams{
@@ -164,10 +272,55 @@ func (s *AuthService) ForgotPassword(ctx context.Context, input ForgotPasswordIn
 		Email:    input.Email,
 	})
 	if err != nil {
-		return ForgotPasswordOutput{Success: true, Message: "Se o e-mail existir, um link será enviado."}
+		return RequestPasswordResetOutput{Success: true, Message: antiEnumerationMessage}
+	}
+
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return RequestPasswordResetOutput{Success: false, Message: "Erro interno"}
+	}
+	token := hex.EncodeToString(tokenBytes)
+	hash := sha256.Sum256([]byte(token))
+	tokenHash := hex.EncodeToString(hash[:])
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return RequestPasswordResetOutput{Success: false, Message: "Erro interno"}
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	qtx := s.queries.WithTx(tx)
+
+	if err := qtx.UpsertPasswordReset(ctx, db.UpsertPasswordResetParams{
+		Email:     input.Email,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(1 * time.Hour),
+	}); err != nil {
+		return RequestPasswordResetOutput{Success: false, Message: "Erro interno"}
+	}
+
+	jobPayload, err := json.Marshal(map[string]string{
+		"email": input.Email,
+		"token": token,
+	})
+	if err != nil {
+		return RequestPasswordResetOutput{Success: false, Message: "Erro interno"}
+	}
+
+	if _, err := qtx.CreateJob(ctx, db.CreateJobParams{
+		TenantID: sql.NullString{String: "default", Valid: true},
+		Type:     "send_password_reset_email",
+		Payload:  jobPayload,
+		RunAt:    sql.NullTime{Time: time.Now(), Valid: true},
+	}); err != nil {
+		return RequestPasswordResetOutput{Success: false, Message: "Erro interno"}
 	}
 
-	return ForgotPasswordOutput{Success: true, Message: "Se o e-mail existir, um link será enviado."}
+	if err := tx.Commit(); err != nil {
+		return RequestPasswordResetOutput{Success: false, Message: "Erro interno"}
+	}
+
+	return RequestPasswordResetOutput{Success: true, Message: antiEnumerationMessage}
 }
 
 type ResetPasswordInput struct {
@@ -180,10 +333,54 @@ type ResetPasswordOutput struct {
 	Error   string
 }
 
+// ResetPassword troca a senha do usuário dono de Token numa única
+// transação: resolve o token pelo seu hash SHA-256 (o mesmo esquema de
+// RequestPasswordReset), confere ExpiresAt, grava a nova senha e invalida o
+// token. Ao contrário de handleResetPassword em internal/web/handlers.go,
+// que só loga um aviso se a invalidação do token falhar, aqui ela faz parte
+// da mesma transação que a troca de senha: se não puder ser invalidado, a
+// troca de senha também é desfeita, para nunca deixar um token de reset
+// ainda válido depois de usado.
 func (s *AuthService) ResetPassword(ctx context.Context, input ResetPasswordInput) ResetPasswordOutput {
 	if err := validator.ValidatePassword(input.Password); err != nil {
 		return ResetPasswordOutput{Success: false, Error: err.Error()}
 	}
 
+	hash := sha256.Sum256([]byte(input.Token))
+	tokenHash := hex.EncodeToString(hash[:])
+
+	reset, err := s.queries.GetPasswordResetByToken(ctx, tokenHash)
+	if err != nil || reset.ExpiresAt.Before(time.Now()) {
+		return ResetPasswordOutput{Success: false, Error: "Link inválido ou expirado"}
+	}
+
+	newHash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return ResetPasswordOutput{Success: false, Error: "Erro ao processar senha"}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ResetPasswordOutput{Success: false, Error: "Erro interno"}
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	qtx := s.queries.WithTx(tx)
+
+	if err := qtx.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{
+		PasswordHash: string(newHash),
+		Email:        reset.Email,
+	}); err != nil {
+		return ResetPasswordOutput{Success: false, Error: "Erro interno"}
+	}
+
+	if err := qtx.DeletePasswordReset(ctx, reset.Email); err != nil {
+		return ResetPasswordOutput{Success: false, Error: "Erro interno"}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ResetPasswordOutput{Success: false, Error: "Erro interno"}
+	}
+
 	return ResetPasswordOutput{Success: true}
 }
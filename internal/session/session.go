@@ -0,0 +1,279 @@
+// Package session mantém um registro de primeira classe das sessões
+// autenticadas, em paralelo ao armazenamento opaco do scs, para permitir
+// listar, rotular (dispositivo/navegador) e revogar sessões individualmente.
+package session
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultTTL espelha o Lifetime padrão do scs (24h), usado quando o chamador
+// não configura um valor próprio.
+const DefaultTTL = 24 * time.Hour
+
+// Session representa uma linha da tabela sessions.
+type Session struct {
+	ID             int64
+	UserID         int64
+	TenantID       string
+	TokenHash      string
+	DevicePlatform string
+	DeviceBrowser  string
+	IP             string
+	CreatedAt      time.Time
+	LastActivityAt time.Time
+	ExpiresAt      time.Time
+	IsOAuth        bool
+	RevokedAt      sql.NullTime
+}
+
+// Label descreve a sessão em um formato curto para exibição ao usuário,
+// por exemplo "Chrome em Windows".
+func (s Session) Label() string {
+	switch {
+	case s.DeviceBrowser != "" && s.DevicePlatform != "":
+		return fmt.Sprintf("%s em %s", s.DeviceBrowser, s.DevicePlatform)
+	case s.DeviceBrowser != "":
+		return s.DeviceBrowser
+	case s.DevicePlatform != "":
+		return s.DevicePlatform
+	default:
+		return "Dispositivo desconhecido"
+	}
+}
+
+// Active reporta se a sessão ainda pode ser usada para autenticar requests.
+func (s Session) Active(now time.Time) bool {
+	if s.RevokedAt.Valid {
+		return false
+	}
+	return now.Before(s.ExpiresAt)
+}
+
+// Store grava e consulta sessões.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore cria um Store sobre a conexão de escrita do banco.
+func NewStore(dbConn *sql.DB) *Store {
+	return &Store{db: dbConn}
+}
+
+// EnsureTable cria a tabela sessions se ainda não existir. Assim como
+// internal/audit.Auditor.EnsureTable, ela fica fora do fluxo normal de
+// db.RunMigrations até que uma migração dedicada seja escrita.
+func (s *Store) EnsureTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS sessions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			tenant_id TEXT NOT NULL DEFAULT 'default',
+			token_hash TEXT NOT NULL UNIQUE,
+			device_platform TEXT,
+			device_browser TEXT,
+			ip TEXT,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			last_activity_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			expires_at DATETIME NOT NULL,
+			is_oauth BOOLEAN NOT NULL DEFAULT 0,
+			revoked_at DATETIME
+		);
+		CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id);
+		CREATE INDEX IF NOT EXISTS idx_sessions_token_hash ON sessions(token_hash);
+	`)
+	if err != nil {
+		return fmt.Errorf("falha ao garantir tabela sessions: %w", err)
+	}
+	return nil
+}
+
+// HashToken resume o token opaco do scs (sm.Token) para o formato persistido
+// na coluna token_hash, para que o token nunca fique em texto plano no banco.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateParams descreve os dados necessários para registrar uma nova sessão.
+type CreateParams struct {
+	UserID    int64
+	TenantID  string
+	Token     string
+	UserAgent string
+	IP        string
+	IsOAuth   bool
+	TTL       time.Duration
+}
+
+// Create grava uma nova sessão, tipicamente chamada logo após um login
+// bem-sucedido (senha ou OIDC).
+func (s *Store) Create(ctx context.Context, params CreateParams) (Session, error) {
+	tenantID := params.TenantID
+	if tenantID == "" {
+		tenantID = "default"
+	}
+
+	ttl := params.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	platform, browser := parseDevice(params.UserAgent)
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO sessions (user_id, tenant_id, token_hash, device_platform, device_browser, ip, created_at, last_activity_at, expires_at, is_oauth)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, params.UserID, tenantID, HashToken(params.Token), platform, browser, params.IP, now, now, expiresAt, params.IsOAuth)
+	if err != nil {
+		return Session{}, fmt.Errorf("falha ao gravar sessão: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return Session{}, fmt.Errorf("falha ao obter id da sessão: %w", err)
+	}
+
+	return Session{
+		ID:             id,
+		UserID:         params.UserID,
+		TenantID:       tenantID,
+		TokenHash:      HashToken(params.Token),
+		DevicePlatform: platform,
+		DeviceBrowser:  browser,
+		IP:             params.IP,
+		CreatedAt:      now,
+		LastActivityAt: now,
+		ExpiresAt:      expiresAt,
+		IsOAuth:        params.IsOAuth,
+	}, nil
+}
+
+// GetByTokenHash busca a sessão associada ao token opaco do scs já resumido.
+func (s *Store) GetByTokenHash(ctx context.Context, tokenHash string) (Session, error) {
+	var sess Session
+	var revokedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, user_id, tenant_id, token_hash, device_platform, device_browser, ip, created_at, last_activity_at, expires_at, is_oauth, revoked_at
+		FROM sessions WHERE token_hash = ?
+	`, tokenHash).Scan(&sess.ID, &sess.UserID, &sess.TenantID, &sess.TokenHash, &sess.DevicePlatform, &sess.DeviceBrowser, &sess.IP, &sess.CreatedAt, &sess.LastActivityAt, &sess.ExpiresAt, &sess.IsOAuth, &revokedAt)
+	if err != nil {
+		return Session{}, err
+	}
+	sess.RevokedAt = revokedAt
+	return sess, nil
+}
+
+// Touch atualiza last_activity_at para agora. Chamada a cada request
+// autenticado por middleware.RequireAuth.
+func (s *Store) Touch(ctx context.Context, tokenHash string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE sessions SET last_activity_at = ? WHERE token_hash = ?`, time.Now(), tokenHash)
+	if err != nil {
+		return fmt.Errorf("falha ao atualizar last_activity_at: %w", err)
+	}
+	return nil
+}
+
+// ListActiveByUser lista as sessões não revogadas e não expiradas de um
+// usuário, mais recentes primeiro, para a tela "Dispositivos conectados".
+func (s *Store) ListActiveByUser(ctx context.Context, userID int64) ([]Session, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, user_id, tenant_id, token_hash, device_platform, device_browser, ip, created_at, last_activity_at, expires_at, is_oauth, revoked_at
+		FROM sessions
+		WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?
+		ORDER BY last_activity_at DESC
+	`, userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("falha ao listar sessões: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		var sess Session
+		var revokedAt sql.NullTime
+		if err := rows.Scan(&sess.ID, &sess.UserID, &sess.TenantID, &sess.TokenHash, &sess.DevicePlatform, &sess.DeviceBrowser, &sess.IP, &sess.CreatedAt, &sess.LastActivityAt, &sess.ExpiresAt, &sess.IsOAuth, &revokedAt); err != nil {
+			return nil, fmt.Errorf("falha ao ler linha de sessão: %w", err)
+		}
+		sess.RevokedAt = revokedAt
+		sessions = append(sessions, sess)
+	}
+	return sessions, nil
+}
+
+// Revoke marca a sessão de id/userID como revogada, desde que pertença ao
+// usuário informado.
+func (s *Store) Revoke(ctx context.Context, id, userID int64) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE sessions SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL
+	`, time.Now(), id, userID)
+	if err != nil {
+		return fmt.Errorf("falha ao revogar sessão: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("falha ao confirmar revogação: %w", err)
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RevokeAllExcept revoga todas as sessões ativas do usuário, exceto a que
+// corresponde a keepTokenHash. Usada após troca de senha e mudança de papel,
+// e pelo endpoint "encerrar outras sessões".
+func (s *Store) RevokeAllExcept(ctx context.Context, userID int64, keepTokenHash string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE sessions SET revoked_at = ? WHERE user_id = ? AND token_hash != ? AND revoked_at IS NULL
+	`, time.Now(), userID, keepTokenHash)
+	if err != nil {
+		return fmt.Errorf("falha ao revogar demais sessões: %w", err)
+	}
+	return nil
+}
+
+// parseDevice extrai uma plataforma e um navegador aproximados a partir do
+// User-Agent. Não pretende ser um parser completo, apenas o suficiente para
+// rotular sessões na UI de conta.
+func parseDevice(userAgent string) (platform, browser string) {
+	ua := userAgent
+
+	switch {
+	case strings.Contains(ua, "Windows"):
+		platform = "Windows"
+	case strings.Contains(ua, "Mac OS X") || strings.Contains(ua, "Macintosh"):
+		platform = "macOS"
+	case strings.Contains(ua, "Android"):
+		platform = "Android"
+	case strings.Contains(ua, "iPhone") || strings.Contains(ua, "iPad"):
+		platform = "iOS"
+	case strings.Contains(ua, "Linux"):
+		platform = "Linux"
+	}
+
+	switch {
+	case strings.Contains(ua, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "OPR/") || strings.Contains(ua, "Opera"):
+		browser = "Opera"
+	case strings.Contains(ua, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "CriOS/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "Safari/") && strings.Contains(ua, "Version/"):
+		browser = "Safari"
+	}
+
+	return platform, browser
+}
@@ -3,33 +3,170 @@ package sse
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 const (
 	maxClientsPerResource = 100
 	maxGlobalClients      = 1000
+
+	// defaultRingBufferSize é quantos frames de uma resource key ficam
+	// guardados para replay quando um client reconecta com Last-Event-ID.
+	defaultRingBufferSize = 64
+	// defaultKeepaliveInterval é o intervalo entre comentários ": keepalive"
+	// enviados a cada client inscrito, para proxies intermediários não
+	// fecharem a conexão por ociosidade.
+	defaultKeepaliveInterval = 15 * time.Second
+	// defaultSlowConsumerTimeout é por quanto tempo o canal de um client pode
+	// ficar cheio antes de Broker desinscrevê-lo.
+	defaultSlowConsumerTimeout = 5 * time.Second
 )
 
+// Client representa uma conexão SSE inscrita numa resource key. slowSince
+// marca desde quando Events está cheio (channel full no select de
+// Broker.SendHTML); Broker desinscreve o client se isso persistir além de
+// slowConsumerTimeout, em vez de só descartar mensagens indefinidamente.
 type Client struct {
 	Events chan string
+
+	mu        sync.Mutex
+	slowSince time.Time
+}
+
+func (c *Client) markSlow(now time.Time) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.slowSince.IsZero() {
+		c.slowSince = now
+		return 0
+	}
+	return now.Sub(c.slowSince)
+}
+
+func (c *Client) clearSlow() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.slowSince = time.Time{}
+}
+
+// bufferedMessage é um frame SSE já formatado guardado no ring buffer de uma
+// resource key, usado para replay em reconexões com Last-Event-ID.
+type bufferedMessage struct {
+	id   int64
+	data string
+}
+
+// resourceState guarda, para uma resource key, os clients inscritos, o
+// próximo ID de evento monotônico e um ring buffer dos últimos frames
+// enviados. Deliberadamente sobrevive a Unsubscribe (mesmo sem clients,
+// o ring buffer continua disponível) para um reconnect logo em seguida
+// ainda conseguir repetir o que perdeu.
+type resourceState struct {
+	clients map[*Client]bool
+	nextID  int64
+	ring    []bufferedMessage
+	ringPos int
+	ringLen int
+}
+
+func newResourceState(ringSize int) *resourceState {
+	return &resourceState{
+		clients: make(map[*Client]bool),
+		ring:    make([]bufferedMessage, ringSize),
+	}
+}
+
+func (rs *resourceState) push(msg bufferedMessage) {
+	rs.ring[rs.ringPos] = msg
+	rs.ringPos = (rs.ringPos + 1) % len(rs.ring)
+	if rs.ringLen < len(rs.ring) {
+		rs.ringLen++
+	}
+}
+
+// since devolve, em ordem de envio, as mensagens bufferizadas com ID maior
+// que lastID.
+func (rs *resourceState) since(lastID int64) []bufferedMessage {
+	if rs.ringLen == 0 {
+		return nil
+	}
+
+	start := (rs.ringPos - rs.ringLen + len(rs.ring)) % len(rs.ring)
+	out := make([]bufferedMessage, 0, rs.ringLen)
+	for i := 0; i < rs.ringLen; i++ {
+		msg := rs.ring[(start+i)%len(rs.ring)]
+		if msg.id > lastID {
+			out = append(out, msg)
+		}
+	}
+	return out
 }
 
+// Broker distribui eventos SSE por resource key (ex.: "evaluation:42") e
+// mantém, para cada uma, um ring buffer que permite a um client reconectando
+// com o cabeçalho Last-Event-ID (semântica padrão de EventSource) recuperar
+// o que perdeu antes de voltar a receber eventos ao vivo.
 type Broker struct {
-	clients      map[string]map[*Client]bool
 	mutex        sync.RWMutex
+	resources    map[string]*resourceState
 	stop         chan struct{}
 	totalClients int
+
+	ringBufferSize      int
+	keepaliveInterval   time.Duration
+	slowConsumerTimeout time.Duration
 }
 
-func NewBroker() *Broker {
-	return &Broker{
-		clients: make(map[string]map[*Client]bool),
-		stop:    make(chan struct{}),
+// BrokerOption configura aspectos opcionais de NewBroker.
+type BrokerOption func(*Broker)
+
+// WithRingBufferSize ajusta quantos frames por resource key ficam
+// disponíveis para replay (padrão defaultRingBufferSize).
+func WithRingBufferSize(n int) BrokerOption {
+	return func(b *Broker) {
+		if n > 0 {
+			b.ringBufferSize = n
+		}
+	}
+}
+
+// WithKeepaliveInterval ajusta o intervalo entre comentários de keepalive
+// (padrão defaultKeepaliveInterval).
+func WithKeepaliveInterval(d time.Duration) BrokerOption {
+	return func(b *Broker) {
+		if d > 0 {
+			b.keepaliveInterval = d
+		}
 	}
 }
 
+// WithSlowConsumerTimeout ajusta por quanto tempo o canal de um client pode
+// ficar cheio antes de ser desinscrito (padrão defaultSlowConsumerTimeout).
+func WithSlowConsumerTimeout(d time.Duration) BrokerOption {
+	return func(b *Broker) {
+		if d > 0 {
+			b.slowConsumerTimeout = d
+		}
+	}
+}
+
+func NewBroker(opts ...BrokerOption) *Broker {
+	b := &Broker{
+		resources:           make(map[string]*resourceState),
+		stop:                make(chan struct{}),
+		ringBufferSize:      defaultRingBufferSize,
+		keepaliveInterval:   defaultKeepaliveInterval,
+		slowConsumerTimeout: defaultSlowConsumerTimeout,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
 func (b *Broker) GetResourceKey(resourceType, resourceID string) string {
 	return fmt.Sprintf("%s:%s", resourceType, resourceID)
 }
@@ -44,11 +181,13 @@ func (b *Broker) Subscribe(resourceType, resourceID string) (*Client, error) {
 		return nil, fmt.Errorf("max global connections reached")
 	}
 
-	if b.clients[key] == nil {
-		b.clients[key] = make(map[*Client]bool)
+	state, ok := b.resources[key]
+	if !ok {
+		state = newResourceState(b.ringBufferSize)
+		b.resources[key] = state
 	}
 
-	if len(b.clients[key]) >= maxClientsPerResource {
+	if len(state.clients) >= maxClientsPerResource {
 		return nil, fmt.Errorf("max connections for resource reached")
 	}
 
@@ -56,7 +195,7 @@ func (b *Broker) Subscribe(resourceType, resourceID string) (*Client, error) {
 		Events: make(chan string, 100),
 	}
 
-	b.clients[key][client] = true
+	state.clients[client] = true
 	b.totalClients++
 	return client, nil
 }
@@ -67,41 +206,109 @@ func (b *Broker) Unsubscribe(client *Client, resourceType, resourceID string) {
 	b.mutex.Lock()
 	defer b.mutex.Unlock()
 
-	if clients, ok := b.clients[key]; ok {
-		delete(clients, client)
+	state, ok := b.resources[key]
+	if !ok {
+		return
+	}
+
+	if _, exists := state.clients[client]; exists {
+		delete(state.clients, client)
 		close(client.Events)
-		if len(clients) == 0 {
-			delete(b.clients, key)
-		}
 		b.totalClients--
 	}
 }
 
+// SendHTML atribui o próximo ID monotônico da resource key, guarda o frame
+// formatado no ring buffer (para replay de reconexões) e tenta entregá-lo a
+// cada client inscrito. Um client cujo canal está cheio é marcado como lento
+// e só é desinscrito se isso persistir além de slowConsumerTimeout —
+// mensagens perdidas nesse meio tempo continuam disponíveis via replay
+// enquanto ainda couberem no ring buffer.
 func (b *Broker) SendHTML(resourceType, resourceID, eventType, html string) {
 	key := b.GetResourceKey(resourceType, resourceID)
 
-	b.mutex.RLock()
-	defer b.mutex.RUnlock()
-
-	var formattedData strings.Builder
-	lines := strings.Split(html, "\n")
-	for i, line := range lines {
-		formattedData.WriteString("data: " + line)
-		if i < len(lines)-1 {
-			formattedData.WriteString("\n")
-		}
+	b.mutex.Lock()
+	state, ok := b.resources[key]
+	if !ok {
+		state = newResourceState(b.ringBufferSize)
+		b.resources[key] = state
 	}
 
-	message := fmt.Sprintf("event: %s\n%s\n\n", eventType, formattedData.String())
+	state.nextID++
+	id := state.nextID
+	message := formatSSEFrame(id, eventType, html)
+	state.push(bufferedMessage{id: id, data: message})
+
+	clients := make([]*Client, 0, len(state.clients))
+	for client := range state.clients {
+		clients = append(clients, client)
+	}
+	b.mutex.Unlock()
 
-	for client := range b.clients[key] {
+	now := time.Now()
+	for _, client := range clients {
 		select {
 		case client.Events <- message:
+			client.clearSlow()
 		default:
+			if client.markSlow(now) >= b.slowConsumerTimeout {
+				b.Unsubscribe(client, resourceType, resourceID)
+			}
 		}
 	}
 }
 
+// formatSSEFrame monta um frame SSE completo com "id:", "event:" e uma ou
+// mais linhas "data:" (html pode ter múltiplas linhas), terminado pela linha
+// em branco exigida pelo protocolo.
+func formatSSEFrame(id int64, eventType, html string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "id: %d\n", id)
+	fmt.Fprintf(&b, "event: %s\n", eventType)
+
+	for _, line := range strings.Split(html, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// parseLastEventID lê o cabeçalho Last-Event-ID enviado automaticamente por
+// EventSource ao reconectar após uma conexão cair.
+func parseLastEventID(r *http.Request) (int64, bool) {
+	raw := r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
+// replaySince devolve os frames bufferizados da resource key com ID maior
+// que lastID, na ordem em que foram enviados.
+func (b *Broker) replaySince(resourceType, resourceID string, lastID int64) []string {
+	key := b.GetResourceKey(resourceType, resourceID)
+
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	state, ok := b.resources[key]
+	if !ok {
+		return nil
+	}
+
+	buffered := state.since(lastID)
+	out := make([]string, len(buffered))
+	for i, msg := range buffered {
+		out[i] = msg.data
+	}
+	return out
+}
+
 func (b *Broker) SendEvaluationProgress(evaluationID, phase string, progress, total int, html string) {
 	b.SendHTML("evaluation", evaluationID, "evaluation_progress", html)
 }
@@ -133,95 +340,102 @@ func Global() *Broker {
 	return globalBroker
 }
 
-func (b *Broker) Handler() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		resourceType := r.URL.Query().Get("type")
-		resourceID := r.URL.Query().Get("id")
+// serve faz o handshake SSE comum a Handler e AuthHandler: inscreve o
+// client, repete (replay) qualquer frame perdido desde o Last-Event-ID do
+// cliente, e então alterna entre eventos ao vivo e um ticker de keepalive
+// até o contexto da requisição terminar.
+func (b *Broker) serve(w http.ResponseWriter, r *http.Request, resourceType, resourceID string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
 
-		if resourceType == "" || resourceID == "" {
-			http.Error(w, "type and id required", http.StatusBadRequest)
-			return
+	client, err := b.Subscribe(resourceType, resourceID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer b.Unsubscribe(client, resourceType, resourceID)
+
+	// Deadline de escrita por client: se o proxy/navegador parar de drenar a
+	// conexão, não deixamos a goroutine deste client travada para sempre num
+	// Write que nunca retorna.
+	rc := http.NewResponseController(w)
+	writeDeadline := 2 * b.keepaliveInterval
+
+	write := func(frame string) bool {
+		_ = rc.SetWriteDeadline(time.Now().Add(writeDeadline))
+		if _, err := fmt.Fprint(w, frame); err != nil {
+			return false
 		}
+		flusher.Flush()
+		return true
+	}
 
-		w.Header().Set("Content-Type", "text/event-stream")
-		w.Header().Set("Cache-Control", "no-cache")
-		w.Header().Set("Connection", "keep-alive")
-		w.Header().Set("X-Accel-Buffering", "no")
-
-		flusher, ok := w.(http.Flusher)
-		if !ok {
-			http.Error(w, "Streaming not supported", http.StatusInternalServerError)
-			return
-		}
+	if !write(": ok\n\n") {
+		return
+	}
 
-		client, err := b.Subscribe(resourceType, resourceID)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusServiceUnavailable)
-			return
+	if lastID, ok := parseLastEventID(r); ok {
+		for _, frame := range b.replaySince(resourceType, resourceID, lastID) {
+			if !write(frame) {
+				return
+			}
 		}
-		defer b.Unsubscribe(client, resourceType, resourceID)
+	}
 
-		fmt.Fprintf(w, ": ok\n\n")
-		flusher.Flush()
+	keepalive := time.NewTicker(b.keepaliveInterval)
+	defer keepalive.Stop()
 
-		for {
-			select {
-			case message, ok := <-client.Events:
-				if !ok {
-					return
-				}
-				fmt.Fprint(w, message)
-				flusher.Flush()
-			case <-r.Context().Done():
+	for {
+		select {
+		case message, ok := <-client.Events:
+			if !ok {
 				return
 			}
+			if !write(message) {
+				return
+			}
+		case <-keepalive.C:
+			if !write(": keepalive\n\n") {
+				return
+			}
+		case <-r.Context().Done():
+			return
 		}
 	}
 }
 
-type AuthHandlerFunc func(w http.ResponseWriter, r *http.Request)
-
-func (b *Broker) AuthHandler(authFunc func(r *http.Request) (userID int64, resourceType, resourceID string)) http.HandlerFunc {
+func (b *Broker) Handler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		_, resourceType, resourceID := authFunc(r)
+		resourceType := r.URL.Query().Get("type")
+		resourceID := r.URL.Query().Get("id")
+
 		if resourceType == "" || resourceID == "" {
 			http.Error(w, "type and id required", http.StatusBadRequest)
 			return
 		}
 
-		w.Header().Set("Content-Type", "text/event-stream")
-		w.Header().Set("Cache-Control", "no-cache")
-		w.Header().Set("Connection", "keep-alive")
-		w.Header().Set("X-Accel-Buffering", "no")
+		b.serve(w, r, resourceType, resourceID)
+	}
+}
 
-		flusher, ok := w.(http.Flusher)
-		if !ok {
-			http.Error(w, "Streaming not supported", http.StatusInternalServerError)
-			return
-		}
+type AuthHandlerFunc func(w http.ResponseWriter, r *http.Request)
 
-		client, err := b.Subscribe("user:"+resourceType, resourceID)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+func (b *Broker) AuthHandler(authFunc func(r *http.Request) (userID int64, resourceType, resourceID string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, resourceType, resourceID := authFunc(r)
+		if resourceType == "" || resourceID == "" {
+			http.Error(w, "type and id required", http.StatusBadRequest)
 			return
 		}
 
-		defer b.Unsubscribe(client, "user:"+resourceType, resourceID)
-
-		fmt.Fprintf(w, ": ok\n\n")
-		flusher.Flush()
-
-		for {
-			select {
-			case message, ok := <-client.Events:
-				if !ok {
-					return
-				}
-				fmt.Fprint(w, message)
-				flusher.Flush()
-			case <-r.Context().Done():
-				return
-			}
-		}
+		b.serve(w, r, "user:"+resourceType, resourceID)
 	}
 }
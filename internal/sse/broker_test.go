@@ -0,0 +1,106 @@
+package sse
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBroker_ReplaySinceLastEventID(t *testing.T) {
+	b := NewBroker(WithRingBufferSize(8))
+
+	b.SendHTML("evaluation", "1", "evaluation_progress", "<p>10%</p>")
+	b.SendHTML("evaluation", "1", "evaluation_progress", "<p>50%</p>")
+	b.SendHTML("evaluation", "1", "evaluation_complete", "<p>done</p>")
+
+	frames := b.replaySince("evaluation", "1", 1)
+	if len(frames) != 2 {
+		t.Fatalf("expected 2 frames after last-event-id 1, got %d", len(frames))
+	}
+	if !strings.Contains(frames[0], "id: 2\n") {
+		t.Errorf("expected first replayed frame to be id 2, got %q", frames[0])
+	}
+	if !strings.Contains(frames[1], "id: 3\n") {
+		t.Errorf("expected second replayed frame to be id 3, got %q", frames[1])
+	}
+}
+
+func TestBroker_HandlerReconnectReplaysBufferedMessages(t *testing.T) {
+	b := NewBroker(WithRingBufferSize(8))
+
+	b.SendHTML("evaluation", "42", "evaluation_progress", "<p>10%</p>")
+	b.SendHTML("evaluation", "42", "evaluation_progress", "<p>50%</p>")
+
+	req := httptest.NewRequest(http.MethodGet, "/sse?type=evaluation&id=42", nil)
+	req.Header.Set("Last-Event-ID", "1")
+
+	ctx, cancel := context.WithCancel(req.Context())
+	req = req.WithContext(ctx)
+	defer cancel()
+
+	rec := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		b.Handler()(rec, req)
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-done
+
+	body := rec.Body.String()
+	if !strings.Contains(body, "id: 2") {
+		t.Errorf("expected replay of buffered message id 2 in response, got: %q", body)
+	}
+	if strings.Contains(body, "id: 1") {
+		t.Errorf("did not expect message id 1 to be replayed (already seen), got: %q", body)
+	}
+}
+
+func TestBroker_SlowConsumerEviction(t *testing.T) {
+	b := NewBroker(
+		WithRingBufferSize(4),
+		WithSlowConsumerTimeout(30*time.Millisecond),
+	)
+
+	client, err := b.Subscribe("evaluation", "7")
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	// Enche o canal do client (capacidade 100) sem nunca drenar, simulando um
+	// consumidor lento.
+	for i := 0; i < 100; i++ {
+		b.SendHTML("evaluation", "7", "evaluation_progress", "<p>tick</p>")
+	}
+
+	b.mutex.RLock()
+	state := b.resources[b.GetResourceKey("evaluation", "7")]
+	_, stillSubscribed := state.clients[client]
+	b.mutex.RUnlock()
+	if !stillSubscribed {
+		t.Fatalf("client should still be subscribed right after filling its channel")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		b.SendHTML("evaluation", "7", "evaluation_progress", "<p>tick</p>")
+
+		b.mutex.RLock()
+		_, stillSubscribed = state.clients[client]
+		b.mutex.RUnlock()
+		if !stillSubscribed {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if stillSubscribed {
+		t.Fatal("expected slow consumer to eventually be unsubscribed")
+	}
+}
@@ -0,0 +1,73 @@
+package totp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+)
+
+// DeriveKey reduz um segredo de aplicação de tamanho arbitrário (ex.
+// config.SessionSecret) a uma chave AES-256 de 32 bytes, para que o mesmo
+// segredo já usado pelo scs também proteja o TOTP em repouso.
+func DeriveKey(appSecret string) []byte {
+	sum := sha256.Sum256([]byte(appSecret))
+	return sum[:]
+}
+
+// EncryptSecret cifra o segredo TOTP em repouso com AES-256-GCM, no mesmo
+// formato usado por mailer.EncryptAPIKey (nonce prefixado ao ciphertext,
+// base64 padrão).
+func EncryptSecret(secret string, key []byte) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	ciphertext := gcm.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// DecryptSecret reverte EncryptSecret.
+func DecryptSecret(encrypted string, key []byte) (string, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	nonceSize := gcm.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return "", errors.New("ciphertext muito curto")
+	}
+
+	nonce, ciphertext := ciphertext[:nonceSize], ciphertext[nonceSize:]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(plaintext), nil
+}
@@ -0,0 +1,77 @@
+package totp
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const recoveryCodeCount = 10
+
+// GenerateRecoveryCodes cria 10 códigos de uso único no formato
+// "xxxx-xxxx" (dígitos), usados para login quando o app autenticador não
+// está disponível.
+func GenerateRecoveryCodes() ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := randomDigits(8)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao gerar código de recuperação: %w", err)
+		}
+		codes[i] = code[:4] + "-" + code[4:]
+	}
+	return codes, nil
+}
+
+func randomDigits(n int) (string, error) {
+	digitsOut := make([]byte, n)
+	for i := range digitsOut {
+		d, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		digitsOut[i] = byte('0') + byte(d.Int64())
+	}
+	return string(digitsOut), nil
+}
+
+// HashRecoveryCodes aplica bcrypt a cada código (mesma convenção usada para
+// senhas) e serializa o resultado como o array JSON persistido em
+// user_totp.recovery_codes_hash.
+func HashRecoveryCodes(codes []string) (json.RawMessage, error) {
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("falha ao hashear código de recuperação: %w", err)
+		}
+		hashes[i] = string(hash)
+	}
+	return json.Marshal(hashes)
+}
+
+// ConsumeRecoveryCode procura code entre os hashes armazenados; se achar,
+// retorna o array restante (sem aquele hash, garantindo uso único) e ok=true.
+func ConsumeRecoveryCode(storedHashes json.RawMessage, code string) (remaining json.RawMessage, ok bool) {
+	var hashes []string
+	if err := json.Unmarshal(storedHashes, &hashes); err != nil {
+		return storedHashes, false
+	}
+
+	for i, hash := range hashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(code)) == nil {
+			rest := make([]string, 0, len(hashes)-1)
+			rest = append(rest, hashes[:i]...)
+			rest = append(rest, hashes[i+1:]...)
+			out, err := json.Marshal(rest)
+			if err != nil {
+				return storedHashes, false
+			}
+			return out, true
+		}
+	}
+	return storedHashes, false
+}
@@ -0,0 +1,164 @@
+package totp
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Enrollment representa uma linha da tabela user_totp.
+type Enrollment struct {
+	UserID            int64
+	SecretEncrypted   string
+	RecoveryCodesHash json.RawMessage
+	EnabledAt         sql.NullTime
+	// LastUsedStep é o maior contador de passo TOTP (ver totp.ValidateStep)
+	// já aceito para este usuário. ConsumeStep rejeita qualquer passo <= este
+	// valor, impedindo que o mesmo código (ou um código de um passo anterior
+	// dentro da janela de tolerância) seja reutilizado.
+	LastUsedStep sql.NullInt64
+}
+
+// Enabled reporta se o enrollment já foi confirmado (segundo fator ativo).
+// Enquanto EnabledAt não é definido, o segredo foi apenas gerado e aguarda
+// confirmação de um código válido (ver handleProfile2FAEnable).
+func (e Enrollment) Enabled() bool {
+	return e.EnabledAt.Valid
+}
+
+// Store grava e consulta o segundo fator TOTP dos usuários.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore cria um Store sobre a conexão de escrita do banco.
+func NewStore(dbConn *sql.DB) *Store {
+	return &Store{db: dbConn}
+}
+
+// EnsureTable cria a tabela user_totp se ainda não existir. Assim como
+// internal/session.Store.EnsureTable, fica fora do fluxo normal de
+// db.RunMigrations até que uma migração dedicada seja escrita.
+func (s *Store) EnsureTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS user_totp (
+			user_id INTEGER PRIMARY KEY,
+			secret_encrypted TEXT NOT NULL,
+			recovery_codes_hash TEXT NOT NULL DEFAULT '[]',
+			enabled_at DATETIME,
+			last_used_step INTEGER,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	if err != nil {
+		return fmt.Errorf("falha ao garantir tabela user_totp: %w", err)
+	}
+
+	// last_used_step foi adicionada depois da criação inicial da tabela;
+	// mesmo idioma de authz.Manager.EnsureSchema para bancos que já tinham
+	// user_totp sem a coluna.
+	if _, err := s.db.ExecContext(ctx, `ALTER TABLE user_totp ADD COLUMN last_used_step INTEGER`); err != nil &&
+		!strings.Contains(strings.ToLower(err.Error()), "duplicate column") {
+		return fmt.Errorf("falha ao adicionar user_totp.last_used_step: %w", err)
+	}
+	return nil
+}
+
+// Get busca o enrollment do usuário. Retorna sql.ErrNoRows quando o usuário
+// nunca iniciou o cadastro do segundo fator.
+func (s *Store) Get(ctx context.Context, userID int64) (Enrollment, error) {
+	var e Enrollment
+	var codesHash string
+	var enabledAt sql.NullTime
+	var lastUsedStep sql.NullInt64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT user_id, secret_encrypted, recovery_codes_hash, enabled_at, last_used_step
+		FROM user_totp WHERE user_id = ?
+	`, userID).Scan(&e.UserID, &e.SecretEncrypted, &codesHash, &enabledAt, &lastUsedStep)
+	if err != nil {
+		return Enrollment{}, err
+	}
+	e.RecoveryCodesHash = json.RawMessage(codesHash)
+	e.EnabledAt = enabledAt
+	e.LastUsedStep = lastUsedStep
+	return e, nil
+}
+
+// ConsumeStep tenta gravar step como o último passo TOTP aceito para userID,
+// rejeitando caso já exista um last_used_step maior ou igual — o UPDATE
+// condicional na própria cláusula WHERE faz a checagem e a gravação na mesma
+// instrução, então duas chamadas concorrentes para o mesmo step (ou chamadas
+// fora de ordem) não conseguem, ambas, "ganhar": no máximo uma linha é
+// afetada. Devolve false (sem erro) quando step já foi consumido antes, o
+// sinal para o chamador (ver internal/web/otp.go) tratar o código como
+// replay e recusar o login/confirmação mesmo que ValidateStep tenha aceitado
+// a assinatura HMAC.
+func (s *Store) ConsumeStep(ctx context.Context, userID int64, step int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE user_totp SET last_used_step = ?
+		WHERE user_id = ? AND (last_used_step IS NULL OR last_used_step < ?)
+	`, step, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("falha ao consumir passo TOTP: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("falha ao confirmar consumo do passo TOTP: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// StartEnrollment grava (ou substitui) um segredo pendente de confirmação
+// para o usuário, sem marcá-lo como habilitado.
+func (s *Store) StartEnrollment(ctx context.Context, userID int64, secretEncrypted string) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO user_totp (user_id, secret_encrypted, recovery_codes_hash, enabled_at)
+		VALUES (?, ?, '[]', NULL)
+		ON CONFLICT(user_id) DO UPDATE SET secret_encrypted = excluded.secret_encrypted, recovery_codes_hash = '[]', enabled_at = NULL
+	`, userID, secretEncrypted)
+	if err != nil {
+		return fmt.Errorf("falha ao iniciar cadastro TOTP: %w", err)
+	}
+	return nil
+}
+
+// Enable confirma o enrollment pendente, gravando os códigos de recuperação
+// hasheados e marcando enabled_at como agora.
+func (s *Store) Enable(ctx context.Context, userID int64, recoveryCodesHash json.RawMessage) error {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE user_totp SET recovery_codes_hash = ?, enabled_at = ? WHERE user_id = ?
+	`, string(recoveryCodesHash), time.Now(), userID)
+	if err != nil {
+		return fmt.Errorf("falha ao habilitar TOTP: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("falha ao confirmar habilitação do TOTP: %w", err)
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// UpdateRecoveryCodes persiste o array de hashes restante após o consumo de
+// um código de recuperação (ver ConsumeRecoveryCode).
+func (s *Store) UpdateRecoveryCodes(ctx context.Context, userID int64, recoveryCodesHash json.RawMessage) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE user_totp SET recovery_codes_hash = ? WHERE user_id = ?`, string(recoveryCodesHash), userID)
+	if err != nil {
+		return fmt.Errorf("falha ao atualizar códigos de recuperação: %w", err)
+	}
+	return nil
+}
+
+// Disable remove o segundo fator do usuário por completo.
+func (s *Store) Disable(ctx context.Context, userID int64) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM user_totp WHERE user_id = ?`, userID)
+	if err != nil {
+		return fmt.Errorf("falha ao desabilitar TOTP: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package totp
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+
+	dbConn, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dbConn.Close() })
+
+	store := NewStore(dbConn)
+	if err := store.EnsureTable(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestConsumeStepRejectsReplayedStep(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	const userID = int64(1)
+
+	if err := store.StartEnrollment(ctx, userID, "encrypted-secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err := store.ConsumeStep(ctx, userID, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("ConsumeStep rejected the first use of a fresh step")
+	}
+
+	// Mesmo passo de novo (ex. um invasor reenviando o mesmo código
+	// observado): deve ser recusado como replay.
+	ok, err = store.ConsumeStep(ctx, userID, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Error("ConsumeStep accepted a replayed step")
+	}
+}
+
+func TestConsumeStepRejectsStepWithinToleranceWindowAfterward(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	const userID = int64(1)
+
+	if err := store.StartEnrollment(ctx, userID, "encrypted-secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := store.ConsumeStep(ctx, userID, 100); err != nil || !ok {
+		t.Fatalf("ConsumeStep(100) = %v, %v", ok, err)
+	}
+
+	// ValidateStep tolera ±skewSteps, então um código do passo anterior
+	// (99) ainda passaria na verificação HMAC — ConsumeStep deve recusá-lo
+	// mesmo assim, por já termos avançado para um passo maior.
+	ok, err := store.ConsumeStep(ctx, userID, 99)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Error("ConsumeStep accepted a step older than the last one already consumed")
+	}
+}
+
+func TestConsumeStepAcceptsMonotonicallyIncreasingSteps(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	const userID = int64(1)
+
+	if err := store.StartEnrollment(ctx, userID, "encrypted-secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, step := range []int64{100, 101, 105} {
+		ok, err := store.ConsumeStep(ctx, userID, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			t.Errorf("ConsumeStep(%d) rejected a step higher than the last consumed one", step)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Package totp implementa TOTP (RFC 6238) sobre HMAC-SHA1, no formato usado
+// por apps autenticadores comuns (Google Authenticator, Authy etc.): passo
+// de 30s, 6 dígitos, com tolerância de ±1 passo para compensar relógios
+// dessincronizados.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	period    = 30 * time.Second
+	digits    = 6
+	skewSteps = 1
+)
+
+// GenerateSecret cria um segredo aleatório de 160 bits codificado em
+// Base32 sem padding, pronto para ser exibido/QR-codificado e usado com
+// ProvisioningURI.
+func GenerateSecret() (string, error) {
+	raw := make([]byte, 20)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("falha ao gerar segredo TOTP: %w", err)
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw), nil
+}
+
+// Generate calcula o código TOTP de 6 dígitos para o instante t.
+func Generate(secret string, t time.Time) (string, error) {
+	return generateAtStep(secret, counterAt(t))
+}
+
+// Validate verifica code contra o segredo, aceitando também o passo anterior
+// e o seguinte (±30s) para tolerar relógios levemente dessincronizados.
+//
+// Validate por si só não impede replay: um código observado por um invasor
+// continua válido por toda a janela de tolerância. Chamadores que persistem
+// o passo aceito (ver ValidateStep e totp.Store.ConsumeStep) devem preferir
+// ValidateStep a Validate.
+func Validate(secret, code string, t time.Time) bool {
+	_, ok := ValidateStep(secret, code, t)
+	return ok
+}
+
+// ValidateStep funciona como Validate, mas também devolve o contador de passo
+// (ver counterAt) que casou com code. Chamadores devem repassar esse valor a
+// totp.Store.ConsumeStep antes de tratar o código como aceito, para rejeitar
+// o reuso do mesmo código dentro da janela de tolerância de ±1 passo.
+func ValidateStep(secret, code string, t time.Time) (int64, bool) {
+	counter := counterAt(t)
+	for delta := -skewSteps; delta <= skewSteps; delta++ {
+		step := counter + int64(delta)
+		expected, err := generateAtStep(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func counterAt(t time.Time) int64 {
+	return t.Unix() / int64(period.Seconds())
+}
+
+func generateAtStep(secret string, counter int64) (string, error) {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("segredo TOTP inválido: %w", err)
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	truncated := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	code := truncated % pow10(digits)
+	return fmt.Sprintf("%0*d", digits, code), nil
+}
+
+func pow10(n int) uint32 {
+	result := uint32(1)
+	for i := 0; i < n; i++ {
+		result *= 10
+	}
+	return result
+}
+
+// ProvisioningURI monta a URI otpauth://totp/ lida por apps autenticadores
+// para registrar issuer/conta/segredo sem digitação manual.
+func ProvisioningURI(issuer, accountName, secret string) string {
+	label := fmt.Sprintf("%s:%s", issuer, accountName)
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprintf("%d", digits))
+	q.Set("period", fmt.Sprintf("%d", int(period.Seconds())))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + label,
+		RawQuery: q.Encode(),
+	}
+	return u.String()
+}
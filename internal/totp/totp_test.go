@@ -0,0 +1,69 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateStepAcceptsCurrentCode(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+
+	code, err := Generate(secret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	step, ok := ValidateStep(secret, code, now)
+	if !ok {
+		t.Fatal("ValidateStep rejected a code generated for the same instant")
+	}
+	if step != counterAt(now) {
+		t.Errorf("step = %d, want %d", step, counterAt(now))
+	}
+}
+
+func TestValidateStepRejectsCodeOutsideSkewWindow(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+
+	code, err := Generate(secret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	future := now.Add(time.Duration(skewSteps+2) * period)
+	if _, ok := ValidateStep(secret, code, future); ok {
+		t.Error("ValidateStep accepted a code well outside the ±skewSteps tolerance window")
+	}
+}
+
+// TestValidateStepAloneDoesNotPreventReplay documenta a limitação descrita
+// no doc comment de Validate/ValidateStep: validar a assinatura HMAC de novo
+// aceita o mesmo código repetidamente — só Store.ConsumeStep, persistindo o
+// passo já usado, barra o replay (ver TestConsumeStepRejectsReplayedStep).
+func TestValidateStepAloneDoesNotPreventReplay(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+
+	code, err := Generate(secret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := ValidateStep(secret, code, now); !ok {
+		t.Fatal("first ValidateStep call unexpectedly rejected the code")
+	}
+	if _, ok := ValidateStep(secret, code, now); !ok {
+		t.Fatal("second ValidateStep call with the same code+instant unexpectedly rejected it")
+	}
+}
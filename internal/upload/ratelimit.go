@@ -0,0 +1,59 @@
+package upload
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"golang.org/x/time/rate"
+
+	"github.com/PauloHFS/goth/internal/contextkeys"
+	"github.com/PauloHFS/goth/internal/db"
+)
+
+// RateLimiter limita quantos uploads um (tenant, usuário) pode fazer numa
+// categoria de Config. Não é o mesmo RateLimiter de internal/middleware —
+// aquele atua por rota HTTP, tipicamente por IP; este atua dentro de
+// SaveFile, por (tenant_id, user_id) extraído do contexto (ver
+// rateLimitKey), o que permite burst mais folgado para avatares do que para
+// documentos sem precisar de uma rota por categoria.
+type RateLimiter interface {
+	Allow(ctx context.Context, key string) bool
+}
+
+// TokenBucketLimiter é a implementação padrão de RateLimiter: um
+// golang.org/x/time/rate.Limiter por chave, guardado num sync.Map — mesma
+// ideia de middleware.MemoryStore, mas sem sweeper de limpeza periódica: o
+// número de chaves (tenant_id, user_id) ativas ao mesmo tempo é pequeno o
+// bastante para não justificar a goroutine extra.
+type TokenBucketLimiter struct {
+	rate     rate.Limit
+	burst    int
+	limiters sync.Map
+}
+
+// NewTokenBucketLimiter cria um TokenBucketLimiter que permite rate uploads
+// por segundo, com um burst inicial de burst.
+func NewTokenBucketLimiter(rate rate.Limit, burst int) *TokenBucketLimiter {
+	return &TokenBucketLimiter{rate: rate, burst: burst}
+}
+
+func (l *TokenBucketLimiter) Allow(_ context.Context, key string) bool {
+	limiterAny, _ := l.limiters.LoadOrStore(key, rate.NewLimiter(l.rate, l.burst))
+	return limiterAny.(*rate.Limiter).Allow()
+}
+
+// rateLimitKey identifica o autor de um upload como "tenant_id:user_id",
+// lido de contextkeys.UserContextKey — o mesmo valor que
+// middleware.RequireAuth injeta no contexto da requisição (ver
+// contextkeys.CSRFTokenKey para o mesmo padrão de valor-por-contexto). Sem
+// um usuário autenticado no contexto, cai para uma chave "anonymous"
+// compartilhada, então SaveFile chamado fora de uma requisição HTTP
+// autenticada ainda é limitado, só que por um balde único.
+func rateLimitKey(ctx context.Context) string {
+	user, ok := ctx.Value(contextkeys.UserContextKey).(db.User)
+	if !ok {
+		return "anonymous"
+	}
+	return user.TenantID + ":" + strconv.FormatInt(user.ID, 10)
+}
@@ -0,0 +1,360 @@
+package upload
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/PauloHFS/goth/internal/filestore"
+	"github.com/PauloHFS/goth/internal/logging"
+)
+
+// tusVersion é a única versão do protocolo tus (https://tus.io/protocols/resumable-upload)
+// que TusHandler entende. Requisições Create/Patch sem o header Tus-Resumable
+// são rejeitadas, como um cliente tus de verdade esperaria.
+const tusVersion = "1.0.0"
+
+// TusHandler implementa o subconjunto core do protocolo tus de uploads
+// resumíveis sobre a mesma validação de Config usada por SaveFile. Diferente
+// de SaveFile (que lê um multipart completo de uma vez), o conteúdo chega aos
+// pedaços via PATCH; como filestore.Backend não tem uma primitiva de append
+// num objeto existente (só Put, que regrava o objeto inteiro), os pedaços são
+// acumulados numa coluna BLOB da tabela tus_uploads e só gravados no backend
+// quando o upload é concluído — uma tabela de staging, não um backend novo.
+type TusHandler struct {
+	db      *sql.DB
+	backend filestore.Backend
+	cfg     Config
+	expiry  time.Duration
+}
+
+// NewTusHandler cria um TusHandler. expiry é quanto tempo um upload
+// incompleto fica visível antes de ser tratado como expirado (e removido na
+// próxima requisição que o referenciar — ver loadUpload).
+func NewTusHandler(dbConn *sql.DB, backend filestore.Backend, cfg Config, expiry time.Duration) *TusHandler {
+	return &TusHandler{db: dbConn, backend: backend, cfg: cfg, expiry: expiry}
+}
+
+// EnsureTable cria a tabela tus_uploads caso não exista.
+func (h *TusHandler) EnsureTable(ctx context.Context) error {
+	_, err := h.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS tus_uploads (
+			id TEXT PRIMARY KEY,
+			total_size INTEGER NOT NULL,
+			offset INTEGER NOT NULL DEFAULT 0,
+			mime_type TEXT NOT NULL DEFAULT '',
+			filename TEXT NOT NULL DEFAULT '',
+			content BLOB NOT NULL DEFAULT (x''),
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			expires_at DATETIME NOT NULL
+		)
+	`)
+	return err
+}
+
+// Create handles POST /uploads.
+// @Summary Iniciar upload resumível
+// @Description Aloca um upload tus com o tamanho total declarado em Upload-Length.
+// @Tags uploads
+// @Param Upload-Length header int true "Tamanho total do arquivo em bytes"
+// @Param Upload-Metadata header string false "filename,filetype em base64, separados por vírgula"
+// @Success 201 {string} string "Created"
+// @Failure 400 {string} string "Bad Request"
+// @Failure 413 {string} string "Payload Too Large"
+// @Router /uploads [post]
+func (h *TusHandler) Create(w http.ResponseWriter, r *http.Request) {
+	ctx, event := logging.NewEventContext(r.Context())
+	event.Add(slog.String("method", r.Method), slog.String("path", r.URL.Path))
+
+	if !h.checkResumable(w, r, ctx, event) {
+		return
+	}
+
+	totalSize, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || totalSize <= 0 {
+		h.fail(w, ctx, event, http.StatusBadRequest, "missing or invalid Upload-Length")
+		return
+	}
+	if totalSize > h.cfg.MaxSize {
+		h.fail(w, ctx, event, http.StatusRequestEntityTooLarge, "upload exceeds configured size limit")
+		return
+	}
+
+	metadata := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+	filename := metadata["filename"]
+	if ext := filepath.Ext(filename); filename != "" && !isAllowedExt(ext, h.cfg.AllowedExt) {
+		h.fail(w, ctx, event, http.StatusBadRequest, fmt.Sprintf("extension not allowed: %s", ext))
+		return
+	}
+	if filetype := metadata["filetype"]; filetype != "" && !isAllowedMIME(filetype, h.cfg.AllowedMIME) {
+		h.fail(w, ctx, event, http.StatusBadRequest, fmt.Sprintf("mime type not allowed: %s", filetype))
+		return
+	}
+
+	id := uuid.New().String()
+	_, err = h.db.ExecContext(ctx, `
+		INSERT INTO tus_uploads (id, total_size, mime_type, filename, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, id, totalSize, metadata["filetype"], filename, time.Now().Add(h.expiry))
+	if err != nil {
+		h.fail(w, ctx, event, http.StatusInternalServerError, "failed to create upload")
+		return
+	}
+
+	event.Add(slog.String("upload_id", id), slog.Int64("total_size", totalSize), slog.String("outcome", "success"))
+	logging.Get().Log(ctx, slog.LevelInfo, "tus upload created", event.Attrs()...)
+
+	w.Header().Set("Location", "/uploads/"+id)
+	w.Header().Set("Upload-Offset", "0")
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// Head handles HEAD /uploads/{id}.
+func (h *TusHandler) Head(w http.ResponseWriter, r *http.Request) {
+	ctx, event := logging.NewEventContext(r.Context())
+	id := r.PathValue("id")
+	event.Add(slog.String("method", r.Method), slog.String("upload_id", id))
+
+	up, err := h.loadUpload(ctx, id)
+	if err != nil {
+		h.fail(w, ctx, event, http.StatusNotFound, "upload not found")
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(up.offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(up.totalSize, 10))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.WriteHeader(http.StatusOK)
+}
+
+// Patch handles PATCH /uploads/{id}, appending bytes at Upload-Offset and,
+// quando o offset resultante alcança o tamanho total, persistindo o arquivo
+// completo via backend com a mesma validação de conteúdo usada por SaveFile.
+func (h *TusHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	ctx, event := logging.NewEventContext(r.Context())
+	id := r.PathValue("id")
+	event.Add(slog.String("method", r.Method), slog.String("upload_id", id))
+
+	if !h.checkResumable(w, r, ctx, event) {
+		return
+	}
+
+	if ct := r.Header.Get("Content-Type"); ct != "application/offset+octet-stream" {
+		h.fail(w, ctx, event, http.StatusUnsupportedMediaType, "Content-Type must be application/offset+octet-stream")
+		return
+	}
+
+	clientOffset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		h.fail(w, ctx, event, http.StatusBadRequest, "missing or invalid Upload-Offset")
+		return
+	}
+
+	up, err := h.loadUpload(ctx, id)
+	if err != nil {
+		h.fail(w, ctx, event, http.StatusNotFound, "upload not found")
+		return
+	}
+	if clientOffset != up.offset {
+		h.fail(w, ctx, event, http.StatusConflict, "Upload-Offset does not match current offset")
+		return
+	}
+
+	remaining := up.totalSize - up.offset
+	chunk, err := io.ReadAll(io.LimitReader(r.Body, remaining+1))
+	if err != nil {
+		h.fail(w, ctx, event, http.StatusInternalServerError, "failed to read chunk body")
+		return
+	}
+	if int64(len(chunk)) > remaining {
+		h.fail(w, ctx, event, http.StatusBadRequest, "chunk exceeds declared Upload-Length")
+		return
+	}
+
+	newOffset := up.offset + int64(len(chunk))
+	if _, err := h.db.ExecContext(ctx, `
+		UPDATE tus_uploads SET content = content || ?, offset = ? WHERE id = ?
+	`, chunk, newOffset, id); err != nil {
+		h.fail(w, ctx, event, http.StatusInternalServerError, "failed to store chunk")
+		return
+	}
+
+	event.Add(slog.Int64("offset", newOffset), slog.Int64("total_size", up.totalSize))
+
+	if newOffset < up.totalSize {
+		event.Add(slog.String("outcome", "partial"))
+		logging.Get().Log(ctx, slog.LevelInfo, "tus upload chunk stored", event.Attrs()...)
+		w.Header().Set("Upload-Offset", strconv.FormatInt(newOffset, 10))
+		w.Header().Set("Tus-Resumable", tusVersion)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	result, err := h.complete(ctx, id, up.filename)
+	if err != nil {
+		var uploadErr *UploadError
+		status := http.StatusInternalServerError
+		if errors.As(err, &uploadErr) {
+			status = http.StatusUnprocessableEntity
+		}
+		event.Add(slog.String("outcome", "error"), slog.String("error", err.Error()))
+		logging.Get().Log(ctx, slog.LevelError, "tus upload completion failed", event.Attrs()...)
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	event.Add(
+		slog.String("outcome", "completed"),
+		slog.String("key", result.Key),
+		slog.Float64("duration_ms", float64(time.Since(start).Nanoseconds())/1e6),
+	)
+	logging.Get().Log(ctx, slog.LevelInfo, "tus upload completed", event.Attrs()...)
+
+	// O protocolo tus core não define um corpo para a resposta de conclusão,
+	// só o header Upload-Offset. Devolvemos o Result como JSON mesmo assim
+	// (um desvio pragmático e documentado) para que o chamador não precise de
+	// um segundo round-trip só para descobrir a Key gravada no backend.
+	w.Header().Set("Upload-Offset", strconv.FormatInt(newOffset, 10))
+	w.Header().Set("Tus-Resumable", tusVersion)
+	writeJSON(w, http.StatusOK, result)
+}
+
+// Delete handles DELETE /uploads/{id}, abandonando um upload incompleto.
+func (h *TusHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	ctx, event := logging.NewEventContext(r.Context())
+	id := r.PathValue("id")
+	event.Add(slog.String("method", r.Method), slog.String("upload_id", id))
+
+	if _, err := h.db.ExecContext(ctx, `DELETE FROM tus_uploads WHERE id = ?`, id); err != nil {
+		h.fail(w, ctx, event, http.StatusInternalServerError, "failed to delete upload")
+		return
+	}
+
+	event.Add(slog.String("outcome", "success"))
+	logging.Get().Log(ctx, slog.LevelInfo, "tus upload deleted", event.Attrs()...)
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// complete roda a mesma validação de conteúdo de SaveFile (sniff + extensão)
+// sobre o buffer acumulado, grava o resultado via backend e remove a linha
+// de staging.
+func (h *TusHandler) complete(ctx context.Context, id, filename string) (*Result, error) {
+	var content []byte
+	if err := h.db.QueryRowContext(ctx, `SELECT content FROM tus_uploads WHERE id = ?`, id).Scan(&content); err != nil {
+		return nil, fmt.Errorf("failed to load assembled upload: %w", err)
+	}
+
+	sniffLen := min(len(content), 512)
+	sniffedType := sniffContentType(content[:sniffLen])
+	if !isAllowedMIME(sniffedType, h.cfg.AllowedMIME) {
+		return nil, &UploadError{
+			Code:    "TYPE_MISMATCH",
+			Message: fmt.Sprintf("conteúdo do arquivo não corresponde a nenhum tipo permitido (detectado: %s)", sniffedType),
+		}
+	}
+
+	ext := filepath.Ext(filename)
+	if ext == "" || !isAllowedExt(ext, h.cfg.AllowedExt) {
+		return nil, &UploadError{Code: "INVALID_EXTENSION", Message: fmt.Sprintf("extensão não permitida: %s", ext)}
+	}
+
+	result, err := finalizeContent(ctx, content, sniffedType, ext, h.cfg, h.backend)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := h.db.ExecContext(ctx, `DELETE FROM tus_uploads WHERE id = ?`, id); err != nil {
+		return nil, fmt.Errorf("failed to clean up staging row: %w", err)
+	}
+
+	return result, nil
+}
+
+type tusUploadRow struct {
+	offset    int64
+	totalSize int64
+	filename  string
+}
+
+// loadUpload busca a linha de staging de id, expirando-a (removendo a linha
+// e reportando not-found) caso o prazo já tenha passado.
+func (h *TusHandler) loadUpload(ctx context.Context, id string) (*tusUploadRow, error) {
+	var up tusUploadRow
+	var expiresAt time.Time
+	err := h.db.QueryRowContext(ctx, `
+		SELECT offset, total_size, filename, expires_at FROM tus_uploads WHERE id = ?
+	`, id).Scan(&up.offset, &up.totalSize, &up.filename, &expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(expiresAt) {
+		_, _ = h.db.ExecContext(ctx, `DELETE FROM tus_uploads WHERE id = ?`, id)
+		return nil, sql.ErrNoRows
+	}
+	return &up, nil
+}
+
+// checkResumable exige o header Tus-Resumable em Create/Patch, como um
+// servidor tus real faria — sem ele não há como saber se o cliente fala a
+// mesma versão do protocolo.
+func (h *TusHandler) checkResumable(w http.ResponseWriter, r *http.Request, ctx context.Context, event *logging.Event) bool {
+	if r.Header.Get("Tus-Resumable") != tusVersion {
+		w.Header().Set("Tus-Resumable", tusVersion)
+		h.fail(w, ctx, event, http.StatusBadRequest, "missing or unsupported Tus-Resumable header")
+		return false
+	}
+	return true
+}
+
+func (h *TusHandler) fail(w http.ResponseWriter, ctx context.Context, event *logging.Event, status int, message string) {
+	event.Add(slog.String("outcome", "error"), slog.String("error", message), slog.Int("status", status))
+	logging.Get().Log(ctx, slog.LevelWarn, "tus request rejected", event.Attrs()...)
+	http.Error(w, message, status)
+}
+
+// parseUploadMetadata decodifica o header Upload-Metadata do tus: pares
+// "chave valor-base64" separados por vírgula (ver
+// https://tus.io/protocols/resumable-upload#upload-metadata). Chaves sem
+// valor (ex. flags) são ignoradas, já que nenhum chamador atual usa esse
+// formato.
+func parseUploadMetadata(header string) map[string]string {
+	result := make(map[string]string)
+	if header == "" {
+		return result
+	}
+	for _, pair := range strings.Split(header, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), " ", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			continue
+		}
+		result[parts[0]] = string(decoded)
+	}
+	return result
+}
+
+// writeJSON serializa v como o corpo da resposta com o Content-Type correto.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
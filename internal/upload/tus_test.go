@@ -0,0 +1,167 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTusTestHandler(t *testing.T, cfg Config) *TusHandler {
+	t.Helper()
+	dbConn, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	t.Cleanup(func() { dbConn.Close() })
+
+	backend := newTestBackend(t)
+	h := NewTusHandler(dbConn, backend, cfg, time.Hour)
+	if err := h.EnsureTable(context.Background()); err != nil {
+		t.Fatalf("EnsureTable: %v", err)
+	}
+	return h
+}
+
+func tusMux(h *TusHandler) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /uploads", h.Create)
+	mux.HandleFunc("HEAD /uploads/{id}", h.Head)
+	mux.HandleFunc("PATCH /uploads/{id}", h.Patch)
+	mux.HandleFunc("DELETE /uploads/{id}", h.Delete)
+	return mux
+}
+
+func TestTusHandler_CreateRequiresResumableHeader(t *testing.T) {
+	h := newTusTestHandler(t, AvatarConfig)
+	mux := tusMux(h)
+
+	req := httptest.NewRequest("POST", "/uploads", nil)
+	req.Header.Set("Upload-Length", "10")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+}
+
+func TestTusHandler_CreateRejectsOversizedUpload(t *testing.T) {
+	h := newTusTestHandler(t, AvatarConfig)
+	mux := tusMux(h)
+
+	req := httptest.NewRequest("POST", "/uploads", nil)
+	req.Header.Set("Tus-Resumable", tusVersion)
+	req.Header.Set("Upload-Length", "999999999")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", rec.Code)
+	}
+}
+
+func TestTusHandler_FullUploadLifecycle(t *testing.T) {
+	h := newTusTestHandler(t, AvatarConfig)
+	mux := tusMux(h)
+	png := newTestPNG(t, 20, 10)
+
+	createReq := httptest.NewRequest("POST", "/uploads", nil)
+	createReq.Header.Set("Tus-Resumable", tusVersion)
+	createReq.Header.Set("Upload-Length", strconv.Itoa(len(png)))
+	createReq.Header.Set("Upload-Metadata", "filename "+base64.StdEncoding.EncodeToString([]byte("photo.png")))
+	createRec := httptest.NewRecorder()
+	mux.ServeHTTP(createRec, createReq)
+
+	if createRec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", createRec.Code, createRec.Body.String())
+	}
+	location := createRec.Header().Get("Location")
+	if location == "" {
+		t.Fatal("expected Location header")
+	}
+
+	headReq := httptest.NewRequest("HEAD", location, nil)
+	headReq.SetPathValue("id", location[len("/uploads/"):])
+	headRec := httptest.NewRecorder()
+	mux.ServeHTTP(headRec, headReq)
+	if headRec.Header().Get("Upload-Offset") != "0" {
+		t.Fatalf("expected Upload-Offset 0, got %s", headRec.Header().Get("Upload-Offset"))
+	}
+
+	patchReq := httptest.NewRequest("PATCH", location, bytes.NewReader(png))
+	patchReq.Header.Set("Tus-Resumable", tusVersion)
+	patchReq.Header.Set("Content-Type", "application/offset+octet-stream")
+	patchReq.Header.Set("Upload-Offset", "0")
+	patchRec := httptest.NewRecorder()
+	mux.ServeHTTP(patchRec, patchReq)
+
+	if patchRec.Code != http.StatusOK {
+		t.Fatalf("expected 200 on completion, got %d: %s", patchRec.Code, patchRec.Body.String())
+	}
+	if patchRec.Header().Get("Upload-Offset") != strconv.Itoa(len(png)) {
+		t.Fatalf("expected final Upload-Offset %d, got %s", len(png), patchRec.Header().Get("Upload-Offset"))
+	}
+}
+
+func TestTusHandler_PatchRejectsOffsetMismatch(t *testing.T) {
+	h := newTusTestHandler(t, AvatarConfig)
+	mux := tusMux(h)
+	png := newTestPNG(t, 20, 10)
+
+	createReq := httptest.NewRequest("POST", "/uploads", nil)
+	createReq.Header.Set("Tus-Resumable", tusVersion)
+	createReq.Header.Set("Upload-Length", strconv.Itoa(len(png)))
+	createReq.Header.Set("Upload-Metadata", "filename "+base64.StdEncoding.EncodeToString([]byte("photo.png")))
+	createRec := httptest.NewRecorder()
+	mux.ServeHTTP(createRec, createReq)
+	location := createRec.Header().Get("Location")
+
+	patchReq := httptest.NewRequest("PATCH", location, bytes.NewReader(png))
+	patchReq.Header.Set("Tus-Resumable", tusVersion)
+	patchReq.Header.Set("Content-Type", "application/offset+octet-stream")
+	patchReq.Header.Set("Upload-Offset", "5")
+	patchRec := httptest.NewRecorder()
+	mux.ServeHTTP(patchRec, patchReq)
+
+	if patchRec.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", patchRec.Code)
+	}
+}
+
+func TestTusHandler_Delete(t *testing.T) {
+	h := newTusTestHandler(t, AvatarConfig)
+	mux := tusMux(h)
+
+	createReq := httptest.NewRequest("POST", "/uploads", nil)
+	createReq.Header.Set("Tus-Resumable", tusVersion)
+	createReq.Header.Set("Upload-Length", "10")
+	createRec := httptest.NewRecorder()
+	mux.ServeHTTP(createRec, createReq)
+	location := createRec.Header().Get("Location")
+	id := location[len("/uploads/"):]
+
+	deleteReq := httptest.NewRequest("DELETE", location, nil)
+	deleteReq.SetPathValue("id", id)
+	deleteRec := httptest.NewRecorder()
+	mux.ServeHTTP(deleteRec, deleteReq)
+
+	if deleteRec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", deleteRec.Code)
+	}
+
+	headReq := httptest.NewRequest("HEAD", location, nil)
+	headReq.SetPathValue("id", id)
+	headRec := httptest.NewRecorder()
+	mux.ServeHTTP(headRec, headReq)
+	if headRec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 after delete, got %d", headRec.Code)
+	}
+}
@@ -1,22 +1,125 @@
 package upload
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
 	"io"
 	"net/http"
-	"os"
 	"path/filepath"
 	"slices"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/PauloHFS/goth/internal/filestore"
 )
 
+// Config descreve os limites aceitos para um campo de upload. Directory é
+// o prefixo de chave usado no filestore.Backend (ex. "avatars"), não mais
+// um diretório de disco local — qual backend de fato persiste o arquivo é
+// uma escolha única e global (STORAGE_BACKEND, ver internal/filestore e
+// cmd.RunServer), a mesma para avatares, AI e webhooks, em vez de um campo
+// por Config aqui.
 type Config struct {
 	AllowedMIME []string
 	AllowedExt  []string
 	MaxSize     int64
 	Directory   string
+	Process     ProcessOptions
+	// RateLimiter, se não nil, limita quantos uploads um (tenant, usuário)
+	// pode fazer nesta categoria — ver NewTokenBucketLimiter e
+	// WithRateLimiter. Configs existentes deixam isso nil e continuam sem
+	// limite, como antes.
+	RateLimiter RateLimiter
+	// AVScanner, se não nil, varre o conteúdo em busca de malware antes de
+	// SaveFile finalizar o upload — ver ClamAVScanner e WithAVScanner.
+	AVScanner AVScanner
+}
+
+// Option configura aspectos de Config que não cabem bem como zero-values
+// simples — RateLimiter e AVScanner são interfaces, então um Config{}
+// literal já funciona sem eles (nil desliga os dois); Option só existe para
+// compor os dois de forma legível nos poucos call sites que querem
+// ativá-los, sem obrigar todo mundo a preencher esses dois campos.
+type Option func(*Config)
+
+// WithRateLimiter devolve um Option que define o RateLimiter de um Config.
+func WithRateLimiter(limiter RateLimiter) Option {
+	return func(c *Config) { c.RateLimiter = limiter }
+}
+
+// WithAVScanner devolve um Option que define o AVScanner de um Config.
+func WithAVScanner(scanner AVScanner) Option {
+	return func(c *Config) { c.AVScanner = scanner }
+}
+
+// NewConfig aplica opts sobre uma cópia de base (tipicamente AvatarConfig,
+// ImageConfig ou DocumentConfig), deixando base intocado.
+func NewConfig(base Config, opts ...Option) Config {
+	cfg := base
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// ProcessOptions controla o pipeline de reprocessamento de imagens aplicado
+// por SaveFile depois da validação de tipo. É zero-value por padrão (sem
+// reencode, sem thumbnail), então Configs existentes continuam salvando o
+// arquivo como recebido.
+type ProcessOptions struct {
+	// MaxWidth/MaxHeight, se diferentes de zero, fazem SaveFile reduzir a
+	// imagem (mantendo proporção) antes de gravar, quando Reencode é true.
+	MaxWidth  int
+	MaxHeight int
+	// Reencode decodifica a imagem e a regrava num formato canônico (o
+	// mesmo detectado por http.DetectContentType), o que também descarta
+	// metadados não suportados pelos decoders/encoders da stdlib (ver
+	// StripMetadata).
+	Reencode bool
+	// StripMetadata remove metadados como EXIF antes de gravar. Como
+	// image/jpeg, image/png e image/gif da stdlib já não preservam esses
+	// metadados ao decodificar e reencodar, isso hoje é só um efeito
+	// colateral automático do Reencode — não há parsing de EXIF separado.
+	StripMetadata bool
+	// GenerateThumbnail, quando true, gera uma segunda variante reduzida
+	// (thumbnailMaxDimension) e a grava sob Result.ThumbnailKey.
+	GenerateThumbnail bool
+}
+
+const thumbnailMaxDimension = 256
+
+// maxDecodeDimension limita a largura/altura declaradas no header da imagem
+// (lidas via image.DecodeConfig, antes do decode completo) — um arquivo
+// pequeno e bem comprimido pode declarar dimensões absurdas e estourar a
+// memória ao ser decodificado por inteiro, mesmo já tendo passado pelo
+// limite de bytes comprimidos (cfg.MaxSize). Bem acima de qualquer foto
+// real, só para barrar esse abuso.
+const maxDecodeDimension = 16384
+
+// reencodableMIME define os tipos de imagem que SaveFile sabe decodificar e
+// regravar usando a biblioteca padrão do Go. image/webp fica de fora: a
+// stdlib não tem encoder (nem decoder) para webp, e este repositório não
+// depende de golang.org/x/image, então um upload webp com Reencode ativado
+// é gravado como recebido (sem decode/re-encode) em vez de falhar.
+var reencodableMIME = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// reencodeBufferPool reaproveitia os *bytes.Buffer usados para serializar a
+// imagem reencodada, evitando uma alocação nova por upload sob carga.
+var reencodeBufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
 }
 
 var (
@@ -42,12 +145,22 @@ var (
 	}
 )
 
+// Result descreve o arquivo gravado. Key é a chave sob a qual o backend
+// guardou o conteúdo (ver filestore.Backend.Put); URL vem do próprio
+// backend — presigned para s3, "/storage/..." para local, conforme
+// configurado.
 type Result struct {
-	Path     string
+	Key      string
 	Filename string
 	Size     int64
 	MIMEType string
 	URL      string
+	// ContentHash é o SHA-256 (hex) do conteúdo efetivamente gravado, para
+	// que chamadores dedupliquem uploads repetidos sem reler o backend.
+	ContentHash string
+	// ThumbnailKey é a chave da variante reduzida gerada quando
+	// Config.Process.GenerateThumbnail está ativo; vazio caso contrário.
+	ThumbnailKey string
 }
 
 type UploadError struct {
@@ -64,13 +177,30 @@ func IsUploadError(err error) bool {
 	return ok
 }
 
-func SaveFile(r *http.Request, fieldName string, cfg Config) (*Result, error) {
+// SaveFile valida o arquivo do campo fieldName de r contra cfg e o persiste
+// via backend — qualquer filestore.Backend configurado (local, webdav ou
+// s3, ver filestore.New), o mesmo já usado para avatares e AI em outras
+// partes do app. Diferente da versão anterior, não escreve mais direto no
+// disco via os.Create, o que desbloqueia deployments com múltiplos nós sem
+// disco compartilhado.
+//
+// O Content-Type e a extensão declarados pelo cliente são fáceis de
+// falsificar, então SaveFile nunca confia neles sozinhos: os primeiros 512
+// bytes são lidos e checados contra http.DetectContentType antes de
+// qualquer gravação (mesma técnica de handleAvatarUpload em
+// internal/web/handlers.go). Se cfg.Process pedir reencode, a imagem ainda
+// é decodificada e regravada num formato canônico — ver ProcessOptions.
+func SaveFile(ctx context.Context, r *http.Request, fieldName string, cfg Config, backend filestore.Backend) (*Result, error) {
 	file, header, err := r.FormFile(fieldName)
 	if err != nil {
 		return nil, &UploadError{Code: "NO_FILE", Message: "Nenhum arquivo enviado"}
 	}
 	defer file.Close()
 
+	if cfg.RateLimiter != nil && !cfg.RateLimiter.Allow(ctx, rateLimitKey(ctx)) {
+		return nil, &UploadError{Code: "RATE_LIMITED", Message: "Limite de uploads excedido, tente novamente mais tarde"}
+	}
+
 	if header.Size > cfg.MaxSize {
 		return nil, &UploadError{
 			Code:    "FILE_TOO_LARGE",
@@ -78,11 +208,11 @@ func SaveFile(r *http.Request, fieldName string, cfg Config) (*Result, error) {
 		}
 	}
 
-	contentType := header.Header.Get("Content-Type")
-	if !isAllowedMIME(contentType, cfg.AllowedMIME) {
+	declaredType := header.Header.Get("Content-Type")
+	if !isAllowedMIME(declaredType, cfg.AllowedMIME) {
 		return nil, &UploadError{
 			Code:    "INVALID_TYPE",
-			Message: fmt.Sprintf("Tipo de arquivo não permitido: %s", contentType),
+			Message: fmt.Sprintf("Tipo de arquivo não permitido: %s", declaredType),
 		}
 	}
 
@@ -94,43 +224,214 @@ func SaveFile(r *http.Request, fieldName string, cfg Config) (*Result, error) {
 		}
 	}
 
-	if err := os.MkdirAll(cfg.Directory, 0755); err != nil {
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, &UploadError{Code: "READ_ERROR", Message: "Falha ao ler arquivo enviado"}
+	}
+	sniff = sniff[:n]
+
+	sniffedType := sniffContentType(sniff)
+	if !isAllowedMIME(sniffedType, cfg.AllowedMIME) {
 		return nil, &UploadError{
-			Code:    "DIRECTORY_ERROR",
-			Message: "Falha ao criar diretório de upload",
+			Code:    "TYPE_MISMATCH",
+			Message: fmt.Sprintf("Conteúdo do arquivo não corresponde ao tipo declarado (detectado: %s)", sniffedType),
 		}
 	}
 
-	filename := generateFilename(ext)
-	dstPath := filepath.Join(cfg.Directory, filename)
-
-	dst, err := os.Create(dstPath)
+	body := io.MultiReader(bytes.NewReader(sniff), file)
+	content, err := io.ReadAll(io.LimitReader(body, cfg.MaxSize+1))
 	if err != nil {
+		return nil, &UploadError{Code: "READ_ERROR", Message: "Falha ao ler arquivo enviado"}
+	}
+	if int64(len(content)) > cfg.MaxSize {
 		return nil, &UploadError{
-			Code:    "CREATE_ERROR",
-			Message: "Falha ao criar arquivo",
+			Code:    "FILE_TOO_LARGE",
+			Message: fmt.Sprintf("Arquivo excede o limite de %dMB", cfg.MaxSize/1024/1024),
+		}
+	}
+
+	return finalizeContent(ctx, content, sniffedType, ext, cfg, backend)
+}
+
+// finalizeContent varre content com cfg.AVScanner (se configurado), aplica
+// o reencode/thumbnail opcional de cfg.Process e grava o resultado (já
+// validado contra cfg por um chamador — SaveFile ou TusHandler.complete)
+// via backend, devolvendo o Result final. Extraído de SaveFile para que o
+// fluxo de conclusão de um upload resumível (ver TusHandler) passe pelo
+// mesmo pipeline de scan/reencode/hash/thumbnail, em vez de duplicá-lo.
+//
+// A varredura roda aqui, antes de qualquer backend.Put — como SaveFile e
+// TusHandler.complete só chamam finalizeContent depois de montar o
+// conteúdo inteiro em memória, nunca há um arquivo parcial no backend para
+// limpar num resultado positivo, diferente de um scanner acoplado direto a
+// um stream já sendo gravado em disco.
+func finalizeContent(ctx context.Context, content []byte, sniffedType, ext string, cfg Config, backend filestore.Backend) (*Result, error) {
+	if cfg.AVScanner != nil {
+		infected, signature, err := cfg.AVScanner.Scan(ctx, content)
+		if err != nil {
+			return nil, &UploadError{Code: "SCAN_ERROR", Message: "Falha ao escanear arquivo em busca de malware"}
+		}
+		if infected {
+			return nil, &UploadError{Code: "MALWARE_DETECTED", Message: fmt.Sprintf("Arquivo rejeitado: %s", signature)}
+		}
+	}
+
+	contentType := sniffedType
+	if cfg.Process.Reencode && reencodableMIME[sniffedType] {
+		reencoded, err := reencodeImage(content, sniffedType, cfg.Process.MaxWidth, cfg.Process.MaxHeight)
+		if err != nil {
+			return nil, &UploadError{Code: "PROCESS_ERROR", Message: "Falha ao reprocessar imagem"}
 		}
+		content = reencoded
 	}
-	defer dst.Close()
 
-	written, err := io.Copy(dst, file)
+	hash := sha256.Sum256(content)
+	filename := generateFilename(ext)
+	key := cfg.Directory + "/" + filename
+
+	counter := &countingReader{r: bytes.NewReader(content)}
+	url, err := backend.Put(ctx, key, counter, contentType)
 	if err != nil {
-		os.Remove(dstPath)
 		return nil, &UploadError{
 			Code:    "WRITE_ERROR",
 			Message: "Falha ao salvar arquivo",
 		}
 	}
 
-	url := fmt.Sprintf("/storage/%s/%s", cfg.Directory, filename)
+	result := &Result{
+		Key:         key,
+		Filename:    filename,
+		Size:        counter.n,
+		MIMEType:    contentType,
+		URL:         url,
+		ContentHash: hex.EncodeToString(hash[:]),
+	}
+
+	if cfg.Process.GenerateThumbnail && reencodableMIME[sniffedType] {
+		thumbnail, err := reencodeImage(content, sniffedType, thumbnailMaxDimension, thumbnailMaxDimension)
+		if err != nil {
+			return nil, &UploadError{Code: "PROCESS_ERROR", Message: "Falha ao gerar thumbnail"}
+		}
+
+		thumbnailKey := cfg.Directory + "/thumb_" + filename
+		if _, err := backend.Put(ctx, thumbnailKey, bytes.NewReader(thumbnail), contentType); err != nil {
+			return nil, &UploadError{Code: "WRITE_ERROR", Message: "Falha ao salvar thumbnail"}
+		}
+		result.ThumbnailKey = thumbnailKey
+	}
+
+	return result, nil
+}
+
+// sniffContentType roda http.DetectContentType sobre os magic bytes de sniff
+// e descarta eventuais parâmetros (ex. "; charset=utf-8"), para comparar
+// direto contra cfg.AllowedMIME.
+func sniffContentType(sniff []byte) string {
+	contentType := http.DetectContentType(sniff)
+	if idx := bytes.IndexByte([]byte(contentType), ';'); idx != -1 {
+		contentType = contentType[:idx]
+	}
+	return contentType
+}
+
+// reencodeImage decodifica content (um dos formatos em reencodableMIME),
+// opcionalmente reduz para caber em maxWidth x maxHeight mantendo a
+// proporção, e regrava no mesmo formato detectado. O redimensionamento usa
+// amostragem do vizinho mais próximo: suficiente para avatares e
+// thumbnails, sem puxar uma dependência de reescalonamento de terceiros.
+func reencodeImage(content []byte, contentType string, maxWidth, maxHeight int) ([]byte, error) {
+	decodedConfig, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image config: %w", err)
+	}
+	if decodedConfig.Width > maxDecodeDimension || decodedConfig.Height > maxDecodeDimension {
+		return nil, fmt.Errorf("image dimensions %dx%d exceed the %dx%d limit", decodedConfig.Width, decodedConfig.Height, maxDecodeDimension, maxDecodeDimension)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	if maxWidth > 0 || maxHeight > 0 {
+		img = resizeToFit(img, maxWidth, maxHeight)
+	}
+
+	buf := reencodeBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer reencodeBufferPool.Put(buf)
+
+	switch contentType {
+	case "image/jpeg":
+		err = jpeg.Encode(buf, img, &jpeg.Options{Quality: 85})
+	case "image/png":
+		err = png.Encode(buf, img)
+	case "image/gif":
+		err = gif.Encode(buf, img, nil)
+	default:
+		return nil, fmt.Errorf("unsupported image type for reencode: %s", contentType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	out := make([]byte, buf.Len())
+	copy(out, buf.Bytes())
+	return out, nil
+}
+
+// resizeToFit reduz img por amostragem do vizinho mais próximo até caber em
+// maxWidth x maxHeight (cada um ignorado se zero), mantendo a proporção.
+// Nunca aumenta a imagem.
+func resizeToFit(img image.Image, maxWidth, maxHeight int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return img
+	}
+
+	scale := 1.0
+	if maxWidth > 0 && width > maxWidth {
+		if s := float64(maxWidth) / float64(width); s < scale {
+			scale = s
+		}
+	}
+	if maxHeight > 0 && height > maxHeight {
+		if s := float64(maxHeight) / float64(height); s < scale {
+			scale = s
+		}
+	}
+	if scale >= 1.0 {
+		return img
+	}
+
+	newWidth := max(1, int(float64(width)*scale))
+	newHeight := max(1, int(float64(height)*scale))
+
+	dst := image.NewRGBA(image.Rect(0, 0, newWidth, newHeight))
+	for y := 0; y < newHeight; y++ {
+		srcY := bounds.Min.Y + y*height/newHeight
+		for x := 0; x < newWidth; x++ {
+			srcX := bounds.Min.X + x*width/newWidth
+			dst.Set(x, y, img.At(srcX, srcY))
+		}
+	}
+	return dst
+}
+
+// countingReader embrulha um io.Reader contando os bytes lidos, para que
+// Result.Size reflita o que de fato passou para o backend em vez de
+// confiar em header.Size, controlado pelo cliente.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
 
-	return &Result{
-		Path:     dstPath,
-		Filename: filename,
-		Size:     written,
-		MIMEType: contentType,
-		URL:      url,
-	}, nil
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
 }
 
 func isAllowedMIME(mime string, allowed []string) bool {
@@ -147,11 +448,13 @@ func generateFilename(ext string) string {
 	return fmt.Sprintf("%d_%s%s", timestamp, unique, ext)
 }
 
-func DeleteFile(path string) error {
-	return os.Remove(path)
+// DeleteFile remove key de backend.
+func DeleteFile(ctx context.Context, backend filestore.Backend, key string) error {
+	return backend.Delete(ctx, key)
 }
 
-func FileExists(path string) bool {
-	_, err := os.Stat(path)
+// FileExists reporta se key existe em backend.
+func FileExists(ctx context.Context, backend filestore.Backend, key string) bool {
+	_, err := backend.Stat(ctx, key)
 	return err == nil
 }
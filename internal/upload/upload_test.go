@@ -2,16 +2,99 @@ package upload
 
 import (
 	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
 	"mime/multipart"
 	"net/http/httptest"
-	"os"
-	"path/filepath"
+	"net/textproto"
 	"testing"
+
+	"github.com/PauloHFS/goth/internal/filestore"
 )
 
+// newTestPNG gera um PNG válido de w x h para exercitar o pipeline de
+// sniffing/reencode sem depender de um fixture binário em disco.
+func newTestPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// newFakePNGHeader monta só a assinatura PNG + um chunk IHDR declarando
+// width x height, sem IDAT/IEND — suficiente para image.DecodeConfig (que
+// para assim que lê o IHDR), mas não para image.Decode. Simula o ataque que
+// reencodeImage precisa barrar: um arquivo minúsculo e bem comprimido
+// declarando dimensões gigantescas, sem de fato carregar os pixels em
+// memória para montar o fixture de teste.
+func newFakePNGHeader(t *testing.T, width, height uint32) []byte {
+	t.Helper()
+
+	data := make([]byte, 13)
+	binary.BigEndian.PutUint32(data[0:4], width)
+	binary.BigEndian.PutUint32(data[4:8], height)
+	data[8] = 8 // bit depth
+	data[9] = 6 // color type: RGBA
+	data[10] = 0
+	data[11] = 0
+	data[12] = 0
+
+	chunkType := []byte("IHDR")
+	crc := crc32.NewIEEE()
+	crc.Write(chunkType)
+	crc.Write(data)
+
+	buf := &bytes.Buffer{}
+	buf.Write([]byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A})
+	binary.Write(buf, binary.BigEndian, uint32(len(data)))
+	buf.Write(chunkType)
+	buf.Write(data)
+	binary.Write(buf, binary.BigEndian, crc.Sum32())
+	return buf.Bytes()
+}
+
+func newTestBackend(t *testing.T) filestore.Backend {
+	t.Helper()
+	backend, err := filestore.NewLocalBackend(t.TempDir(), "/storage")
+	if err != nil {
+		t.Fatalf("NewLocalBackend: %v", err)
+	}
+	return backend
+}
+
+// createFormFile faz o mesmo que multipart.Writer.CreateFormFile, mas declara
+// contentType no Content-Type da parte em vez do application/octet-stream fixo
+// de CreateFormFile, que SaveFile recusaria como INVALID_TYPE antes de ler o
+// conteúdo.
+func createFormFile(t *testing.T, w *multipart.Writer, fieldName, filename, contentType string) io.Writer {
+	t.Helper()
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, fieldName, filename))
+	h.Set("Content-Type", contentType)
+	part, err := w.CreatePart(h)
+	if err != nil {
+		t.Fatalf("CreatePart: %v", err)
+	}
+	return part
+}
+
 func TestSaveFile_ValidImage(t *testing.T) {
 	cfg := AvatarConfig
-	cfg.Directory = t.TempDir()
+	backend := newTestBackend(t)
 
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -21,7 +104,7 @@ func TestSaveFile_ValidImage(t *testing.T) {
 	req := httptest.NewRequest("POST", "/", body)
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
-	_, err := SaveFile(req, "avatar", cfg)
+	_, err := SaveFile(context.Background(), req, "avatar", cfg, backend)
 	if err == nil {
 		t.Fatal("expected error for no file")
 	}
@@ -39,7 +122,7 @@ func TestSaveFile_ValidImage(t *testing.T) {
 func TestSaveFile_FileTooLarge(t *testing.T) {
 	cfg := AvatarConfig
 	cfg.MaxSize = 10
-	cfg.Directory = t.TempDir()
+	backend := newTestBackend(t)
 
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -50,7 +133,7 @@ func TestSaveFile_FileTooLarge(t *testing.T) {
 	req := httptest.NewRequest("POST", "/", body)
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
-	_, err := SaveFile(req, "avatar", cfg)
+	_, err := SaveFile(context.Background(), req, "avatar", cfg, backend)
 	if err == nil {
 		t.Fatal("expected error for file too large")
 	}
@@ -67,8 +150,8 @@ func TestSaveFile_FileTooLarge(t *testing.T) {
 
 func TestSaveFile_InvalidExtension(t *testing.T) {
 	cfg := AvatarConfig
-	cfg.Directory = t.TempDir()
 	cfg.AllowedMIME = append(cfg.AllowedMIME, "application/octet-stream")
+	backend := newTestBackend(t)
 
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -79,7 +162,7 @@ func TestSaveFile_InvalidExtension(t *testing.T) {
 	req := httptest.NewRequest("POST", "/", body)
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
-	_, err := SaveFile(req, "avatar", cfg)
+	_, err := SaveFile(context.Background(), req, "avatar", cfg, backend)
 	if err == nil {
 		t.Fatal("expected error for invalid extension")
 	}
@@ -96,7 +179,7 @@ func TestSaveFile_InvalidExtension(t *testing.T) {
 
 func TestSaveFile_NoFile(t *testing.T) {
 	cfg := AvatarConfig
-	cfg.Directory = t.TempDir()
+	backend := newTestBackend(t)
 
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -105,7 +188,7 @@ func TestSaveFile_NoFile(t *testing.T) {
 	req := httptest.NewRequest("POST", "/", body)
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
-	_, err := SaveFile(req, "avatar", cfg)
+	_, err := SaveFile(context.Background(), req, "avatar", cfg, backend)
 	if err == nil {
 		t.Fatal("expected error when no file")
 	}
@@ -120,6 +203,215 @@ func TestSaveFile_NoFile(t *testing.T) {
 	}
 }
 
+func TestSaveFile_StoresViaBackend(t *testing.T) {
+	cfg := AvatarConfig
+	backend := newTestBackend(t)
+	png := newTestPNG(t, 20, 10)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part := createFormFile(t, writer, "avatar", "photo.png", "image/png")
+	_, _ = part.Write(png)
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	result, err := SaveFile(context.Background(), req, "avatar", cfg, backend)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Size != int64(len(png)) {
+		t.Errorf("expected size %d, got %d", len(png), result.Size)
+	}
+
+	if result.ContentHash == "" {
+		t.Error("expected ContentHash to be set")
+	}
+
+	if !FileExists(context.Background(), backend, result.Key) {
+		t.Error("expected stored file to exist in backend")
+	}
+}
+
+func TestSaveFile_TypeMismatch(t *testing.T) {
+	cfg := AvatarConfig
+	backend := newTestBackend(t)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part := createFormFile(t, writer, "avatar", "fake.png", "image/png")
+	_, _ = part.Write([]byte("this is not actually a png"))
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	_, err := SaveFile(context.Background(), req, "avatar", cfg, backend)
+	if err == nil {
+		t.Fatal("expected error for spoofed content type")
+	}
+
+	uploadErr, ok := err.(*UploadError)
+	if !ok {
+		t.Fatal("expected UploadError")
+	}
+
+	if uploadErr.Code != "TYPE_MISMATCH" {
+		t.Errorf("expected TYPE_MISMATCH, got %s", uploadErr.Code)
+	}
+}
+
+func TestSaveFile_ReencodeResizes(t *testing.T) {
+	cfg := AvatarConfig
+	cfg.Process = ProcessOptions{Reencode: true, MaxWidth: 8, MaxHeight: 8}
+	backend := newTestBackend(t)
+	png := newTestPNG(t, 20, 10)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part := createFormFile(t, writer, "avatar", "photo.png", "image/png")
+	_, _ = part.Write(png)
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	result, err := SaveFile(context.Background(), req, "avatar", cfg, backend)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Size == int64(len(png)) {
+		t.Error("expected reencoded file to differ in size from the original")
+	}
+}
+
+func TestReencodeImage_RejectsOversizedDeclaredDimensions(t *testing.T) {
+	bomb := newFakePNGHeader(t, maxDecodeDimension+1, maxDecodeDimension+1)
+
+	_, err := reencodeImage(bomb, "image/png", 8, 8)
+	if err == nil {
+		t.Fatal("expected reencodeImage to reject a declared width/height above maxDecodeDimension")
+	}
+}
+
+func TestSaveFile_GeneratesThumbnail(t *testing.T) {
+	cfg := AvatarConfig
+	cfg.Process = ProcessOptions{GenerateThumbnail: true}
+	backend := newTestBackend(t)
+	png := newTestPNG(t, 20, 10)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part := createFormFile(t, writer, "avatar", "photo.png", "image/png")
+	_, _ = part.Write(png)
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	result, err := SaveFile(context.Background(), req, "avatar", cfg, backend)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.ThumbnailKey == "" {
+		t.Fatal("expected ThumbnailKey to be set")
+	}
+
+	if !FileExists(context.Background(), backend, result.ThumbnailKey) {
+		t.Error("expected thumbnail to exist in backend")
+	}
+}
+
+// alwaysDenyLimiter nega toda chamada a Allow, para exercitar o caminho
+// RATE_LIMITED de SaveFile sem depender de tempo real.
+type alwaysDenyLimiter struct{}
+
+func (alwaysDenyLimiter) Allow(context.Context, string) bool { return false }
+
+// fakeScanner devolve infected para todo Scan, para exercitar o caminho
+// MALWARE_DETECTED de SaveFile sem subir um clamd de verdade.
+type fakeScanner struct{ infected bool }
+
+func (s fakeScanner) Scan(context.Context, []byte) (bool, string, error) {
+	if s.infected {
+		return true, "Test.Signature", nil
+	}
+	return false, "", nil
+}
+
+func TestSaveFile_RateLimited(t *testing.T) {
+	cfg := AvatarConfig
+	cfg.RateLimiter = alwaysDenyLimiter{}
+	backend := newTestBackend(t)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("avatar", "photo.png")
+	_, _ = part.Write(newTestPNG(t, 10, 10))
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	_, err := SaveFile(context.Background(), req, "avatar", cfg, backend)
+	if err == nil {
+		t.Fatal("expected error when rate limited")
+	}
+
+	uploadErr, ok := err.(*UploadError)
+	if !ok {
+		t.Fatal("expected UploadError")
+	}
+	if uploadErr.Code != "RATE_LIMITED" {
+		t.Errorf("expected RATE_LIMITED, got %s", uploadErr.Code)
+	}
+}
+
+func TestSaveFile_MalwareDetected(t *testing.T) {
+	cfg := NewConfig(AvatarConfig, WithAVScanner(fakeScanner{infected: true}))
+	backend := newTestBackend(t)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part := createFormFile(t, writer, "avatar", "photo.png", "image/png")
+	_, _ = part.Write(newTestPNG(t, 10, 10))
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	_, err := SaveFile(context.Background(), req, "avatar", cfg, backend)
+	if err == nil {
+		t.Fatal("expected error when malware detected")
+	}
+
+	uploadErr, ok := err.(*UploadError)
+	if !ok {
+		t.Fatal("expected UploadError")
+	}
+	if uploadErr.Code != "MALWARE_DETECTED" {
+		t.Errorf("expected MALWARE_DETECTED, got %s", uploadErr.Code)
+	}
+
+	if FileExists(context.Background(), backend, cfg.Directory) {
+		t.Error("expected no file written to backend when malware detected")
+	}
+}
+
+func TestNewConfig_LeavesBaseUnchanged(t *testing.T) {
+	cfg := NewConfig(AvatarConfig, WithAVScanner(NoopScanner{}))
+	if AvatarConfig.AVScanner != nil {
+		t.Error("expected AvatarConfig to remain untouched by NewConfig")
+	}
+	if cfg.AVScanner == nil {
+		t.Error("expected returned Config to carry the AVScanner option")
+	}
+}
+
 func TestIsUploadError(t *testing.T) {
 	err := &UploadError{Code: "TEST", Message: "test"}
 	if !IsUploadError(err) {
@@ -130,39 +422,43 @@ func TestIsUploadError(t *testing.T) {
 		t.Error("expected false for nil")
 	}
 
-	if IsUploadError(os.ErrNotExist) {
+	if IsUploadError(filestore.ErrNotFound) {
 		t.Error("expected false for regular error")
 	}
 }
 
 func TestDeleteFile(t *testing.T) {
-	tmpDir := t.TempDir()
-	testFile := filepath.Join(tmpDir, "test.txt")
+	backend := newTestBackend(t)
+	ctx := context.Background()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part := createFormFile(t, writer, "avatar", "test.png", "image/png")
+	_, _ = part.Write(newTestPNG(t, 10, 10))
+	writer.Close()
 
-	if err := os.WriteFile(testFile, []byte("content"), 0644); err != nil {
-		t.Fatal(err)
+	req := httptest.NewRequest("POST", "/", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	result, err := SaveFile(ctx, req, "avatar", AvatarConfig, backend)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
 
-	if err := DeleteFile(testFile); err != nil {
+	if err := DeleteFile(ctx, backend, result.Key); err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
 
-	if FileExists(testFile) {
+	if FileExists(ctx, backend, result.Key) {
 		t.Error("expected file to be deleted")
 	}
 }
 
 func TestFileExists(t *testing.T) {
-	tmpFile := filepath.Join(t.TempDir(), "exists.txt")
-	if err := os.WriteFile(tmpFile, []byte("content"), 0644); err != nil {
-		t.Fatal(err)
-	}
-
-	if !FileExists(tmpFile) {
-		t.Error("expected file to exist")
-	}
+	backend := newTestBackend(t)
+	ctx := context.Background()
 
-	if FileExists("/nonexistent/file.txt") {
+	if FileExists(ctx, backend, "nonexistent/file.txt") {
 		t.Error("expected false for nonexistent file")
 	}
 }
@@ -3,6 +3,7 @@ package validator
 import (
 	"fmt"
 	"mime"
+	"net/http"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -115,6 +116,32 @@ func ValidateUpload(filename string, contentType string, maxSize int64) error {
 	return nil
 }
 
+// magicImageTypes são os tipos que http.DetectContentType pode identificar a
+// partir dos magic bytes de uma imagem suportada por ValidateUpload.
+var magicImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// ValidateMagicBytes confere os primeiros bytes de um arquivo (sniff, tipicamente
+// os primeiros 512 bytes lidos do upload) contra os magic bytes reais de uma
+// imagem suportada, independente do que o cliente declarou no Content-Type
+// ou na extensão do nome do arquivo — ambos fáceis de falsificar.
+func ValidateMagicBytes(sniff []byte) error {
+	contentType := http.DetectContentType(sniff)
+	if idx := strings.Index(contentType, ";"); idx != -1 {
+		contentType = contentType[:idx]
+	}
+
+	if !magicImageTypes[contentType] {
+		return fmt.Errorf("conteúdo do arquivo não corresponde a uma imagem válida (detectado: %s)", contentType)
+	}
+
+	return nil
+}
+
 func SanitizeFilename(filename string) string {
 	ext := strings.ToLower(filepath.Ext(filename))
 	name := strings.TrimSuffix(filepath.Base(filename), ext)
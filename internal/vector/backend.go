@@ -0,0 +1,23 @@
+package vector
+
+import "context"
+
+// Backend é o conjunto mínimo de operações de armazenamento que
+// Service.VectorStore (o backend sqlite-vec, via *Store) e qualquer backend
+// alternativo precisam expor — inspirado no split de storage traits do
+// Aerogramme (Garage/S3/memória), para permitir plugar outro backend sem
+// mexer em Service. Filtros de metadata (Filter), índices ANN em memória
+// (IndexSpec) e busca híbrida com FTS5 (HybridSearch) ficam de fora de
+// propósito: são recursos específicos do backend sqlite-vec e continuam só
+// disponíveis quando o Service é construído sobre um *Store (ver
+// Service.requireStore) — um Backend alternativo como MemoryBackend cobre
+// só o caminho básico de CRUD + k-NN.
+type Backend interface {
+	EnsureTable(ctx context.Context) error
+	Insert(ctx context.Context, embedding Embedding) (int64, error)
+	Search(ctx context.Context, contentType string, queryVector []float64, limit int, metric DistanceMetric) ([]SearchResult, error)
+	SearchGlobal(ctx context.Context, queryVector []float64, limit int, metric DistanceMetric) ([]SearchResult, error)
+	Delete(ctx context.Context, contentType string, contentID int64) error
+	Update(ctx context.Context, contentType string, contentID int64, newVector []float64) error
+	Count(ctx context.Context, contentType string) (int, error)
+}
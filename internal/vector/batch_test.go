@@ -0,0 +1,110 @@
+package vector
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// openTestStore abre um banco sqlite em memória e devolve um Store pronto
+// para uso — mesma receita de test/benchmarks/benchmark_test.go
+// (setupVectorService), adaptada para *testing.T em vez de *testing.B.
+func openTestStore(t *testing.T, dimension int) *Store {
+	t.Helper()
+
+	dbConn, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dbConn.Close() })
+
+	store := NewStore(dbConn, Config{
+		Enabled:            true,
+		EmbeddingDimension: dimension,
+		TableName:          "vectors_test",
+	})
+	if err := store.EnsureTable(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestStoreBatchPartialFailure(t *testing.T) {
+	const dimension = 4
+	store := openTestStore(t, dimension)
+	service := NewService(store)
+	ctx := context.Background()
+
+	embeddings := []Embedding{
+		{ContentType: "document", ContentID: 1, Vector: []float64{0, 1, 2, 3}},
+		// Dimensão errada (3 em vez de 4): a vec0 virtual table rejeita o
+		// INSERT, mas só este item deve falhar.
+		{ContentType: "document", ContentID: 2, Vector: []float64{0, 1, 2}},
+		{ContentType: "document", ContentID: 3, Vector: []float64{4, 5, 6, 7}},
+	}
+
+	ids, err := service.StoreBatch(ctx, embeddings)
+
+	var batchErr *BatchError
+	if err == nil || !errors.As(err, &batchErr) {
+		t.Fatalf("StoreBatch error = %v (%T), want *BatchError", err, err)
+	}
+	if batchErr.Total != len(embeddings) {
+		t.Errorf("BatchError.Total = %d, want %d", batchErr.Total, len(embeddings))
+	}
+	if len(batchErr.Failures) != 1 {
+		t.Fatalf("BatchError.Failures = %v, want exactly 1 failure", batchErr.Failures)
+	}
+	if _, failed := batchErr.Failures[1]; !failed {
+		t.Errorf("expected the item at index 1 (wrong dimension) to fail, failures = %v", batchErr.Failures)
+	}
+
+	// Os itens válidos ainda devem ter sido gravados com ids reais; o item
+	// que falhou fica com id zero.
+	if ids[0] == 0 {
+		t.Error("valid item at index 0 should have a non-zero id")
+	}
+	if ids[1] != 0 {
+		t.Errorf("failed item at index 1 should keep id 0, got %d", ids[1])
+	}
+	if ids[2] == 0 {
+		t.Error("valid item at index 2 should have a non-zero id")
+	}
+
+	stored, err := service.GetByContent(ctx, "document", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored == nil {
+		t.Error("successfully stored item should be retrievable by its content id")
+	}
+}
+
+func TestBatchUpsertPartialFailure(t *testing.T) {
+	const dimension = 4
+	store := openTestStore(t, dimension)
+	service := NewService(store)
+	ctx := context.Background()
+
+	embeddings := []Embedding{
+		{ContentType: "document", ContentID: 1, Vector: []float64{0, 1, 2, 3}},
+		{ContentType: "document", ContentID: 2, Vector: []float64{0, 1, 2}},
+	}
+
+	ids, err := service.BatchUpsert(ctx, embeddings)
+
+	var batchErr *BatchError
+	if err == nil || !errors.As(err, &batchErr) {
+		t.Fatalf("BatchUpsert error = %v, want *BatchError", err)
+	}
+	if len(batchErr.Failures) != 1 {
+		t.Fatalf("BatchError.Failures = %v, want exactly 1 failure", batchErr.Failures)
+	}
+	if ids[0] == 0 {
+		t.Error("valid item at index 0 should have a non-zero id")
+	}
+}
+
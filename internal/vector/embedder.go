@@ -2,6 +2,7 @@ package vector
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/PauloHFS/goth/internal/llm"
 )
@@ -9,12 +10,16 @@ import (
 type Embedder struct {
 	llmClient *llm.Client
 	model     string
+	service   *Service
 }
 
-func NewEmbedder(llmClient *llm.Client, model string) *Embedder {
+// NewEmbedder cria um Embedder. service é opcional: pode ser nil se só
+// Embed/EmbedBatch forem usados, mas é obrigatório para IndexText.
+func NewEmbedder(llmClient *llm.Client, model string, service *Service) *Embedder {
 	return &Embedder{
 		llmClient: llmClient,
 		model:     model,
+		service:   service,
 	}
 }
 
@@ -51,6 +56,27 @@ func (e *Embedder) EmbedBatch(ctx context.Context, texts []string) ([][]float64,
 	return embeddings, nil
 }
 
+// IndexText gera o embedding de text via o client LLM e já grava em uma
+// única chamada, associando-o a (contentType, contentID) com os metadados
+// informados. Requer que o Embedder tenha sido construído com um Service.
+func (e *Embedder) IndexText(ctx context.Context, contentType string, contentID int64, text string, metadata map[string]any) (int64, error) {
+	if e.service == nil {
+		return 0, fmt.Errorf("vector: Embedder sem Service configurado, não é possível indexar")
+	}
+
+	vec, err := e.Embed(ctx, text)
+	if err != nil {
+		return 0, fmt.Errorf("failed to embed text: %w", err)
+	}
+
+	return e.service.Upsert(ctx, Embedding{
+		ContentType: contentType,
+		ContentID:   contentID,
+		Vector:      vec,
+		Metadata:    metadata,
+	})
+}
+
 var ErrNoEmbedding = &EmbeddingError{Message: "no embedding returned"}
 
 type EmbeddingError struct {
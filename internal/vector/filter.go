@@ -0,0 +1,214 @@
+package vector
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Filter é uma expressão booleana sobre chaves de metadata, usada por
+// Service.Search/SearchGlobal para restringir a busca de vizinhos (ver
+// BenchmarkVector_WithMetadataFilter em test/benchmarks, que antes filtrava
+// os top-k em memória depois da busca — perdendo resultados relevantes que
+// não entravam no top-k não filtrado). compile traduz a expressão numa
+// cláusula SQL empurrada para dentro da query k-NN; matches reavalia a
+// mesma expressão em memória contra um map[string]any já decodificado, para
+// o caminho de busca via HNSWIndex (ver searchGlobalHNSW), que não tem como
+// executar SQL.
+type Filter interface {
+	compile(allowed map[string]bool) (clause string, args []any, err error)
+	matches(metadata map[string]any) bool
+}
+
+// metadataColumn devolve a expressão SQL para key: o nome da coluna gerada
+// direto, se key estiver em indexedMetadataColumns (usa o índice b-tree
+// criado por Store.ensureFilterColumns), ou json_extract(metadata, '$.key')
+// caso contrário. Em ambos os casos, key só é aceita se estiver em allowed.
+func metadataColumn(key string, allowed map[string]bool) (string, error) {
+	if !allowed[key] {
+		return "", fmt.Errorf("metadata key %q is not in Config.AllowedFilterKeys", key)
+	}
+	for _, indexed := range indexedMetadataColumns {
+		if indexed == key {
+			return key, nil
+		}
+	}
+	return fmt.Sprintf("json_extract(metadata, '$.%s')", key), nil
+}
+
+type eqFilter struct {
+	key   string
+	value any
+}
+
+// Eq exige que metadata[key] seja igual a value.
+func Eq(key string, value any) Filter {
+	return eqFilter{key: key, value: value}
+}
+
+func (f eqFilter) compile(allowed map[string]bool) (string, []any, error) {
+	col, err := metadataColumn(f.key, allowed)
+	if err != nil {
+		return "", nil, err
+	}
+	return col + " = ?", []any{f.value}, nil
+}
+
+func (f eqFilter) matches(metadata map[string]any) bool {
+	return fmt.Sprint(metadata[f.key]) == fmt.Sprint(f.value)
+}
+
+type inFilter struct {
+	key    string
+	values []any
+}
+
+// In exige que metadata[key] esteja entre values.
+func In(key string, values ...any) Filter {
+	return inFilter{key: key, values: values}
+}
+
+func (f inFilter) compile(allowed map[string]bool) (string, []any, error) {
+	col, err := metadataColumn(f.key, allowed)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(f.values) == 0 {
+		return "0", nil, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(f.values)), ",")
+	return fmt.Sprintf("%s IN (%s)", col, placeholders), f.values, nil
+}
+
+func (f inFilter) matches(metadata map[string]any) bool {
+	actual := fmt.Sprint(metadata[f.key])
+	for _, v := range f.values {
+		if fmt.Sprint(v) == actual {
+			return true
+		}
+	}
+	return false
+}
+
+type comparisonFilter struct {
+	key    string
+	value  float64
+	op     string
+	negate bool // não lido: compile e matches decidem só por op (Lt usa op "<" diretamente)
+}
+
+// Gt exige que metadata[key] (numérico) seja maior que value.
+func Gt(key string, value float64) Filter {
+	return comparisonFilter{key: key, value: value, op: ">"}
+}
+
+// Lt exige que metadata[key] (numérico) seja menor que value.
+func Lt(key string, value float64) Filter {
+	return comparisonFilter{key: key, value: value, op: "<"}
+}
+
+func (f comparisonFilter) compile(allowed map[string]bool) (string, []any, error) {
+	col, err := metadataColumn(f.key, allowed)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("CAST(%s AS REAL) %s ?", col, f.op), []any{f.value}, nil
+}
+
+func (f comparisonFilter) matches(metadata map[string]any) bool {
+	actual, ok := metadata[f.key].(float64)
+	if !ok {
+		return false
+	}
+	if f.op == ">" {
+		return actual > f.value
+	}
+	return actual < f.value
+}
+
+type existsFilter struct{ key string }
+
+// Exists exige que metadata contenha key, com qualquer valor (inclusive
+// null). Diferente de Eq/In/Gt/Lt, não compara contra um valor — útil para
+// filtrar por presença de um campo opcional sem precisar conhecer seu
+// conteúdo de antemão.
+func Exists(key string) Filter {
+	return existsFilter{key: key}
+}
+
+func (f existsFilter) compile(allowed map[string]bool) (string, []any, error) {
+	if !allowed[f.key] {
+		return "", nil, fmt.Errorf("metadata key %q is not in Config.AllowedFilterKeys", f.key)
+	}
+	for _, indexed := range indexedMetadataColumns {
+		if indexed == f.key {
+			return f.key + " IS NOT NULL", nil, nil
+		}
+	}
+	// json_extract devolve NULL tanto quando a chave não existe quanto
+	// quando ela existe com valor json null; json_type distingue os dois
+	// casos (devolve NULL só quando a chave está mesmo ausente).
+	return fmt.Sprintf("json_type(metadata, '$.%s') IS NOT NULL", f.key), nil, nil
+}
+
+func (f existsFilter) matches(metadata map[string]any) bool {
+	_, ok := metadata[f.key]
+	return ok
+}
+
+type andFilter struct{ filters []Filter }
+
+// And exige que todos os filters combinem.
+func And(filters ...Filter) Filter {
+	return andFilter{filters: filters}
+}
+
+func (f andFilter) compile(allowed map[string]bool) (string, []any, error) {
+	return combineFilters(f.filters, "AND", allowed)
+}
+
+func (f andFilter) matches(metadata map[string]any) bool {
+	for _, sub := range f.filters {
+		if !sub.matches(metadata) {
+			return false
+		}
+	}
+	return true
+}
+
+type orFilter struct{ filters []Filter }
+
+// Or exige que ao menos um dos filters combine.
+func Or(filters ...Filter) Filter {
+	return orFilter{filters: filters}
+}
+
+func (f orFilter) compile(allowed map[string]bool) (string, []any, error) {
+	return combineFilters(f.filters, "OR", allowed)
+}
+
+func (f orFilter) matches(metadata map[string]any) bool {
+	for _, sub := range f.filters {
+		if sub.matches(metadata) {
+			return true
+		}
+	}
+	return false
+}
+
+func combineFilters(filters []Filter, joiner string, allowed map[string]bool) (string, []any, error) {
+	if len(filters) == 0 {
+		return "1", nil, nil
+	}
+
+	var clauses []string
+	var args []any
+	for _, sub := range filters {
+		clause, subArgs, err := sub.compile(allowed)
+		if err != nil {
+			return "", nil, err
+		}
+		clauses = append(clauses, clause)
+		args = append(args, subArgs...)
+	}
+	return "(" + strings.Join(clauses, " "+joiner+" ") + ")", args, nil
+}
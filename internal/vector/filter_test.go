@@ -0,0 +1,204 @@
+package vector
+
+import (
+	"strings"
+	"testing"
+)
+
+func allowAll(keys ...string) map[string]bool {
+	allowed := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		allowed[k] = true
+	}
+	return allowed
+}
+
+func TestEqFilter(t *testing.T) {
+	f := Eq("category", "article")
+
+	clause, args, err := f.compile(allowAll("category"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clause != "category = ?" {
+		t.Errorf("compile() clause = %q, want %q", clause, "category = ?")
+	}
+	if len(args) != 1 || args[0] != "article" {
+		t.Errorf("compile() args = %v, want [article]", args)
+	}
+
+	if !f.matches(map[string]any{"category": "article"}) {
+		t.Error("matches() = false for an equal value, want true")
+	}
+	if f.matches(map[string]any{"category": "video"}) {
+		t.Error("matches() = true for a different value, want false")
+	}
+
+	if _, _, err := f.compile(allowAll()); err == nil {
+		t.Error("compile() with key not in allowed should error, got nil")
+	}
+}
+
+func TestInFilter(t *testing.T) {
+	f := In("category", "article", "video")
+
+	clause, args, err := f.compile(allowAll("category"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clause != "category IN (?,?)" {
+		t.Errorf("compile() clause = %q, want %q", clause, "category IN (?,?)")
+	}
+	if len(args) != 2 {
+		t.Errorf("compile() args = %v, want 2 values", args)
+	}
+
+	if !f.matches(map[string]any{"category": "video"}) {
+		t.Error("matches() = false for a value in the set, want true")
+	}
+	if f.matches(map[string]any{"category": "audio"}) {
+		t.Error("matches() = true for a value outside the set, want false")
+	}
+
+	empty := In("category")
+	clause, args, err = empty.compile(allowAll("category"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clause != "0" || args != nil {
+		t.Errorf("compile() for an empty In() = (%q, %v), want (\"0\", nil)", clause, args)
+	}
+}
+
+func TestComparisonFilters(t *testing.T) {
+	gt := Gt("score", 5)
+	clause, args, err := gt.compile(allowAll("score"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(clause, "> ?") {
+		t.Errorf("Gt compile() clause = %q, want a '> ?' comparison", clause)
+	}
+	if len(args) != 1 || args[0] != 5.0 {
+		t.Errorf("Gt compile() args = %v, want [5]", args)
+	}
+	if !gt.matches(map[string]any{"score": 6.0}) {
+		t.Error("Gt matches() = false for 6 > 5, want true")
+	}
+	if gt.matches(map[string]any{"score": 5.0}) {
+		t.Error("Gt matches() = true for 5 > 5, want false")
+	}
+	if gt.matches(map[string]any{"score": "not-a-number"}) {
+		t.Error("Gt matches() = true for a non-numeric value, want false")
+	}
+
+	lt := Lt("score", 5)
+	clause, _, err = lt.compile(allowAll("score"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(clause, "< ?") {
+		t.Errorf("Lt compile() clause = %q, want a '< ?' comparison", clause)
+	}
+	if !lt.matches(map[string]any{"score": 4.0}) {
+		t.Error("Lt matches() = false for 4 < 5, want true")
+	}
+	if lt.matches(map[string]any{"score": 5.0}) {
+		t.Error("Lt matches() = true for 5 < 5, want false")
+	}
+}
+
+func TestExistsFilter(t *testing.T) {
+	f := Exists("tags")
+
+	clause, args, err := f.compile(allowAll("tags"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args != nil {
+		t.Errorf("compile() args = %v, want nil", args)
+	}
+	if !strings.Contains(clause, "tags") {
+		t.Errorf("compile() clause = %q, want it to reference the key", clause)
+	}
+
+	if !f.matches(map[string]any{"tags": nil}) {
+		t.Error("matches() = false for a present key with a nil value, want true")
+	}
+	if f.matches(map[string]any{"other": "x"}) {
+		t.Error("matches() = true for an absent key, want false")
+	}
+
+	if _, _, err := f.compile(allowAll()); err == nil {
+		t.Error("compile() with key not in allowed should error, got nil")
+	}
+}
+
+func TestExistsFilterUsesIndexedColumnWhenAvailable(t *testing.T) {
+	f := Exists("category")
+	clause, _, err := f.compile(allowAll("category"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// "category" está em indexedMetadataColumns: a coluna gerada direto deve
+	// ser usada em vez de json_type(metadata, ...), para aproveitar o índice
+	// b-tree criado por Store.ensureFilterColumns.
+	if clause != "category IS NOT NULL" {
+		t.Errorf("compile() clause = %q, want %q", clause, "category IS NOT NULL")
+	}
+}
+
+func TestAndFilter(t *testing.T) {
+	f := And(Eq("category", "article"), Gt("score", 5))
+
+	clause, args, err := f.compile(allowAll("category", "score"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(clause, " AND ") {
+		t.Errorf("compile() clause = %q, want it joined with AND", clause)
+	}
+	if len(args) != 2 {
+		t.Errorf("compile() args = %v, want 2 values", args)
+	}
+
+	if !f.matches(map[string]any{"category": "article", "score": 6.0}) {
+		t.Error("matches() = false when both subfilters match, want true")
+	}
+	if f.matches(map[string]any{"category": "article", "score": 1.0}) {
+		t.Error("matches() = true when one subfilter fails, want false")
+	}
+
+	if _, _, err := f.compile(allowAll("category")); err == nil {
+		t.Error("compile() should propagate a subfilter's error for a disallowed key")
+	}
+}
+
+func TestOrFilter(t *testing.T) {
+	f := Or(Eq("category", "article"), Eq("category", "video"))
+
+	clause, _, err := f.compile(allowAll("category"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(clause, " OR ") {
+		t.Errorf("compile() clause = %q, want it joined with OR", clause)
+	}
+
+	if !f.matches(map[string]any{"category": "video"}) {
+		t.Error("matches() = false when one subfilter matches, want true")
+	}
+	if f.matches(map[string]any{"category": "audio"}) {
+		t.Error("matches() = true when no subfilter matches, want false")
+	}
+}
+
+func TestCombineFiltersEmpty(t *testing.T) {
+	clause, args, err := combineFilters(nil, "AND", allowAll())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clause != "1" || args != nil {
+		t.Errorf("combineFilters(nil) = (%q, %v), want (\"1\", nil)", clause, args)
+	}
+}
@@ -0,0 +1,358 @@
+package vector
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// HNSWConfig ajusta os parâmetros do índice HNSW (Hierarchical Navigable
+// Small World) mantido em memória por Store como atalho de busca aproximada
+// de vizinho mais próximo sobre grandes volumes (acima de ~100k vetores),
+// evitando depender só do full scan feito pela extensão sqlite-vec.
+type HNSWConfig struct {
+	M              int // vizinhos por nó nas camadas acima de 0
+	Mmax           int // grau máximo por nó após poda de vizinhos
+	EfConstruction int // tamanho da lista de candidatos durante inserção
+	Ef             int // tamanho da lista de candidatos durante busca
+}
+
+// DefaultHNSWConfig são os parâmetros recomendados pelo paper original do
+// HNSW (Malkov & Yashunin, 2016) para a maioria das cargas de trabalho.
+var DefaultHNSWConfig = HNSWConfig{
+	M:              16,
+	Mmax:           16,
+	EfConstruction: 200,
+	Ef:             50,
+}
+
+type hnswNode struct {
+	id     int64
+	vector []float64
+	// neighbors[l] são os ids dos vizinhos do nó na camada l.
+	neighbors [][]int64
+}
+
+type hnswCandidate struct {
+	id   int64
+	dist float64
+}
+
+// HNSWIndex é um índice aproximado de vizinho mais próximo em memória,
+// construído incrementalmente conforme o algoritmo 1 do paper do HNSW. Seguro
+// para uso concorrente: Insert, Delete, Search e Len serializam o acesso pelo
+// sync.RWMutex do próprio índice.
+type HNSWIndex struct {
+	mu       sync.RWMutex
+	cfg      HNSWConfig
+	metric   DistanceMetric
+	mL       float64 // fator de normalização de camada, 1/ln(M)
+	nodes    map[int64]*hnswNode
+	entry    int64
+	entrySet bool
+	maxLayer int
+}
+
+// NewHNSWIndex cria um índice vazio para a métrica de distância informada.
+// Quando cfg é o valor zero, DefaultHNSWConfig é usado.
+func NewHNSWIndex(metric DistanceMetric, cfg HNSWConfig) *HNSWIndex {
+	if cfg.M <= 0 {
+		cfg = DefaultHNSWConfig
+	}
+	return &HNSWIndex{
+		cfg:    cfg,
+		metric: metric,
+		mL:     1 / math.Log(float64(cfg.M)),
+		nodes:  make(map[int64]*hnswNode),
+	}
+}
+
+// Len reporta quantos vetores estão indexados.
+func (h *HNSWIndex) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.nodes)
+}
+
+// distanceFor calcula a distância entre a e b na métrica informada —
+// compartilhada por HNSWIndex e IVFFlatIndex, os dois índices em memória de
+// Store (ver annIndex em store.go).
+func distanceFor(metric DistanceMetric, a, b []float64) float64 {
+	switch metric {
+	case DistanceL2:
+		return l2Distance(a, b)
+	case DistanceL1:
+		return l1Distance(a, b)
+	default:
+		return cosineDistance(a, b)
+	}
+}
+
+func (h *HNSWIndex) distance(a, b []float64) float64 {
+	return distanceFor(h.metric, a, b)
+}
+
+// distanceFuncSQL mapeia metric para o nome da função de distância
+// correspondente na extensão sqlite-vec — compartilhada por Service.Search,
+// Service.SearchGlobal, Service.searchVectorFiltered e Store.search (ver
+// store_backend.go), que antes repetiam o mesmo switch cada uma.
+func distanceFuncSQL(metric DistanceMetric) string {
+	switch metric {
+	case DistanceL1:
+		return "vec_distance_l1"
+	case DistanceL2:
+		return "vec_distance_l2"
+	default:
+		return "vec_distance_cosine"
+	}
+}
+
+func cosineDistance(a, b []float64) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 1
+	}
+	return 1 - dot/(math.Sqrt(na)*math.Sqrt(nb))
+}
+
+func l2Distance(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return math.Sqrt(sum)
+}
+
+func l1Distance(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		sum += math.Abs(a[i] - b[i])
+	}
+	return sum
+}
+
+// randomLayer sorteia a camada de inserção de um novo nó como
+// floor(-ln(U(0,1)) * mL), igual ao algoritmo 1 do paper original.
+func (h *HNSWIndex) randomLayer() int {
+	u := rand.Float64()
+	for u == 0 {
+		u = rand.Float64()
+	}
+	return int(math.Floor(-math.Log(u) * h.mL))
+}
+
+// searchLayer roda uma busca best-first gulosa a partir de entryPoints na
+// camada layer, mantendo até ef candidatos visitados, e devolve os mais
+// próximos de query nessa camada.
+func (h *HNSWIndex) searchLayer(query []float64, entryPoints []int64, ef, layer int) []hnswCandidate {
+	visited := make(map[int64]bool, ef*2)
+	var frontier []hnswCandidate
+	var found []hnswCandidate
+
+	for _, ep := range entryPoints {
+		node, ok := h.nodes[ep]
+		if !ok || visited[ep] {
+			continue
+		}
+		visited[ep] = true
+		c := hnswCandidate{ep, h.distance(query, node.vector)}
+		frontier = append(frontier, c)
+		found = append(found, c)
+	}
+
+	for len(frontier) > 0 {
+		sort.Slice(frontier, func(i, j int) bool { return frontier[i].dist < frontier[j].dist })
+		c := frontier[0]
+		frontier = frontier[1:]
+
+		sort.Slice(found, func(i, j int) bool { return found[i].dist < found[j].dist })
+		if len(found) >= ef && c.dist > found[len(found)-1].dist {
+			break
+		}
+
+		node, ok := h.nodes[c.id]
+		if !ok || layer >= len(node.neighbors) {
+			continue
+		}
+		for _, nb := range node.neighbors[layer] {
+			if visited[nb] {
+				continue
+			}
+			visited[nb] = true
+			nbNode, ok := h.nodes[nb]
+			if !ok {
+				continue
+			}
+			nc := hnswCandidate{nb, h.distance(query, nbNode.vector)}
+			frontier = append(frontier, nc)
+			found = append(found, nc)
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].dist < found[j].dist })
+	if len(found) > ef {
+		found = found[:ef]
+	}
+	return found
+}
+
+// Insert adiciona um vetor ao índice, substituindo qualquer vetor anterior
+// com o mesmo id.
+func (h *HNSWIndex) Insert(id int64, vector []float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.deleteLocked(id)
+
+	layer := h.randomLayer()
+	node := &hnswNode{id: id, vector: vector, neighbors: make([][]int64, layer+1)}
+	h.nodes[id] = node
+
+	if !h.entrySet {
+		h.entry = id
+		h.entrySet = true
+		h.maxLayer = layer
+		return
+	}
+
+	entryPoints := []int64{h.entry}
+	for l := h.maxLayer; l > layer; l-- {
+		if nearest := h.searchLayer(vector, entryPoints, 1, l); len(nearest) > 0 {
+			entryPoints = []int64{nearest[0].id}
+		}
+	}
+
+	for l := min(layer, h.maxLayer); l >= 0; l-- {
+		candidates := h.searchLayer(vector, entryPoints, h.cfg.EfConstruction, l)
+		if len(candidates) > h.cfg.M {
+			candidates = candidates[:h.cfg.M]
+		}
+
+		for _, c := range candidates {
+			node.neighbors[l] = append(node.neighbors[l], c.id)
+			h.connectLocked(c.id, id, l)
+		}
+		if len(candidates) > 0 {
+			entryPoints = make([]int64, len(candidates))
+			for i, c := range candidates {
+				entryPoints[i] = c.id
+			}
+		}
+	}
+
+	if layer > h.maxLayer {
+		h.maxLayer = layer
+		h.entry = id
+	}
+}
+
+// connectLocked cria a aresta id -> newID (newID entra na lista de vizinhos de
+// id) na camada layer e poda os vizinhos
+// de id para o grau máximo Mmax, mantendo os mais próximos.
+func (h *HNSWIndex) connectLocked(id, newID int64, layer int) {
+	node, ok := h.nodes[id]
+	if !ok || layer >= len(node.neighbors) {
+		return
+	}
+
+	node.neighbors[layer] = append(node.neighbors[layer], newID)
+	if len(node.neighbors[layer]) <= h.cfg.Mmax {
+		return
+	}
+
+	ranked := make([]hnswCandidate, 0, len(node.neighbors[layer]))
+	for _, nb := range node.neighbors[layer] {
+		if nbNode, ok := h.nodes[nb]; ok {
+			ranked = append(ranked, hnswCandidate{nb, h.distance(node.vector, nbNode.vector)})
+		}
+	}
+	sort.Slice(ranked, func(i, j int) bool { return ranked[i].dist < ranked[j].dist })
+	if len(ranked) > h.cfg.Mmax {
+		ranked = ranked[:h.cfg.Mmax]
+	}
+
+	pruned := make([]int64, len(ranked))
+	for i, r := range ranked {
+		pruned[i] = r.id
+	}
+	node.neighbors[layer] = pruned
+}
+
+// Delete remove um vetor do índice, se presente.
+func (h *HNSWIndex) Delete(id int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.deleteLocked(id)
+}
+
+func (h *HNSWIndex) deleteLocked(id int64) {
+	if _, ok := h.nodes[id]; !ok {
+		return
+	}
+	delete(h.nodes, id)
+
+	for _, node := range h.nodes {
+		for l := range node.neighbors {
+			node.neighbors[l] = removeID(node.neighbors[l], id)
+		}
+	}
+
+	if h.entry == id {
+		h.entrySet = false
+		for otherID := range h.nodes {
+			h.entry = otherID
+			h.entrySet = true
+			break
+		}
+	}
+}
+
+func removeID(ids []int64, target int64) []int64 {
+	out := ids[:0]
+	for _, id := range ids {
+		if id != target {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// Search faz a descida gulosa padrão do HNSW até a camada 0 e, nela, uma
+// busca best-first com params.Ef candidatos (ou cfg.Ef, se params.Ef for
+// zero), retornando os k vetores mais próximos de query. params.Nprobe é
+// ignorado — não se aplica a HNSW (ver IVFFlatIndex.Search).
+func (h *HNSWIndex) Search(query []float64, k int, params SearchParams) []hnswCandidate {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if !h.entrySet {
+		return nil
+	}
+
+	entryPoints := []int64{h.entry}
+	for l := h.maxLayer; l > 0; l-- {
+		if nearest := h.searchLayer(query, entryPoints, 1, l); len(nearest) > 0 {
+			entryPoints = []int64{nearest[0].id}
+		}
+	}
+
+	ef := params.Ef
+	if ef <= 0 {
+		ef = h.cfg.Ef
+	}
+	if ef < k {
+		ef = k
+	}
+	found := h.searchLayer(query, entryPoints, ef, 0)
+	if len(found) > k {
+		found = found[:k]
+	}
+	return found
+}
@@ -0,0 +1,102 @@
+package vector
+
+import "testing"
+
+// clusteredVectors gera clusters bem separados em torno dos pontos de
+// centers, cada um com perCluster pontos deslocados do centro em i*0.01 no
+// primeiro eixo (de 0 a (perCluster-1)*0.01) — dataset pequeno o bastante para calcular à mão quem são os vizinhos
+// verdadeiros mais próximos de qualquer ponto do cluster 0.
+func clusteredVectors(centers [][]float64, perCluster int) (ids []int64, vectors [][]float64) {
+	var id int64
+	for _, center := range centers {
+		for i := 0; i < perCluster; i++ {
+			id++
+			v := make([]float64, len(center))
+			copy(v, center)
+			// Desloca cada ponto um pouco do centro do cluster, sem cruzar a
+			// metade da distância até o cluster mais próximo (ver uso em
+			// TestHNSWIndexSearchRecall).
+			v[0] += float64(i) * 0.01
+			ids = append(ids, id)
+			vectors = append(vectors, v)
+		}
+	}
+	return ids, vectors
+}
+
+func TestHNSWIndexSearchRecall(t *testing.T) {
+	centers := [][]float64{
+		{0, 0, 0},
+		{10, 0, 0},
+		{0, 10, 0},
+		{0, 0, 10},
+	}
+	const perCluster = 10
+	ids, vectors := clusteredVectors(centers, perCluster)
+
+	idx := NewHNSWIndex(DistanceL2, DefaultHNSWConfig)
+	for i, id := range ids {
+		idx.Insert(id, vectors[i])
+	}
+
+	if got := idx.Len(); got != len(ids) {
+		t.Fatalf("Len() = %d, want %d", got, len(ids))
+	}
+
+	// Query igual ao centro do cluster 0: os k=perCluster vizinhos
+	// verdadeiros mais próximos são exatamente os outros pontos desse
+	// cluster (ids[0:perCluster]), já que o cluster mais próximo está a
+	// distância 10 e o espalhamento interno do cluster é de no máximo 0.09.
+	query := []float64{0, 0, 0}
+	found := idx.Search(query, perCluster, SearchParams{Ef: 200})
+
+	if len(found) != perCluster {
+		t.Fatalf("Search returned %d results, want %d", len(found), perCluster)
+	}
+
+	wantCluster := make(map[int64]bool, perCluster)
+	for _, id := range ids[:perCluster] {
+		wantCluster[id] = true
+	}
+
+	hits := 0
+	for _, c := range found {
+		if wantCluster[c.id] {
+			hits++
+		}
+	}
+
+	// HNSW é aproximado; com Ef generoso e clusters tão separados, espera-se
+	// recall perfeito, mas tolera-se até 1 miss para não tornar o teste
+	// instável por causa da aleatoriedade de randomLayer.
+	if hits < perCluster-1 {
+		t.Errorf("recall too low: %d/%d true neighbors found (%v)", hits, perCluster, found)
+	}
+
+	// Resultados devem vir ordenados por distância crescente.
+	for i := 1; i < len(found); i++ {
+		if found[i].dist < found[i-1].dist {
+			t.Errorf("results not sorted by distance: found[%d].dist=%f < found[%d].dist=%f", i, found[i].dist, i-1, found[i-1].dist)
+		}
+	}
+}
+
+func TestHNSWIndexDelete(t *testing.T) {
+	idx := NewHNSWIndex(DistanceL2, DefaultHNSWConfig)
+	idx.Insert(1, []float64{0, 0})
+	idx.Insert(2, []float64{1, 0})
+	idx.Insert(3, []float64{2, 0})
+
+	idx.Delete(2)
+
+	if got := idx.Len(); got != 2 {
+		t.Fatalf("Len() after delete = %d, want 2", got)
+	}
+
+	found := idx.Search([]float64{0, 0}, 3, SearchParams{Ef: 10})
+	for _, c := range found {
+		if c.id == 2 {
+			t.Errorf("deleted id 2 still returned by Search: %v", found)
+		}
+	}
+}
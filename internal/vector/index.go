@@ -0,0 +1,86 @@
+package vector
+
+import "strconv"
+
+// IndexKind identifica o tipo de índice aproximado de vizinho mais próximo
+// mantido em memória por Store para acelerar Service.Search/SearchGlobal
+// acima do full scan que a extensão sqlite-vec faz sobre a tabela vec0.
+type IndexKind string
+
+const (
+	// IndexFlat não cria índice em memória nenhum: Service.Search* sempre
+	// cai para o full scan via sqlite-vec. É o comportamento de antes desta
+	// abstração existir, e o padrão quando IndexSpec.Kind está vazio.
+	IndexFlat IndexKind = "flat"
+	// IndexIVFFlat usa IVFFlatIndex: os vetores são particionados em
+	// IndexParams["nlist"] células, e a busca varre só as
+	// SearchParams.Nprobe (ou IndexParams["nprobe"]) mais próximas da
+	// consulta.
+	IndexIVFFlat IndexKind = "ivf_flat"
+	// IndexHNSW usa HNSWIndex, com IndexParams "M"/"efConstruction"/"ef"
+	// (ver HNSWConfig).
+	IndexHNSW IndexKind = "hnsw"
+)
+
+// IndexSpec descreve o índice de um content_type (ou, com content_type "",
+// o índice global usado por Service.SearchGlobal), inspirado no par
+// typeParams/indexParams do Milvus: TypeParams descreve a forma dos dados
+// (reservado para uso futuro, ex. tipo de quantização; nenhuma chave é lida
+// hoje) e IndexParams ajusta a construção do índice em si (nlist/nprobe
+// para ivf_flat; M/efConstruction/ef para hnsw). Store.SaveIndexSpec
+// persiste um IndexSpec por content_type em vector_indexes; Store.EnableIndex
+// constrói e popula o índice em memória correspondente.
+type IndexSpec struct {
+	Kind        IndexKind
+	TypeParams  map[string]string
+	IndexParams map[string]string
+}
+
+// SearchParams ajusta a busca aproximada conforme o IndexKind do índice em
+// uso: Nprobe para ivf_flat (quantas células de Voronoi visitar) e Ef para
+// hnsw (tamanho da lista de candidatos). O campo que não se aplica ao índice
+// em uso é ignorado; zero usa o padrão do próprio índice (IndexSpec.IndexParams
+// ou, na ausência destes, HNSWConfig/IVFConfig default).
+type SearchParams struct {
+	Nprobe int
+	Ef     int
+}
+
+// intParam lê a chave key de params como inteiro, devolvendo def se a chave
+// não existir ou não parsear — usado por buildIndex para montar
+// HNSWConfig/IVFConfig a partir de IndexSpec.IndexParams sem exigir que o
+// chamador preencha todas as chaves.
+func intParam(params map[string]string, key string, def int) int {
+	v, ok := params[key]
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// buildIndex constrói o índice em memória correspondente a spec, ou nil
+// para IndexFlat. vec0 não expõe construtores de índice IVF/HNSW — a
+// extensão só faz full scan, opcionalmente restrito por uma partition key —
+// então a aceleração para IndexIVFFlat/IndexHNSW só existe do lado Go,
+// nunca dentro do vec0 em si (ver Store.populateIndex).
+func buildIndex(metric DistanceMetric, spec IndexSpec) annIndex {
+	switch spec.Kind {
+	case IndexHNSW:
+		cfg := DefaultHNSWConfig
+		cfg.M = intParam(spec.IndexParams, "M", cfg.M)
+		cfg.EfConstruction = intParam(spec.IndexParams, "efConstruction", cfg.EfConstruction)
+		cfg.Ef = intParam(spec.IndexParams, "ef", cfg.Ef)
+		return NewHNSWIndex(metric, cfg)
+	case IndexIVFFlat:
+		cfg := DefaultIVFConfig
+		cfg.Nlist = intParam(spec.IndexParams, "nlist", cfg.Nlist)
+		cfg.Nprobe = intParam(spec.IndexParams, "nprobe", cfg.Nprobe)
+		return NewIVFFlatIndex(metric, cfg)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,150 @@
+package vector
+
+import (
+	"sort"
+	"sync"
+)
+
+// IVFConfig ajusta os parâmetros do índice IVF Flat (Inverted File with
+// Flat quantization): Nlist células de Voronoi e Nprobe células visitadas
+// por busca.
+type IVFConfig struct {
+	Nlist  int
+	Nprobe int
+}
+
+// DefaultIVFConfig são os parâmetros usados quando IVFConfig.Nlist não é
+// informado.
+var DefaultIVFConfig = IVFConfig{Nlist: 100, Nprobe: 8}
+
+// IVFFlatIndex é um índice aproximado de vizinho mais próximo em memória
+// que particiona os vetores inseridos em até cfg.Nlist células: os
+// primeiros Nlist vetores inseridos viram os centróides iniciais (uma
+// aproximação em streaming do treino offline por k-means do IVF clássico —
+// sem um Rebuild os centróides nunca se realinham aos dados inseridos
+// depois), e cada vetor seguinte é atribuído à célula de centróide mais
+// próximo. A busca varre só as cfg.Nprobe células mais próximas da
+// consulta, ao custo de não encontrar o vizinho exato quando ele cai numa
+// célula não visitada — a mesma troca de recall por latência que HNSWIndex
+// faz de outro jeito (ver Store.EnableIndex).
+type IVFFlatIndex struct {
+	mu        sync.RWMutex
+	cfg       IVFConfig
+	metric    DistanceMetric
+	centroids [][]float64
+	cells     map[int]map[int64][]float64
+	cellOf    map[int64]int
+}
+
+// NewIVFFlatIndex cria um índice vazio para a métrica de distância
+// informada. Quando cfg é o valor zero, DefaultIVFConfig é usado.
+func NewIVFFlatIndex(metric DistanceMetric, cfg IVFConfig) *IVFFlatIndex {
+	if cfg.Nlist <= 0 {
+		cfg = DefaultIVFConfig
+	}
+	return &IVFFlatIndex{
+		cfg:    cfg,
+		metric: metric,
+		cells:  make(map[int]map[int64][]float64),
+		cellOf: make(map[int64]int),
+	}
+}
+
+// Len reporta quantos vetores estão indexados.
+func (idx *IVFFlatIndex) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.cellOf)
+}
+
+func (idx *IVFFlatIndex) nearestCentroidLocked(vector []float64) int {
+	best := 0
+	bestDist := distanceFor(idx.metric, vector, idx.centroids[0])
+	for i := 1; i < len(idx.centroids); i++ {
+		if d := distanceFor(idx.metric, vector, idx.centroids[i]); d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+// Insert adiciona um vetor ao índice, substituindo qualquer vetor anterior
+// com o mesmo id.
+func (idx *IVFFlatIndex) Insert(id int64, vector []float64) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.deleteLocked(id)
+
+	var cell int
+	if len(idx.centroids) < idx.cfg.Nlist {
+		idx.centroids = append(idx.centroids, append([]float64(nil), vector...))
+		cell = len(idx.centroids) - 1
+	} else {
+		cell = idx.nearestCentroidLocked(vector)
+	}
+
+	if idx.cells[cell] == nil {
+		idx.cells[cell] = make(map[int64][]float64)
+	}
+	idx.cells[cell][id] = vector
+	idx.cellOf[id] = cell
+}
+
+// Delete remove um vetor do índice, se presente.
+func (idx *IVFFlatIndex) Delete(id int64) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.deleteLocked(id)
+}
+
+func (idx *IVFFlatIndex) deleteLocked(id int64) {
+	cell, ok := idx.cellOf[id]
+	if !ok {
+		return
+	}
+	delete(idx.cells[cell], id)
+	delete(idx.cellOf, id)
+}
+
+// Search visita as params.Nprobe (ou cfg.Nprobe, se params.Nprobe for zero)
+// células de centróide mais próximo de query e devolve os k vetores mais
+// próximos entre os candidatos encontrados nelas.
+func (idx *IVFFlatIndex) Search(query []float64, k int, params SearchParams) []hnswCandidate {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	if len(idx.centroids) == 0 {
+		return nil
+	}
+
+	nprobe := params.Nprobe
+	if nprobe <= 0 {
+		nprobe = idx.cfg.Nprobe
+	}
+	if nprobe > len(idx.centroids) {
+		nprobe = len(idx.centroids)
+	}
+
+	type cellDist struct {
+		cell int
+		dist float64
+	}
+	ranked := make([]cellDist, len(idx.centroids))
+	for i, c := range idx.centroids {
+		ranked[i] = cellDist{i, distanceFor(idx.metric, query, c)}
+	}
+	sort.Slice(ranked, func(i, j int) bool { return ranked[i].dist < ranked[j].dist })
+
+	var candidates []hnswCandidate
+	for _, rc := range ranked[:nprobe] {
+		for id, vec := range idx.cells[rc.cell] {
+			candidates = append(candidates, hnswCandidate{id, distanceFor(idx.metric, query, vec)})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].dist < candidates[j].dist })
+	if len(candidates) > k {
+		candidates = candidates[:k]
+	}
+	return candidates
+}
@@ -0,0 +1,88 @@
+package vector
+
+import "testing"
+
+func TestIVFFlatIndexSeedsCentroidsFromFirstInserts(t *testing.T) {
+	idx := NewIVFFlatIndex(DistanceL2, IVFConfig{Nlist: 2, Nprobe: 2})
+
+	idx.Insert(1, []float64{0, 0})
+	idx.Insert(2, []float64{10, 10})
+
+	if got := len(idx.centroids); got != 2 {
+		t.Fatalf("after seeding Nlist=2, len(centroids) = %d, want 2", got)
+	}
+	if got := idx.cellOf[1]; got != 0 {
+		t.Errorf("first insert should seed cell 0, got cell %d", got)
+	}
+	if got := idx.cellOf[2]; got != 1 {
+		t.Errorf("second insert should seed cell 1, got cell %d", got)
+	}
+
+	// Uma vez que os Nlist centróides já foram semeados, inserções
+	// subsequentes vão para a célula do centróide mais próximo em vez de
+	// criar um novo centróide.
+	idx.Insert(3, []float64{1, 1})
+	if got := len(idx.centroids); got != 2 {
+		t.Fatalf("after Nlist reached, len(centroids) grew to %d, want 2", got)
+	}
+	if got := idx.cellOf[3]; got != 0 {
+		t.Errorf("vector near centroid 0 assigned to cell %d, want 0", got)
+	}
+
+	idx.Insert(4, []float64{9, 9})
+	if got := idx.cellOf[4]; got != 1 {
+		t.Errorf("vector near centroid 1 assigned to cell %d, want 1", got)
+	}
+}
+
+func TestIVFFlatIndexSearchRespectsNprobe(t *testing.T) {
+	idx := NewIVFFlatIndex(DistanceL2, IVFConfig{Nlist: 3, Nprobe: 1})
+
+	// Três células bem separadas, uma delas (em torno de {0,0}) com dois
+	// vetores próximos da query.
+	idx.Insert(1, []float64{0, 0})
+	idx.Insert(2, []float64{100, 100})
+	idx.Insert(3, []float64{-100, -100})
+	idx.Insert(4, []float64{0.5, 0.5})
+
+	found := idx.Search([]float64{0, 0}, 10, SearchParams{Nprobe: 1})
+
+	// Com Nprobe=1 só a célula mais próxima (a de id 1) é visitada, então o
+	// resultado não deve incluir ids de células distantes mesmo pedindo k=10.
+	for _, c := range found {
+		if c.id == 2 || c.id == 3 {
+			t.Errorf("Search with Nprobe=1 visited a distant cell: found id %d (%v)", c.id, found)
+		}
+	}
+	if len(found) != 2 {
+		t.Fatalf("expected exactly the 2 vectors in the nearest cell, got %d (%v)", len(found), found)
+	}
+
+	// Resultados ordenados por distância crescente.
+	if found[0].dist > found[1].dist {
+		t.Errorf("results not sorted by distance: %v", found)
+	}
+}
+
+func TestIVFFlatIndexDelete(t *testing.T) {
+	idx := NewIVFFlatIndex(DistanceL2, IVFConfig{Nlist: 2, Nprobe: 2})
+	idx.Insert(1, []float64{0, 0})
+	idx.Insert(2, []float64{10, 10})
+	idx.Insert(3, []float64{0.1, 0.1})
+
+	idx.Delete(3)
+
+	if got := idx.Len(); got != 2 {
+		t.Fatalf("Len() after delete = %d, want 2", got)
+	}
+	if _, ok := idx.cellOf[3]; ok {
+		t.Error("cellOf still tracks a deleted id")
+	}
+
+	found := idx.Search([]float64{0, 0}, 10, SearchParams{})
+	for _, c := range found {
+		if c.id == 3 {
+			t.Errorf("deleted id 3 still returned by Search: %v", found)
+		}
+	}
+}
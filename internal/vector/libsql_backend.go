@@ -0,0 +1,60 @@
+package vector
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrBackendNotImplemented é devolvido por todo método de LibSQLBackend — ele
+// existe só para marcar o ponto de extensão onde um backend libSQL/Turso de
+// verdade entraria (libSQL fala o protocolo de rede HRANA do Turso, não
+// carrega a extensão sqlite-vec via cgo como Store; ver vec0 em
+// https://github.com/tursodatabase/libsql para o que falta portar), sem
+// puxar o driver libSQL como dependência antes de haver uma implementação
+// real por trás dele.
+var ErrBackendNotImplemented = errors.New("vector: libsql backend ainda não implementado")
+
+// LibSQLBackend é o stub de um Backend sobre libSQL/Turso — NewLibSQLBackend
+// devolve um valor que satisfaz a interface Backend (para já poder ser
+// passado a NewService hoje), mas cujos métodos só devolvem
+// ErrBackendNotImplemented até a implementação de verdade chegar.
+type LibSQLBackend struct {
+	dsn string
+}
+
+// NewLibSQLBackend recebe o DSN de conexão libSQL/Turso (ex.
+// "libsql://<db>.turso.io?authToken=...") só para já fixar a assinatura que
+// a implementação futura vai usar; hoje ele não abre conexão nenhuma.
+func NewLibSQLBackend(dsn string) *LibSQLBackend {
+	return &LibSQLBackend{dsn: dsn}
+}
+
+func (b *LibSQLBackend) EnsureTable(ctx context.Context) error {
+	return ErrBackendNotImplemented
+}
+
+func (b *LibSQLBackend) Insert(ctx context.Context, embedding Embedding) (int64, error) {
+	return 0, ErrBackendNotImplemented
+}
+
+func (b *LibSQLBackend) Search(ctx context.Context, contentType string, queryVector []float64, limit int, metric DistanceMetric) ([]SearchResult, error) {
+	return nil, ErrBackendNotImplemented
+}
+
+func (b *LibSQLBackend) SearchGlobal(ctx context.Context, queryVector []float64, limit int, metric DistanceMetric) ([]SearchResult, error) {
+	return nil, ErrBackendNotImplemented
+}
+
+func (b *LibSQLBackend) Delete(ctx context.Context, contentType string, contentID int64) error {
+	return ErrBackendNotImplemented
+}
+
+func (b *LibSQLBackend) Update(ctx context.Context, contentType string, contentID int64, newVector []float64) error {
+	return ErrBackendNotImplemented
+}
+
+func (b *LibSQLBackend) Count(ctx context.Context, contentType string) (int, error) {
+	return 0, ErrBackendNotImplemented
+}
+
+var _ Backend = (*LibSQLBackend)(nil)
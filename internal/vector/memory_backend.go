@@ -0,0 +1,140 @@
+package vector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryBackend é uma implementação de Backend inteiramente em memória, sem
+// cgo nem a extensão sqlite-vec — pensada para testes e benchmarks em
+// ambientes de CI onde esses dois não estão disponíveis (ver
+// BenchmarkVector_Backend_Search em test/benchmarks, que roda o mesmo
+// benchmark com b.Run("sqlite-vec", ...) e b.Run("memory", ...), cada um com
+// seu próprio Service sobre o Backend correspondente).
+// Busca é sempre full scan: não há índice ANN aqui, só comparação exaustiva
+// via distanceFor — adequado para os volumes pequenos de um teste, não para
+// produção.
+type MemoryBackend struct {
+	mu        sync.RWMutex
+	dimension int
+	nextID    int64
+	rows      map[int64]Embedding
+}
+
+// NewMemoryBackend cria um MemoryBackend vazio para vetores de dimension
+// posições. Insert rejeita vetores de dimensão diferente, igual ao
+// float[dimension] declarado por Store.EnsureTable para o backend
+// sqlite-vec.
+func NewMemoryBackend(dimension int) *MemoryBackend {
+	return &MemoryBackend{
+		dimension: dimension,
+		rows:      make(map[int64]Embedding),
+	}
+}
+
+func (b *MemoryBackend) EnsureTable(ctx context.Context) error {
+	return nil
+}
+
+func (b *MemoryBackend) Insert(ctx context.Context, embedding Embedding) (int64, error) {
+	if len(embedding.Vector) != b.dimension {
+		return 0, fmt.Errorf("vector: expected dimension %d, got %d", b.dimension, len(embedding.Vector))
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	id := b.nextID
+
+	embedding.ID = id
+	embedding.CreatedAt = time.Now()
+	embedding.UpdatedAt = embedding.CreatedAt
+	b.rows[id] = embedding
+
+	return id, nil
+}
+
+func (b *MemoryBackend) Search(ctx context.Context, contentType string, queryVector []float64, limit int, metric DistanceMetric) ([]SearchResult, error) {
+	return b.search(func(e Embedding) bool { return e.ContentType == contentType }, queryVector, limit, metric)
+}
+
+func (b *MemoryBackend) SearchGlobal(ctx context.Context, queryVector []float64, limit int, metric DistanceMetric) ([]SearchResult, error) {
+	return b.search(func(Embedding) bool { return true }, queryVector, limit, metric)
+}
+
+func (b *MemoryBackend) search(include func(Embedding) bool, queryVector []float64, limit int, metric DistanceMetric) ([]SearchResult, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	results := make([]SearchResult, 0, len(b.rows))
+	for _, e := range b.rows {
+		if !include(e) {
+			continue
+		}
+		results = append(results, SearchResult{
+			Embedding:  e,
+			Similarity: distanceFor(metric, queryVector, e.Vector),
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Similarity < results[j].Similarity })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func (b *MemoryBackend) Delete(ctx context.Context, contentType string, contentID int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for id, e := range b.rows {
+		if e.ContentType == contentType && e.ContentID == contentID {
+			delete(b.rows, id)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBackend) Update(ctx context.Context, contentType string, contentID int64, newVector []float64) error {
+	if len(newVector) != b.dimension {
+		return fmt.Errorf("vector: expected dimension %d, got %d", b.dimension, len(newVector))
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for id, e := range b.rows {
+		if e.ContentType == contentType && e.ContentID == contentID {
+			e.Vector = newVector
+			e.UpdatedAt = time.Now()
+			b.rows[id] = e
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (b *MemoryBackend) Count(ctx context.Context, contentType string) (int, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	count := 0
+	for _, e := range b.rows {
+		if e.ContentType == contentType {
+			count++
+		}
+	}
+	return count, nil
+}
+
+var _ Backend = (*MemoryBackend)(nil)
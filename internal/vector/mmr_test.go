@@ -0,0 +1,68 @@
+package vector
+
+import "testing"
+
+func TestMMRSelectPrefersRelevanceWhenLambdaIsOne(t *testing.T) {
+	// doc1 e doc2 são quase-duplicados (ambos muito parecidos com a query);
+	// doc3 é menos relevante porém diverso dos outros dois.
+	candidates := []SearchResult{
+		{Embedding: Embedding{ID: 1, Vector: []float64{1, 0}}},
+		{Embedding: Embedding{ID: 2, Vector: []float64{0.99, 0.01}}},
+		{Embedding: Embedding{ID: 3, Vector: []float64{0, 1}}},
+	}
+	query := []float64{1, 0}
+
+	selected := mmrSelect(candidates, query, 2, 1.0)
+
+	if len(selected) != 2 {
+		t.Fatalf("mmrSelect returned %d results, want 2", len(selected))
+	}
+	// lambda=1.0 ignora diversidade: os dois mais relevantes (doc1, doc2)
+	// vencem mesmo sendo quase-duplicados.
+	if selected[0].ID != 1 || selected[1].ID != 2 {
+		t.Errorf("lambda=1.0 selection = %v, want [1, 2] in relevance order", ids(selected))
+	}
+}
+
+func TestMMRSelectPrefersDiversityWhenLambdaIsZero(t *testing.T) {
+	candidates := []SearchResult{
+		{Embedding: Embedding{ID: 1, Vector: []float64{1, 0}}},
+		{Embedding: Embedding{ID: 2, Vector: []float64{0.99, 0.01}}},
+		{Embedding: Embedding{ID: 3, Vector: []float64{0, 1}}},
+	}
+	query := []float64{1, 0}
+
+	selected := mmrSelect(candidates, query, 2, 0.0)
+
+	if len(selected) != 2 {
+		t.Fatalf("mmrSelect returned %d results, want 2", len(selected))
+	}
+	// Primeiro candidato escolhido ainda é o mais relevante (nada selecionado
+	// ainda para penalizar similaridade), mas o segundo deve ser doc3: com
+	// lambda=0.0 o score só penaliza parecer com o já selecionado, e doc2 é
+	// quase idêntico a doc1.
+	if selected[0].ID != 1 {
+		t.Errorf("first pick = %d, want 1 (most relevant with nothing selected yet)", selected[0].ID)
+	}
+	if selected[1].ID != 3 {
+		t.Errorf("lambda=0.0 second pick = %d, want 3 (diverse from doc1, unlike near-duplicate doc2)", selected[1].ID)
+	}
+}
+
+func TestMMRSelectReturnsAllCandidatesWhenFewerThanLimit(t *testing.T) {
+	candidates := []SearchResult{
+		{Embedding: Embedding{ID: 1, Vector: []float64{1, 0}}},
+	}
+	selected := mmrSelect(candidates, []float64{1, 0}, 5, 0.5)
+	if len(selected) != 1 {
+		t.Fatalf("mmrSelect returned %d results, want 1", len(selected))
+	}
+}
+
+func ids(results []SearchResult) []int64 {
+	out := make([]int64, len(results))
+	for i, r := range results {
+		out[i] = r.ID
+	}
+	return out
+}
@@ -0,0 +1,186 @@
+package vector
+
+import "fmt"
+
+// QuantizationMode seleciona o codec secundário gravado em embedding_q (ver
+// Store.EnsureTable) ao lado do vetor float32 de sempre — QuantizationNone
+// (o padrão) não grava nada a mais; QuantizationInt8 e QuantizationBinary
+// trocam precisão por um primeiro corte muito mais barato em Service.
+// SearchQuantized (ver vec_distance_hamming/vec_distance_l2 sobre a coluna
+// quantizada, em vez da coluna embedding float[N] inteira).
+type QuantizationMode string
+
+const (
+	QuantizationNone   QuantizationMode = ""
+	QuantizationInt8   QuantizationMode = "int8"
+	QuantizationBinary QuantizationMode = "binary"
+)
+
+// QuantizationCalibration guarda o min/max observado por dimensão, usado
+// por quantizeInt8 para mapear cada componente float64 de um vetor para o
+// intervalo int8 [-127, 127]. O valor zero (sem Min/Max) faz quantizeInt8
+// calibrar cada vetor por si mesmo, o que é impreciso para comparar
+// vetores calibrados com escalas diferentes — a mesma distância int8 bruta
+// deixa de corresponder à mesma distância no espaço float32 original. Use
+// CalibrateInt8 sobre uma amostra representativa da coleção antes de
+// popular Config.Calibration.
+type QuantizationCalibration struct {
+	Min []float64
+	Max []float64
+}
+
+// CalibrateInt8 varre samples (uma amostra representativa da coleção, não
+// precisa ser o corpus inteiro) e devolve o min/max observado em cada
+// dimensão — para uso em Config.Calibration quando Config.Quantization é
+// QuantizationInt8. samples deve ter ao menos um vetor; vetores com menos
+// dimensões que o primeiro são ignorados.
+func CalibrateInt8(samples [][]float64) QuantizationCalibration {
+	if len(samples) == 0 {
+		return QuantizationCalibration{}
+	}
+
+	dim := len(samples[0])
+	min := make([]float64, dim)
+	max := make([]float64, dim)
+	copy(min, samples[0])
+	copy(max, samples[0])
+
+	for _, vec := range samples[1:] {
+		if len(vec) != dim {
+			continue
+		}
+		for i, v := range vec {
+			if v < min[i] {
+				min[i] = v
+			}
+			if v > max[i] {
+				max[i] = v
+			}
+		}
+	}
+
+	return QuantizationCalibration{Min: min, Max: max}
+}
+
+// quantizeInt8 escala cada dimensão de vec para o intervalo int8
+// [-127, 127] a partir de calibration.Min/Max — ou, se calibration não
+// cobrir essa dimensão (zero value, ou samples menores que vec), a partir
+// do próprio min/max de vec, que funciona como fallback honesto mas não
+// produz distâncias comparáveis entre vetores quantizados com escalas
+// diferentes (ver QuantizationCalibration).
+func quantizeInt8(vec []float64, calibration QuantizationCalibration) []int8 {
+	min, max := make([]float64, len(vec)), make([]float64, len(vec))
+	selfCalibrated := len(calibration.Min) != len(vec) || len(calibration.Max) != len(vec)
+	if selfCalibrated {
+		lo, hi := vec[0], vec[0]
+		for _, v := range vec {
+			if v < lo {
+				lo = v
+			}
+			if v > hi {
+				hi = v
+			}
+		}
+		for i := range vec {
+			min[i], max[i] = lo, hi
+		}
+	} else {
+		copy(min, calibration.Min)
+		copy(max, calibration.Max)
+	}
+
+	out := make([]int8, len(vec))
+	for i, v := range vec {
+		spread := max[i] - min[i]
+		if spread == 0 {
+			out[i] = 0
+			continue
+		}
+		scaled := (v-min[i])/spread*255 - 127
+		switch {
+		case scaled > 127:
+			scaled = 127
+		case scaled < -127:
+			scaled = -127
+		}
+		out[i] = int8(scaled)
+	}
+	return out
+}
+
+// quantizeForColumn devolve o blob a gravar na coluna embedding_q para vec,
+// conforme s.config.Quantization — nil (sem erro) se QuantizationNone.
+// Compartilhado por Service.Store/storeWithText/Insert/Upsert/BatchUpsert/
+// StoreBatch/UpdateEmbedding, que são os únicos pontos que escrevem em
+// embedding_q.
+func (s *Store) quantizeForColumn(vec []float64) ([]byte, error) {
+	switch s.config.Quantization {
+	case QuantizationNone:
+		return nil, nil
+	case QuantizationInt8:
+		vals := quantizeInt8(vec, s.config.Calibration)
+		out := make([]byte, len(vals))
+		for i, v := range vals {
+			out[i] = byte(v)
+		}
+		return out, nil
+	case QuantizationBinary:
+		return quantizeBinary(vec)
+	default:
+		return nil, fmt.Errorf("vector: quantization mode desconhecido: %q", s.config.Quantization)
+	}
+}
+
+// quantizationPlaceholder devolve um []byte não nil se store tiver
+// quantização configurada, ou nil caso contrário — usado só para decidir se
+// a coluna embedding_q entra no SQL de um INSERT preparado uma única vez
+// por chunk (ver storeBatchChunk/batchUpsertChunk), antes de qualquer vetor
+// específico estar disponível para quantizar de verdade.
+func quantizationPlaceholder(store *Store) []byte {
+	if store.config.Quantization == QuantizationNone {
+		return nil
+	}
+	return []byte{}
+}
+
+// quantizedInsertColumn devolve a cláusula de coluna e o placeholder extra
+// para incluir embedding_q no fim da lista de colunas de um INSERT, e o(s)
+// argumento(s) extra(s) para anexar aos args na mesma ordem — quantized nil
+// (Config.Quantization é QuantizationNone) devolve tudo vazio, deixando o
+// INSERT exatamente como antes de existir quantização.
+func quantizedInsertColumn(quantized []byte) (columnSQL, placeholderSQL string, extraArgs []any) {
+	if quantized == nil {
+		return "", "", nil
+	}
+	return ", embedding_q", ", ?", []any{quantized}
+}
+
+// quantizedSetClause é o equivalente de quantizedInsertColumn para um
+// UPDATE: devolve a cláusula "SET embedding_q = ?" extra e seu argumento,
+// ou tudo vazio se quantized for nil.
+func quantizedSetClause(quantized []byte) (setSQL string, extraArgs []any) {
+	if quantized == nil {
+		return "", nil
+	}
+	return ", embedding_q = ?", []any{quantized}
+}
+
+// quantizeBinary empacota vec num bitstring de len(vec) bits (8 por byte):
+// bit 1 se o componente for > 0, bit 0 caso contrário — o corte mais
+// grosseiro possível (1 bit por dimensão), usado com vec_distance_hamming
+// em Service.SearchQuantized como um primeiro filtro muito mais barato que
+// a distância float32 completa. Exige len(vec) múltiplo de 8 (limitação da
+// coluna bit[N] do sqlite-vec, que empacota N bits em N/8 bytes).
+func quantizeBinary(vec []float64) ([]byte, error) {
+	if len(vec)%8 != 0 {
+		return nil, fmt.Errorf("vector: quantização binária exige dimensão múltipla de 8, recebido %d", len(vec))
+	}
+
+	out := make([]byte, len(vec)/8)
+	for i, v := range vec {
+		if v > 0 {
+			out[i/8] |= 1 << uint(i%8)
+		}
+	}
+	return out, nil
+}
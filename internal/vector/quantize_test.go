@@ -0,0 +1,116 @@
+package vector
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCalibrateInt8(t *testing.T) {
+	samples := [][]float64{
+		{0, -5, 10},
+		{2, -1, 8},
+		{-1, -3, 12},
+	}
+
+	cal := CalibrateInt8(samples)
+
+	wantMin := []float64{-1, -5, 8}
+	wantMax := []float64{2, -1, 12}
+	for i := range wantMin {
+		if cal.Min[i] != wantMin[i] {
+			t.Errorf("Min[%d] = %v, want %v", i, cal.Min[i], wantMin[i])
+		}
+		if cal.Max[i] != wantMax[i] {
+			t.Errorf("Max[%d] = %v, want %v", i, cal.Max[i], wantMax[i])
+		}
+	}
+
+	if got := CalibrateInt8(nil); len(got.Min) != 0 || len(got.Max) != 0 {
+		t.Errorf("CalibrateInt8(nil) = %+v, want zero value", got)
+	}
+}
+
+func TestQuantizeInt8RoundTripErrorBound(t *testing.T) {
+	vec := []float64{-10, -5, 0, 5, 10}
+	cal := QuantizationCalibration{
+		Min: []float64{-10, -10, -10, -10, -10},
+		Max: []float64{10, 10, 10, 10, 10},
+	}
+
+	quantized := quantizeInt8(vec, cal)
+	if len(quantized) != len(vec) {
+		t.Fatalf("quantizeInt8 returned %d values, want %d", len(quantized), len(vec))
+	}
+
+	// Reconstrói o valor original a partir do int8 quantizado e confere que o
+	// erro fica dentro de 1,5 "degrau" de quantização (spread/255): int8()
+	// trunca em vez de arredondar, e o topo do intervalo satura em 127, o que
+	// custa até um degrau inteiro.
+	spread := 20.0
+	tolerance := spread/255*1.5 + 1e-9
+	for i, v := range vec {
+		reconstructed := (float64(quantized[i])+127)/255*spread + cal.Min[i]
+		if diff := math.Abs(reconstructed - v); diff > tolerance {
+			t.Errorf("component %d: original=%v quantized=%d reconstructed=%v, error %v exceeds tolerance %v", i, v, quantized[i], reconstructed, diff, tolerance)
+		}
+	}
+}
+
+func TestQuantizeInt8ClampsOutOfRangeValues(t *testing.T) {
+	cal := QuantizationCalibration{Min: []float64{0}, Max: []float64{10}}
+
+	// Um valor além do range calibrado ainda deve produzir um int8 válido,
+	// saturado em vez de estourar o tipo.
+	quantized := quantizeInt8([]float64{100}, cal)
+	if quantized[0] != 127 {
+		t.Errorf("quantizeInt8 for an out-of-range high value = %d, want clamped to 127", quantized[0])
+	}
+
+	quantized = quantizeInt8([]float64{-100}, cal)
+	if quantized[0] != -127 {
+		t.Errorf("quantizeInt8 for an out-of-range low value = %d, want clamped to -127", quantized[0])
+	}
+}
+
+func TestQuantizeInt8SelfCalibratesWhenCalibrationMissing(t *testing.T) {
+	// calibration vazia (zero value) força quantizeInt8 a usar o min/max do
+	// próprio vetor como fallback, conforme documentado.
+	quantized := quantizeInt8([]float64{-5, 0, 5}, QuantizationCalibration{})
+
+	if quantized[0] != -127 {
+		t.Errorf("self-calibrated min should map to -127, got %d", quantized[0])
+	}
+	if quantized[2] != 127 {
+		t.Errorf("self-calibrated max should map to 127, got %d", quantized[2])
+	}
+}
+
+func TestQuantizeBinaryEncodesSignBits(t *testing.T) {
+	vec := []float64{1, -1, 2, -2, 0, 0.5, -0.5, 3}
+
+	out, err := quantizeBinary(vec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1 {
+		t.Fatalf("quantizeBinary for 8 dims returned %d bytes, want 1", len(out))
+	}
+
+	// bit i é 1 sse vec[i] > 0 (0 e negativos viram 0).
+	want := byte(0)
+	for i, v := range vec {
+		if v > 0 {
+			want |= 1 << uint(i)
+		}
+	}
+	if out[0] != want {
+		t.Errorf("quantizeBinary(%v) = %08b, want %08b", vec, out[0], want)
+	}
+}
+
+func TestQuantizeBinaryRequiresDimensionMultipleOf8(t *testing.T) {
+	_, err := quantizeBinary([]float64{1, 2, 3})
+	if err == nil {
+		t.Error("quantizeBinary with a non-multiple-of-8 dimension should error, got nil")
+	}
+}
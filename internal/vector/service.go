@@ -4,158 +4,1851 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"strings"
 
 	sqlitevec "github.com/asg017/sqlite-vec-go-bindings/cgo"
 )
 
+// ErrNotFound é devolvido por UpdateEmbedding/UpdateMetadata quando
+// (contentType, contentID) não tem embedding gravado.
+var ErrNotFound = errors.New("vector: embedding não encontrado")
+
+// defaultBatchChunkSize é usado por StoreBatch/BatchUpsert quando
+// Config.BatchChunkSize é zero — grande o bastante para amortizar o
+// overhead de uma transação por item, pequeno o bastante para não esbarrar
+// no SQLITE_MAX_VARIABLE_NUMBER do processo mesmo em embeddings com
+// metadata grande.
+const defaultBatchChunkSize = 500
+
+// BatchError é devolvido por StoreBatch/BatchUpsert quando ao menos um item
+// do lote falhou — ao contrário dos demais métodos de Service, que abortam
+// a transação inteira no primeiro erro, StoreBatch/BatchUpsert gravam todo
+// item que não falhou e reportam os que falharam aqui, para o chamador
+// poder reenviar só esses (ex. um payload malformado isolado em meio a
+// milhares de itens válidos num pipeline de indexação).
+type BatchError struct {
+	// Failures mapeia o índice do item em embeddings (o slice passado para
+	// StoreBatch/BatchUpsert) para o erro ocorrido ao gravá-lo.
+	Failures map[int]error
+	// Total é o número de itens do lote original, para contextualizar
+	// len(Failures) na mensagem de Error.
+	Total int
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("vector: %d de %d itens do lote falharam", len(e.Failures), e.Total)
+}
+
+// batchChunkSize devolve Config.BatchChunkSize, ou defaultBatchChunkSize se
+// ele for zero.
+func batchChunkSize(cfg Config) int {
+	if cfg.BatchChunkSize > 0 {
+		return cfg.BatchChunkSize
+	}
+	return defaultBatchChunkSize
+}
+
+// Service opera sobre um Backend (ver backend.go) — na prática, quase
+// sempre um *Store (sqlite-vec), o único que implementa os recursos
+// avançados usados pela maioria dos métodos abaixo (Filter, IndexSpec,
+// FTS5/HybridSearch). Um Backend alternativo como MemoryBackend só serve o
+// caminho básico (Store/Search/SearchGlobal/Delete/Count); os demais
+// métodos devolvem erro via requireStore quando o Backend não é um *Store.
 type Service struct {
-	store *Store
+	backend Backend
+}
+
+func NewService(backend Backend) *Service {
+	return &Service{backend: backend}
+}
+
+// VectorStore devolve o *Store por trás deste Service, ou nil se ele foi
+// construído sobre outro Backend — útil para quem precisa chamar
+// Store.EnableIndex/RebuildIndex diretamente (ex. benchmarks comparando
+// IndexKind, ver BenchmarkVector_IndexComparison), recurso que só o backend
+// sqlite-vec oferece.
+func (s *Service) VectorStore() *Store {
+	store, _ := s.backend.(*Store)
+	return store
+}
+
+// requireStore devolve o *Store por trás deste Service, ou ok=false se ele
+// foi construído sobre outro Backend — usado pelos métodos que dependem de
+// recursos específicos do sqlite-vec (Filter, IndexSpec, FTS5), que não
+// fazem parte de Backend.
+func (s *Service) requireStore() (*Store, bool) {
+	store, ok := s.backend.(*Store)
+	return store, ok
+}
+
+// errRequiresStore formata o erro devolvido quando um método exige o
+// backend sqlite-vec (*Store) e o Service foi construído sobre outro
+// Backend.
+func (s *Service) errRequiresStore() error {
+	return fmt.Errorf("vector: esta operação exige o backend sqlite-vec (*Store); atual é %T", s.backend)
+}
+
+// Store grava embedding como uma linha nova, sem checar duplicata por
+// (ContentType, ContentID) (para isso, ver Upsert, que exige o backend
+// sqlite-vec). Com o backend sqlite-vec e embedding.Text não vazio, grava
+// vetor e texto na mesma transação (ver storeWithText); nos demais casos
+// delega direto a Backend.Insert, que qualquer Backend implementa e que não
+// indexa texto.
+func (s *Service) Store(ctx context.Context, embedding Embedding) (int64, error) {
+	if store, ok := s.requireStore(); ok && embedding.Text != "" {
+		return s.storeWithText(ctx, store, embedding)
+	}
+	return s.backend.Insert(ctx, embedding)
+}
+
+// storeWithText é o caminho usado por Store quando embedding.Text não é
+// vazio: grava o vetor e o texto na mesma transação, como Insert já faz
+// para quem passa o texto como parâmetro separado — sem isso, o rowid do
+// vetor e o da linha FTS5 correspondente poderiam divergir se o processo
+// morresse entre as duas escritas.
+func (s *Service) storeWithText(ctx context.Context, store *Store, embedding Embedding) (int64, error) {
+	metadataJSON, err := json.Marshal(embedding.Metadata)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	vectorBin, err := serializeVector(embedding.Vector)
+	if err != nil {
+		return 0, err
+	}
+
+	quantized, err := store.quantizeForColumn(embedding.Vector)
+	if err != nil {
+		return 0, err
+	}
+	qCol, qPlaceholder, qArgs := quantizedInsertColumn(quantized)
+
+	tx, err := store.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	insertQuery := fmt.Sprintf(`
+		INSERT INTO %s (content_type, content_id, embedding, metadata%s)
+		VALUES (?, ?, ?, ?%s)
+	`, store.Config().TableName, qCol, qPlaceholder)
+	insertArgs := append([]any{embedding.ContentType, embedding.ContentID, vectorBin, string(metadataJSON)}, qArgs...)
+	result, err := tx.ExecContext(ctx, insertQuery, insertArgs...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert embedding: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read inserted embedding id: %w", err)
+	}
+
+	ftsQuery := fmt.Sprintf(`INSERT INTO %s (rowid, content) VALUES (?, ?)`, store.ftsTableName())
+	if _, err := tx.ExecContext(ctx, ftsQuery, id, embedding.Text); err != nil {
+		return 0, fmt.Errorf("failed to index content: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit insert: %w", err)
+	}
+
+	if index := store.Index(); index != nil {
+		index.Insert(id, embedding.Vector)
+	}
+
+	return id, nil
+}
+
+// StoreBatch grava cada item de embeddings como uma linha nova (igual
+// Store, sem checar duplicata por ContentType/ContentID), dividido em
+// transações de até Config.BatchChunkSize itens (ver batchChunkSize) que
+// preparam o INSERT uma única vez por chunk em vez de repassá-lo por
+// ExecContext a cada linha. Um erro num item não aborta o chunk inteiro: os
+// demais itens continuam sendo gravados e o chunk é commitado; os itens que
+// falharam são agregados num *BatchError, com ids[i] == 0 para eles, para
+// que o chamador reenvie só os que falharam em vez de repetir o lote todo.
+func (s *Service) StoreBatch(ctx context.Context, embeddings []Embedding) ([]int64, error) {
+	if len(embeddings) == 0 {
+		return nil, nil
+	}
+
+	store, ok := s.requireStore()
+	if !ok {
+		return nil, s.errRequiresStore()
+	}
+
+	ids := make([]int64, len(embeddings))
+	failures := make(map[int]error)
+
+	chunkSize := batchChunkSize(store.Config())
+	for start := 0; start < len(embeddings); start += chunkSize {
+		end := start + chunkSize
+		if end > len(embeddings) {
+			end = len(embeddings)
+		}
+		if err := s.storeBatchChunk(ctx, store, embeddings[start:end], start, ids, failures); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(failures) > 0 {
+		return ids, &BatchError{Failures: failures, Total: len(embeddings)}
+	}
+	return ids, nil
+}
+
+func (s *Service) storeBatchChunk(ctx context.Context, store *Store, chunk []Embedding, offset int, ids []int64, failures map[int]error) error {
+	tx, err := store.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	quantizedCol, quantizedPlaceholder, _ := quantizedInsertColumn(quantizationPlaceholder(store))
+	insertStmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s (content_type, content_id, embedding, metadata%s)
+		VALUES (?, ?, ?, ?%s)
+	`, store.Config().TableName, quantizedCol, quantizedPlaceholder))
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer insertStmt.Close()
+
+	var ftsStmt *sql.Stmt
+	if batchHasText(chunk) {
+		ftsStmt, err = tx.PrepareContext(ctx, fmt.Sprintf(`INSERT INTO %s (rowid, content) VALUES (?, ?)`, store.ftsTableName()))
+		if err != nil {
+			return fmt.Errorf("failed to prepare fts insert: %w", err)
+		}
+		defer ftsStmt.Close()
+	}
+
+	for i, embedding := range chunk {
+		metadataJSON, err := json.Marshal(embedding.Metadata)
+		if err != nil {
+			failures[offset+i] = fmt.Errorf("failed to marshal metadata: %w", err)
+			continue
+		}
+		vectorBin, err := serializeVector(embedding.Vector)
+		if err != nil {
+			failures[offset+i] = err
+			continue
+		}
+		quantized, err := store.quantizeForColumn(embedding.Vector)
+		if err != nil {
+			failures[offset+i] = err
+			continue
+		}
+		_, _, qArgs := quantizedInsertColumn(quantized)
+
+		insertArgs := append([]any{embedding.ContentType, embedding.ContentID, vectorBin, string(metadataJSON)}, qArgs...)
+		result, err := insertStmt.ExecContext(ctx, insertArgs...)
+		if err != nil {
+			failures[offset+i] = fmt.Errorf("failed to insert embedding: %w", err)
+			continue
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			failures[offset+i] = fmt.Errorf("failed to read inserted embedding id: %w", err)
+			continue
+		}
+
+		if embedding.Text != "" {
+			if _, err := ftsStmt.ExecContext(ctx, id, embedding.Text); err != nil {
+				failures[offset+i] = fmt.Errorf("failed to index content: %w", err)
+				continue
+			}
+		}
+
+		ids[offset+i] = id
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit batch: %w", err)
+	}
+
+	if index := store.Index(); index != nil {
+		for i, embedding := range chunk {
+			if _, failed := failures[offset+i]; !failed {
+				index.Insert(ids[offset+i], embedding.Vector)
+			}
+		}
+	}
+
+	return nil
+}
+
+// batchHasText devolve true se ao menos um item de chunk tem Text não
+// vazio — usado por storeBatchChunk para não preparar (nem exigir) a
+// tabela FTS5 companheira quando o chunk inteiro é só vetor.
+func batchHasText(embeddings []Embedding) bool {
+	for _, e := range embeddings {
+		if e.Text != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Insert grava embedding e text em uma única transação: o vetor na tabela
+// vec0 e o texto na tabela FTS5 companheira criada por Store.EnsureTable,
+// sob o mesmo id — a perna textual de HybridSearch enxerga o documento
+// assim que a perna vetorial enxergar, sem uma janela em que um índice
+// tenha o documento e o outro ainda não.
+func (s *Service) Insert(ctx context.Context, contentType string, contentID int64, embedding []float64, text string, metadata map[string]any) (int64, error) {
+	store, ok := s.requireStore()
+	if !ok {
+		return 0, s.errRequiresStore()
+	}
+
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	vector32 := make([]float32, len(embedding))
+	for i, v := range embedding {
+		vector32[i] = float32(v)
+	}
+	vectorBin, err := sqlitevec.SerializeFloat32(vector32)
+	if err != nil {
+		return 0, fmt.Errorf("failed to serialize vector: %w", err)
+	}
+
+	quantized, err := store.quantizeForColumn(embedding)
+	if err != nil {
+		return 0, err
+	}
+	qCol, qPlaceholder, qArgs := quantizedInsertColumn(quantized)
+
+	tx, err := store.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	insertQuery := fmt.Sprintf(`
+		INSERT INTO %s (content_type, content_id, embedding, metadata%s)
+		VALUES (?, ?, ?, ?%s)
+	`, store.Config().TableName, qCol, qPlaceholder)
+
+	insertArgs := append([]any{contentType, contentID, vectorBin, string(metadataJSON)}, qArgs...)
+	result, err := tx.ExecContext(ctx, insertQuery, insertArgs...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert embedding: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read inserted embedding id: %w", err)
+	}
+
+	ftsQuery := fmt.Sprintf(`INSERT INTO %s (rowid, content) VALUES (?, ?)`, store.ftsTableName())
+	if _, err := tx.ExecContext(ctx, ftsQuery, id, text); err != nil {
+		return 0, fmt.Errorf("failed to index content: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit insert: %w", err)
+	}
+
+	if index := store.Index(); index != nil {
+		index.Insert(id, embedding)
+	}
+
+	return id, nil
+}
+
+// Upsert grava embedding, substituindo atomicamente qualquer linha
+// existente para o mesmo (ContentType, ContentID) em vez de inserir uma
+// segunda linha — ao contrário de Store, que sempre insere. Como a tabela
+// vec0 só tem um PRIMARY KEY de verdade em id (ON CONFLICT não tem como
+// casar por ContentType/ContentID), a transação primeiro procura o id
+// existente e decide entre UPDATE e INSERT a partir dele.
+func (s *Service) Upsert(ctx context.Context, embedding Embedding) (int64, error) {
+	store, ok := s.requireStore()
+	if !ok {
+		return 0, s.errRequiresStore()
+	}
+
+	metadataJSON, err := json.Marshal(embedding.Metadata)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	vectorBin, err := serializeVector(embedding.Vector)
+	if err != nil {
+		return 0, err
+	}
+
+	quantized, err := store.quantizeForColumn(embedding.Vector)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := store.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var existingID int64
+	lookupQuery := fmt.Sprintf(`SELECT id FROM %s WHERE content_type = ? AND content_id = ?`, store.Config().TableName)
+	err = tx.QueryRowContext(ctx, lookupQuery, embedding.ContentType, embedding.ContentID).Scan(&existingID)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to look up existing embedding: %w", err)
+	}
+
+	var id int64
+	if err == sql.ErrNoRows {
+		qCol, qPlaceholder, qArgs := quantizedInsertColumn(quantized)
+		insertQuery := fmt.Sprintf(`
+			INSERT INTO %s (content_type, content_id, embedding, metadata%s)
+			VALUES (?, ?, ?, ?%s)
+		`, store.Config().TableName, qCol, qPlaceholder)
+		insertArgs := append([]any{embedding.ContentType, embedding.ContentID, vectorBin, string(metadataJSON)}, qArgs...)
+		result, err := tx.ExecContext(ctx, insertQuery, insertArgs...)
+		if err != nil {
+			return 0, fmt.Errorf("failed to upsert embedding: %w", err)
+		}
+		id, err = result.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+		if embedding.Text != "" {
+			ftsQuery := fmt.Sprintf(`INSERT INTO %s (rowid, content) VALUES (?, ?)`, store.ftsTableName())
+			if _, err := tx.ExecContext(ctx, ftsQuery, id, embedding.Text); err != nil {
+				return 0, fmt.Errorf("failed to index content: %w", err)
+			}
+		}
+	} else {
+		id = existingID
+		qSet, qArgs := quantizedSetClause(quantized)
+		updateQuery := fmt.Sprintf(`
+			UPDATE %s SET embedding = ?, metadata = ?, updated_at = CURRENT_TIMESTAMP%s WHERE id = ?
+		`, store.Config().TableName, qSet)
+		updateArgs := append([]any{vectorBin, string(metadataJSON)}, qArgs...)
+		updateArgs = append(updateArgs, id)
+		if _, err := tx.ExecContext(ctx, updateQuery, updateArgs...); err != nil {
+			return 0, fmt.Errorf("failed to upsert embedding: %w", err)
+		}
+		if embedding.Text != "" {
+			// FTS5 não suporta UPSERT (ON CONFLICT) de verdade — INSERT OR
+			// REPLACE é a forma documentada de sobrescrever uma linha
+			// existente por rowid numa tabela virtual fts5.
+			ftsQuery := fmt.Sprintf(`INSERT OR REPLACE INTO %s (rowid, content) VALUES (?, ?)`, store.ftsTableName())
+			if _, err := tx.ExecContext(ctx, ftsQuery, id, embedding.Text); err != nil {
+				return 0, fmt.Errorf("failed to index content: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit upsert: %w", err)
+	}
+
+	if index := store.Index(); index != nil {
+		index.Insert(id, embedding.Vector)
+	}
+
+	return id, nil
+}
+
+// BatchUpsert faz o mesmo que Upsert para cada item de embeddings, dividido
+// em transações de até Config.BatchChunkSize itens (ver batchChunkSize) que
+// preparam lookup/insert/update uma única vez por chunk em vez de
+// repassá-los por ExecContext a cada linha — reduz drasticamente o custo
+// por item quando há muitos (ver BenchmarkVector_BatchUpsert em
+// test/benchmarks, cujo loop de Upsert individuais esta função substitui).
+// Um erro num item não aborta o chunk inteiro: os demais itens continuam
+// sendo gravados e o chunk é commitado; os itens que falharam são
+// agregados num *BatchError, com ids[i] == 0 para eles, para que o
+// chamador reenvie só os que falharam em vez de repetir o lote todo.
+func (s *Service) BatchUpsert(ctx context.Context, embeddings []Embedding) ([]int64, error) {
+	if len(embeddings) == 0 {
+		return nil, nil
+	}
+
+	store, ok := s.requireStore()
+	if !ok {
+		return nil, s.errRequiresStore()
+	}
+
+	ids := make([]int64, len(embeddings))
+	failures := make(map[int]error)
+
+	chunkSize := batchChunkSize(store.Config())
+	for start := 0; start < len(embeddings); start += chunkSize {
+		end := start + chunkSize
+		if end > len(embeddings) {
+			end = len(embeddings)
+		}
+		if err := s.batchUpsertChunk(ctx, store, embeddings[start:end], start, ids, failures); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(failures) > 0 {
+		return ids, &BatchError{Failures: failures, Total: len(embeddings)}
+	}
+	return ids, nil
+}
+
+func (s *Service) batchUpsertChunk(ctx context.Context, store *Store, chunk []Embedding, offset int, ids []int64, failures map[int]error) error {
+	tx, err := store.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	lookupStmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`SELECT id FROM %s WHERE content_type = ? AND content_id = ?`, store.Config().TableName))
+	if err != nil {
+		return fmt.Errorf("failed to prepare lookup: %w", err)
+	}
+	defer lookupStmt.Close()
+
+	quantizedCol, quantizedPlaceholder, _ := quantizedInsertColumn(quantizationPlaceholder(store))
+	insertStmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`
+		INSERT INTO %s (content_type, content_id, embedding, metadata%s)
+		VALUES (?, ?, ?, ?%s)
+	`, store.Config().TableName, quantizedCol, quantizedPlaceholder))
+	if err != nil {
+		return fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer insertStmt.Close()
+
+	quantizedSet, _ := quantizedSetClause(quantizationPlaceholder(store))
+	updateStmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`
+		UPDATE %s SET embedding = ?, metadata = ?, updated_at = CURRENT_TIMESTAMP%s WHERE id = ?
+	`, store.Config().TableName, quantizedSet))
+	if err != nil {
+		return fmt.Errorf("failed to prepare update: %w", err)
+	}
+	defer updateStmt.Close()
+
+	var ftsInsertStmt, ftsReplaceStmt *sql.Stmt
+	if batchHasText(chunk) {
+		ftsInsertStmt, err = tx.PrepareContext(ctx, fmt.Sprintf(`INSERT INTO %s (rowid, content) VALUES (?, ?)`, store.ftsTableName()))
+		if err != nil {
+			return fmt.Errorf("failed to prepare fts insert: %w", err)
+		}
+		defer ftsInsertStmt.Close()
+
+		// FTS5 não suporta UPSERT (ON CONFLICT) de verdade — INSERT OR
+		// REPLACE é a forma documentada de sobrescrever uma linha
+		// existente por rowid numa tabela virtual fts5.
+		ftsReplaceStmt, err = tx.PrepareContext(ctx, fmt.Sprintf(`INSERT OR REPLACE INTO %s (rowid, content) VALUES (?, ?)`, store.ftsTableName()))
+		if err != nil {
+			return fmt.Errorf("failed to prepare fts replace: %w", err)
+		}
+		defer ftsReplaceStmt.Close()
+	}
+
+	for i, embedding := range chunk {
+		metadataJSON, err := json.Marshal(embedding.Metadata)
+		if err != nil {
+			failures[offset+i] = fmt.Errorf("failed to marshal metadata: %w", err)
+			continue
+		}
+		vectorBin, err := serializeVector(embedding.Vector)
+		if err != nil {
+			failures[offset+i] = err
+			continue
+		}
+		quantized, err := store.quantizeForColumn(embedding.Vector)
+		if err != nil {
+			failures[offset+i] = err
+			continue
+		}
+
+		var existingID int64
+		err = lookupStmt.QueryRowContext(ctx, embedding.ContentType, embedding.ContentID).Scan(&existingID)
+
+		var id int64
+		var ftsStmt *sql.Stmt
+		switch {
+		case err == sql.ErrNoRows:
+			_, _, qArgs := quantizedInsertColumn(quantized)
+			insertArgs := append([]any{embedding.ContentType, embedding.ContentID, vectorBin, string(metadataJSON)}, qArgs...)
+			result, err := insertStmt.ExecContext(ctx, insertArgs...)
+			if err != nil {
+				failures[offset+i] = fmt.Errorf("failed to upsert embedding: %w", err)
+				continue
+			}
+			id, err = result.LastInsertId()
+			if err != nil {
+				failures[offset+i] = fmt.Errorf("failed to read inserted embedding id: %w", err)
+				continue
+			}
+			ftsStmt = ftsInsertStmt
+		case err != nil:
+			failures[offset+i] = fmt.Errorf("failed to look up existing embedding: %w", err)
+			continue
+		default:
+			id = existingID
+			_, qArgs := quantizedSetClause(quantized)
+			updateArgs := append([]any{vectorBin, string(metadataJSON)}, qArgs...)
+			updateArgs = append(updateArgs, id)
+			if _, err := updateStmt.ExecContext(ctx, updateArgs...); err != nil {
+				failures[offset+i] = fmt.Errorf("failed to upsert embedding: %w", err)
+				continue
+			}
+			ftsStmt = ftsReplaceStmt
+		}
+
+		if embedding.Text != "" {
+			if _, err := ftsStmt.ExecContext(ctx, id, embedding.Text); err != nil {
+				failures[offset+i] = fmt.Errorf("failed to index content: %w", err)
+				continue
+			}
+		}
+
+		ids[offset+i] = id
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit batch upsert: %w", err)
+	}
+
+	if index := store.Index(); index != nil {
+		for i, embedding := range chunk {
+			if _, failed := failures[offset+i]; !failed {
+				index.Insert(ids[offset+i], embedding.Vector)
+			}
+		}
+	}
+
+	return nil
+}
+
+// UpdateEmbedding substitui só o vetor de (contentType, contentID),
+// preservando metadata e o id original — ao contrário de deletar e
+// reinserir, que perderia created_at e exigiria duas viagens ao banco.
+// Retorna ErrNotFound se não houver embedding para (contentType, contentID).
+func (s *Service) UpdateEmbedding(ctx context.Context, contentType string, contentID int64, newVector []float64) error {
+	store, ok := s.requireStore()
+	if !ok {
+		return s.errRequiresStore()
+	}
+
+	existing, err := s.GetByContent(ctx, contentType, contentID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return ErrNotFound
+	}
+
+	vectorBin, err := serializeVector(newVector)
+	if err != nil {
+		return err
+	}
+
+	quantized, err := store.quantizeForColumn(newVector)
+	if err != nil {
+		return err
+	}
+	qSet, qArgs := quantizedSetClause(quantized)
+
+	query := fmt.Sprintf(`
+		UPDATE %s SET embedding = ?, updated_at = CURRENT_TIMESTAMP%s WHERE id = ?
+	`, store.Config().TableName, qSet)
+	args := append([]any{vectorBin}, qArgs...)
+	args = append(args, existing.ID)
+	if _, err := store.DB().ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to update embedding: %w", err)
+	}
+
+	if index := store.Index(); index != nil {
+		index.Insert(existing.ID, newVector)
+	}
+
+	return nil
 }
 
-func NewService(store *Store) *Service {
-	return &Service{store: store}
+// UpdateMetadata aplica patch sobre a metadata de (contentType, contentID)
+// com semântica de JSON merge patch (RFC 7396): chaves ausentes em patch
+// preservam o valor atual, chaves presentes sobrescrevem, e um valor nil
+// remove a chave. Retorna ErrNotFound se não houver embedding para
+// (contentType, contentID).
+func (s *Service) UpdateMetadata(ctx context.Context, contentType string, contentID int64, patch map[string]any) error {
+	store, ok := s.requireStore()
+	if !ok {
+		return s.errRequiresStore()
+	}
+
+	existing, err := s.GetByContent(ctx, contentType, contentID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return ErrNotFound
+	}
+
+	merged := make(map[string]any, len(existing.Metadata)+len(patch))
+	for k, v := range existing.Metadata {
+		merged[k] = v
+	}
+	for k, v := range patch {
+		if v == nil {
+			delete(merged, k)
+			continue
+		}
+		merged[k] = v
+	}
+
+	metadataJSON, err := json.Marshal(merged)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE %s SET metadata = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+	`, store.Config().TableName)
+	if _, err := store.DB().ExecContext(ctx, query, string(metadataJSON), existing.ID); err != nil {
+		return fmt.Errorf("failed to update metadata: %w", err)
+	}
+
+	return nil
+}
+
+// serializeVector converte vec de float64 para o formato binário float32
+// que a extensão sqlite-vec espera na coluna embedding — compartilhado por
+// Upsert/BatchUpsert/UpdateEmbedding para não repetir a conversão feita
+// também por Store/Insert.
+func serializeVector(vec []float64) ([]byte, error) {
+	vector32 := make([]float32, len(vec))
+	for i, v := range vec {
+		vector32[i] = float32(v)
+	}
+	bin, err := sqlitevec.SerializeFloat32(vector32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize vector: %w", err)
+	}
+	return bin, nil
+}
+
+// Search busca os limit vizinhos mais próximos de queryVector dentro de
+// contentType. filter, se não nil (ver Filter em filter.go), é compilado
+// numa cláusula WHERE e empurrado para dentro da mesma query k-NN — ao
+// contrário de filtrar os resultados depois de trazer o top-k, o que perde
+// vizinhos relevantes que só entrariam no top-k se o filtro já tivesse sido
+// aplicado (ver BenchmarkVector_WithMetadataFilter em test/benchmarks).
+// filter pode ser nil para manter o comportamento anterior. Se
+// Store.EnableIndex foi chamado para contentType, usa o índice em memória
+// correspondente (ver searchViaIndex) em vez do full scan via sqlite-vec;
+// params ajusta essa busca aproximada (ver SearchParams) e é ignorado
+// quando nenhum índice está habilitado para contentType.
+func (s *Service) Search(ctx context.Context, contentType string, queryVector []float64, limit int, metric DistanceMetric, filter Filter, params SearchParams) ([]SearchResult, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	store, ok := s.requireStore()
+	if !ok {
+		if filter != nil || params != (SearchParams{}) {
+			return nil, s.errRequiresStore()
+		}
+		return s.backend.Search(ctx, contentType, queryVector, limit, metric)
+	}
+
+	if index := store.IndexFor(contentType); index != nil {
+		return s.searchViaIndex(ctx, index, queryVector, limit, filter, params)
+	}
+
+	distanceFunc := distanceFuncSQL(metric)
+
+	whereClause := "WHERE content_type = ?"
+	args := []any{}
+	if filter != nil {
+		clause, filterArgs, err := filter.compile(store.Config().allowedFilterKeys())
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter: %w", err)
+		}
+		whereClause += " AND " + clause
+		args = append(args, filterArgs...)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			id, content_type, content_id, vec_to_json(embedding) as embedding, metadata, updated_at,
+			%s(embedding, ?) as distance
+		FROM %s
+		%s
+		ORDER BY distance
+		LIMIT ?
+	`, distanceFunc, store.Config().TableName, whereClause)
+
+	// Serializar queryVector para formato binário
+	queryVector32 := make([]float32, len(queryVector))
+	for i, v := range queryVector {
+		queryVector32[i] = float32(v)
+	}
+	queryVectorBin, err := sqlitevec.SerializeFloat32(queryVector32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize query vector: %w", err)
+	}
+
+	queryArgs := append([]any{queryVectorBin, contentType}, args...)
+	queryArgs = append(queryArgs, limit)
+
+	rows, err := store.DB().QueryContext(ctx, query, queryArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var e Embedding
+		var embeddingJSON string
+		var metadataJSON string
+		var distance float64
+
+		err := rows.Scan(
+			&e.ID,
+			&e.ContentType,
+			&e.ContentID,
+			&embeddingJSON,
+			&metadataJSON,
+			&e.UpdatedAt,
+			&distance,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if err := json.Unmarshal([]byte(embeddingJSON), &e.Vector); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal vector: %w", err)
+		}
+
+		if metadataJSON != "" {
+			if err := json.Unmarshal([]byte(metadataJSON), &e.Metadata); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+			}
+		}
+
+		results = append(results, SearchResult{
+			Embedding:  e,
+			Similarity: distance,
+		})
+	}
+
+	return results, nil
+}
+
+// SearchGlobal busca os vetores mais próximos de queryVector em toda a
+// tabela, sem filtrar por content_type. Quando Store.EnableHNSW/EnableIndex
+// foi chamado para o índice global, usa esse índice em memória (muito mais
+// rápido acima de ~100k vetores, ver searchViaIndex e params); caso
+// contrário cai para o full scan via sqlite-vec.
+func (s *Service) SearchGlobal(ctx context.Context, queryVector []float64, limit int, metric DistanceMetric, filter Filter, params SearchParams) ([]SearchResult, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	store, ok := s.requireStore()
+	if !ok {
+		if filter != nil || params != (SearchParams{}) {
+			return nil, s.errRequiresStore()
+		}
+		return s.backend.SearchGlobal(ctx, queryVector, limit, metric)
+	}
+
+	if index := store.Index(); index != nil {
+		return s.searchViaIndex(ctx, index, queryVector, limit, filter, params)
+	}
+
+	distanceFunc := distanceFuncSQL(metric)
+
+	whereClause := ""
+	args := []any{}
+	if filter != nil {
+		clause, filterArgs, err := filter.compile(store.Config().allowedFilterKeys())
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter: %w", err)
+		}
+		whereClause = "WHERE " + clause
+		args = append(args, filterArgs...)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			id, content_type, content_id, vec_to_json(embedding) as embedding, metadata, updated_at,
+			%s(embedding, ?) as distance
+		FROM %s
+		%s
+		ORDER BY distance
+		LIMIT ?
+	`, distanceFunc, store.Config().TableName, whereClause)
+
+	// Serializar queryVector para formato binário
+	queryVector32 := make([]float32, len(queryVector))
+	for i, v := range queryVector {
+		queryVector32[i] = float32(v)
+	}
+	queryVectorBin, err := sqlitevec.SerializeFloat32(queryVector32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize query vector: %w", err)
+	}
+
+	queryArgs := append([]any{queryVectorBin}, args...)
+	queryArgs = append(queryArgs, limit)
+
+	rows, err := store.DB().QueryContext(ctx, query, queryArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var e Embedding
+		var embeddingJSON string
+		var metadataJSON string
+		var distance float64
+
+		err := rows.Scan(
+			&e.ID,
+			&e.ContentType,
+			&e.ContentID,
+			&embeddingJSON,
+			&metadataJSON,
+			&e.UpdatedAt,
+			&distance,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if err := json.Unmarshal([]byte(embeddingJSON), &e.Vector); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal vector: %w", err)
+		}
+
+		if metadataJSON != "" {
+			if err := json.Unmarshal([]byte(metadataJSON), &e.Metadata); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+			}
+		}
+
+		results = append(results, SearchResult{
+			Embedding:  e,
+			Similarity: distance,
+		})
+	}
+
+	return results, nil
+}
+
+// hnswOverFetchFactor e hnswMaxOverFetchFactor controlam quantos candidatos
+// a mais que limit searchViaIndex pede ao índice quando há um filter: nem
+// HNSWIndex nem IVFFlatIndex sabem filtrar por metadata (eles só enxergam
+// vetores), então um filtro só pode ser aplicado depois de hidratar cada
+// candidato — pedir exatamente limit candidatos e filtrar em cima erraria o
+// mesmo jeito que BenchmarkVector_WithMetadataFilter errava antes desta
+// mudança. Em vez disso, busca-se k'=limit*hnswOverFetchFactor, dobrando
+// até hnswMaxOverFetchFactor enquanto não houver candidatos suficientes ou
+// o índice se esgotar.
+const (
+	hnswOverFetchFactor    = 4
+	hnswMaxOverFetchFactor = 32
+)
+
+// searchViaIndex resolve os top-k ids via um índice em memória (HNSW ou IVF
+// Flat, ver Store.EnableIndex) e depois busca as linhas completas
+// (embedding/metadata) no SQLite, na ordem de distância já calculada pelo
+// índice. Sem filter, busca exatamente limit candidatos; com filter,
+// super-busca e itera (ver hnswOverFetchFactor) até acumular limit
+// candidatos que combinem com filter ou esgotar o índice.
+func (s *Service) searchViaIndex(ctx context.Context, index annIndex, queryVector []float64, limit int, filter Filter, params SearchParams) ([]SearchResult, error) {
+	if filter == nil {
+		return s.hydrateHNSWCandidates(ctx, index.Search(queryVector, limit, params), nil)
+	}
+
+	for overFetch := hnswOverFetchFactor; ; overFetch *= 2 {
+		fetchLimit := limit * overFetch
+		candidates := index.Search(queryVector, fetchLimit, params)
+
+		results, err := s.hydrateHNSWCandidates(ctx, candidates, filter)
+		if err != nil {
+			return nil, err
+		}
+		if len(results) > limit {
+			results = results[:limit]
+		}
+
+		if len(results) >= limit || len(candidates) < fetchLimit || overFetch >= hnswMaxOverFetchFactor {
+			return results, nil
+		}
+	}
+}
+
+// hydrateHNSWCandidates busca a linha completa de cada candidato e, se
+// filter não for nil, descarta os que não combinam com filter.matches.
+func (s *Service) hydrateHNSWCandidates(ctx context.Context, candidates []hnswCandidate, filter Filter) ([]SearchResult, error) {
+	results := make([]SearchResult, 0, len(candidates))
+	for _, c := range candidates {
+		e, err := s.getByID(ctx, c.id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hydrate hnsw result %d: %w", c.id, err)
+		}
+		if e == nil {
+			continue
+		}
+		if filter != nil && !filter.matches(e.Metadata) {
+			continue
+		}
+		results = append(results, SearchResult{Embedding: *e, Similarity: c.dist})
+	}
+
+	return results, nil
+}
+
+func (s *Service) getByID(ctx context.Context, id int64) (*Embedding, error) {
+	store, ok := s.requireStore()
+	if !ok {
+		return nil, s.errRequiresStore()
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, content_type, content_id, vec_to_json(embedding) as embedding, metadata, updated_at
+		FROM %s WHERE id = ?
+	`, store.Config().TableName)
+
+	var e Embedding
+	var embeddingJSON string
+	var metadataJSON string
+
+	err := store.DB().QueryRowContext(ctx, query, id).Scan(
+		&e.ID, &e.ContentType, &e.ContentID, &embeddingJSON, &metadataJSON, &e.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get embedding by id: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(embeddingJSON), &e.Vector); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal vector: %w", err)
+	}
+	if metadataJSON != "" {
+		if err := json.Unmarshal([]byte(metadataJSON), &e.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+		}
+	}
+
+	return &e, nil
+}
+
+// Delete remove o embedding de contentType/contentID de TableName e, se
+// existir, a linha correspondente na tabela FTS5 companheira — ver Insert.
+func (s *Service) Delete(ctx context.Context, contentType string, contentID int64) error {
+	store, ok := s.requireStore()
+	if !ok {
+		return s.backend.Delete(ctx, contentType, contentID)
+	}
+
+	existing, err := s.GetByContent(ctx, contentType, contentID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := store.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE content_type = ? AND content_id = ?`, store.Config().TableName)
+	if _, err := tx.ExecContext(ctx, query, contentType, contentID); err != nil {
+		return fmt.Errorf("failed to delete embedding: %w", err)
+	}
+
+	if existing != nil {
+		ftsQuery := fmt.Sprintf(`DELETE FROM %s WHERE rowid = ?`, store.ftsTableName())
+		if _, err := tx.ExecContext(ctx, ftsQuery, existing.ID); err != nil {
+			return fmt.Errorf("failed to delete indexed content: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit delete: %w", err)
+	}
+
+	if existing != nil {
+		if index := store.Index(); index != nil {
+			index.Delete(existing.ID)
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) DeleteByIDs(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	store, ok := s.requireStore()
+	if !ok {
+		return s.errRequiresStore()
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+
+	query := fmt.Sprintf(`
+		DELETE FROM %s WHERE id IN (%s)
+	`, store.Config().TableName, strings.Join(placeholders, ","))
+
+	if _, err := store.DB().ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+
+	if index := store.Index(); index != nil {
+		for _, id := range ids {
+			index.Delete(id)
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) GetByContent(ctx context.Context, contentType string, contentID int64) (*Embedding, error) {
+	store, ok := s.requireStore()
+	if !ok {
+		return nil, s.errRequiresStore()
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, content_type, content_id, embedding, metadata, updated_at
+		FROM %s WHERE content_type = ? AND content_id = ?
+	`, store.Config().TableName)
+
+	var e Embedding
+	var embeddingJSON string
+	var metadataJSON string
+
+	err := store.DB().QueryRowContext(ctx, query, contentType, contentID).Scan(
+		&e.ID,
+		&e.ContentType,
+		&e.ContentID,
+		&embeddingJSON,
+		&metadataJSON,
+		&e.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get embedding: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(embeddingJSON), &e.Vector); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal vector: %w", err)
+	}
+
+	if metadataJSON != "" {
+		if err := json.Unmarshal([]byte(metadataJSON), &e.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+		}
+	}
+
+	return &e, nil
+}
+
+func (s *Service) Count(ctx context.Context, contentType string) (int, error) {
+	store, ok := s.requireStore()
+	if !ok {
+		return s.backend.Count(ctx, contentType)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) FROM %s WHERE content_type = ?
+	`, store.Config().TableName)
+
+	var count int
+	err := store.DB().QueryRowContext(ctx, query, contentType).Scan(&count)
+	return count, err
+}
+
+// SearchQuery parametriza HybridSearch. Text e Vector podem ser informados
+// juntos (busca híbrida) ou isoladamente (busca só textual ou só vetorial).
+// FilterByContentType e FilterByMetadata restringem a perna vetorial via
+// cláusulas WHERE — content_type já é indexado por Store.EnsureTable, e
+// FilterByMetadata vira uma ou mais comparações json_extract(metadata, ...)
+// sobre a coluna metadata. FTSTable aponta a perna textual para uma tabela
+// FTS5 diferente da companheira que o próprio Store gerencia (ver
+// Insert/Delete) — por exemplo uma provisionada via EnsureFTS5Companion
+// sobre uma tabela de conteúdo que não passa por Service.Insert; vazio usa
+// a tabela companheira de sempre. Nesse caso os rowids devolvidos pertencem
+// à tabela de conteúdo externa, não à tabela de embeddings — um hit que não
+// tiver um id de embedding correspondente (getByID não encontra) é
+// descartado da perna textual em vez de hidratado incorretamente. Alpha
+// pondera as duas pernas na fusão (ver
+// HybridSearch); zero usa 0.5 (peso igual), o mesmo valor padrão que
+// internal/search.HybridOptions usa para seus pesos.
+type SearchQuery struct {
+	Text   string
+	Vector []float64
+
+	FilterByContentType string
+	FilterByMetadata     map[string]string
+
+	FTSTable string
+	Alpha    float64
+
+	Limit  int
+	Metric DistanceMetric
+}
+
+// Hit é um documento combinado pela fusão de rankings de HybridSearch.
+type Hit struct {
+	ID          int64
+	ContentType string
+	ContentID   int64
+	Score       float64
+	FTSRank     int
+	VectorRank  int
+}
+
+const hybridRRFConstant = 60
+
+// HybridSearch roda a busca vetorial (k-NN contra a tabela vec0, com os
+// filtros de SearchQuery) e a busca textual (BM25 contra query.FTSTable, ou
+// a tabela FTS5 companheira se vazio) e funde os dois rankings com
+// Reciprocal Rank Fusion ponderado: score(d) = alpha/(hybridRRFConstant +
+// rank_vec(d)) + (1-alpha)/(hybridRRFConstant + rank_fts(d)), somado sobre
+// cada ranker em que d aparece (rank_i começando em 1; um ranker que não
+// devolveu d não contribui, o equivalente a rank +∞) — a mesma fórmula
+// usada por internal/search.Searcher.Hybrid, aqui reimplementada porque
+// aquele pacote já importa vector e um import de volta causaria um ciclo.
+func (s *Service) HybridSearch(ctx context.Context, query SearchQuery) ([]Hit, error) {
+	store, ok := s.requireStore()
+	if !ok {
+		return nil, s.errRequiresStore()
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	fanout := limit * 4
+	if fanout < 20 {
+		fanout = 20
+	}
+	alpha := query.Alpha
+	if alpha == 0 {
+		alpha = 0.5
+	}
+
+	byID := make(map[int64]*Hit)
+
+	if len(query.Vector) > 0 {
+		vectorHits, err := s.searchVectorFiltered(ctx, store, query, fanout)
+		if err != nil {
+			return nil, fmt.Errorf("vector search failed: %w", err)
+		}
+		for rank, hit := range vectorHits {
+			h := byID[hit.ID]
+			if h == nil {
+				h = &Hit{ID: hit.ID, ContentType: hit.ContentType, ContentID: hit.ContentID}
+				byID[hit.ID] = h
+			}
+			h.VectorRank = rank + 1
+			h.Score += alpha / float64(hybridRRFConstant+rank+1)
+		}
+	}
+
+	if query.Text != "" {
+		ftsTable := query.FTSTable
+		if ftsTable == "" {
+			ftsTable = store.ftsTableName()
+		}
+		ftsIDs, err := s.searchFTS(ctx, store, ftsTable, query.Text, fanout)
+		if err != nil {
+			return nil, fmt.Errorf("fts search failed: %w", err)
+		}
+		for rank, id := range ftsIDs {
+			h := byID[id]
+			if h == nil {
+				e, err := s.getByID(ctx, id)
+				if err != nil {
+					return nil, err
+				}
+				if e == nil {
+					continue
+				}
+				h = &Hit{ID: e.ID, ContentType: e.ContentType, ContentID: e.ContentID}
+				byID[id] = h
+			}
+			h.FTSRank = rank + 1
+			h.Score += (1 - alpha) / float64(hybridRRFConstant+rank+1)
+		}
+	}
+
+	hits := make([]Hit, 0, len(byID))
+	for _, h := range byID {
+		hits = append(hits, *h)
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	return hits, nil
+}
+
+// searchVectorFiltered roda a perna k-NN de HybridSearch, traduzindo
+// FilterByContentType e FilterByMetadata em cláusulas WHERE.
+func (s *Service) searchVectorFiltered(ctx context.Context, store *Store, query SearchQuery, limit int) ([]Embedding, error) {
+	distanceFunc := distanceFuncSQL(query.Metric)
+
+	vector32 := make([]float32, len(query.Vector))
+	for i, v := range query.Vector {
+		vector32[i] = float32(v)
+	}
+	vectorBin, err := sqlitevec.SerializeFloat32(vector32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize query vector: %w", err)
+	}
+
+	var conditions []string
+	args := []any{vectorBin}
+
+	if query.FilterByContentType != "" {
+		conditions = append(conditions, "content_type = ?")
+		args = append(args, query.FilterByContentType)
+	}
+	for key, value := range query.FilterByMetadata {
+		conditions = append(conditions, fmt.Sprintf("json_extract(metadata, '$.%s') = ?", key))
+		args = append(args, value)
+	}
+	args = append(args, limit)
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	sqlQuery := fmt.Sprintf(`
+		SELECT id, content_type, content_id, %s(embedding, ?) as distance
+		FROM %s
+		%s
+		ORDER BY distance
+		LIMIT ?
+	`, distanceFunc, store.Config().TableName, whereClause)
+
+	rows, err := store.DB().QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	var results []Embedding
+	for rows.Next() {
+		var e Embedding
+		var distance float64
+		if err := rows.Scan(&e.ID, &e.ContentType, &e.ContentID, &distance); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		results = append(results, e)
+	}
+	return results, rows.Err()
+}
+
+// searchFTS roda a perna textual de HybridSearch contra a tabela FTS5
+// companheira, ordenada por bm25() ascendente (menor é mais relevante).
+func (s *Service) searchFTS(ctx context.Context, store *Store, tableName, text string, limit int) ([]int64, error) {
+	sqlQuery := fmt.Sprintf(`
+		SELECT rowid FROM %s WHERE %s MATCH ? ORDER BY bm25(%s) LIMIT ?
+	`, tableName, tableName, tableName)
+
+	rows, err := store.DB().QueryContext(ctx, sqlQuery, text, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search indexed content: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// scoredID é um par (id, score bruto) devolvido por vectorScored/ftsScored,
+// onde menor é sempre melhor (distância do sqlite-vec ou peso bm25) — ver
+// normalizeAscending, que converte essa lista para similaridade em [0,1].
+type scoredID struct {
+	id    int64
+	score float64
+}
+
+// vectorScored roda a perna k-NN de SearchHybrid contra contentType,
+// mantendo a distância bruta de cada resultado (ao contrário de
+// searchVectorFiltered, que descarta a distância depois de ordenar por
+// ela — HybridSearch só precisa do rank, SearchHybrid precisa do score
+// para normalizar min-max).
+func (s *Service) vectorScored(ctx context.Context, store *Store, contentType string, queryVector []float64, limit int) ([]scoredID, error) {
+	vectorBin, err := serializeVector(queryVector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize query vector: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, %s(embedding, ?) as distance
+		FROM %s
+		WHERE content_type = ?
+		ORDER BY distance
+		LIMIT ?
+	`, distanceFuncSQL(DistanceCosine), store.Config().TableName)
+
+	rows, err := store.DB().QueryContext(ctx, query, vectorBin, contentType, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	var items []scoredID
+	for rows.Next() {
+		var item scoredID
+		if err := rows.Scan(&item.id, &item.score); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
 }
 
-func (s *Service) Store(ctx context.Context, embedding Embedding) (int64, error) {
-	metadataJSON, err := json.Marshal(embedding.Metadata)
+// ftsScored roda a perna textual de SearchHybrid contra a tabela FTS5
+// companheira, mantendo o peso bm25() bruto de cada resultado (menor é
+// melhor, como a distância de vectorScored) em vez de só o rank.
+func (s *Service) ftsScored(ctx context.Context, store *Store, text string, limit int) ([]scoredID, error) {
+	tableName := store.ftsTableName()
+	query := fmt.Sprintf(`
+		SELECT rowid, bm25(%s) FROM %s WHERE %s MATCH ? ORDER BY bm25(%s) LIMIT ?
+	`, tableName, tableName, tableName, tableName)
+
+	rows, err := store.DB().QueryContext(ctx, query, text, limit)
 	if err != nil {
-		return 0, fmt.Errorf("failed to marshal metadata: %w", err)
+		return nil, fmt.Errorf("failed to search indexed content: %w", err)
 	}
+	defer rows.Close()
 
-	// Converter float64 para float32 e serializar
-	vector32 := make([]float32, len(embedding.Vector))
-	for i, v := range embedding.Vector {
-		vector32[i] = float32(v)
+	var items []scoredID
+	for rows.Next() {
+		var item scoredID
+		if err := rows.Scan(&item.id, &item.score); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		items = append(items, item)
 	}
+	return items, rows.Err()
+}
 
-	vectorBin, err := sqlitevec.SerializeFloat32(vector32)
-	if err != nil {
-		return 0, fmt.Errorf("failed to serialize vector: %w", err)
+// normalizeAscending converte scores onde menor é melhor (distância
+// sqlite-vec, peso bm25) em similaridade min-max normalizada em [0,1], onde
+// maior é melhor — o que SearchHybrid soma ponderado por alpha. Uma lista
+// com spread zero (um único resultado, ou todos empatados) vira 1.0 para
+// todo mundo, em vez de dividir por zero.
+func normalizeAscending(items []scoredID) map[int64]float64 {
+	norm := make(map[int64]float64, len(items))
+	if len(items) == 0 {
+		return norm
 	}
 
-	query := fmt.Sprintf(`
-		INSERT INTO %s (content_type, content_id, embedding, metadata)
-		VALUES (?, ?, ?, ?)
-	`, s.store.Config().TableName)
-
-	result, err := s.store.DB().ExecContext(ctx, query,
-		embedding.ContentType,
-		embedding.ContentID,
-		vectorBin,
-		string(metadataJSON),
-	)
-	if err != nil {
-		return 0, fmt.Errorf("failed to insert embedding: %w", err)
+	min, max := items[0].score, items[0].score
+	for _, item := range items {
+		if item.score < min {
+			min = item.score
+		}
+		if item.score > max {
+			max = item.score
+		}
 	}
 
-	return result.LastInsertId()
+	spread := max - min
+	for _, item := range items {
+		if spread == 0 {
+			norm[item.id] = 1
+			continue
+		}
+		norm[item.id] = 1 - (item.score-min)/spread
+	}
+	return norm
 }
 
-func (s *Service) Upsert(ctx context.Context, embedding Embedding) (int64, error) {
-	metadataJSON, err := json.Marshal(embedding.Metadata)
-	if err != nil {
-		return 0, fmt.Errorf("failed to marshal metadata: %w", err)
+// SearchHybrid funde busca vetorial e BM25 por combinação linear de scores
+// normalizados min-max: score(d) = alpha*vecScore(d) + (1-alpha)*bm25Score(d)
+// (zero usa 0.5, peso igual). Ao contrário de HybridSearch/SearchRRF, que
+// fundem por Reciprocal Rank Fusion e por isso só enxergam a posição de
+// cada documento em cada ranking, esta combinação preserva a magnitude da
+// vantagem de um documento sobre outro — mais sensível a outliers de escala
+// entre as duas pernas (ver SearchRRF) mas mais informativa quando as duas
+// distribuições são parecidas.
+func (s *Service) SearchHybrid(ctx context.Context, contentType, queryText string, queryVector []float64, limit int, alpha float64) ([]SearchResult, error) {
+	store, ok := s.requireStore()
+	if !ok {
+		return nil, s.errRequiresStore()
+	}
+
+	if limit <= 0 {
+		limit = 10
+	}
+	if alpha == 0 {
+		alpha = 0.5
+	}
+	fanout := limit * 4
+	if fanout < 20 {
+		fanout = 20
+	}
+
+	vecScores := make(map[int64]float64)
+	if len(queryVector) > 0 {
+		vecItems, err := s.vectorScored(ctx, store, contentType, queryVector, fanout)
+		if err != nil {
+			return nil, fmt.Errorf("vector search failed: %w", err)
+		}
+		vecScores = normalizeAscending(vecItems)
+	}
+
+	ftsScoresByID := make(map[int64]float64)
+	if queryText != "" {
+		ftsItems, err := s.ftsScored(ctx, store, queryText, fanout)
+		if err != nil {
+			return nil, fmt.Errorf("fts search failed: %w", err)
+		}
+		ftsScoresByID = normalizeAscending(ftsItems)
+	}
+
+	combined := make(map[int64]float64, len(vecScores)+len(ftsScoresByID))
+	for id, score := range vecScores {
+		combined[id] += alpha * score
+	}
+	for id, score := range ftsScoresByID {
+		combined[id] += (1 - alpha) * score
+	}
+
+	ids := make([]int64, 0, len(combined))
+	for id := range combined {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return combined[ids[i]] > combined[ids[j]] })
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	results := make([]SearchResult, 0, len(ids))
+	for _, id := range ids {
+		e, err := s.getByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if e == nil {
+			continue
+		}
+		results = append(results, SearchResult{Embedding: *e, Similarity: combined[id]})
 	}
+	return results, nil
+}
 
-	vectorJSON, err := json.Marshal(embedding.Vector)
+// SearchRRF é a contraparte pura-RRF de SearchHybrid, para quando as duas
+// pernas têm distribuições de score muito diferentes (BM25 não é limitado
+// a [0,1] como similaridade de cosseno, por exemplo) — normalização min-max
+// nesse cenário deixa um único outlier dominar a combinação linear, o que
+// RRF evita por só olhar posição no ranking, nunca a magnitude do score.
+// Delega para HybridSearch, que já implementa RRF ponderado por alpha (ver
+// hybridRRFConstant); aqui sempre com alpha=0.5, que produz o mesmo
+// ranking que RRF sem peso porque as duas pernas ficam escaladas pelo
+// mesmo fator constante.
+func (s *Service) SearchRRF(ctx context.Context, contentType, queryText string, queryVector []float64, limit int) ([]SearchResult, error) {
+	hits, err := s.HybridSearch(ctx, SearchQuery{
+		Text:                 queryText,
+		Vector:               queryVector,
+		FilterByContentType:  contentType,
+		Limit:                limit,
+		Metric:               DistanceCosine,
+	})
 	if err != nil {
-		return 0, fmt.Errorf("failed to marshal vector: %w", err)
+		return nil, err
 	}
 
-	query := fmt.Sprintf(`
-		INSERT INTO %s (content_type, content_id, embedding, metadata)
-		VALUES (?, ?, ?, ?)
-		ON CONFLICT(id) DO UPDATE SET
-			content_type = excluded.content_type,
-			content_id = excluded.content_id,
-			embedding = excluded.embedding,
-			metadata = excluded.metadata
-	`, s.store.Config().TableName)
-
-	result, err := s.store.DB().ExecContext(ctx, query,
-		embedding.ContentType,
-		embedding.ContentID,
-		string(vectorJSON),
-		string(metadataJSON),
-	)
+	results := make([]SearchResult, 0, len(hits))
+	for _, h := range hits {
+		e, err := s.getByID(ctx, h.ID)
+		if err != nil {
+			return nil, err
+		}
+		if e == nil {
+			continue
+		}
+		results = append(results, SearchResult{Embedding: *e, Similarity: h.Score})
+	}
+	return results, nil
+}
+
+// SearchMMR busca fetchK candidatos por similaridade de cosseno (fetchK
+// deveria ser bem maior que limit, senão a reordenação por MMR não tem
+// candidatos "de sobra" para trocar por diversidade) e os reordena por
+// Maximal Marginal Relevance: a cada passo escolhe o candidato que maximiza
+// lambda*sim(query, d) - (1-lambda)*max_{s em S} sim(d, s), onde S é o
+// conjunto já selecionado, até formar limit resultados. Isso evita que
+// chunks quase-duplicados dominem o top-k só porque estão todos muito perto
+// da query — um problema comum em RAG quando o corpus tem parágrafos
+// repetidos ou muito parecidos entre si. lambda=1.0 equivale a pura
+// relevância (ignora diversidade); lambda=0.0 maximiza diversidade (ignora
+// o quanto o candidato se parece com a query, só evita parecer com o que já
+// foi escolhido).
+func (s *Service) SearchMMR(ctx context.Context, contentType string, queryVector []float64, limit, fetchK int, lambda float64) ([]SearchResult, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	if fetchK < limit {
+		fetchK = limit * 4
+	}
+
+	candidates, err := s.Search(ctx, contentType, queryVector, fetchK, DistanceCosine, nil, SearchParams{})
 	if err != nil {
-		return 0, fmt.Errorf("failed to upsert embedding: %w", err)
+		return nil, fmt.Errorf("candidate search failed: %w", err)
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	return mmrSelect(candidates, queryVector, limit, lambda), nil
+}
+
+// mmrSelect reordena candidates (já buscados por similaridade de cosseno à
+// query) por Maximal Marginal Relevance, conforme a fórmula descrita no doc
+// comment de SearchMMR, até formar limit resultados ou esgotar candidates.
+// Extraída de SearchMMR como função pura para ser testável sem um *Service
+// nem um banco reais.
+func mmrSelect(candidates []SearchResult, queryVector []float64, limit int, lambda float64) []SearchResult {
+	querySim := make([]float64, len(candidates))
+	for i, c := range candidates {
+		querySim[i] = cosineSimilarity(queryVector, c.Vector)
 	}
 
-	return result.LastInsertId()
+	remaining := make([]int, len(candidates))
+	for i := range candidates {
+		remaining[i] = i
+	}
+
+	selected := make([]int, 0, limit)
+	for len(selected) < limit && len(remaining) > 0 {
+		bestPos, bestScore := 0, math.Inf(-1)
+		for pos, idx := range remaining {
+			maxSimToSelected := 0.0
+			for _, sIdx := range selected {
+				if sim := cosineSimilarity(candidates[idx].Vector, candidates[sIdx].Vector); sim > maxSimToSelected {
+					maxSimToSelected = sim
+				}
+			}
+
+			score := lambda*querySim[idx] - (1-lambda)*maxSimToSelected
+			if score > bestScore {
+				bestScore = score
+				bestPos = pos
+			}
+		}
+
+		selected = append(selected, remaining[bestPos])
+		remaining = append(remaining[:bestPos], remaining[bestPos+1:]...)
+	}
+
+	results := make([]SearchResult, len(selected))
+	for i, idx := range selected {
+		results[i] = candidates[idx]
+		results[i].Similarity = querySim[idx]
+	}
+	return results
+}
+
+// cosineSimilarity devolve a similaridade de cosseno entre a e b (1 para
+// vetores idênticos em direção, -1 para opostos, 0 para ortogonais ou para
+// qualquer vetor nulo). Usada por SearchMMR para comparar candidato a
+// candidato sobre os vetores já hidratados em Go, em vez de depender do
+// valor de distância que o sqlite-vec devolve para a query original — MMR
+// também precisa da similaridade entre pares de candidatos, que nenhuma
+// consulta SQL devolve de uma vez só.
+func cosineSimilarity(a, b []float64) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// SearchOptions agrupa os parâmetros de paginação e projeção de
+// SearchPaginated — ficaram de fora de Search/SearchGlobal para não quebrar
+// a assinatura usada por internal/search, que já chama os dois com
+// SearchParams{} zero-value.
+type SearchOptions struct {
+	// Offset pula os primeiros Offset resultados (depois de ordenar por
+	// distância), para paginar sobre o mesmo queryVector/filter.
+	Offset int
+
+	// MinSimilarity e MaxDistance cortam candidatos com base no mesmo valor
+	// devolvido em SearchResult.Similarity (a distância bruta do
+	// sqlite-vec, menor é melhor — ver o comentário de Search). Zero em
+	// qualquer um dos dois significa "sem limiar"; setar os dois ao mesmo
+	// tempo só faz sentido para restringir um intervalo.
+	MinSimilarity float64
+	MaxDistance   float64
+
+	// IncludeVector, quando false (o padrão), pula o vec_to_json(embedding)
+	// e o json.Unmarshal de volta para []float64 — evita esse round-trip
+	// quando o chamador só precisa de ID/ContentID/metadata (ex. uma lista
+	// paginada numa UI).
+	IncludeVector bool
+
+	// Rerank e RerankK só se aplicam a Service.SearchQuantized — as demais
+	// buscas deste arquivo nunca usam a coluna quantizada, então não têm o
+	// que re-rankear. Rerank, quando true, faz SearchQuantized buscar
+	// RerankK candidatos (0 usa limit*10) pela coluna embedding_q, barata
+	// mas aproximada, e depois recalcular a distância de verdade contra
+	// embedding (float32) só para esses RerankK, reordenando antes de
+	// cortar para limit. Rerank=false devolve o top-limit aproximado direto
+	// da coluna quantizada, sem o segundo passo.
+	Rerank  bool
+	RerankK int
 }
 
-func (s *Service) Search(ctx context.Context, contentType string, queryVector []float64, limit int, metric DistanceMetric) ([]SearchResult, error) {
+// SearchPaginated é como Search, mas dá suporte a paginação (Offset), corte
+// por limiar de distância/similaridade e pular a hidratação do vetor quando
+// o chamador não precisa dele (ver SearchOptions). Também devolve o total de
+// candidatos que passam em content_type/filter/limiares, antes de
+// Offset/limit, para quem monta uma UI paginada saber quantas páginas
+// existem — calcular esse total é barato porque reaproveita a mesma
+// cláusula WHERE da busca k-NN (a distância já precisa ser avaliada linha a
+// linha de qualquer forma), mas continua sendo um full scan sobre a tabela
+// quando nenhum índice em memória está habilitado para contentType (ver
+// Store.EnableIndex).
+func (s *Service) SearchPaginated(ctx context.Context, contentType string, queryVector []float64, limit int, metric DistanceMetric, filter Filter, opts SearchOptions) ([]SearchResult, int, error) {
 	if limit <= 0 {
 		limit = 10
 	}
 
-	var distanceFunc string
-	switch metric {
-	case DistanceCosine:
-		distanceFunc = "vec_distance_cosine"
-	case DistanceL1:
-		distanceFunc = "vec_distance_l1"
-	case DistanceL2:
-		distanceFunc = "vec_distance_l2"
-	default:
-		distanceFunc = "vec_distance_cosine"
+	store, ok := s.requireStore()
+	if !ok {
+		return nil, 0, s.errRequiresStore()
 	}
 
-	query := fmt.Sprintf(`
-		SELECT
-			id, content_type, content_id, vec_to_json(embedding) as embedding, metadata,
-			%s(embedding, ?) as distance
-		FROM %s
-		WHERE content_type = ?
-		ORDER BY distance
-		LIMIT ?
-	`, distanceFunc, s.store.Config().TableName)
+	distanceFunc := distanceFuncSQL(metric)
 
-	// Serializar queryVector para formato binário
-	queryVector32 := make([]float32, len(queryVector))
-	for i, v := range queryVector {
-		queryVector32[i] = float32(v)
+	whereClause := "WHERE content_type = ?"
+	args := []any{contentType}
+	if filter != nil {
+		clause, filterArgs, err := filter.compile(store.Config().allowedFilterKeys())
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid filter: %w", err)
+		}
+		whereClause += " AND " + clause
+		args = append(args, filterArgs...)
 	}
-	queryVectorBin, err := sqlitevec.SerializeFloat32(queryVector32)
+
+	thresholdClause := ""
+	var thresholdArgs []any
+	if opts.MaxDistance != 0 {
+		thresholdClause += " AND distance <= ?"
+		thresholdArgs = append(thresholdArgs, opts.MaxDistance)
+	}
+	if opts.MinSimilarity != 0 {
+		thresholdClause += " AND distance >= ?"
+		thresholdArgs = append(thresholdArgs, opts.MinSimilarity)
+	}
+
+	queryVectorBin, err := serializeVector(queryVector)
 	if err != nil {
-		return nil, fmt.Errorf("failed to serialize query vector: %w", err)
+		return nil, 0, fmt.Errorf("failed to serialize query vector: %w", err)
+	}
+
+	scoredCTE := fmt.Sprintf(`
+		WITH scored AS (
+			SELECT id, content_type, content_id, embedding, metadata, updated_at,
+				%s(embedding, ?) as distance
+			FROM %s
+			%s
+		)
+	`, distanceFunc, store.Config().TableName, whereClause)
+
+	countQuery := scoredCTE + "SELECT COUNT(*) FROM scored WHERE 1=1" + thresholdClause
+	countArgs := append(append([]any{queryVectorBin}, args...), thresholdArgs...)
+
+	var total int
+	if err := store.DB().QueryRowContext(ctx, countQuery, countArgs...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count matching embeddings: %w", err)
+	}
+
+	embeddingColumn := "NULL"
+	if opts.IncludeVector {
+		embeddingColumn = "vec_to_json(embedding)"
 	}
 
-	rows, err := s.store.DB().QueryContext(ctx, query, queryVectorBin, contentType, limit)
+	selectQuery := scoredCTE + fmt.Sprintf(`
+		SELECT id, content_type, content_id, %s, metadata, updated_at, distance
+		FROM scored
+		WHERE 1=1 %s
+		ORDER BY distance
+		LIMIT ? OFFSET ?
+	`, embeddingColumn, thresholdClause)
+
+	selectArgs := append(append([]any{queryVectorBin}, args...), thresholdArgs...)
+	selectArgs = append(selectArgs, limit, opts.Offset)
+
+	rows, err := store.DB().QueryContext(ctx, selectQuery, selectArgs...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search embeddings: %w", err)
+		return nil, 0, fmt.Errorf("failed to search embeddings: %w", err)
 	}
 	defer rows.Close()
 
 	var results []SearchResult
 	for rows.Next() {
 		var e Embedding
-		var embeddingJSON string
+		var embeddingJSON sql.NullString
 		var metadataJSON string
 		var distance float64
 
-		err := rows.Scan(
+		if err := rows.Scan(
 			&e.ID,
 			&e.ContentType,
 			&e.ContentID,
 			&embeddingJSON,
 			&metadataJSON,
+			&e.UpdatedAt,
 			&distance,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan row: %w", err)
 		}
 
-		if err := json.Unmarshal([]byte(embeddingJSON), &e.Vector); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal vector: %w", err)
+		if embeddingJSON.Valid {
+			if err := json.Unmarshal([]byte(embeddingJSON.String), &e.Vector); err != nil {
+				return nil, 0, fmt.Errorf("failed to unmarshal vector: %w", err)
+			}
 		}
 
 		if metadataJSON != "" {
 			if err := json.Unmarshal([]byte(metadataJSON), &e.Metadata); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+				return nil, 0, fmt.Errorf("failed to unmarshal metadata: %w", err)
 			}
 		}
 
@@ -164,162 +1857,161 @@ func (s *Service) Search(ctx context.Context, contentType string, queryVector []
 			Similarity: distance,
 		})
 	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to iterate search results: %w", err)
+	}
 
-	return results, nil
+	return results, total, nil
 }
 
-func (s *Service) SearchGlobal(ctx context.Context, queryVector []float64, limit int, metric DistanceMetric) ([]SearchResult, error) {
+// SearchQuantized é como Search, mas faz o corte inicial contra a coluna
+// quantizada embedding_q (ver Config.Quantization) em vez da coluna
+// embedding float[N] inteira — muito mais barato por linha (1 byte por
+// dimensão em QuantizationInt8, 1 bit em QuantizationBinary, contra 4 bytes
+// em float32), o que importa quando a coleção já não cabe confortavelmente
+// num full scan de vetores float32. Quando opts.Rerank é true, esse corte
+// grosseiro só escolhe opts.RerankK candidatos (0 usa limit*10), que são
+// então reordenados pela distância de verdade contra embedding — sem essa
+// segunda passada, a ordenação final herda a perda de precisão da
+// quantização, especialmente severa em QuantizationBinary (1 bit por
+// dimensão). Cai para Search sem nenhum passo extra quando
+// Config.Quantization é QuantizationNone.
+func (s *Service) SearchQuantized(ctx context.Context, contentType string, queryVector []float64, limit int, metric DistanceMetric, filter Filter, opts SearchOptions) ([]SearchResult, error) {
 	if limit <= 0 {
 		limit = 10
 	}
 
-	var distanceFunc string
-	switch metric {
-	case DistanceCosine:
-		distanceFunc = "vec_distance_cosine"
-	case DistanceL1:
-		distanceFunc = "vec_distance_l1"
-	case DistanceL2:
-		distanceFunc = "vec_distance_l2"
-	default:
-		distanceFunc = "vec_distance_cosine"
+	store, ok := s.requireStore()
+	if !ok {
+		return nil, s.errRequiresStore()
 	}
 
-	query := fmt.Sprintf(`
-		SELECT
-			id, content_type, content_id, vec_to_json(embedding) as embedding, metadata,
-			%s(embedding, ?) as distance
+	if store.Config().Quantization == QuantizationNone {
+		return s.Search(ctx, contentType, queryVector, limit, metric, filter, SearchParams{})
+	}
+
+	fetchK := limit
+	if opts.Rerank {
+		fetchK = opts.RerankK
+		if fetchK <= 0 {
+			fetchK = limit * 10
+		}
+	}
+
+	var coarseQueryVec []byte
+	var coarseDistanceFunc string
+	switch store.Config().Quantization {
+	case QuantizationBinary:
+		queryQ, err := quantizeBinary(queryVector)
+		if err != nil {
+			return nil, err
+		}
+		coarseQueryVec = queryQ
+		coarseDistanceFunc = "vec_distance_hamming"
+	case QuantizationInt8:
+		vals := quantizeInt8(queryVector, store.Config().Calibration)
+		queryQ := make([]byte, len(vals))
+		for i, v := range vals {
+			queryQ[i] = byte(v)
+		}
+		coarseQueryVec = queryQ
+		coarseDistanceFunc = distanceFuncSQL(metric)
+	}
+
+	whereClause := "WHERE content_type = ?"
+	args := []any{contentType}
+	if filter != nil {
+		clause, filterArgs, err := filter.compile(store.Config().allowedFilterKeys())
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter: %w", err)
+		}
+		whereClause += " AND " + clause
+		args = append(args, filterArgs...)
+	}
+
+	coarseQuery := fmt.Sprintf(`
+		SELECT id, content_type, content_id, vec_to_json(embedding) as embedding, metadata, updated_at,
+			%s(embedding_q, ?) as distance
 		FROM %s
+		%s
 		ORDER BY distance
 		LIMIT ?
-	`, distanceFunc, s.store.Config().TableName)
+	`, coarseDistanceFunc, store.Config().TableName, whereClause)
 
-	// Serializar queryVector para formato binário
-	queryVector32 := make([]float32, len(queryVector))
-	for i, v := range queryVector {
-		queryVector32[i] = float32(v)
-	}
-	queryVectorBin, err := sqlitevec.SerializeFloat32(queryVector32)
-	if err != nil {
-		return nil, fmt.Errorf("failed to serialize query vector: %w", err)
-	}
+	coarseArgs := append([]any{coarseQueryVec}, args...)
+	coarseArgs = append(coarseArgs, fetchK)
 
-	rows, err := s.store.DB().QueryContext(ctx, query, queryVectorBin, limit)
+	rows, err := store.DB().QueryContext(ctx, coarseQuery, coarseArgs...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search embeddings: %w", err)
 	}
 	defer rows.Close()
 
-	var results []SearchResult
+	var candidates []SearchResult
 	for rows.Next() {
 		var e Embedding
 		var embeddingJSON string
 		var metadataJSON string
 		var distance float64
 
-		err := rows.Scan(
-			&e.ID,
-			&e.ContentType,
-			&e.ContentID,
-			&embeddingJSON,
-			&metadataJSON,
-			&distance,
-		)
-		if err != nil {
+		if err := rows.Scan(&e.ID, &e.ContentType, &e.ContentID, &embeddingJSON, &metadataJSON, &e.UpdatedAt, &distance); err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
-
 		if err := json.Unmarshal([]byte(embeddingJSON), &e.Vector); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal vector: %w", err)
 		}
-
 		if metadataJSON != "" {
 			if err := json.Unmarshal([]byte(metadataJSON), &e.Metadata); err != nil {
 				return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
 			}
 		}
-
-		results = append(results, SearchResult{
-			Embedding:  e,
-			Similarity: distance,
-		})
+		candidates = append(candidates, SearchResult{Embedding: e, Similarity: distance})
 	}
-
-	return results, nil
-}
-
-func (s *Service) Delete(ctx context.Context, contentType string, contentID int64) error {
-	query := fmt.Sprintf(`
-		DELETE FROM %s WHERE content_type = ? AND content_id = ?
-	`, s.store.Config().TableName)
-
-	_, err := s.store.DB().ExecContext(ctx, query, contentType, contentID)
-	return err
-}
-
-func (s *Service) DeleteByIDs(ctx context.Context, ids []int64) error {
-	if len(ids) == 0 {
-		return nil
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate search results: %w", err)
 	}
 
-	placeholders := make([]string, len(ids))
-	args := make([]any, len(ids))
-	for i, id := range ids {
-		placeholders[i] = "?"
-		args[i] = id
+	if !opts.Rerank {
+		if len(candidates) > limit {
+			candidates = candidates[:limit]
+		}
+		return candidates, nil
 	}
 
-	query := fmt.Sprintf(`
-		DELETE FROM %s WHERE id IN (%s)
-	`, s.store.Config().TableName, strings.Join(placeholders, ","))
-
-	_, err := s.store.DB().ExecContext(ctx, query, args...)
-	return err
-}
-
-func (s *Service) GetByContent(ctx context.Context, contentType string, contentID int64) (*Embedding, error) {
-	query := fmt.Sprintf(`
-		SELECT id, content_type, content_id, embedding, metadata
-		FROM %s WHERE content_type = ? AND content_id = ?
-	`, s.store.Config().TableName)
-
-	var e Embedding
-	var embeddingJSON string
-	var metadataJSON string
-
-	err := s.store.DB().QueryRowContext(ctx, query, contentType, contentID).Scan(
-		&e.ID,
-		&e.ContentType,
-		&e.ContentID,
-		&embeddingJSON,
-		&metadataJSON,
-	)
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to get embedding: %w", err)
+	for i := range candidates {
+		candidates[i].Similarity = distanceBetween(metric, queryVector, candidates[i].Vector)
 	}
-
-	if err := json.Unmarshal([]byte(embeddingJSON), &e.Vector); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal vector: %w", err)
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Similarity < candidates[j].Similarity })
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
 	}
+	return candidates, nil
+}
 
-	if metadataJSON != "" {
-		if err := json.Unmarshal([]byte(metadataJSON), &e.Metadata); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+// distanceBetween calcula em Go a mesma distância que distanceFuncSQL(metric)
+// calcularia em SQL sobre dois vetores já hidratados — usado pelo passo de
+// rerank de SearchQuantized, que precisa comparar o vetor de query contra
+// cada candidato sem pagar uma query por candidato.
+func distanceBetween(metric DistanceMetric, a, b []float64) float64 {
+	switch metric {
+	case DistanceL1:
+		var sum float64
+		for i := range a {
+			d := a[i] - b[i]
+			if d < 0 {
+				d = -d
+			}
+			sum += d
+		}
+		return sum
+	case DistanceCosine:
+		return 1 - cosineSimilarity(a, b)
+	default:
+		var sum float64
+		for i := range a {
+			d := a[i] - b[i]
+			sum += d * d
 		}
+		return math.Sqrt(sum)
 	}
-
-	return &e, nil
-}
-
-func (s *Service) Count(ctx context.Context, contentType string) (int, error) {
-	query := fmt.Sprintf(`
-		SELECT COUNT(*) FROM %s WHERE content_type = ?
-	`, s.store.Config().TableName)
-
-	var count int
-	err := s.store.DB().QueryRowContext(ctx, query, contentType).Scan(&count)
-	return count, err
 }
@@ -3,7 +3,10 @@ package vector
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
 
 	sqlitevec "github.com/asg017/sqlite-vec-go-bindings/cgo"
 )
@@ -12,15 +15,34 @@ func init() {
 	sqlitevec.Auto()
 }
 
+// annIndex é o índice aproximado de vizinho mais próximo em memória mantido
+// por Store por content_type (ver indexes abaixo) — HNSWIndex e
+// IVFFlatIndex são as duas implementações (ver IndexSpec/buildIndex em
+// index.go).
+type annIndex interface {
+	Insert(id int64, vector []float64)
+	Delete(id int64)
+	Search(query []float64, k int, params SearchParams) []hnswCandidate
+}
+
+// globalIndexKey é o content_type reservado para o índice usado por
+// Service.SearchGlobal, que varre a tabela inteira em vez de um único
+// content_type (ver Store.Index/EnableHNSW).
+const globalIndexKey = ""
+
 type Store struct {
 	db     *sql.DB
 	config Config
+
+	mu      sync.RWMutex
+	indexes map[string]annIndex
 }
 
 func NewStore(db *sql.DB, config Config) *Store {
 	return &Store{
-		db:     db,
-		config: config,
+		db:      db,
+		config:  config,
+		indexes: make(map[string]annIndex),
 	}
 }
 
@@ -32,32 +54,359 @@ func (s *Store) Config() Config {
 	return s.config
 }
 
+// indexedMetadataColumns são as chaves de metadata materializadas como
+// colunas geradas por ensureFilterColumns — as duas usadas pelos filtros
+// multi-tenant mais comuns (ver Filter em filter.go). Uma consulta que
+// filtra por essas chaves usa o índice b-tree da coluna gerada em vez de
+// forçar um table scan avaliando json_extract linha a linha.
+var indexedMetadataColumns = []string{"tenant_id", "category"}
+
+// quantizationColumn devolve a declaração de coluna vec0 para embedding_q
+// (ver Config.Quantization), ou "" se nenhuma quantização estiver
+// configurada — int8[N] para QuantizationInt8, bit[N] (N múltiplo de 8)
+// para QuantizationBinary.
+func quantizationColumn(cfg Config) (string, error) {
+	switch cfg.Quantization {
+	case QuantizationNone:
+		return "", nil
+	case QuantizationInt8:
+		return fmt.Sprintf(",\n\t\t\tembedding_q int8[%d]", cfg.EmbeddingDimension), nil
+	case QuantizationBinary:
+		if cfg.EmbeddingDimension%8 != 0 {
+			return "", fmt.Errorf("vector: QuantizationBinary exige EmbeddingDimension múltiplo de 8, configurado %d", cfg.EmbeddingDimension)
+		}
+		return fmt.Sprintf(",\n\t\t\tembedding_q bit[%d]", cfg.EmbeddingDimension), nil
+	default:
+		return "", fmt.Errorf("vector: quantization mode desconhecido: %q", cfg.Quantization)
+	}
+}
+
 func (s *Store) EnsureTable(ctx context.Context) error {
+	quantizationCol, err := quantizationColumn(s.config)
+	if err != nil {
+		return err
+	}
+
 	query := fmt.Sprintf(`
 		CREATE VIRTUAL TABLE IF NOT EXISTS %s USING vec0(
 			id INTEGER PRIMARY KEY,
 			content_type TEXT NOT NULL,
 			content_id INTEGER NOT NULL,
-			embedding float[%d],
+			embedding float[%d]%s,
 			metadata TEXT,
-			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		);
-		
+
 		CREATE INDEX IF NOT EXISTS idx_%s_content ON %s(content_type, content_id);
 		CREATE INDEX IF NOT EXISTS idx_%s_content_type ON %s(content_type);
+
+		CREATE VIRTUAL TABLE IF NOT EXISTS %s USING fts5(content);
 	`,
 		s.config.TableName,
-		s.config.EmbeddingDimension,
+		s.config.EmbeddingDimension, quantizationCol,
 		s.config.TableName, s.config.TableName,
 		s.config.TableName, s.config.TableName,
+		s.ftsTableName(),
 	)
 
-	_, err := s.db.ExecContext(ctx, query)
+	if _, err := s.db.ExecContext(ctx, query); err != nil {
+		return err
+	}
+
+	if err := s.ensureFilterColumns(ctx); err != nil {
+		return err
+	}
+
+	if err := s.ensureUpdatedAtColumn(ctx); err != nil {
+		return err
+	}
+
+	if err := s.ensureIndexTable(ctx); err != nil {
+		return err
+	}
+
+	if s.config.Index.Kind == "" {
+		return nil
+	}
+	if _, ok, err := s.LoadIndexSpec(ctx, globalIndexKey); err != nil {
+		return err
+	} else if !ok {
+		return s.SaveIndexSpec(ctx, globalIndexKey, s.config.Index)
+	}
+	return nil
+}
+
+// ensureIndexTable cria vector_indexes, que guarda o IndexSpec escolhido
+// para cada content_type (globalIndexKey para o índice usado por
+// SearchGlobal) — ver SaveIndexSpec/LoadIndexSpec/EnableIndex.
+func (s *Store) ensureIndexTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS vector_indexes (
+			content_type TEXT PRIMARY KEY,
+			kind TEXT NOT NULL,
+			type_params TEXT NOT NULL DEFAULT '{}',
+			index_params TEXT NOT NULL DEFAULT '{}'
+		)
+	`)
 	return err
 }
 
+// SaveIndexSpec grava (ou substitui) o IndexSpec de contentType em
+// vector_indexes.
+func (s *Store) SaveIndexSpec(ctx context.Context, contentType string, spec IndexSpec) error {
+	typeParams, err := json.Marshal(spec.TypeParams)
+	if err != nil {
+		return fmt.Errorf("failed to marshal type params: %w", err)
+	}
+	indexParams, err := json.Marshal(spec.IndexParams)
+	if err != nil {
+		return fmt.Errorf("failed to marshal index params: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO vector_indexes (content_type, kind, type_params, index_params)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(content_type) DO UPDATE SET
+			kind = excluded.kind,
+			type_params = excluded.type_params,
+			index_params = excluded.index_params
+	`, contentType, string(spec.Kind), string(typeParams), string(indexParams))
+	if err != nil {
+		return fmt.Errorf("failed to save index spec: %w", err)
+	}
+	return nil
+}
+
+// LoadIndexSpec lê o IndexSpec de contentType salvo por SaveIndexSpec, ou
+// ok=false se nenhum foi salvo ainda.
+func (s *Store) LoadIndexSpec(ctx context.Context, contentType string) (spec IndexSpec, ok bool, err error) {
+	var kind, typeParams, indexParams string
+	err = s.db.QueryRowContext(ctx, `
+		SELECT kind, type_params, index_params FROM vector_indexes WHERE content_type = ?
+	`, contentType).Scan(&kind, &typeParams, &indexParams)
+	if err == sql.ErrNoRows {
+		return IndexSpec{}, false, nil
+	}
+	if err != nil {
+		return IndexSpec{}, false, fmt.Errorf("failed to load index spec: %w", err)
+	}
+
+	spec.Kind = IndexKind(kind)
+	if err := json.Unmarshal([]byte(typeParams), &spec.TypeParams); err != nil {
+		return IndexSpec{}, false, fmt.Errorf("failed to unmarshal type params: %w", err)
+	}
+	if err := json.Unmarshal([]byte(indexParams), &spec.IndexParams); err != nil {
+		return IndexSpec{}, false, fmt.Errorf("failed to unmarshal index params: %w", err)
+	}
+	return spec, true, nil
+}
+
+// ensureFilterColumns roda um ALTER TABLE ADD COLUMN ... GENERATED ALWAYS
+// AS (...) VIRTUAL por chave de indexedMetadataColumns, mais o índice
+// correspondente — mesma técnica (ALTER idempotente tolerando "duplicate
+// column") de authz.Manager.EnsureSchema para roles.inherits, porque a
+// tabela vec0 já existe (de EnsureTable) antes dessas colunas serem
+// adicionadas; não dá para declará-las na DSL de CREATE VIRTUAL TABLE
+// USING vec0(...), que só entende seus próprios tipos de coluna
+// (float[N], PARTITION KEY etc.), não GENERATED ALWAYS AS.
+func (s *Store) ensureFilterColumns(ctx context.Context) error {
+	for _, key := range indexedMetadataColumns {
+		alterQuery := fmt.Sprintf(
+			`ALTER TABLE %s ADD COLUMN %s TEXT GENERATED ALWAYS AS (json_extract(metadata, '$.%s')) VIRTUAL`,
+			s.config.TableName, key, key,
+		)
+		if _, err := s.db.ExecContext(ctx, alterQuery); err != nil && !strings.Contains(err.Error(), "duplicate column") {
+			return fmt.Errorf("failed to add generated column %s: %w", key, err)
+		}
+
+		indexQuery := fmt.Sprintf(`CREATE INDEX IF NOT EXISTS idx_%s_%s ON %s(%s)`, s.config.TableName, key, s.config.TableName, key)
+		if _, err := s.db.ExecContext(ctx, indexQuery); err != nil {
+			return fmt.Errorf("failed to index generated column %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// ensureUpdatedAtColumn adiciona updated_at a um deployment existente cuja
+// tabela foi criada antes dessa coluna existir — mesma técnica de ALTER
+// idempotente tolerando "duplicate column" de ensureFilterColumns, exceto
+// que updated_at é uma coluna comum (gravada por Service.UpdateEmbedding/
+// UpdateMetadata/Upsert), não GENERATED ALWAYS AS a partir de metadata.
+func (s *Store) ensureUpdatedAtColumn(ctx context.Context) error {
+	query := fmt.Sprintf(
+		`ALTER TABLE %s ADD COLUMN updated_at DATETIME DEFAULT CURRENT_TIMESTAMP`,
+		s.config.TableName,
+	)
+	if _, err := s.db.ExecContext(ctx, query); err != nil && !strings.Contains(err.Error(), "duplicate column") {
+		return fmt.Errorf("failed to add updated_at column: %w", err)
+	}
+	return nil
+}
+
+// ftsTableName é a tabela virtual FTS5 companheira de TableName, criada por
+// EnsureTable para a perna textual de Service.HybridSearch. Cada linha
+// usa o mesmo rowid do embedding correspondente em TableName, então o join
+// entre os dois rankings (ver HybridSearch) é só comparar esse id.
+func (s *Store) ftsTableName() string {
+	return s.config.TableName + "_fts"
+}
+
+// fts5CompanionTableName nomeia a tabela FTS5 externa de contentType criada
+// por EnsureFTS5Companion, seguindo a mesma convenção de sufixo "_fts" de
+// ftsTableName.
+func fts5CompanionTableName(contentType string) string {
+	return contentType + "_fts"
+}
+
+// EnsureFTS5Companion cria (se ainda não existir) uma tabela virtual FTS5
+// "externa" sobre uma tabela de conteúdo já existente chamada contentType,
+// com as colunas informadas — ao contrário da tabela FTS5 criada por
+// EnsureTable (que guarda o texto ela mesma), esta usa content=/
+// content_rowid='id' para indexar o texto já armazenado em contentType sem
+// duplicá-lo. É pensada para que a perna textual de Service.HybridSearch
+// aponte, via SearchQuery.FTSTable, para o conteúdo original (ex.: "posts")
+// em vez da tabela de embeddings. O chamador ainda precisa popular o índice
+// invocando um INSERT INTO ... (<tabela>_fts) VALUES ('rebuild') após a
+// criação, ou mantê-lo em sincronia com triggers — isso fica por conta de
+// quem gerencia contentType, fora do pacote vector.
+func (s *Store) EnsureFTS5Companion(ctx context.Context, contentType string, columns []string) error {
+	if contentType == "" {
+		return fmt.Errorf("content type must not be empty")
+	}
+	if len(columns) == 0 {
+		return fmt.Errorf("at least one column is required")
+	}
+
+	query := fmt.Sprintf(
+		`CREATE VIRTUAL TABLE IF NOT EXISTS %s USING fts5(%s, content=%q, content_rowid='id')`,
+		fts5CompanionTableName(contentType), strings.Join(columns, ", "), contentType,
+	)
+	if _, err := s.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to create fts5 companion table: %w", err)
+	}
+	return nil
+}
+
 func (s *Store) Version(ctx context.Context) (string, error) {
 	var version string
 	err := s.db.QueryRowContext(ctx, "SELECT vec_version()").Scan(&version)
 	return version, err
 }
+
+// EnableHNSW cria um índice HNSW em memória para metric e o popula varrendo
+// a tabela de embeddings inteira (todos os content_type), substituindo
+// qualquer índice global anterior. Pensado para ser chamado uma vez na
+// subida do processo (ou sob demanda, em testes) para acelerar
+// Service.SearchGlobal sobre grandes volumes sem depender só do full scan
+// feito pela extensão sqlite-vec. Mantido por compatibilidade; código novo
+// deve preferir EnableIndex, que também cobre ivf_flat, persiste o
+// IndexSpec em vector_indexes e permite indexar por content_type para
+// Service.Search.
+func (s *Store) EnableHNSW(ctx context.Context, metric DistanceMetric, cfg HNSWConfig) error {
+	index := NewHNSWIndex(metric, cfg)
+	if err := s.populateIndex(ctx, index, globalIndexKey); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.indexes[globalIndexKey] = index
+	s.mu.Unlock()
+	return nil
+}
+
+// EnableIndex constrói o índice em memória especificado por spec para
+// contentType — globalIndexKey ("") indexa a tabela inteira e fica visível
+// para Service.SearchGlobal via Store.Index(); qualquer outro valor indexa
+// só as linhas daquele content_type e fica visível para Service.Search via
+// Store.IndexFor(contentType). spec.Kind IndexFlat não cria índice em
+// memória nenhum (Service.Search* cai para o full scan via sqlite-vec) e
+// remove um índice anterior para contentType, se houver. O spec é sempre
+// persistido em vector_indexes, mesmo quando nenhum índice em memória é
+// construído — isso não sobrevive a um restart do processo sozinho; quem
+// sobe o processo deve chamar Store.LoadIndexSpec e EnableIndex de novo.
+func (s *Store) EnableIndex(ctx context.Context, contentType string, metric DistanceMetric, spec IndexSpec) error {
+	if err := s.SaveIndexSpec(ctx, contentType, spec); err != nil {
+		return err
+	}
+
+	index := buildIndex(metric, spec)
+	if index == nil {
+		s.mu.Lock()
+		delete(s.indexes, contentType)
+		s.mu.Unlock()
+		return nil
+	}
+
+	if err := s.populateIndex(ctx, index, contentType); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.indexes[contentType] = index
+	s.mu.Unlock()
+	return nil
+}
+
+// RebuildIndex troca o índice de contentType por um novo construído do zero
+// a partir de newSpec: EnableIndex já popula o índice novo inteiro antes de
+// torná-lo visível a Store.Index/IndexFor, então uma busca concorrente
+// nunca enxerga um índice pela metade — o mesmo efeito que a troca atômica
+// de uma tabela sombra busca para um índice persistido em disco. Aqui não
+// há nada em disco para trocar: vec0 sempre guarda os vetores em formato
+// flat, e o índice em si só existe em memória (ver IndexSpec).
+func (s *Store) RebuildIndex(ctx context.Context, contentType string, metric DistanceMetric, newSpec IndexSpec) error {
+	return s.EnableIndex(ctx, contentType, metric, newSpec)
+}
+
+// populateIndex varre a tabela de embeddings — toda, se contentType for
+// globalIndexKey, ou só as linhas daquele content_type — inserindo cada
+// vetor em index. Usado por EnableHNSW/EnableIndex para popular um índice
+// novo antes de torná-lo visível a buscas.
+func (s *Store) populateIndex(ctx context.Context, index annIndex, contentType string) error {
+	query := fmt.Sprintf(`SELECT id, vec_to_json(embedding) FROM %s`, s.config.TableName)
+	var args []any
+	if contentType != globalIndexKey {
+		query += " WHERE content_type = ?"
+		args = append(args, contentType)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to scan embeddings table for index rebuild: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var vectorJSON string
+		if err := rows.Scan(&id, &vectorJSON); err != nil {
+			return fmt.Errorf("failed to scan embedding row: %w", err)
+		}
+
+		var vec []float64
+		if err := json.Unmarshal([]byte(vectorJSON), &vec); err != nil {
+			return fmt.Errorf("failed to unmarshal embedding vector: %w", err)
+		}
+		index.Insert(id, vec)
+	}
+	return rows.Err()
+}
+
+// Index devolve o índice em memória global (content_type globalIndexKey,
+// usado por Service.SearchGlobal), ou nil se EnableHNSW/EnableIndex nunca
+// foi chamado para ele.
+func (s *Store) Index() annIndex {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.indexes[globalIndexKey]
+}
+
+// IndexFor devolve o índice em memória de contentType (usado por
+// Service.Search), ou nil se EnableIndex nunca foi chamado para esse
+// content_type.
+func (s *Store) IndexFor(contentType string) annIndex {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.indexes[contentType]
+}
@@ -0,0 +1,158 @@
+package vector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Este arquivo implementa Backend em *Store — a versão básica, sem filtro
+// de metadata nem índice ANN em memória, dessas operações. No caminho
+// sqlite-vec, Service chama o SQL equivalente diretamente na maioria das
+// operações, para ter acesso a Filter/IndexSpec, que não fazem parte de
+// Backend; a exceção é Service.Store sem Text, que chama Insert daqui.
+
+// Insert implementa Backend.Insert: grava embedding como uma linha nova,
+// sem checar duplicata por (ContentType, ContentID) — para upsert atômico,
+// use Service.Upsert.
+func (s *Store) Insert(ctx context.Context, embedding Embedding) (int64, error) {
+	metadataJSON, err := json.Marshal(embedding.Metadata)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	vectorBin, err := serializeVector(embedding.Vector)
+	if err != nil {
+		return 0, err
+	}
+
+	quantized, err := s.quantizeForColumn(embedding.Vector)
+	if err != nil {
+		return 0, err
+	}
+	qCol, qPlaceholder, qArgs := quantizedInsertColumn(quantized)
+
+	query := fmt.Sprintf(`
+		INSERT INTO %s (content_type, content_id, embedding, metadata%s)
+		VALUES (?, ?, ?, ?%s)
+	`, s.config.TableName, qCol, qPlaceholder)
+
+	args := append([]any{embedding.ContentType, embedding.ContentID, vectorBin, string(metadataJSON)}, qArgs...)
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert embedding: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// Search implementa Backend.Search: k-NN via full scan sqlite-vec dentro de
+// contentType, sem filtro de metadata nem índice ANN (ver Service.Search
+// para essas duas coisas).
+func (s *Store) Search(ctx context.Context, contentType string, queryVector []float64, limit int, metric DistanceMetric) ([]SearchResult, error) {
+	return s.search(ctx, "WHERE content_type = ?", []any{contentType}, queryVector, limit, metric)
+}
+
+// SearchGlobal implementa Backend.SearchGlobal: igual a Search, mas sobre a
+// tabela inteira, sem filtrar por content_type.
+func (s *Store) SearchGlobal(ctx context.Context, queryVector []float64, limit int, metric DistanceMetric) ([]SearchResult, error) {
+	return s.search(ctx, "", nil, queryVector, limit, metric)
+}
+
+func (s *Store) search(ctx context.Context, whereClause string, whereArgs []any, queryVector []float64, limit int, metric DistanceMetric) ([]SearchResult, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			id, content_type, content_id, vec_to_json(embedding) as embedding, metadata, updated_at,
+			%s(embedding, ?) as distance
+		FROM %s
+		%s
+		ORDER BY distance
+		LIMIT ?
+	`, distanceFuncSQL(metric), s.config.TableName, whereClause)
+
+	queryVectorBin, err := serializeVector(queryVector)
+	if err != nil {
+		return nil, err
+	}
+
+	args := append([]any{queryVectorBin}, whereArgs...)
+	args = append(args, limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var e Embedding
+		var embeddingJSON string
+		var metadataJSON string
+		var distance float64
+
+		if err := rows.Scan(&e.ID, &e.ContentType, &e.ContentID, &embeddingJSON, &metadataJSON, &e.UpdatedAt, &distance); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		if err := json.Unmarshal([]byte(embeddingJSON), &e.Vector); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal vector: %w", err)
+		}
+		if metadataJSON != "" {
+			if err := json.Unmarshal([]byte(metadataJSON), &e.Metadata); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+			}
+		}
+
+		results = append(results, SearchResult{Embedding: e, Similarity: distance})
+	}
+
+	return results, rows.Err()
+}
+
+// Delete implementa Backend.Delete.
+func (s *Store) Delete(ctx context.Context, contentType string, contentID int64) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE content_type = ? AND content_id = ?`, s.config.TableName)
+	_, err := s.db.ExecContext(ctx, query, contentType, contentID)
+	return err
+}
+
+// Update implementa Backend.Update: substitui o vetor de (contentType,
+// contentID), preservando metadata e atualizando updated_at.
+func (s *Store) Update(ctx context.Context, contentType string, contentID int64, newVector []float64) error {
+	vectorBin, err := serializeVector(newVector)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE %s SET embedding = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE content_type = ? AND content_id = ?
+	`, s.config.TableName)
+	result, err := s.db.ExecContext(ctx, query, vectorBin, contentType, contentID)
+	if err != nil {
+		return fmt.Errorf("failed to update embedding: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Count implementa Backend.Count.
+func (s *Store) Count(ctx context.Context, contentType string) (int, error) {
+	query := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE content_type = ?`, s.config.TableName)
+	var count int
+	err := s.db.QueryRowContext(ctx, query, contentType).Scan(&count)
+	return count, err
+}
+
+var _ Backend = (*Store)(nil)
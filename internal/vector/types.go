@@ -6,6 +6,49 @@ type Config struct {
 	Enabled            bool
 	EmbeddingDimension int
 	TableName          string
+	// AllowedFilterKeys lista as chaves de metadata que um Filter (ver
+	// filter.go) pode referenciar em Service.Search/SearchGlobal. Uma chave
+	// fora dessa lista é rejeitada em vez de compilada — sem isso, um
+	// chamador poderia montar json_extract(metadata, '$.<qualquer coisa>')
+	// a partir de entrada não confiável. Vazio/nil significa "nenhum filtro
+	// permitido", não "todas as chaves permitidas".
+	AllowedFilterKeys []string
+	// Index é o IndexSpec padrão do índice global (content_type ""),
+	// persistido em vector_indexes na primeira chamada de EnsureTable se
+	// ainda não houver um salvo ali. Não constrói o índice em memória
+	// sozinho — isso continua exigindo uma chamada explícita a
+	// Store.EnableIndex (normalmente feita uma vez na subida do processo);
+	// Index aqui só registra a intenção, para quem for chamar EnableIndex
+	// depois poder consultá-la via Store.LoadIndexSpec.
+	Index IndexSpec
+	// BatchChunkSize é o número de itens que Service.StoreBatch/BatchUpsert
+	// grava por transação antes de abrir a próxima — zero usa
+	// defaultBatchChunkSize. Existe para quem precisa ficar bem abaixo do
+	// SQLITE_MAX_VARIABLE_NUMBER do processo (ex. embeddings com metadata
+	// grande, que consomem mais parâmetros por linha) sem recompilar.
+	BatchChunkSize int
+	// Quantization liga a coluna secundária embedding_q (ver
+	// Store.EnsureTable e quantize.go) gravada por Service.Store/Upsert/
+	// BatchUpsert ao lado do vetor float32 de sempre, usada como primeiro
+	// corte barato por Service.SearchQuantized. QuantizationNone (o valor
+	// zero) não cria a coluna.
+	Quantization QuantizationMode
+	// Calibration é usado por quantizeInt8 quando Quantization é
+	// QuantizationInt8 — sem ele, cada vetor é quantizado com seu próprio
+	// min/max, o que distorce a comparação entre vetores de escalas
+	// diferentes (ver CalibrateInt8). Ignorado por QuantizationBinary, que
+	// não depende de escala (só do sinal de cada componente).
+	Calibration QuantizationCalibration
+}
+
+// allowedFilterKeys converte AllowedFilterKeys num set, para checagem O(1)
+// por Filter.compile (ver metadataColumn em filter.go).
+func (c Config) allowedFilterKeys() map[string]bool {
+	allowed := make(map[string]bool, len(c.AllowedFilterKeys))
+	for _, key := range c.AllowedFilterKeys {
+		allowed[key] = true
+	}
+	return allowed
 }
 
 type Embedding struct {
@@ -15,6 +58,18 @@ type Embedding struct {
 	Vector      []float64
 	Metadata    map[string]any
 	CreatedAt   time.Time
+	// UpdatedAt é atualizado por Service.UpdateEmbedding/UpdateMetadata/Upsert
+	// a cada escrita que muda o vetor ou a metadata — permite ranking
+	// staleness-aware (ex. penalizar resultados cujo embedding não é
+	// recalculado há muito tempo) sem precisar de uma tabela separada.
+	UpdatedAt time.Time
+	// Text é opcional e, quando não vazio, é gravado na tabela FTS5
+	// companheira (ver Store.ftsTableName) sob o mesmo id pelas mesmas
+	// transações que já escrevem o vetor (Service.Store/Upsert/BatchUpsert),
+	// em vez de exigir a chamada separada Service.Insert para indexar texto.
+	// Vazio não apaga um texto indexado anteriormente — para isso, grave ""
+	// explicitamente via Service.Insert ou delete e reinsira.
+	Text string
 }
 
 type SearchResult struct {
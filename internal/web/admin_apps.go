@@ -0,0 +1,71 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/PauloHFS/goth/internal/contextkeys"
+	"github.com/PauloHFS/goth/internal/db"
+	"github.com/PauloHFS/goth/internal/routes"
+	"github.com/PauloHFS/goth/internal/view/pages"
+	"github.com/a-h/templ"
+)
+
+// handleAdminApps lista as aplicações OAuth2 de terceiros registradas,
+// restrita a administradores como handleAdmin.
+func handleAdminApps(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	user, _ := r.Context().Value(contextkeys.UserContextKey).(db.User)
+
+	if user.RoleID != "admin" {
+		http.Redirect(w, r, routes.Dashboard, http.StatusForbidden)
+		return nil
+	}
+
+	clients, err := deps.OAuthClients.List(r.Context())
+	if err != nil {
+		return err
+	}
+
+	templ.Handler(pages.AdminApps(user, clients, "", "")).ServeHTTP(w, r)
+	return nil
+}
+
+// handleAdminAppsCreate registra um novo cliente OAuth2. O client_secret
+// (quando o cliente não é público) só é exibido uma vez, na resposta deste
+// request — a exemplo do que já é feito com os códigos de recuperação do
+// TOTP em handleProfile2FAEnable.
+func handleAdminAppsCreate(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	user, _ := r.Context().Value(contextkeys.UserContextKey).(db.User)
+
+	if user.RoleID != "admin" {
+		http.Redirect(w, r, routes.Dashboard, http.StatusForbidden)
+		return nil
+	}
+
+	name := r.FormValue("name")
+	redirectURIs := strings.Fields(r.FormValue("redirect_uris"))
+	allowedScopes := strings.Fields(r.FormValue("allowed_scopes"))
+	isPublic := r.FormValue("is_public") == "on"
+
+	if name == "" || len(redirectURIs) == 0 {
+		clients, err := deps.OAuthClients.List(r.Context())
+		if err != nil {
+			return err
+		}
+		templ.Handler(pages.AdminApps(user, clients, "", "Nome e ao menos um redirect_uri são obrigatórios")).ServeHTTP(w, r)
+		return nil
+	}
+
+	clientID, clientSecret, err := deps.OAuthClients.Register(r.Context(), name, redirectURIs, allowedScopes, isPublic)
+	if err != nil {
+		return err
+	}
+
+	clients, err := deps.OAuthClients.List(r.Context())
+	if err != nil {
+		return err
+	}
+
+	templ.Handler(pages.AdminApps(user, clients, clientID+":"+clientSecret, "")).ServeHTTP(w, r)
+	return nil
+}
@@ -0,0 +1,34 @@
+package web
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/PauloHFS/goth/internal/audit"
+	"github.com/PauloHFS/goth/internal/contextkeys"
+	"github.com/PauloHFS/goth/internal/db"
+	"github.com/PauloHFS/goth/internal/middleware"
+	"github.com/alexedwards/scs/v2"
+)
+
+// WithAuditMetadata extrai IP, user agent e o token da sessão atual e os
+// anexa ao contexto da requisição, para que internal/audit.Auditor.Record
+// os inclua automaticamente em cada registro de auditoria.
+func WithAuditMetadata(sm *scs.SessionManager) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := audit.WithRequestMetadata(r.Context(), middleware.ExtractIP(r), r.UserAgent(), sm.Token(r.Context()))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// actorIDFromContext extrai o ID do usuário autenticado do contexto, quando
+// presente, para uso nas chamadas de Auditor.Record feitas fora dos handlers
+// de autenticação (onde o ID ainda não está disponível por outra via).
+func actorIDFromContext(ctx context.Context) int64 {
+	if user, ok := ctx.Value(contextkeys.UserContextKey).(db.User); ok {
+		return user.ID
+	}
+	return 0
+}
@@ -3,27 +3,163 @@ package web
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 	"sync"
 
 	"github.com/PauloHFS/goth/internal/contextkeys"
 	"github.com/PauloHFS/goth/internal/db"
+	"github.com/PauloHFS/goth/internal/metrics"
 )
 
+// DropPolicy decides what happens when a client's bounded buffer (see
+// clientBuffer) is full and a new event needs to be queued.
+type DropPolicy int
+
+const (
+	// DropOldest discards the oldest queued event to make room for the new one.
+	DropOldest DropPolicy = iota
+	// DropNewest discards the incoming event, keeping the queue as-is.
+	DropNewest
+	// Coalesce replaces any queued event with the same event name before
+	// falling back to DropOldest if the buffer is still full afterwards.
+	Coalesce
+)
+
+// DefaultClientBufferSize and DefaultDropPolicy configure every client
+// registered after they're changed (see newClientBuffer) — settable at
+// startup the same way worker.DefaultJobRateConfigs is, before any client
+// connects.
+var (
+	DefaultClientBufferSize = 64
+	DefaultDropPolicy       = Coalesce
+)
+
+// globalHistorySize bounds how many past global broadcasts Broker keeps
+// around for Last-Event-ID replay (see Broker.eventsSince). Targeted
+// (per-user) messages aren't kept here — "globally" in the request this
+// implements refers to GlobalSSEHandler's global broadcast stream.
+const globalHistorySize = 256
+
+// sseEvent is one broadcast, with the monotonic id used for Last-Event-ID
+// resume and client buffer coalescing by event name.
+type sseEvent struct {
+	id    uint64
+	event string
+	data  string
+}
+
+func (e sseEvent) render() string {
+	return fmt.Sprintf("id: %d\nevent: %s\ndata: %s\n\n", e.id, e.event, e.data)
+}
+
+// clientBuffer is one connected client's outgoing queue. All sends to it
+// happen under mu, never under Broker.mu — a client that stops draining
+// (paused tab, dead TCP connection) only ever blocks itself, never other
+// clients or the broker's listen loop.
+type clientBuffer struct {
+	mu       sync.Mutex
+	events   []sseEvent
+	capacity int
+	policy   DropPolicy
+	notify   chan struct{}
+	dropped  uint64
+}
+
+func newClientBuffer() *clientBuffer {
+	return &clientBuffer{
+		capacity: DefaultClientBufferSize,
+		policy:   DefaultDropPolicy,
+		notify:   make(chan struct{}, 1),
+	}
+}
+
+// push queues evt, applying the configured DropPolicy if the buffer is
+// already at capacity, and wakes the client's drain loop (see
+// GlobalSSEHandler).
+func (c *clientBuffer) push(evt sseEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.policy == Coalesce {
+		for i, queued := range c.events {
+			if queued.event == evt.event {
+				c.events = append(c.events[:i], c.events[i+1:]...)
+				break
+			}
+		}
+	}
+
+	if len(c.events) >= c.capacity {
+		switch c.policy {
+		case DropNewest:
+			c.dropped++
+			metrics.SSEClientDroppedEvents.WithLabelValues("drop_newest").Inc()
+			return
+		default: // DropOldest and Coalesce (still full after dedup) both drop the oldest
+			c.events = c.events[1:]
+			c.dropped++
+			metrics.SSEClientDroppedEvents.WithLabelValues(policyLabel(c.policy)).Inc()
+		}
+	}
+
+	c.events = append(c.events, evt)
+
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+// drain removes and returns every currently queued event.
+func (c *clientBuffer) drain() []sseEvent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	events := c.events
+	c.events = nil
+	return events
+}
+
+// Dropped reports how many events this client has lost to its DropPolicy
+// since it connected, used by tests to assert backpressure kicked in.
+func (c *clientBuffer) Dropped() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dropped
+}
+
+func policyLabel(p DropPolicy) string {
+	switch p {
+	case DropOldest:
+		return "drop_oldest"
+	case DropNewest:
+		return "drop_newest"
+	case Coalesce:
+		return "coalesce"
+	default:
+		return "unknown"
+	}
+}
+
 // Broker handles SSE connections and targeted broadcasting
 type Broker struct {
-	// Mapeia UserID -> lista de canais (um usuário pode ter múltiplas abas abertas)
-	userClients map[int64][]chan string
+	// Mapeia UserID -> lista de clientBuffers (um usuário pode ter múltiplas abas abertas)
+	userClients map[int64][]*clientBuffer
 	mu          sync.Mutex
 
 	newClient     chan clientRegistration
 	closingClient chan clientRegistration
 	message       chan targetedMessage
 	stop          chan struct{}
+
+	historyMu sync.Mutex
+	history   []sseEvent
+	nextID    uint64
 }
 
 type clientRegistration struct {
 	userID int64
-	ch     chan string
+	buf    *clientBuffer
 }
 
 type targetedMessage struct {
@@ -36,7 +172,7 @@ var globalBroker *Broker
 
 func init() {
 	globalBroker = &Broker{
-		userClients:   make(map[int64][]chan string),
+		userClients:   make(map[int64][]*clientBuffer),
 		newClient:     make(chan clientRegistration),
 		closingClient: make(chan clientRegistration),
 		message:       make(chan targetedMessage),
@@ -45,29 +181,60 @@ func init() {
 	go globalBroker.listen()
 }
 
+// recordGlobalEvent assigns evt the next monotonic id and appends it to the
+// bounded history ring buffer used by eventsSince. Only called for
+// broadcasts (targetedMessage.userID == 0).
+func (b *Broker) recordGlobalEvent(event, data string) sseEvent {
+	b.historyMu.Lock()
+	defer b.historyMu.Unlock()
+
+	b.nextID++
+	evt := sseEvent{id: b.nextID, event: event, data: data}
+
+	b.history = append(b.history, evt)
+	if len(b.history) > globalHistorySize {
+		b.history = b.history[len(b.history)-globalHistorySize:]
+	}
+	return evt
+}
+
+// eventsSince devolve os eventos globais com id > lastID, usado para
+// reproduzir o que um cliente perdeu entre a desconexão e o reconnect (ver
+// GlobalSSEHandler e o header Last-Event-ID). Eventos mais antigos que o
+// início do ring buffer simplesmente não são reproduzidos — o cliente
+// reconecta no estado atual em vez de travar esperando um histórico que já
+// rotacionou.
+func (b *Broker) eventsSince(lastID uint64) []sseEvent {
+	b.historyMu.Lock()
+	defer b.historyMu.Unlock()
+
+	var replay []sseEvent
+	for _, evt := range b.history {
+		if evt.id > lastID {
+			replay = append(replay, evt)
+		}
+	}
+	return replay
+}
+
 func (b *Broker) listen() {
 	for {
 		select {
 		case <-b.stop:
 			b.mu.Lock()
-			for _, channels := range b.userClients {
-				for _, ch := range channels {
-					close(ch)
-				}
-			}
-			b.userClients = make(map[int64][]chan string)
+			b.userClients = make(map[int64][]*clientBuffer)
 			b.mu.Unlock()
 			return
 		case reg := <-b.newClient:
 			b.mu.Lock()
-			b.userClients[reg.userID] = append(b.userClients[reg.userID], reg.ch)
+			b.userClients[reg.userID] = append(b.userClients[reg.userID], reg.buf)
 			b.mu.Unlock()
 
 		case reg := <-b.closingClient:
 			b.mu.Lock()
 			clients := b.userClients[reg.userID]
-			for i, ch := range clients {
-				if ch == reg.ch {
+			for i, buf := range clients {
+				if buf == reg.buf {
 					b.userClients[reg.userID] = append(clients[:i], clients[i+1:]...)
 					break
 				}
@@ -78,25 +245,31 @@ func (b *Broker) listen() {
 			b.mu.Unlock()
 
 		case tm := <-b.message:
-			b.mu.Lock()
-			msg := fmt.Sprintf("event: %s\ndata: %s\n\n", tm.event, tm.data)
+			var targets []*clientBuffer
+			var evt sseEvent
 
 			if tm.userID == 0 {
-				// Broadcast Global
-				for _, channels := range b.userClients {
-					for _, ch := range channels {
-						ch <- msg
-					}
+				evt = b.recordGlobalEvent(tm.event, tm.data)
+
+				b.mu.Lock()
+				for _, clients := range b.userClients {
+					targets = append(targets, clients...)
 				}
+				b.mu.Unlock()
 			} else {
-				// Broadcast Direcionado
-				if channels, ok := b.userClients[tm.userID]; ok {
-					for _, ch := range channels {
-						ch <- msg
-					}
-				}
+				evt = sseEvent{event: tm.event, data: tm.data}
+
+				b.mu.Lock()
+				targets = append(targets, b.userClients[tm.userID]...)
+				b.mu.Unlock()
+			}
+
+			// Enfileirado em cada clientBuffer sob o lock do próprio cliente,
+			// nunca b.mu — um cliente parado só trava a si mesmo (ver
+			// clientBuffer.push), não o listen loop nem os demais clientes.
+			for _, buf := range targets {
+				buf.push(evt)
 			}
-			b.mu.Unlock()
 		}
 	}
 }
@@ -134,24 +307,36 @@ func GlobalSSEHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	messageChan := make(chan string, 10) // Buffer para evitar bloqueio
-	reg := clientRegistration{userID: user.ID, ch: messageChan}
+	buf := newClientBuffer()
+	reg := clientRegistration{userID: user.ID, buf: buf}
 
 	globalBroker.newClient <- reg
-
 	defer func() {
 		globalBroker.closingClient <- reg
 	}()
 
+	// Last-Event-ID: o navegador reenvia o último id recebido antes de uma
+	// reconexão automática (ver spec de EventSource) — reproduz o que ficou
+	// no ring buffer global desde então, antes de entrar no loop normal.
+	if lastEventID, err := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64); err == nil {
+		for _, evt := range globalBroker.eventsSince(lastEventID) {
+			fmt.Fprint(w, evt.render())
+		}
+		flusher.Flush()
+	}
+
 	flusher.Flush()
 
 	for {
 		select {
-		case msg := <-messageChan:
-			fmt.Fprint(w, msg)
+		case <-buf.notify:
+			for _, evt := range buf.drain() {
+				fmt.Fprint(w, evt.render())
+			}
 			flusher.Flush()
 		case <-r.Context().Done():
 			return
 		}
 	}
 }
+
@@ -0,0 +1,98 @@
+package web
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClientBuffer_DropOldest(t *testing.T) {
+	buf := &clientBuffer{capacity: 2, policy: DropOldest, notify: make(chan struct{}, 1)}
+
+	buf.push(sseEvent{id: 1, event: "tick", data: "1"})
+	buf.push(sseEvent{id: 2, event: "tick", data: "2"})
+	buf.push(sseEvent{id: 3, event: "tick", data: "3"})
+
+	events := buf.drain()
+	if len(events) != 2 {
+		t.Fatalf("esperava 2 eventos, obteve %d", len(events))
+	}
+	if events[0].data != "2" || events[1].data != "3" {
+		t.Fatalf("esperava descartar o mais antigo, obteve %+v", events)
+	}
+	if buf.Dropped() != 1 {
+		t.Fatalf("esperava 1 evento descartado, obteve %d", buf.Dropped())
+	}
+}
+
+func TestClientBuffer_DropNewest(t *testing.T) {
+	buf := &clientBuffer{capacity: 2, policy: DropNewest, notify: make(chan struct{}, 1)}
+
+	buf.push(sseEvent{id: 1, event: "tick", data: "1"})
+	buf.push(sseEvent{id: 2, event: "tick", data: "2"})
+	buf.push(sseEvent{id: 3, event: "tick", data: "3"})
+
+	events := buf.drain()
+	if len(events) != 2 || events[0].data != "1" || events[1].data != "2" {
+		t.Fatalf("esperava manter os dois primeiros eventos, obteve %+v", events)
+	}
+}
+
+func TestClientBuffer_Coalesce(t *testing.T) {
+	buf := &clientBuffer{capacity: 2, policy: Coalesce, notify: make(chan struct{}, 1)}
+
+	buf.push(sseEvent{id: 1, event: "progress", data: "10%"})
+	buf.push(sseEvent{id: 2, event: "progress", data: "50%"})
+
+	events := buf.drain()
+	if len(events) != 1 || events[0].data != "50%" {
+		t.Fatalf("esperava coalescer em um único evento 'progress' com o dado mais recente, obteve %+v", events)
+	}
+}
+
+// TestBroker_StalledClientDoesNotBlockOthers garante que um clientBuffer
+// cheio (cliente parado) não impede outros clientes de receberem eventos —
+// o ponto central do pedido que motivou esta reescrita.
+func TestBroker_StalledClientDoesNotBlockOthers(t *testing.T) {
+	stalled := &clientBuffer{capacity: 1, policy: DropOldest, notify: make(chan struct{}, 1)}
+	healthy := &clientBuffer{capacity: 8, policy: DropOldest, notify: make(chan struct{}, 1)}
+
+	b := &Broker{userClients: map[int64][]*clientBuffer{
+		1: {stalled},
+		2: {healthy},
+	}}
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			for _, clients := range b.userClients {
+				for _, c := range clients {
+					c.push(sseEvent{event: "tick", data: "x"})
+				}
+			}
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("broadcast travou — um cliente estagnado bloqueou os demais")
+	}
+
+	if got := len(healthy.drain()); got == 0 {
+		t.Fatal("esperava que o cliente saudável tivesse recebido eventos")
+	}
+}
+
+func TestBroker_EventsSinceReplaysOnlyNewer(t *testing.T) {
+	b := &Broker{userClients: make(map[int64][]*clientBuffer)}
+
+	b.recordGlobalEvent("tick", "1")
+	second := b.recordGlobalEvent("tick", "2")
+	third := b.recordGlobalEvent("tick", "3")
+
+	replay := b.eventsSince(second.id)
+	if len(replay) != 1 || replay[0].id != third.id {
+		t.Fatalf("esperava só o evento após o id %d, obteve %+v", second.id, replay)
+	}
+}
@@ -1,6 +1,7 @@
 package web
 
 import (
+	"bytes"
 	crypto_rand "crypto/rand"
 	"crypto/sha256"
 	"database/sql"
@@ -10,18 +11,26 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/PauloHFS/goth/internal/audit"
+	"github.com/PauloHFS/goth/internal/authz"
 	"github.com/PauloHFS/goth/internal/config"
 	"github.com/PauloHFS/goth/internal/contextkeys"
 	"github.com/PauloHFS/goth/internal/db"
+	"github.com/PauloHFS/goth/internal/filestore"
 	"github.com/PauloHFS/goth/internal/logging"
+	"github.com/PauloHFS/goth/internal/magiclink"
 	"github.com/PauloHFS/goth/internal/middleware"
+	"github.com/PauloHFS/goth/internal/oauth"
+	"github.com/PauloHFS/goth/internal/oidc"
+	"github.com/PauloHFS/goth/internal/pubsub"
 	"github.com/PauloHFS/goth/internal/routes"
+	"github.com/PauloHFS/goth/internal/session"
+	"github.com/PauloHFS/goth/internal/totp"
 	"github.com/PauloHFS/goth/internal/validator"
 	"github.com/PauloHFS/goth/internal/view"
 	"github.com/PauloHFS/goth/internal/view/pages"
@@ -35,6 +44,21 @@ type HandlerDeps struct {
 	Queries        *db.Queries
 	SessionManager *scs.SessionManager
 	Config         *config.Config
+	Auditor        *audit.Auditor
+	Sessions       *session.Store
+	FileStore      filestore.Backend
+	Authz          *authz.Manager
+	OIDC           *oidc.Manager
+	Identities     *oidc.IdentityStore
+	TOTP           *totp.Store
+	MagicLinks     *magiclink.Store
+	UserCache      middleware.UserCache
+	Jobs           *pubsub.Broker
+
+	OAuthClients        *oauth.ClientStore
+	OAuthAuthorizations *oauth.AuthorizationStore
+	OAuthRefreshTokens  *oauth.RefreshStore
+	OAuthKeys           *oauth.KeyStore
 }
 
 // AppHandler é um tipo customizado que permite retornar erros dos handlers
@@ -74,10 +98,52 @@ func RegisterRoutes(mux *http.ServeMux, deps HandlerDeps) {
 	mux.HandleFunc("POST "+routes.Login, Handle(deps, handleLogin))
 	mux.HandleFunc("POST "+routes.Logout, Handle(deps, handleLogout))
 
+	// Segundo fator (TOTP) — ver internal/totp e internal/web/otp.go.
+	mux.HandleFunc("GET "+routes.LoginOTP, Handle(deps, handleLoginOTP))
+	mux.HandleFunc("POST "+routes.LoginOTP, Handle(deps, handleLoginOTP))
+
+	// Login sem senha por link mágico — ver internal/magiclink e
+	// internal/web/magiclink.go. Limitado por IP e por e-mail para conter
+	// abuso do envio de e-mails.
+	mux.HandleFunc("GET "+routes.LoginMagic, func(w http.ResponseWriter, r *http.Request) {
+		templ.Handler(pages.LoginMagic("")).ServeHTTP(w, r)
+	})
+	mux.Handle("POST "+routes.LoginMagic, magicLinkIPLimiter.Middleware(magicLinkEmailLimiter.Middleware(Handle(deps, handleLoginMagic))))
+	mux.HandleFunc("GET "+routes.LoginMagicVerify, Handle(deps, handleLoginMagicVerify))
+
+	// SSO via OIDC/OAuth2 — um ou mais provedores, ver internal/oidc.
+	mux.HandleFunc("GET /auth/{provider}/start", Handle(deps, handleOIDCStart))
+	mux.HandleFunc("GET /auth/{provider}/callback", Handle(deps, handleOIDCCallback))
+
 	// Protected Routes
-	mux.Handle("GET "+routes.Dashboard, middleware.RequireAuth(deps.SessionManager, deps.Queries, Handle(deps, handleDashboard)))
-	mux.Handle("GET "+routes.Admin, middleware.RequireAuth(deps.SessionManager, deps.Queries, Handle(deps, handleAdmin)))
-	mux.Handle("POST /profile/avatar", middleware.RequireAuth(deps.SessionManager, deps.Queries, Handle(deps, handleAvatarUpload)))
+	mux.Handle("GET "+routes.Dashboard, middleware.RequireAuth(deps.SessionManager, deps.Queries, deps.Sessions, Handle(deps, handleDashboard), middleware.WithUserCache(deps.UserCache)))
+	mux.Handle("GET "+routes.Admin, middleware.RequireAuth(deps.SessionManager, deps.Queries, deps.Sessions, Handle(deps, handleAdmin), middleware.WithUserCache(deps.UserCache)))
+	mux.Handle("POST /profile/avatar", middleware.RequireAuth(deps.SessionManager, deps.Queries, deps.Sessions,
+		middleware.RequireOTPElevation(deps.SessionManager, deps.TOTP, Handle(deps, handleAvatarUpload)),
+		middleware.WithUserCache(deps.UserCache)))
+
+	mux.Handle("GET "+routes.Profile2FA, middleware.RequireAuth(deps.SessionManager, deps.Queries, deps.Sessions, Handle(deps, handleProfile2FAStart), middleware.WithUserCache(deps.UserCache)))
+	mux.Handle("POST "+routes.Profile2FA, middleware.RequireAuth(deps.SessionManager, deps.Queries, deps.Sessions, Handle(deps, handleProfile2FAEnable), middleware.WithUserCache(deps.UserCache)))
+
+	// Authorization server OAuth2/OIDC para apps de terceiros — ver
+	// internal/oauth e internal/web/oauth.go.
+	mux.Handle("GET "+routes.OAuthAuthorize, middleware.RequireAuth(deps.SessionManager, deps.Queries, deps.Sessions, Handle(deps, handleOAuthAuthorize), middleware.WithUserCache(deps.UserCache)))
+	mux.Handle("POST "+routes.OAuthAuthorize, middleware.RequireAuth(deps.SessionManager, deps.Queries, deps.Sessions, Handle(deps, handleOAuthAuthorizeSubmit), middleware.WithUserCache(deps.UserCache)))
+	mux.HandleFunc("POST "+routes.OAuthToken, Handle(deps, handleOAuthToken))
+	mux.HandleFunc("GET "+routes.OAuthUserinfo, Handle(deps, handleOAuthUserinfo))
+	mux.HandleFunc("GET "+routes.OIDCDiscovery, Handle(deps, handleOIDCDiscovery))
+	mux.HandleFunc("GET "+routes.JWKS, Handle(deps, handleJWKS))
+
+	mux.Handle("GET "+routes.AdminApps, middleware.RequireAuth(deps.SessionManager, deps.Queries, deps.Sessions, Handle(deps, handleAdminApps), middleware.WithUserCache(deps.UserCache)))
+	mux.Handle("POST "+routes.AdminApps, middleware.RequireAuth(deps.SessionManager, deps.Queries, deps.Sessions, Handle(deps, handleAdminAppsCreate), middleware.WithUserCache(deps.UserCache)))
+
+	mux.Handle("GET "+routes.AccountSessions, middleware.RequireAuth(deps.SessionManager, deps.Queries, deps.Sessions, Handle(deps, handleListSessions), middleware.WithUserCache(deps.UserCache)))
+	mux.Handle("POST "+routes.AccountSessionRevoke, middleware.RequireAuth(deps.SessionManager, deps.Queries, deps.Sessions, Handle(deps, handleRevokeSession), middleware.WithUserCache(deps.UserCache)))
+	mux.Handle("POST "+routes.AccountSessionRevokeOther, middleware.RequireAuth(deps.SessionManager, deps.Queries, deps.Sessions, Handle(deps, handleRevokeOtherSessions), middleware.WithUserCache(deps.UserCache)))
+
+	mux.Handle("GET "+routes.AdminUserPermissions, middleware.RequireAuth(deps.SessionManager, deps.Queries, deps.Sessions,
+		authz.RequirePermission(deps.Authz, "users.manage_permissions", nil)(Handle(deps, handleUserPermissions)),
+		middleware.WithUserCache(deps.UserCache)))
 
 	// Public Routes
 	mux.HandleFunc("GET "+routes.Home, func(w http.ResponseWriter, r *http.Request) {
@@ -187,6 +253,10 @@ func handleRegister(deps HandlerDeps, w http.ResponseWriter, r *http.Request) er
 		return fmt.Errorf("failed to commit registration: %w", err)
 	}
 
+	if deps.Jobs != nil {
+		deps.Jobs.Publish("send_verification_email")
+	}
+
 	logging.AddToEvent(r.Context(),
 		slog.String("outcome", "success"),
 	)
@@ -256,6 +326,10 @@ func handleForgotPassword(deps HandlerDeps, w http.ResponseWriter, r *http.Reque
 		return fmt.Errorf("failed to commit forgot password: %w", err)
 	}
 
+	if deps.Jobs != nil {
+		deps.Jobs.Publish("send_password_reset_email")
+	}
+
 	templ.Handler(pages.ForgotPassword("Se o e-mail existir, um link será enviado.")).ServeHTTP(w, r)
 	return nil
 }
@@ -303,6 +377,19 @@ func handleResetPassword(deps HandlerDeps, w http.ResponseWriter, r *http.Reques
 		logging.Get().Warn("failed to delete password reset token", "error", err)
 	}
 
+	if user, err := qtx.GetUserByEmail(r.Context(), db.GetUserByEmailParams{TenantID: "default", Email: reset.Email}); err == nil {
+		if deps.Sessions != nil {
+			// Nenhuma sessão atual é mantida: o fluxo de reset acontece fora de
+			// qualquer sessão autenticada do usuário.
+			if err := deps.Sessions.RevokeAllExcept(r.Context(), user.ID, ""); err != nil {
+				logging.Get().Warn("failed to revoke sessions after password reset", "error", err)
+			}
+		}
+		if deps.UserCache != nil {
+			deps.UserCache.Invalidate(r.Context(), user.ID)
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit password reset: %w", err)
 	}
@@ -382,6 +469,9 @@ func handleLogin(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error
 			slog.String("outcome", "error"),
 			slog.String("error_reason", "user_not_found"),
 		)
+		if deps.Auditor != nil {
+			_ = deps.Auditor.Record(r.Context(), audit.ActionLoginFailure, 0, "user", email, audit.ResultFailure, nil)
+		}
 		templ.Handler(pages.Login("Usuário ou senha inválidos")).ServeHTTP(w, r)
 		return nil
 	}
@@ -392,22 +482,66 @@ func handleLogin(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error
 			slog.String("error_reason", "invalid_password"),
 			slog.Int64("user_id", user.ID),
 		)
+		if deps.Auditor != nil {
+			_ = deps.Auditor.Record(r.Context(), audit.ActionLoginFailure, user.ID, "user", email, audit.ResultFailure, nil)
+		}
 		templ.Handler(pages.Login("Usuário ou senha inválidos")).ServeHTTP(w, r)
 		return nil
 	}
 
+	if deps.TOTP != nil {
+		if enrollment, err := deps.TOTP.Get(r.Context(), user.ID); err == nil && enrollment.Enabled() {
+			logging.AddToEvent(r.Context(),
+				slog.String("outcome", "otp_required"),
+				slog.Int64("user_id", user.ID),
+			)
+			deps.SessionManager.Put(r.Context(), "pending_user_id", user.ID)
+			http.Redirect(w, r, routes.LoginOTP, http.StatusSeeOther)
+			return nil
+		}
+	}
+
 	logging.AddToEvent(r.Context(),
 		slog.String("outcome", "success"),
 		slog.Int64("user_id", user.ID),
 		slog.String("user_role", user.RoleID),
 	)
 
+	if deps.Auditor != nil {
+		_ = deps.Auditor.Record(r.Context(), audit.ActionLoginSuccess, user.ID, "user", email, audit.ResultSuccess, nil)
+	}
+
 	deps.SessionManager.Put(r.Context(), "user_id", user.ID)
+
+	if deps.Sessions != nil {
+		_, err := deps.Sessions.Create(r.Context(), session.CreateParams{
+			UserID:    user.ID,
+			TenantID:  "default",
+			Token:     deps.SessionManager.Token(r.Context()),
+			UserAgent: r.UserAgent(),
+			IP:        middleware.ExtractIP(r),
+			TTL:       session.DefaultTTL,
+		})
+		if err != nil {
+			logging.Get().Warn("failed to record session", "error", err)
+		}
+	}
+
 	http.Redirect(w, r, routes.Dashboard, http.StatusSeeOther)
 	return nil
 }
 
 func handleLogout(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	if deps.Sessions != nil {
+		tokenHash := session.HashToken(deps.SessionManager.Token(r.Context()))
+		if sess, err := deps.Sessions.GetByTokenHash(r.Context(), tokenHash); err == nil {
+			_ = deps.Sessions.Revoke(r.Context(), sess.ID, sess.UserID)
+			if deps.UserCache != nil {
+				deps.UserCache.Invalidate(r.Context(), sess.UserID)
+			}
+		}
+	}
+
 	if err := deps.SessionManager.Destroy(r.Context()); err != nil {
 		return fmt.Errorf("failed to destroy session: %w", err)
 	}
@@ -485,7 +619,9 @@ func handleAvatarUpload(deps HandlerDeps, w http.ResponseWriter, r *http.Request
 	}
 	defer file.Close()
 
-	if err := validator.ValidateUpload(header.Filename, header.Header.Get("Content-Type"), 2<<20); err != nil {
+	const maxAvatarSize = 2 << 20 // 2MB
+
+	if err := validator.ValidateUpload(header.Filename, header.Header.Get("Content-Type"), maxAvatarSize); err != nil {
 		logging.AddToEvent(r.Context(),
 			slog.String("outcome", "error"),
 			slog.String("error_reason", "validation_failed"),
@@ -495,38 +631,56 @@ func handleAvatarUpload(deps HandlerDeps, w http.ResponseWriter, r *http.Request
 		return nil
 	}
 
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return fmt.Errorf("failed to sniff uploaded file: %w", err)
+	}
+	sniff = sniff[:n]
+
+	if err := validator.ValidateMagicBytes(sniff); err != nil {
+		logging.AddToEvent(r.Context(),
+			slog.String("outcome", "error"),
+			slog.String("error_reason", "magic_bytes_mismatch"),
+			slog.String("filename", header.Filename),
+		)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+
 	ext := strings.ToLower(filepath.Ext(header.Filename))
-	filename := fmt.Sprintf("%d_%d%s", user.ID, time.Now().Unix(), ext)
-	dstPath := filepath.Join("storage", "avatars", filename)
+	sanitized := validator.SanitizeFilename(header.Filename)
+	key := fmt.Sprintf("avatars/%d/%d_%s", user.ID, time.Now().Unix(), sanitized)
 
 	logging.AddToEvent(r.Context(),
 		slog.String("file_extension", ext),
 		slog.Int64("file_size", header.Size),
 	)
 
-	dst, err := os.Create(dstPath)
+	body := io.LimitReader(io.MultiReader(bytes.NewReader(sniff), file), maxAvatarSize)
+
+	avatarURL, err := deps.FileStore.Put(r.Context(), key, body, header.Header.Get("Content-Type"))
 	if err != nil {
 		logging.AddToEvent(r.Context(),
 			slog.String("outcome", "error"),
 			slog.String("error_reason", "file_creation_failed"),
 		)
-		return fmt.Errorf("failed to create file: %w", err)
-	}
-	defer dst.Close()
-
-	if _, err := io.Copy(dst, file); err != nil {
-		return fmt.Errorf("failed to copy file: %w", err)
+		return fmt.Errorf("failed to store file: %w", err)
 	}
 
-	avatarURL := "/storage/avatars/" + filename
 	if err := deps.Queries.UpdateUserAvatar(r.Context(), db.UpdateUserAvatarParams{
 		AvatarUrl: sql.NullString{String: avatarURL, Valid: true},
 		ID:        user.ID,
 	}); err != nil {
 		logging.Get().Warn("failed to update avatar in database", "error", err)
+	} else if deps.UserCache != nil {
+		deps.UserCache.Invalidate(r.Context(), user.ID)
 	}
 
-	jobPayload, _ := json.Marshal(map[string]string{"image": avatarURL})
+	// A chave de storage (não a URL) é enviada para o job, para que workers
+	// em outros nós possam buscar o arquivo via deps.FileStore.Get
+	// independente de qual backend/host serve a URL pública.
+	jobPayload, _ := json.Marshal(map[string]string{"storage_key": key})
 	if _, err := deps.Queries.CreateJob(r.Context(), db.CreateJobParams{
 		TenantID: sql.NullString{String: fmt.Sprintf("%d", user.ID), Valid: true},
 		Type:     "process_ai",
@@ -537,6 +691,8 @@ func handleAvatarUpload(deps HandlerDeps, w http.ResponseWriter, r *http.Request
 			slog.String("outcome", "partial_success"),
 			slog.String("error_reason", "job_creation_failed"),
 		)
+	} else if deps.Jobs != nil {
+		deps.Jobs.Publish("process_ai")
 	}
 
 	logging.AddToEvent(r.Context(),
@@ -586,3 +742,136 @@ func handleAdmin(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error
 	templ.Handler(pages.Admin(user, stats)).ServeHTTP(w, r)
 	return nil
 }
+
+// sessionView é a representação de uma sessão exposta pela API de conta,
+// já com o rótulo de dispositivo resolvido e sem o token_hash.
+type sessionView struct {
+	ID             int64     `json:"id"`
+	Label          string    `json:"label"`
+	IP             string    `json:"ip"`
+	IsOAuth        bool      `json:"is_oauth"`
+	CreatedAt      time.Time `json:"created_at"`
+	LastActivityAt time.Time `json:"last_activity_at"`
+	Current        bool      `json:"current"`
+}
+
+func handleListSessions(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	user, _ := r.Context().Value(contextkeys.UserContextKey).(db.User)
+
+	if deps.Sessions == nil {
+		http.Error(w, "gerenciamento de sessões indisponível", http.StatusServiceUnavailable)
+		return nil
+	}
+
+	sessions, err := deps.Sessions.ListActiveByUser(r.Context(), user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	currentTokenHash := session.HashToken(deps.SessionManager.Token(r.Context()))
+
+	views := make([]sessionView, 0, len(sessions))
+	for _, s := range sessions {
+		views = append(views, sessionView{
+			ID:             s.ID,
+			Label:          s.Label(),
+			IP:             s.IP,
+			IsOAuth:        s.IsOAuth,
+			CreatedAt:      s.CreatedAt,
+			LastActivityAt: s.LastActivityAt,
+			Current:        s.TokenHash == currentTokenHash,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(views)
+}
+
+func handleRevokeSession(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	user, _ := r.Context().Value(contextkeys.UserContextKey).(db.User)
+
+	if deps.Sessions == nil {
+		http.Error(w, "gerenciamento de sessões indisponível", http.StatusServiceUnavailable)
+		return nil
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "id inválido", http.StatusBadRequest)
+		return nil
+	}
+
+	if err := deps.Sessions.Revoke(r.Context(), id, user.ID); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "sessão não encontrada", http.StatusNotFound)
+			return nil
+		}
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	if deps.Auditor != nil {
+		_ = deps.Auditor.Record(r.Context(), audit.ActionSessionRevoked, user.ID, "session", r.PathValue("id"), audit.ResultSuccess, nil)
+	}
+
+	http.Redirect(w, r, routes.AccountSessions, http.StatusSeeOther)
+	return nil
+}
+
+// userPermissionsView expõe o papel e o array bruto de permissões de um
+// usuário, para depuração administrativa de authz.
+type userPermissionsView struct {
+	UserID      int64           `json:"user_id"`
+	RoleID      string          `json:"role_id"`
+	Permissions json.RawMessage `json:"permissions"`
+}
+
+// handleUserPermissions inspeciona as permissões efetivas de qualquer
+// usuário do tenant — rota de depuração protegida por
+// authz.RequirePermission("users.manage_permissions"), concedida hoje apenas
+// ao papel admin (permissions ["*"]).
+func handleUserPermissions(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "id inválido", http.StatusBadRequest)
+		return nil
+	}
+
+	target, err := deps.Queries.GetUserByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, "usuário não encontrado", http.StatusNotFound)
+		return nil
+	}
+
+	var raw json.RawMessage
+	if err := deps.DB.QueryRowContext(r.Context(), "SELECT permissions FROM roles WHERE id = ?", target.RoleID).Scan(&raw); err != nil {
+		return fmt.Errorf("failed to load role permissions: %w", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(userPermissionsView{
+		UserID:      target.ID,
+		RoleID:      target.RoleID,
+		Permissions: raw,
+	})
+}
+
+func handleRevokeOtherSessions(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	user, _ := r.Context().Value(contextkeys.UserContextKey).(db.User)
+
+	if deps.Sessions == nil {
+		http.Error(w, "gerenciamento de sessões indisponível", http.StatusServiceUnavailable)
+		return nil
+	}
+
+	currentTokenHash := session.HashToken(deps.SessionManager.Token(r.Context()))
+	if err := deps.Sessions.RevokeAllExcept(r.Context(), user.ID, currentTokenHash); err != nil {
+		return fmt.Errorf("failed to revoke other sessions: %w", err)
+	}
+
+	if deps.Auditor != nil {
+		_ = deps.Auditor.Record(r.Context(), audit.ActionSessionRevoked, user.ID, "user", user.Email, audit.ResultSuccess, map[string]any{"scope": "all_others"})
+	}
+
+	http.Redirect(w, r, routes.AccountSessions, http.StatusSeeOther)
+	return nil
+}
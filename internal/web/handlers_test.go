@@ -7,8 +7,14 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/PauloHFS/goth/internal/authz"
 	"github.com/PauloHFS/goth/internal/config"
 	"github.com/PauloHFS/goth/internal/db"
+	"github.com/PauloHFS/goth/internal/filestore"
+	"github.com/PauloHFS/goth/internal/magiclink"
+	"github.com/PauloHFS/goth/internal/oauth"
+	"github.com/PauloHFS/goth/internal/oidc"
+	"github.com/PauloHFS/goth/internal/totp"
 	"github.com/alexedwards/scs/v2"
 	_ "github.com/mattn/go-sqlite3"
 	"golang.org/x/crypto/bcrypt"
@@ -32,11 +38,27 @@ func setupTestDeps(t *testing.T) HandlerDeps {
 	queries := db.New(dbConn)
 	sm := scs.New()
 
+	fileStore, err := filestore.NewLocalBackend(t.TempDir(), "/storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	return HandlerDeps{
 		DB:             dbConn,
 		Queries:        queries,
 		SessionManager: sm,
 		Config:         &config.Config{Env: "test"},
+		FileStore:      fileStore,
+		Authz:          authz.NewManager(dbConn),
+		OIDC:           oidc.NewManager(nil),
+		Identities:     oidc.NewIdentityStore(dbConn),
+		TOTP:           totp.NewStore(dbConn),
+		MagicLinks:     magiclink.NewStore(dbConn),
+
+		OAuthClients:        oauth.NewClientStore(dbConn),
+		OAuthAuthorizations: oauth.NewAuthorizationStore(dbConn),
+		OAuthRefreshTokens:  oauth.NewRefreshStore(dbConn),
+		OAuthKeys:           oauth.NewKeyStore(dbConn),
 	}
 }
 
@@ -0,0 +1,169 @@
+package web
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/PauloHFS/goth/internal/audit"
+	"github.com/PauloHFS/goth/internal/db"
+	"github.com/PauloHFS/goth/internal/logging"
+	"github.com/PauloHFS/goth/internal/magiclink"
+	"github.com/PauloHFS/goth/internal/middleware"
+	"github.com/PauloHFS/goth/internal/routes"
+	"github.com/PauloHFS/goth/internal/session"
+	"github.com/PauloHFS/goth/internal/validator"
+	"github.com/PauloHFS/goth/internal/view/pages"
+	"github.com/a-h/templ"
+	"golang.org/x/time/rate"
+)
+
+// magicLinkSentMessage é sempre exibida após POST /login/magic, exista ou
+// não o e-mail informado, para não revelar a terceiros quais e-mails estão
+// cadastrados (mesmo comportamento anti-enumeração de handleForgotPassword).
+const magicLinkSentMessage = "Se o e-mail existir, um link de acesso será enviado."
+
+// magicLinkIPLimiter e magicLinkEmailLimiter limitam, respectivamente, a
+// taxa de solicitações de link mágico por IP e por e-mail, para conter tanto
+// um atacante distribuído quanto alguém bombardeando um único e-mail alheio
+// de links. DefaultRateLimitConfigs["auth"] já é o perfil usado para os
+// demais endpoints sensíveis de autenticação.
+var (
+	magicLinkIPLimiter    = middleware.NewRateLimiter("magic_link_ip", middleware.DefaultRateLimitConfigs["auth"])
+	magicLinkEmailLimiter = middleware.NewRateLimiter("magic_link_email", middleware.RateLimitConfig{
+		Rate:   rate.Limit(1.0 / 60),
+		Burst:  3,
+		Window: time.Minute,
+		KeyFunc: func(r *http.Request) string {
+			return "magic_link_email:" + r.FormValue("email")
+		},
+	})
+)
+
+func handleLoginMagic(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	email := r.FormValue("email")
+
+	if err := validator.ValidateEmail(email); err != nil {
+		templ.Handler(pages.LoginMagic(err.Error())).ServeHTTP(w, r)
+		return nil
+	}
+
+	user, err := deps.Queries.GetUserByEmail(r.Context(), db.GetUserByEmailParams{
+		TenantID: "default",
+		Email:    email,
+	})
+	if err != nil {
+		templ.Handler(pages.LoginMagic(magicLinkSentMessage)).ServeHTTP(w, r)
+		return nil
+	}
+
+	token, err := deps.MagicLinks.Create(r.Context(), magiclink.CreateParams{
+		Email:      email,
+		OriginHash: magiclink.HashOrigin(r.UserAgent(), middleware.ExtractIP(r)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create magic link: %w", err)
+	}
+
+	jobPayload, err := json.Marshal(map[string]string{
+		"email": email,
+		"token": token,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal job payload: %w", err)
+	}
+	if _, err := deps.Queries.CreateJob(r.Context(), db.CreateJobParams{
+		TenantID: sql.NullString{String: "default", Valid: true},
+		Type:     "send_magic_link_email",
+		Payload:  jobPayload,
+		RunAt:    sql.NullTime{Time: time.Now(), Valid: true},
+	}); err != nil {
+		return fmt.Errorf("failed to create job: %w", err)
+	}
+
+	if deps.Jobs != nil {
+		deps.Jobs.Publish("send_magic_link_email")
+	}
+
+	logging.AddToEvent(r.Context(),
+		slog.String("operation", "login_magic_request"),
+		slog.Int64("user_id", user.ID),
+	)
+
+	templ.Handler(pages.LoginMagic(magicLinkSentMessage)).ServeHTTP(w, r)
+	return nil
+}
+
+func handleLoginMagicVerify(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Redirect(w, r, routes.Login+"?error=token_invalido", http.StatusSeeOther)
+		return nil
+	}
+
+	tokenHash := magiclink.HashToken(token)
+
+	link, err := deps.MagicLinks.GetByTokenHash(r.Context(), tokenHash)
+	if err != nil || link.ConsumedAt.Valid || link.Expired(time.Now()) {
+		http.Redirect(w, r, routes.Login+"?error=token_expirado", http.StatusSeeOther)
+		return nil
+	}
+
+	if deps.Config.MagicLink.BindToOrigin {
+		if link.OriginHash != magiclink.HashOrigin(r.UserAgent(), middleware.ExtractIP(r)) {
+			logging.AddToEvent(r.Context(),
+				slog.String("outcome", "error"),
+				slog.String("error_reason", "magic_link_origin_mismatch"),
+			)
+			http.Redirect(w, r, routes.Login+"?error=token_invalido", http.StatusSeeOther)
+			return nil
+		}
+	}
+
+	if err := deps.MagicLinks.Consume(r.Context(), tokenHash); err != nil {
+		http.Redirect(w, r, routes.Login+"?error=token_expirado", http.StatusSeeOther)
+		return nil
+	}
+
+	user, err := deps.Queries.GetUserByEmail(r.Context(), db.GetUserByEmailParams{
+		TenantID: "default",
+		Email:    link.Email,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load user for magic link: %w", err)
+	}
+
+	if deps.TOTP != nil {
+		if enrollment, err := deps.TOTP.Get(r.Context(), user.ID); err == nil && enrollment.Enabled() {
+			deps.SessionManager.Put(r.Context(), "pending_user_id", user.ID)
+			http.Redirect(w, r, routes.LoginOTP, http.StatusSeeOther)
+			return nil
+		}
+	}
+
+	if deps.Auditor != nil {
+		_ = deps.Auditor.Record(r.Context(), audit.ActionLoginSuccess, user.ID, "user", user.Email, audit.ResultSuccess, nil)
+	}
+
+	deps.SessionManager.Put(r.Context(), "user_id", user.ID)
+
+	if deps.Sessions != nil {
+		_, err := deps.Sessions.Create(r.Context(), session.CreateParams{
+			UserID:    user.ID,
+			TenantID:  "default",
+			Token:     deps.SessionManager.Token(r.Context()),
+			UserAgent: r.UserAgent(),
+			IP:        middleware.ExtractIP(r),
+			TTL:       session.DefaultTTL,
+		})
+		if err != nil {
+			logging.Get().Warn("failed to record session", "error", err)
+		}
+	}
+
+	http.Redirect(w, r, routes.Dashboard, http.StatusSeeOther)
+	return nil
+}
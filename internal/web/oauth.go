@@ -0,0 +1,397 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PauloHFS/goth/internal/contextkeys"
+	"github.com/PauloHFS/goth/internal/db"
+	"github.com/PauloHFS/goth/internal/logging"
+	"github.com/PauloHFS/goth/internal/oauth"
+	"github.com/PauloHFS/goth/internal/view/pages"
+	"github.com/a-h/templ"
+)
+
+// handleOAuthAuthorize renderiza a tela de consentimento para o fluxo
+// Authorization Code + PKCE (response_type=code), exigida antes de emitir
+// um código para o client_id/redirect_uri/scope solicitados.
+func handleOAuthAuthorize(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	q := r.URL.Query()
+	if q.Get("response_type") != "code" {
+		http.Error(w, "response_type não suportado", http.StatusBadRequest)
+		return nil
+	}
+
+	client, allowedScopes, err := validateAuthorizeRequest(r.Context(), deps, q)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+
+	templ.Handler(pages.OAuthConsent(client.Name, allowedScopes, q.Get("client_id"), q.Get("redirect_uri"), q.Get("state"), q.Get("code_challenge"), q.Get("code_challenge_method"))).ServeHTTP(w, r)
+	return nil
+}
+
+// handleOAuthAuthorizeSubmit processa a decisão do usuário na tela de
+// consentimento: nega redirecionando com error=access_denied, ou aprova
+// emitindo um código de autorização vinculado ao desafio PKCE recebido.
+func handleOAuthAuthorizeSubmit(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	user, ok := r.Context().Value(contextkeys.UserContextKey).(db.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil
+	}
+
+	redirectURI := r.FormValue("redirect_uri")
+	state := r.FormValue("state")
+
+	// Valida client_id/redirect_uri/PKCE antes de qualquer redirect, mesmo na
+	// recusa: sem isso, um redirect_uri forjado no form chegaria direto a
+	// http.Redirect com o usuário já autenticado, um open redirect usando a
+	// origem deste authorization server.
+	form := url.Values{
+		"client_id":             {r.FormValue("client_id")},
+		"redirect_uri":          {redirectURI},
+		"scope":                 {r.FormValue("scope")},
+		"code_challenge":        {r.FormValue("code_challenge")},
+		"code_challenge_method": {r.FormValue("code_challenge_method")},
+	}
+
+	_, allowedScopes, err := validateAuthorizeRequest(r.Context(), deps, form)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+
+	if r.FormValue("decision") != "approve" {
+		http.Redirect(w, r, redirectURI+"?error=access_denied&state="+url.QueryEscape(state), http.StatusSeeOther)
+		return nil
+	}
+
+	auth, err := deps.OAuthAuthorizations.Create(r.Context(), oauth.CreateParams{
+		UserID:              user.ID,
+		ClientID:            r.FormValue("client_id"),
+		Scopes:              allowedScopes,
+		CodeChallenge:       r.FormValue("code_challenge"),
+		CodeChallengeMethod: r.FormValue("code_challenge_method"),
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.AddToEvent(r.Context(),
+		slog.String("operation", "oauth_authorize"),
+		slog.Int64("user_id", user.ID),
+		slog.String("client_id", auth.ClientID),
+		slog.String("outcome", "success"),
+	)
+
+	redirectURL := redirectURI + "?code=" + url.QueryEscape(auth.Code)
+	if state != "" {
+		redirectURL += "&state=" + url.QueryEscape(state)
+	}
+	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
+	return nil
+}
+
+// validateAuthorizeRequest confere client_id/redirect_uri/PKCE contra o
+// cliente registrado e retorna o conjunto de scopes que ele de fato pode
+// solicitar — compartilhado entre o GET que exibe o consentimento e o POST
+// que o processa, para que um client_id/redirect_uri forjado no form nunca
+// passe sem ser revalidado.
+func validateAuthorizeRequest(ctx context.Context, deps HandlerDeps, q url.Values) (oauth.Client, []string, error) {
+	clientID := q.Get("client_id")
+	client, err := deps.OAuthClients.Get(ctx, clientID)
+	if err != nil {
+		return oauth.Client{}, nil, fmt.Errorf("client_id desconhecido")
+	}
+
+	redirectURI := q.Get("redirect_uri")
+	if !client.HasRedirectURI(redirectURI) {
+		return oauth.Client{}, nil, fmt.Errorf("redirect_uri não cadastrado para este cliente")
+	}
+
+	if q.Get("code_challenge") == "" || !strings.EqualFold(q.Get("code_challenge_method"), "S256") {
+		return oauth.Client{}, nil, fmt.Errorf("code_challenge com method S256 é obrigatório")
+	}
+
+	requestedScopes := strings.Fields(q.Get("scope"))
+	allowedScopes := client.AllowedScopeSet(requestedScopes)
+
+	return client, allowedScopes, nil
+}
+
+// oauthErrorResponse escreve um erro no formato exigido pelo RFC 6749 para
+// POST /oauth/token (corpo JSON, status 400).
+func oauthErrorResponse(w http.ResponseWriter, code, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+}
+
+// tokenResponse é o corpo de sucesso de POST /oauth/token.
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope"`
+}
+
+// handleOAuthToken troca um código de autorização, refresh_token ou
+// credenciais de cliente por tokens, conforme o grant_type recebido.
+func handleOAuthToken(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	grantType := r.FormValue("grant_type")
+	clientID := r.FormValue("client_id")
+
+	client, err := deps.OAuthClients.Get(ctx, clientID)
+	if err != nil {
+		oauthErrorResponse(w, "invalid_client", "client_id desconhecido")
+		return nil
+	}
+	if !client.ValidateSecret(r.FormValue("client_secret")) {
+		oauthErrorResponse(w, "invalid_client", "client_secret inválido")
+		return nil
+	}
+
+	var resp tokenResponse
+	switch grantType {
+	case "authorization_code":
+		auth, err := deps.OAuthAuthorizations.Consume(ctx, r.FormValue("code"))
+		if err != nil {
+			oauthErrorResponse(w, "invalid_grant", err.Error())
+			return nil
+		}
+		if auth.ClientID != clientID {
+			oauthErrorResponse(w, "invalid_grant", "código não pertence a este cliente")
+			return nil
+		}
+		if !auth.VerifyPKCE(r.FormValue("code_verifier")) {
+			oauthErrorResponse(w, "invalid_grant", "code_verifier não confere com o code_challenge")
+			return nil
+		}
+
+		resp, err = issueTokenResponse(ctx, deps, auth.UserID, clientID, auth.Scopes, true)
+		if err != nil {
+			return err
+		}
+
+	case "refresh_token":
+		oldRefreshToken := r.FormValue("refresh_token")
+		grant, err := deps.OAuthRefreshTokens.Validate(ctx, oldRefreshToken, clientID)
+		if err != nil {
+			oauthErrorResponse(w, "invalid_grant", err.Error())
+			return nil
+		}
+
+		resp, err = issueTokenResponse(ctx, deps, grant.UserID, clientID, grant.Scopes, containsScope(grant.Scopes, "openid"))
+		if err != nil {
+			return err
+		}
+
+		// Rotaciona: o refresh_token usado nesta troca não serve para outra,
+		// só o novo emitido por issueTokenResponse acima. Isso limita o
+		// estrago de um refresh_token vazado ao intervalo até o próximo uso
+		// legítimo, em vez de ficar válido pelos 30 dias inteiros de
+		// RefreshTokenTTL.
+		if err := deps.OAuthRefreshTokens.Revoke(ctx, oldRefreshToken); err != nil {
+			return err
+		}
+
+	case "client_credentials":
+		scopes := client.AllowedScopeSet(strings.Fields(r.FormValue("scope")))
+		key, kid, err := deps.OAuthKeys.Active(ctx)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		accessToken, err := oauth.SignJWT(key, kid, oauth.Claims{
+			"iss":   deps.Config.BaseURL,
+			"sub":   clientID,
+			"aud":   clientID,
+			"scope": strings.Join(scopes, " "),
+			"iat":   now.Unix(),
+			"exp":   now.Add(oauth.AccessTokenTTL).Unix(),
+		})
+		if err != nil {
+			return err
+		}
+		resp = tokenResponse{
+			AccessToken: accessToken,
+			TokenType:   "Bearer",
+			ExpiresIn:   int(oauth.AccessTokenTTL.Seconds()),
+			Scope:       strings.Join(scopes, " "),
+		}
+
+	default:
+		oauthErrorResponse(w, "unsupported_grant_type", fmt.Sprintf("grant_type %q não suportado", grantType))
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	return json.NewEncoder(w).Encode(resp)
+}
+
+// issueTokenResponse monta o access token (e, quando includeIDToken e o
+// escopo openid foram concedidos, o ID token) mais um refresh_token novo
+// para userID/clientID/scopes.
+func issueTokenResponse(ctx context.Context, deps HandlerDeps, userID int64, clientID string, scopes []string, includeIDToken bool) (tokenResponse, error) {
+	key, kid, err := deps.OAuthKeys.Active(ctx)
+	if err != nil {
+		return tokenResponse{}, err
+	}
+
+	now := time.Now()
+	sub := fmt.Sprintf("%d", userID)
+
+	accessToken, err := oauth.SignJWT(key, kid, oauth.Claims{
+		"iss":   deps.Config.BaseURL,
+		"sub":   sub,
+		"aud":   clientID,
+		"scope": strings.Join(scopes, " "),
+		"iat":   now.Unix(),
+		"exp":   now.Add(oauth.AccessTokenTTL).Unix(),
+	})
+	if err != nil {
+		return tokenResponse{}, err
+	}
+
+	refreshToken, err := deps.OAuthRefreshTokens.Create(ctx, userID, clientID, scopes)
+	if err != nil {
+		return tokenResponse{}, err
+	}
+
+	resp := tokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(oauth.AccessTokenTTL.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        strings.Join(scopes, " "),
+	}
+
+	if includeIDToken && containsScope(scopes, "openid") {
+		user, err := deps.Queries.GetUserByID(ctx, userID)
+		if err != nil {
+			return tokenResponse{}, err
+		}
+
+		idToken, err := oauth.SignJWT(key, kid, oauth.Claims{
+			"iss":   deps.Config.BaseURL,
+			"sub":   sub,
+			"aud":   clientID,
+			"email": user.Email,
+			"iat":   now.Unix(),
+			"exp":   now.Add(oauth.IDTokenTTL).Unix(),
+		})
+		if err != nil {
+			return tokenResponse{}, err
+		}
+		resp.IDToken = idToken
+	}
+
+	return resp, nil
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// handleOAuthUserinfo devolve as claims do usuário identificado pelo access
+// token Bearer, no formato userinfo_endpoint do OIDC.
+func handleOAuthUserinfo(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	authHeader := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		http.Error(w, "access token ausente", http.StatusUnauthorized)
+		return nil
+	}
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+
+	kid, err := oauth.ParseKID(token)
+	if err != nil {
+		http.Error(w, "access token inválido", http.StatusUnauthorized)
+		return nil
+	}
+
+	pub, err := deps.OAuthKeys.PublicKey(r.Context(), kid)
+	if err != nil {
+		http.Error(w, "access token inválido", http.StatusUnauthorized)
+		return nil
+	}
+
+	claims, err := oauth.VerifyJWT(token, pub)
+	if err != nil {
+		http.Error(w, "access token inválido ou expirado", http.StatusUnauthorized)
+		return nil
+	}
+
+	sub, _ := claims["sub"].(string)
+	var userID int64
+	if _, err := fmt.Sscanf(sub, "%d", &userID); err != nil {
+		http.Error(w, "access token inválido", http.StatusUnauthorized)
+		return nil
+	}
+
+	user, err := deps.Queries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "usuário não encontrado", http.StatusNotFound)
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(map[string]any{
+		"sub":   sub,
+		"email": user.Email,
+	})
+}
+
+// handleOIDCDiscovery publica o documento de descoberta em
+// /.well-known/openid-configuration, usado por clientes OIDC para localizar
+// os demais endpoints deste authorization server.
+func handleOIDCDiscovery(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	base := deps.Config.BaseURL
+	doc := map[string]any{
+		"issuer":                                base,
+		"authorization_endpoint":                base + "/oauth/authorize",
+		"token_endpoint":                        base + "/oauth/token",
+		"userinfo_endpoint":                     base + "/oauth/userinfo",
+		"jwks_uri":                              base + "/.well-known/jwks.json",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported":  []string{"RS256"},
+		"scopes_supported":                       []string{"openid", "email", "profile"},
+		"token_endpoint_auth_methods_supported":   []string{"client_secret_post"},
+		"grant_types_supported":                  []string{"authorization_code", "refresh_token", "client_credentials"},
+		"code_challenge_methods_supported":        []string{"S256"},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(doc)
+}
+
+// handleJWKS publica as chaves públicas ativas (e em janela de graça após
+// rotação) em /.well-known/jwks.json, para que clientes verifiquem a
+// assinatura dos tokens emitidos por este servidor.
+func handleJWKS(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	set, err := deps.OAuthKeys.PublicJWKS(r.Context())
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	return json.NewEncoder(w).Encode(set)
+}
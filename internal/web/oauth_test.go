@@ -0,0 +1,411 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/PauloHFS/goth/internal/contextkeys"
+	"github.com/PauloHFS/goth/internal/db"
+	"github.com/PauloHFS/goth/internal/oidc"
+)
+
+// registerTestOAuthClient garante as tabelas do authorization server (fora
+// do fluxo normal de db.RunMigrations, ver ClientStore.EnsureTable) e
+// cadastra um cliente público (sem client_secret, a proteção fica só por
+// conta do PKCE) com uma única redirect_uri registrada, pronto para os
+// testes de handleOAuthAuthorizeSubmit/handleOAuthToken.
+func registerTestOAuthClient(t *testing.T, deps HandlerDeps) (clientID, redirectURI string) {
+	t.Helper()
+
+	if err := deps.OAuthClients.EnsureTable(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := deps.OAuthAuthorizations.EnsureTable(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := deps.OAuthRefreshTokens.EnsureTable(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := deps.OAuthKeys.EnsureTable(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	redirectURI = "https://client.example/callback"
+	clientID, _, err := deps.OAuthClients.Register(context.Background(), "Test Client", []string{redirectURI}, []string{"read"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return clientID, redirectURI
+}
+
+func requireUser(t *testing.T, deps HandlerDeps) db.User {
+	t.Helper()
+	_, err := deps.DB.ExecContext(context.Background(), "INSERT INTO tenants (id, name, settings) VALUES (?, ?, ?)", "default", "Default Tenant", []byte("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = deps.DB.ExecContext(context.Background(), "INSERT OR IGNORE INTO roles (id, name, permissions) VALUES (?, ?, ?)", "user", "User", []byte("[]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := deps.Queries.CreateUser(context.Background(), db.CreateUserParams{
+		TenantID:     "default",
+		Email:        "oauth-user@example.com",
+		PasswordHash: "hash",
+		RoleID:       "user",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// withUser injeta user no contexto como contextkeys.UserContextKey, do jeito
+// que o middleware de autenticação faz antes de chegar aos handlers.
+func withUser(r *http.Request, user db.User) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), contextkeys.UserContextKey, user))
+}
+
+func TestHandleOAuthAuthorizeSubmit(t *testing.T) {
+	t.Run("ApproveRedirectsWithCode", func(t *testing.T) {
+		deps := setupTestDeps(t)
+		if err := deps.OAuthAuthorizations.EnsureTable(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		clientID, redirectURI := registerTestOAuthClient(t, deps)
+		user := requireUser(t, deps)
+
+		verifier, err := oidc.RandomString(32)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		form := url.Values{
+			"decision":              {"approve"},
+			"client_id":             {clientID},
+			"redirect_uri":          {redirectURI},
+			"scope":                 {"read"},
+			"state":                 {"xyz"},
+			"code_challenge":        {oidc.Challenge(verifier)},
+			"code_challenge_method": {"S256"},
+		}
+		req := httptest.NewRequest("POST", "/oauth/authorize", nil)
+		req.PostForm = form
+		req = withUser(req, user)
+		rr := httptest.NewRecorder()
+
+		Handle(deps, handleOAuthAuthorizeSubmit).ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusSeeOther {
+			t.Fatalf("expected status %d, got %d (body: %s)", http.StatusSeeOther, rr.Code, rr.Body.String())
+		}
+		loc, err := url.Parse(rr.Header().Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if loc.Query().Get("code") == "" {
+			t.Error("expected a code query param on redirect")
+		}
+		if loc.Query().Get("state") != "xyz" {
+			t.Errorf("expected state to be echoed back, got %q", loc.Query().Get("state"))
+		}
+	})
+
+	t.Run("DenyRedirectsWithAccessDenied", func(t *testing.T) {
+		deps := setupTestDeps(t)
+		if err := deps.OAuthAuthorizations.EnsureTable(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		clientID, redirectURI := registerTestOAuthClient(t, deps)
+		user := requireUser(t, deps)
+
+		form := url.Values{
+			"decision":              {"deny"},
+			"client_id":             {clientID},
+			"redirect_uri":          {redirectURI},
+			"scope":                 {"read"},
+			"state":                 {"xyz"},
+			"code_challenge":        {"abc"},
+			"code_challenge_method": {"S256"},
+		}
+		req := httptest.NewRequest("POST", "/oauth/authorize", nil)
+		req.PostForm = form
+		req = withUser(req, user)
+		rr := httptest.NewRecorder()
+
+		Handle(deps, handleOAuthAuthorizeSubmit).ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusSeeOther {
+			t.Fatalf("expected status %d, got %d (body: %s)", http.StatusSeeOther, rr.Code, rr.Body.String())
+		}
+		loc, err := url.Parse(rr.Header().Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if loc.Query().Get("error") != "access_denied" {
+			t.Errorf("expected error=access_denied, got %q", loc.Query().Get("error"))
+		}
+	})
+
+	t.Run("DenyRejectsUnregisteredRedirectURI", func(t *testing.T) {
+		deps := setupTestDeps(t)
+		if err := deps.OAuthAuthorizations.EnsureTable(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		clientID, _ := registerTestOAuthClient(t, deps)
+		user := requireUser(t, deps)
+
+		// redirect_uri forjado, nunca cadastrado para este cliente — a
+		// recusa precisa validar contra o cliente antes de redirecionar,
+		// senão isso vira um open redirect usando a origem deste servidor.
+		form := url.Values{
+			"decision":              {"deny"},
+			"client_id":             {clientID},
+			"redirect_uri":          {"https://attacker.example/steal"},
+			"scope":                 {"read"},
+			"state":                 {"xyz"},
+			"code_challenge":        {"abc"},
+			"code_challenge_method": {"S256"},
+		}
+		req := httptest.NewRequest("POST", "/oauth/authorize", nil)
+		req.PostForm = form
+		req = withUser(req, user)
+		rr := httptest.NewRecorder()
+
+		Handle(deps, handleOAuthAuthorizeSubmit).ServeHTTP(rr, req)
+
+		if rr.Code == http.StatusSeeOther {
+			t.Fatalf("expected the deny branch to reject an unregistered redirect_uri, got redirect to %q", rr.Header().Get("Location"))
+		}
+	})
+}
+
+// approveAndExchangeCode aprova um consentimento e troca o código resultante
+// por tokens, devolvendo a resposta decodificada, o code_verifier usado e o
+// código já consumido — usado como setup pelos testes de
+// grant_type=refresh_token e de replay do código de autorização.
+func approveAndExchangeCode(t *testing.T, deps HandlerDeps, clientID, redirectURI string, user db.User) (resp tokenResponse, verifier, code string) {
+	t.Helper()
+
+	var err error
+	verifier, err = oidc.RandomString(32)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	approveForm := url.Values{
+		"decision":              {"approve"},
+		"client_id":             {clientID},
+		"redirect_uri":          {redirectURI},
+		"scope":                 {"read"},
+		"code_challenge":        {oidc.Challenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	approveReq := httptest.NewRequest("POST", "/oauth/authorize", nil)
+	approveReq.PostForm = approveForm
+	approveReq = withUser(approveReq, user)
+	approveRR := httptest.NewRecorder()
+	Handle(deps, handleOAuthAuthorizeSubmit).ServeHTTP(approveRR, approveReq)
+
+	if approveRR.Code != http.StatusSeeOther {
+		t.Fatalf("expected approve to redirect, got %d (body: %s)", approveRR.Code, approveRR.Body.String())
+	}
+	loc, err := url.Parse(approveRR.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code = loc.Query().Get("code")
+	if code == "" {
+		t.Fatal("expected a code in the approve redirect")
+	}
+
+	resp = exchangeToken(t, deps, url.Values{
+		"grant_type":    {"authorization_code"},
+		"client_id":     {clientID},
+		"client_secret": {""},
+		"code":          {code},
+		"code_verifier": {verifier},
+	})
+	return resp, verifier, code
+}
+
+func exchangeToken(t *testing.T, deps HandlerDeps, form url.Values) tokenResponse {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/oauth/token", nil)
+	req.PostForm = form
+	rr := httptest.NewRecorder()
+
+	Handle(deps, handleOAuthToken).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d (body: %s)", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var resp tokenResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode token response: %v (body: %s)", err, rr.Body.String())
+	}
+	return resp
+}
+
+func TestHandleOAuthToken(t *testing.T) {
+	t.Run("AuthorizationCodeGrant", func(t *testing.T) {
+		deps := setupTestDeps(t)
+		if err := deps.OAuthAuthorizations.EnsureTable(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		clientID, redirectURI := registerTestOAuthClient(t, deps)
+		user := requireUser(t, deps)
+
+		resp, _, _ := approveAndExchangeCode(t, deps, clientID, redirectURI, user)
+
+		if resp.AccessToken == "" {
+			t.Error("expected a non-empty access_token")
+		}
+		if resp.RefreshToken == "" {
+			t.Error("expected a non-empty refresh_token")
+		}
+	})
+
+	t.Run("PKCEMismatchRejected", func(t *testing.T) {
+		deps := setupTestDeps(t)
+		if err := deps.OAuthAuthorizations.EnsureTable(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		clientID, redirectURI := registerTestOAuthClient(t, deps)
+		user := requireUser(t, deps)
+
+		verifier, err := oidc.RandomString(32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		approveForm := url.Values{
+			"decision":              {"approve"},
+			"client_id":             {clientID},
+			"redirect_uri":          {redirectURI},
+			"scope":                 {"read"},
+			"code_challenge":        {oidc.Challenge(verifier)},
+			"code_challenge_method": {"S256"},
+		}
+		approveReq := httptest.NewRequest("POST", "/oauth/authorize", nil)
+		approveReq.PostForm = approveForm
+		approveReq = withUser(approveReq, user)
+		approveRR := httptest.NewRecorder()
+		Handle(deps, handleOAuthAuthorizeSubmit).ServeHTTP(approveRR, approveReq)
+
+		loc, _ := url.Parse(approveRR.Header().Get("Location"))
+		code := loc.Query().Get("code")
+
+		req := httptest.NewRequest("POST", "/oauth/token", nil)
+		req.PostForm = url.Values{
+			"grant_type":    {"authorization_code"},
+			"client_id":     {clientID},
+			"client_secret": {""},
+			"code":          {code},
+			"code_verifier": {"wrong-verifier"},
+		}
+		rr := httptest.NewRecorder()
+		Handle(deps, handleOAuthToken).ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected status %d for a mismatched code_verifier, got %d (body: %s)", http.StatusBadRequest, rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("AuthorizationCodeReplayRejected", func(t *testing.T) {
+		deps := setupTestDeps(t)
+		if err := deps.OAuthAuthorizations.EnsureTable(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		clientID, redirectURI := registerTestOAuthClient(t, deps)
+		user := requireUser(t, deps)
+
+		// O código já foi consumido pela troca dentro de
+		// approveAndExchangeCode; reutilizá-lo precisa falhar mesmo com o
+		// code_verifier correto.
+		_, verifier, code := approveAndExchangeCode(t, deps, clientID, redirectURI, user)
+
+		req := httptest.NewRequest("POST", "/oauth/token", nil)
+		req.PostForm = url.Values{
+			"grant_type":    {"authorization_code"},
+			"client_id":     {clientID},
+			"client_secret": {""},
+			"code":          {code},
+			"code_verifier": {verifier},
+		}
+		rr := httptest.NewRecorder()
+		Handle(deps, handleOAuthToken).ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected status %d for a replayed authorization code, got %d (body: %s)", http.StatusBadRequest, rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("RefreshTokenGrantRotatesToken", func(t *testing.T) {
+		deps := setupTestDeps(t)
+		if err := deps.OAuthAuthorizations.EnsureTable(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		clientID, redirectURI := registerTestOAuthClient(t, deps)
+		user := requireUser(t, deps)
+
+		original, _, _ := approveAndExchangeCode(t, deps, clientID, redirectURI, user)
+		if original.RefreshToken == "" {
+			t.Fatal("setup: expected a refresh_token from the authorization_code exchange")
+		}
+
+		refreshed := exchangeToken(t, deps, url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {clientID},
+			"client_secret": {""},
+			"refresh_token": {original.RefreshToken},
+		})
+
+		if refreshed.AccessToken == "" {
+			t.Error("expected a non-empty access_token from the refresh")
+		}
+		if refreshed.RefreshToken == "" {
+			t.Error("expected the refresh to return a new refresh_token (rotation)")
+		}
+		if refreshed.RefreshToken == original.RefreshToken {
+			t.Error("expected the rotated refresh_token to differ from the one consumed")
+		}
+
+		// O refresh_token original foi consumido pela rotação: reutilizá-lo
+		// precisa falhar, mesmo antes de expirar.
+		req := httptest.NewRequest("POST", "/oauth/token", nil)
+		req.PostForm = url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {clientID},
+			"client_secret": {""},
+			"refresh_token": {original.RefreshToken},
+		}
+		rr := httptest.NewRecorder()
+		Handle(deps, handleOAuthToken).ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected the rotated-out refresh_token to be rejected with %d, got %d (body: %s)", http.StatusBadRequest, rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("ClientCredentialsGrant", func(t *testing.T) {
+		deps := setupTestDeps(t)
+		clientID, _ := registerTestOAuthClient(t, deps)
+
+		resp := exchangeToken(t, deps, url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {clientID},
+			"client_secret": {""},
+			"scope":         {"read"},
+		})
+
+		if resp.AccessToken == "" {
+			t.Error("expected a non-empty access_token")
+		}
+		if resp.RefreshToken != "" {
+			t.Error("client_credentials não deveria emitir refresh_token")
+		}
+	})
+}
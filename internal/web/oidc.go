@@ -0,0 +1,283 @@
+package web
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/PauloHFS/goth/internal/db"
+	"github.com/PauloHFS/goth/internal/logging"
+	"github.com/PauloHFS/goth/internal/middleware"
+	"github.com/PauloHFS/goth/internal/oidc"
+	"github.com/PauloHFS/goth/internal/routes"
+	"github.com/PauloHFS/goth/internal/session"
+	"github.com/PauloHFS/goth/internal/validator"
+)
+
+// handleOIDCStart inicia o fluxo de Authorization Code com PKCE para o
+// provedor {provider}, redirecionando o usuário ao seu authorization_endpoint.
+func handleOIDCStart(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	cfg := deps.Config.OIDC
+	if !cfg.Enabled || deps.OIDC == nil {
+		http.Error(w, "SSO não está habilitado", http.StatusNotFound)
+		return nil
+	}
+
+	providerName := r.PathValue("provider")
+	if _, ok := deps.OIDC.Provider(providerName); !ok {
+		http.Error(w, "provedor desconhecido", http.StatusNotFound)
+		return nil
+	}
+
+	state, err := oidc.RandomString(32)
+	if err != nil {
+		return err
+	}
+	nonce, err := oidc.RandomString(32)
+	if err != nil {
+		return err
+	}
+	verifier, err := oidc.RandomString(48)
+	if err != nil {
+		return err
+	}
+
+	deps.SessionManager.Put(r.Context(), "oidc_provider", providerName)
+	deps.SessionManager.Put(r.Context(), "oidc_state", state)
+	deps.SessionManager.Put(r.Context(), "oidc_nonce", nonce)
+	deps.SessionManager.Put(r.Context(), "oidc_code_verifier", verifier)
+
+	// Permite que o app funcione na frente de um consent server (ex: Hydra/Ory),
+	// repassando o login_challenge recebido do proxy reverso.
+	extra := url.Values{}
+	if challenge := r.URL.Query().Get("login_challenge"); challenge != "" {
+		extra.Set("login_challenge", challenge)
+	}
+
+	authURL, err := deps.OIDC.AuthCodeURL(providerName, state, nonce, verifier, extra)
+	if err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, authURL, http.StatusSeeOther)
+	return nil
+}
+
+var errOIDCStateMismatch = errors.New("state inválido ou expirado")
+
+// handleOIDCCallback troca o código de autorização por tokens, valida o
+// state/nonce da sessão e a assinatura do ID token (JWKS), então resolve (ou
+// provisiona/linka) o usuário local, emitindo a mesma sessão usada pelo
+// login por senha. O vínculo com o provedor é persistido em identities,
+// por (provider, subject); na ausência de um vínculo já existente, o
+// usuário é resolvido por e-mail verificado, preservando contas já
+// cadastradas por senha.
+func handleOIDCCallback(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	cfg := deps.Config.OIDC
+	if !cfg.Enabled || deps.OIDC == nil {
+		http.Error(w, "SSO não está habilitado", http.StatusNotFound)
+		return nil
+	}
+
+	providerName := r.PathValue("provider")
+	provider, ok := deps.OIDC.Provider(providerName)
+	if !ok {
+		http.Error(w, "provedor desconhecido", http.StatusNotFound)
+		return nil
+	}
+
+	ctx, event := logging.NewEventContext(r.Context())
+	event.Add(slog.String("operation", "oidc_callback"), slog.String("provider", providerName))
+
+	expectedProvider := deps.SessionManager.GetString(ctx, "oidc_provider")
+	expectedState := deps.SessionManager.GetString(ctx, "oidc_state")
+	nonce := deps.SessionManager.GetString(ctx, "oidc_nonce")
+	verifier := deps.SessionManager.GetString(ctx, "oidc_code_verifier")
+	deps.SessionManager.Remove(ctx, "oidc_provider")
+	deps.SessionManager.Remove(ctx, "oidc_state")
+	deps.SessionManager.Remove(ctx, "oidc_nonce")
+	deps.SessionManager.Remove(ctx, "oidc_code_verifier")
+
+	if expectedProvider != providerName || expectedState == "" || r.URL.Query().Get("state") != expectedState {
+		event.Add(slog.String("outcome", "error"), slog.String("error_reason", "state_mismatch"))
+		logging.Get().Log(ctx, slog.LevelWarn, "oidc callback rejected", event.Attrs()...)
+		return errOIDCStateMismatch
+	}
+
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		return fmt.Errorf("código de autorização ausente")
+	}
+
+	tokens, err := deps.OIDC.Exchange(ctx, providerName, code, verifier)
+	if err != nil {
+		return err
+	}
+
+	claims, err := deps.OIDC.VerifyIDToken(ctx, providerName, tokens.IDToken, nonce)
+	if err != nil {
+		event.Add(slog.String("outcome", "error"), slog.String("error_reason", "invalid_id_token"))
+		logging.Get().Log(ctx, slog.LevelWarn, "oidc callback rejected", event.Attrs()...)
+		return fmt.Errorf("id_token inválido: %w", err)
+	}
+
+	identity := oidc.MapIdentity(provider, claims)
+	if identity.Email == "" && tokens.AccessToken != "" {
+		// Nem todo provedor embute o e-mail no ID token (ex. GitHub); quando
+		// faltar, complementamos via userinfo_endpoint.
+		if info, err := deps.OIDC.Userinfo(ctx, providerName, tokens.AccessToken); err == nil {
+			if userinfoIdentity := oidc.MapIdentity(provider, info); userinfoIdentity.Email != "" {
+				identity.Email = userinfoIdentity.Email
+				identity.EmailVerified = userinfoIdentity.EmailVerified
+			}
+		}
+	}
+
+	if err := validator.ValidateEmail(identity.Email); err != nil {
+		event.Add(slog.String("outcome", "error"), slog.String("error_reason", "email_not_verified"))
+		logging.Get().Log(ctx, slog.LevelWarn, "oidc callback rejected", event.Attrs()...)
+		return fmt.Errorf("provedor não retornou um e-mail válido")
+	}
+
+	roleID := cfg.DefaultRole
+	if roleID == "" {
+		roleID = "user"
+	}
+	if cfg.RolesClaim != "" {
+		for _, claimed := range rolesFromClaims(claims, cfg.RolesClaim) {
+			if mapped, ok := cfg.RoleMapping[claimed]; ok {
+				roleID = mapped
+				break
+			}
+		}
+	}
+
+	user, err := deps.resolveOIDCUser(ctx, providerName, identity, roleID)
+	if err != nil {
+		return fmt.Errorf("falha ao resolver usuário via SSO: %w", err)
+	}
+
+	event.Add(
+		slog.String("outcome", "success"),
+		slog.Int64("user_id", user.ID),
+		slog.String("provider_subject", identity.Subject),
+	)
+	logging.Get().Log(ctx, slog.LevelInfo, "oidc login succeeded", event.Attrs()...)
+
+	deps.SessionManager.Put(ctx, "user_id", user.ID)
+
+	if deps.Sessions != nil {
+		_, err := deps.Sessions.Create(ctx, session.CreateParams{
+			UserID:    user.ID,
+			TenantID:  "default",
+			Token:     deps.SessionManager.Token(ctx),
+			UserAgent: r.UserAgent(),
+			IP:        middleware.ExtractIP(r),
+			IsOAuth:   true,
+			TTL:       session.DefaultTTL,
+		})
+		if err != nil {
+			logging.Get().Warn("failed to record oidc session", "error", err)
+		}
+	}
+
+	http.Redirect(w, r, routes.Dashboard, http.StatusSeeOther)
+	return nil
+}
+
+// resolveOIDCUser linka (provider, identity.Subject) a um db.User, na ordem:
+// 1) identidade já linkada; 2) conta existente com o mesmo e-mail, só quando
+// identity.EmailVerified é true (linkando-a); 3) provisiona uma nova conta
+// federada (sem senha). Auto-linkar por e-mail não verificado permitiria que
+// qualquer IdP que deixe o usuário declarar um e-mail arbitrário (ou uma
+// configuração de provider mal-feita) assumisse silenciosamente uma conta
+// local já existente só por coincidência de endereço — por isso um e-mail
+// não verificado sempre cai no caminho de provisionar uma conta nova.
+func (deps HandlerDeps) resolveOIDCUser(ctx context.Context, providerName string, identity oidc.Identity, roleID string) (db.User, error) {
+	if deps.Identities != nil {
+		if userID, err := deps.Identities.FindUserID(ctx, providerName, identity.Subject); err == nil {
+			user, err := deps.Queries.GetUserByID(ctx, userID)
+			if err != nil {
+				return db.User{}, err
+			}
+			if roleID != user.RoleID {
+				// O IdP é a fonte da verdade para roles: se o claim mapeado
+				// mudou desde o último login, refletimos no usuário existente.
+				if _, err := deps.DB.ExecContext(ctx, "UPDATE users SET role_id = ? WHERE id = ?", roleID, user.ID); err != nil {
+					logging.Get().Warn("failed to sync oidc role", "error", err, "user_id", user.ID)
+				} else {
+					user.RoleID = roleID
+					if deps.UserCache != nil {
+						deps.UserCache.Invalidate(ctx, user.ID)
+					}
+					if deps.Sessions != nil {
+						// Mesma política de handleResetPassword: uma troca de
+						// role invalida todas as sessões existentes. Não há
+						// uma sessão atual a preservar ainda (a deste login
+						// só é criada depois que resolveOIDCUser retorna),
+						// então passamos "" para revogar todas.
+						if err := deps.Sessions.RevokeAllExcept(ctx, user.ID, ""); err != nil {
+							logging.Get().Warn("failed to revoke sessions after oidc role sync", "error", err, "user_id", user.ID)
+						}
+					}
+				}
+			}
+			return user, nil
+		}
+	}
+
+	var (
+		user db.User
+		err  error
+	)
+	if identity.EmailVerified {
+		user, err = deps.Queries.GetUserByEmail(ctx, db.GetUserByEmailParams{
+			TenantID: "default",
+			Email:    identity.Email,
+		})
+	} else {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		user, err = deps.Queries.CreateUser(ctx, db.CreateUserParams{
+			TenantID:     "default",
+			Email:        identity.Email,
+			PasswordHash: "",
+			RoleID:       roleID,
+		})
+		if err != nil {
+			return db.User{}, err
+		}
+	}
+
+	if deps.Identities != nil {
+		if err := deps.Identities.Link(ctx, providerName, identity.Subject, user.ID); err != nil {
+			logging.Get().Warn("failed to link oidc identity", "error", err, "user_id", user.ID)
+		}
+	}
+
+	return user, nil
+}
+
+// rolesFromClaims extrai um array de roles da claim key, aceitando tanto uma
+// string única quanto uma lista — provedores divergem em como emitem o claim.
+func rolesFromClaims(claims oidc.Claims, key string) []string {
+	switch v := claims[key].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		roles := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				roles = append(roles, s)
+			}
+		}
+		return roles
+	default:
+		return nil
+	}
+}
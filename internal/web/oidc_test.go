@@ -0,0 +1,185 @@
+package web
+
+import (
+	"context"
+	"testing"
+
+	"github.com/PauloHFS/goth/internal/db"
+	"github.com/PauloHFS/goth/internal/oidc"
+	"github.com/PauloHFS/goth/internal/session"
+)
+
+// ensureTenantAndRoles insere o tenant "default" e as roles usadas pelos
+// testes de resolveOIDCUser — mesma receita de requireUser em
+// oauth_test.go, mas sem criar um usuário de antemão.
+func ensureTenantAndRoles(t *testing.T, deps HandlerDeps) {
+	t.Helper()
+	if _, err := deps.DB.ExecContext(context.Background(), "INSERT OR IGNORE INTO tenants (id, name, settings) VALUES (?, ?, ?)", "default", "Default Tenant", []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	for _, role := range []string{"user", "admin"} {
+		if _, err := deps.DB.ExecContext(context.Background(), "INSERT OR IGNORE INTO roles (id, name, permissions) VALUES (?, ?, ?)", role, role, []byte("[]")); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestResolveOIDCUser(t *testing.T) {
+	t.Run("LinksExistingAccountByVerifiedEmail", func(t *testing.T) {
+		deps := setupTestDeps(t)
+		ensureTenantAndRoles(t, deps)
+		ctx := context.Background()
+
+		existing, err := deps.Queries.CreateUser(ctx, dbCreateUserParams("owner@example.com"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		identity := oidc.Identity{Subject: "sub-1", Email: "owner@example.com", EmailVerified: true}
+		user, err := deps.resolveOIDCUser(ctx, "google", identity, "user")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.ID != existing.ID {
+			t.Errorf("resolveOIDCUser created/returned user %d, want it linked to existing account %d", user.ID, existing.ID)
+		}
+
+		linkedID, err := deps.Identities.FindUserID(ctx, "google", "sub-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if linkedID != existing.ID {
+			t.Errorf("identity not linked to the existing account: linked to %d, want %d", linkedID, existing.ID)
+		}
+	})
+
+	t.Run("DoesNotAutoLinkUnverifiedEmail", func(t *testing.T) {
+		deps := setupTestDeps(t)
+		ensureTenantAndRoles(t, deps)
+		ctx := context.Background()
+
+		existing, err := deps.Queries.CreateUser(ctx, dbCreateUserParams("owner@example.com"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Mesmo e-mail do usuário já existente, mas o provedor não confirma
+		// que foi verificado: não deve linkar a conta existente por senha.
+		identity := oidc.Identity{Subject: "sub-2", Email: "owner@example.com", EmailVerified: false}
+		user, err := deps.resolveOIDCUser(ctx, "google", identity, "user")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.ID == existing.ID {
+			t.Error("resolveOIDCUser linked an unverified email to an existing password account, want a new account")
+		}
+
+		linkedID, err := deps.Identities.FindUserID(ctx, "google", "sub-2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if linkedID == existing.ID {
+			t.Error("identity linked to the pre-existing account despite an unverified email")
+		}
+	})
+
+	t.Run("ProvisionsNewAccountWhenNoMatch", func(t *testing.T) {
+		deps := setupTestDeps(t)
+		ensureTenantAndRoles(t, deps)
+		ctx := context.Background()
+
+		identity := oidc.Identity{Subject: "sub-3", Email: "new-user@example.com", EmailVerified: true}
+		user, err := deps.resolveOIDCUser(ctx, "google", identity, "user")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.Email != "new-user@example.com" {
+			t.Errorf("provisioned user email = %q, want %q", user.Email, "new-user@example.com")
+		}
+
+		linkedID, err := deps.Identities.FindUserID(ctx, "google", "sub-3")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if linkedID != user.ID {
+			t.Errorf("newly provisioned account not linked: linked to %d, want %d", linkedID, user.ID)
+		}
+	})
+
+	t.Run("ReturnsAlreadyLinkedIdentityAndSyncsRole", func(t *testing.T) {
+		deps := setupTestDeps(t)
+		ensureTenantAndRoles(t, deps)
+		ctx := context.Background()
+
+		existing, err := deps.Queries.CreateUser(ctx, dbCreateUserParams("linked@example.com"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := deps.Identities.Link(ctx, "google", "sub-4", existing.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		identity := oidc.Identity{Subject: "sub-4", Email: "linked@example.com", EmailVerified: true}
+		user, err := deps.resolveOIDCUser(ctx, "google", identity, "admin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.ID != existing.ID {
+			t.Errorf("resolveOIDCUser = user %d, want already-linked user %d", user.ID, existing.ID)
+		}
+		if user.RoleID != "admin" {
+			t.Errorf("role not synced from IdP claim: RoleID = %q, want %q", user.RoleID, "admin")
+		}
+	})
+
+	t.Run("RoleSyncRevokesExistingSessions", func(t *testing.T) {
+		deps := setupTestDeps(t)
+		ensureTenantAndRoles(t, deps)
+		deps.Sessions = session.NewStore(deps.DB)
+		if err := deps.Sessions.EnsureTable(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		ctx := context.Background()
+
+		existing, err := deps.Queries.CreateUser(ctx, dbCreateUserParams("rolechange@example.com"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := deps.Identities.Link(ctx, "google", "sub-5", existing.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		priorSession, err := deps.Sessions.Create(ctx, session.CreateParams{
+			UserID: existing.ID,
+			Token:  "prior-session-token",
+			TTL:    session.DefaultTTL,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		identity := oidc.Identity{Subject: "sub-5", Email: "rolechange@example.com", EmailVerified: true}
+		if _, err := deps.resolveOIDCUser(ctx, "google", identity, "admin"); err != nil {
+			t.Fatal(err)
+		}
+
+		active, err := deps.Sessions.ListActiveByUser(ctx, existing.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range active {
+			if s.ID == priorSession.ID {
+				t.Error("role change via OIDC did not revoke the pre-existing session")
+			}
+		}
+	})
+}
+
+func dbCreateUserParams(email string) db.CreateUserParams {
+	return db.CreateUserParams{
+		TenantID:     "default",
+		Email:        email,
+		PasswordHash: "hash",
+		RoleID:       "user",
+	}
+}
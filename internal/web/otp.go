@@ -0,0 +1,220 @@
+package web
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/PauloHFS/goth/internal/audit"
+	"github.com/PauloHFS/goth/internal/contextkeys"
+	"github.com/PauloHFS/goth/internal/db"
+	"github.com/PauloHFS/goth/internal/logging"
+	"github.com/PauloHFS/goth/internal/middleware"
+	"github.com/PauloHFS/goth/internal/routes"
+	"github.com/PauloHFS/goth/internal/session"
+	"github.com/PauloHFS/goth/internal/totp"
+	"github.com/PauloHFS/goth/internal/view/pages"
+	"github.com/a-h/templ"
+)
+
+// handleLoginOTP completa o login de um usuário com segundo fator TOTP
+// habilitado, após handleLogin já ter validado a senha e armazenado
+// "pending_user_id" na sessão em vez de "user_id".
+func handleLoginOTP(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	pendingUserID := deps.SessionManager.GetInt64(r.Context(), "pending_user_id")
+	if pendingUserID == 0 {
+		http.Redirect(w, r, routes.Login, http.StatusSeeOther)
+		return nil
+	}
+
+	if r.Method == http.MethodGet {
+		templ.Handler(pages.LoginOTP("")).ServeHTTP(w, r)
+		return nil
+	}
+
+	enrollment, err := deps.TOTP.Get(r.Context(), pendingUserID)
+	if err != nil || !enrollment.Enabled() {
+		deps.SessionManager.Remove(r.Context(), "pending_user_id")
+		http.Redirect(w, r, routes.Login, http.StatusSeeOther)
+		return nil
+	}
+
+	code := r.FormValue("code")
+	recoveryCode := r.FormValue("recovery_code")
+
+	ok := false
+	usedRecoveryCode := false
+	if code != "" {
+		secret, err := totp.DecryptSecret(enrollment.SecretEncrypted, totp.DeriveKey(deps.Config.SessionSecret))
+		if err == nil {
+			if step, valid := totp.ValidateStep(secret, code, time.Now()); valid {
+				ok, err = deps.TOTP.ConsumeStep(r.Context(), pendingUserID, step)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	} else if recoveryCode != "" {
+		if remaining, found := totp.ConsumeRecoveryCode(enrollment.RecoveryCodesHash, recoveryCode); found {
+			if err := deps.TOTP.UpdateRecoveryCodes(r.Context(), pendingUserID, remaining); err != nil {
+				return err
+			}
+			ok = true
+			usedRecoveryCode = true
+		}
+	}
+
+	if !ok {
+		if deps.Auditor != nil {
+			_ = deps.Auditor.Record(r.Context(), audit.Action2FALoginDenied, pendingUserID, "user", "", audit.ResultFailure, nil)
+		}
+		templ.Handler(pages.LoginOTP("Código inválido")).ServeHTTP(w, r)
+		return nil
+	}
+
+	user, err := deps.Queries.GetUserByID(r.Context(), pendingUserID)
+	if err != nil {
+		return err
+	}
+
+	logging.AddToEvent(r.Context(),
+		slog.String("operation", "login_otp"),
+		slog.Int64("user_id", user.ID),
+		slog.Bool("used_recovery_code", usedRecoveryCode),
+		slog.String("outcome", "success"),
+	)
+
+	deps.SessionManager.Remove(r.Context(), "pending_user_id")
+	deps.SessionManager.Put(r.Context(), "user_id", user.ID)
+	deps.SessionManager.Put(r.Context(), "otp_elevated_at", time.Now().Unix())
+
+	if deps.Sessions != nil {
+		_, err := deps.Sessions.Create(r.Context(), session.CreateParams{
+			UserID:    user.ID,
+			TenantID:  "default",
+			Token:     deps.SessionManager.Token(r.Context()),
+			UserAgent: r.UserAgent(),
+			IP:        middleware.ExtractIP(r),
+			TTL:       session.DefaultTTL,
+		})
+		if err != nil {
+			logging.Get().Warn("failed to record session", "error", err)
+		}
+	}
+
+	if deps.Auditor != nil {
+		_ = deps.Auditor.Record(r.Context(), audit.ActionLoginSuccess, user.ID, "user", user.Email, audit.ResultSuccess, nil)
+	}
+
+	http.Redirect(w, r, routes.Dashboard, http.StatusSeeOther)
+	return nil
+}
+
+// handleProfile2FAStart gera (ou regenera) um segredo TOTP pendente para o
+// usuário autenticado e exibe a URI de provisionamento para ser escaneada
+// por um app autenticador. O segredo só passa a valer como segundo fator
+// depois de confirmado em handleProfile2FAEnable.
+func handleProfile2FAStart(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	user, ok := r.Context().Value(contextkeys.UserContextKey).(db.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil
+	}
+
+	if enrollment, err := deps.TOTP.Get(r.Context(), user.ID); err == nil && enrollment.Enabled() {
+		templ.Handler(pages.Profile2FAEnabled()).ServeHTTP(w, r)
+		return nil
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return err
+	}
+
+	encrypted, err := totp.EncryptSecret(secret, totp.DeriveKey(deps.Config.SessionSecret))
+	if err != nil {
+		return err
+	}
+
+	if err := deps.TOTP.StartEnrollment(r.Context(), user.ID, encrypted); err != nil {
+		return err
+	}
+
+	uri := totp.ProvisioningURI("GOTH Stack", user.Email, secret)
+	templ.Handler(pages.Profile2FASetup(secret, uri, "")).ServeHTTP(w, r)
+	return nil
+}
+
+// handleProfile2FAEnable confirma o enrollment pendente validando um código
+// gerado a partir do segredo já armazenado, emite os códigos de recuperação
+// de uso único e marca o segundo fator como habilitado.
+func handleProfile2FAEnable(deps HandlerDeps, w http.ResponseWriter, r *http.Request) error {
+	user, ok := r.Context().Value(contextkeys.UserContextKey).(db.User)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil
+	}
+
+	enrollment, err := deps.TOTP.Get(r.Context(), user.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			http.Redirect(w, r, routes.Profile2FA, http.StatusSeeOther)
+			return nil
+		}
+		return err
+	}
+
+	if enrollment.Enabled() {
+		templ.Handler(pages.Profile2FAEnabled()).ServeHTTP(w, r)
+		return nil
+	}
+
+	secret, err := totp.DecryptSecret(enrollment.SecretEncrypted, totp.DeriveKey(deps.Config.SessionSecret))
+	if err != nil {
+		return err
+	}
+
+	code := r.FormValue("code")
+	step, valid := totp.ValidateStep(secret, code, time.Now())
+	if valid {
+		valid, err = deps.TOTP.ConsumeStep(r.Context(), user.ID, step)
+		if err != nil {
+			return err
+		}
+	}
+	if !valid {
+		uri := totp.ProvisioningURI("GOTH Stack", user.Email, secret)
+		templ.Handler(pages.Profile2FASetup(secret, uri, "Código inválido")).ServeHTTP(w, r)
+		return nil
+	}
+
+	recoveryCodes, err := totp.GenerateRecoveryCodes()
+	if err != nil {
+		return err
+	}
+
+	hashed, err := totp.HashRecoveryCodes(recoveryCodes)
+	if err != nil {
+		return err
+	}
+
+	if err := deps.TOTP.Enable(r.Context(), user.ID, hashed); err != nil {
+		return err
+	}
+
+	deps.SessionManager.Put(r.Context(), "otp_elevated_at", time.Now().Unix())
+
+	if deps.Auditor != nil {
+		_ = deps.Auditor.Record(r.Context(), audit.Action2FAEnabled, user.ID, "user", user.Email, audit.ResultSuccess, nil)
+	}
+
+	logging.AddToEvent(r.Context(),
+		slog.String("operation", "2fa_enable"),
+		slog.Int64("user_id", user.ID),
+		slog.String("outcome", "success"),
+	)
+
+	templ.Handler(pages.Profile2FARecoveryCodes(recoveryCodes)).ServeHTTP(w, r)
+	return nil
+}
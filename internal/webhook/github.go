@@ -0,0 +1,35 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// GitHubVerifier valida o header "X-Hub-Signature-256", no formato
+// "sha256=<hex hmac>" de HMAC-SHA256(Secret, body) — o esquema usado pelo
+// GitHub (e compatível com o de vários outros provedores que seguem o mesmo
+// padrão) para webhooks de repositório.
+type GitHubVerifier struct {
+	Secret string
+}
+
+// Verify implementa Verifier para o formato de assinatura do GitHub.
+func (v *GitHubVerifier) Verify(r *http.Request, body []byte) error {
+	header := r.Header.Get("X-Hub-Signature-256")
+	sig, found := strings.CutPrefix(header, "sha256=")
+	if !found || sig == "" {
+		return ErrSignatureMismatch
+	}
+
+	mac := hmac.New(sha256.New, []byte(v.Secret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(sig), []byte(expected)) {
+		return ErrSignatureMismatch
+	}
+	return nil
+}
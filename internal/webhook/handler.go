@@ -6,10 +6,12 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/PauloHFS/goth/internal/db"
 	"github.com/PauloHFS/goth/internal/logging"
+	"github.com/PauloHFS/goth/internal/pubsub"
 	"github.com/PauloHFS/goth/internal/validator"
 )
 
@@ -18,11 +20,42 @@ type webhookInput struct {
 }
 
 type Handler struct {
-	queries *db.Queries
+	db        *sql.DB
+	queries   *db.Queries
+	jobs      *pubsub.Broker
+	verifiers *Registry
 }
 
-func NewHandler(q *db.Queries) *Handler {
-	return &Handler{queries: q}
+// NewHandler cria um Handler de webhooks. jobs é opcional: quando nil, o
+// job enfileirado só é descoberto pelo ticker de polling do worker.
+// verifiers é opcional: quando nil, toda requisição é rejeitada com 401,
+// já que nenhuma fonte tem Verifier registrado (ver Registry.Lookup).
+func NewHandler(dbConn *sql.DB, q *db.Queries, jobs *pubsub.Broker, verifiers *Registry) *Handler {
+	if verifiers == nil {
+		verifiers = NewRegistry()
+	}
+	return &Handler{db: dbConn, queries: q, jobs: jobs, verifiers: verifiers}
+}
+
+// signatureHeaderValue devolve o header de assinatura presente em r, dentre
+// os formatos que os Verifiers embutidos conhecem — só para fins de
+// auditoria (ver Signature em db.CreateWebhookParams); a verificação em si
+// é feita pelo Verifier da fonte, não por esta função.
+func signatureHeaderValue(r *http.Request) string {
+	for _, header := range []string{"Stripe-Signature", "X-Hub-Signature-256", hmacSignatureHeader} {
+		if v := r.Header.Get(header); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// isUniqueViolation reconhece o erro do driver sqlite para uma violação do
+// índice único (source, external_id) da tabela webhooks — usado para tratar
+// um retry de provedor como idempotente em vez de um erro de
+// armazenamento.
+func isUniqueViolation(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
 }
 
 // ServeHTTP handles incoming webhooks
@@ -35,6 +68,7 @@ func NewHandler(q *db.Queries) *Handler {
 // @Param payload body webhookInput true "Payload do webhook"
 // @Success 200 {string} string "OK"
 // @Failure 400 {string} string "Bad Request"
+// @Failure 401 {string} string "Unauthorized"
 // @Router /webhooks/{source} [post]
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	start := time.Now()
@@ -64,6 +98,31 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	verifier, ok := h.verifiers.Lookup(source)
+	if !ok {
+		event.Add(
+			slog.String("outcome", "error"),
+			slog.String("error", "no verifier registered for source"),
+		)
+		logging.Get().Log(ctx, slog.LevelWarn, "webhook processing failed", event.Attrs()...)
+		http.Error(w, "unknown webhook source", http.StatusUnauthorized)
+		return
+	}
+
+	if err := verifier.Verify(r, payload); err != nil {
+		status := http.StatusUnauthorized
+		if err == ErrTimestampSkew {
+			status = http.StatusBadRequest
+		}
+		event.Add(
+			slog.String("outcome", "error"),
+			slog.String("error", err.Error()),
+		)
+		logging.Get().Log(ctx, slog.LevelWarn, "webhook signature verification failed", event.Attrs()...)
+		http.Error(w, err.Error(), status)
+		return
+	}
+
 	var input webhookInput
 	if err := json.Unmarshal(payload, &input); err != nil {
 		event.Add(
@@ -89,13 +148,40 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	headers, _ := json.Marshal(r.Header)
 
-	webhook, err := h.queries.CreateWebhook(r.Context(), db.CreateWebhookParams{
+	tx, err := h.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		event.Add(
+			slog.String("outcome", "error"),
+			slog.String("error", "failed to start transaction"),
+		)
+		logging.Get().Log(ctx, slog.LevelError, "webhook processing failed", event.Attrs()...)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	qtx := h.queries.WithTx(tx)
+
+	webhook, err := qtx.CreateWebhook(r.Context(), db.CreateWebhookParams{
 		Source:     source,
 		ExternalID: sql.NullString{String: input.ExternalID, Valid: true},
 		Payload:    payload,
 		Headers:    headers,
+		Signature:  sql.NullString{String: signatureHeaderValue(r), Valid: true},
 	})
 	if err != nil {
+		if isUniqueViolation(err) {
+			// (source, external_id) já visto: o provedor está reentregando um
+			// webhook já processado (retry). Reconhece com 200 sem enfileirar
+			// um process_webhook duplicado, em vez de tratar como erro.
+			event.Add(
+				slog.String("outcome", "duplicate"),
+				slog.Int("status", http.StatusOK),
+			)
+			logging.Get().Log(ctx, slog.LevelInfo, "webhook already received, skipping duplicate", event.Attrs()...)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
 		event.Add(
 			slog.String("outcome", "error"),
 			slog.String("error", "failed to store webhook"),
@@ -108,7 +194,7 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	event.Add(slog.Int64("webhook_id", webhook.ID))
 
 	jobPayload, _ := json.Marshal(map[string]int64{"webhook_id": webhook.ID})
-	_, err = h.queries.CreateJob(r.Context(), db.CreateJobParams{
+	_, err = qtx.CreateJob(r.Context(), db.CreateJobParams{
 		TenantID: sql.NullString{String: "default", Valid: true},
 		Type:     "process_webhook",
 		Payload:  jobPayload,
@@ -125,6 +211,23 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := tx.Commit(); err != nil {
+		event.Add(
+			slog.String("outcome", "error"),
+			slog.String("error", "failed to commit transaction"),
+		)
+		logging.Get().Log(ctx, slog.LevelError, "webhook processing failed", event.Attrs()...)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	// Publicado só depois do commit: publicar antes arriscaria acordar um
+	// worker que tenta PickNextJob antes da linha existir para outras
+	// conexões.
+	if h.jobs != nil {
+		h.jobs.Publish("process_webhook")
+	}
+
 	event.Add(
 		slog.String("outcome", "success"),
 		slog.Int("status", http.StatusOK),
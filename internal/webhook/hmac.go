@@ -0,0 +1,38 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+)
+
+// hmacSignatureHeader é o header lido por HMACVerifier — sem um formato de
+// provedor específico a seguir (diferente de Stripe ou GitHub), um único
+// nome de header configurado por código é suficiente para as fontes que só
+// precisam de uma assinatura HMAC simples do corpo.
+const hmacSignatureHeader = "X-Webhook-Signature"
+
+// HMACVerifier é o provedor genérico usado por qualquer fonte configurada
+// sem um Provider mais específico ("stripe" ou "github"): exige o header
+// X-Webhook-Signature com o hex de HMAC-SHA256(Secret, body), sem prefixo.
+type HMACVerifier struct {
+	Secret string
+}
+
+// Verify implementa Verifier para a assinatura HMAC genérica.
+func (v *HMACVerifier) Verify(r *http.Request, body []byte) error {
+	sig := r.Header.Get(hmacSignatureHeader)
+	if sig == "" {
+		return ErrSignatureMismatch
+	}
+
+	mac := hmac.New(sha256.New, []byte(v.Secret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(sig), []byte(expected)) {
+		return ErrSignatureMismatch
+	}
+	return nil
+}
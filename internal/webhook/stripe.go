@@ -0,0 +1,78 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StripeVerifier valida o header "Stripe-Signature" no formato
+// "t=<timestamp>,v1=<hex hmac>[,v1=...]": a assinatura é
+// HMAC-SHA256(Secret, "<timestamp>.<body>"), e Verify rejeita tanto uma
+// assinatura que não bate quanto um timestamp fora de Tolerance, seguindo o
+// esquema documentado pela Stripe para evitar replay de webhooks
+// capturados.
+type StripeVerifier struct {
+	Secret    string
+	Tolerance time.Duration
+}
+
+// Verify implementa Verifier para o formato de assinatura da Stripe.
+func (v *StripeVerifier) Verify(r *http.Request, body []byte) error {
+	header := r.Header.Get("Stripe-Signature")
+	if header == "" {
+		return ErrSignatureMismatch
+	}
+
+	timestamp, signatures := parseStripeSignatureHeader(header)
+	if timestamp == "" || len(signatures) == 0 {
+		return ErrSignatureMismatch
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrSignatureMismatch
+	}
+
+	tolerance := v.Tolerance
+	if tolerance <= 0 {
+		tolerance = defaultTimestampTolerance
+	}
+	if skew := time.Since(time.Unix(ts, 0)); skew > tolerance || skew < -tolerance {
+		return ErrTimestampSkew
+	}
+
+	mac := hmac.New(sha256.New, []byte(v.Secret))
+	mac.Write([]byte(timestamp + "." + string(body)))
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	for _, sig := range signatures {
+		if hmac.Equal([]byte(sig), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrSignatureMismatch
+}
+
+// parseStripeSignatureHeader separa "t=...,v1=...,v1=..." no timestamp e na
+// lista de assinaturas v1 — a Stripe pode mandar mais de um v1 durante a
+// rotação de secret, então qualquer um bater já é suficiente.
+func parseStripeSignatureHeader(header string) (timestamp string, signatures []string) {
+	for _, part := range strings.Split(header, ",") {
+		key, value, found := strings.Cut(part, "=")
+		if !found {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "t":
+			timestamp = strings.TrimSpace(value)
+		case "v1":
+			signatures = append(signatures, strings.TrimSpace(value))
+		}
+	}
+	return timestamp, signatures
+}
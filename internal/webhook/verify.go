@@ -0,0 +1,81 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/PauloHFS/goth/internal/config"
+)
+
+// defaultTimestampTolerance é usado quando ToleranceSeconds não é
+// configurado (zero) para uma fonte cujo Verifier assina um timestamp.
+const defaultTimestampTolerance = 5 * time.Minute
+
+// Verifier autentica uma requisição de webhook antes do payload ser
+// persistido e enfileirado. body já foi lido de r.Body por ServeHTTP (que
+// precisa dele inteiro para CreateWebhookParams.Payload de qualquer forma),
+// então Verify recebe os bytes prontos em vez de ler de r.Body de novo;
+// headers de assinatura continuam sendo lidos direto de r.
+type Verifier interface {
+	Verify(r *http.Request, body []byte) error
+}
+
+// ErrSignatureMismatch é devolvido por um Verifier quando a assinatura
+// recebida não bate com a calculada a partir do secret configurado —
+// Handler.ServeHTTP traduz isso para 401.
+var ErrSignatureMismatch = errors.New("webhook: assinatura inválida")
+
+// ErrTimestampSkew é devolvido quando o timestamp assinado está fora da
+// janela de tolerância configurada — Handler.ServeHTTP traduz isso para
+// 400, já que indica replay ou relógio dessincronizado, não necessariamente
+// uma assinatura forjada.
+var ErrTimestampSkew = errors.New("webhook: timestamp fora da janela de tolerância")
+
+// Registry associa cada fonte (o {source} de /webhooks/{source}) ao
+// Verifier que autentica suas requisições.
+type Registry struct {
+	verifiers map[string]Verifier
+}
+
+// NewRegistry cria um Registry vazio.
+func NewRegistry() *Registry {
+	return &Registry{verifiers: make(map[string]Verifier)}
+}
+
+// Register associa v à fonte source, substituindo qualquer Verifier
+// previamente registrado para ela.
+func (reg *Registry) Register(source string, v Verifier) {
+	reg.verifiers[source] = v
+}
+
+// Lookup devolve o Verifier registrado para source, se houver.
+func (reg *Registry) Lookup(source string) (Verifier, bool) {
+	v, ok := reg.verifiers[source]
+	return v, ok
+}
+
+// NewRegistryFromConfig monta um Registry a partir de cfg, instanciando
+// StripeVerifier, GitHubVerifier ou HMACVerifier conforme o campo Provider
+// de cada entrada — "hmac" (também o padrão, se Provider vier em branco)
+// usa a assinatura genérica configurável, pensada para fontes que não são
+// nenhum dos provedores com formato fixo.
+func NewRegistryFromConfig(cfg config.WebhookConfig) *Registry {
+	reg := NewRegistry()
+	for _, p := range cfg.Providers {
+		tolerance := time.Duration(p.ToleranceSeconds) * time.Second
+		if tolerance <= 0 {
+			tolerance = defaultTimestampTolerance
+		}
+
+		switch p.Provider {
+		case "stripe":
+			reg.Register(p.Source, &StripeVerifier{Secret: p.Secret, Tolerance: tolerance})
+		case "github":
+			reg.Register(p.Source, &GitHubVerifier{Secret: p.Secret})
+		default:
+			reg.Register(p.Source, &HMACVerifier{Secret: p.Secret})
+		}
+	}
+	return reg
+}
@@ -0,0 +1,139 @@
+package worker
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// acquirerWaiter é um worker ocioso registrado em Acquirer.Wait, esperando
+// uma notificação compatível com jobTypes/tenantID. ch é fechado (não
+// escrito) por Notify para acordar o waiter mesmo que ele já tenha saído do
+// select por outro motivo (ctx cancelado, safety poll) — fechar é seguro
+// mesmo sem ninguém do outro lado ainda lendo.
+type acquirerWaiter struct {
+	jobTypes map[string]struct{} // vazio = qualquer tipo
+	tenantID string              // "" = qualquer tenant
+	ch       chan struct{}
+}
+
+func (w *acquirerWaiter) matches(jobType, tenantID string) bool {
+	if len(w.jobTypes) > 0 {
+		if _, ok := w.jobTypes[jobType]; !ok {
+			return false
+		}
+	}
+	if w.tenantID != "" && tenantID != "" && w.tenantID != tenantID {
+		return false
+	}
+	return true
+}
+
+// Acquirer deduplica notificações de "há job disponível" por (job_type,
+// tenant_id) e entrega cada uma a no máximo um worker ocioso compatível, em
+// vez do broadcast para todos os shards que Processor fazia antes — isso
+// evita que N shards disputem PickNextJob ao mesmo tempo por um único job
+// publicado (thundering herd). tenantID existe para o dia em que um
+// enqueue site souber de qual tenant o job é (ver internal/pubsub.Broker,
+// que hoje só publica por job type); até lá todo chamador usa "" e a
+// deduplicação é efetivamente só por job_type.
+//
+// Inspirado no mesmo padrão Acquirer do provisionerdserver do Coder citado
+// em internal/pubsub: lá o round-robin já evita acordar todo mundo por
+// tópico; Acquirer estende a ideia para os N shards de um mesmo Processor,
+// que competem pelo mesmo conjunto de tópicos.
+type Acquirer struct {
+	mu      sync.Mutex
+	waiters []*acquirerWaiter
+	pending map[string]struct{}
+}
+
+func NewAcquirer() *Acquirer {
+	return &Acquirer{pending: make(map[string]struct{})}
+}
+
+func pendingKey(jobType, tenantID string) string {
+	return jobType + "|" + tenantID
+}
+
+// Notify registra que um job do tipo jobType (tenantID, quando conhecido)
+// está disponível. Se houver um waiter ocioso compatível, a notificação é
+// entregue só a ele; caso contrário fica marcada em pending, coalescida com
+// qualquer notificação anterior para a mesma chave, até um waiter
+// compatível aparecer em Wait.
+func (a *Acquirer) Notify(jobType, tenantID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for i, w := range a.waiters {
+		if w.matches(jobType, tenantID) {
+			a.waiters = append(a.waiters[:i], a.waiters[i+1:]...)
+			close(w.ch)
+			return
+		}
+	}
+
+	a.pending[pendingKey(jobType, tenantID)] = struct{}{}
+}
+
+// Wait bloqueia até chegar uma notificação compatível com jobTypes/tenantID
+// (via Notify, ou um pending já coalescido antes de Wait ser chamado), ctx
+// ser cancelado, ou safetyPoll decorrer — o poll de segurança garante que um
+// job perdido por alguma corrida (processo que morre entre o commit e o
+// Publish, notificação perdida) ainda seja descoberto, sem exigir um ticker
+// curto como antes.
+func (a *Acquirer) Wait(ctx context.Context, jobTypes []string, tenantID string, safetyPoll time.Duration) {
+	typeSet := make(map[string]struct{}, len(jobTypes))
+	for _, t := range jobTypes {
+		typeSet[t] = struct{}{}
+	}
+
+	if a.consumePending(typeSet, tenantID) {
+		return
+	}
+
+	w := &acquirerWaiter{jobTypes: typeSet, tenantID: tenantID, ch: make(chan struct{})}
+	a.mu.Lock()
+	a.waiters = append(a.waiters, w)
+	a.mu.Unlock()
+	defer a.removeWaiter(w)
+
+	timer := time.NewTimer(safetyPoll)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-w.ch:
+	case <-timer.C:
+	}
+}
+
+// consumePending procura em pending uma chave compatível com typeSet/
+// tenantID já coalescida antes de Wait ser chamado, consumindo-a sem
+// precisar registrar um waiter e esperar.
+func (a *Acquirer) consumePending(typeSet map[string]struct{}, tenantID string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	probe := &acquirerWaiter{jobTypes: typeSet, tenantID: tenantID}
+	for key := range a.pending {
+		jobType, keyTenant, _ := strings.Cut(key, "|")
+		if probe.matches(jobType, keyTenant) {
+			delete(a.pending, key)
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Acquirer) removeWaiter(w *acquirerWaiter) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, candidate := range a.waiters {
+		if candidate == w {
+			a.waiters = append(a.waiters[:i], a.waiters[i+1:]...)
+			return
+		}
+	}
+}
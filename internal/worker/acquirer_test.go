@@ -0,0 +1,56 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAcquirer_NotifyWakesOnlyOneWaiter(t *testing.T) {
+	a := NewAcquirer()
+
+	done := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		i := i
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			a.Wait(ctx, []string{"process_webhook"}, "", 500*time.Millisecond)
+			if ctx.Err() == nil {
+				done <- i
+			}
+		}()
+	}
+
+	// Dá tempo dos dois goroutines se registrarem como waiters antes de notificar.
+	time.Sleep(20 * time.Millisecond)
+	a.Notify("process_webhook", "")
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("esperava que algum waiter acordasse")
+	}
+
+	select {
+	case <-done:
+		t.Fatal("esperava que só um waiter acordasse por Notify")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestAcquirer_PendingCoalescedBeforeWait(t *testing.T) {
+	a := NewAcquirer()
+
+	a.Notify("process_ai", "")
+	a.Notify("process_ai", "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+	a.Wait(ctx, []string{"process_ai"}, "", time.Second)
+	if time.Since(start) > 100*time.Millisecond {
+		t.Fatal("esperava consumir a notificação pendente sem esperar o safety poll")
+	}
+}
@@ -0,0 +1,274 @@
+package worker
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// JobFilter restringe quais jobs ExportJobs inclui no dump. Campos vazios
+// não filtram: Type == "" exporta todos os tipos, TenantID == "" exporta
+// de todos os tenants.
+type JobFilter struct {
+	Type              string
+	TenantID          string
+	IncludeDeadLetter bool
+}
+
+// BackupEntry é uma linha do dump NDJSON produzido por ExportJobs e
+// consumido por ImportJobs. Status distingue um job ativo ("pending",
+// "processing", ...) de um item vindo da DLQ ("dead_letter").
+type BackupEntry struct {
+	ID           int64           `json:"id"`
+	Type         string          `json:"type"`
+	Payload      json.RawMessage `json:"payload"`
+	Status       string          `json:"status"`
+	AttemptCount int64           `json:"attempt_count"`
+	TenantID     string          `json:"tenant_id,omitempty"`
+	LastError    string          `json:"last_error,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+	RunAt        time.Time       `json:"run_at"`
+}
+
+// ImportOptions controla como ImportJobs recria as entradas lidas.
+type ImportOptions struct {
+	// Dedupe pula uma entrada se já existir um job com o mesmo
+	// (type, payload_hash, tenant_id) — ver BackupManager.EnsurePayloadHashSchema.
+	Dedupe bool
+	// PreserveTimestamps mantém created_at/run_at originais em vez de usar
+	// o momento da importação. Assume relógios comparáveis entre origem e
+	// destino, então fica atrás de uma flag em vez de ser o padrão.
+	PreserveTimestamps bool
+}
+
+// ImportReport resume o resultado de um ImportJobs, devolvido para o
+// operador decidir se precisa investigar os Failed.
+type ImportReport struct {
+	Imported int
+	Skipped  int
+	Failed   int
+}
+
+// BackupManager exporta/importa jobs (incluindo a DLQ) como NDJSON, para
+// snapshot da fila antes de migrações e para reproduzir itens da DLQ em
+// outro ambiente (ver cmd/api "backup export"/"backup import"). Usa SQL
+// bruto como o resto do pacote (rpc.go, dead_letter.go) porque não existe
+// query gerada pelo sqlc para essas operações.
+type BackupManager struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+func NewBackupManager(dbConn *sql.DB, logger *slog.Logger) *BackupManager {
+	return &BackupManager{db: dbConn, logger: logger}
+}
+
+// EnsurePayloadHashSchema adiciona a coluna payload_hash a jobs e
+// dead_letter_jobs, usada por ImportJobs para deduplicar quando
+// ImportOptions.Dedupe está ligado. Idempotente como os demais
+// EnsureXSchema do pacote (ver EnsureLeaseSchema, JobServer.EnsureTargetHostSchema).
+func (b *BackupManager) EnsurePayloadHashSchema(ctx context.Context) error {
+	for _, table := range []string{"jobs", "dead_letter_jobs"} {
+		_, err := b.db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN payload_hash TEXT", table))
+		if err != nil && !strings.Contains(err.Error(), "duplicate column") {
+			return fmt.Errorf("failed to add payload_hash to %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+func payloadHash(payload json.RawMessage) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// ExportJobs grava em w um job por linha em NDJSON: primeiro os jobs
+// ativos da tabela jobs e, se filter.IncludeDeadLetter, em seguida os da
+// DLQ.
+func (b *BackupManager) ExportJobs(ctx context.Context, w io.Writer, filter JobFilter) error {
+	enc := json.NewEncoder(w)
+
+	if err := b.exportJobsTable(ctx, enc, filter); err != nil {
+		return err
+	}
+	if filter.IncludeDeadLetter {
+		if err := b.exportDeadLetterTable(ctx, enc, filter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *BackupManager) exportJobsTable(ctx context.Context, enc *json.Encoder, filter JobFilter) error {
+	query := "SELECT id, type, CAST(payload AS BLOB), status, attempt_count, tenant_id, last_error, created_at, run_at FROM jobs"
+	where, args := filter.whereClause()
+	if where != "" {
+		query += " WHERE " + where
+	}
+	query += " ORDER BY id ASC"
+
+	rows, err := b.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query jobs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry BackupEntry
+		var tenantID, lastError sql.NullString
+		if err := rows.Scan(&entry.ID, &entry.Type, &entry.Payload, &entry.Status, &entry.AttemptCount, &tenantID, &lastError, &entry.CreatedAt, &entry.RunAt); err != nil {
+			return fmt.Errorf("failed to scan job row: %w", err)
+		}
+		entry.TenantID = tenantID.String
+		entry.LastError = lastError.String
+
+		if err := enc.Encode(entry); err != nil {
+			return fmt.Errorf("failed to write job entry: %w", err)
+		}
+	}
+	return rows.Err()
+}
+
+func (b *BackupManager) exportDeadLetterTable(ctx context.Context, enc *json.Encoder, filter JobFilter) error {
+	query := "SELECT id, type, CAST(payload AS BLOB), attempt_count, tenant_id, last_error, moved_at FROM dead_letter_jobs"
+	where, args := filter.whereClause()
+	if where != "" {
+		query += " WHERE " + where
+	}
+	query += " ORDER BY id ASC"
+
+	rows, err := b.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query dead_letter_jobs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry BackupEntry
+		var tenantID, lastError sql.NullString
+		if err := rows.Scan(&entry.ID, &entry.Type, &entry.Payload, &entry.AttemptCount, &tenantID, &lastError, &entry.CreatedAt); err != nil {
+			return fmt.Errorf("failed to scan dead_letter_jobs row: %w", err)
+		}
+		entry.TenantID = tenantID.String
+		entry.LastError = lastError.String
+		entry.Status = "dead_letter"
+		entry.RunAt = entry.CreatedAt
+
+		if err := enc.Encode(entry); err != nil {
+			return fmt.Errorf("failed to write dead letter entry: %w", err)
+		}
+	}
+	return rows.Err()
+}
+
+func (f JobFilter) whereClause() (string, []any) {
+	var clauses []string
+	var args []any
+	if f.Type != "" {
+		clauses = append(clauses, "type = ?")
+		args = append(args, f.Type)
+	}
+	if f.TenantID != "" {
+		clauses = append(clauses, "tenant_id = ?")
+		args = append(args, f.TenantID)
+	}
+	if len(clauses) == 0 {
+		return "", nil
+	}
+	return strings.Join(clauses, " AND "), args
+}
+
+// ImportJobs lê NDJSON no formato de ExportJobs e recria cada entrada como
+// um job pendente em jobs — inclusive entradas originalmente da DLQ, já
+// que reproduzi-las de volta na fila ativa é o próprio objetivo do import,
+// não preservar que estavam mortas. IDs são sempre reescritos pelo
+// autoincrement do banco de destino; attempt_count sempre reinicia em 0,
+// dando a cada job reimportado a chance completa de tentativas de novo.
+func (b *BackupManager) ImportJobs(ctx context.Context, r io.Reader, opts ImportOptions) (ImportReport, error) {
+	var report ImportReport
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var entry BackupEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			report.Failed++
+			if b.logger != nil {
+				b.logger.Warn("backup: failed to decode job entry, skipping", "error", err.Error())
+			}
+			continue
+		}
+
+		if opts.Dedupe {
+			exists, err := b.jobExists(ctx, entry.Type, payloadHash(entry.Payload), entry.TenantID)
+			if err != nil {
+				report.Failed++
+				continue
+			}
+			if exists {
+				report.Skipped++
+				continue
+			}
+		}
+
+		if err := b.insertJob(ctx, entry, opts.PreserveTimestamps); err != nil {
+			report.Failed++
+			if b.logger != nil {
+				b.logger.Error("backup: failed to import job entry", "type", entry.Type, "error", err.Error())
+			}
+			continue
+		}
+		report.Imported++
+	}
+	if err := scanner.Err(); err != nil {
+		return report, fmt.Errorf("failed to read backup stream: %w", err)
+	}
+
+	return report, nil
+}
+
+func (b *BackupManager) jobExists(ctx context.Context, jobType, hash, tenantID string) (bool, error) {
+	var count int64
+	err := b.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM jobs WHERE type = ? AND payload_hash = ? AND tenant_id IS ?",
+		jobType, hash, sql.NullString{String: tenantID, Valid: tenantID != ""},
+	).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (b *BackupManager) insertJob(ctx context.Context, entry BackupEntry, preserveTimestamps bool) error {
+	hash := payloadHash(entry.Payload)
+	tenantID := sql.NullString{String: entry.TenantID, Valid: entry.TenantID != ""}
+
+	if preserveTimestamps {
+		_, err := b.db.ExecContext(ctx,
+			`INSERT INTO jobs (type, payload, payload_hash, tenant_id, status, attempt_count, created_at, run_at)
+			 VALUES (?, ?, ?, ?, 'pending', 0, ?, ?)`,
+			entry.Type, []byte(entry.Payload), hash, tenantID, entry.CreatedAt, entry.RunAt,
+		)
+		return err
+	}
+
+	_, err := b.db.ExecContext(ctx,
+		`INSERT INTO jobs (type, payload, payload_hash, tenant_id, status, attempt_count)
+		 VALUES (?, ?, ?, ?, 'pending', 0)`,
+		entry.Type, []byte(entry.Payload), hash, tenantID,
+	)
+	return err
+}
@@ -0,0 +1,32 @@
+package worker
+
+import "testing"
+
+func TestJobFilter_WhereClause(t *testing.T) {
+	if where, args := (JobFilter{}).whereClause(); where != "" || args != nil {
+		t.Fatalf("esperava clause e args vazios sem filtro, obteve clause=%q args=%v", where, args)
+	}
+
+	where, args := JobFilter{Type: "process_webhook"}.whereClause()
+	if where != "type = ?" || len(args) != 1 || args[0] != "process_webhook" {
+		t.Fatalf("clause/args inesperados só com Type: %q %v", where, args)
+	}
+
+	where, args = JobFilter{Type: "process_webhook", TenantID: "t1"}.whereClause()
+	if where != "type = ? AND tenant_id = ?" || len(args) != 2 {
+		t.Fatalf("clause/args inesperados com Type e TenantID: %q %v", where, args)
+	}
+}
+
+func TestPayloadHash_Stable(t *testing.T) {
+	a := payloadHash([]byte(`{"a":1}`))
+	b := payloadHash([]byte(`{"a":1}`))
+	c := payloadHash([]byte(`{"a":2}`))
+
+	if a != b {
+		t.Fatal("esperava hash estável para o mesmo payload")
+	}
+	if a == c {
+		t.Fatal("esperava hashes diferentes para payloads diferentes")
+	}
+}
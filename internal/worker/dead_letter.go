@@ -100,6 +100,113 @@ func (dlq *DeadLetterQueue) Cleanup(ctx context.Context) error {
 	return dlq.queries.CleanupDeadLetterJobs(ctx)
 }
 
+// DeadLetterEntry é uma linha de dead_letter_jobs. Não existe um tipo
+// gerado pelo sqlc para essa tabela nesta árvore (ao contrário de db.Job),
+// então ListDeadLetter usa SQL bruto e escaneia direto para este tipo local
+// — mesmo recurso já usado em rpc.go para target_host. LastError é
+// copiado do jobs.last_error que já estava na linha antes de MoveToDeadLetter
+// rodar (ver FailJob em rpc.go), por isso Move acima não precisa passar
+// lastErr separadamente.
+type DeadLetterEntry struct {
+	ID           int64
+	Type         string
+	Payload      []byte
+	AttemptCount int64
+	LastError    sql.NullString
+	MovedAt      time.Time
+}
+
+// ListDeadLetter pagina dead_letter_jobs por keyset (ver db.CursorParams),
+// ordenado por moved_at decrescente (mais recente primeiro) com id como
+// tie-breaker. includeTotal liga a contagem total via CountDeadLetterJobs —
+// deixado como opt-in porque é exatamente o custo que keyset pagination
+// existe para evitar.
+//
+// Esta é a única das três listagens citadas pelo pedido original (jobs,
+// DLQ, eventos de webhook) com um candidato real para essa troca nesta
+// árvore: não existe endpoint de listagem de jobs nem de eventos de
+// webhook em internal/web hoje, só contagens (ver Stats acima e
+// handlers.go). Os helpers em internal/db (CursorParams, PagedCursor,
+// KeysetWhere, KeysetOrderBy) são genéricos e ficam prontos para quando
+// esses endpoints existirem.
+func (dlq *DeadLetterQueue) ListDeadLetter(ctx context.Context, cursor db.CursorParams, includeTotal bool) (db.PagedCursor[DeadLetterEntry], error) {
+	limit := cursor.Limit()
+	scanDesc := !cursor.Backward
+
+	where, args := db.KeysetWhere(cursor.Cursor, "moved_at", scanDesc)
+
+	query := "SELECT id, type, payload, attempt_count, last_error, moved_at FROM dead_letter_jobs"
+	if where != "" {
+		query += " WHERE " + where
+	}
+	query += " ORDER BY " + db.KeysetOrderBy("moved_at", scanDesc) + " LIMIT ?"
+	args = append(args, limit+1)
+
+	rows, err := dlq.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return db.PagedCursor[DeadLetterEntry]{}, err
+	}
+	defer rows.Close()
+
+	var entries []DeadLetterEntry
+	for rows.Next() {
+		var e DeadLetterEntry
+		if err := rows.Scan(&e.ID, &e.Type, &e.Payload, &e.AttemptCount, &e.LastError, &e.MovedAt); err != nil {
+			return db.PagedCursor[DeadLetterEntry]{}, err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return db.PagedCursor[DeadLetterEntry]{}, err
+	}
+
+	hasMore := len(entries) > limit
+	if hasMore {
+		entries = entries[:limit]
+	}
+
+	if cursor.Backward {
+		for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+			entries[i], entries[j] = entries[j], entries[i]
+		}
+	}
+
+	result := db.PagedCursor[DeadLetterEntry]{Items: entries}
+
+	if len(entries) > 0 {
+		first, last := entries[0], entries[len(entries)-1]
+		movedAtKey := func(e DeadLetterEntry) string { return e.MovedAt.Format(time.RFC3339Nano) }
+
+		if !cursor.Backward {
+			if hasMore {
+				next := db.EncodeCursor(movedAtKey(last), last.ID)
+				result.NextCursor = &next
+			}
+			if cursor.Cursor != "" {
+				prev := db.EncodeCursor(movedAtKey(first), first.ID)
+				result.PrevCursor = &prev
+			}
+		} else {
+			next := db.EncodeCursor(movedAtKey(last), last.ID)
+			result.NextCursor = &next
+			if hasMore {
+				prev := db.EncodeCursor(movedAtKey(first), first.ID)
+				result.PrevCursor = &prev
+			}
+		}
+	}
+
+	if includeTotal {
+		total, err := dlq.queries.CountDeadLetterJobs(ctx)
+		if err == nil {
+			t := int(total)
+			result.TotalItems = &t
+		}
+	}
+
+	return result, nil
+}
+
 func (dlq *DeadLetterQueue) Stats(ctx context.Context) (map[string]int64, error) {
 	total, err := dlq.queries.CountDeadLetterJobs(ctx)
 	if err != nil {
@@ -0,0 +1,116 @@
+package worker
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/PauloHFS/goth/internal/metrics"
+)
+
+// ExtractTargetHost tenta achar a URL de destino no payload de um job que
+// faz uma chamada HTTP de saída (process_webhook hoje só carrega um
+// webhook_id, mas um futuro send_activitypub carregaria a inbox de destino
+// aqui) e devolve o host dela. Devolve "" quando o payload não declara uma
+// URL — o job fica fora do particionamento por host, tratado como antes.
+func ExtractTargetHost(payload json.RawMessage) string {
+	var data struct {
+		TargetURL string `json:"target_url"`
+	}
+	if err := json.Unmarshal(payload, &data); err != nil || data.TargetURL == "" {
+		return ""
+	}
+
+	u, err := url.Parse(data.TargetURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// ShardFor distribui host entre shardCount workers por hash, de forma
+// estável entre chamadas (o mesmo host sempre cai no mesmo shard) — assim um
+// host lento só segura o shard dele, não a fila inteira. shardCount <= 0
+// devolve sempre 0 (equivalente a um único shard, sem particionamento).
+func ShardFor(host string, shardCount int) int {
+	if shardCount <= 0 {
+		return 0
+	}
+	if host == "" {
+		return 0
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(host))
+	return int(h.Sum32() % uint32(shardCount))
+}
+
+// BadHostTracker guarda, por host de destino, até quando ele está banido
+// depois de responder 5xx ou 429 — enquanto banido, AcquireJob empurra
+// run_at dos jobs desse host para a frente em vez de tentar entregá-los de
+// novo, liberando os outros shards para seguir processando normalmente.
+type BadHostTracker struct {
+	mu          sync.RWMutex
+	bannedUntil map[string]time.Time
+}
+
+func NewBadHostTracker() *BadHostTracker {
+	return &BadHostTracker{bannedUntil: make(map[string]time.Time)}
+}
+
+// Ban pune host até until. Uma chamada subsequente com until menor que o
+// banimento em vigor não o encurta — o maior Retry-After observado vence.
+func (t *BadHostTracker) Ban(host string, until time.Time) {
+	if host == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if current, ok := t.bannedUntil[host]; ok && current.After(until) {
+		return
+	}
+	t.bannedUntil[host] = until
+	metrics.BannedHosts.Set(float64(len(t.bannedUntil)))
+}
+
+// IsBanned diz se host está banido agora e até quando. Bans expirados são
+// removidos aqui (lazy cleanup), em vez de por um ticker dedicado.
+func (t *BadHostTracker) IsBanned(host string) (bool, time.Time) {
+	if host == "" {
+		return false, time.Time{}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	until, ok := t.bannedUntil[host]
+	if !ok {
+		return false, time.Time{}
+	}
+	if !time.Now().Before(until) {
+		delete(t.bannedUntil, host)
+		metrics.BannedHosts.Set(float64(len(t.bannedUntil)))
+		return false, time.Time{}
+	}
+	return true, until
+}
+
+// BannedHosts devolve os hosts atualmente banidos, usado por
+// JobServer.BadHosts — não expõe o mapa interno diretamente.
+func (t *BadHostTracker) BannedHosts() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	hosts := make([]string, 0, len(t.bannedUntil))
+	now := time.Now()
+	for host, until := range t.bannedUntil {
+		if now.Before(until) {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
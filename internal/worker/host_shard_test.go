@@ -0,0 +1,45 @@
+package worker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShardFor_Stable(t *testing.T) {
+	host := "api.example.com"
+	first := ShardFor(host, 4)
+	for i := 0; i < 10; i++ {
+		if got := ShardFor(host, 4); got != first {
+			t.Fatalf("ShardFor não é estável: esperava %d, obteve %d", first, got)
+		}
+	}
+}
+
+func TestBadHostTracker(t *testing.T) {
+	tracker := NewBadHostTracker()
+
+	if banned, _ := tracker.IsBanned("api.example.com"); banned {
+		t.Fatal("não esperava banimento antes de Ban")
+	}
+
+	tracker.Ban("api.example.com", time.Now().Add(50*time.Millisecond))
+	if banned, _ := tracker.IsBanned("api.example.com"); !banned {
+		t.Fatal("esperava banimento logo após Ban")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if banned, _ := tracker.IsBanned("api.example.com"); banned {
+		t.Fatal("esperava banimento expirado")
+	}
+}
+
+func TestExtractTargetHost(t *testing.T) {
+	host := ExtractTargetHost([]byte(`{"target_url":"https://hooks.example.com/deliver"}`))
+	if host != "hooks.example.com" {
+		t.Fatalf("esperava hooks.example.com, obteve %q", host)
+	}
+
+	if host := ExtractTargetHost([]byte(`{"webhook_id":1}`)); host != "" {
+		t.Fatalf("esperava host vazio para payload sem target_url, obteve %q", host)
+	}
+}
@@ -11,60 +11,136 @@ import (
 
 	"github.com/PauloHFS/goth/internal/config"
 	"github.com/PauloHFS/goth/internal/db"
+	"github.com/PauloHFS/goth/internal/filestore"
 	"github.com/PauloHFS/goth/internal/logging"
 	"github.com/PauloHFS/goth/internal/mailer"
-	"github.com/PauloHFS/goth/internal/metrics"
-	"github.com/PauloHFS/goth/internal/web"
+	"github.com/PauloHFS/goth/internal/pubsub"
 )
 
+// HandledJobTypes são os tópicos de pubsub.Broker que este Processor sabe
+// processar (ver handleJob) — usados para se inscrever e ser acordado assim
+// que um enqueue site publica, em vez de só descobrir o job no próximo tick.
+var HandledJobTypes = []string{
+	"send_email",
+	"send_password_reset_email",
+	"send_magic_link_email",
+	"send_verification_email",
+	"process_ai",
+	"process_webhook",
+}
+
+// workerSafetyPollInterval é o intervalo do poll de segurança de cada shard
+// quando Acquirer.Wait não recebe nenhuma notificação — muito mais longo que
+// o antigo ticker de 1s, já que a notificação via pubsub+Acquirer cobre o
+// caso comum; o poll só existe para o caso raro de notificação perdida (ver
+// Acquirer.Wait).
+const workerSafetyPollInterval = 30 * time.Second
+
 type Processor struct {
-	config      *config.Config
-	db          *sql.DB
-	queries     *db.Queries
-	logger      *slog.Logger
-	mailer      *mailer.Mailer
-	wg          sync.WaitGroup
-	jobNotify   chan struct{}
-	rateLimiter *JobRateLimiter
-	dlq         *DeadLetterQueue
+	config     *config.Config
+	queries    *db.Queries
+	logger     *slog.Logger
+	mailer     *mailer.Mailer
+	fileStore  filestore.Backend
+	wg         sync.WaitGroup
+	shardCount int
+	acquirer   *Acquirer
+	jobs       *pubsub.Broker
+	jobServer  *JobServer
 }
 
-func New(cfg *config.Config, dbConn *sql.DB, q *db.Queries, l *slog.Logger) *Processor {
+// New cria um Processor. jobs é opcional: quando nil, o Processor depende
+// apenas do poll de segurança de cada shard (comportamento anterior ao
+// pubsub). Acquire/complete/fail de jobs ficam em JobServer (ver rpc.go),
+// que este Processor usa diretamente via chamada Go — o mesmo JobServer é
+// exposto por HTTP a daemons remotos via JobServer().Register, então os
+// dois caminhos compartilham a mesma lógica de banco, sem duplicar.
+//
+// Start roda cfg.Worker.HostShardCount goroutines, cada uma dona de um
+// shard (ver worker.ShardFor) — um job com target_host só é processado pelo
+// shard dono dele, então um host lento ou banido (ver BadHostTracker) só
+// atrasa o shard dele, não os outros. Os shards competem pelas notificações
+// de job disponível através de um Acquirer compartilhado, que acorda só um
+// shard ocioso por notificação em vez de todos (ver NotifyNewJob).
+func New(cfg *config.Config, dbConn *sql.DB, q *db.Queries, l *slog.Logger, fileStore filestore.Backend, jobs *pubsub.Broker) *Processor {
+	shardCount := cfg.Worker.HostShardCount
+	if shardCount <= 0 {
+		shardCount = 4
+	}
+	badHostTTL := time.Duration(cfg.Worker.BadHostTTLSeconds) * time.Second
+
 	return &Processor{
-		config:      cfg,
-		db:          dbConn,
-		queries:     q,
-		logger:      l,
-		mailer:      mailer.New(cfg),
-		jobNotify:   make(chan struct{}, 1),
-		rateLimiter: NewJobRateLimiter(),
-		dlq:         NewDeadLetterQueue(q, dbConn, l),
+		config:     cfg,
+		queries:    q,
+		logger:     l,
+		mailer:     mailer.New(cfg),
+		fileStore:  fileStore,
+		shardCount: shardCount,
+		acquirer:   NewAcquirer(),
+		jobs:       jobs,
+		jobServer:  NewJobServerWithHostConfig(dbConn, q, jobs, l, shardCount, badHostTTL),
 	}
 }
 
+// JobServer devolve o JobServer por trás deste Processor, para montar as
+// rotas remotas (ver cmd.RunServer e cmd/workerd).
+func (p *Processor) JobServer() *JobServer {
+	return p.jobServer
+}
+
 func (p *Processor) Start(ctx context.Context) {
-	p.logger.Info("worker started")
-	ticker := time.NewTicker(1 * time.Second)
-	defer ticker.Stop()
+	p.logger.Info("worker started", "shard_count", p.shardCount)
+
+	if p.jobs != nil {
+		for _, jobType := range HandledJobTypes {
+			sub := p.jobs.Subscribe(jobType)
+			defer sub.Close()
+			go p.forwardJobNotifications(ctx, sub, jobType)
+		}
+	}
+
+	for shard := 0; shard < p.shardCount; shard++ {
+		go p.runShard(ctx, shard)
+	}
+
+	<-ctx.Done()
+	p.logger.Info("worker stopping: waiting for active jobs to finish")
+	p.wg.Wait()
+}
+
+// runShard é o loop de um único shard: espera em p.acquirer por uma
+// notificação compatível com HandledJobTypes (ou o poll de segurança) e só
+// então tenta AcquireJobForShard — nenhum polling de banco acontece entre
+// uma notificação e a próxima enquanto o shard está ocioso.
+func (p *Processor) runShard(ctx context.Context, shardIndex int) {
+	for {
+		p.acquirer.Wait(ctx, HandledJobTypes, "", workerSafetyPollInterval)
+		if ctx.Err() != nil {
+			return
+		}
+		p.processNext(ctx, shardIndex)
+	}
+}
+
+// forwardJobNotifications repassa os eventos de sub (inscrição em jobType)
+// para p.acquirer até ctx ser cancelado — o Acquirer decide qual shard
+// ocioso (se algum) recebe a notificação, em vez de acordar todos.
+func (p *Processor) forwardJobNotifications(ctx context.Context, sub *pubsub.Subscription, jobType string) {
 	for {
 		select {
 		case <-ctx.Done():
-			p.logger.Info("worker stopping: waiting for active jobs to finish")
-			p.wg.Wait()
 			return
-		case <-p.jobNotify:
-			p.processNext(ctx)
-		case <-ticker.C:
-			p.processNext(ctx)
+		case <-sub.C:
+			p.NotifyNewJob(jobType)
 		}
 	}
 }
 
-func (p *Processor) NotifyNewJob() {
-	select {
-	case p.jobNotify <- struct{}{}:
-	default:
-	}
+// NotifyNewJob avisa o Acquirer que um job do tipo jobType está disponível —
+// ele entrega a notificação a no máximo um shard ocioso compatível (ver
+// Acquirer.Notify), em vez de acordar todos como antes.
+func (p *Processor) NotifyNewJob(jobType string) {
+	p.acquirer.Notify(jobType, "")
 }
 
 func (p *Processor) Wait() {
@@ -72,16 +148,16 @@ func (p *Processor) Wait() {
 }
 
 func (p *Processor) GetDLQ() *DeadLetterQueue {
-	return p.dlq
+	return p.jobServer.dlq
 }
 
 func (p *Processor) GetRateLimiter() *JobRateLimiter {
-	return p.rateLimiter
+	return p.jobServer.rateLimiter
 }
 
-func (p *Processor) processNext(ctx context.Context) {
-	job, err := p.queries.PickNextJob(ctx)
-	if err != nil {
+func (p *Processor) processNext(ctx context.Context, shardIndex int) {
+	job, ok := p.jobServer.AcquireJobForShard(ctx, "local", HandledJobTypes, shardIndex)
+	if !ok {
 		return
 	}
 
@@ -89,32 +165,20 @@ func (p *Processor) processNext(ctx context.Context) {
 	go p.processJob(ctx, job)
 }
 
+// processJob executa job (via DispatchJob) e relata o resultado a
+// p.jobServer — o acquire já aconteceu em processNext, incluindo o
+// deduplicate-e-rate-limit que antes vivia aqui.
 func (p *Processor) processJob(ctx context.Context, job db.Job) {
 	defer p.wg.Done()
 
-	start := time.Now()
-
 	ctx, event := logging.NewEventContext(ctx)
 	event.Add(
-		slog.Int64("job_id", int64(job.ID)),
+		slog.Int64("job_id", job.ID),
 		slog.String("job_type", string(job.Type)),
 		slog.Int64("attempt", job.AttemptCount),
 	)
 
-	processed, err := p.queries.IsJobProcessed(ctx, job.ID)
-	if err == nil && processed == 1 {
-		p.logger.InfoContext(ctx, "job already processed, skipping", event.Attrs()...)
-		_ = p.queries.CompleteJob(ctx, job.ID)
-		return
-	}
-
-	if err := p.rateLimiter.Acquire(ctx, string(job.Type)); err != nil {
-		p.logger.WarnContext(ctx, "rate limit wait cancelled", "error", err.Error())
-		return
-	}
-	defer p.rateLimiter.Release(string(job.Type))
-
-	errProcessing := p.handleJob(ctx, job)
+	errProcessing := p.DispatchJob(ctx, string(job.Type), job.Payload)
 
 	if retryAfter := GetRetryAfterDuration(errProcessing); retryAfter > 0 && IsExternalRateLimitError(errProcessing) {
 		p.logger.WarnContext(ctx, "external rate limit detected, backing off",
@@ -126,101 +190,43 @@ func (p *Processor) processJob(ctx context.Context, job db.Job) {
 	}
 
 	if errProcessing != nil {
-		p.handleFailure(ctx, job, errProcessing, start, event)
+		if err := p.jobServer.FailJob(ctx, "local", job.ID, errProcessing.Error()); err != nil {
+			p.logger.ErrorContext(ctx, "failed to report job failure", "error", err.Error())
+		}
 		return
 	}
 
-	p.handleSuccess(ctx, job, start, event)
-
-	if job.TenantID.Valid {
-		var userID int64
-		if _, err := fmt.Sscanf(job.TenantID.String, "%d", &userID); err == nil && userID > 0 {
-			web.BroadcastToUser(userID, "job_completed", string(job.Type))
-			return
-		}
+	if err := p.jobServer.CompleteJob(ctx, "local", job.ID); err != nil {
+		p.logger.ErrorContext(ctx, "failed to report job completion", "error", err.Error())
+		return
 	}
 
-	web.Broadcast("job_completed", string(job.Type))
+	p.logger.InfoContext(ctx, "job dispatched", event.Attrs()...)
 }
 
-func (p *Processor) handleJob(ctx context.Context, job db.Job) error {
-	switch job.Type {
+// DispatchJob executa o handler de jobType sobre payload. É o único lugar
+// que decide como processar cada tipo de job, usado tanto pelo worker
+// in-process (processJob) quanto por cmd/workerd, que chama DispatchJob
+// sobre o job recebido de JobServer via HTTP — a execução de fato não
+// depende de onde o job foi adquirido.
+func (p *Processor) DispatchJob(ctx context.Context, jobType string, payload json.RawMessage) error {
+	switch jobType {
 	case "send_email":
-		return p.handleSendEmail(ctx, job.Payload)
+		return p.handleSendEmail(ctx, payload)
 	case "send_password_reset_email":
-		return p.handleSendPasswordResetEmail(ctx, job.Payload)
+		return p.handleSendPasswordResetEmail(ctx, payload)
+	case "send_magic_link_email":
+		return p.handleSendMagicLinkEmail(ctx, payload)
 	case "send_verification_email":
-		return p.handleSendVerificationEmail(ctx, job.Payload)
+		return p.handleSendVerificationEmail(ctx, payload)
 	case "process_ai":
-		return p.handleProcessAI(ctx, job.Payload)
+		return p.handleProcessAI(ctx, payload)
 	case "process_webhook":
-		return p.handleProcessWebhook(ctx, job.Payload)
+		return p.handleProcessWebhook(ctx, payload)
 	default:
-		p.logger.WarnContext(ctx, "unknown job type", "type", job.Type)
-		return fmt.Errorf("unknown job type: %s", job.Type)
-	}
-}
-
-func (p *Processor) handleFailure(ctx context.Context, job db.Job, errProcessing error, start time.Time, event *logging.Event) {
-	metrics.JobDuration.WithLabelValues(string(job.Type), "failed").Observe(time.Since(start).Seconds())
-
-	if p.dlq.ShouldMoveToDLQ(job) {
-		if err := p.dlq.Move(ctx, job, errProcessing); err != nil {
-			p.logger.ErrorContext(ctx, "failed to move job to DLQ", "error", err.Error())
-		}
-		return
+		p.logger.WarnContext(ctx, "unknown job type", "type", jobType)
+		return fmt.Errorf("unknown job type: %s", jobType)
 	}
-
-	if err := p.queries.FailJob(ctx, db.FailJobParams{
-		LastError: sql.NullString{String: errProcessing.Error(), Valid: true},
-		ID:        job.ID,
-	}); err != nil {
-		p.logger.ErrorContext(ctx, "failed to record job failure in db", "error", err.Error())
-	}
-
-	if IsRetryableError(errProcessing) {
-		backoff := FullJitter(int(job.AttemptCount), DefaultBackoffConfig)
-		p.logger.InfoContext(ctx, "retryable error, will retry",
-			"backoff", backoff.String(),
-			"error", errProcessing.Error(),
-		)
-		metrics.JobRetries.WithLabelValues(string(job.Type)).Inc()
-	}
-
-	p.logger.ErrorContext(ctx, "job processing failed",
-		append(event.Attrs(), slog.String("error", errProcessing.Error()))...)
-}
-
-func (p *Processor) handleSuccess(ctx context.Context, job db.Job, start time.Time, event *logging.Event) {
-	tx, err := p.db.BeginTx(ctx, nil)
-	if err != nil {
-		p.logger.ErrorContext(ctx, "failed to start transaction", "error", err.Error())
-		return
-	}
-	defer func() { _ = tx.Rollback() }()
-
-	qtx := p.queries.WithTx(tx)
-
-	if err := qtx.RecordJobProcessed(ctx, job.ID); err != nil {
-		p.logger.ErrorContext(ctx, "failed to record job processed", "error", err.Error())
-		return
-	}
-
-	if err := qtx.CompleteJob(ctx, job.ID); err != nil {
-		p.logger.ErrorContext(ctx, "failed to complete job", "error", err.Error())
-		return
-	}
-
-	if err := tx.Commit(); err != nil {
-		p.logger.ErrorContext(ctx, "failed to commit transaction", "error", err.Error())
-		return
-	}
-
-	duration := time.Since(start)
-	metrics.JobDuration.WithLabelValues(string(job.Type), "success").Observe(duration.Seconds())
-	event.Add(slog.Float64("duration_ms", float64(duration.Nanoseconds())/1e6))
-
-	p.logger.InfoContext(ctx, "job completed", event.Attrs()...)
 }
 
 func (p *Processor) handleSendEmail(ctx context.Context, payload json.RawMessage) error {
@@ -272,16 +278,52 @@ func (p *Processor) handleSendPasswordResetEmail(ctx context.Context, payload js
 	return p.mailer.Send(data.Email, subject, body)
 }
 
+func (p *Processor) handleSendMagicLinkEmail(ctx context.Context, payload json.RawMessage) error {
+	var data struct {
+		Email string `json:"email"`
+		Token string `json:"token"`
+	}
+
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return err
+	}
+
+	verifyURL := p.config.BaseURL + "/login/magic/verify?token=" + data.Token
+	subject := "Seu link de acesso"
+	body := "Olá,\n\nClique no link abaixo para entrar na sua conta:\n\n" +
+		verifyURL + "\n\nEste link expira em 15 minutos e só pode ser usado uma vez."
+
+	return p.mailer.Send(data.Email, subject, body)
+}
+
 func (p *Processor) handleProcessAI(ctx context.Context, payload json.RawMessage) error {
 	var data struct {
-		Prompt string `json:"prompt"`
+		Prompt     string `json:"prompt"`
+		StorageKey string `json:"storage_key"`
 	}
 
 	if err := json.Unmarshal(payload, &data); err != nil {
 		return err
 	}
 
-	p.logger.InfoContext(ctx, "AI processing started", "prompt", data.Prompt)
+	if data.StorageKey != "" {
+		if p.fileStore == nil {
+			return fmt.Errorf("process_ai: storage_key informado mas nenhum filestore.Backend configurado")
+		}
+
+		// Busca pela chave de storage (não uma URL), para que o job rode em
+		// qualquer nó independente de onde o arquivo foi originalmente salvo.
+		f, err := p.fileStore.Get(ctx, data.StorageKey)
+		if err != nil {
+			return fmt.Errorf("process_ai: falha ao buscar %q: %w", data.StorageKey, err)
+		}
+		defer f.Close()
+
+		p.logger.InfoContext(ctx, "AI processing started", "storage_key", data.StorageKey)
+	} else {
+		p.logger.InfoContext(ctx, "AI processing started", "prompt", data.Prompt)
+	}
+
 	time.Sleep(2 * time.Second)
 
 	return nil
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/time/rate"
@@ -25,83 +26,324 @@ var DefaultJobRateConfigs = map[string]JobRateConfig{
 	"process_webhook":           {Concurrency: 10, Rate: 5, Burst: 10},
 }
 
+// Priority determina a ordem em que waiters de diferentes tenants disputam
+// uma vaga de concorrência quando o semáforo de um jobType está cheio.
+// Valores menores são atendidos primeiro.
+type Priority int
+
+const (
+	PriorityHigh Priority = iota
+	PriorityNormal
+	PriorityLow
+)
+
+const numPriorities = 3
+
+// waitBucket é um corte do histograma de tempo de espera exposto em
+// GetStats, em ordem crescente de duração.
+type waitBucket int
+
+const (
+	waitUnder10ms waitBucket = iota
+	waitUnder100ms
+	waitUnder1s
+	waitOver1s
+	numWaitBuckets
+)
+
+func bucketFor(d time.Duration) waitBucket {
+	switch {
+	case d < 10*time.Millisecond:
+		return waitUnder10ms
+	case d < 100*time.Millisecond:
+		return waitUnder100ms
+	case d < time.Second:
+		return waitUnder1s
+	default:
+		return waitOver1s
+	}
+}
+
+// tenantState isola o rate limit e as estatísticas de um único
+// (jobType, tenantID): cada tenant tem seu próprio token bucket, derivado da
+// taxa global do jobType por weighted fair queuing (ver jobTypeState.rebalanceLocked).
+type tenantState struct {
+	limiter     *rate.Limiter
+	weight      float64
+	inFlight    int64
+	waitBuckets [numWaitBuckets]int64
+}
+
+func (t *tenantState) recordWait(d time.Duration) {
+	atomic.AddInt64(&t.waitBuckets[bucketFor(d)], 1)
+}
+
+// ticket representa um waiter na fila de prioridade de um jobType, esperando
+// por uma vaga de concorrência que outra chamada a Release vai liberar.
+type ticket struct {
+	tenantID string
+	ready    chan struct{}
+}
+
+// jobTypeState guarda o cap de concorrência global de um jobType (o limite
+// superior que nenhum tenant pode ultrapassar sozinho), os tenants
+// conhecidos e as filas de espera por banda de prioridade.
+type jobTypeState struct {
+	mu          sync.Mutex
+	concurrency int
+	inUse       int
+	baseRate    rate.Limit
+	baseBurst   int
+	tenants     map[string]*tenantState
+	queues      [numPriorities][]*ticket
+}
+
+func newJobTypeState(cfg JobRateConfig) *jobTypeState {
+	return &jobTypeState{
+		concurrency: cfg.Concurrency,
+		baseRate:    cfg.Rate,
+		baseBurst:   cfg.Burst,
+		tenants:     make(map[string]*tenantState),
+	}
+}
+
+// getOrCreateTenant devolve o tenantState de tenantID, criando um novo token
+// bucket com peso 1 na primeira chamada e rebalanceando a taxa de todos os
+// tenants do jobType em seguida.
+func (jts *jobTypeState) getOrCreateTenant(tenantID string) *tenantState {
+	jts.mu.Lock()
+	defer jts.mu.Unlock()
+
+	if t, ok := jts.tenants[tenantID]; ok {
+		return t
+	}
+
+	t := &tenantState{weight: 1, limiter: rate.NewLimiter(jts.baseRate, jts.baseBurst)}
+	jts.tenants[tenantID] = t
+	jts.rebalanceLocked()
+	return t
+}
+
+// rebalanceLocked redistribui a taxa global do jobType entre os tenants
+// conhecidos proporcionalmente ao peso de cada um (weighted fair queuing).
+// Chamado com jts.mu já travado.
+func (jts *jobTypeState) rebalanceLocked() {
+	var totalWeight float64
+	for _, t := range jts.tenants {
+		totalWeight += t.weight
+	}
+	if totalWeight <= 0 {
+		return
+	}
+
+	for _, t := range jts.tenants {
+		share := t.weight / totalWeight
+		t.limiter.SetLimit(jts.baseRate * rate.Limit(share))
+
+		burst := int(float64(jts.baseBurst) * share)
+		if burst < 1 {
+			burst = 1
+		}
+		t.limiter.SetBurst(burst)
+	}
+}
+
+// tryAcquireOrEnqueue tenta tomar uma vaga de concorrência imediatamente; se
+// o jobType já está no limite, enfileira um ticket na banda de prioridade
+// informada e o devolve para o chamador esperar em ticket.ready.
+func (jts *jobTypeState) tryAcquireOrEnqueue(priority Priority) (*ticket, bool) {
+	jts.mu.Lock()
+	defer jts.mu.Unlock()
+
+	if jts.inUse < jts.concurrency {
+		jts.inUse++
+		return nil, true
+	}
+
+	t := &ticket{ready: make(chan struct{})}
+	jts.queues[priority] = append(jts.queues[priority], t)
+	return t, false
+}
+
+// releaseSlot devolve a vaga de concorrência liberada por Release ao
+// próximo waiter na fila (na banda de prioridade mais alta não vazia) ou,
+// se não houver ninguém esperando, diminui o contador de uso.
+func (jts *jobTypeState) releaseSlot() {
+	jts.mu.Lock()
+	defer jts.mu.Unlock()
+
+	for p := 0; p < numPriorities; p++ {
+		q := jts.queues[p]
+		if len(q) == 0 {
+			continue
+		}
+		next := q[0]
+		jts.queues[p] = q[1:]
+		close(next.ready)
+		return
+	}
+
+	jts.inUse--
+}
+
+// cancelWaiter remove t da fila de espera quando o contexto do chamador é
+// cancelado. Se t já tiver sido retirado da fila por releaseSlot (corrida
+// entre a liberação da vaga e o cancelamento), a vaga foi concedida a um
+// waiter que não vai mais consumi-la, então ela é repassada adiante.
+func (jts *jobTypeState) cancelWaiter(priority Priority, t *ticket) {
+	jts.mu.Lock()
+	q := jts.queues[priority]
+	for i, candidate := range q {
+		if candidate == t {
+			jts.queues[priority] = append(q[:i], q[i+1:]...)
+			jts.mu.Unlock()
+			return
+		}
+	}
+	jts.mu.Unlock()
+
+	jts.releaseSlot()
+}
+
+// JobRateLimiter é um agendador de dois níveis por jobType: um cap de
+// concorrência global, e dentro dele um token bucket por (jobType, tenantID)
+// com taxa derivada por weighted fair queuing, mais uma fila de prioridade
+// de 3 bandas para decidir quem recebe a próxima vaga liberada. Isso evita
+// que um único tenant barulhento esgote o semáforo de um jobType compartilhado.
 type JobRateLimiter struct {
-	semaphores map[string]chan struct{}
-	limiters   map[string]*rate.Limiter
-	mu         sync.RWMutex
+	mu    sync.RWMutex
+	types map[string]*jobTypeState
 }
 
 func NewJobRateLimiter() *JobRateLimiter {
 	jrl := &JobRateLimiter{
-		semaphores: make(map[string]chan struct{}),
-		limiters:   make(map[string]*rate.Limiter),
+		types: make(map[string]*jobTypeState),
 	}
 
 	for jobType, cfg := range DefaultJobRateConfigs {
-		jrl.semaphores[jobType] = make(chan struct{}, cfg.Concurrency)
-		jrl.limiters[jobType] = rate.NewLimiter(cfg.Rate, cfg.Burst)
+		jrl.types[jobType] = newJobTypeState(cfg)
 	}
 
-	jrl.semaphores["default"] = make(chan struct{}, 5)
-	jrl.limiters["default"] = rate.NewLimiter(1, 5)
+	jrl.types["default"] = newJobTypeState(JobRateConfig{Concurrency: 5, Rate: 1, Burst: 5})
 
 	return jrl
 }
 
-func (jrl *JobRateLimiter) Acquire(ctx context.Context, jobType string) error {
-	limiter := jrl.getLimiter(jobType)
-	if err := limiter.Wait(ctx); err != nil {
+// Acquire espera pela taxa individual de (jobType, tenantID) e então por uma
+// vaga de concorrência do jobType, respeitando priority quando há disputa.
+func (jrl *JobRateLimiter) Acquire(ctx context.Context, jobType, tenantID string, priority Priority) error {
+	state := jrl.getJobTypeState(jobType)
+	tenant := state.getOrCreateTenant(tenantID)
+
+	if err := tenant.limiter.Wait(ctx); err != nil {
 		return err
 	}
 
-	semaphore := jrl.getSemaphore(jobType)
+	t, acquired := state.tryAcquireOrEnqueue(priority)
+	if acquired {
+		atomic.AddInt64(&tenant.inFlight, 1)
+		return nil
+	}
+
+	enqueuedAt := time.Now()
 	select {
-	case semaphore <- struct{}{}:
+	case <-t.ready:
+		tenant.recordWait(time.Since(enqueuedAt))
+		atomic.AddInt64(&tenant.inFlight, 1)
 		return nil
 	case <-ctx.Done():
+		state.cancelWaiter(priority, t)
 		return ctx.Err()
 	}
 }
 
-func (jrl *JobRateLimiter) Release(jobType string) {
-	semaphore := jrl.getSemaphore(jobType)
-	select {
-	case <-semaphore:
-	default:
+// Release libera a vaga de concorrência de tenantID no jobType, repassando-a
+// ao próximo waiter na fila de prioridade se houver algum.
+func (jrl *JobRateLimiter) Release(jobType, tenantID string) {
+	state := jrl.getJobTypeState(jobType)
+
+	state.mu.Lock()
+	tenant, ok := state.tenants[tenantID]
+	state.mu.Unlock()
+	if ok {
+		atomic.AddInt64(&tenant.inFlight, -1)
 	}
+
+	state.releaseSlot()
 }
 
-func (jrl *JobRateLimiter) getLimiter(jobType string) *rate.Limiter {
-	jrl.mu.RLock()
-	defer jrl.mu.RUnlock()
+// SetTenantWeight ajusta o peso de (jobType, tenantID) usado para dividir a
+// taxa global do jobType entre tenants (weighted fair queuing). Pesos maiores
+// recebem uma fatia maior da taxa; weight <= 0 é tratado como 1.
+func (jrl *JobRateLimiter) SetTenantWeight(jobType, tenantID string, weight float64) {
+	if weight <= 0 {
+		weight = 1
+	}
+
+	state := jrl.getJobTypeState(jobType)
 
-	if limiter, ok := jrl.limiters[jobType]; ok {
-		return limiter
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	t, ok := state.tenants[tenantID]
+	if !ok {
+		t = &tenantState{limiter: rate.NewLimiter(state.baseRate, state.baseBurst)}
+		state.tenants[tenantID] = t
 	}
-	return jrl.limiters["default"]
+	t.weight = weight
+	state.rebalanceLocked()
 }
 
-func (jrl *JobRateLimiter) getSemaphore(jobType string) chan struct{} {
+func (jrl *JobRateLimiter) getJobTypeState(jobType string) *jobTypeState {
 	jrl.mu.RLock()
-	defer jrl.mu.RUnlock()
+	if state, ok := jrl.types[jobType]; ok {
+		jrl.mu.RUnlock()
+		return state
+	}
+	jrl.mu.RUnlock()
 
-	if sem, ok := jrl.semaphores[jobType]; ok {
-		return sem
+	jrl.mu.Lock()
+	defer jrl.mu.Unlock()
+	if state, ok := jrl.types[jobType]; ok {
+		return state
 	}
-	return jrl.semaphores["default"]
+	return jrl.types["default"]
 }
 
+// GetStats devolve, por jobType, o cap e uso de concorrência global e, por
+// tenant, a taxa atual, o peso, requisições em voo e um histograma de tempo
+// de espera por vaga de concorrência.
 func (jrl *JobRateLimiter) GetStats() map[string]interface{} {
 	jrl.mu.RLock()
 	defer jrl.mu.RUnlock()
 
 	stats := make(map[string]interface{})
-	for jobType := range jrl.semaphores {
+	for jobType, state := range jrl.types {
+		state.mu.Lock()
+
+		tenantStats := make(map[string]interface{}, len(state.tenants))
+		for tenantID, t := range state.tenants {
+			tenantStats[tenantID] = map[string]interface{}{
+				"weight":    t.weight,
+				"rate":      float64(t.limiter.Limit()),
+				"in_flight": atomic.LoadInt64(&t.inFlight),
+				"wait_histogram": map[string]int64{
+					"under_10ms":  atomic.LoadInt64(&t.waitBuckets[waitUnder10ms]),
+					"under_100ms": atomic.LoadInt64(&t.waitBuckets[waitUnder100ms]),
+					"under_1s":    atomic.LoadInt64(&t.waitBuckets[waitUnder1s]),
+					"over_1s":     atomic.LoadInt64(&t.waitBuckets[waitOver1s]),
+				},
+			}
+		}
+
 		stats[jobType] = map[string]interface{}{
-			"concurrency": cap(jrl.semaphores[jobType]),
-			"in_use":      len(jrl.semaphores[jobType]),
-			"rate":        float64(jrl.limiters[jobType].Limit()),
+			"concurrency": state.concurrency,
+			"in_use":      state.inUse,
+			"rate":        float64(state.baseRate),
+			"tenants":     tenantStats,
 		}
+
+		state.mu.Unlock()
 	}
 	return stats
 }
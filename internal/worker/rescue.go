@@ -2,17 +2,47 @@ package worker
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+
+	"github.com/PauloHFS/goth/internal/metrics"
 )
 
-// RescueZombies resgata jobs que ficaram presos no status 'processing'
-// devido a um crash ou restart inesperado do servidor.
+// RescueZombies resgata jobs cujo lease (ver ExtendJobLease em rpc.go)
+// venceu sem complete/fail correspondente — sinal de que o worker que
+// adquiriu o job travou ou caiu antes de terminar. Cada job vencido segue a
+// mesma decisão requeue-ou-DLQ de JobServer.FailJob, via
+// DeadLetterQueue.ShouldMoveToDLQ.
 func (p *Processor) RescueZombies(ctx context.Context) error {
-	p.logger.Info("zombie hunter: searching for stuck jobs")
-	err := p.queries.RescueZombies(ctx)
+	p.logger.Info("zombie hunter: searching for jobs with expired lease")
+
+	jobs, err := p.queries.ListExpiredLeaseJobs(ctx)
 	if err != nil {
-		p.logger.Error("zombie hunter: failed to rescue jobs", slog.String("error", err.Error()))
+		p.logger.Error("zombie hunter: failed to list expired lease jobs", slog.String("error", err.Error()))
 		return err
 	}
+
+	dlq := p.jobServer.dlq
+	for _, job := range jobs {
+		outcome := "requeued"
+
+		if dlq.ShouldMoveToDLQ(job) {
+			outcome = "dead_letter"
+			if err := dlq.Move(ctx, job, fmt.Errorf("job lease expired without completion")); err != nil {
+				p.logger.Error("zombie hunter: failed to move expired lease job to DLQ",
+					slog.Int64("job_id", job.ID), slog.String("error", err.Error()))
+				continue
+			}
+		} else if err := p.queries.RequeueExpiredLeaseJob(ctx, job.ID); err != nil {
+			p.logger.Error("zombie hunter: failed to requeue expired lease job",
+				slog.Int64("job_id", job.ID), slog.String("error", err.Error()))
+			continue
+		}
+
+		metrics.JobLeaseRescuesTotal.WithLabelValues(string(job.Type), outcome).Inc()
+		p.logger.Warn("zombie hunter: rescued job with expired lease",
+			slog.Int64("job_id", job.ID), slog.String("job_type", string(job.Type)), slog.String("outcome", outcome))
+	}
+
 	return nil
 }
@@ -0,0 +1,630 @@
+package worker
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PauloHFS/goth/internal/db"
+	"github.com/PauloHFS/goth/internal/metrics"
+	"github.com/PauloHFS/goth/internal/pubsub"
+	"github.com/PauloHFS/goth/internal/web"
+)
+
+// acquireLongPollTimeout limita quanto tempo uma chamada HTTP a
+// POST /api/worker/acquire fica pendurada esperando um job aparecer antes de
+// devolver 204 — o daemon remoto (ver cmd/workerd) simplesmente chama de
+// novo em seguida, o mesmo padrão de retry do ticker de Processor.Start.
+const acquireLongPollTimeout = 25 * time.Second
+
+// jobLeaseDuration e jobLeaseRenewInterval implementam o modelo de lease do
+// Woodpecker: um job adquirido fica reservado até leased_until, renovado
+// periodicamente por quem o está processando. RescueZombies (ver rescue.go)
+// usa leased_until expirado, em vez de um "processing há muito tempo"
+// genérico, para decidir o que resgatar — o worker que travou ou caiu nem
+// precisa ser detectado, o lease simplesmente vence.
+const (
+	jobLeaseDuration      = 45 * time.Second
+	jobLeaseRenewInterval = 20 * time.Second
+)
+
+// inFlightJob é o que JobServer precisa lembrar entre AcquireJob e o
+// CompleteJob/FailJob correspondente: o db.Job original (exigido por
+// DeadLetterQueue.Move), o instante em que a execução começou, para as
+// métricas de duração, e cancelLease, que encerra a goroutine de renovação
+// de lease assim que o job é finalizado.
+type inFlightJob struct {
+	job         db.Job
+	tenantID    string
+	start       time.Time
+	cancelLease context.CancelFunc
+}
+
+// JobServer concentra tudo que o worker faz contra o banco — acquire,
+// complete, fail, rate limiting e a decisão de mover para a dead letter
+// queue — num único lugar usado tanto pelo Processor in-process (chamada Go
+// direta, sem rede) quanto pelas rotas HTTP em Register, consultadas por
+// daemons remotos (ver cmd/workerd). Execução de fato (mailer, filestore)
+// fica fora daqui, em Processor.DispatchJob: JobServer só decide o que um
+// job pendente, completo ou falho significa para o banco.
+type JobServer struct {
+	queries     *db.Queries
+	db          *sql.DB
+	logger      *slog.Logger
+	jobs        *pubsub.Broker
+	rateLimiter *JobRateLimiter
+	dlq         *DeadLetterQueue
+	badHosts    *BadHostTracker
+	badHostTTL  time.Duration
+	shardCount  int
+
+	mu       sync.Mutex
+	inFlight map[int64]inFlightJob
+}
+
+// NewJobServer cria um JobServer. jobs é opcional (ver pubsub.Broker):
+// quando nil, AcquireJob via HTTP cai só no polling por ticker feito pelo
+// chamador, sem long-poll acordado por publish. shardCount e badHostTTL <= 0
+// caem nos mesmos padrões de config.WorkerConfig (4 shards, 60s de TTL).
+func NewJobServer(dbConn *sql.DB, q *db.Queries, jobs *pubsub.Broker, logger *slog.Logger) *JobServer {
+	return NewJobServerWithHostConfig(dbConn, q, jobs, logger, 4, 60*time.Second)
+}
+
+// NewJobServerWithHostConfig é NewJobServer com o shardCount e badHostTTL do
+// particionamento por host de destino explícitos (ver worker.ShardFor e
+// worker.BadHostTracker) — usado por worker.New, que repassa
+// config.WorkerConfig.HostShardCount/BadHostTTLSeconds.
+func NewJobServerWithHostConfig(dbConn *sql.DB, q *db.Queries, jobs *pubsub.Broker, logger *slog.Logger, shardCount int, badHostTTL time.Duration) *JobServer {
+	if shardCount <= 0 {
+		shardCount = 4
+	}
+	if badHostTTL <= 0 {
+		badHostTTL = 60 * time.Second
+	}
+
+	return &JobServer{
+		queries:     q,
+		db:          dbConn,
+		logger:      logger,
+		jobs:        jobs,
+		rateLimiter: NewJobRateLimiter(),
+		dlq:         NewDeadLetterQueue(q, dbConn, logger),
+		badHosts:    NewBadHostTracker(),
+		badHostTTL:  badHostTTL,
+		shardCount:  shardCount,
+		inFlight:    make(map[int64]inFlightJob),
+	}
+}
+
+// EnsureLeaseSchema adiciona as colunas leased_until/leased_by à tabela
+// jobs, já criada por uma migração anterior — segue o mesmo idioma
+// EnsureTable usado por session.Store, magiclink.Store etc., só que via
+// ALTER TABLE em vez de CREATE TABLE IF NOT EXISTS, já que a tabela jobs
+// não nasceu com este backlog. SQLite não suporta ADD COLUMN IF NOT EXISTS,
+// então erros de "duplicate column" são tolerados para manter a chamada
+// idempotente entre reinícios do servidor.
+func (s *JobServer) EnsureLeaseSchema(ctx context.Context) error {
+	stmts := []string{
+		"ALTER TABLE jobs ADD COLUMN leased_until DATETIME",
+		"ALTER TABLE jobs ADD COLUMN leased_by TEXT",
+		"CREATE INDEX IF NOT EXISTS idx_jobs_leased_until ON jobs(leased_until)",
+	}
+
+	for _, stmt := range stmts {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil && !strings.Contains(err.Error(), "duplicate column") {
+			return fmt.Errorf("failed to ensure jobs lease schema: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// EnsureTargetHostSchema adiciona a coluna target_host à tabela jobs, no
+// mesmo idioma idempotente de EnsureLeaseSchema. target_host é preenchido
+// por SetJobTargetHost logo após o enqueue de um job que faz uma chamada
+// HTTP de saída (ver worker.ExtractTargetHost) e lido por AcquireJob para
+// decidir o shard do job e se o host dele está banido (ver BadHostTracker).
+func (s *JobServer) EnsureTargetHostSchema(ctx context.Context) error {
+	stmts := []string{
+		"ALTER TABLE jobs ADD COLUMN target_host TEXT",
+		"CREATE INDEX IF NOT EXISTS idx_jobs_target_host ON jobs(target_host)",
+	}
+
+	for _, stmt := range stmts {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil && !strings.Contains(err.Error(), "duplicate column") {
+			return fmt.Errorf("failed to ensure jobs target_host schema: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// SetJobTargetHost grava o host de destino de jobID, extraído do payload no
+// enqueue (ver worker.ExtractTargetHost). Fica fora do código gerado pelo
+// sqlc — db.CreateJobParams não tem esse campo — então é uma chamada SQL
+// separada logo depois do CreateJob original, o mesmo padrão de escape hatch
+// de EnsureLeaseSchema/EnsureTargetHostSchema para colunas que essa tabela
+// não previa.
+func SetJobTargetHost(ctx context.Context, dbConn *sql.DB, jobID int64, targetHost string) error {
+	if targetHost == "" {
+		return nil
+	}
+
+	_, err := dbConn.ExecContext(ctx, "UPDATE jobs SET target_host = ? WHERE id = ?", targetHost, jobID)
+	return err
+}
+
+// jobTargetHost lê o target_host gravado por SetJobTargetHost para jobID.
+// Devolve "" (sem erro) quando a coluna está nula ou o job não tem host
+// associado, o caminho comum para jobs que não fazem chamadas de saída.
+func (s *JobServer) jobTargetHost(ctx context.Context, jobID int64) string {
+	var host sql.NullString
+	if err := s.db.QueryRowContext(ctx, "SELECT target_host FROM jobs WHERE id = ?", jobID).Scan(&host); err != nil {
+		return ""
+	}
+	return host.String
+}
+
+// requeueAfterHostBan empurra run_at de jobID para until, sem marcar o job
+// como falho (não é um erro do job, é o host de destino que está cumprindo
+// backoff) — o job some da fila até lá e é repicado normalmente depois,
+// sem incrementar attempt_count nem passar por DeadLetterQueue.
+func (s *JobServer) requeueAfterHostBan(ctx context.Context, jobID int64, until time.Time) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE jobs SET run_at = ? WHERE id = ?", until, jobID)
+	return err
+}
+
+// RecordHostOutcome marca host como banido até badHostTTL (ou até
+// retryAfter, se maior) quando statusCode indica que ele está sobrecarregado
+// ou fora do ar (5xx, 429) — chamado por Processor depois de uma chamada de
+// saída malsucedida para um job com target_host. statusCode fora dessa faixa
+// não bane nada.
+func (s *JobServer) RecordHostOutcome(host string, statusCode int, retryAfter time.Duration) {
+	if host == "" || (statusCode < 500 && statusCode != 429) {
+		return
+	}
+
+	ttl := s.badHostTTL
+	if retryAfter > ttl {
+		ttl = retryAfter
+	}
+	s.badHosts.Ban(host, time.Now().Add(ttl))
+}
+
+// BadHosts devolve os hosts atualmente banidos (ver BadHostTracker). A
+// métrica worker_banned_hosts não passa por aqui: BadHostTracker.Ban e
+// IsBanned a atualizam a cada mudança do mapa.
+func (s *JobServer) BadHosts() []string {
+	return s.badHosts.BannedHosts()
+}
+
+// AcquireJob tenta pegar o próximo job pendente em nome de workerID,
+// ignorando o particionamento por host de destino (ver AcquireJobForShard) —
+// usado pelos caminhos que não são donos de um shard fixo, como as rotas
+// HTTP consultadas por daemons remotos via cmd/workerd.
+func (s *JobServer) AcquireJob(ctx context.Context, workerID string, jobTypes []string) (db.Job, bool) {
+	return s.AcquireJobForShard(ctx, workerID, jobTypes, -1)
+}
+
+// AcquireJobForShard tenta pegar o próximo job pendente em nome de workerID.
+// Replica o comportamento de uma única tentativa de PickNextJob por chamada
+// (sem fila interna de espera) que o Processor in-process sempre teve —
+// found é false tanto para fila vazia quanto para erro de banco, mesmo
+// silenciamento de antes.
+//
+// jobTypes é hoje só informativo: PickNextJob não filtra por tipo (é uma
+// query genérica, ver worker.Processor), então um daemon que anuncia só um
+// subconjunto de tipos ainda pode, em teoria, receber um job de outro tipo.
+// Filtrar de verdade exigiria uma query dedicada no código gerado pelo
+// sqlc, fora do alcance desta mudança.
+//
+// shardIndex < 0 desliga o particionamento por host (AcquireJob cai aqui).
+// Caso contrário, um job com target_host (ver worker.ExtractTargetHost) só é
+// devolvido se worker.ShardFor(host, s.shardCount) == shardIndex — caso
+// contrário ele é deixado de lado para o shard dono pegar depois, do mesmo
+// jeito que um host banido: PickNextJob já tirou o job do topo da fila sem
+// filtrar por shard (mesma limitação de jobTypes acima), então em vez de
+// falhar, o job tem seu run_at empurrado um segundo à frente e
+// devolve-se found=false, deixando o próximo tick do shard certo pegá-lo.
+func (s *JobServer) AcquireJobForShard(ctx context.Context, workerID string, jobTypes []string, shardIndex int) (db.Job, bool) {
+	job, err := s.queries.PickNextJob(ctx)
+	if err != nil {
+		return db.Job{}, false
+	}
+
+	if processed, err := s.queries.IsJobProcessed(ctx, job.ID); err == nil && processed == 1 {
+		s.logger.InfoContext(ctx, "job already processed, skipping",
+			slog.Int64("job_id", job.ID), slog.String("worker_id", workerID))
+		_ = s.queries.CompleteJob(ctx, job.ID)
+		return db.Job{}, false
+	}
+
+	if host := s.jobTargetHost(ctx, job.ID); host != "" {
+		if banned, until := s.badHosts.IsBanned(host); banned {
+			metrics.JobsQueuedByHost.WithLabelValues(host).Inc()
+			if err := s.requeueAfterHostBan(ctx, job.ID, until); err != nil {
+				s.logger.WarnContext(ctx, "failed to requeue job behind banned host", "job_id", job.ID, "host", host, "error", err.Error())
+			}
+			return db.Job{}, false
+		}
+
+		if shardIndex >= 0 && ShardFor(host, s.shardCount) != shardIndex {
+			metrics.JobsQueuedByHost.WithLabelValues(host).Inc()
+			if err := s.requeueAfterHostBan(ctx, job.ID, time.Now().Add(1*time.Second)); err != nil {
+				s.logger.WarnContext(ctx, "failed to requeue job for its owning shard", "job_id", job.ID, "host", host, "error", err.Error())
+			}
+			return db.Job{}, false
+		}
+
+		metrics.JobsQueuedByHost.WithLabelValues(host).Set(0)
+	}
+
+	tenantID := "default"
+	if job.TenantID.Valid && job.TenantID.String != "" {
+		tenantID = job.TenantID.String
+	}
+
+	if err := s.rateLimiter.Acquire(ctx, string(job.Type), tenantID, PriorityNormal); err != nil {
+		s.logger.WarnContext(ctx, "rate limit wait cancelled", "error", err.Error())
+		return db.Job{}, false
+	}
+
+	if err := s.queries.ExtendJobLease(ctx, db.ExtendJobLeaseParams{
+		ID:          job.ID,
+		LeasedBy:    sql.NullString{String: workerID, Valid: true},
+		LeasedUntil: sql.NullTime{Time: time.Now().Add(jobLeaseDuration), Valid: true},
+	}); err != nil {
+		s.logger.WarnContext(ctx, "failed to set initial job lease", "job_id", job.ID, "error", err.Error())
+	}
+	metrics.JobsLeased.Inc()
+
+	leaseCtx, cancelLease := context.WithCancel(context.Background())
+	go s.renewLease(leaseCtx, job.ID, workerID)
+
+	s.mu.Lock()
+	s.inFlight[job.ID] = inFlightJob{job: job, tenantID: tenantID, start: time.Now(), cancelLease: cancelLease}
+	s.mu.Unlock()
+
+	return job, true
+}
+
+// renewLease estende o lease de jobID a cada jobLeaseRenewInterval até ctx
+// ser cancelado (ver takeInFlight), o que acontece assim que o job é
+// completado ou falhado — falhas de renovação só são logadas, já que
+// RescueZombies acaba resgatando o job de qualquer forma se o lease vencer.
+func (s *JobServer) renewLease(ctx context.Context, jobID int64, workerID string) {
+	ticker := time.NewTicker(jobLeaseRenewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.queries.ExtendJobLease(ctx, db.ExtendJobLeaseParams{
+				ID:          jobID,
+				LeasedBy:    sql.NullString{String: workerID, Valid: true},
+				LeasedUntil: sql.NullTime{Time: time.Now().Add(jobLeaseDuration), Valid: true},
+			}); err != nil && ctx.Err() == nil {
+				s.logger.WarnContext(ctx, "failed to renew job lease", "job_id", jobID, "error", err.Error())
+			}
+		}
+	}
+}
+
+func (s *JobServer) takeInFlight(jobID int64) (inFlightJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.inFlight[jobID]
+	if ok {
+		delete(s.inFlight, jobID)
+		entry.cancelLease()
+		metrics.JobsLeased.Dec()
+	}
+	return entry, ok
+}
+
+// CompleteJob finaliza um job adquirido via AcquireJob: grava o resultado,
+// libera o rate limiter e notifica via SSE — o equivalente direto do antigo
+// Processor.handleSuccess, agora compartilhado com o caminho remoto.
+func (s *JobServer) CompleteJob(ctx context.Context, workerID string, jobID int64) error {
+	entry, ok := s.takeInFlight(jobID)
+	if !ok {
+		return fmt.Errorf("job %d não está em andamento neste servidor", jobID)
+	}
+	defer s.rateLimiter.Release(string(entry.job.Type), entry.tenantID)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	qtx := s.queries.WithTx(tx)
+
+	if err := qtx.RecordJobProcessed(ctx, jobID); err != nil {
+		return fmt.Errorf("failed to record job processed: %w", err)
+	}
+	if err := qtx.CompleteJob(ctx, jobID); err != nil {
+		return fmt.Errorf("failed to complete job: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	duration := time.Since(entry.start)
+	metrics.JobDuration.WithLabelValues(string(entry.job.Type), "success").Observe(duration.Seconds())
+
+	s.logger.InfoContext(ctx, "job completed",
+		slog.Int64("job_id", jobID),
+		slog.String("job_type", string(entry.job.Type)),
+		slog.String("worker_id", workerID),
+		slog.Float64("duration_ms", float64(duration.Nanoseconds())/1e6),
+	)
+
+	job := entry.job
+	if job.TenantID.Valid {
+		var userID int64
+		if _, err := fmt.Sscanf(job.TenantID.String, "%d", &userID); err == nil && userID > 0 {
+			web.BroadcastToUser(userID, "job_completed", string(job.Type))
+			return nil
+		}
+	}
+	web.Broadcast("job_completed", string(job.Type))
+	return nil
+}
+
+// FailJob registra a falha de um job adquirido via AcquireJob, movendo para
+// a dead letter queue quando dlq.ShouldMoveToDLQ decidir que já tentou o
+// suficiente — o equivalente direto do antigo Processor.handleFailure.
+func (s *JobServer) FailJob(ctx context.Context, workerID string, jobID int64, errMsg string) error {
+	entry, ok := s.takeInFlight(jobID)
+	if !ok {
+		return fmt.Errorf("job %d não está em andamento neste servidor", jobID)
+	}
+	defer s.rateLimiter.Release(string(entry.job.Type), entry.tenantID)
+
+	job := entry.job
+	processingErr := fmt.Errorf("%s", errMsg)
+
+	metrics.JobDuration.WithLabelValues(string(job.Type), "failed").Observe(time.Since(entry.start).Seconds())
+
+	if s.dlq.ShouldMoveToDLQ(job) {
+		if err := s.dlq.Move(ctx, job, processingErr); err != nil {
+			s.logger.ErrorContext(ctx, "failed to move job to DLQ", "error", err.Error())
+		}
+		return nil
+	}
+
+	if err := s.queries.FailJob(ctx, db.FailJobParams{
+		LastError: sql.NullString{String: errMsg, Valid: true},
+		ID:        job.ID,
+	}); err != nil {
+		s.logger.ErrorContext(ctx, "failed to record job failure in db", "error", err.Error())
+	}
+
+	if IsRetryableError(processingErr) {
+		backoff := FullJitter(int(job.AttemptCount), DefaultBackoffConfig)
+		s.logger.InfoContext(ctx, "retryable error, will retry",
+			"backoff", backoff.String(), "error", errMsg)
+		metrics.JobRetries.WithLabelValues(string(job.Type)).Inc()
+	}
+
+	s.logger.ErrorContext(ctx, "job processing failed",
+		slog.Int64("job_id", jobID),
+		slog.String("job_type", string(job.Type)),
+		slog.String("worker_id", workerID),
+		slog.String("error", errMsg),
+	)
+	return nil
+}
+
+// JobView é a representação serializável de um db.Job trocada pelas rotas
+// HTTP de JobServer — db.Job vem do código gerado pelo sqlc sem tags json.
+type JobView struct {
+	ID           int64           `json:"id"`
+	Type         string          `json:"type"`
+	Payload      json.RawMessage `json:"payload"`
+	AttemptCount int64           `json:"attempt_count"`
+	TenantID     string          `json:"tenant_id,omitempty"`
+}
+
+func newJobView(job db.Job) JobView {
+	view := JobView{
+		ID:           job.ID,
+		Type:         string(job.Type),
+		Payload:      job.Payload,
+		AttemptCount: job.AttemptCount,
+	}
+	if job.TenantID.Valid {
+		view.TenantID = job.TenantID.String
+	}
+	return view
+}
+
+type acquireRequest struct {
+	WorkerID string   `json:"worker_id"`
+	JobTypes []string `json:"job_types"`
+}
+
+type acquireResponse struct {
+	Job *JobView `json:"job,omitempty"`
+}
+
+type completeRequest struct {
+	WorkerID string `json:"worker_id"`
+	JobID    int64  `json:"job_id"`
+}
+
+type failRequest struct {
+	WorkerID string `json:"worker_id"`
+	JobID    int64  `json:"job_id"`
+	Error    string `json:"error"`
+}
+
+type updateRequest struct {
+	WorkerID string `json:"worker_id"`
+	JobID    int64  `json:"job_id"`
+	Message  string `json:"message"`
+}
+
+type heartbeatRequest struct {
+	WorkerID string   `json:"worker_id"`
+	JobTypes []string `json:"job_types"`
+}
+
+// Register monta as rotas de JobServer em mux sob /api/worker/, atrás de um
+// shared secret (header "Authorization: Bearer <secret>", comparado em
+// tempo constante) — o mesmo esquema long-poll de AcquireJob, Heartbeat e
+// Update/Complete/Fail que cmd/workerd usa para rodar fora do processo do
+// servidor HTTP. secret vazio desabilita as rotas: só o worker in-process
+// (que chama AcquireJob/CompleteJob/FailJob direto, sem rede) continua
+// disponível.
+func (s *JobServer) Register(mux *http.ServeMux, secret string) {
+	if secret == "" {
+		return
+	}
+
+	auth := requireSecret(secret)
+	mux.Handle("POST /api/worker/acquire", auth(http.HandlerFunc(s.handleAcquire)))
+	mux.Handle("POST /api/worker/complete", auth(http.HandlerFunc(s.handleComplete)))
+	mux.Handle("POST /api/worker/fail", auth(http.HandlerFunc(s.handleFail)))
+	mux.Handle("POST /api/worker/update", auth(http.HandlerFunc(s.handleUpdate)))
+	mux.Handle("POST /api/worker/heartbeat", auth(http.HandlerFunc(s.handleHeartbeat)))
+}
+
+func requireSecret(secret string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (s *JobServer) handleAcquire(w http.ResponseWriter, r *http.Request) {
+	var req acquireRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if req.WorkerID == "" {
+		http.Error(w, "worker_id obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), acquireLongPollTimeout)
+	defer cancel()
+
+	woken := make(chan struct{}, 1)
+	if s.jobs != nil {
+		for _, jobType := range req.JobTypes {
+			sub := s.jobs.Subscribe(jobType)
+			defer sub.Close()
+			go func(sub *pubsub.Subscription) {
+				select {
+				case <-ctx.Done():
+				case <-sub.C:
+					select {
+					case woken <- struct{}{}:
+					default:
+					}
+				}
+			}(sub)
+		}
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		if job, ok := s.AcquireJob(ctx, req.WorkerID, req.JobTypes); ok {
+			view := newJobView(job)
+			writeJSON(w, http.StatusOK, acquireResponse{Job: &view})
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case <-woken:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *JobServer) handleComplete(w http.ResponseWriter, r *http.Request) {
+	var req completeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if err := s.CompleteJob(r.Context(), req.WorkerID, req.JobID); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *JobServer) handleFail(w http.ResponseWriter, r *http.Request) {
+	var req failRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if err := s.FailJob(r.Context(), req.WorkerID, req.JobID, req.Error); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleUpdate só registra o progresso no log: não há, hoje, uma coluna de
+// progresso em jobs para persistir (ver schema usado por PickNextJob e
+// companhia), então streaming de progresso fica observável via log
+// estruturado, não via estado consultável.
+func (s *JobServer) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	var req updateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	s.logger.Info("job progress update",
+		slog.Int64("job_id", req.JobID),
+		slog.String("worker_id", req.WorkerID),
+		slog.String("message", req.Message),
+	)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *JobServer) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
+	var req heartbeatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	s.logger.Debug("worker heartbeat",
+		slog.String("worker_id", req.WorkerID),
+		slog.Any("job_types", req.JobTypes),
+	)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -13,7 +13,7 @@ func TestProcessor_New(t *testing.T) {
 	cfg := &config.Config{SMTPHost: "localhost", SMTPPort: "1025"}
 
 	t.Run("ProcessorInitialization", func(t *testing.T) {
-		p := New(cfg, nil, nil, logger)
+		p := New(cfg, nil, nil, logger, nil, nil)
 		if p == nil {
 			t.Fatal("expected processor, got nil")
 		}
@@ -0,0 +1,196 @@
+package benchmarks
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// updateBaselines espelha o padrão "-update" de golden files: quando
+// ligada, AssertNoRegression só grava o baseline atual (via SaveBaseline)
+// em vez de compará-lo com o anterior — assim, um ganho intencional de
+// performance é fácil de registrar: `go test ./test/benchmarks -bench=. -update-baselines`.
+var updateBaselines = flag.Bool("update-baselines", false, "grava o baseline atual em vez de compará-lo com o anterior")
+
+// RegressionThreshold é a degradação relativa (em p95 ou allocs/op) acima
+// da qual Compare considera uma regressão — configurável como
+// web.DefaultClientBufferSize/worker.DefaultJobRateConfigs, um var de
+// pacote ajustável antes dos testes rodarem em vez de um parâmetro extra
+// em toda chamada.
+var RegressionThreshold = 0.10
+
+// SignificanceLevel é o p-value máximo (Mann-Whitney U) abaixo do qual uma
+// diferença é considerada estatisticamente significativa.
+const SignificanceLevel = 0.05
+
+// Baseline é o que SaveBaseline persiste em benchmarks/baselines/<name>.json
+// — os resumos pedidos (mean, p50/p95/p99, allocs, bytes) mais as amostras
+// brutas de duração, porque Compare precisa delas para o teste de
+// Mann-Whitney (um resumo sozinho não dá pra testar significância).
+type Baseline struct {
+	Name           string  `json:"name"`
+	MeanNanos      int64   `json:"mean_ns"`
+	P50Nanos       int64   `json:"p50_ns"`
+	P95Nanos       int64   `json:"p95_ns"`
+	P99Nanos       int64   `json:"p99_ns"`
+	AllocsPerOp    int64   `json:"allocs_per_op"`
+	BytesPerOp     int64   `json:"bytes_per_op"`
+	DurationsNanos []int64 `json:"durations_ns"`
+}
+
+// baselinePath devolve o caminho de benchmarks/baselines/<name>.json,
+// relativo ao diretório do pacote (onde `go test` executa).
+func baselinePath(name string) string {
+	return filepath.Join("baselines", name+".json")
+}
+
+// toBaseline converte as amostras acumuladas em m para o formato
+// persistido por SaveBaseline/lido por LoadBaseline.
+func (m *Metrics) toBaseline(name string) Baseline {
+	durations := make([]int64, len(m.Durations))
+	for i, d := range m.Durations {
+		durations[i] = int64(d)
+	}
+
+	return Baseline{
+		Name:           name,
+		MeanNanos:      int64(m.Mean()),
+		P50Nanos:       int64(m.P50()),
+		P95Nanos:       int64(m.P95()),
+		P99Nanos:       int64(m.P99()),
+		AllocsPerOp:    m.AllocsPerOp,
+		BytesPerOp:     m.BytesPerOp,
+		DurationsNanos: durations,
+	}
+}
+
+// SaveBaseline grava o estado atual de m em path (ver baselinePath),
+// criando benchmarks/baselines/ se necessário.
+func (m *Metrics) SaveBaseline(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(m.toBaseline(filepathBase(path)), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+func filepathBase(path string) string {
+	base := filepath.Base(path)
+	return base[:len(base)-len(filepath.Ext(base))]
+}
+
+// LoadBaseline lê um Baseline gravado por SaveBaseline. Devolve
+// (nil, nil) — não um erro — quando o arquivo ainda não existe, para que a
+// primeira execução de um benchmark novo só estabeleça o baseline em vez
+// de falhar (ver AssertNoRegression).
+func LoadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// Report é o resultado de Compare.
+type Report struct {
+	Name          string
+	Regressed     bool
+	P95DeltaRatio float64 // (current-baseline)/baseline, ex.: 0.15 = 15% mais lento
+	AllocsDelta   float64
+	PValue        float64
+	Message       string
+}
+
+// Compare confronta current contra baseline e devolve um Report. Flags
+// uma regressão quando p95 OU allocs/op degradam mais que threshold E a
+// diferença nas durações brutas é estatisticamente significativa (Mann-Whitney
+// U, rejeitando a hipótese nula em p < SignificanceLevel) — só o desvio
+// no p95/allocs não basta, porque ruído de máquina sozinho pode superar
+// 10% numa amostra pequena.
+func Compare(current *Metrics, baseline *Baseline, threshold float64) Report {
+	name := baseline.Name
+	report := Report{Name: name}
+
+	if baseline.P95Nanos > 0 {
+		report.P95DeltaRatio = float64(current.P95()-time.Duration(baseline.P95Nanos)) / float64(baseline.P95Nanos)
+	}
+	if baseline.AllocsPerOp > 0 {
+		report.AllocsDelta = float64(current.AllocsPerOp-baseline.AllocsPerOp) / float64(baseline.AllocsPerOp)
+	}
+
+	degraded := report.P95DeltaRatio > threshold || report.AllocsDelta > threshold
+
+	currentNanos := make([]int64, len(current.Durations))
+	for i, d := range current.Durations {
+		currentNanos[i] = int64(d)
+	}
+	report.PValue = mannWhitneyPValue(currentNanos, baseline.DurationsNanos)
+
+	report.Regressed = degraded && report.PValue < SignificanceLevel
+
+	switch {
+	case report.Regressed:
+		report.Message = "regression detected: p95 or allocs/op degraded beyond threshold with statistical significance"
+	case degraded:
+		report.Message = "degradation observed but not statistically significant, not flagging"
+	default:
+		report.Message = "no regression"
+	}
+
+	return report
+}
+
+// AssertNoRegression é o helper pensado para `go test`: benchmarks chamam
+// isso no fim de rodar, passando as Metrics coletadas. Com
+// -update-baselines, só grava o baseline atual. Caso contrário, compara
+// contra benchmarks/baselines/<name>.json (estabelecendo-o na primeira
+// execução) e falha o teste com t.Errorf se Compare sinalizar regressão.
+//
+// Assinatura diverge do `AssertNoRegression(t, name)` descrito originalmente:
+// sem receber as Metrics da rodada atual não há o que comparar contra o
+// baseline, então m é um parâmetro explícito aqui.
+func AssertNoRegression(t *testing.B, name string, m *Metrics) {
+	t.Helper()
+	path := baselinePath(name)
+
+	if *updateBaselines {
+		if err := m.SaveBaseline(path); err != nil {
+			t.Fatalf("failed to update baseline %s: %v", name, err)
+		}
+		return
+	}
+
+	baseline, err := LoadBaseline(path)
+	if err != nil {
+		t.Fatalf("failed to load baseline %s: %v", name, err)
+	}
+	if baseline == nil {
+		if err := m.SaveBaseline(path); err != nil {
+			t.Fatalf("failed to establish baseline %s: %v", name, err)
+		}
+		t.Logf("baseline %s did not exist, established from this run", name)
+		return
+	}
+
+	report := Compare(m, baseline, RegressionThreshold)
+	if report.Regressed {
+		t.Errorf("performance regression in %s: p95 delta=%.1f%% allocs delta=%.1f%% p-value=%.4f",
+			name, report.P95DeltaRatio*100, report.AllocsDelta*100, report.PValue)
+	}
+}
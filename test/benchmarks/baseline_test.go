@@ -0,0 +1,100 @@
+package benchmarks
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveAndLoadBaseline(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "example.json")
+
+	m := NewMetrics()
+	for _, d := range []time.Duration{10 * time.Millisecond, 12 * time.Millisecond, 11 * time.Millisecond} {
+		m.Record(d)
+	}
+	m.AllocsPerOp = 5
+	m.BytesPerOp = 128
+
+	if err := m.SaveBaseline(path); err != nil {
+		t.Fatalf("SaveBaseline failed: %v", err)
+	}
+
+	baseline, err := LoadBaseline(path)
+	if err != nil {
+		t.Fatalf("LoadBaseline failed: %v", err)
+	}
+	if baseline == nil {
+		t.Fatal("expected a baseline, got nil")
+	}
+	if baseline.AllocsPerOp != 5 || baseline.BytesPerOp != 128 {
+		t.Fatalf("unexpected baseline: %+v", baseline)
+	}
+	if len(baseline.DurationsNanos) != 3 {
+		t.Fatalf("expected 3 raw samples, got %d", len(baseline.DurationsNanos))
+	}
+}
+
+func TestLoadBaseline_MissingFileIsNotAnError(t *testing.T) {
+	baseline, err := LoadBaseline(filepath.Join(t.TempDir(), "does-not-exist.json"))
+	if err != nil {
+		t.Fatalf("expected no error for a missing baseline, got %v", err)
+	}
+	if baseline != nil {
+		t.Fatalf("expected nil baseline, got %+v", baseline)
+	}
+}
+
+func sampleMetrics(base time.Duration, jitter time.Duration, n int) *Metrics {
+	m := NewMetrics()
+	for i := 0; i < n; i++ {
+		offset := time.Duration(i%5) * jitter
+		m.Record(base + offset)
+	}
+	return m
+}
+
+func TestCompare_NoRegressionWhenSimilar(t *testing.T) {
+	baselineMetrics := sampleMetrics(10*time.Millisecond, time.Millisecond, 40)
+	baseline := baselineMetrics.toBaseline("stable")
+	baseline.AllocsPerOp = 10
+
+	current := sampleMetrics(10*time.Millisecond, time.Millisecond, 40)
+	current.AllocsPerOp = 10
+
+	report := Compare(current, &baseline, RegressionThreshold)
+	if report.Regressed {
+		t.Fatalf("expected no regression for near-identical samples, got %+v", report)
+	}
+}
+
+func TestCompare_FlagsSignificantRegression(t *testing.T) {
+	baselineMetrics := sampleMetrics(10*time.Millisecond, time.Millisecond, 60)
+	baseline := baselineMetrics.toBaseline("slow")
+	baseline.AllocsPerOp = 10
+
+	current := sampleMetrics(20*time.Millisecond, time.Millisecond, 60)
+	current.AllocsPerOp = 10
+
+	report := Compare(current, &baseline, RegressionThreshold)
+	if !report.Regressed {
+		t.Fatalf("expected a flagged regression for a doubled p95, got %+v", report)
+	}
+}
+
+func TestMannWhitneyPValue_IdenticalSamplesAreNotSignificant(t *testing.T) {
+	a := []int64{10, 11, 12, 13, 14, 10, 11, 12, 13, 14}
+	b := []int64{10, 11, 12, 13, 14, 10, 11, 12, 13, 14}
+
+	if p := mannWhitneyPValue(a, b); p < SignificanceLevel {
+		t.Fatalf("expected a high p-value for identical samples, got %f", p)
+	}
+}
+
+func TestMannWhitneyPValue_EmptySampleIsNotSignificant(t *testing.T) {
+	if p := mannWhitneyPValue(nil, []int64{1, 2, 3}); p != 1 {
+		t.Fatalf("expected p-value 1 for an empty sample, got %f", p)
+	}
+}
+
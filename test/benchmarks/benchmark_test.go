@@ -10,10 +10,12 @@ import (
 	"net/http/httptest"
 	"os"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/PauloHFS/goth/internal/contextkeys"
 	"github.com/PauloHFS/goth/internal/db"
+	"github.com/PauloHFS/goth/internal/search"
 	"github.com/PauloHFS/goth/internal/vector"
 	"github.com/PauloHFS/goth/internal/view"
 	"github.com/PauloHFS/goth/internal/view/pages"
@@ -266,7 +268,7 @@ func BenchmarkVector_Search_Cosine(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		results, err := service.Search(ctx, "document", queryVector, 10, vector.DistanceCosine)
+		results, err := service.Search(ctx, "document", queryVector, 10, vector.DistanceCosine, nil, vector.SearchParams{})
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -296,7 +298,7 @@ func BenchmarkVector_Search_L2(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		results, err := service.Search(ctx, "document", queryVector, 10, vector.DistanceL2)
+		results, err := service.Search(ctx, "document", queryVector, 10, vector.DistanceL2, nil, vector.SearchParams{})
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -326,7 +328,7 @@ func BenchmarkVector_Search_Global(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		results, err := service.SearchGlobal(ctx, queryVector, 10, vector.DistanceCosine)
+		results, err := service.SearchGlobal(ctx, queryVector, 10, vector.DistanceCosine, nil, vector.SearchParams{})
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -387,6 +389,35 @@ func BenchmarkVector_BatchInsert(b *testing.B) {
 	}
 }
 
+// BenchmarkVector_BatchUpsert mede vector.Service.BatchUpsert sobre o mesmo
+// batchSize/corpus de BenchmarkVector_BatchInsert acima, mas sem o
+// boilerplate de abrir a transação e serializar cada vetor manualmente —
+// BatchUpsert faz isso internamente (ver PauloHFS/goth#chunk6-4).
+func BenchmarkVector_BatchUpsert(b *testing.B) {
+	dbConn, _ := setupTestDB(b, "single")
+	service := setupVectorService(b, dbConn, 128)
+
+	batchSize := 100
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		embeddings := make([]vector.Embedding, batchSize)
+		for j := 0; j < batchSize; j++ {
+			idx := i*batchSize + j
+			embeddings[j] = vector.Embedding{
+				ContentType: "document",
+				ContentID:   int64(idx),
+				Vector:      generateRandomVector(128),
+				Metadata:    map[string]any{"title": fmt.Sprintf("Doc %d", idx)},
+			}
+		}
+
+		if _, err := service.BatchUpsert(context.Background(), embeddings); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func BenchmarkVector_ConcurrentSearch(b *testing.B) {
 	dbConn, _ := setupTestDB(b, "dual")
 	service := setupVectorService(b, dbConn, 128)
@@ -408,7 +439,7 @@ func BenchmarkVector_ConcurrentSearch(b *testing.B) {
 	b.ResetTimer()
 	b.RunParallel(func(pb *testing.PB) {
 		for pb.Next() {
-			results, err := service.Search(ctx, "document", queryVector, 10, vector.DistanceCosine)
+			results, err := service.Search(ctx, "document", queryVector, 10, vector.DistanceCosine, nil, vector.SearchParams{})
 			if err != nil {
 				b.Fatal(err)
 			}
@@ -443,7 +474,7 @@ func BenchmarkVector_Dimension_Scale(b *testing.B) {
 
 			b.ResetTimer()
 			for i := 0; i < b.N; i++ {
-				results, err := service.Search(ctx, "document", queryVector, 10, vector.DistanceCosine)
+				results, err := service.Search(ctx, "document", queryVector, 10, vector.DistanceCosine, nil, vector.SearchParams{})
 				if err != nil {
 					b.Fatal(err)
 				}
@@ -486,6 +517,7 @@ func setupProductionVectorDB(b *testing.B, numVectors int, dimension int) (*sql.
 		Enabled:            true,
 		EmbeddingDimension: dimension,
 		TableName:          "vectors_prod",
+		AllowedFilterKeys:  []string{"tenant_id", "category"},
 	}
 
 	store := vector.NewStore(dbConn, config)
@@ -554,7 +586,7 @@ func BenchmarkVector_KNN_Index(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		results, err := service.Search(ctx, "document", queryVector, 20, vector.DistanceCosine)
+		results, err := service.Search(ctx, "document", queryVector, 20, vector.DistanceCosine, nil, vector.SearchParams{})
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -564,30 +596,63 @@ func BenchmarkVector_KNN_Index(b *testing.B) {
 	}
 }
 
+// BenchmarkVector_IndexComparison mede o tradeoff recall/latência entre os
+// três IndexKind de internal/vector (ver IndexSpec): "flat" é o full scan
+// via sqlite-vec feito quando nenhum índice em memória está habilitado;
+// "ivf_flat" e "hnsw" usam Store.EnableIndex para construir o índice
+// correspondente sobre o mesmo corpus de 10k vetores de 384 dimensões das
+// demais produção-realistic benchmarks acima.
+func BenchmarkVector_IndexComparison(b *testing.B) {
+	kinds := []vector.IndexKind{vector.IndexFlat, vector.IndexIVFFlat, vector.IndexHNSW}
+
+	for _, kind := range kinds {
+		b.Run(string(kind), func(b *testing.B) {
+			_, service := setupProductionVectorDB(b, 10000, 384)
+			queryVector := generateRealisticVector(384, 5)
+			ctx := context.Background()
+
+			if kind != vector.IndexFlat {
+				spec := vector.IndexSpec{Kind: kind}
+				if err := service.VectorStore().EnableIndex(ctx, "document", vector.DistanceCosine, spec); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				results, err := service.Search(ctx, "document", queryVector, 20, vector.DistanceCosine, nil, vector.SearchParams{})
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(results) == 0 {
+					b.Error("expected results")
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkVector_WithMetadataFilter empurra o filtro de tenant para dentro
+// da query k-NN via vector.Eq (ver Filter em internal/vector/filter.go), em
+// vez de trazer o top-k sem filtro e descartar linhas no app layer depois —
+// essa segunda abordagem erra o resultado sempre que os vizinhos mais
+// próximos do tenant filtrado não estão entre os top-k globais.
 func BenchmarkVector_WithMetadataFilter(b *testing.B) {
 	_, service := setupProductionVectorDB(b, 10000, 384)
 	ctx := context.Background()
 
 	queryVector := generateRealisticVector(384, 5)
+	filter := vector.Eq("tenant_id", "tenant_5")
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		// Simula filtro por tenant + categoria
-		results, err := service.Search(ctx, "document", queryVector, 20, vector.DistanceCosine)
+		results, err := service.Search(ctx, "document", queryVector, 20, vector.DistanceCosine, filter, vector.SearchParams{})
 		if err != nil {
 			b.Fatal(err)
 		}
-
-		// Filtrar resultados por metadata (simula WHERE no app layer)
-		filtered := 0
-		for _, r := range results {
-			if tenant, ok := r.Metadata["tenant_id"].(string); ok {
-				if tenant == "tenant_5" {
-					filtered++
-				}
-			}
+		if len(results) == 0 {
+			b.Error("expected results")
 		}
-		_ = filtered
 	}
 }
 
@@ -600,7 +665,7 @@ func BenchmarkVector_ProductionScale(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		results, err := service.Search(ctx, "document", queryVector, 10, vector.DistanceCosine)
+		results, err := service.Search(ctx, "document", queryVector, 10, vector.DistanceCosine, nil, vector.SearchParams{})
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -619,7 +684,7 @@ func BenchmarkVector_OpenAI_Dimensions(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		results, err := service.Search(ctx, "document", queryVector, 10, vector.DistanceCosine)
+		results, err := service.Search(ctx, "document", queryVector, 10, vector.DistanceCosine, nil, vector.SearchParams{})
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -655,7 +720,7 @@ func BenchmarkVector_MixedWorkload(b *testing.B) {
 			} else {
 				// Leitura: busca por similaridade
 				queryVector := generateRealisticVector(384, readCount%100)
-				results, err := service.Search(ctx, "document", queryVector, 10, vector.DistanceCosine)
+				results, err := service.Search(ctx, "document", queryVector, 10, vector.DistanceCosine, nil, vector.SearchParams{})
 				if err != nil {
 					b.Fatal(err)
 				}
@@ -667,6 +732,10 @@ func BenchmarkVector_MixedWorkload(b *testing.B) {
 	})
 }
 
+// BenchmarkVector_ConcurrentFilteredSearch mede o custo do pushdown de
+// vector.Eq (ver BenchmarkVector_WithMetadataFilter) sob concorrência, em vez
+// de trazer o top-k sem filtro e só inspecionar o resultado por tenant no
+// app layer.
 func BenchmarkVector_ConcurrentFilteredSearch(b *testing.B) {
 	_, service := setupProductionVectorDB(b, 10000, 384)
 	ctx := context.Background()
@@ -674,19 +743,12 @@ func BenchmarkVector_ConcurrentFilteredSearch(b *testing.B) {
 	b.ResetTimer()
 	b.RunParallel(func(pb *testing.PB) {
 		tenantID := fmt.Sprintf("tenant_%d", rand.Intn(100))
+		filter := vector.Eq("tenant_id", tenantID)
 		for pb.Next() {
 			queryVector := generateRealisticVector(384, rand.Intn(100))
-			results, err := service.Search(ctx, "document", queryVector, 20, vector.DistanceCosine)
-			if err != nil {
+			if _, err := service.Search(ctx, "document", queryVector, 20, vector.DistanceCosine, filter, vector.SearchParams{}); err != nil {
 				b.Fatal(err)
 			}
-
-			// Filtrar por tenant
-			for _, r := range results {
-				if t, ok := r.Metadata["tenant_id"].(string); ok && t == tenantID {
-					break
-				}
-			}
 		}
 	})
 }
@@ -717,3 +779,199 @@ func BenchmarkVector_WALCheckpoint(b *testing.B) {
 		}
 	}
 }
+
+// ============================================
+// Hybrid Search Benchmarks (internal/search)
+// ============================================
+
+// hybridTopics é o corpus sintético usado pelos benchmarks de busca híbrida:
+// cada tópico vira um cluster de vetores via generateRealisticVector, para
+// que a perna vetorial tenha algo semanticamente coerente para encontrar.
+var hybridTopics = []string{
+	"golang concurrency patterns",
+	"sqlite performance tuning",
+	"vector search indexing",
+	"adaptive rate limiting",
+	"magic link authentication",
+}
+
+// topicEmbedder embeda uma query buscando o tópico correspondente em
+// hybridTopics e devolvendo o vetor do seu cluster, para simular um
+// Embedder real sem depender de uma chamada de LLM de verdade.
+type topicEmbedder struct {
+	dimension int
+}
+
+func (e topicEmbedder) Embed(_ context.Context, text string) ([]float64, error) {
+	for i, topic := range hybridTopics {
+		if strings.Contains(text, topic) {
+			return generateRealisticVector(e.dimension, i), nil
+		}
+	}
+	return generateRealisticVector(e.dimension, 0), nil
+}
+
+// setupHybridCorpus popula uma tabela virtual FTS5 "posts_fts" e o vector.Service
+// correspondente com o mesmo conjunto de 500 documentos sintéticos, cada um
+// pertencente a um dos hybridTopics, para exercitar as três estratégias de
+// busca sobre o mesmo corpus.
+func setupHybridCorpus(b *testing.B, dbConn *sql.DB, service *vector.Service, dimension int) {
+	if _, err := dbConn.Exec(`CREATE VIRTUAL TABLE posts_fts USING fts5(body)`); err != nil {
+		b.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 500; i++ {
+		topicIdx := i % len(hybridTopics)
+		body := fmt.Sprintf("%s deep dive number %d", hybridTopics[topicIdx], i)
+
+		if _, err := dbConn.Exec(`INSERT INTO posts_fts(rowid, body) VALUES (?, ?)`, i+1, body); err != nil {
+			b.Fatal(err)
+		}
+
+		embedding := vector.Embedding{
+			ContentType: "post",
+			ContentID:   int64(i + 1),
+			Vector:      generateRealisticVector(dimension, topicIdx),
+		}
+		if _, err := service.Store(ctx, embedding); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkHybridSearch(b *testing.B) {
+	dimension := 128
+	dbConn, _ := setupTestDB(b, "single")
+	service := setupVectorService(b, dbConn, dimension)
+	setupHybridCorpus(b, dbConn, service, dimension)
+
+	embedder := topicEmbedder{dimension: dimension}
+	searcher := search.NewSearcher(dbConn, search.FTSConfig{TableName: "posts_fts"}, service, embedder)
+
+	query := hybridTopics[2]
+	ctx := context.Background()
+
+	b.Run("FTS", func(b *testing.B) {
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			rows, err := dbConn.Query(`SELECT rowid FROM posts_fts WHERE posts_fts MATCH ? ORDER BY bm25(posts_fts) LIMIT 10`, query)
+			if err != nil {
+				b.Fatal(err)
+			}
+			rows.Close()
+		}
+	})
+
+	b.Run("Vector", func(b *testing.B) {
+		queryVector, _ := embedder.Embed(ctx, query)
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := service.SearchGlobal(ctx, queryVector, 10, vector.DistanceCosine, nil, vector.SearchParams{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("Hybrid", func(b *testing.B) {
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := searcher.Hybrid(ctx, query, 10, search.HybridOptions{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkVector_HybridSearch mede vector.Service.HybridSearch diretamente
+// (RRF dentro do próprio pacote vector, ver SearchQuery), apontando
+// SearchQuery.FTSTable para a "posts_fts" de setupHybridCorpus em vez da
+// tabela companheira de sempre — o mesmo corpus e consulta de
+// BenchmarkHybridSearch acima, mas sem passar por internal/search.Searcher.
+func BenchmarkVector_HybridSearch(b *testing.B) {
+	dimension := 128
+	dbConn, _ := setupTestDB(b, "single")
+	service := setupVectorService(b, dbConn, dimension)
+	setupHybridCorpus(b, dbConn, service, dimension)
+
+	embedder := topicEmbedder{dimension: dimension}
+	query := hybridTopics[2]
+	ctx := context.Background()
+	queryVector, _ := embedder.Embed(ctx, query)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		hits, err := service.HybridSearch(ctx, vector.SearchQuery{
+			Text:     query,
+			Vector:   queryVector,
+			FTSTable: "posts_fts",
+			Limit:    10,
+			Metric:   vector.DistanceCosine,
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(hits) == 0 {
+			b.Error("expected hits")
+		}
+	}
+}
+
+// BenchmarkVector_Backend_Search roda o mesmo Search em dois Backend
+// diferentes (ver vector.Backend) para comparar o custo do full scan via
+// sqlite-vec contra o full scan equivalente em memória (vector.MemoryBackend)
+// — os dois seguem o mesmo caminho sem filtro/índice em Service.Search, só
+// trocando o que está por trás.
+func BenchmarkVector_Backend_Search(b *testing.B) {
+	const dimension = 128
+	const corpusSize = 1000
+
+	queryVector := generateRandomVector(dimension)
+
+	dbConn, _ := setupTestDB(b, "single")
+	config := vector.Config{
+		Enabled:            true,
+		EmbeddingDimension: dimension,
+		TableName:          "vectors_test",
+	}
+	store := vector.NewStore(dbConn, config)
+	if err := store.EnsureTable(context.Background()); err != nil {
+		b.Fatal(err)
+	}
+
+	backends := map[string]vector.Backend{
+		"sqlite-vec": store,
+		"memory":     vector.NewMemoryBackend(dimension),
+	}
+
+	for name, backend := range backends {
+		backend := backend
+		b.Run(name, func(b *testing.B) {
+			service := vector.NewService(backend)
+			ctx := context.Background()
+
+			for i := 0; i < corpusSize; i++ {
+				embedding := vector.Embedding{
+					ContentType: "document",
+					ContentID:   int64(i),
+					Vector:      generateRandomVector(dimension),
+				}
+				if _, err := service.Store(ctx, embedding); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				results, err := service.Search(ctx, "document", queryVector, 10, vector.DistanceCosine, nil, vector.SearchParams{})
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(results) == 0 {
+					b.Error("expected results")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package benchmarks
+
+import (
+	"math"
+	"sort"
+)
+
+// mannWhitneyPValue roda um teste U de Mann-Whitney (duas caudas, com
+// aproximação normal e correção de empates) sobre duas amostras de
+// durações em nanossegundos, devolvendo o p-value da hipótese nula de que
+// vêm da mesma distribuição. Usado por Compare para não sinalizar uma
+// regressão só porque o p95/allocs mudou — a diferença nas amostras brutas
+// também precisa ser estatisticamente significativa (ver
+// SignificanceLevel).
+//
+// Implementação simples o bastante para amostras de benchmark (dezenas a
+// milhares de pontos): aproximação normal em vez da distribuição exata de
+// U, o suficiente para n1,n2 > ~8 (regra usual para a aproximação).
+// Amostras menores que isso ainda rodam, só com menos poder estatístico.
+func mannWhitneyPValue(a, b []int64) float64 {
+	n1, n2 := len(a), len(b)
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+
+	type sample struct {
+		value float64
+		group int // 0 = a, 1 = b
+	}
+
+	combined := make([]sample, 0, n1+n2)
+	for _, v := range a {
+		combined = append(combined, sample{value: float64(v), group: 0})
+	}
+	for _, v := range b {
+		combined = append(combined, sample{value: float64(v), group: 1})
+	}
+
+	sort.Slice(combined, func(i, j int) bool { return combined[i].value < combined[j].value })
+
+	ranks := make([]float64, len(combined))
+	var tieCorrection float64
+	i := 0
+	for i < len(combined) {
+		j := i
+		for j+1 < len(combined) && combined[j+1].value == combined[i].value {
+			j++
+		}
+		// Empates recebem o rank médio do grupo [i, j].
+		avgRank := float64(i+j)/2 + 1
+		for k := i; k <= j; k++ {
+			ranks[k] = avgRank
+		}
+		tieSize := float64(j - i + 1)
+		tieCorrection += tieSize*tieSize*tieSize - tieSize
+		i = j + 1
+	}
+
+	var rankSumA float64
+	for idx, s := range combined {
+		if s.group == 0 {
+			rankSumA += ranks[idx]
+		}
+	}
+
+	u1 := rankSumA - float64(n1*(n1+1))/2
+	u2 := float64(n1*n2) - u1
+	u := math.Min(u1, u2)
+
+	nf1, nf2 := float64(n1), float64(n2)
+	meanU := nf1 * nf2 / 2
+
+	total := nf1 + nf2
+	varianceU := nf1 * nf2 / 12 * ((total + 1) - tieCorrection/(total*(total-1)))
+	if varianceU <= 0 {
+		// Todos os valores empatados entre os dois grupos — sem variância
+		// pra testar, trata como "não significativo".
+		return 1
+	}
+
+	z := (u - meanU) / math.Sqrt(varianceU)
+	return 2 * (1 - standardNormalCDF(math.Abs(z)))
+}
+
+// standardNormalCDF é a CDF da normal padrão via math.Erf — mesma
+// identidade usada por qualquer implementação de teste estatístico sem
+// depender de uma lib externa de estatística (não há uma no projeto).
+func standardNormalCDF(z float64) float64 {
+	return 0.5 * (1 + math.Erf(z/math.Sqrt2))
+}
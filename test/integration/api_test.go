@@ -65,7 +65,7 @@ func setupTestServer(t *testing.T) *TestServer {
 
 	handler := middleware.Recovery(
 		middleware.Logger(
-			middleware.SecurityHeaders(false)(
+			middleware.SecurityHeaders(middleware.DefaultSecurityHeadersConfig(false))(
 				middleware.Locale(
 					sessionManager.LoadAndSave(
 						mux,